@@ -1,6 +1,11 @@
 package commands
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/erigon/cmd/devnettest/erigon"
+	"github.com/ledgerwatch/erigon/cmd/devnettest/services"
 	"github.com/spf13/cobra"
 )
 
@@ -12,9 +17,20 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&reqId, "req-id", 0, "Defines number of request id")
 }
 
+// rootCmd starts the dev node and rpc daemon the commands run against, the scenario command launches its own devnet
 var rootCmd = &cobra.Command{
 	Use:   "devnettest",
 	Short: "Devnettest root command",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		erigon.StartProcess()
+
+		time.Sleep(10 * time.Second)
+
+		fmt.Printf("SUCCESS => Started!\n\n")
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		services.ClearDevDB()
+	},
 }
 
 // Execute executes the root command.
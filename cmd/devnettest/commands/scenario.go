@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ledgerwatch/erigon/cmd/devnettest/devnet"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scenarioName      string
+	scenarioFile      string
+	scenarioNodes     int
+	scenarioSigners   int
+	scenarioDataDir   string
+	scenarioPeriod    uint64
+	scenarioP2PPort   int
+	scenarioHTTPPort  int
+	scenarioVerbosity int
+)
+
+func init() {
+	scenarioCmd.Flags().StringVar(&scenarioName, "name", "", fmt.Sprintf("Builtin scenario to run, one of %v", devnet.BuiltinScenarios()))
+	scenarioCmd.Flags().StringVar(&scenarioFile, "file", "", "JSON scenario file to run, instead of a builtin scenario")
+	scenarioCmd.Flags().IntVar(&scenarioNodes, "nodes", devnet.DefaultConfig.Nodes, "Number of the nodes of the devnet, if the scenario doesn't require more")
+	scenarioCmd.Flags().IntVar(&scenarioSigners, "signers", 0, "Number of the nodes sealing the blocks, all of them if 0")
+	scenarioCmd.Flags().StringVar(&scenarioDataDir, "datadir", "", "Directory of the datadirs of the nodes, a temporary directory removed at the end if empty")
+	scenarioCmd.Flags().Uint64Var(&scenarioPeriod, "dev.period", devnet.DefaultConfig.Period, "Seconds between the blocks")
+	scenarioCmd.Flags().IntVar(&scenarioP2PPort, "port", devnet.DefaultConfig.P2PPort, "P2P port of the first node, the next nodes use the next ports")
+	scenarioCmd.Flags().IntVar(&scenarioHTTPPort, "http.port", devnet.DefaultConfig.HTTPPort, "HTTP-RPC port of the first node, the next nodes use the next ports")
+	scenarioCmd.Flags().IntVar(&scenarioVerbosity, "verbosity", int(log.LvlInfo), "Logging verbosity: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail")
+	rootCmd.AddCommand(scenarioCmd)
+}
+
+var scenarioCmd = &cobra.Command{
+	Use:          "scenario",
+	Short:        "Launches a devnet of several nodes in this process and runs a scenario against it",
+	SilenceUsage: true,
+	// the scenario runs against its own devnet, not the single dev node of the other commands
+	PersistentPreRun:  func(cmd *cobra.Command, args []string) {},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(scenarioVerbosity), log.StderrHandler))
+
+		var scenario *devnet.Scenario
+		var err error
+		switch {
+		case scenarioFile != "" && scenarioName != "":
+			return fmt.Errorf("either --name or --file")
+		case scenarioFile != "":
+			scenario, err = devnet.ReadScenario(scenarioFile)
+		default:
+			scenario, err = devnet.BuiltinScenario(scenarioName)
+		}
+		if err != nil {
+			return err
+		}
+
+		config := devnet.DefaultConfig
+		config.Nodes, config.Signers = scenarioNodes, scenarioNodes
+		if scenarioSigners > 0 {
+			config.Signers = scenarioSigners
+		}
+		if required := scenario.Config(config); required.Nodes > config.Nodes || required.Signers > config.Signers {
+			config.Nodes, config.Signers = required.Nodes, required.Signers
+		}
+		config.Period, config.P2PPort, config.HTTPPort = scenarioPeriod, scenarioP2PPort, scenarioHTTPPort
+		config.DataDir = scenarioDataDir
+		if config.DataDir == "" {
+			if config.DataDir, err = os.MkdirTemp("", "devnet"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(config.DataDir)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		fmt.Printf("Starting a devnet of %d nodes with %d signers in %s...\n", config.Nodes, config.Signers, config.DataDir)
+		nw, err := devnet.Start(ctx, config)
+		if err != nil {
+			return err
+		}
+		defer nw.Close()
+		if err := devnet.Run(ctx, nw, scenario); err != nil {
+			return err
+		}
+		fmt.Printf("SUCCESS => Scenario %s passed\n", scenario.Name)
+		return nil
+	},
+}
@@ -0,0 +1,402 @@
+// Package devnet runs a devnet of several Erigon nodes in a process, and scenarios against it. It serves the
+// scenario command of devnettest, and the tests: Start a devnet, then Run a scenario or drive the nodes by their rpc.
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/node/nodecfg"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/erigon/rpc"
+	erigoncli "github.com/ledgerwatch/erigon/turbo/cli"
+	"github.com/ledgerwatch/erigon/turbo/node"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+)
+
+// Config is the configuration of a devnet
+type Config struct {
+	Nodes    int    // number of the nodes
+	Signers  int    // the first Signers nodes are the clique signers, sealing the blocks
+	DataDir  string // the datadirs of the nodes are its node<i> subdirectories, they must not exist
+	Period   uint64 // seconds between the blocks
+	P2PPort  int    // p2p port of the first node, the next nodes use the next ports
+	HTTPPort int    // http rpc port of the first node, the next nodes use the next ports
+}
+
+var DefaultConfig = Config{
+	Nodes:    3,
+	Signers:  3,
+	Period:   2,
+	P2PPort:  30400,
+	HTTPPort: 8600,
+}
+
+// startTimeout is how long a node may take to seal or import its first blocks
+const startTimeout = time.Minute
+
+// httpAPI are the rpc namespaces served by the nodes
+const httpAPI = "eth,erigon,web3,net,debug,trace,txpool,admin"
+
+var (
+	// FaucetKey is the key of the account pre-funded by the dev chain, which sends the transactions of the scenarios
+	FaucetKey = core.DevnetSignPrivateKey
+	// Faucet is the address of FaucetKey
+	Faucet = core.DevnetEtherbase
+)
+
+// Node is a node of the devnet, an Erigon with its txpool and rpcdaemon running in this process
+type Node struct {
+	index   int
+	key     *ecdsa.PrivateKey // p2p node key
+	signer  *ecdsa.PrivateKey // clique signer, nil for the nodes which don't seal
+	p2pPort int
+	url     string
+	erigon  *node.ErigonNode
+	rpc     *rpc.Client
+}
+
+// Network is a devnet of the nodes running in this process, on the dev chain sealed by clique.
+// The sentries of the nodes are registered for the admin rpc, which is process wide: admin_addPeer on any node of
+// the devnet changes the peers of all of them.
+type Network struct {
+	config  Config
+	nodes   []*Node
+	chainID uint64
+}
+
+// Start launches the nodes of the devnet, connected to each other
+func Start(ctx context.Context, config Config) (*Network, error) {
+	if config.Nodes < 1 || config.Signers < 1 || config.Signers > config.Nodes {
+		return nil, fmt.Errorf("devnet: %d nodes with %d signers", config.Nodes, config.Signers)
+	}
+	if config.DataDir == "" {
+		return nil, fmt.Errorf("devnet: no datadir")
+	}
+	nw := &Network{config: config}
+	for i := 0; i < config.Nodes; i++ {
+		n := &Node{
+			index:   i,
+			key:     deriveKey("devnet node key", i),
+			p2pPort: config.P2PPort + i,
+			url:     fmt.Sprintf("http://127.0.0.1:%d", config.HTTPPort+i),
+		}
+		if i < config.Signers {
+			n.signer = deriveKey("devnet signer key", i)
+		}
+		nw.nodes = append(nw.nodes, n)
+	}
+	genesis := nw.genesis()
+	nw.chainID = genesis.Config.ChainID.Uint64()
+	for i, n := range nw.nodes {
+		// the signers join one by one, on top of the blocks of the previous ones, else each of them seals its own
+		// first block and they never agree on a chain
+		if i > 0 && n.signer != nil {
+			if err := nw.nodes[i-1].waitBlock(ctx, uint64(i)); err != nil {
+				nw.Close()
+				return nil, fmt.Errorf("devnet: node %d: %w", i-1, err)
+			}
+		}
+		if err := n.start(nw.args(i), genesis); err != nil {
+			nw.Close()
+			return nil, fmt.Errorf("devnet: node %d: %w", i, err)
+		}
+	}
+	return nw, nil
+}
+
+// waitBlock waits until the node has the block
+func (n *Node) waitBlock(ctx context.Context, number uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	return wait(ctx, func(ctx context.Context) (bool, error) {
+		head, err := n.Head(ctx)
+		if err != nil {
+			return false, err
+		}
+		return uint64(head.Number) >= number, nil
+	})
+}
+
+// deriveKey is a deterministic key, so that the enodes and the signers are the same in every run
+func deriveKey(seed string, i int) *ecdsa.PrivateKey {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s %d", seed, i)))
+	key, err := crypto.ToECDSA(hash[:])
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// genesis is the dev chain genesis with the signers of the devnet
+func (nw *Network) genesis() *core.Genesis {
+	genesis := core.DeveloperGenesisBlock(nw.config.Period, Faucet)
+	extra := make([]byte, 32)
+	for _, n := range nw.nodes {
+		if n.signer != nil {
+			extra = append(extra, crypto.PubkeyToAddress(n.signer.PublicKey).Bytes()...)
+		}
+	}
+	genesis.ExtraData = append(extra, make([]byte, crypto.SignatureLength)...)
+	return genesis
+}
+
+// args are the command line of the node i
+func (nw *Network) args(i int) []string {
+	n := nw.nodes[i]
+	// a node dials the previous ones only, the simultaneous dials of two nodes fail their handshakes
+	var peers []string
+	for _, peer := range nw.nodes[:i] {
+		peers = append(peers, peer.Enode())
+	}
+	args := []string{
+		"devnet",
+		"--datadir=" + filepath.Join(nw.config.DataDir, fmt.Sprintf("node%d", i)),
+		"--chain=" + networkname.DevChainName,
+		fmt.Sprintf("--dev.period=%d", nw.config.Period),
+		fmt.Sprintf("--port=%d", n.p2pPort),
+		fmt.Sprintf("--http.port=%d", nw.config.HTTPPort+i),
+		"--http.api=" + httpAPI,
+		"--private.api.addr=",
+		"--no-downloader",
+		"--nodiscover",
+		"--nodekeyhex=" + hex.EncodeToString(crypto.FromECDSA(n.key)),
+		"--staticpeers=" + strings.Join(peers, ","),
+		// the requests of the blocks of the short lived forks get empty responses, which mustn't ban the few peers
+		fmt.Sprintf("--p2p.ban.threshold=%d", math.MinInt32),
+	}
+	if n.signer != nil {
+		args = append(args, "--mine")
+	}
+	return args
+}
+
+// start configures the node by its command line, like the erigon command, then runs it
+func (n *Node) start(args []string, genesis *core.Genesis) error {
+	var nodeCfg *nodecfg.Config
+	var ethCfg *ethconfig.Config
+	app := cli.NewApp()
+	app.Flags = erigoncli.DefaultFlags
+	app.Action = func(ctx *cli.Context) {
+		nodeCfg = node.NewNodConfigUrfave(ctx)
+		ethCfg = node.NewEthConfigUrfave(ctx, nodeCfg)
+	}
+	if err := app.Run(args); err != nil {
+		return err
+	}
+	ethCfg.Genesis = genesis
+	if n.signer != nil {
+		ethCfg.Miner.SigKey = n.signer
+		ethCfg.Miner.Etherbase = crypto.PubkeyToAddress(n.signer.PublicKey)
+	}
+
+	erigon, err := node.New(nodeCfg, ethCfg, log.New("devnet", n.index))
+	if err != nil {
+		return err
+	}
+	if err := erigon.Start(); err != nil {
+		erigon.Close()
+		return err
+	}
+	n.erigon = erigon
+	if n.rpc, err = rpc.DialHTTP(n.url); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close stops the nodes
+func (nw *Network) Close() {
+	for _, n := range nw.nodes {
+		if n.rpc != nil {
+			n.rpc.Close()
+		}
+		if n.erigon != nil {
+			if err := n.erigon.Close(); err != nil {
+				log.Warn("Devnet node close", "node", n.index, "err", err)
+			}
+		}
+	}
+}
+
+// Nodes are the nodes of the devnet
+func (nw *Network) Nodes() []*Node {
+	return nw.nodes
+}
+
+// Node is the node i of the devnet
+func (nw *Network) Node(i int) (*Node, error) {
+	if i < 0 || i >= len(nw.nodes) {
+		return nil, fmt.Errorf("no node %d, the devnet has %d nodes", i, len(nw.nodes))
+	}
+	return nw.nodes[i], nil
+}
+
+// ChainID is the chain id of the devnet
+func (nw *Network) ChainID() uint64 {
+	return nw.chainID
+}
+
+// Partition disconnects the groups of the nodes from each other. The nodes out of the groups are isolated.
+func (nw *Network) Partition(groups ...[]int) error {
+	group := make(map[int]int)
+	for g, nodes := range groups {
+		for _, i := range nodes {
+			if _, err := nw.Node(i); err != nil {
+				return err
+			}
+			if _, ok := group[i]; ok {
+				return fmt.Errorf("node %d is in several groups", i)
+			}
+			group[i] = g
+		}
+	}
+	for i := range nw.nodes {
+		for j := i + 1; j < len(nw.nodes); j++ {
+			gi, iok := group[i]
+			gj, jok := group[j]
+			if iok && jok && gi == gj {
+				continue
+			}
+			if err := nw.nodes[i].removePeer(nw.nodes[j]); err != nil {
+				return err
+			}
+			if err := nw.nodes[j].removePeer(nw.nodes[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Heal reconnects all the nodes, ending a partition
+func (nw *Network) Heal() error {
+	for i, n := range nw.nodes {
+		for _, peer := range nw.nodes[:i] {
+			if err := n.addPeer(peer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Index is the index of the node in the devnet
+func (n *Node) Index() int {
+	return n.index
+}
+
+// RPC is the client of the http rpc of the node
+func (n *Node) RPC() *rpc.Client {
+	return n.rpc
+}
+
+// Enode is the url of the node for its peers
+func (n *Node) Enode() string {
+	return enode.NewV4(&n.key.PublicKey, net.IPv4(127, 0, 0, 1), n.p2pPort, n.p2pPort).URLv4()
+}
+
+// Signer is the clique signer of the node, zero if the node doesn't seal
+func (n *Node) Signer() common.Address {
+	if n.signer == nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(n.signer.PublicKey)
+}
+
+func (n *Node) sentries() []*sentry.GrpcServer {
+	return n.erigon.Backend().SentryServers()
+}
+
+func (n *Node) addPeer(peer *Node) error {
+	for _, ss := range n.sentries() {
+		if err := ss.AddPeer(context.Background(), peer.Enode()); err != nil {
+			return fmt.Errorf("node %d: add peer %d: %w", n.index, peer.index, err)
+		}
+	}
+	return nil
+}
+
+func (n *Node) removePeer(peer *Node) error {
+	for _, ss := range n.sentries() {
+		if err := ss.RemovePeer(context.Background(), peer.Enode()); err != nil {
+			return fmt.Errorf("node %d: remove peer %d: %w", n.index, peer.index, err)
+		}
+	}
+	return nil
+}
+
+// BlockRef is a block by its number and hash
+type BlockRef struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// Block is the block of the node by its number, nil if the node doesn't have it
+func (n *Node) Block(ctx context.Context, number uint64) (*BlockRef, error) {
+	return n.block(ctx, hexutil.Uint64(number))
+}
+
+// Head is the latest block of the node
+func (n *Node) Head(ctx context.Context) (*BlockRef, error) {
+	head, err := n.block(ctx, "latest")
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("node %d: no latest block", n.index)
+	}
+	return head, nil
+}
+
+func (n *Node) block(ctx context.Context, number interface{}) (*BlockRef, error) {
+	var block *BlockRef
+	if err := n.rpc.CallContext(ctx, &block, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, fmt.Errorf("node %d: %w", n.index, err)
+	}
+	return block, nil
+}
+
+// Sealer is the clique signer of the block of the node
+func (n *Node) Sealer(ctx context.Context, hash common.Hash) (common.Address, error) {
+	var header *types.Header
+	if err := n.erigon.Backend().ChainDB().View(ctx, func(tx kv.Tx) (err error) {
+		header, err = rawdb.ReadHeaderByHash(tx, hash)
+		return err
+	}); err != nil {
+		return common.Address{}, err
+	}
+	if header == nil {
+		return common.Address{}, fmt.Errorf("node %d: no header %x", n.index, hash)
+	}
+	if len(header.Extra) < crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("node %d: header %x has no seal", n.index, hash)
+	}
+	pubkey, err := crypto.Ecrecover(clique.SealHash(header).Bytes(), header.Extra[len(header.Extra)-crypto.SignatureLength:])
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
@@ -0,0 +1,26 @@
+//go:build integration
+
+package devnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinScenariosRun runs every builtin scenario against its own devnet
+func TestBuiltinScenariosRun(t *testing.T) {
+	for _, name := range BuiltinScenarios() {
+		t.Run(name, func(t *testing.T) {
+			scenario, err := BuiltinScenario(name)
+			require.NoError(t, err)
+			config := scenario.Config(DefaultConfig)
+			config.DataDir = t.TempDir()
+			nw, err := Start(context.Background(), config)
+			require.NoError(t, err)
+			defer nw.Close()
+			require.NoError(t, Run(context.Background(), nw, scenario))
+		})
+	}
+}
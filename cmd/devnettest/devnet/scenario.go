@@ -0,0 +1,450 @@
+package devnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// The actions of the steps of the scenarios
+const (
+	ActionWaitBlocks         = "waitBlocks"         // wait until the head of the node advanced by blocks
+	ActionWaitSealer         = "waitSealer"         // wait until the head of the node is sealed by the signer of the node sealer
+	ActionSendTx             = "sendTx"             // send value and data from the faucet to the address or the contract, as the transaction name
+	ActionDeploy             = "deploy"             // deploy the contract name with the init code data
+	ActionWaitTx             = "waitTx"             // wait until the transaction name is included by the node, and succeeded
+	ActionPartition          = "partition"          // disconnect the groups of the nodes from each other
+	ActionHeal               = "heal"               // reconnect all the nodes
+	ActionRecordHead         = "recordHead"         // record the head of the node as the block name
+	ActionAssertBalance      = "assertBalance"      // check the balance of the address or the contract
+	ActionAssertCode         = "assertCode"         // check the code of the contract, any code if code is empty
+	ActionAssertSynced       = "assertSynced"       // wait until all the nodes have the same head
+	ActionAssertNotCanonical = "assertNotCanonical" // check the recorded block name was reorged out of the chain of the node
+	ActionSleep              = "sleep"              // wait for seconds
+)
+
+// stepTimeout is how long the steps wait for the devnet
+const stepTimeout = 2 * time.Minute
+
+// pollInterval is how often the waiting steps check the devnet
+const pollInterval = 250 * time.Millisecond
+
+// Scenario is a script run against a devnet, and the devnet it requires
+type Scenario struct {
+	Name    string `json:"name"`
+	Nodes   int    `json:"nodes,omitempty"`   // nodes of the devnet, DefaultConfig.Nodes if 0
+	Signers int    `json:"signers,omitempty"` // signers of the devnet, all the nodes if 0
+	Steps   []Step `json:"steps"`
+}
+
+// Step is a step of a scenario. The fields used depend on the action.
+type Step struct {
+	Action   string          `json:"action"`
+	Node     int             `json:"node,omitempty"`     // node the step runs on
+	Name     string          `json:"name,omitempty"`     // transaction, contract or block recorded or referred to
+	To       *common.Address `json:"to,omitempty"`       // address of sendTx and the assertions
+	Contract string          `json:"contract,omitempty"` // deployed contract, instead of the address
+	Value    *hexutil.Big    `json:"value,omitempty"`
+	Data     hexutil.Bytes   `json:"data,omitempty"`
+	Balance  *hexutil.Big    `json:"balance,omitempty"`
+	Code     hexutil.Bytes   `json:"code,omitempty"`
+	Blocks   uint64          `json:"blocks,omitempty"`
+	Sealer   int             `json:"sealer,omitempty"`
+	Groups   [][]int         `json:"groups,omitempty"`
+	Seconds  uint64          `json:"seconds,omitempty"`
+}
+
+// ParseScenario decodes and validates a json scenario
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// ReadScenario reads a json scenario file
+func ReadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScenario(data)
+}
+
+// Config is the devnet configuration of the scenario, based on the given one
+func (s *Scenario) Config(base Config) Config {
+	config := base
+	if s.Nodes > 0 {
+		config.Nodes = s.Nodes
+		config.Signers = s.Nodes
+	}
+	if s.Signers > 0 {
+		config.Signers = s.Signers
+	}
+	return config
+}
+
+// Validate checks the steps, without the devnet
+func (s *Scenario) Validate() error {
+	nodes := s.Config(DefaultConfig).Nodes
+	names := map[string]string{} // recorded name -> action
+	for i, step := range s.Steps {
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("scenario %s: step %d (%s): %s", s.Name, i, step.Action, fmt.Sprintf(format, args...))
+		}
+		if step.Node < 0 || step.Node >= nodes {
+			return fail("no node %d", step.Node)
+		}
+		refers := func(action string) error {
+			if names[step.Name] != action {
+				return fail("no %s %q before", action, step.Name)
+			}
+			return nil
+		}
+		target := func() error {
+			if (step.To == nil) == (step.Contract == "") {
+				return fail("either to or contract is required")
+			}
+			if step.Contract != "" && names[step.Contract] != ActionDeploy {
+				return fail("no contract %q deployed before", step.Contract)
+			}
+			return nil
+		}
+		records := func(action string) error {
+			if step.Name == "" {
+				return fail("no name")
+			}
+			if _, ok := names[step.Name]; ok {
+				return fail("name %q is used by a previous step", step.Name)
+			}
+			names[step.Name] = action
+			return nil
+		}
+		var err error
+		switch step.Action {
+		case ActionWaitBlocks:
+			if step.Blocks == 0 {
+				err = fail("no blocks")
+			}
+		case ActionWaitSealer:
+			if step.Sealer < 0 || step.Sealer >= s.Config(DefaultConfig).Signers {
+				err = fail("node %d isn't a signer", step.Sealer)
+			}
+		case ActionSendTx:
+			if err = target(); err == nil {
+				err = records(ActionSendTx)
+			}
+		case ActionDeploy:
+			if len(step.Data) == 0 {
+				err = fail("no init code")
+			} else {
+				err = records(ActionDeploy)
+			}
+		case ActionWaitTx:
+			if names[step.Name] != ActionSendTx && names[step.Name] != ActionDeploy {
+				err = fail("no transaction %q before", step.Name)
+			}
+		case ActionPartition:
+			for _, group := range step.Groups {
+				for _, node := range group {
+					if node < 0 || node >= nodes {
+						return fail("no node %d", node)
+					}
+				}
+			}
+		case ActionRecordHead:
+			err = records(ActionRecordHead)
+		case ActionAssertBalance:
+			if step.Balance == nil {
+				err = fail("no balance")
+			} else {
+				err = target()
+			}
+		case ActionAssertCode:
+			if step.Contract == "" {
+				err = fail("no contract")
+			} else {
+				err = target()
+			}
+		case ActionAssertNotCanonical:
+			err = refers(ActionRecordHead)
+		case ActionHeal, ActionAssertSynced, ActionSleep:
+		default:
+			err = fail("unknown action")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runner is the state of a running scenario
+type runner struct {
+	nw        *Network
+	scenario  *Scenario
+	nonce     *uint64 // next nonce of the faucet, read from the devnet by the first transaction
+	txs       map[string]common.Hash
+	contracts map[string]common.Address
+	blocks    map[string]*BlockRef
+}
+
+// Run runs the steps of the scenario against the devnet, and stops at the first failure.
+// The devnet must have at least the nodes and the signers of the scenario.
+func Run(ctx context.Context, nw *Network, scenario *Scenario) error {
+	if err := scenario.Validate(); err != nil {
+		return err
+	}
+	if config := scenario.Config(nw.config); config.Nodes > nw.config.Nodes || config.Signers > nw.config.Signers {
+		return fmt.Errorf("scenario %s: requires %d nodes with %d signers, the devnet has %d with %d",
+			scenario.Name, config.Nodes, config.Signers, nw.config.Nodes, nw.config.Signers)
+	}
+	r := &runner{
+		nw:        nw,
+		scenario:  scenario,
+		txs:       map[string]common.Hash{},
+		contracts: map[string]common.Address{},
+		blocks:    map[string]*BlockRef{},
+	}
+	for i, step := range scenario.Steps {
+		log.Info("Scenario step", "scenario", scenario.Name, "step", i, "action", step.Action, "node", step.Node, "name", step.Name)
+		if err := r.step(ctx, step); err != nil {
+			return fmt.Errorf("scenario %s: step %d (%s): %w", scenario.Name, i, step.Action, err)
+		}
+	}
+	return nil
+}
+
+func (r *runner) step(ctx context.Context, step Step) error {
+	n, err := r.nw.Node(step.Node)
+	if err != nil {
+		return err
+	}
+	switch step.Action {
+	case ActionWaitBlocks:
+		start, err := n.Head(ctx)
+		if err != nil {
+			return err
+		}
+		target := uint64(start.Number) + step.Blocks
+		return wait(ctx, func(ctx context.Context) (bool, error) {
+			head, err := n.Head(ctx)
+			if err != nil {
+				return false, err
+			}
+			return uint64(head.Number) >= target, nil
+		})
+	case ActionWaitSealer:
+		sealer, err := r.nw.Node(step.Sealer)
+		if err != nil {
+			return err
+		}
+		return wait(ctx, func(ctx context.Context) (bool, error) {
+			head, err := n.Head(ctx)
+			if err != nil {
+				return false, err
+			}
+			signer, err := n.Sealer(ctx, head.Hash)
+			if err != nil {
+				return false, err
+			}
+			return signer == sealer.Signer(), nil
+		})
+	case ActionSendTx:
+		to := r.address(step)
+		hash, _, err := r.sendTx(ctx, n, &to, step.Value, step.Data)
+		if err != nil {
+			return err
+		}
+		r.txs[step.Name] = hash
+	case ActionDeploy:
+		hash, nonce, err := r.sendTx(ctx, n, nil, step.Value, step.Data)
+		if err != nil {
+			return err
+		}
+		r.txs[step.Name] = hash
+		r.contracts[step.Name] = crypto.CreateAddress(Faucet, nonce)
+	case ActionWaitTx:
+		hash := r.txs[step.Name]
+		var receipt *receipt
+		if err := wait(ctx, func(ctx context.Context) (bool, error) {
+			return receipt != nil, n.rpc.CallContext(ctx, &receipt, "eth_getTransactionReceipt", hash)
+		}); err != nil {
+			return err
+		}
+		if uint64(receipt.Status) != types.ReceiptStatusSuccessful {
+			return fmt.Errorf("transaction %s %x failed in block %d", step.Name, hash, receipt.BlockNumber)
+		}
+	case ActionPartition:
+		return r.nw.Partition(step.Groups...)
+	case ActionHeal:
+		return r.nw.Heal()
+	case ActionRecordHead:
+		head, err := n.Head(ctx)
+		if err != nil {
+			return err
+		}
+		r.blocks[step.Name] = head
+	case ActionAssertBalance:
+		var balance hexutil.Big
+		if err := n.rpc.CallContext(ctx, &balance, "eth_getBalance", r.address(step), "latest"); err != nil {
+			return err
+		}
+		if balance.ToInt().Cmp(step.Balance.ToInt()) != 0 {
+			return fmt.Errorf("balance of %x on node %d: %s, expected %s", r.address(step), n.index, balance.ToInt(), step.Balance.ToInt())
+		}
+	case ActionAssertCode:
+		var code hexutil.Bytes
+		if err := n.rpc.CallContext(ctx, &code, "eth_getCode", r.address(step), "latest"); err != nil {
+			return err
+		}
+		if len(code) == 0 || (len(step.Code) > 0 && !bytes.Equal(code, step.Code)) {
+			return fmt.Errorf("code of %s on node %d: %x", step.Contract, n.index, []byte(code))
+		}
+	case ActionAssertSynced:
+		return wait(ctx, func(ctx context.Context) (bool, error) {
+			var first *BlockRef
+			for _, node := range r.nw.nodes {
+				head, err := node.Head(ctx)
+				if err != nil {
+					return false, err
+				}
+				if first == nil {
+					first = head
+				} else if head.Hash != first.Hash {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+	case ActionAssertNotCanonical:
+		recorded := r.blocks[step.Name]
+		block, err := n.Block(ctx, uint64(recorded.Number))
+		if err != nil {
+			return err
+		}
+		if block != nil && block.Hash == recorded.Hash {
+			return fmt.Errorf("block %s %d %x is canonical on node %d", step.Name, recorded.Number, recorded.Hash, n.index)
+		}
+	case ActionSleep:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(step.Seconds) * time.Second):
+		}
+	}
+	return nil
+}
+
+// address is the address of the step, or the one of its contract
+func (r *runner) address(step Step) common.Address {
+	if step.To != nil {
+		return *step.To
+	}
+	return r.contracts[step.Contract]
+}
+
+// receipt is the part of the receipts checked by the scenarios
+type receipt struct {
+	Status      hexutil.Uint64 `json:"status"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// sendTx signs a transaction of the faucet, a contract creation if to is nil, and sends it to the node
+func (r *runner) sendTx(ctx context.Context, n *Node, to *common.Address, value *hexutil.Big, data []byte) (common.Hash, uint64, error) {
+	if r.nonce == nil {
+		var nonce hexutil.Uint64
+		if err := n.rpc.CallContext(ctx, &nonce, "eth_getTransactionCount", Faucet, "pending"); err != nil {
+			return common.Hash{}, 0, err
+		}
+		r.nonce = (*uint64)(&nonce)
+	}
+	amount := new(uint256.Int)
+	if value != nil {
+		if overflow := amount.SetFromBig(value.ToInt()); overflow {
+			return common.Hash{}, 0, fmt.Errorf("value %s overflows", value.ToInt())
+		}
+	}
+	var gasPrice hexutil.Big
+	if err := n.rpc.CallContext(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return common.Hash{}, 0, err
+	}
+	// twice the price, to stay above the base fee of the next blocks
+	price, _ := uint256.FromBig(new(big.Int).Mul(gasPrice.ToInt(), big.NewInt(2)))
+	call := map[string]interface{}{"from": Faucet, "value": (*hexutil.Big)(amount.ToBig()), "data": hexutil.Bytes(data)}
+	if to != nil {
+		call["to"] = to
+	}
+	var gas hexutil.Uint64
+	// the estimate is 0 while the node has no header for the latest block, in the middle of a reorg
+	if err := wait(ctx, func(ctx context.Context) (bool, error) {
+		return gas > 0, n.rpc.CallContext(ctx, &gas, "eth_estimateGas", call)
+	}); err != nil {
+		return common.Hash{}, 0, fmt.Errorf("estimate gas: %w", err)
+	}
+
+	nonce := *r.nonce
+	var tx types.Transaction
+	if to == nil {
+		tx = types.NewContractCreation(nonce, amount, uint64(gas), price, data)
+	} else {
+		tx = types.NewTransaction(nonce, *to, amount, uint64(gas), price, data)
+	}
+	signed, err := types.SignTx(tx, *types.LatestSignerForChainID(new(big.Int).SetUint64(r.nw.chainID)), FaucetKey)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	var buf bytes.Buffer
+	if err := signed.MarshalBinary(&buf); err != nil {
+		return common.Hash{}, 0, err
+	}
+	var hash common.Hash
+	if err := n.rpc.CallContext(ctx, &hash, "eth_sendRawTransaction", hexutil.Bytes(buf.Bytes())); err != nil {
+		return common.Hash{}, 0, err
+	}
+	*r.nonce++
+	return hash, nonce, nil
+}
+
+// wait polls the condition until it's true. The errors are retried, as the devnet may not be ready yet.
+func wait(ctx context.Context, cond func(ctx context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		ok, err := cond(ctx)
+		if err == nil && ok {
+			return nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w, last error: %v", ctx.Err(), lastErr)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
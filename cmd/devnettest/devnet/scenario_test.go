@@ -0,0 +1,68 @@
+package devnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinScenarios(t *testing.T) {
+	for _, name := range BuiltinScenarios() {
+		scenario, err := BuiltinScenario(name)
+		require.NoError(t, err, name)
+		require.Equal(t, name, scenario.Name)
+	}
+	_, err := BuiltinScenario("nonexistent")
+	require.Error(t, err)
+}
+
+func TestScenarioConfig(t *testing.T) {
+	base := Config{Nodes: 3, Signers: 3}
+	require.Equal(t, base, (&Scenario{}).Config(base))
+	require.Equal(t, Config{Nodes: 5, Signers: 5}, (&Scenario{Nodes: 5}).Config(base))
+	require.Equal(t, Config{Nodes: 5, Signers: 1}, (&Scenario{Nodes: 5, Signers: 1}).Config(base))
+	require.Equal(t, Config{Nodes: 3, Signers: 2}, (&Scenario{Signers: 2}).Config(base))
+}
+
+func TestParseScenario(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		scenario string
+		err      string
+	}{
+		{"valid", `{"name": "t", "steps": [
+			{"action": "deploy", "name": "c", "data": "0x00"},
+			{"action": "waitTx", "name": "c"},
+			{"action": "assertCode", "node": 2, "contract": "c"},
+			{"action": "recordHead", "name": "b"},
+			{"action": "assertNotCanonical", "name": "b"}
+		]}`, ""},
+		{"unknown field", `{"name": "t", "steps": [{"action": "heal", "nodes": 1}]}`, "unknown field"},
+		{"unknown action", `{"name": "t", "steps": [{"action": "mine"}]}`, "unknown action"},
+		{"no node", `{"name": "t", "steps": [{"action": "waitBlocks", "node": 3, "blocks": 1}]}`, "no node 3"},
+		{"more nodes", `{"name": "t", "nodes": 4, "steps": [{"action": "waitBlocks", "node": 3, "blocks": 1}]}`, ""},
+		{"no signer", `{"name": "t", "signers": 1, "steps": [{"action": "waitSealer", "sealer": 1}]}`, "isn't a signer"},
+		{"no blocks", `{"name": "t", "steps": [{"action": "waitBlocks"}]}`, "no blocks"},
+		{"no target", `{"name": "t", "steps": [{"action": "sendTx", "name": "tx"}]}`, "either to or contract"},
+		{"no contract", `{"name": "t", "steps": [{"action": "sendTx", "name": "tx", "contract": "c"}]}`, "no contract \"c\" deployed"},
+		{"no transaction", `{"name": "t", "steps": [{"action": "waitTx", "name": "tx"}]}`, "no transaction \"tx\""},
+		{"name reused", `{"name": "t", "steps": [
+			{"action": "recordHead", "name": "b"},
+			{"action": "recordHead", "name": "b"}
+		]}`, "used by a previous step"},
+		{"not a block", `{"name": "t", "steps": [
+			{"action": "deploy", "name": "c", "data": "0x00"},
+			{"action": "assertNotCanonical", "name": "c"}
+		]}`, "no recordHead \"c\""},
+		{"partition", `{"name": "t", "steps": [{"action": "partition", "groups": [[0], [1, 5]]}]}`, "no node 5"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScenario([]byte(tt.scenario))
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
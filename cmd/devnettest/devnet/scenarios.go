@@ -0,0 +1,72 @@
+package devnet
+
+import (
+	"fmt"
+	"sort"
+)
+
+// builtinScenarios are the scenarios shipped with the devnet, they're also examples of the scenario files
+var builtinScenarios = map[string]string{
+	// a transfer sent to a node is included, and seen by the others
+	"transfer": `{
+	"name": "transfer",
+	"steps": [
+		{"action": "waitBlocks", "node": 0, "blocks": 1},
+		{"action": "sendTx", "node": 0, "name": "transfer", "to": "0x00000000000000000000000000000000000dead1", "value": "0xde0b6b3a7640000"},
+		{"action": "waitTx", "node": 0, "name": "transfer"},
+		{"action": "assertSynced"},
+		{"action": "assertBalance", "node": 1, "to": "0x00000000000000000000000000000000000dead1", "balance": "0xde0b6b3a7640000"}
+	]
+}`,
+	// a contract returning 42 is deployed, then receives a transfer
+	"deploy": `{
+	"name": "deploy",
+	"steps": [
+		{"action": "waitBlocks", "node": 0, "blocks": 1},
+		{"action": "deploy", "node": 0, "name": "answer", "data": "0x600a600c600039600a6000f3602a60005260206000f3"},
+		{"action": "waitTx", "node": 0, "name": "answer"},
+		{"action": "sendTx", "node": 1, "name": "fund", "contract": "answer", "value": "0x3e8"},
+		{"action": "waitTx", "node": 1, "name": "fund"},
+		{"action": "assertSynced"},
+		{"action": "assertCode", "node": 1, "contract": "answer", "code": "0x602a60005260206000f3"},
+		{"action": "assertBalance", "node": 0, "contract": "answer", "balance": "0x3e8"}
+	]
+}`,
+	// the single signer cut from the others seals a block, which is reorged out when the partition heals.
+	// The partition starts after a block of node 0, so that node 2 is allowed to seal the next one.
+	"reorg": `{
+	"name": "reorg",
+	"nodes": 3,
+	"signers": 3,
+	"steps": [
+		{"action": "waitBlocks", "node": 0, "blocks": 1},
+		{"action": "waitSealer", "node": 0, "sealer": 0},
+		{"action": "partition", "groups": [[0, 1], [2]]},
+		{"action": "waitBlocks", "node": 2, "blocks": 1},
+		{"action": "recordHead", "node": 2, "name": "fork"},
+		{"action": "waitBlocks", "node": 0, "blocks": 3},
+		{"action": "heal"},
+		{"action": "assertSynced"},
+		{"action": "assertNotCanonical", "node": 2, "name": "fork"}
+	]
+}`,
+}
+
+// BuiltinScenario is the builtin scenario by its name
+func BuiltinScenario(name string) (*Scenario, error) {
+	data, ok := builtinScenarios[name]
+	if !ok {
+		return nil, fmt.Errorf("no builtin scenario %q, the builtin scenarios are %v", name, BuiltinScenarios())
+	}
+	return ParseScenario([]byte(data))
+}
+
+// BuiltinScenarios are the names of the builtin scenarios
+func BuiltinScenarios() []string {
+	names := make([]string, 0, len(builtinScenarios))
+	for name := range builtinScenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -3,20 +3,25 @@ package downloader
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
 	"github.com/anacrolix/torrent/storage"
+	"github.com/c2h5oh/datasize"
 	common2 "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/cmd/downloader/downloader/downloadercfg"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
 	"github.com/ledgerwatch/log/v3"
 	mdbx2 "github.com/torquem-ch/mdbx-go/mdbx"
 	"go.uber.org/atomic"
@@ -30,10 +35,13 @@ type Downloader struct {
 	torrentClient     *torrent.Client
 	clientLock        *sync.RWMutex
 
-	cfg *downloadercfg.Cfg
+	cfg       *downloadercfg.Cfg
+	ratesLock *sync.Mutex
 
 	statsLock *sync.RWMutex
 	stats     AggStats
+	// corrupted - files found corrupted by background verification, until they are repaired (re-downloaded)
+	corrupted map[string]struct{}
 
 	folder storage.ClientImplCloser
 }
@@ -50,6 +58,8 @@ type AggStats struct {
 
 	BytesDownload, BytesUpload uint64
 	UploadRate, DownloadRate   uint64
+
+	Corrupted []string
 }
 
 func New(cfg *downloadercfg.Cfg) (*Downloader, error) {
@@ -94,9 +104,12 @@ func New(cfg *downloadercfg.Cfg) (*Downloader, error) {
 		folder:            m,
 		torrentClient:     torrentClient,
 		clientLock:        &sync.RWMutex{},
+		ratesLock:         &sync.Mutex{},
 
 		statsLock: &sync.RWMutex{},
+		corrupted: map[string]struct{}{},
 	}
+	d.applyRates(time.Now())
 	if err := d.addSegments(); err != nil {
 		return nil, err
 	}
@@ -152,6 +165,20 @@ func (d *Downloader) ReCalcStats(interval time.Duration) {
 	}
 	stats.PeersUnique = int32(len(peers))
 	stats.FilesTotal = int32(len(torrents))
+	updatePieceMetrics(torrents)
+
+	stats.Corrupted = stats.Corrupted[:0:0]
+	for _, t := range torrents {
+		if _, ok := d.corrupted[t.Name()]; !ok {
+			continue
+		}
+		if t.Complete.Bool() {
+			log.Info("[snapshots] Repaired", "file", t.Name())
+			delete(d.corrupted, t.Name())
+			continue
+		}
+		stats.Corrupted = append(stats.Corrupted, t.Name())
+	}
 
 	if !prevStats.Completed && stats.Completed {
 		d.onComplete()
@@ -261,6 +288,74 @@ func (d *Downloader) verify() error {
 	return nil
 }
 
+// verifyAndRepair - re-hash pieces of downloaded files (one file at a time, to limit IO). A copy of a corrupted file
+// is kept in <snapDir>/quarantine - for investigation of the corruption, the file itself stays in place: it's mapped by
+// the torrent storage and its good pieces are kept. Pieces with wrong hash are marked as not downloaded: then MainLoop
+// re-downloads them - from peers or webseeds.
+func (d *Downloader) verifyAndRepair(ctx context.Context) {
+	snapDir := d.SnapDir()
+	for _, t := range d.Torrent().Torrents() {
+		select {
+		case <-t.GotInfo():
+		default:
+			continue
+		}
+		if !t.Complete.Bool() {
+			continue
+		}
+		var corrupted []int
+		if err := verifyTorrent(t.Info(), snapDir, func(i int, good bool) error {
+			if !good {
+				corrupted = append(corrupted, i)
+			}
+			return ctx.Err()
+		}); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("[snapshots] Verify", "file", t.Name(), "err", err)
+			continue
+		}
+		if len(corrupted) == 0 {
+			continue
+		}
+		quarantined, err := quarantine(snapDir, t.Name())
+		if err != nil {
+			log.Warn("[snapshots] Can't quarantine corrupted file", "file", t.Name(), "err", err)
+		}
+		log.Warn("[snapshots] Corrupted file, re-downloading it", "file", t.Name(), "pieces", len(corrupted), "quarantined", quarantined)
+		for _, i := range corrupted {
+			t.Piece(i).VerifyData()
+		}
+		d.statsLock.Lock()
+		d.corrupted[t.Name()] = struct{}{}
+		d.statsLock.Unlock()
+	}
+}
+
+// quarantine - copy the file to <snapDir>/quarantine, returns the path of the copy
+func quarantine(snapDir, name string) (string, error) {
+	dir := filepath.Join(snapDir, "quarantine")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	src, err := os.Open(filepath.Join(snapDir, name))
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	path := filepath.Join(dir, name)
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", err
+	}
+	return path, dst.Close()
+}
+
 func (d *Downloader) addSegments() error {
 	if err := BuildTorrentFilesIfNeed(context.Background(), d.cfg.DataDir); err != nil {
 		return err
@@ -278,6 +373,29 @@ func (d *Downloader) addSegments() error {
 	return nil
 }
 
+// SetRates - change rate limits applied outside of schedule windows, without restart
+func (d *Downloader) SetRates(downloadRate, uploadRate datasize.ByteSize) {
+	d.ratesLock.Lock()
+	d.cfg.DownloadRate, d.cfg.UploadRate = downloadRate, uploadRate
+	d.ratesLock.Unlock()
+	d.applyRates(time.Now())
+}
+
+// Rates - rate limits applied now, and if they are from schedule window
+func (d *Downloader) Rates(now time.Time) (downloadRate, uploadRate datasize.ByteSize, scheduled bool) {
+	d.ratesLock.Lock()
+	defer d.ratesLock.Unlock()
+	if w, ok := downloadercfg.ActiveWindow(d.cfg.RateSchedule, now); ok {
+		return w.DownloadRate, w.UploadRate, true
+	}
+	return d.cfg.DownloadRate, d.cfg.UploadRate, false
+}
+
+func (d *Downloader) applyRates(now time.Time) {
+	downloadRate, uploadRate, _ := d.Rates(now)
+	downloadercfg.SetRates(d.cfg.ClientConfig, downloadRate, uploadRate)
+}
+
 func (d *Downloader) Stats() AggStats {
 	d.statsLock.RLock()
 	defer d.statsLock.RUnlock()
@@ -358,12 +476,17 @@ func MainLoop(ctx context.Context, d *Downloader, silent bool) {
 			torrents := d.Torrent().Torrents()
 			for _, t := range torrents {
 				<-t.GotInfo()
+			}
+			// headers first - they are needed by bodies and transactions
+			sort.SliceStable(torrents, func(i, j int) bool { return torrentPriority(torrents[i]) < torrentPriority(torrents[j]) })
+			for _, t := range torrents {
 				if t.Complete.Bool() {
 					continue
 				}
 				if err := sem.Acquire(ctx, 1); err != nil {
 					return
 				}
+				t.AddWebSeeds(d.cfg.WebSeeds)
 				t.AllowDataDownload()
 				t.DownloadAll()
 				go func(t *torrent.Torrent) {
@@ -379,6 +502,21 @@ func MainLoop(ctx context.Context, d *Downloader, silent bool) {
 		}
 	}()
 
+	if d.cfg.VerifyInterval > 0 {
+		go func() {
+			verifyEvery := time.NewTicker(d.cfg.VerifyInterval)
+			defer verifyEvery.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-verifyEvery.C:
+					d.verifyAndRepair(ctx)
+				}
+			}
+		}()
+	}
+
 	logEvery := time.NewTicker(20 * time.Second)
 	defer logEvery.Stop()
 
@@ -391,6 +529,7 @@ func MainLoop(ctx context.Context, d *Downloader, silent bool) {
 			return
 		case <-statEvery.C:
 			d.ReCalcStats(statInterval)
+			d.applyRates(time.Now())
 
 		case <-logEvery.C:
 			if silent {
@@ -404,6 +543,9 @@ func MainLoop(ctx context.Context, d *Downloader, silent bool) {
 				continue
 			}
 
+			if len(stats.Corrupted) > 0 {
+				log.Warn("[Snapshots] Repairing corrupted files", "files", stats.Corrupted)
+			}
 			if stats.Completed {
 				log.Info("[Snapshots] Seeding",
 					"up", common2.ByteCount(stats.UploadRate)+"/s",
@@ -430,6 +572,15 @@ func MainLoop(ctx context.Context, d *Downloader, silent bool) {
 	}
 }
 
+// torrentPriority - order of downloading: by snapshot type (headers, bodies, transactions), then by block number
+func torrentPriority(t *torrent.Torrent) uint64 {
+	f, err := snap.ParseFileName("", t.Name())
+	if err != nil {
+		return math.MaxUint64
+	}
+	return uint64(f.T)<<48 | f.From
+}
+
 func HasSegFile(dir string) bool {
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -51,7 +51,12 @@ func (s *GrpcServer) Download(ctx context.Context, request *proto_downloader.Dow
 		}
 
 		magnet := mi.Magnet(&hash, nil)
-		go func(magnetUrl string) {
+		go func(magnetUrl string, hash metainfo.Hash, name string) {
+			if len(s.d.cfg.WebSeeds) > 0 {
+				if s.addFromWebSeeds(hash, name) {
+					return
+				}
+			}
 			t, err := torrentClient.AddMagnet(magnetUrl)
 			if err != nil {
 				log.Warn("[downloader] add magnet link", "err", err)
@@ -66,12 +71,35 @@ func (s *GrpcServer) Download(ctx context.Context, request *proto_downloader.Dow
 				log.Warn("[downloader] create torrent file", "err", err)
 				return
 			}
-		}(magnet.String())
+		}(magnet.String(), hash, it.Path)
 	}
 	s.d.ReCalcStats(10 * time.Second) // immediately call ReCalc to set stat.Complete flag
 	return &emptypb.Empty{}, nil
 }
 
+// addFromWebSeeds - add snapshot by .torrent file from webseeds, returns false if BitTorrent peers must be asked for metadata
+func (s *GrpcServer) addFromWebSeeds(hash metainfo.Hash, name string) bool {
+	mi, err := torrentFromWebSeeds(context.Background(), s.d.cfg.WebSeeds, name, hash)
+	if err != nil {
+		log.Warn("[downloader] fallback to BitTorrent", "err", err)
+		return false
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		log.Warn("[downloader] webseed .torrent file", "name", name, "err", err)
+		return false
+	}
+	if err := CreateTorrentFileIfNotExists(s.d.SnapDir(), &info, mi); err != nil {
+		log.Warn("[downloader] create torrent file", "err", err)
+		return false
+	}
+	if _, err := AddSegment(name, s.d.SnapDir(), s.d.Torrent()); err != nil {
+		log.Warn("[downloader] add segment", "err", err)
+		return false
+	}
+	return true
+}
+
 func (s *GrpcServer) Verify(ctx context.Context, request *proto_downloader.VerifyRequest) (*emptypb.Empty, error) {
 	err := s.d.verify()
 	if err != nil {
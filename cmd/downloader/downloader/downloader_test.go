@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lg "github.com/anacrolix/log"
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloader/downloadercfg"
+	"github.com/stretchr/testify/require"
+)
+
+func waitComplete(t *testing.T, complete <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-complete:
+	case <-time.After(time.Minute):
+		require.FailNow(t, "file is not complete")
+	}
+}
+
+func TestVerifyAndRepair(t *testing.T) {
+	name := "v1-000000-000500-headers.seg"
+	data := make([]byte, 2*downloadercfg.DefaultPieceSize+1000) // 3 pieces
+	rand.New(rand.NewSource(42)).Read(data)
+
+	// the webseed has the good file
+	seedDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(seedDir, name), data, 0644))
+	srv := httptest.NewServer(http.FileServer(http.Dir(seedDir)))
+	defer srv.Close()
+
+	snapDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(snapDir, name), data, 0644))
+	cfg, err := downloadercfg.New(snapDir, lg.Warning, false, nil, datasize.GB, datasize.MB, 0, 10, 1, nil, []string{srv.URL})
+	require.NoError(t, err)
+	cfg.DisableTrackers = true
+	d, err := New(cfg)
+	require.NoError(t, err)
+	defer d.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	torrents := d.Torrent().Torrents()
+	require.Len(t, torrents, 1)
+	tor := torrents[0]
+	<-tor.GotInfo()
+	waitComplete(t, tor.Complete.On())
+
+	// the good files are left as they are
+	d.verifyAndRepair(ctx)
+	d.ReCalcStats(time.Second)
+	require.Empty(t, d.Stats().Corrupted)
+	require.NoDirExists(t, filepath.Join(snapDir, "quarantine"))
+
+	// corrupt the second piece
+	f, err := os.OpenFile(filepath.Join(snapDir, name), os.O_RDWR, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("corrupted"), downloadercfg.DefaultPieceSize+10)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	corrupted, err := os.ReadFile(filepath.Join(snapDir, name))
+	require.NoError(t, err)
+
+	d.verifyAndRepair(ctx)
+	require.False(t, tor.Complete.Bool())
+	require.True(t, tor.PieceState(0).Complete)
+	require.False(t, tor.PieceState(1).Complete)
+	require.True(t, tor.PieceState(2).Complete)
+	d.ReCalcStats(time.Second)
+	require.Equal(t, []string{name}, d.Stats().Corrupted)
+	quarantined, err := os.ReadFile(filepath.Join(snapDir, "quarantine", name))
+	require.NoError(t, err)
+	require.Equal(t, corrupted, quarantined)
+
+	// the corrupted piece is re-downloaded from the webseed
+	go MainLoop(ctx, d, true)
+	waitComplete(t, tor.Complete.On())
+	repaired, err := os.ReadFile(filepath.Join(snapDir, name))
+	require.NoError(t, err)
+	require.Equal(t, data, repaired)
+	d.ReCalcStats(time.Second)
+	require.Empty(t, d.Stats().Corrupted)
+}
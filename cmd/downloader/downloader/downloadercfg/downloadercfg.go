@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	lg "github.com/anacrolix/log"
 	"github.com/anacrolix/torrent"
@@ -26,6 +27,14 @@ const DefaultNetworkChunkSize = DefaultPieceSize
 type Cfg struct {
 	*torrent.ClientConfig
 	DownloadSlots int
+	// DownloadRate, UploadRate - limits applied outside of RateSchedule windows
+	DownloadRate, UploadRate datasize.ByteSize
+	RateSchedule             []RateWindow
+	// VerifyInterval - how often to re-hash downloaded files, 0 - never
+	VerifyInterval time.Duration
+	// WebSeeds - base URLs of HTTP(S) servers having the snapshots and their .torrent files
+	// (BEP 19: <url>/<file name>), alternative source for networks without BitTorrent access
+	WebSeeds []string
 }
 
 func Default() *torrent.ClientConfig {
@@ -54,7 +63,7 @@ func Default() *torrent.ClientConfig {
 	return torrentConfig
 }
 
-func New(snapDir string, verbosity lg.Level, dbg bool, natif nat.Interface, downloadRate, uploadRate datasize.ByteSize, port, connsPerFile, downloadSlots int) (*Cfg, error) {
+func New(snapDir string, verbosity lg.Level, dbg bool, natif nat.Interface, downloadRate, uploadRate datasize.ByteSize, port, connsPerFile, downloadSlots int, rateSchedule []RateWindow, webseeds []string) (*Cfg, error) {
 	torrentConfig := Default()
 	// We would-like to reduce amount of goroutines in Erigon, so reducing next params
 	torrentConfig.EstablishedConnsPerTorrent = connsPerFile // default: 50
@@ -103,15 +112,10 @@ func New(snapDir string, verbosity lg.Level, dbg bool, natif nat.Interface, down
 			log.Info("[torrent] Public IP", "ip", ip)
 		}
 	}
-	// rates are divided by 2 - I don't know why it works, maybe bug inside torrent lib accounting
-	torrentConfig.UploadRateLimiter = rate.NewLimiter(rate.Limit(uploadRate.Bytes()), 2*DefaultPieceSize) // default: unlimited
-	if downloadRate.Bytes() < 500_000_000 {
-		b := int(2 * DefaultPieceSize)
-		if downloadRate.Bytes() > DefaultPieceSize {
-			b = int(2 * downloadRate.Bytes())
-		}
-		torrentConfig.DownloadRateLimiter = rate.NewLimiter(rate.Limit(downloadRate.Bytes()), b) // default: unlimited
-	}
+	// own limiters (default ones are shared and unlimited) - to be able to change rates at runtime
+	torrentConfig.UploadRateLimiter = rate.NewLimiter(rate.Inf, 0)
+	torrentConfig.DownloadRateLimiter = rate.NewLimiter(rate.Inf, 0)
+	SetRates(torrentConfig, downloadRate, uploadRate)
 
 	// debug
 	if dbg {
@@ -120,5 +124,27 @@ func New(snapDir string, verbosity lg.Level, dbg bool, natif nat.Interface, down
 	torrentConfig.Logger = lg.Default.FilterLevel(verbosity)
 	torrentConfig.Logger.Handlers = []lg.Handler{adapterHandler{}}
 
-	return &Cfg{ClientConfig: torrentConfig, DownloadSlots: downloadSlots}, nil
+	for i, u := range webseeds {
+		if !strings.HasSuffix(u, "/") { // webseed URLs ending with "/" get the file name appended
+			webseeds[i] = u + "/"
+		}
+	}
+	return &Cfg{ClientConfig: torrentConfig, DownloadSlots: downloadSlots, DownloadRate: downloadRate, UploadRate: uploadRate, RateSchedule: rateSchedule, WebSeeds: webseeds}, nil
+}
+
+// SetRates - change limits of torrent client, can be called while client is running
+func SetRates(torrentConfig *torrent.ClientConfig, downloadRate, uploadRate datasize.ByteSize) {
+	// rates are divided by 2 - I don't know why it works, maybe bug inside torrent lib accounting
+	torrentConfig.UploadRateLimiter.SetLimit(rate.Limit(uploadRate.Bytes()))
+	torrentConfig.UploadRateLimiter.SetBurst(2 * DefaultPieceSize)
+	if downloadRate.Bytes() >= 500_000_000 {
+		torrentConfig.DownloadRateLimiter.SetLimit(rate.Inf)
+		return
+	}
+	b := int(2 * DefaultPieceSize)
+	if downloadRate.Bytes() > DefaultPieceSize {
+		b = int(2 * downloadRate.Bytes())
+	}
+	torrentConfig.DownloadRateLimiter.SetLimit(rate.Limit(downloadRate.Bytes()))
+	torrentConfig.DownloadRateLimiter.SetBurst(b)
 }
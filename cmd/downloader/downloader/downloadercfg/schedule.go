@@ -0,0 +1,82 @@
+package downloadercfg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// RateWindow - rate limits applied every day between From and To (local time, from midnight).
+// To can be less than From - then window goes over midnight.
+type RateWindow struct {
+	From, To                 time.Duration
+	DownloadRate, UploadRate datasize.ByteSize
+}
+
+func (w RateWindow) Contains(t time.Time) bool {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.From <= w.To {
+		return w.From <= sinceMidnight && sinceMidnight < w.To
+	}
+	return w.From <= sinceMidnight || sinceMidnight < w.To
+}
+
+// ActiveWindow - first window of schedule containing given time
+func ActiveWindow(schedule []RateWindow, t time.Time) (RateWindow, bool) {
+	for _, w := range schedule {
+		if w.Contains(t) {
+			return w, true
+		}
+	}
+	return RateWindow{}, false
+}
+
+// ParseRateSchedule - parse comma separated windows in format: HH:MM-HH:MM=<download rate>/<upload rate>
+// for example: 09:00-18:00=8mb/1mb,22:00-06:00=512mb/64mb
+func ParseRateSchedule(in string) ([]RateWindow, error) {
+	var schedule []RateWindow
+	for _, s := range strings.Split(in, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		period, rates, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate window %q: expected HH:MM-HH:MM=<download rate>/<upload rate>", s)
+		}
+		from, to, ok := strings.Cut(period, "-")
+		if !ok {
+			return nil, fmt.Errorf("rate window %q: expected HH:MM-HH:MM period", s)
+		}
+		downloadRate, uploadRate, ok := strings.Cut(rates, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate window %q: expected <download rate>/<upload rate>", s)
+		}
+		var w RateWindow
+		var err error
+		if w.From, err = parseTimeOfDay(from); err != nil {
+			return nil, fmt.Errorf("rate window %q: %w", s, err)
+		}
+		if w.To, err = parseTimeOfDay(to); err != nil {
+			return nil, fmt.Errorf("rate window %q: %w", s, err)
+		}
+		if err := w.DownloadRate.UnmarshalText([]byte(downloadRate)); err != nil {
+			return nil, fmt.Errorf("rate window %q: %w", s, err)
+		}
+		if err := w.UploadRate.UnmarshalText([]byte(uploadRate)); err != nil {
+			return nil, fmt.Errorf("rate window %q: %w", s, err)
+		}
+		schedule = append(schedule, w)
+	}
+	return schedule, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,38 @@
+package downloadercfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateSchedule(t *testing.T) {
+	schedule, err := ParseRateSchedule("09:00-18:00=8mb/1mb, 22:00-06:00=512mb/64mb")
+	require.NoError(t, err)
+	require.Equal(t, []RateWindow{
+		{From: 9 * time.Hour, To: 18 * time.Hour, DownloadRate: 8 * datasize.MB, UploadRate: datasize.MB},
+		{From: 22 * time.Hour, To: 6 * time.Hour, DownloadRate: 512 * datasize.MB, UploadRate: 64 * datasize.MB},
+	}, schedule)
+
+	at := func(hour, min int) time.Time { return time.Date(2022, 7, 1, hour, min, 0, 0, time.Local) }
+	w, ok := ActiveWindow(schedule, at(12, 0))
+	require.True(t, ok)
+	require.Equal(t, 8*datasize.MB, w.DownloadRate)
+	w, ok = ActiveWindow(schedule, at(23, 30))
+	require.True(t, ok)
+	require.Equal(t, 512*datasize.MB, w.DownloadRate)
+	_, ok = ActiveWindow(schedule, at(5, 59))
+	require.True(t, ok)
+	_, ok = ActiveWindow(schedule, at(18, 0))
+	require.False(t, ok)
+
+	empty, err := ParseRateSchedule("")
+	require.NoError(t, err)
+	require.Empty(t, empty)
+	for _, bad := range []string{"09:00-18:00", "09:00=8mb/1mb", "09:00-18:00=8mb", "9-18=8mb/1mb", "09:00-18:00=fast/1mb"} {
+		_, err = ParseRateSchedule(bad)
+		require.Error(t, err, bad)
+	}
+}
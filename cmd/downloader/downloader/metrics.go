@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/anacrolix/torrent"
+)
+
+var (
+	piecesComplete = metrics.GetOrCreateCounter(`downloader_pieces{state="complete"}`)
+	piecesMissing  = metrics.GetOrCreateCounter(`downloader_pieces{state="missing"}`)
+
+	// the missing pieces by the number of the connected peers having them, the pieces missing from all the peers are
+	// only downloaded from the web seeds, or after new peers connect
+	availabilityBuckets = []struct {
+		label string
+		max   int
+	}{{"0", 0}, {"1", 1}, {"2-4", 4}, {"5-16", 16}, {"17+", 1 << 30}}
+	missingPiecesByPeers []*metrics.Counter
+)
+
+func init() {
+	for _, b := range availabilityBuckets {
+		missingPiecesByPeers = append(missingPiecesByPeers, metrics.GetOrCreateCounter(fmt.Sprintf(`downloader_missing_pieces{peers="%s"}`, b.label)))
+	}
+}
+
+// updatePieceMetrics sets the metrics of the pieces of the torrents having their info
+func updatePieceMetrics(torrents []*torrent.Torrent) {
+	var complete, missing uint64
+	counts := make([]uint64, len(availabilityBuckets))
+	for _, t := range torrents {
+		select {
+		case <-t.GotInfo():
+		default:
+			continue
+		}
+		if t.Complete.Bool() {
+			complete += uint64(t.NumPieces())
+			continue
+		}
+		var peerPieces []*roaring.Bitmap
+		for _, conn := range t.PeerConns() {
+			peerPieces = append(peerPieces, conn.PeerPieces())
+		}
+		piece := 0
+		for _, run := range t.PieceStateRuns() {
+			if run.Complete {
+				complete += uint64(run.Length)
+				piece += run.Length
+				continue
+			}
+			for end := piece + run.Length; piece < end; piece++ {
+				missing++
+				peers := 0
+				for _, pieces := range peerPieces {
+					if pieces.Contains(uint32(piece)) {
+						peers++
+					}
+				}
+				for i, b := range availabilityBuckets {
+					if peers <= b.max {
+						counts[i]++
+						break
+					}
+				}
+			}
+		}
+	}
+	piecesComplete.Set(complete)
+	piecesMissing.Set(missing)
+	for i, count := range counts {
+		missingPiecesByPeers[i].Set(count)
+	}
+}
@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"time"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// RateAPI - JSON-RPC "downloader" namespace, to adjust rate limits of running Downloader without restart
+type RateAPI struct {
+	d *Downloader
+}
+
+func NewRateAPI(d *Downloader) *RateAPI { return &RateAPI{d: d} }
+
+type Rates struct {
+	DownloadRate string `json:"downloadRate"`
+	UploadRate   string `json:"uploadRate"`
+	// Scheduled - rates are from time-of-day schedule window, then SetRates takes effect only after the window
+	Scheduled bool `json:"scheduled"`
+}
+
+// Rates - implements downloader_rates, rate limits applied now
+func (api *RateAPI) Rates() Rates {
+	downloadRate, uploadRate, scheduled := api.d.Rates(time.Now())
+	return Rates{DownloadRate: downloadRate.HR(), UploadRate: uploadRate.HR(), Scheduled: scheduled}
+}
+
+// SetRates - implements downloader_setRates, change rate limits applied outside of schedule windows (example: "32mb")
+func (api *RateAPI) SetRates(downloadRate, uploadRate string) (Rates, error) {
+	var download, upload datasize.ByteSize
+	if err := download.UnmarshalText([]byte(downloadRate)); err != nil {
+		return Rates{}, err
+	}
+	if err := upload.UnmarshalText([]byte(uploadRate)); err != nil {
+		return Rates{}, err
+	}
+	api.d.SetRates(download, upload)
+	return api.Rates(), nil
+}
@@ -0,0 +1,53 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// maxTorrentFileSize - .torrent files of snapshots are few Kb, bigger responses are not .torrent files
+const maxTorrentFileSize = 16 * 1024 * 1024
+
+var webSeedClient = &http.Client{Timeout: time.Minute}
+
+// torrentFromWebSeeds - download .torrent file of given snapshot from webseeds - instead of asking BitTorrent peers
+// for its metadata. Info hash of .torrent file must be the expected one, then all pieces downloaded
+// from webseeds are verified by hashes of this .torrent file (as pieces downloaded from peers).
+func torrentFromWebSeeds(ctx context.Context, webseeds []string, name string, hash metainfo.Hash) (*metainfo.MetaInfo, error) {
+	for _, webseed := range webseeds {
+		mi, err := fetchTorrentFile(ctx, webseed+url.PathEscape(name)+".torrent")
+		if err != nil {
+			log.Debug("[downloader] webseed", "name", name, "err", err)
+			continue
+		}
+		if mi.HashInfoBytes() != hash {
+			log.Warn("[downloader] webseed has unexpected .torrent file", "webseed", webseed, "name", name, "hash", mi.HashInfoBytes(), "expected", hash)
+			continue
+		}
+		return mi, nil
+	}
+	return nil, fmt.Errorf("%s.torrent not found on webseeds", name)
+}
+
+func fetchTorrentFile(ctx context.Context, fileUrl string) (*metainfo.MetaInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := webSeedClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", fileUrl, resp.Status)
+	}
+	return metainfo.Load(io.LimitReader(resp.Body, maxTorrentFileSize))
+}
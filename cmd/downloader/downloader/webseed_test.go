@@ -0,0 +1,38 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTorrentFromWebSeeds(t *testing.T) {
+	name := "v1-000000-000500-headers.seg"
+	infoBytes, err := bencode.Marshal(&metainfo.Info{Name: name, PieceLength: 1024, Pieces: make([]byte, 20), Length: 1024})
+	require.NoError(t, err)
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/snapshots/"+name+".torrent" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = mi.Write(w)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	// the first webseed doesn't have the file
+	webseeds := []string{srv.URL + "/", srv.URL + "/snapshots/"}
+	got, err := torrentFromWebSeeds(ctx, webseeds, name, mi.HashInfoBytes())
+	require.NoError(t, err)
+	require.Equal(t, mi.HashInfoBytes(), got.HashInfoBytes())
+
+	_, err = torrentFromWebSeeds(ctx, webseeds, name, metainfo.Hash{1})
+	require.Error(t, err)
+}
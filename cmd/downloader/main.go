@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -21,6 +23,7 @@ import (
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/p2p/nat"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/cobra"
@@ -44,6 +47,10 @@ var (
 	torrentPort                    int
 	torrentMaxPeers                int
 	torrentConnsPerFile            int
+	torrentWebSeeds                string
+	rateScheduleStr                string
+	downloaderRpcAddr              string
+	verifyInterval                 time.Duration
 	targetFile                     string
 )
 
@@ -62,6 +69,10 @@ func init() {
 	rootCmd.Flags().IntVar(&torrentMaxPeers, "torrent.maxpeers", utils.TorrentMaxPeersFlag.Value, utils.TorrentMaxPeersFlag.Usage)
 	rootCmd.Flags().IntVar(&torrentConnsPerFile, "torrent.conns.perfile", utils.TorrentConnsPerFileFlag.Value, utils.TorrentConnsPerFileFlag.Usage)
 	rootCmd.Flags().IntVar(&torrentDownloadSlots, "torrent.download.slots", utils.TorrentDownloadSlotsFlag.Value, utils.TorrentDownloadSlotsFlag.Usage)
+	rootCmd.Flags().StringVar(&rateScheduleStr, "torrent.rate.schedule", utils.TorrentRateScheduleFlag.Value, utils.TorrentRateScheduleFlag.Usage)
+	rootCmd.Flags().StringVar(&downloaderRpcAddr, "downloader.rpc.addr", "", "JSON-RPC over HTTP network address to change rate limits at runtime (downloader_rates, downloader_setRates), for example: 127.0.0.1:9094. Disabled by default")
+	rootCmd.Flags().DurationVar(&verifyInterval, "downloader.verify.interval", 0, utils.DownloaderVerifyIntervalFlag.Usage)
+	rootCmd.Flags().StringVar(&torrentWebSeeds, "torrent.webseeds", utils.TorrentWebSeedsFlag.Value, utils.TorrentWebSeedsFlag.Usage)
 
 	withDataDir(printTorrentHashes)
 	printTorrentHashes.PersistentFlags().BoolVar(&forceRebuild, "rebuild", false, "Force re-create .torrent files")
@@ -126,6 +137,10 @@ func Downloader(ctx context.Context) error {
 	if err := uploadRate.UnmarshalText([]byte(uploadRateStr)); err != nil {
 		return err
 	}
+	rateSchedule, err := downloadercfg.ParseRateSchedule(rateScheduleStr)
+	if err != nil {
+		return err
+	}
 
 	log.Info("Run snapshot downloader", "addr", downloaderApiAddr, "datadir", dirs.DataDir, "download.rate", downloadRate.String(), "upload.rate", uploadRate.String())
 	natif, err := nat.Parse(natSetting)
@@ -133,11 +148,13 @@ func Downloader(ctx context.Context) error {
 		return fmt.Errorf("invalid nat option %s: %w", natSetting, err)
 	}
 
-	cfg, err := downloadercfg.New(dirs.Snap, torrentLogLevel, dbg, natif, downloadRate, uploadRate, torrentPort, torrentConnsPerFile, torrentDownloadSlots)
+	cfg, err := downloadercfg.New(dirs.Snap, torrentLogLevel, dbg, natif, downloadRate, uploadRate, torrentPort, torrentConnsPerFile, torrentDownloadSlots, rateSchedule, utils.SplitAndTrim(torrentWebSeeds))
 	if err != nil {
 		return err
 	}
 
+	cfg.VerifyInterval = verifyInterval
+
 	d, err := downloader.New(cfg)
 	if err != nil {
 		return err
@@ -157,10 +174,38 @@ func Downloader(ctx context.Context) error {
 	}
 	defer grpcServer.GracefulStop()
 
+	if downloaderRpcAddr != "" {
+		rpcServer, err := StartRateRpc(d, downloaderRpcAddr)
+		if err != nil {
+			return err
+		}
+		defer rpcServer.Close()
+	}
+
 	<-ctx.Done()
 	return nil
 }
 
+// StartRateRpc - serve "downloader" JSON-RPC namespace over HTTP
+func StartRateRpc(d *downloader.Downloader, addr string) (*http.Server, error) {
+	srv := rpc.NewServer(50, false /* traceRequests */, true)
+	if err := srv.RegisterName("downloader", downloader.NewRateAPI(d)); err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not create listener: %w, addr=%s", err, addr)
+	}
+	httpServer := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Downloader JSON-RPC server fail", "err", err)
+		}
+	}()
+	log.Info("Started Downloader JSON-RPC server", "addr", addr)
+	return httpServer, nil
+}
+
 var printTorrentHashes = &cobra.Command{
 	Use:     "torrent_hashes",
 	Example: "go run ./cmd/downloader torrent_hashes --datadir <your_datadir>",
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/ledgerwatch/erigon/tests"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+)
+
+var RunFlag = cli.StringFlag{
+	Name:  "run",
+	Value: ".*",
+	Usage: "Run only those tests matching the regular expression.",
+}
+
+var blockTestCommand = cli.Command{
+	Action:    blockTestCmd,
+	Name:      "blocktest",
+	Usage:     "executes the given blockchain tests",
+	ArgsUsage: "<file>",
+	Flags:     []cli.Flag{RunFlag},
+}
+
+func blockTestCmd(ctx *cli.Context) error {
+	if len(ctx.Args().First()) == 0 {
+		return errors.New("path-to-test argument required")
+	}
+	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)), log.StderrHandler))
+
+	re, err := regexp.Compile(ctx.String(RunFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid regex -%s: %v", RunFlag.Name, err)
+	}
+	// Load the test content from the input file
+	src, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var tests map[string]tests.BlockTest
+	if err = json.Unmarshal(src, &tests); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Import the blocks of each test into a chain of its own, and validate it
+	results := make([]StatetestResult, 0, len(names))
+	for _, name := range names {
+		test := tests[name]
+		result := StatetestResult{Name: name, Fork: test.Network(), Pass: true}
+		if err := test.Run(nil, false); err != nil {
+			result.Pass, result.Error = false, err.Error()
+		}
+		results = append(results, result)
+	}
+	out, _ := json.MarshalIndent(results, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
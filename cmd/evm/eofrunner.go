@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/urfave/cli"
+)
+
+var eofTestCommand = cli.Command{
+	Action:    eofTestCmd,
+	Name:      "eoftest",
+	Usage:     "validates the containers of the given EOF tests",
+	ArgsUsage: "<file>",
+}
+
+// EOFTest is a test of the EOF container validation, its vectors being the containers with the expected result
+// of the validation for each fork.
+type EOFTest struct {
+	Vectors map[string]struct {
+		Code    hexutil.Bytes `json:"code"`
+		Results map[string]struct {
+			Result    bool   `json:"result"`
+			Exception string `json:"exception,omitempty"`
+		} `json:"results"`
+	} `json:"vectors"`
+}
+
+// EOFTestResult contains the validation status of a vector of an EOF test.
+type EOFTestResult struct {
+	Name  string `json:"name"`
+	Pass  bool   `json:"pass"`
+	Fork  string `json:"fork"`
+	Error string `json:"error,omitempty"`
+}
+
+func eofTestCmd(ctx *cli.Context) error {
+	if len(ctx.Args().First()) == 0 {
+		return errors.New("path-to-test argument required")
+	}
+	src, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var tests map[string]EOFTest
+	if err = json.Unmarshal(src, &tests); err != nil {
+		return err
+	}
+	var results []EOFTestResult
+	for key, test := range tests {
+		for name, vector := range test.Vectors {
+			err := vm.ValidateEOF(vector.Code)
+			for fork, expected := range vector.Results {
+				result := EOFTestResult{Name: key + "/" + name, Fork: fork, Pass: (err == nil) == expected.Result}
+				switch {
+				case !result.Pass && err != nil:
+					result.Error = fmt.Sprintf("unexpected validation error: %v", err)
+				case !result.Pass:
+					result.Error = fmt.Sprintf("expected validation error %q", expected.Exception)
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Fork < results[j].Fork
+	})
+	out, _ := json.MarshalIndent(results, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
@@ -184,6 +184,8 @@ func init() {
 		disasmCommand,
 		runCommand,
 		stateTestCommand,
+		blockTestCommand,
+		eofTestCommand,
 		stateTransitionCommand,
 	}
 }
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/common"
@@ -39,6 +40,7 @@ var stateTestCommand = cli.Command{
 	Name:      "statetest",
 	Usage:     "executes the given state tests",
 	ArgsUsage: "<file>",
+	Flags:     []cli.Flag{RunFlag},
 }
 
 // StatetestResult contains the execution status after running a state test, any
@@ -82,6 +84,10 @@ func stateTestCmd(ctx *cli.Context) error {
 	default:
 		debugger = vm.NewStructLogger(config)
 	}
+	re, err := regexp.Compile(ctx.String(RunFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid regex -%s: %v", RunFlag.Name, err)
+	}
 	// Load the test content from the input file
 	src, err := os.ReadFile(ctx.Args().First())
 	if err != nil {
@@ -107,6 +113,9 @@ func stateTestCmd(ctx *cli.Context) error {
 	defer tx.Rollback()
 
 	for key, test := range tests {
+		if !re.MatchString(key) {
+			continue
+		}
 		for _, st := range test.Subtests() {
 			// Run the test and aggregate the result
 			result := &StatetestResult{Name: key, Fork: st.Fork, Pass: true}
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"runtime"
+
 	"github.com/spf13/cobra"
 
 	"github.com/ledgerwatch/erigon/cmd/utils"
@@ -11,10 +13,13 @@ import (
 var (
 	chaindata                      string
 	databaseVerbosity              int
+	dbBackend                      string
 	referenceChaindata             string
 	block, pruneTo, unwind         uint64
 	unwindEvery                    uint64
 	batchSizeStr                   string
+	execWorkers                    int
+	execCache                      bool
 	reset                          bool
 	bucket                         string
 	datadirCli, toChaindata        string
@@ -28,8 +33,11 @@ var (
 	pruneHBefore, pruneRBefore     uint64
 	pruneTBefore, pruneCBefore     uint64
 	experiments                    []string
+	receiptsMode                   string
 	chain                          string // Which chain to use (mainnet, ropsten, rinkeby, goerli, etc.)
 	snapshotsBool                  bool
+	recheckFrom, recheckTo         uint64
+	recheckWorkers                 int
 )
 
 func must(err error) {
@@ -94,6 +102,7 @@ func withDataDir2(cmd *cobra.Command) {
 	must(cmd.MarkFlagDirname(utils.DataDirFlag.Name))
 	must(cmd.MarkFlagRequired(utils.DataDirFlag.Name))
 	cmd.Flags().IntVar(&databaseVerbosity, "database.verbosity", 2, "Enabling internal db logs. Very high verbosity levels may require recompile db. Default: 2, means warning.")
+	cmd.Flags().StringVar(&dbBackend, utils.DbBackendFlag.Name, utils.DbBackendFlag.Value, utils.DbBackendFlag.Usage)
 	cmd.Flags().BoolVar(&snapshotsBool, "snapshots", true, utils.SnapshotFlag.Usage)
 }
 
@@ -105,6 +114,7 @@ func withDataDir(cmd *cobra.Command) {
 	must(cmd.MarkFlagDirname("chaindata"))
 
 	cmd.Flags().IntVar(&databaseVerbosity, "database.verbosity", 2, "Enabling internal db logs. Very high verbosity levels may require recompile db. Default: 2, means warning")
+	cmd.Flags().StringVar(&dbBackend, utils.DbBackendFlag.Name, utils.DbBackendFlag.Value, utils.DbBackendFlag.Usage)
 	cmd.Flags().BoolVar(&snapshotsBool, "snapshots", true, utils.SnapshotFlag.Usage)
 }
 
@@ -112,6 +122,11 @@ func withBatchSize(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&batchSizeStr, "batchSize", "512M", "batch size for execution stage")
 }
 
+func withExecWorkers(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&execWorkers, "exec.workers", 1, "number of workers executing the transactions of a block in parallel, 1 executes them sequentially")
+	cmd.Flags().BoolVar(&execCache, "exec.cache", false, "cache the state read by the sequential execution of the blocks across the blocks")
+}
+
 func withIntegrityChecks(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&integritySlow, "integrity.slow", false, "enable slow data-integrity checks")
 	cmd.Flags().BoolVar(&integrityFast, "integrity.fast", true, "enable fast data-integrity checks")
@@ -129,6 +144,12 @@ func withChain(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&chain, "chain", "", "pick a chain to assume (mainnet, ropsten, etc.)")
 }
 
+func withRecheckRange(cmd *cobra.Command) {
+	cmd.Flags().Uint64Var(&recheckFrom, "from", 1, "first block to re-execute")
+	cmd.Flags().Uint64Var(&recheckTo, "to", 0, "last block to re-execute, 0 for the last executed block")
+	cmd.Flags().IntVar(&recheckWorkers, "workers", runtime.NumCPU(), "number of workers re-executing the blocks in parallel")
+}
+
 func withHeimdall(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&HeimdallURL, "bor.heimdall", "http://localhost:1317", "URL of Heimdall service")
 }
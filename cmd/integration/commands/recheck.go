@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+)
+
+var cmdRecheck = &cobra.Command{
+	Use:   "recheck",
+	Short: "Re-execute the blocks --from --to in parallel, and compare their receipts, state changes and state roots with the stored ones, to detect a corrupted DB",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		logger := log.New()
+		db := openDB(dbCfg(kv.ChainDB, logger, chaindata), true)
+		defer db.Close()
+
+		if err := recheck(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	withDataDir(cmdRecheck)
+	withRecheckRange(cmdRecheck)
+	withChain(cmdRecheck)
+	withHeimdall(cmdRecheck)
+
+	rootCmd.AddCommand(cmdRecheck)
+}
+
+func recheck(db kv.RwDB, ctx context.Context) error {
+	_, _, chainConfig, vmConfig, _, _, _ := newSync(ctx, db, nil)
+	allSn := allSnapshots(chainConfig, db)
+	cfg := stagedsync.BlockRecheckCfg{ChainConfig: chainConfig, BlockReader: getBlockReader(chainConfig, db), VMConfig: *vmConfig, TmpDir: filepath.Join(datadirCli, etl.TmpDirName)}
+	logger := log.New()
+
+	from, to := recheckFrom, recheckTo
+	if from == 0 {
+		from = 1 // the genesis isn't executed
+	}
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		executed, err := stages.GetStageProgress(tx, stages.Execution)
+		if err != nil {
+			return err
+		}
+		if to == 0 || to > executed {
+			to = executed
+		}
+		availableFrom, err := changeset.AvailableFrom(tx)
+		if err != nil {
+			return err
+		}
+		if from < availableFrom {
+			return fmt.Errorf("history of block %d is pruned, available from block %d", from, availableFrom)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("no blocks to recheck from %d to %d", from, to)
+	}
+	log.Info("Recheck", "from", from, "to", to, "workers", recheckWorkers)
+
+	blocks := make(chan uint64, recheckWorkers)
+	var done, mismatches uint64
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < recheckWorkers; i++ {
+		wg.Add(1)
+		// the engines keep state of their own, like the validator sets of AuRa: one per worker
+		workerCfg := cfg
+		workerCfg.Engine = newConsensusEngine(chainConfig, logger, allSn, true)
+		go func() {
+			defer wg.Done()
+			defer workerCfg.Engine.Close()
+			tx, err := db.BeginRo(ctx)
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				for range blocks {
+				}
+				return
+			}
+			defer tx.Rollback()
+			for n := range blocks {
+				if err := stagedsync.RecheckBlock(ctx, tx, n, workerCfg); err != nil {
+					atomic.AddUint64(&mismatches, 1)
+					log.Error("Recheck mismatch", "block", n, "err", err)
+				}
+				atomic.AddUint64(&done, 1)
+			}
+		}()
+	}
+
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+Loop:
+	for n := from; n <= to; {
+		select {
+		case blocks <- n:
+			n++
+		case <-ctx.Done():
+			break Loop
+		case <-logEvery.C:
+			log.Info("Recheck", "block", n, "rechecked", atomic.LoadUint64(&done), "mismatches", atomic.LoadUint64(&mismatches))
+		}
+	}
+	close(blocks)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of the blocks %d-%d mismatch", mismatches, from, to)
+	}
+	log.Info("Recheck done", "blocks", done)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/migrations"
+	"github.com/ledgerwatch/erigon/node"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
 )
@@ -34,7 +35,15 @@ func RootCommand() *cobra.Command {
 	return rootCmd
 }
 
-func dbCfg(label kv.Label, logger log.Logger, path string) kv2.MdbxOpts {
+// dbOpts - the options of the database opened with the engine of --db.backend, the mdbx ones apply to mdbx only
+type dbOpts struct {
+	kv2.MdbxOpts
+	logger   log.Logger
+	path     string
+	readonly bool
+}
+
+func dbCfg(label kv.Label, logger log.Logger, path string) dbOpts {
 	opts := kv2.NewMDBX(logger).Path(path).Label(label)
 	if label == kv.ChainDB {
 		opts = opts.MapSize(8 * datasize.TB)
@@ -42,11 +51,37 @@ func dbCfg(label kv.Label, logger log.Logger, path string) kv2.MdbxOpts {
 	if databaseVerbosity != -1 {
 		opts = opts.DBVerbosity(kv.DBVerbosityLvl(databaseVerbosity))
 	}
+	return dbOpts{MdbxOpts: opts, logger: logger, path: path}
+}
+
+func (opts dbOpts) Readonly() dbOpts {
+	opts.MdbxOpts = opts.MdbxOpts.Readonly()
+	opts.readonly = true
 	return opts
 }
 
-func openDB(opts kv2.MdbxOpts, applyMigrations bool) kv.RwDB {
-	db := opts.MustOpen()
+func (opts dbOpts) mustOpen(exclusive bool) kv.RwDB {
+	if dbBackend == "" || dbBackend == node.DefaultDBBackend {
+		if exclusive {
+			return opts.Exclusive().MustOpen()
+		}
+		return opts.MustOpen()
+	}
+	mode := node.DBOpenDefault
+	if opts.readonly {
+		mode = node.DBOpenReadonly
+	} else if exclusive {
+		mode = node.DBOpenExclusive
+	}
+	db, err := node.OpenDBBackend(dbBackend, opts.logger, opts.GetLabel(), opts.path, mode)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+func openDB(opts dbOpts, applyMigrations bool) kv.RwDB {
+	db := opts.mustOpen(false)
 	if applyMigrations {
 		migrator := migrations.NewMigrator(opts.GetLabel())
 		has, err := migrator.HasPendingMigrations(db)
@@ -56,12 +91,12 @@ func openDB(opts kv2.MdbxOpts, applyMigrations bool) kv.RwDB {
 		if has {
 			log.Info("Re-Opening DB in exclusive mode to apply DB migrations")
 			db.Close()
-			db = opts.Exclusive().MustOpen()
+			db = opts.mustOpen(true)
 			if err := migrator.Apply(db, datadirCli); err != nil {
 				panic(err)
 			}
 			db.Close()
-			db = opts.MustOpen()
+			db = opts.mustOpen(false)
 		}
 	}
 	return db
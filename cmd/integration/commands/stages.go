@@ -14,9 +14,12 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/bor"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	reset2 "github.com/ledgerwatch/erigon/core/rawdb/rawdbreset"
@@ -339,6 +342,7 @@ func init() {
 	withUnwind(cmdStageExec)
 	withPruneTo(cmdStageExec)
 	withBatchSize(cmdStageExec)
+	withExecWorkers(cmdStageExec)
 	withTxTrace(cmdStageExec)
 	withChain(cmdStageExec)
 	withHeimdall(cmdStageExec)
@@ -437,6 +441,7 @@ func init() {
 	cmdSetPrune.Flags().Uint64Var(&pruneTBefore, "prune.t.before", 0, "")
 	cmdSetPrune.Flags().Uint64Var(&pruneCBefore, "prune.c.before", 0, "")
 	cmdSetPrune.Flags().StringSliceVar(&experiments, "experiments", nil, "Storage mode to override database")
+	cmdSetPrune.Flags().StringVar(&receiptsMode, "receipts.mode", "", "")
 	rootCmd.AddCommand(cmdSetPrune)
 }
 
@@ -663,7 +668,7 @@ func stageExec(db kv.RwDB, ctx context.Context) error {
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
 	}
 
-	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil,
+	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, execWorkers, execCache, false, nil, nil, chainConfig, engine, vmConfig, nil,
 		/*stateStream=*/ false,
 		/*badBlockHalt=*/ false, tmpdir, getBlockReader(chainConfig, db), nil)
 	if unwind > 0 {
@@ -1163,23 +1168,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 	cfg.Dirs = datadir.New(datadirCli)
 	allSn := allSnapshots(chainConfig, db)
 	cfg.Snapshot = allSn.Cfg()
-	var engine consensus.Engine
-	config := &ethconfig.Defaults
-	if chainConfig.Clique != nil {
-		c := params.CliqueSnapshot
-		c.DBPath = filepath.Join(datadirCli, "clique", "db")
-		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, c, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
-	} else if chainConfig.Aura != nil {
-		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, &params.AuRaConfig{DBPath: filepath.Join(datadirCli, "aura")}, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
-	} else if chainConfig.Parlia != nil {
-		consensusConfig := &params.ParliaConfig{DBPath: filepath.Join(datadirCli, "parlia")}
-		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
-	} else if chainConfig.Bor != nil {
-		consensusConfig := &config.Bor
-		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, HeimdallURL, false, datadirCli, allSn)
-	} else { //ethash
-		engine = ethash.NewFaker()
-	}
+	engine := newConsensusEngine(chainConfig, logger, allSn, false)
 
 	br := getBlockReader(chainConfig, db)
 	sentryControlServer, err := sentry.NewMultiClient(
@@ -1198,7 +1187,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 		panic(err)
 	}
 
-	sync, err := stages2.NewStagedSync(context.Background(), logger, db, p2p.Config{}, cfg, sentryControlServer, tmpdir, &stagedsync.Notifications{}, nil, allSn, nil, nil)
+	sync, err := stages2.NewStagedSync(context.Background(), logger, db, p2p.Config{}, cfg, sentryControlServer, tmpdir, &stagedsync.Notifications{}, nil, allSn, nil, nil, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -1207,7 +1196,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 	miningSync := stagedsync.New(
 		stagedsync.MiningStages(ctx,
 			stagedsync.StageMiningCreateBlockCfg(db, miner, *chainConfig, engine, nil, nil, nil, tmpdir),
-			stagedsync.StageMiningExecCfg(db, miner, events, *chainConfig, engine, &vm.Config{}, tmpdir, nil),
+			stagedsync.StageMiningExecCfg(db, miner, events, *chainConfig, engine, &vm.Config{}, tmpdir, nil, nil),
 			stagedsync.StageHashStateCfg(db, tmpdir),
 			stagedsync.StageTrieCfg(db, false, true, false, tmpdir, br, nil),
 			stagedsync.StageMiningFinishCfg(db, *chainConfig, engine, miner, ctx.Done()),
@@ -1237,7 +1226,7 @@ func stage(st *stagedsync.Sync, tx kv.Tx, db kv.RoDB, stage stages.SyncStage) *s
 
 func overrideStorageMode(db kv.RwDB) error {
 	pm, err := prune.FromCli(pruneFlag, pruneH, pruneR, pruneT, pruneC,
-		pruneHBefore, pruneRBefore, pruneTBefore, pruneCBefore, experiments)
+		pruneHBefore, pruneRBefore, pruneTBefore, pruneCBefore, experiments, receiptsMode)
 	if err != nil {
 		return err
 	}
@@ -1266,3 +1255,34 @@ func genesisByChain(chain string) (*core.Genesis, *params.ChainConfig) {
 	}
 	return genesis, chainConfig
 }
+
+// newConsensusEngine creates the consensus engine of the chain. With inMemory, the databases of the engine are kept
+// in memory: for the engines created in addition to the one of the node, which would share its databases otherwise.
+func newConsensusEngine(chainConfig *params.ChainConfig, logger log.Logger, allSn *snapshotsync.RoSnapshots, inMemory bool) consensus.Engine {
+	var engine consensus.Engine
+	config := &ethconfig.Defaults
+	if chainConfig.Clique != nil {
+		c := *params.CliqueSnapshot
+		c.DBPath = filepath.Join(datadirCli, "clique", "db")
+		c.InMemory = c.InMemory || inMemory
+		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, &c, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
+	} else if chainConfig.Aura != nil {
+		consensusConfig := &params.AuRaConfig{DBPath: filepath.Join(datadirCli, "aura"), InMemory: inMemory}
+		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
+	} else if chainConfig.Parlia != nil {
+		consensusConfig := &params.ParliaConfig{DBPath: filepath.Join(datadirCli, "parlia"), InMemory: inMemory}
+		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, "", true, datadirCli, allSn)
+	} else if chainConfig.Bor != nil && inMemory {
+		// CreateConsensusEngine always opens datadir/bor
+		engine = bor.New(chainConfig, memdb.New(), HeimdallURL, false)
+		if chainConfig.TerminalTotalDifficulty != nil {
+			engine = serenity.New(engine)
+		}
+	} else if chainConfig.Bor != nil {
+		consensusConfig := &config.Bor
+		engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, HeimdallURL, false, datadirCli, allSn)
+	} else { //ethash
+		engine = ethash.NewFaker()
+	}
+	return engine
+}
@@ -2,15 +2,15 @@ package cli
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/big"
 	"net"
 	"net/http"
-	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,11 +29,12 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcservices"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/erigon/cmd/utils"
-	"github.com/ledgerwatch/erigon/common"
-	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/paths"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/node"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
@@ -46,6 +47,7 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 	"google.golang.org/grpc"
 	grpcHealth "google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -61,11 +63,12 @@ const JwtDefaultFile = "jwt.hex"
 func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	utils.CobraFlags(rootCmd, append(debug.Flags, utils.MetricFlags...))
 
-	cfg := &httpcfg.HttpCfg{StateCache: kvcache.DefaultCoherentConfig}
+	cfg := &httpcfg.HttpCfg{StateCache: kvcache.DefaultCoherentConfig, GPO: ethconfig.Defaults.GPO}
 	rootCmd.PersistentFlags().StringVar(&cfg.PrivateApiAddr, "private.api.addr", "127.0.0.1:9090", "private api network address, for example: 127.0.0.1:9090")
+	rootCmd.PersistentFlags().BoolVar(&cfg.PrivateApiCompression, "private.api.compression", false, "gzip-compress calls to private api (and its responses): for rpcdaemon on separate host")
 	rootCmd.PersistentFlags().StringVar(&cfg.DataDir, "datadir", "", "path to Erigon working directory")
 	rootCmd.PersistentFlags().StringVar(&cfg.HttpListenAddress, "http.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface")
-	rootCmd.PersistentFlags().StringVar(&cfg.EngineHTTPListenAddress, "engine.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface for engineAPI")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.EngineHTTPListenAddresses, utils.EngineAddr.Name, []string{nodecfg.DefaultHTTPHost}, utils.EngineAddr.Usage)
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCertfile, "tls.cert", "", "certificate for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSKeyFile, "tls.key", "", "key file for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCACert, "tls.cacert", "", "CA certificate for client side TLS handshake")
@@ -77,23 +80,43 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().StringSliceVar(&cfg.API, "http.api", []string{"eth", "erigon", "engine"}, "API's offered over the HTTP-RPC interface: eth,engine,erigon,web3,net,debug,trace,txpool,db,starknet. Supported methods: https://github.com/ledgerwatch/erigon/tree/devel/cmd/rpcdaemon")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.Gascap, "rpc.gascap", 50000000, "Sets a cap on gas that can be used in eth_call/estimateGas")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraces, "trace.maxtraces", 200, "Sets a limit on traces that can be returned in trace_filter")
+	var traceCacheSize string
+	rootCmd.PersistentFlags().StringVar(&traceCacheSize, utils.TraceCacheSizeFlag.Name, utils.TraceCacheSizeFlag.Value, utils.TraceCacheSizeFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.TraceCacheDir, utils.TraceCacheDirFlag.Name, "", utils.TraceCacheDirFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
+	rootCmd.PersistentFlags().IntVar(&cfg.WebsocketPendingTxsRate, utils.WsPendingTxsRateFlag.Name, utils.WsPendingTxsRateFlag.Value, utils.WsPendingTxsRateFlag.Usage)
+	rootCmd.PersistentFlags().BoolVar(&cfg.GraphQLEnabled, utils.GraphQLEnabledFlag.Name, false, utils.GraphQLEnabledFlag.Usage)
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcRateLimitsFilePath, utils.RpcRateLimitsFlag.Name, "", utils.RpcRateLimitsFlag.Usage)
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcBatchLimit, utils.RpcBatchLimitFlag.Name, utils.RpcBatchLimitFlag.Value, utils.RpcBatchLimitFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.RpcSlowThreshold, utils.RpcSlowThresholdFlag.Name, utils.RpcSlowThresholdFlag.Value, utils.RpcSlowThresholdFlag.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&cfg.Health.MaxBlocksBehind, utils.HealthMaxBlocksBehindFlag.Name, utils.HealthMaxBlocksBehindFlag.Value, utils.HealthMaxBlocksBehindFlag.Usage)
+	rootCmd.PersistentFlags().UintVar(&cfg.Health.MinPeers, utils.HealthMinPeersFlag.Name, utils.HealthMinPeersFlag.Value, utils.HealthMinPeersFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.Health.EngineTimeout, utils.HealthEngineTimeoutFlag.Name, utils.HealthEngineTimeoutFlag.Value, utils.HealthEngineTimeoutFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, "db.read.concurrency", runtime.GOMAXPROCS(-1), "Does limit amount of parallel db reads")
+	rootCmd.PersistentFlags().StringVar(&cfg.DBBackend, utils.DbBackendFlag.Name, utils.DbBackendFlag.Value, utils.DbBackendFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceCompatibility, "trace.compat", false, "Bug for bug compatibility with OE for trace_ routines")
 	rootCmd.PersistentFlags().StringVar(&cfg.TxPoolApiAddr, "txpool.api.addr", "", "txpool api network address, for example: 127.0.0.1:9090 (default: use value of --private.api.addr)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.TevmEnabled, utils.TevmFlag.Name, false, utils.TevmFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.Sync.UseSnapshots, "snapshot", true, utils.SnapshotFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.StateCache.KeysLimit, "state.cache", kvcache.DefaultCoherentConfig.KeysLimit, "Amount of keys to store in StateCache (enabled if no --datadir set). Set 0 to disable StateCache. 1_000_000 keys ~ equal to 2Gb RAM (maybe we will add RAM accounting in future versions).")
+	rootCmd.PersistentFlags().Uint64Var(&cfg.StateCacheWarmupBlocks, utils.StateCacheWarmupFlag.Name, utils.StateCacheWarmupFlag.Value, utils.StateCacheWarmupFlag.Usage)
+	var gpoMinPrice, gpoMaxPrice int64
+	rootCmd.PersistentFlags().StringVar(&cfg.GPO.Strategy, utils.GpoStrategyFlag.Name, utils.GpoStrategyFlag.Value, utils.GpoStrategyFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.GPO.Blocks, utils.GpoBlocksFlag.Name, utils.GpoBlocksFlag.Value, utils.GpoBlocksFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.GPO.Percentile, utils.GpoPercentileFlag.Name, utils.GpoPercentileFlag.Value, utils.GpoPercentileFlag.Usage)
+	rootCmd.PersistentFlags().Int64Var(&gpoMinPrice, utils.GpoMinGasPriceFlag.Name, utils.GpoMinGasPriceFlag.Value, utils.GpoMinGasPriceFlag.Usage)
+	rootCmd.PersistentFlags().Int64Var(&gpoMaxPrice, utils.GpoMaxGasPriceFlag.Name, utils.GpoMaxGasPriceFlag.Value, utils.GpoMaxGasPriceFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
 	rootCmd.PersistentFlags().StringVar(&cfg.GRPCListenAddress, "grpc.addr", nodecfg.DefaultGRPCHost, "GRPC server listening interface")
 	rootCmd.PersistentFlags().IntVar(&cfg.GRPCPort, "grpc.port", nodecfg.DefaultGRPCPort, "GRPC server listening port")
 	rootCmd.PersistentFlags().BoolVar(&cfg.GRPCHealthCheckEnabled, "grpc.healthcheck", false, "Enable GRPC health check")
 	rootCmd.PersistentFlags().StringVar(&cfg.StarknetGRPCAddress, "starknet.grpc.address", "127.0.0.1:6066", "Starknet GRPC address")
 	rootCmd.PersistentFlags().StringVar(&cfg.JWTSecretPath, utils.JWTSecretPath.Name, utils.JWTSecretPath.Value, "Token to ensure safe connection between CL and EL")
+	rootCmd.PersistentFlags().DurationVar(&cfg.JWTSecretRotation, utils.JWTSecretRotationFlag.Name, utils.JWTSecretRotationFlag.Value, utils.JWTSecretRotationFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceRequests, utils.HTTPTraceFlag.Name, false, "Trace HTTP requests with INFO level")
 	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeouts.ReadTimeout, "http.timeouts.read", rpccfg.DefaultHTTPTimeouts.ReadTimeout, "Maximum duration for reading the entire request, including the body.")
 	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeouts.WriteTimeout, "http.timeouts.write", rpccfg.DefaultHTTPTimeouts.WriteTimeout, "Maximum duration before timing out writes of the response. It is reset whenever a new request's header is read")
@@ -123,6 +146,13 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 		if cfg.TxPoolApiAddr == "" {
 			cfg.TxPoolApiAddr = cfg.PrivateApiAddr
 		}
+		if err := cfg.TraceCacheSize.UnmarshalText([]byte(traceCacheSize)); err != nil {
+			return fmt.Errorf("invalid --%s: %w", utils.TraceCacheSizeFlag.Name, err)
+		}
+		if !slices.Contains(gasprice.Strategies, cfg.GPO.Strategy) {
+			return fmt.Errorf("invalid %s: %s, expected one of: %s", utils.GpoStrategyFlag.Name, cfg.GPO.Strategy, strings.Join(gasprice.Strategies, ", "))
+		}
+		cfg.GPO.MinPrice, cfg.GPO.MaxPrice = big.NewInt(gpoMinPrice), big.NewInt(gpoMaxPrice)
 		return nil
 	}
 	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
@@ -139,7 +169,7 @@ type StateChangesClient interface {
 	StateChanges(ctx context.Context, in *remote.StateChangeRequest, opts ...grpc.CallOption) (remote.KV_StateChangesClient, error)
 }
 
-func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient, cache kvcache.Cache) {
+func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient, cache kvcache.Cache, warmer *rpchelper.CacheWarmer, ff *rpchelper.Filters) {
 	go func() {
 		for {
 			select {
@@ -147,18 +177,19 @@ func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient,
 				return
 			default:
 			}
-			if err := subscribeToStateChanges(ctx, client, cache); err != nil {
-				if grpcutil.IsRetryLater(err) || grpcutil.IsEndOfStream(err) {
-					time.Sleep(3 * time.Second)
-					continue
+			// changes of blocks missed while disconnected are not replayed: state cache sees the gap in view ids and
+			// starts the new view from empty
+			if err := subscribeToStateChanges(ctx, client, cache, warmer, ff); err != nil {
+				if !grpcutil.IsRetryLater(err) && !grpcutil.IsEndOfStream(err) {
+					log.Warn("[rpcdaemon.handleStateChanges] re-subscribing", "err", err)
 				}
-				log.Warn("[txpool.handleStateChanges]", "err", err)
 			}
+			time.Sleep(3 * time.Second)
 		}
 	}()
 }
 
-func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cache kvcache.Cache) error {
+func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cache kvcache.Cache, warmer *rpchelper.CacheWarmer, ff *rpchelper.Filters) error {
 	streamCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	stream, err := client.StateChanges(streamCtx, &remote.StateChangeRequest{WithStorage: true, WithTransactions: false}, grpc.WaitForReady(true))
@@ -174,6 +205,12 @@ func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cac
 		}
 
 		cache.OnNewBlock(req)
+		if warmer != nil {
+			warmer.OnNewBlock(ctx, req)
+		}
+		if ff != nil {
+			ff.OnStateChanges(req)
+		}
 	}
 }
 
@@ -214,27 +251,33 @@ func checkDbCompatibility(ctx context.Context, db kv.RoDB) error {
 	return nil
 }
 
-func EmbeddedServices(ctx context.Context, erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig, blockReader services.FullBlockReader, ethBackendServer remote.ETHBACKENDServer,
-	txPoolServer txpool.TxpoolServer, miningServer txpool.MiningServer,
+func EmbeddedServices(ctx context.Context, erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig, stateCacheWarmupBlocks uint64, blockReader services.FullBlockReader, ethBackendServer remote.ETHBACKENDServer,
+	txPoolServer txpool.TxpoolServer, miningServer txpool.MiningServer, sentryAdmin sentryadmin.Admin,
 ) (
 	eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, starknet *rpcservices.StarknetService, stateCache kvcache.Cache, ff *rpchelper.Filters, err error,
 ) {
+	var warmer *rpchelper.CacheWarmer
 	if stateCacheCfg.KeysLimit > 0 {
 		stateCache = kvcache.New(stateCacheCfg)
+		rpchelper.RegisterCacheHitRatio(stateCacheCfg.MetricsLabel)
+		if stateCacheWarmupBlocks > 0 {
+			warmer = rpchelper.NewCacheWarmer(erigonDB, stateCache, stateCacheWarmupBlocks)
+		}
 	} else {
 		stateCache = kvcache.NewDummy()
 	}
 	kvRPC := remotedbserver.NewKvServer(ctx, erigonDB)
 	stateDiffClient := direct.NewStateDiffClientDirect(kvRPC)
-	_ = stateDiffClient
-	subscribeToStateChangesLoop(ctx, stateDiffClient, stateCache)
 
 	directClient := direct.NewEthBackendClientDirect(ethBackendServer)
 
-	eth = rpcservices.NewRemoteBackend(directClient, erigonDB, blockReader)
+	remoteEth := rpcservices.NewRemoteBackend(directClient, erigonDB, blockReader)
+	remoteEth.SetSentryAdmin(sentryAdmin)
+	eth = remoteEth
 	txPool = direct.NewTxPoolClient(txPoolServer)
 	mining = direct.NewMiningClient(miningServer)
 	ff = rpchelper.New(ctx, eth, txPool, mining, func() {})
+	subscribeToStateChangesLoop(ctx, stateDiffClient, stateCache, warmer, ff)
 	return
 }
 
@@ -256,7 +299,11 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		var rwKv kv.RwDB
 		log.Trace("Creating chain db", "path", cfg.Dirs.Chaindata)
 		limiter := make(chan struct{}, cfg.DBReadConcurrency)
-		rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
+		if cfg.DBBackend != "" && cfg.DBBackend != node.DefaultDBBackend {
+			rwKv, err = node.OpenDBBackend(cfg.DBBackend, logger, kv.ChainDB, cfg.Dirs.Chaindata, node.DBOpenReadonly)
+		} else {
+			rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
+		}
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, nil, ff, err
 		}
@@ -272,14 +319,14 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		borDbPath := filepath.Join(cfg.DataDir, "bor")
 		{
 			// ensure db exist
-			tmpDb, err := kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Open()
+			tmpDb, err := node.OpenDBBackend(cfg.DBBackend, logger, kv.ConsensusDB, borDbPath, node.DBOpenDefault)
 			if err != nil {
 				return nil, nil, nil, nil, nil, nil, nil, nil, ff, err
 			}
 			tmpDb.Close()
 		}
 		log.Trace("Creating consensus db", "path", borDbPath)
-		borKv, err = kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Readonly().Open()
+		borKv, err = node.OpenDBBackend(cfg.DBBackend, logger, kv.ConsensusDB, borDbPath, node.DBOpenReadonly)
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, nil, ff, err
 		}
@@ -288,6 +335,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	} else {
 		if cfg.StateCache.KeysLimit > 0 {
 			stateCache = kvcache.New(cfg.StateCache)
+			rpchelper.RegisterCacheHitRatio(cfg.StateCache.MetricsLabel)
 		} else {
 			stateCache = kvcache.NewDummy()
 		}
@@ -369,7 +417,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	if err != nil {
 		return nil, nil, nil, nil, nil, nil, nil, nil, ff, fmt.Errorf("open tls cert: %w", err)
 	}
-	conn, err := grpcutil.Connect(creds, cfg.PrivateApiAddr)
+	conn, err := connect(creds, cfg.PrivateApiAddr, cfg.PrivateApiCompression)
 	if err != nil {
 		return nil, nil, nil, nil, nil, nil, nil, nil, ff, fmt.Errorf("could not connect to execution service privateApi: %w", err)
 	}
@@ -380,17 +428,21 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		return nil, nil, nil, nil, nil, nil, nil, nil, ff, fmt.Errorf("could not connect to remoteKv: %w", err)
 	}
 
-	subscribeToStateChangesLoop(ctx, kvClient, stateCache)
+	var warmer *rpchelper.CacheWarmer
+	if !cfg.WithDatadir && cfg.StateCache.KeysLimit > 0 && cfg.StateCacheWarmupBlocks > 0 {
+		warmer = rpchelper.NewCacheWarmer(remoteKv, stateCache, cfg.StateCacheWarmupBlocks)
+	}
 
 	if !cfg.WithDatadir {
 		blockReader = snapshotsync.NewRemoteBlockReader(remote.NewETHBACKENDClient(conn))
 	}
 	remoteEth := rpcservices.NewRemoteBackend(remote.NewETHBACKENDClient(conn), db, blockReader)
+	remoteEth.SetSentryAdmin(sentryadmin.NewClient(conn))
 	blockReader = remoteEth
 
 	txpoolConn := conn
 	if cfg.TxPoolApiAddr != cfg.PrivateApiAddr {
-		txpoolConn, err = grpcutil.Connect(creds, cfg.TxPoolApiAddr)
+		txpoolConn, err = connect(creds, cfg.TxPoolApiAddr, cfg.PrivateApiCompression)
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, nil, ff, fmt.Errorf("could not connect to txpool api: %w", err)
 		}
@@ -428,20 +480,23 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	}
 
 	ff = rpchelper.New(ctx, eth, txPool, mining, onNewSnapshot)
+	subscribeToStateChangesLoop(ctx, kvClient, stateCache, warmer, ff)
 
 	return db, borDb, eth, txPool, mining, starknet, stateCache, blockReader, ff, err
 }
 
 func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API) error {
-	var engineListener *http.Server
+	var engineListeners []*http.Server
 	var engineSrv *rpc.Server
-	var engineHttpEndpoint string
+	var engineHttpEndpoints []string
 
 	// register apis and create handler stack
 	httpEndpoint := fmt.Sprintf("%s:%d", cfg.HttpListenAddress, cfg.HttpPort)
 
 	fmt.Printf("TraceRequests = %t\n", cfg.TraceRequests)
 	srv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, cfg.RpcStreamingDisable)
+	srv.SetBatchLimit(cfg.RpcBatchLimit)
+	srv.SetSlowThreshold(cfg.RpcSlowThreshold)
 
 	allowListForRPC, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
 	if err != nil {
@@ -449,14 +504,24 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 	}
 	srv.SetAllowList(allowListForRPC)
 
+	rateLimits, err := parseRateLimitsForRPC(cfg.RpcRateLimitsFilePath)
+	if err != nil {
+		return err
+	}
+	srv.SetRateLimits(rateLimits)
+
 	var defaultAPIList []rpc.API
 	var engineAPI []rpc.API
+	var graphqlHandler http.Handler
 
 	for _, api := range rpcAPI {
-		if api.Namespace != "engine" {
-			defaultAPIList = append(defaultAPIList, api)
-		} else {
+		switch api.Namespace {
+		case "engine":
 			engineAPI = append(engineAPI, api)
+		case "graphql":
+			graphqlHandler = api.Service.(http.Handler)
+		default:
+			defaultAPIList = append(defaultAPIList, api)
 		}
 	}
 
@@ -490,16 +555,24 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 	if err != nil {
 		return err
 	}
+	if graphqlHandler != nil {
+		mux := http.NewServeMux()
+		// The GraphQL queries are rate limited like the calls, at the weight of "graphql"
+		graphqlHandler = srv.RateLimitHandler("graphql", graphqlHandler)
+		mux.Handle("/graphql", node.NewHTTPHandlerStack(graphqlHandler, cfg.HttpCORSDomain, cfg.HttpVirtualHost, cfg.HttpCompression))
+		mux.Handle("/", apiHandler)
+		apiHandler = mux
+	}
 
 	listener, _, err := node.StartHTTPEndpoint(httpEndpoint, cfg.HTTPTimeouts, apiHandler)
 	if err != nil {
 		return fmt.Errorf("could not start RPC api: %w", err)
 	}
 	info := []interface{}{"url", httpEndpoint, "ws", cfg.WebsocketEnabled,
-		"ws.compression", cfg.WebsocketCompression, "grpc", cfg.GRPCServerEnabled}
+		"ws.compression", cfg.WebsocketCompression, "grpc", cfg.GRPCServerEnabled, "graphql", graphqlHandler != nil}
 
 	if len(engineAPI) > 0 {
-		engineListener, engineSrv, engineHttpEndpoint, err = createEngineListener(cfg, engineAPI)
+		engineListeners, engineSrv, engineHttpEndpoints, err = createEngineListeners(ctx, cfg, engineAPI)
 		if err != nil {
 			return fmt.Errorf("could not start RPC api for engine: %w", err)
 		}
@@ -537,9 +610,9 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 		_ = listener.Shutdown(shutdownCtx)
 		log.Info("HTTP endpoint closed", "url", httpEndpoint)
 
-		if engineListener != nil {
+		for i, engineListener := range engineListeners {
 			_ = engineListener.Shutdown(shutdownCtx)
-			log.Info("Engine HTTP endpoint close", "url", engineHttpEndpoint)
+			log.Info("Engine HTTP endpoint close", "url", engineHttpEndpoints[i])
 		}
 
 		if cfg.GRPCServerEnabled {
@@ -562,47 +635,21 @@ func isWebsocket(r *http.Request) bool {
 		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
 }
 
-// obtainJWTSecret loads the jwt-secret, either from the provided config,
-// or from the default location. If neither of those are present, it generates
-// a new secret and stores to the default location.
-func obtainJWTSecret(cfg httpcfg.HttpCfg) ([]byte, error) {
-	// try reading from file
-	log.Info("Reading JWT secret", "path", cfg.JWTSecretPath)
-	// If we run the rpcdaemon and datadir is not specified we just use jwt.hex in current directory.
-	if len(cfg.JWTSecretPath) == 0 {
-		cfg.JWTSecretPath = "jwt.hex"
-	}
-	if data, err := os.ReadFile(cfg.JWTSecretPath); err == nil {
-		jwtSecret := common.FromHex(strings.TrimSpace(string(data)))
-		if len(jwtSecret) == 32 {
-			return jwtSecret, nil
-		}
-		log.Error("Invalid JWT secret", "path", cfg.JWTSecretPath, "length", len(jwtSecret))
-		return nil, errors.New("invalid JWT secret")
-	}
-	// Need to generate one
-	jwtSecret := make([]byte, 32)
-	rand.Read(jwtSecret)
-
-	if err := os.WriteFile(cfg.JWTSecretPath, []byte(hexutil.Encode(jwtSecret)), 0600); err != nil {
-		return nil, err
-	}
-	log.Info("Generated JWT secret", "path", cfg.JWTSecretPath)
-	return jwtSecret, nil
-}
-
-func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Handler, wsHandler http.Handler, jwtSecret []byte) (http.Handler, error) {
+func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Handler, wsHandler http.Handler, jwtSecrets func() [][]byte) (http.Handler, error) {
 	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// adding a healthcheck here
 		if health.ProcessHealthcheckIfNeeded(w, r, apiList) {
 			return
 		}
+		if health.ProcessReadinessIfNeeded(w, r, apiList, cfg.Health) {
+			return
+		}
 		if cfg.WebsocketEnabled && wsHandler != nil && isWebsocket(r) {
 			wsHandler.ServeHTTP(w, r)
 			return
 		}
 
-		if jwtSecret != nil && !rpc.CheckJwtSecret(w, r, jwtSecret) {
+		if jwtSecrets != nil && !rpc.CheckJwtSecrets(w, r, jwtSecrets()) {
 			return
 		}
 
@@ -612,45 +659,74 @@ func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Hand
 	return handler, nil
 }
 
-func createEngineListener(cfg httpcfg.HttpCfg, engineApi []rpc.API) (*http.Server, *rpc.Server, string, error) {
-	engineHttpEndpoint := fmt.Sprintf("%s:%d", cfg.EngineHTTPListenAddress, cfg.EnginePort)
+// engineEndpoints returns the addresses the Engine API listens on. The interfaces given without a port listen on engine.port.
+func engineEndpoints(cfg httpcfg.HttpCfg) []string {
+	endpoints := make([]string, 0, len(cfg.EngineHTTPListenAddresses))
+	for _, addr := range cfg.EngineHTTPListenAddresses {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, strconv.Itoa(cfg.EnginePort))
+		}
+		endpoints = append(endpoints, addr)
+	}
+	return endpoints
+}
+
+func createEngineListeners(ctx context.Context, cfg httpcfg.HttpCfg, engineApi []rpc.API) ([]*http.Server, *rpc.Server, []string, error) {
+	engineHttpEndpoints := engineEndpoints(cfg)
+	if len(engineHttpEndpoints) == 0 {
+		return nil, nil, nil, errors.New("no listening address for the engine API")
+	}
 
 	engineSrv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, true)
+	engineSrv.SetSlowThreshold(cfg.RpcSlowThreshold)
 
 	allowListForRPC, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
 	engineSrv.SetAllowList(allowListForRPC)
 
 	if err := node.RegisterApisFromWhitelist(engineApi, nil, engineSrv, true); err != nil {
-		return nil, nil, "", fmt.Errorf("could not start register RPC engine api: %w", err)
+		return nil, nil, nil, fmt.Errorf("could not start register RPC engine api: %w", err)
 	}
 
-	jwtSecret, err := obtainJWTSecret(cfg)
+	jwtSecrets, err := obtainJWTSecrets(cfg)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
+	go jwtSecrets.watch(ctx)
 
 	var wsHandler http.Handler
 	if cfg.WebsocketEnabled {
-		wsHandler = engineSrv.WebsocketHandler([]string{"*"}, jwtSecret, cfg.WebsocketCompression)
+		wsHandler = engineSrv.WebsocketHandler([]string{"*"}, jwtSecrets.Secrets, cfg.WebsocketCompression)
 	}
 
 	engineHttpHandler := node.NewHTTPHandlerStack(engineSrv, cfg.HttpCORSDomain, cfg.HttpVirtualHost, cfg.HttpCompression)
 
-	engineApiHandler, err := createHandler(cfg, engineApi, engineHttpHandler, wsHandler, jwtSecret)
+	engineApiHandler, err := createHandler(cfg, engineApi, engineHttpHandler, wsHandler, jwtSecrets.Secrets)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
 
-	engineListener, _, err := node.StartHTTPEndpoint(engineHttpEndpoint, cfg.EngineTimeouts, engineApiHandler)
-	if err != nil {
-		return nil, nil, "", fmt.Errorf("could not start RPC api: %w", err)
-	}
+	// every consensus client may be given its own endpoint, they all share the engine API
+	engineListeners := make([]*http.Server, 0, len(engineHttpEndpoints))
+	for _, engineHttpEndpoint := range engineHttpEndpoints {
+		engineListener, _, err := node.StartHTTPEndpoint(engineHttpEndpoint, cfg.EngineTimeouts, engineApiHandler)
+		if err != nil {
+			for _, l := range engineListeners {
+				_ = l.Close()
+			}
+			return nil, nil, nil, fmt.Errorf("could not start RPC api: %w", err)
+		}
+		engineListeners = append(engineListeners, engineListener)
 
-	engineInfo := []interface{}{"url", engineHttpEndpoint, "ws", cfg.WebsocketEnabled}
-	log.Info("HTTP endpoint opened for Engine API", engineInfo...)
+		engineInfo := []interface{}{"url", engineHttpEndpoint, "ws", cfg.WebsocketEnabled}
+		log.Info("HTTP endpoint opened for Engine API", engineInfo...)
+	}
 
-	return engineListener, engineSrv, engineHttpEndpoint, nil
+	return engineListeners, engineSrv, engineHttpEndpoints, nil
 }
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+// connect - same as grpcutil.Connect, but tuned for rpcdaemon on separate host: keepalive pings detect broken
+// connections (then gRPC reconnects with backoff), and responses may be gzip-compressed to save bandwidth.
+func connect(creds credentials.TransportCredentials, dialAddress string, compression bool) (*grpc.ClientConn, error) {
+	backoffCfg := backoff.DefaultConfig
+	backoffCfg.BaseDelay = 500 * time.Millisecond
+	backoffCfg.MaxDelay = 10 * time.Second
+	callOpts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(int(200 * datasize.MB))}
+	if compression {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffCfg, MinConnectTimeout: 10 * time.Minute}),
+		grpc.WithDefaultCallOptions(callOpts...),
+		// server's keepalive.EnforcementPolicy allows pings not more often than every 10 seconds
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 30 * time.Second, Timeout: 10 * time.Second, PermitWithoutStream: true}),
+	}
+	if creds == nil {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return grpc.DialContext(ctx, dialAddress, dialOpts...)
+}
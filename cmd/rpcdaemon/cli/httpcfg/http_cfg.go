@@ -1,50 +1,68 @@
 package httpcfg
 
 import (
+	"time"
+
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 )
 
 type HttpCfg struct {
-	Enabled                 bool
-	PrivateApiAddr          string
-	WithDatadir             bool // Erigon's database can be read by separated processes on same machine - in read-only mode - with full support of transactions. It will share same "OS PageCache" with Erigon process.
-	DataDir                 string
-	Dirs                    datadir.Dirs
-	HttpListenAddress       string
-	EngineHTTPListenAddress string
-	TLSCertfile             string
-	TLSCACert               string
-	TLSKeyFile              string
-	HttpPort                int
-	EnginePort              int
-	HttpCORSDomain          []string
-	HttpVirtualHost         []string
-	HttpCompression         bool
-	API                     []string
-	Gascap                  uint64
-	MaxTraces               uint64
-	WebsocketEnabled        bool
-	WebsocketCompression    bool
-	RpcAllowListFilePath    string
-	RpcBatchConcurrency     uint
-	RpcStreamingDisable     bool
-	DBReadConcurrency       int
-	TraceCompatibility      bool // Bug for bug compatibility for trace_ routines with OpenEthereum
-	TxPoolApiAddr           string
-	TevmEnabled             bool
-	StateCache              kvcache.CoherentConfig
-	Snap                    ethconfig.Snapshot
-	Sync                    ethconfig.Sync
-	GRPCServerEnabled       bool
-	GRPCListenAddress       string
-	GRPCPort                int
-	GRPCHealthCheckEnabled  bool
-	StarknetGRPCAddress     string
-	JWTSecretPath           string // Engine API Authentication
-	TraceRequests           bool   // Always trace requests in INFO level
-	HTTPTimeouts            rpccfg.HTTPTimeouts
-	EngineTimeouts          rpccfg.HTTPTimeouts
+	Enabled                   bool
+	PrivateApiAddr            string
+	PrivateApiCompression     bool
+	WithDatadir               bool // Erigon's database can be read by separated processes on same machine - in read-only mode - with full support of transactions. It will share same "OS PageCache" with Erigon process.
+	DataDir                   string
+	Dirs                      datadir.Dirs
+	HttpListenAddress         string
+	EngineHTTPListenAddresses []string
+	TLSCertfile               string
+	TLSCACert                 string
+	TLSKeyFile                string
+	HttpPort                  int
+	EnginePort                int
+	HttpCORSDomain            []string
+	HttpVirtualHost           []string
+	HttpCompression           bool
+	API                       []string
+	Gascap                    uint64
+	MaxTraces                 uint64
+	TraceCacheSize            datasize.ByteSize // of the cache of the debug_trace* outputs, 0 disables it
+	TraceCacheDir             string            // <datadir>/tracecache by default
+	WebsocketEnabled          bool
+	WebsocketCompression      bool
+	WebsocketPendingTxsRate   int // of the newPendingTransactions subscriptions, per connection and second
+	GraphQLEnabled            bool
+	RpcAllowListFilePath      string
+	RpcRateLimitsFilePath     string
+	RpcBatchConcurrency       uint
+	RpcBatchLimit             int
+	RpcSlowThreshold          time.Duration // the requests taking longer are logged at WARN, 0 if none are
+	RpcStreamingDisable       bool
+	Health                    health.Criteria // of /ready and erigon_health
+	DBReadConcurrency         int
+	DBBackend                 string // the database engine, see node.RegisterDBBackend
+	TraceCompatibility        bool   // Bug for bug compatibility for trace_ routines with OpenEthereum
+	TxPoolApiAddr             string
+	TevmEnabled               bool
+	StateCache                kvcache.CoherentConfig
+	StateCacheWarmupBlocks    uint64 // Recent blocks which state is loaded into the StateCache at startup
+	GPO                       gasprice.Config
+	Snap                      ethconfig.Snapshot
+	Sync                      ethconfig.Sync
+	GRPCServerEnabled         bool
+	GRPCListenAddress         string
+	GRPCPort                  int
+	GRPCHealthCheckEnabled    bool
+	StarknetGRPCAddress       string
+	JWTSecretPath             string        // Engine API Authentication
+	JWTSecretRotation         time.Duration // How long the replaced secrets remain valid
+	TraceRequests             bool          // Always trace requests in INFO level
+	HTTPTimeouts              rpccfg.HTTPTimeouts
+	EngineTimeouts            rpccfg.HTTPTimeouts
 }
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTSecretsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), JwtDefaultFile)
+	cfg := httpcfg.HttpCfg{JWTSecretPath: path, JWTSecretRotation: time.Hour}
+
+	// generated on the first start
+	secrets, err := obtainJWTSecrets(cfg)
+	require.NoError(t, err)
+	require.Len(t, secrets.Secrets(), 1)
+	generated := secrets.Secrets()[0]
+
+	changed, err := secrets.reload()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	first := common.Hash{1}
+	second := common.Hash{2}
+	require.NoError(t, os.WriteFile(path, []byte(first.Hex()+"\n"+second.Hex()[2:]+"\n"), 0600))
+	changed, err = secrets.reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, [][]byte{first[:], second[:], generated}, secrets.Secrets())
+
+	// an invalid file doesn't replace the secrets
+	require.NoError(t, os.WriteFile(path, []byte("0x1234"), 0600))
+	_, err = secrets.reload()
+	require.Error(t, err)
+	require.Len(t, secrets.Secrets(), 3)
+
+	// the replaced secrets expire after the rotation window
+	secrets.rotationWindow = 0
+	require.NoError(t, os.WriteFile(path, []byte(second.Hex()), 0600))
+	changed, err = secrets.reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	time.Sleep(time.Millisecond)
+	require.Equal(t, [][]byte{second[:]}, secrets.Secrets())
+}
+
+func TestEngineEndpoints(t *testing.T) {
+	cfg := httpcfg.HttpCfg{
+		EngineHTTPListenAddresses: []string{"localhost", " 127.0.0.1:8552", "::1", "[::1]:8553", ""},
+		EnginePort:                8551,
+	}
+	require.Equal(t, []string{"localhost:8551", "127.0.0.1:8552", "[::1]:8551", "[::1]:8553"}, engineEndpoints(cfg))
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// parseRateLimitsForRPC reads the rate limits of the calls from the given JSON file, if any.
+func parseRateLimitsForRPC(path string) (*rpc.RateLimits, error) {
+	path = strings.TrimSpace(path)
+	if path == "" { // no file is provided
+		return nil, nil
+	}
+
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var limits rpc.RateLimits
+	if err := json.Unmarshal(fileContents, &limits); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
@@ -17,11 +19,40 @@ type AdminAPI interface {
 	// Peers returns information about the connected remote nodes.
 	// https://geth.ethereum.org/docs/rpc/ns-admin#admin_peers
 	Peers(ctx context.Context) ([]*p2p.PeerInfo, error)
+
+	// AddPeer adds the node to the static peers, which are kept connected.
+	// https://geth.ethereum.org/docs/rpc/ns-admin#admin_addpeer
+	AddPeer(ctx context.Context, url string) (bool, error)
+
+	// RemovePeer removes the node from the static peers, and disconnects it.
+	RemovePeer(ctx context.Context, url string) (bool, error)
+
+	// AddTrustedPeer adds the node to the trusted peers, which are always allowed to connect.
+	AddTrustedPeer(ctx context.Context, url string) (bool, error)
+
+	// RemoveTrustedPeer removes the node from the trusted peers.
+	RemoveTrustedPeer(ctx context.Context, url string) (bool, error)
+
+	// PeerScores returns the scores of the peers which misbehaved, and the banned peers.
+	PeerScores(ctx context.Context) ([]sentryadmin.PeerReputation, error)
+
+	// AdjustPeerScore changes the score of the peer, which is banned if the score drops to the ban threshold.
+	AdjustPeerScore(ctx context.Context, peer string, delta int) (*sentryadmin.PeerReputation, error)
+
+	// UnbanPeer lifts the ban of the peer, and resets its score.
+	UnbanPeer(ctx context.Context, peer string) (*sentryadmin.PeerReputation, error)
+
+	// TraceCacheStatus returns the size and the hits of the cache of the debug_trace* outputs.
+	TraceCacheStatus(ctx context.Context) (*TraceCacheStatus, error)
+
+	// FlushTraceCache removes all the traces from the cache, and returns their number.
+	FlushTraceCache(ctx context.Context) (hexutil.Uint64, error)
 }
 
 // AdminAPIImpl data structure to store things needed for admin_* commands.
 type AdminAPIImpl struct {
 	ethBackend rpchelper.ApiBackend
+	traceCache *traceCache // nil if disabled
 }
 
 // NewAdminAPI returns AdminAPIImpl instance.
@@ -44,6 +75,76 @@ func (api *AdminAPIImpl) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
 	return &nodes[0], nil
 }
 
+// Peers implements admin_peers. The detail of the protocols of the peers is reported by the sentries serving
+// sentryadmin.
 func (api *AdminAPIImpl) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
+	if peers, err := api.ethBackend.SentryAdmin().PeersInfo(ctx); err == nil {
+		return peers, nil
+	}
 	return api.ethBackend.Peers(ctx)
 }
+
+// AddPeer implements admin_addPeer. The peer is persisted, and added again after a restart.
+func (api *AdminAPIImpl) AddPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.SentryAdmin().AddPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeer implements admin_removePeer.
+func (api *AdminAPIImpl) RemovePeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.SentryAdmin().RemovePeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddTrustedPeer implements admin_addTrustedPeer. The peer is persisted, and trusted again after a restart.
+func (api *AdminAPIImpl) AddTrustedPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.SentryAdmin().AddTrustedPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer implements admin_removeTrustedPeer.
+func (api *AdminAPIImpl) RemoveTrustedPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.SentryAdmin().RemoveTrustedPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PeerScores implements admin_peerScores. The peers are identified by their public key, or by their enode URL.
+func (api *AdminAPIImpl) PeerScores(ctx context.Context) ([]sentryadmin.PeerReputation, error) {
+	return api.ethBackend.SentryAdmin().PeerScores(ctx)
+}
+
+// AdjustPeerScore implements admin_adjustPeerScore.
+func (api *AdminAPIImpl) AdjustPeerScore(ctx context.Context, peer string, delta int) (*sentryadmin.PeerReputation, error) {
+	return api.ethBackend.SentryAdmin().AdjustPeerScore(ctx, peer, delta)
+}
+
+// UnbanPeer implements admin_unbanPeer.
+func (api *AdminAPIImpl) UnbanPeer(ctx context.Context, peer string) (*sentryadmin.PeerReputation, error) {
+	return api.ethBackend.SentryAdmin().UnbanPeer(ctx, peer)
+}
+
+var errTraceCacheDisabled = errors.New("the trace cache is disabled, see --trace.cache.size")
+
+// TraceCacheStatus implements admin_traceCacheStatus.
+func (api *AdminAPIImpl) TraceCacheStatus(_ context.Context) (*TraceCacheStatus, error) {
+	if api.traceCache == nil {
+		return nil, errTraceCacheDisabled
+	}
+	return api.traceCache.status(), nil
+}
+
+// FlushTraceCache implements admin_flushTraceCache.
+func (api *AdminAPIImpl) FlushTraceCache(_ context.Context) (hexutil.Uint64, error) {
+	if api.traceCache == nil {
+		return 0, errTraceCacheDisabled
+	}
+	return hexutil.Uint64(api.traceCache.flush()), nil
+}
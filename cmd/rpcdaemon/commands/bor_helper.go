@@ -41,8 +41,20 @@ var (
 	// errMissingVanity is returned if a block's extra-data section is shorter than
 	// 32 bytes, which is required to store the signer vanity.
 	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errNoBorDb is returned when the bor namespace is served without access to the consensus db,
+	// e.g. by an rpcdaemon connected to a remote Erigon without --datadir
+	errNoBorDb = errors.New("bor consensus db is not available, run rpcdaemon with --datadir")
 )
 
+// beginBorTx opens a read-only transaction in the consensus db, where the validator snapshots are stored.
+func (api *BorImpl) beginBorTx(ctx context.Context) (kv.Tx, error) {
+	if api.borDb == nil {
+		return nil, errNoBorDb
+	}
+	return api.borDb.BeginRo(ctx)
+}
+
 // getHeaderByNumber returns a block's header given a block number ignoring the block's transaction and uncle list (may be faster).
 // derived from erigon_getHeaderByNumber implementation (see ./erigon_block.go)
 func getHeaderByNumber(ctx context.Context, number rpc.BlockNumber, api *BorImpl, tx kv.Tx) (*types.Header, error) {
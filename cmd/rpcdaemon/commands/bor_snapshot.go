@@ -53,7 +53,7 @@ func (api *BorImpl) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	}
 
 	// init consensus db
-	borTx, err := api.borDb.BeginRo(ctx)
+	borTx, err := api.beginBorTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +82,10 @@ func (api *BorImpl) GetAuthor(number *rpc.BlockNumber) (*common.Address, error)
 		return nil, errUnknownBlock
 	}
 	author, err := author(api, tx, header)
-	return &author, err
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
 }
 
 // GetSnapshotAtHash retrieves the state snapshot at a given block.
@@ -104,7 +107,7 @@ func (api *BorImpl) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
 	}
 
 	// init consensus db
-	borTx, err := api.borDb.BeginRo(ctx)
+	borTx, err := api.beginBorTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -135,13 +138,16 @@ func (api *BorImpl) GetSigners(number *rpc.BlockNumber) ([]common.Address, error
 	}
 
 	// init consensus db
-	borTx, err := api.borDb.BeginRo(ctx)
+	borTx, err := api.beginBorTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer borTx.Rollback()
 	snap, err := snapshot(ctx, api, tx, borTx, header)
-	return snap.signers(), err
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
 }
 
 // GetSignersAtHash retrieves the list of authorized signers at the specified block.
@@ -163,14 +169,17 @@ func (api *BorImpl) GetSignersAtHash(hash common.Hash) ([]common.Address, error)
 	}
 
 	// init consensus db
-	borTx, err := api.borDb.BeginRo(ctx)
+	borTx, err := api.beginBorTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer borTx.Rollback()
 
 	snap, err := snapshot(ctx, api, tx, borTx, header)
-	return snap.signers(), err
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
 }
 
 // GetCurrentProposer gets the current proposer
@@ -193,10 +202,6 @@ func (api *BorImpl) GetCurrentValidators() ([]*bor.Validator, error) {
 
 // GetRootHash returns the merkle root of the start to end block headers
 func (api *BorImpl) GetRootHash(start, end uint64) (string, error) {
-	length := uint64(end - start + 1)
-	if length > bor.MaxCheckpointLength {
-		return "", &bor.MaxCheckpointLengthExceededError{Start: start, End: end}
-	}
 	ctx := context.Background()
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -205,16 +210,25 @@ func (api *BorImpl) GetRootHash(start, end uint64) (string, error) {
 	defer tx.Rollback()
 	header := rawdb.ReadCurrentHeader(tx)
 	var currentHeaderNumber uint64 = 0
-	if header == nil {
-		return "", &bor.InvalidStartEndBlockError{Start: start, End: end, CurrentHeader: currentHeaderNumber}
+	if header != nil {
+		currentHeaderNumber = header.Number.Uint64()
 	}
-	currentHeaderNumber = header.Number.Uint64()
-	if start > end || end > currentHeaderNumber {
+	// check the order before the length, end - start would wrap around otherwise
+	if header == nil || start > end || end > currentHeaderNumber {
 		return "", &bor.InvalidStartEndBlockError{Start: start, End: end, CurrentHeader: currentHeaderNumber}
 	}
-	blockHeaders := make([]*types.Header, end-start+1)
+	length := end - start + 1
+	if length > bor.MaxCheckpointLength {
+		return "", &bor.MaxCheckpointLengthExceededError{Start: start, End: end}
+	}
+	blockHeaders := make([]*types.Header, length)
 	for number := start; number <= end; number++ {
-		blockHeaders[number-start], _ = getHeaderByNumber(ctx, rpc.BlockNumber(number), api, tx)
+		if blockHeaders[number-start], err = getHeaderByNumber(ctx, rpc.BlockNumber(number), api, tx); err != nil {
+			return "", err
+		}
+		if blockHeaders[number-start] == nil {
+			return "", fmt.Errorf("header %d not found", number)
+		}
 	}
 
 	headers := make([][32]byte, bor.NextPowerOfTwo(length))
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/consensus/bor"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBorGetRootHash(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	api := NewBorAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), db, nil)
+
+	root, err := api.GetRootHash(1, 5)
+	require.NoError(t, err)
+	require.Len(t, root, 64)
+	again, err := api.GetRootHash(1, 5)
+	require.NoError(t, err)
+	require.Equal(t, root, again)
+	other, err := api.GetRootHash(2, 5)
+	require.NoError(t, err)
+	require.NotEqual(t, root, other)
+
+	var rangeErr *bor.InvalidStartEndBlockError
+	_, err = api.GetRootHash(5, 1)
+	require.ErrorAs(t, err, &rangeErr)
+	_, err = api.GetRootHash(1, 1_000_000)
+	require.ErrorAs(t, err, &rangeErr)
+}
+
+func TestBorWithoutConsensusDb(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	api := NewBorAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), db, nil)
+
+	latest := rpc.LatestBlockNumber
+	_, err := api.GetSnapshot(&latest)
+	require.ErrorIs(t, err, errNoBorDb)
+	_, err = api.GetSigners(nil)
+	require.ErrorIs(t, err, errNoBorDb)
+	_, err = api.GetCurrentValidators()
+	require.ErrorIs(t, err, errNoBorDb)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,8 @@ import (
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
+	stagedsync_stages "github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
@@ -263,3 +266,91 @@ func TestFilterAddressIntersection(t *testing.T) {
 		require.Empty(t, blockNumbersFromTraces(t, stream.Buffer()))
 	})
 }
+
+func TestFilterNotIndexed(t *testing.T) {
+	m := stages.Mock(t)
+	defer m.DB.Close()
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 10, func(i int, gen *core.BlockGen) {
+		if i < 5 {
+			gen.SetCoinbase(common.Address{1})
+		} else {
+			gen.SetCoinbase(common.Address{2})
+		}
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatalf("generate chain: %v", err)
+	}
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), m.DB, &httpcfg.HttpCfg{})
+	if err = m.InsertChain(chain); err != nil {
+		t.Fatalf("inserting chain: %v", err)
+	}
+	// The index lags behind the execution: the blocks it doesn't cover yet are replayed
+	require.NoError(t, m.DB.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := tx.ClearBucket(kv.CallFromIndex); err != nil {
+			return err
+		}
+		if err := tx.ClearBucket(kv.CallToIndex); err != nil {
+			return err
+		}
+		return stagedsync_stages.SaveStageProgress(tx, stagedsync_stages.CallTraces, 5)
+	}))
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	var fromBlock, toBlock uint64
+	fromBlock = 1
+	toBlock = 10
+	toAddress2 := common.Address{2}
+	traceReq1 := TraceFilterRequest{
+		FromBlock: (*hexutil.Uint64)(&fromBlock),
+		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		ToAddress: []*common.Address{&toAddress2},
+	}
+	if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
+		t.Fatalf("trace_filter failed: %v", err)
+	}
+	require.NoError(t, stream.Flush())
+	assert.Equal(t, []int{6, 7, 8, 9, 10}, blockNumbersFromTraces(t, buf.Bytes()))
+}
+
+func TestFilterPruned(t *testing.T) {
+	m := stages.Mock(t)
+	defer m.DB.Close()
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 10, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatalf("generate chain: %v", err)
+	}
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), m.DB, &httpcfg.HttpCfg{})
+	if err = m.InsertChain(chain); err != nil {
+		t.Fatalf("inserting chain: %v", err)
+	}
+	require.NoError(t, m.DB.Update(context.Background(), func(tx kv.RwTx) error {
+		return prune.SetPrunedTo(tx, prune.KindCallTraces, 5)
+	}))
+	toAddress1 := common.Address{1}
+	filter := func(fromBlock *hexutil.Uint64, toBlock uint64) ([]int, error) {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		req := TraceFilterRequest{
+			FromBlock: fromBlock,
+			ToBlock:   (*hexutil.Uint64)(&toBlock),
+			ToAddress: []*common.Address{&toAddress1},
+		}
+		if err := api.Filter(context.Background(), req, stream); err != nil {
+			return nil, err
+		}
+		require.NoError(t, stream.Flush())
+		return blockNumbersFromTraces(t, buf.Bytes()), nil
+	}
+	// The blocks of which the index was pruned aren't replayed: refused if asked for, skipped by default
+	_, err = filter((*hexutil.Uint64)(new(uint64)), 10)
+	require.ErrorContains(t, err, "call traces of block 0 are pruned, available from block 5")
+	from := hexutil.Uint64(5)
+	blocks, err := filter(&from, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 6, 7, 8, 9, 10}, blocks)
+	blocks, err = filter(nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 6, 7, 8, 9, 10}, blocks)
+}
@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// errNoCliqueEngine is returned when the clique namespace is served without access to the running engine,
+// e.g. by a standalone rpcdaemon
+var errNoCliqueEngine = errors.New("clique engine is not available, enable the clique API of the embedded RPC server")
+
+// CliqueAPI clique specific routines
+type CliqueAPI interface {
+	GetSnapshot(ctx context.Context, number *rpc.BlockNumber) (*clique.Snapshot, error)
+	GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*clique.Snapshot, error)
+	GetSigners(ctx context.Context, number *rpc.BlockNumber) ([]common.Address, error)
+	GetSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error)
+	Proposals(ctx context.Context) (map[common.Address]bool, error)
+	Propose(ctx context.Context, address common.Address, auth bool) error
+	Discard(ctx context.Context, address common.Address) error
+	Status(ctx context.Context) (*CliqueStatus, error)
+}
+
+// CliqueImpl is implementation of the CliqueAPI interface
+type CliqueImpl struct {
+	*BaseAPI
+	db     kv.RoDB
+	engine *clique.Clique
+}
+
+// NewCliqueAPI returns CliqueImpl instance
+func NewCliqueAPI(base *BaseAPI, db kv.RoDB, engine *clique.Clique) *CliqueImpl {
+	return &CliqueImpl{
+		BaseAPI: base,
+		db:      db,
+		engine:  engine,
+	}
+}
+
+// CliqueStatus is the signing activity of the last blocks
+type CliqueStatus struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"`
+	NumBlocks     uint64                 `json:"numBlocks"`
+}
+
+// GetSnapshot retrieves the voting snapshot at a given block (or the latest one if not specified).
+func (api *CliqueImpl) GetSnapshot(ctx context.Context, number *rpc.BlockNumber) (*clique.Snapshot, error) {
+	var snap *clique.Snapshot
+	err := api.withHeaderReader(ctx, func(chain *cliqueHeaderReader) (err error) {
+		snap, err = api.snapshotByNumber(chain, number)
+		return err
+	})
+	return snap, err
+}
+
+// GetSnapshotAtHash retrieves the voting snapshot at a given block.
+func (api *CliqueImpl) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*clique.Snapshot, error) {
+	var snap *clique.Snapshot
+	err := api.withHeaderReader(ctx, func(chain *cliqueHeaderReader) (err error) {
+		snap, err = api.snapshotByHash(chain, hash)
+		return err
+	})
+	return snap, err
+}
+
+// GetSigners retrieves the list of authorized signers at the specified block (or the latest one if not specified).
+func (api *CliqueImpl) GetSigners(ctx context.Context, number *rpc.BlockNumber) ([]common.Address, error) {
+	snap, err := api.GetSnapshot(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.GetSigners(), nil
+}
+
+// GetSignersAtHash retrieves the list of authorized signers at the specified block.
+func (api *CliqueImpl) GetSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	snap, err := api.GetSnapshotAtHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return snap.GetSigners(), nil
+}
+
+// Proposals returns the current proposals the node tries to uphold and vote on.
+func (api *CliqueImpl) Proposals(_ context.Context) (map[common.Address]bool, error) {
+	if api.engine == nil {
+		return nil, errNoCliqueEngine
+	}
+	return api.engine.Proposals(), nil
+}
+
+// Propose injects a new authorization proposal that the signer will attempt to push through.
+func (api *CliqueImpl) Propose(_ context.Context, address common.Address, auth bool) error {
+	if api.engine == nil {
+		return errNoCliqueEngine
+	}
+	api.engine.Propose(address, auth)
+	return nil
+}
+
+// Discard drops a currently running proposal, stopping the signer from casting further votes.
+func (api *CliqueImpl) Discard(_ context.Context, address common.Address) error {
+	if api.engine == nil {
+		return errNoCliqueEngine
+	}
+	api.engine.Discard(address)
+	return nil
+}
+
+// Status returns the signing activity of the last 64 blocks: how many blocks each
+// of the signers sealed and the percentage of in-turn blocks.
+func (api *CliqueImpl) Status(ctx context.Context) (*CliqueStatus, error) {
+	var status *CliqueStatus
+	err := api.withHeaderReader(ctx, func(chain *cliqueHeaderReader) error {
+		header := chain.CurrentHeader()
+		if header == nil {
+			return errUnknownBlock
+		}
+		snap, err := api.engine.Snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+		if err != nil {
+			return err
+		}
+		numBlocks := uint64(64)
+		end := header.Number.Uint64()
+		start := end - numBlocks
+		if numBlocks > end {
+			// the genesis block isn't sealed, the status of a chain without other blocks is empty
+			start, numBlocks = 1, 0
+			if end > start {
+				numBlocks = end - start
+			}
+		}
+		signStatus := make(map[common.Address]int)
+		for _, s := range snap.GetSigners() {
+			signStatus[s] = 0
+		}
+		optimals := 0
+		for n := start; n < end; n++ {
+			h := chain.GetHeaderByNumber(n)
+			if h == nil {
+				return fmt.Errorf("missing block %d", n)
+			}
+			if h.Difficulty.Cmp(clique.DiffInTurn) == 0 {
+				optimals++
+			}
+			sealer, err := api.engine.Author(h)
+			if err != nil {
+				return err
+			}
+			signStatus[sealer]++
+		}
+		status = &CliqueStatus{SigningStatus: signStatus, NumBlocks: numBlocks}
+		if numBlocks > 0 {
+			status.InturnPercent = float64(100*optimals) / float64(numBlocks)
+		}
+		return nil
+	})
+	return status, err
+}
+
+func (api *CliqueImpl) withHeaderReader(ctx context.Context, f func(chain *cliqueHeaderReader) error) error {
+	if api.engine == nil {
+		return errNoCliqueEngine
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+	if chainConfig.Clique == nil {
+		return fmt.Errorf("chain %s doesn't use clique", chainConfig.ChainName)
+	}
+	return f(&cliqueHeaderReader{ctx: ctx, tx: tx, config: chainConfig, blockReader: api._blockReader})
+}
+
+func (api *CliqueImpl) snapshotByNumber(chain *cliqueHeaderReader, number *rpc.BlockNumber) (*clique.Snapshot, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = chain.CurrentHeader()
+	} else {
+		var err error
+		if header, err = api.headerByRPCNumber(*number, chain.tx); err != nil {
+			return nil, err
+		}
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.engine.Snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+func (api *CliqueImpl) snapshotByHash(chain *cliqueHeaderReader, hash common.Hash) (*clique.Snapshot, error) {
+	header := chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.engine.Snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// cliqueHeaderReader gives the engine access to the headers, including the ones in the block snapshots,
+// to rebuild the voting snapshots.
+type cliqueHeaderReader struct {
+	ctx         context.Context
+	tx          kv.Tx
+	config      *params.ChainConfig
+	blockReader services.FullBlockReader
+}
+
+func (r *cliqueHeaderReader) Config() *params.ChainConfig { return r.config }
+
+func (r *cliqueHeaderReader) CurrentHeader() *types.Header { return rawdb.ReadCurrentHeader(r.tx) }
+
+func (r *cliqueHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := r.blockReader.Header(r.ctx, r.tx, hash, number)
+	if err != nil {
+		log.Warn("[rpc] clique: reading header", "number", number, "err", err)
+	}
+	return header
+}
+
+func (r *cliqueHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	header, err := r.blockReader.HeaderByNumber(r.ctx, r.tx, number)
+	if err != nil {
+		log.Warn("[rpc] clique: reading header", "number", number, "err", err)
+	}
+	return header
+}
+
+func (r *cliqueHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, err := r.blockReader.HeaderByHash(r.ctx, r.tx, hash)
+	if err != nil {
+		log.Warn("[rpc] clique: reading header", "hash", hash, "err", err)
+	}
+	return header
+}
+
+func (r *cliqueHeaderReader) GetTd(hash common.Hash, number uint64) *big.Int {
+	td, err := rawdb.ReadTd(r.tx, hash, number)
+	if err != nil {
+		log.Warn("[rpc] clique: reading total difficulty", "number", number, "err", err)
+	}
+	return td
+}
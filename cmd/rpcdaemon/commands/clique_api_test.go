@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliqueStatusAtGenesis(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	engine := clique.New(params.AllCliqueProtocolChanges, params.CliqueSnapshot, memdb.NewTestDB(t))
+	genspec := &core.Genesis{
+		ExtraData: make([]byte, clique.ExtraVanity+common.AddressLength+clique.ExtraSeal),
+		Config:    params.AllCliqueProtocolChanges,
+	}
+	copy(genspec.ExtraData[clique.ExtraVanity:], addr[:])
+	m := stages.MockWithGenesisEngine(t, genspec, engine)
+
+	api := NewCliqueAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), m.DB, engine)
+	status, err := api.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), status.NumBlocks)
+	require.Equal(t, map[common.Address]int{addr: 0}, status.SigningStatus)
+}
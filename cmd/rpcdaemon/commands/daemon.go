@@ -6,34 +6,61 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
 )
 
-// APIList describes the list of available RPC apis
-func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
+// APIList describes the list of available RPC apis. The engine and the dev chain are only known to the embedded RPC server,
+// they're nil for the standalone rpcdaemon.
+func APIList(db kv.RoDB, borDb kv.RoDB, engine consensus.Engine, devChain DevChain, stateOverlay rpchelper.StateOverlay, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	starknet starknet.CAIROVMClient, filters *rpchelper.Filters, stateCache kvcache.Cache,
 	blockReader services.FullBlockReader, cfg httpcfg.HttpCfg) (list []rpc.API) {
 
+	// the reads of the database are counted in the stats of the requests
+	db = rpchelper.NewStatsDB(db)
 	base := NewBaseApi(filters, stateCache, blockReader, cfg.WithDatadir)
 	if cfg.TevmEnabled {
 		base.EnableTevmExperiment()
 	}
+	if stateOverlay != nil {
+		base.SetStateOverlay(stateOverlay)
+	}
 	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl.GPO = cfg.GPO
+	ethImpl.PendingTxsRate = cfg.WebsocketPendingTxsRate
 	erigonImpl := NewErigonAPI(base, db, eth)
+	if cfg.WithDatadir {
+		ethImpl.tmpDir = cfg.Dirs.Tmp
+		erigonImpl.snapDir = cfg.Dirs.Snap
+	}
 	starknetImpl := NewStarknetAPI(base, db, starknet, txPool)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
+	erigonImpl.health = health.NewChecker(cfg.Health, netImpl, ethImpl)
 	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
 	traceImpl := NewTraceAPI(base, db, &cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
 	engineImpl := NewEngineAPI(base, db, eth)
 	adminImpl := NewAdminAPI(eth)
+	if cfg.TraceCacheSize > 0 {
+		if cache, err := newTraceCache(cfg); err != nil {
+			log.Warn("The trace cache is disabled", "err", err)
+		} else {
+			debugImpl.traceCache, adminImpl.traceCache = cache, cache
+		}
+	}
 	parityImpl := NewParityAPIImpl(db)
 	borImpl := NewBorAPI(base, db, borDb) // bor (consensus) specific
 	otsImpl := NewOtterscanAPI(base, db)
+	cliqueEngine, _ := engine.(*clique.Clique)
+	cliqueImpl := NewCliqueAPI(base, db, cliqueEngine) // clique (consensus) specific
+	evmImpl := NewEvmAPI(devChain)                     // dev chain specific
 
 	for _, enabledAPI := range cfg.API {
 		switch enabledAPI {
@@ -114,6 +141,20 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 				Service:   BorAPI(borImpl),
 				Version:   "1.0",
 			})
+		case "clique":
+			list = append(list, rpc.API{
+				Namespace: "clique",
+				Public:    false,
+				Service:   CliqueAPI(cliqueImpl),
+				Version:   "1.0",
+			})
+		case "evm":
+			list = append(list, rpc.API{
+				Namespace: "evm",
+				Public:    false,
+				Service:   EvmAPI(evmImpl),
+				Version:   "1.0",
+			})
 		case "admin":
 			list = append(list, rpc.API{
 				Namespace: "admin",
@@ -137,6 +178,15 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 			})
 		}
 	}
+	if cfg.GraphQLEnabled {
+		// served over HTTP by StartRpcServer, not registered as RPC methods
+		list = append(list, rpc.API{
+			Namespace: "graphql",
+			Public:    true,
+			Service:   ethImpl.GraphQLHandler(),
+			Version:   "1.0",
+		})
+	}
 
 	return list
 }
@@ -4,16 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"math/big"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/eth/tracers"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
 )
 
 var debugTraceTransactionTests = []struct {
@@ -183,3 +190,153 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 		}
 	}
 }
+
+func TestGetBlockWitness(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000)
+	api := NewPrivateDebugAPI(baseApi, db, 0)
+	latest, err := ethApi.BlockNumber(context.Background())
+	require.NoError(t, err)
+	// the latest block needs the trie of its parent to be unwound in memory, the older ones more
+	for _, number := range []rpc.BlockNumber{rpc.BlockNumber(latest), 3, 1} {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		require.NoError(t, api.GetBlockWitness(context.Background(), rpc.BlockNumberOrHashWithNumber(number), stream))
+		var result hexutil.Bytes
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+
+		witness, err := trie.NewWitnessFromReader(bytes.NewReader(result), false)
+		require.NoError(t, err)
+		witnessTrie, err := trie.BuildTrieFromWitness(witness, false)
+		require.NoError(t, err)
+		parent, err := ethApi.GetBlockByNumber(context.Background(), number-1, false)
+		require.NoError(t, err)
+		require.Equal(t, parent["stateRoot"], witnessTrie.Hash(), "block %d", number)
+		var leaves int
+		for _, op := range witness.Operators {
+			switch op.(type) {
+			case *trie.OperatorLeafAccount, *trie.OperatorLeafValue:
+				leaves++
+			}
+		}
+		require.NotZero(t, leaves) // at least the sender and the coinbase
+	}
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.Error(t, api.GetBlockWitness(context.Background(), rpc.BlockNumberOrHashWithNumber(0), stream))
+}
+
+func TestTraceCallOverrides(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, 0)
+
+	// The contract returns COINBASE, NUMBER, TIMESTAMP and DIFFICULTY (the test chain has no BASEFEE opcode)
+	contract := common.Address{0xcc}
+	code := hexutil.Bytes(common.FromHex("0x4160005243602052426040524460605260806000f3"))
+	stateOverrides := ethapi.StateOverrides{contract: ethapi.Account{Code: &code}}
+	coinbase := common.Address{0xbb}
+	number, time, baseFee, difficulty := hexutil.Big(*big.NewInt(1000)), hexutil.Uint64(2000), hexutil.Big(*big.NewInt(7)), hexutil.Big(*big.NewInt(3000))
+	config := &tracers.TraceConfig{
+		StateOverrides: &stateOverrides,
+		BlockOverrides: &ethapi.BlockOverrides{Number: &number, Time: &time, Coinbase: &coinbase, BaseFee: &baseFee, Difficulty: &difficulty},
+	}
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	err := api.TraceCall(context.Background(), ethapi.CallArgs{To: &contract}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config, stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Flush())
+	var res struct {
+		Failed      bool
+		ReturnValue string
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	require.False(t, res.Failed)
+	var expected []byte
+	for _, v := range []*big.Int{new(big.Int).SetBytes(coinbase.Bytes()), big.NewInt(1000), big.NewInt(2000), big.NewInt(3000)} {
+		expected = append(expected, common.LeftPadBytes(v.Bytes(), 32)...)
+	}
+	require.Equal(t, common.Bytes2Hex(expected), res.ReturnValue)
+}
+
+func TestTraceTransactionJSTracer(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, 0)
+	tracer := `{steps: 0, step: function() { this.steps++; }, fault: function() {},
+		result: function(ctx) { return {steps: this.steps, type: ctx.type, output: toHex(ctx.output)}; }}`
+	for _, tt := range debugTraceTransactionTests {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		err := api.TraceTransaction(context.Background(), common.HexToHash(tt.txHash), &tracers.TraceConfig{Tracer: &tracer}, stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Flush())
+		var res struct {
+			Steps  int
+			Type   string
+			Output string
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+		require.Equal(t, "CALL", res.Type)
+		require.Equal(t, "0x"+tt.returnValue, res.Output)
+		require.Equal(t, tt.gas == params.TxGas, res.Steps == 0) // the plain transfers execute no code
+	}
+}
+
+func TestStorageRangeAtPaging(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, 0)
+	ctx := context.Background()
+
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	// the contract with the most storage slots
+	slots := map[common.Address]int{}
+	var contract common.Address
+	require.NoError(t, tx.ForEach(kv.PlainState, nil, func(k, v []byte) error {
+		if len(k) == common.AddressLength+common.IncarnationLength+common.HashLength {
+			addr := common.BytesToAddress(k[:common.AddressLength])
+			slots[addr]++
+			if slots[addr] > slots[contract] {
+				contract = addr
+			}
+		}
+		return nil
+	}))
+	require.Greater(t, slots[contract], 1)
+	var hashes []common.Hash
+	for _, blockNum := range []uint64{5, 10} {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+	}
+	tx.Rollback()
+
+	for _, hash := range hashes {
+		all, err := api.StorageRangeAt(ctx, hash, 0, contract, nil, 1000)
+		require.NoError(t, err)
+		require.Nil(t, all.NextKey)
+
+		paged := StorageMap{}
+		var start hexutil.Bytes
+		for {
+			page, err := api.StorageRangeAt(ctx, hash, 0, contract, start, 1)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Storage), 1)
+			for k, v := range page.Storage {
+				paged[k] = v
+			}
+			if page.NextKey == nil {
+				break
+			}
+			start = page.NextKey.Bytes()
+		}
+		require.Equal(t, all.Storage, paged, "block %x", hash)
+	}
+}
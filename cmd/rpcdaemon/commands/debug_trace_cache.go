@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/c2h5oh/datasize"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// TraceCacheStatus is the state of the cache of the debug_trace* outputs.
+type TraceCacheStatus struct {
+	Dir         string         `json:"dir"`
+	Entries     hexutil.Uint64 `json:"entries"`
+	Size        hexutil.Uint64 `json:"size"`
+	Limit       hexutil.Uint64 `json:"limit"`
+	Hits        hexutil.Uint64 `json:"hits"`
+	Misses      hexutil.Uint64 `json:"misses"`
+	Invalidated hexutil.Uint64 `json:"invalidated"`
+}
+
+// traceCache keeps the outputs of the debug_trace* methods in files, named by the number and the hash of the block and
+// a hash of the method and the tracer config, so the traces are deterministic. Only the traces of the canonical
+// blocks are kept, the ones of the blocks removed from the canonical chain by a reorg are removed once they're
+// looked up, and the least recently used ones are removed once the cache is over its limit.
+type traceCache struct {
+	dir   string
+	limit datasize.ByteSize
+
+	lock    sync.Mutex
+	entries map[string]*traceCacheEntry            // by file name
+	blocks  map[uint64]map[string]*traceCacheEntry // by block number and file name
+	lru     *list.List                             // of the entries, the least recently used at the front
+	size    uint64
+
+	hits, misses, invalidated uint64
+}
+
+type traceCacheEntry struct {
+	name   string
+	number uint64
+	hash   common.Hash
+	size   uint64
+	elem   *list.Element
+}
+
+// newTraceCache opens the cache of the config, in the datadir unless its directory is set
+func newTraceCache(cfg httpcfg.HttpCfg) (*traceCache, error) {
+	dir := cfg.TraceCacheDir
+	if dir == "" {
+		if cfg.Dirs.DataDir == "" {
+			return nil, errors.New("the trace cache needs --trace.cache.dir without --datadir")
+		}
+		dir = filepath.Join(cfg.Dirs.DataDir, "tracecache")
+	}
+	return openTraceCache(dir, cfg.TraceCacheSize)
+}
+
+// openTraceCache opens the cache in dir, and loads the entries it has from a previous run
+func openTraceCache(dir string, limit datasize.ByteSize) (*traceCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type loaded struct {
+		entry   *traceCacheEntry
+		modTime int64
+	}
+	var entries []loaded
+	for _, file := range files {
+		number, hash, ok := parseTraceCacheName(file.Name())
+		info, err := file.Info()
+		if !ok || err != nil || !info.Mode().IsRegular() {
+			// the leftovers of the writes interrupted by a crash
+			_ = os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		entries = append(entries, loaded{
+			entry:   &traceCacheEntry{name: file.Name(), number: number, hash: hash, size: uint64(info.Size())},
+			modTime: info.ModTime().UnixNano(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime < entries[j].modTime })
+
+	c := &traceCache{
+		dir:     dir,
+		limit:   limit,
+		entries: map[string]*traceCacheEntry{},
+		blocks:  map[uint64]map[string]*traceCacheEntry{},
+		lru:     list.New(),
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, l := range entries {
+		c.add(l.entry)
+	}
+	c.evict()
+	return c, nil
+}
+
+// traceCacheKey is a hash of the method, the transaction of the block if any, and the tracer config
+func traceCacheKey(method string, txIndex int, config *tracers.TraceConfig) (string, error) {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(txIndex)))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+func traceCacheName(number uint64, hash common.Hash, key string) string {
+	return fmt.Sprintf("%d-%x-%s", number, hash, key)
+}
+
+func parseTraceCacheName(name string) (number uint64, hash common.Hash, ok bool) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 || len(parts[1]) != 2*common.HashLength || len(parts[2]) != 32 {
+		return 0, common.Hash{}, false
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, common.Hash{}, false
+	}
+	b, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return 0, common.Hash{}, false
+	}
+	return number, common.BytesToHash(b), true
+}
+
+// trace writes the trace of the block by the method, of the transaction at txIndex if any, to the stream from the
+// cache, or writes it by trace and keeps it unless it's incomplete. The traces of the blocks which aren't canonical
+// aren't kept. A nil cache always traces.
+func (c *traceCache) trace(tx kv.Tx, number uint64, hash common.Hash, method string, txIndex int, config *tracers.TraceConfig, stream *jsoniter.Stream, trace func(stream *jsoniter.Stream) (complete bool, err error)) error {
+	if c == nil {
+		_, err := trace(stream)
+		return err
+	}
+	key, err := traceCacheKey(method, txIndex, config)
+	if err != nil {
+		return err
+	}
+	canonical, err := rawdb.ReadCanonicalHash(tx, number)
+	if err != nil {
+		return err
+	}
+	c.invalidate(number, canonical)
+	if canonical != hash {
+		_, err := trace(stream)
+		return err
+	}
+
+	name := traceCacheName(number, hash, key)
+	if data, ok := c.get(name); ok {
+		if _, err := stream.Write(data); err != nil {
+			return err
+		}
+		return stream.Flush()
+	}
+
+	// the trace is streamed as it's written, and kept in memory unless it's too large to be kept
+	out := &traceCacheBuffer{stream: stream, limit: c.limit.Bytes() / 4}
+	tee := jsoniter.NewStream(jsoniter.ConfigDefault, out, 4096)
+	complete, err := trace(tee)
+	if flushErr := tee.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil || !complete || out.overflow {
+		return err
+	}
+	if err := c.put(name, number, hash, out.data); err != nil {
+		log.Warn("Failed to keep the trace in the cache", "block", number, "err", err)
+	}
+	return nil
+}
+
+func (c *traceCache) get(name string) ([]byte, bool) {
+	c.lock.Lock()
+	entry, ok := c.entries[name]
+	if ok {
+		c.lru.MoveToBack(entry.elem)
+	}
+	c.lock.Unlock()
+	if ok {
+		// the entry may be removed meanwhile
+		if data, err := os.ReadFile(filepath.Join(c.dir, name)); err == nil {
+			c.lock.Lock()
+			c.hits++
+			c.lock.Unlock()
+			return data, true
+		}
+	}
+	c.lock.Lock()
+	c.misses++
+	c.lock.Unlock()
+	return nil, false
+}
+
+func (c *traceCache) put(name string, number uint64, hash common.Hash, data []byte) error {
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[name]; ok {
+		return nil
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+	c.add(&traceCacheEntry{name: name, number: number, hash: hash, size: uint64(len(data))})
+	c.evict()
+	return nil
+}
+
+// invalidate removes the traces of the blocks with the number but not the canonical hash
+func (c *traceCache) invalidate(number uint64, canonical common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, entry := range c.blocks[number] {
+		if entry.hash != canonical {
+			c.remove(entry)
+			c.invalidated++
+		}
+	}
+}
+
+// flush removes all the traces, and returns their number
+func (c *traceCache) flush() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	n := len(c.entries)
+	for _, entry := range c.entries {
+		c.remove(entry)
+	}
+	return n
+}
+
+func (c *traceCache) status() *TraceCacheStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &TraceCacheStatus{
+		Dir:         c.dir,
+		Entries:     hexutil.Uint64(len(c.entries)),
+		Size:        hexutil.Uint64(c.size),
+		Limit:       hexutil.Uint64(c.limit.Bytes()),
+		Hits:        hexutil.Uint64(c.hits),
+		Misses:      hexutil.Uint64(c.misses),
+		Invalidated: hexutil.Uint64(c.invalidated),
+	}
+}
+
+func (c *traceCache) add(entry *traceCacheEntry) {
+	entry.elem = c.lru.PushBack(entry)
+	c.entries[entry.name] = entry
+	if c.blocks[entry.number] == nil {
+		c.blocks[entry.number] = map[string]*traceCacheEntry{}
+	}
+	c.blocks[entry.number][entry.name] = entry
+	c.size += entry.size
+}
+
+func (c *traceCache) remove(entry *traceCacheEntry) {
+	if err := os.Remove(filepath.Join(c.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to remove the trace from the cache", "file", entry.name, "err", err)
+	}
+	c.lru.Remove(entry.elem)
+	delete(c.entries, entry.name)
+	delete(c.blocks[entry.number], entry.name)
+	if len(c.blocks[entry.number]) == 0 {
+		delete(c.blocks, entry.number)
+	}
+	c.size -= entry.size
+}
+
+// evict removes the least recently used traces until the cache is within its limit
+func (c *traceCache) evict() {
+	for c.size > c.limit.Bytes() && c.lru.Len() > 0 {
+		c.remove(c.lru.Front().Value.(*traceCacheEntry))
+	}
+}
+
+// traceCacheBuffer writes to the stream, and keeps what's written up to the limit
+type traceCacheBuffer struct {
+	stream   *jsoniter.Stream
+	limit    uint64
+	data     []byte
+	overflow bool
+}
+
+func (b *traceCacheBuffer) Write(p []byte) (int, error) {
+	// the stream counts the bytes it had buffered before too
+	if _, err := b.stream.Write(p); err != nil {
+		return 0, err
+	}
+	if !b.overflow {
+		if uint64(len(b.data)+len(p)) > b.limit {
+			b.overflow, b.data = true, nil
+		} else {
+			b.data = append(b.data, p...)
+		}
+	}
+	return len(p), nil
+}
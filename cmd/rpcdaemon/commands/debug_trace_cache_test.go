@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceCache(t *testing.T) {
+	ctx := context.Background()
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, 0)
+	txHash := common.HexToHash(debugTraceTransactionTests[2].txHash)
+
+	traceTx := func(config *tracers.TraceConfig) []byte {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		require.NoError(t, api.TraceTransaction(ctx, txHash, config, stream))
+		require.NoError(t, stream.Flush())
+		return buf.Bytes()
+	}
+	traceBlock := func(number rpc.BlockNumber) []byte {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		// the trace follows what the server has written to the stream already
+		stream.WriteObjectStart()
+		stream.WriteObjectField("result")
+		require.NoError(t, api.TraceBlockByNumber(ctx, number, &tracers.TraceConfig{}, stream))
+		stream.WriteObjectEnd()
+		require.NoError(t, stream.Flush())
+		return buf.Bytes()
+	}
+	uncached, uncachedBlock := traceTx(&tracers.TraceConfig{}), traceBlock(1)
+
+	dir := t.TempDir()
+	cache, err := openTraceCache(dir, datasize.MB)
+	require.NoError(t, err)
+	api.traceCache = cache
+
+	require.Equal(t, uncached, traceTx(&tracers.TraceConfig{}))
+	require.Equal(t, uncached, traceTx(&tracers.TraceConfig{}))
+	require.Equal(t, uncachedBlock, traceBlock(1))
+	require.Equal(t, uncachedBlock, traceBlock(1))
+	status := cache.status()
+	require.Equal(t, hexutil.Uint64(2), status.Entries)
+	require.Equal(t, hexutil.Uint64(2), status.Hits)
+	require.Equal(t, hexutil.Uint64(2), status.Misses)
+
+	// the tracer config is a part of the key
+	noRefunds := true
+	require.NotEqual(t, uncached, traceTx(&tracers.TraceConfig{NoRefunds: &noRefunds}))
+	require.Equal(t, hexutil.Uint64(3), cache.status().Entries)
+
+	// the traces are kept across restarts
+	cache, err = openTraceCache(dir, datasize.MB)
+	require.NoError(t, err)
+	api.traceCache = cache
+	require.Equal(t, hexutil.Uint64(3), cache.status().Entries)
+	require.Equal(t, uncachedBlock, traceBlock(1))
+	require.Equal(t, hexutil.Uint64(1), cache.status().Hits)
+
+	// the traces of the block replaced by a reorg are removed once the block number is traced
+	require.NoError(t, cache.put(traceCacheName(1, common.Hash{1}, "00000000000000000000000000000000"), 1, common.Hash{1}, []byte("[]")))
+	require.Equal(t, hexutil.Uint64(4), cache.status().Entries)
+	traceBlock(1)
+	status = cache.status()
+	require.Equal(t, hexutil.Uint64(3), status.Entries)
+	require.Equal(t, hexutil.Uint64(1), status.Invalidated)
+
+	// the least recently used traces are removed over the limit
+	cache, err = openTraceCache(dir, datasize.ByteSize(status.Size)-1)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), cache.status().Entries)
+
+	require.Equal(t, 2, cache.flush())
+	require.Equal(t, hexutil.Uint64(0), cache.status().Size)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
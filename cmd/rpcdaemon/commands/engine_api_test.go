@@ -1,12 +1,21 @@
 package commands
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test case for https://github.com/ethereum/execution-apis/pull/217 responses
@@ -16,3 +25,50 @@ func TestZeroLatestValidHash(t *testing.T) {
 	assert.Equal(t, "INVALID", json["status"])
 	assert.Equal(t, common.Hash{}, json["latestValidHash"])
 }
+
+func TestGetPayloadBodies(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	api := NewEngineAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), db, nil)
+	ctx := context.Background()
+
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	latest := rawdb.ReadCurrentHeader(tx).Number.Uint64()
+	block, err := rawdb.ReadBlockByNumber(tx, 1)
+	tx.Rollback()
+	require.NoError(t, err)
+	require.NotEmpty(t, block.Transactions())
+	encoded, err := types.MarshalTransactionsBinary(block.Transactions())
+	require.NoError(t, err)
+
+	bodies, err := api.GetPayloadBodiesByHashV1(ctx, []common.Hash{block.Hash(), {0x1}})
+	require.NoError(t, err)
+	require.Len(t, bodies, 2)
+	require.Len(t, bodies[0].Transactions, len(encoded))
+	for i := range encoded {
+		require.Equal(t, hexutil.Bytes(encoded[i]), bodies[0].Transactions[i])
+	}
+	require.Nil(t, bodies[1])
+
+	bodies, err = api.GetPayloadBodiesByRangeV1(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, bodies, 2)
+	require.Len(t, bodies[0].Transactions, len(encoded))
+
+	// no trailing nulls past the latest block
+	bodies, err = api.GetPayloadBodiesByRangeV1(ctx, hexutil.Uint64(latest), 10)
+	require.NoError(t, err)
+	require.Len(t, bodies, 1)
+	require.NotNil(t, bodies[0])
+
+	var rpcErr rpc.Error
+	_, err = api.GetPayloadBodiesByRangeV1(ctx, 0, 1)
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -32602, rpcErr.ErrorCode())
+	_, err = api.GetPayloadBodiesByRangeV1(ctx, 1, maxPayloadBodies+1)
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -38004, rpcErr.ErrorCode())
+	_, err = api.GetPayloadBodiesByHashV1(ctx, make([]common.Hash, maxPayloadBodies+1))
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -38004, rpcErr.ErrorCode())
+}
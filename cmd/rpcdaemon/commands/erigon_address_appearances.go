@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// Roles of an address in an appearance
+const (
+	AppearanceFrom     = "from"     // sender of the transaction
+	AppearanceTo       = "to"       // recipient of the transaction, or the contract it creates
+	AppearanceLog      = "log"      // emitter of a log of the transaction
+	AppearanceInternal = "internal" // caller or target of an internal call (or creation) of the transaction
+	AppearanceMiner    = "miner"    // coinbase of the block or of one of its uncles, without transaction
+)
+
+// AddressAppearance - a transaction where the address appears, or the block itself for the miner
+type AddressAppearance struct {
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex,omitempty"`
+	TransactionHash  *common.Hash    `json:"transactionHash,omitempty"`
+	Roles            []string        `json:"roles"`
+}
+
+// AddressAppearances - a page of appearances, in order. NextBlock is the fromBlock of the next page, nil on the last one.
+type AddressAppearances struct {
+	Appearances []*AddressAppearance `json:"appearances"`
+	NextBlock   *hexutil.Uint64      `json:"nextBlock"`
+}
+
+// GetAddressAppearances implements erigon_getAddressAppearances. Returns the transactions where the address appears,
+// from fromBlock on, found by the call traces and logs indices (the ones behind ots_search*): as sender, recipient,
+// log emitter, or caller or target of an internal call. The pageSize indicates how many appearances may be returned,
+// but the appearances of the last block are all returned. The pageSize must be between 1 and MaxSearchPageSize.
+func (api *ErigonImpl) GetAddressAppearances(ctx context.Context, addr common.Address, fromBlock uint64, pageSize uint16) (*AddressAppearances, error) {
+	if err := checkSearchPageSize(pageSize); err != nil {
+		return nil, err
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	indexedFrom, indexedTo, err := appearancesIndexed(tx)
+	if err != nil {
+		return nil, err
+	}
+	if fromBlock < indexedFrom {
+		return nil, fmt.Errorf("fromBlock %d is pruned, appearances are available from block %d", fromBlock, indexedFrom)
+	}
+	res := &AddressAppearances{Appearances: []*AddressAppearance{}}
+	if fromBlock > indexedTo {
+		return res, nil
+	}
+
+	callBlocks, err := bitmapdb.Get64(tx, kv.CallFromIndex, addr[:], fromBlock, indexedTo)
+	if err != nil {
+		return nil, err
+	}
+	callToBlocks, err := bitmapdb.Get64(tx, kv.CallToIndex, addr[:], fromBlock, indexedTo)
+	if err != nil {
+		return nil, err
+	}
+	callBlocks.Or(callToBlocks)
+	logBlocks32, err := bitmapdb.Get(tx, kv.LogAddressIndex, addr[:], uint32(fromBlock), uint32(indexedTo))
+	if err != nil {
+		return nil, err
+	}
+	logBlocks := roaring64.New()
+	for it := logBlocks32.Iterator(); it.HasNext(); {
+		logBlocks.Add(uint64(it.Next()))
+	}
+	blocks := roaring64.Or(callBlocks, logBlocks)
+	blocks.RemoveRange(0, fromBlock)
+	blocks.RemoveRange(indexedTo+1, uint64(0x100000000))
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	for it := blocks.Iterator(); it.HasNext(); {
+		blockNum := it.Next()
+		if len(res.Appearances) >= int(pageSize) {
+			next := hexutil.Uint64(blockNum)
+			res.NextBlock = &next
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		appearances, err := api.blockAppearances(ctx, tx, chainConfig, addr, blockNum, callBlocks.Contains(blockNum), logBlocks.Contains(blockNum))
+		if err != nil {
+			return nil, err
+		}
+		res.Appearances = append(res.Appearances, appearances...)
+	}
+	return res, nil
+}
+
+// appearancesIndexed returns the range of the blocks covered by both the call traces and the logs indices
+func appearancesIndexed(tx kv.Tx) (from, to uint64, err error) {
+	from, to, err = callTracesIndexed(tx)
+	if err != nil {
+		return 0, 0, err
+	}
+	logsTo, err := stages.GetStageProgress(tx, stages.LogIndex)
+	if err != nil {
+		return 0, 0, err
+	}
+	if logsTo < to {
+		to = logsTo
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return 0, 0, err
+	}
+	logsFrom, err := pm.KeptFrom(tx, prune.KindReceipts, logsTo)
+	if err != nil {
+		return 0, 0, err
+	}
+	if logsFrom > from {
+		from = logsFrom
+	}
+	return from, to, nil
+}
+
+// blockAppearances - the transactions of the block where addr appears: the senders and recipients from the block,
+// the log emitters from the receipts if the logs index has the block, and the internal calls by replaying the block
+// if the call traces index has it
+func (api *ErigonImpl) blockAppearances(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, addr common.Address, blockNum uint64, calls, logs bool) ([]*AddressAppearance, error) {
+	blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	block, senders, err := api._blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", blockNum)
+	}
+
+	roles := make([][]string, len(block.Transactions()))
+	for i, txn := range block.Transactions() {
+		if i < len(senders) && senders[i] == addr {
+			roles[i] = append(roles[i], AppearanceFrom)
+		}
+		if to := txn.GetTo(); to != nil && *to == addr {
+			roles[i] = append(roles[i], AppearanceTo)
+		}
+	}
+	if logs {
+		receipts, err := api.getReceipts(ctx, tx, chainConfig, block, senders)
+		if err != nil {
+			return nil, err
+		}
+		for i, receipt := range receipts {
+			for _, log := range receipt.Logs {
+				if log.Address == addr {
+					roles[i] = append(roles[i], AppearanceLog)
+					break
+				}
+			}
+		}
+	}
+	if calls {
+		if err := api.traceAppearances(ctx, tx, chainConfig, block, addr, roles); err != nil {
+			return nil, err
+		}
+	}
+
+	var appearances []*AddressAppearance
+	for i, txn := range block.Transactions() {
+		if len(roles[i]) == 0 {
+			continue
+		}
+		txIndex, txHash := hexutil.Uint64(i), txn.Hash()
+		appearances = append(appearances, &AddressAppearance{BlockNumber: hexutil.Uint64(blockNum), TransactionIndex: &txIndex, TransactionHash: &txHash, Roles: roles[i]})
+	}
+	miner := block.Coinbase() == addr
+	for _, uncle := range block.Uncles() {
+		miner = miner || uncle.Coinbase == addr
+	}
+	if miner {
+		appearances = append(appearances, &AddressAppearance{BlockNumber: hexutil.Uint64(blockNum), Roles: []string{AppearanceMiner}})
+	}
+	return appearances, nil
+}
+
+// traceAppearances replays the transactions of the block, and adds the roles found by AppearanceTracer
+func (api *ErigonImpl) traceAppearances(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, addr common.Address, roles [][]string) error {
+	ibs := state.New(state.NewPlainState(tx, block.NumberU64()))
+	noop := state.NewNoopWriter()
+	signer := types.MakeSigner(chainConfig, block.NumberU64())
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		return rawdb.ReadHeader(tx, hash, number)
+	}
+	header := block.Header()
+	rules := chainConfig.Rules(block.NumberU64())
+	blockCtx := core.NewEVMBlockContext(header, getHeader, ethash.NewFaker(), nil, ethdb.GetHasTEVM(tx))
+	for i, txn := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ibs.Prepare(txn.Hash(), block.Hash(), i)
+		msg, err := txn.AsMessage(*signer, header.BaseFee, rules)
+		if err != nil {
+			return err
+		}
+		tracer := NewAppearanceTracer(addr)
+		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(txn.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+			return err
+		}
+		if err := ibs.FinalizeTx(rules, noop); err != nil {
+			return err
+		}
+		if tracer.Created && !hasRole(roles[i], AppearanceTo) {
+			roles[i] = append(roles[i], AppearanceTo)
+		}
+		if tracer.Internal {
+			roles[i] = append(roles[i], AppearanceInternal)
+		}
+	}
+	return nil
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AppearanceTracer finds whether the transaction creates the address (Created), or calls it or is called by it
+// internally (Internal)
+type AppearanceTracer struct {
+	DefaultTracer
+	addr     common.Address
+	Created  bool
+	Internal bool
+}
+
+func NewAppearanceTracer(addr common.Address) *AppearanceTracer {
+	return &AppearanceTracer{addr: addr}
+}
+
+func (t *AppearanceTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth == 0 {
+		t.Created = t.Created || (create && to == t.addr)
+		return
+	}
+	t.Internal = t.Internal || from == t.addr || to == t.addr
+}
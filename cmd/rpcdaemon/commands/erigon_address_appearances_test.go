@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAddressAppearances(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethApi := NewEthAPI(base, db, nil, nil, nil, 5000000)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	all := func(addr common.Address, pageSize uint16) []*AddressAppearance {
+		var appearances []*AddressAppearance
+		fromBlock := uint64(0)
+		for {
+			page, err := api.GetAddressAppearances(ctx, addr, fromBlock, pageSize)
+			require.NoError(t, err)
+			appearances = append(appearances, page.Appearances...)
+			if page.NextBlock == nil {
+				return appearances
+			}
+			require.Greater(t, uint64(*page.NextBlock), fromBlock)
+			fromBlock = uint64(*page.NextBlock)
+		}
+	}
+
+	// The emitter of a log appears in its transaction, the same in pages of any size
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	logs, err := ethApi.getLogs(ctx, tx, filters.FilterCriteria{FromBlock: big.NewInt(0)})
+	tx.Rollback()
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	emitter := all(logs[0].Address, 100)
+	require.Equal(t, emitter, all(logs[0].Address, 1))
+	found := false
+	for _, a := range emitter {
+		if a.TransactionHash != nil && *a.TransactionHash == logs[0].TxHash {
+			require.Contains(t, a.Roles, AppearanceLog)
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	// The sender of the transactions appears in each of them
+	sender := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	appearances := all(sender, 100)
+	require.NotEmpty(t, appearances)
+	for i, a := range appearances {
+		require.Contains(t, a.Roles, AppearanceFrom)
+		if i > 0 {
+			require.GreaterOrEqual(t, a.BlockNumber, appearances[i-1].BlockNumber)
+		}
+	}
+
+	_, err = api.GetAddressAppearances(ctx, sender, 0, 0)
+	require.Error(t, err)
+}
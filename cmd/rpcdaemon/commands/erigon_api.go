@@ -2,10 +2,17 @@ package commands
 
 import (
 	"context"
+	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/ethdb/dbstats"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -23,6 +30,15 @@ type ErigonAPI interface {
 
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
+	GetLatestLogs(ctx context.Context, crit filters.FilterCriteria, logCount uint64) ([]*types.Log, error)
+	GetBlockReceiptsByBlockRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error
+
+	// Address appearances (see ./erigon_address_appearances.go)
+	GetAddressAppearances(ctx context.Context, addr common.Address, fromBlock uint64, pageSize uint16) (*AddressAppearances, error)
+
+	// Token transfers (see ./erigon_tokens.go)
+	GetTokenBalance(ctx context.Context, token common.Address, holder common.Address) (*TokenBalance, error)
+	GetTokenTransfers(ctx context.Context, token common.Address, holder common.Address, fromBlock uint64, pageSize uint16) (*TokenTransfers, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
 
 	// WatchTheBurn / reward related (see ./erigon_issuance.go)
@@ -33,6 +49,27 @@ type ErigonAPI interface {
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
+	// NodeStatus returns the sync and storage status of the node (see ./erigon_node_status.go)
+	NodeStatus(ctx context.Context) (*NodeStatus, error)
+	// DbStats returns statistics of the database tables (see ./erigon_db_stats.go)
+	DbStats(ctx context.Context) (*DBStats, error)
+	// RpcStats returns the latencies of the methods served (see ./erigon_rpc_stats.go)
+	RpcStats(ctx context.Context, reset *bool) (*rpc.Stats, error)
+	// Health returns the liveness and the readiness of the node, as /health and /ready (see ./erigon_health.go)
+	Health(ctx context.Context) (*health.Status, error)
+
+	// Ephemeral state forks (see ./erigon_fork.go)
+	CreateFork(ctx context.Context, blockNrOrHash *rpc.BlockNumberOrHash, ttl *hexutil.Uint64) (rpc.ID, error)
+	DeleteFork(ctx context.Context, id rpc.ID) (bool, error)
+	ForkSendTransaction(ctx context.Context, id rpc.ID, args ethapi.CallArgs) (*SimulatedCall, error)
+	ForkCall(ctx context.Context, id rpc.ID, args ethapi.CallArgs) (hexutil.Bytes, error)
+	ForkGetBalance(ctx context.Context, id rpc.ID, address common.Address) (*hexutil.Big, error)
+	ForkGetTransactionCount(ctx context.Context, id rpc.ID, address common.Address) (*hexutil.Uint64, error)
+	ForkGetCode(ctx context.Context, id rpc.ID, address common.Address) (hexutil.Bytes, error)
+	ForkGetStorageAt(ctx context.Context, id rpc.ID, address common.Address, index string) (string, error)
+
+	// Batches of historical calls (see ./erigon_callMany.go)
+	CallMany(ctx context.Context, batches []CallBatch) ([][]CallResult, error)
 }
 
 // ErigonImpl is implementation of the ErigonAPI interface
@@ -40,6 +77,11 @@ type ErigonImpl struct {
 	*BaseAPI
 	db         kv.RoDB
 	ethBackend rpchelper.ApiBackend
+	snapDir    string // set when the snapshots are local
+
+	dbStatsHistory *dbstats.History
+	forks          *stateForks
+	health         *health.Checker
 }
 
 // NewErigonAPI returns ErigonImpl instance
@@ -48,5 +90,8 @@ func NewErigonAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend) *ErigonIm
 		BaseAPI:    base,
 		db:         db,
 		ethBackend: eth,
+
+		dbStatsHistory: dbstats.NewHistory(24 * time.Hour),
+		forks:          newStateForks(),
 	}
 }
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	// maxCallManyCalls is the maximum number of calls a single erigon_callMany request may execute.
+	maxCallManyCalls = 10_000
+	// callManyTimeout is the time a single erigon_callMany request may take.
+	callManyTimeout = 30 * time.Second
+)
+
+// CallBatch is a batch of calls to execute on the state after a block.
+type CallBatch struct {
+	Block rpc.BlockNumberOrHash `json:"block"`
+	Calls []ethapi.CallArgs     `json:"calls"`
+}
+
+// CallResult is the result of a call of erigon_callMany.
+type CallResult struct {
+	ReturnData hexutil.Bytes       `json:"returnData"`
+	GasUsed    hexutil.Uint64      `json:"gasUsed"`
+	Error      *SimulatedCallError `json:"error,omitempty"`
+}
+
+// callManyBlock are the calls of the batches to execute on the state after the same block
+type callManyBlock struct {
+	number uint64
+	hash   common.Hash
+	calls  []callManyIndex
+}
+
+type callManyIndex struct{ batch, call int }
+
+// CallMany implements erigon_callMany. Executes the calls of the batches, each like eth_call on the state after the
+// block of its batch, independent of the others, and returns their results in the same order. The calls of the same
+// block share a cache of the state they read, and the blocks are executed in parallel.
+func (api *ErigonImpl) CallMany(ctx context.Context, batches []CallBatch) ([][]CallResult, error) {
+	results := make([][]CallResult, len(batches))
+	var count int
+	for i, batch := range batches {
+		results[i] = make([]CallResult, len(batch.Calls))
+		count += len(batch.Calls)
+	}
+	if count > maxCallManyCalls {
+		return nil, fmt.Errorf("too many calls: %d, the maximum is %d", count, maxCallManyCalls)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callManyTimeout)
+	defer cancel()
+	defer func(start time.Time) {
+		log.Trace("Executing EVM callMany finished", "calls", count, "runtime", time.Since(start))
+	}(time.Now())
+
+	blocks, err := api.callManyBlocks(ctx, batches)
+	if err != nil {
+		return nil, err
+	}
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(blocks) {
+		workers = len(blocks)
+	}
+	jobs := make(chan *callManyBlock, len(blocks))
+	for _, block := range blocks {
+		jobs <- block
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range jobs {
+				if blockErr := api.callManyBlock(ctx, batches, block, results); blockErr != nil {
+					errOnce.Do(func() {
+						cancel()
+						err = fmt.Errorf("block %d: %w", block.number, blockErr)
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// callManyBlocks groups the calls of the batches by their block, in the order the blocks first appear
+func (api *ErigonImpl) callManyBlocks(ctx context.Context, batches []CallBatch) ([]*callManyBlock, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var blocks []*callManyBlock
+	byHash := map[common.Hash]*callManyBlock{}
+	for i, batch := range batches {
+		number, hash, _, err := rpchelper.GetCanonicalBlockNumber(batch.Block, tx, api.filters)
+		if err != nil {
+			return nil, fmt.Errorf("batch %d: %w", i, err)
+		}
+		block, ok := byHash[hash]
+		if !ok {
+			block = &callManyBlock{number: number, hash: hash}
+			byHash[hash] = block
+			blocks = append(blocks, block)
+		}
+		for j := range batch.Calls {
+			block.calls = append(block.calls, callManyIndex{batch: i, call: j})
+		}
+	}
+	return blocks, nil
+}
+
+// callManyBlock executes the calls of the block, in a read transaction of its own
+func (api *ErigonImpl) callManyBlock(ctx context.Context, batches []CallBatch, block *callManyBlock, results [][]CallResult) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+	header, err := api._blockReader.Header(ctx, tx, block.hash, block.number)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("block %d(%x) not found", block.number, block.hash)
+	}
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithHash(block.hash, true), api.filters, api.stateCache, api.stateOverlay)
+	if err != nil {
+		return err
+	}
+	// The calls don't write to the cache: each starts from the state after the block
+	cachedReader := state.NewCachedReader(stateReader, shards.NewStateCache(32, 0 /* no limit */))
+	contractHasTEVM := func(contractHash common.Hash) (bool, error) { return false, nil }
+	if api.TevmEnabled {
+		contractHasTEVM = ethdb.GetHasTEVM(tx)
+	}
+	var baseFee *uint256.Int
+	if header.BaseFee != nil {
+		baseFee, _ = uint256.FromBig(header.BaseFee)
+	}
+
+	for _, index := range block.calls {
+		// The calls may use all the gas of the block
+		msg, err := batches[index.batch].Calls[index.call].ToMessage(header.GasLimit, baseFee)
+		if err != nil {
+			return fmt.Errorf("call %d of batch %d: %w", index.call, index.batch, err)
+		}
+		blockCtx, txCtx := transactions.GetEvmContext(msg, header, true, tx, contractHasTEVM, api._blockReader)
+		evm := vm.NewEVM(blockCtx, txCtx, state.New(cachedReader), chainConfig, vm.Config{NoBaseFee: true})
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */, false /* gasBailout */)
+		close(done)
+		if evm.Cancelled() {
+			return fmt.Errorf("execution aborted (timeout = %v)", callManyTimeout)
+		}
+
+		res := &results[index.batch][index.call]
+		switch {
+		case err != nil:
+			res.Error = &SimulatedCallError{Code: -32000, Message: err.Error()}
+		case len(result.Revert()) > 0:
+			revertErr := ethapi.NewRevertError(result)
+			res.Error = &SimulatedCallError{Code: revertErr.ErrorCode(), Message: revertErr.Error(), Data: hexutil.Encode(result.Revert())}
+		case result.Err != nil:
+			res.Error = &SimulatedCallError{Code: -32015, Message: result.Err.Error()}
+		default:
+			res.ReturnData = result.Return()
+		}
+		if result != nil {
+			res.GasUsed = hexutil.Uint64(result.UsedGas)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErigonCallMany(t *testing.T) {
+	ctx := context.Background()
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethApi := NewEthAPI(base, db, nil, nil, nil, 5000000)
+	api := NewErigonAPI(base, db, nil)
+
+	var (
+		from    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		address = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+		// Deploys the balance of the address as the code
+		balanceCode = hexutil.Bytes(common.FromHex("0x7371562b71999873db5b286df957af199ec94617f73160005260206000f3"))
+		// Reverts with 0xaa
+		reverterCode = hexutil.Bytes(common.FromHex("0x60aa60005260206000fd"))
+	)
+	balance := ethapi.CallArgs{From: &from, Data: &balanceCode}
+	batch := func(number rpc.BlockNumber, calls ...ethapi.CallArgs) CallBatch {
+		return CallBatch{Block: rpc.BlockNumberOrHashWithNumber(number), Calls: calls}
+	}
+
+	results, err := api.CallMany(ctx, []CallBatch{
+		batch(1, balance, ethapi.CallArgs{From: &from, Data: &reverterCode}),
+		batch(rpc.LatestBlockNumber, balance),
+		batch(1, balance),
+		batch(2),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	require.Len(t, results[0], 2)
+	require.Empty(t, results[3])
+
+	for i, number := range []rpc.BlockNumber{1, rpc.LatestBlockNumber, 1} {
+		expected, err := ethApi.GetBalance(ctx, address, rpc.BlockNumberOrHashWithNumber(number))
+		require.NoError(t, err)
+		require.Nil(t, results[i][0].Error)
+		require.NotZero(t, results[i][0].GasUsed)
+		require.Equal(t, common.BigToHash(expected.ToInt()).Bytes(), []byte(results[i][0].ReturnData), "batch %d", i)
+	}
+	require.NotEqual(t, results[0][0].ReturnData, results[1][0].ReturnData)
+
+	reverted := results[0][1]
+	require.Equal(t, 3, reverted.Error.Code)
+	require.Equal(t, hexutil.Encode(common.LeftPadBytes([]byte{0xaa}, 32)), reverted.Error.Data)
+	require.Empty(t, reverted.ReturnData)
+
+	_, err = api.CallMany(ctx, []CallBatch{batch(1, balance), batch(1000, balance)})
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/ledgerwatch/erigon/ethdb/dbstats"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// DBStats is the result of erigon_dbStats
+type DBStats struct {
+	PageSize uint64          `json:"pageSize"`
+	Tables   []dbstats.Table `json:"tables"`
+	// GrowthSince is the time of the previous call which growth of tables is measured from (calls of the last day are
+	// kept), nil on the first call
+	GrowthSince *time.Time `json:"growthSince"`
+}
+
+// DbStats implements erigon_dbStats. Returns statistics of the database tables, biggest first. Only for local database
+// (rpcdaemon with --datadir, or embedded).
+func (api *ErigonImpl) DbStats(ctx context.Context) (*DBStats, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tables, pageSize, err := dbstats.Collect(rpchelper.UnwrapTx(tx))
+	if err != nil {
+		return nil, err
+	}
+	res := &DBStats{PageSize: pageSize, Tables: tables}
+	now := time.Now()
+	if prev, ok := api.dbStatsHistory.Add(dbstats.NewSample(tables, now)); ok {
+		dbstats.SetGrowth(tables, prev, now)
+		res.GrowthSince = &prev.Time
+	}
+	return res, nil
+}
@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+)
+
+const (
+	// maxStateForks is the maximum number of state forks kept at once.
+	maxStateForks = 64
+	// maxStateForkWrites is the maximum size of the state a single fork may write.
+	maxStateForkWrites = 64 * datasize.MB
+	// stateForkReads is the size of the state read by a fork it keeps in memory.
+	stateForkReads = 16 * datasize.MB
+	// defaultStateForkTTL is how long a fork is kept unused, unless told otherwise.
+	defaultStateForkTTL = 5 * time.Minute
+	// maxStateForkTTL is the maximum time a fork may be kept unused.
+	maxStateForkTTL = time.Hour
+	// forkCallTimeout is the time a single transaction or call in a fork may take.
+	forkCallTimeout = 5 * time.Second
+)
+
+// stateFork is an in-memory overlay of the state after a block: the transactions sent to the fork are executed in
+// the block following it, one after another, and their writes are kept in the overlay only.
+type stateFork struct {
+	ttl   time.Duration
+	timer *time.Timer
+	used  time.Time // guarded by stateForks.lock
+
+	lock   sync.Mutex // one request to the fork at a time
+	hash   common.Hash
+	header *types.Header      // of the block the transactions are executed in
+	writes *shards.StateCache // the state written by the transactions and the state read
+	txs    int                // number of the transactions executed
+}
+
+// stateForks are the forks by id, a fork is removed once it hasn't been used for its TTL
+type stateForks struct {
+	lock  sync.Mutex
+	forks map[rpc.ID]*stateFork
+}
+
+func newStateForks() *stateForks {
+	return &stateForks{forks: map[rpc.ID]*stateFork{}}
+}
+
+func (s *stateForks) add(fork *stateFork) (rpc.ID, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.forks) >= maxStateForks {
+		return "", fmt.Errorf("too many forks: %d, delete some or wait for them to expire", len(s.forks))
+	}
+	id := rpc.NewID()
+	fork.used = time.Now()
+	fork.timer = time.AfterFunc(fork.ttl, func() { s.expire(id) })
+	s.forks[id] = fork
+	return id, nil
+}
+
+func (s *stateForks) get(id rpc.ID) (*stateFork, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fork, ok := s.forks[id]
+	if !ok {
+		return nil, fmt.Errorf("fork %s not found", id)
+	}
+	fork.used = time.Now()
+	return fork, nil
+}
+
+func (s *stateForks) remove(id rpc.ID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fork, ok := s.forks[id]
+	if ok {
+		fork.timer.Stop()
+		delete(s.forks, id)
+	}
+	return ok
+}
+
+// expire removes the fork unless it has been used since the timer was set, the timer is set again then
+func (s *stateForks) expire(id rpc.ID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fork, ok := s.forks[id]
+	if !ok {
+		return
+	}
+	if left := time.Until(fork.used.Add(fork.ttl)); left > 0 {
+		fork.timer.Reset(left)
+		return
+	}
+	delete(s.forks, id)
+}
+
+// CreateFork implements erigon_createFork. Creates an in-memory overlay of the state after the given block, the
+// latest one by default, and returns its id. The fork is removed once it hasn't been used for ttl seconds.
+func (api *ErigonImpl) CreateFork(ctx context.Context, blockNrOrHash *rpc.BlockNumberOrHash, ttl *hexutil.Uint64) (rpc.ID, error) {
+	fork := &stateFork{ttl: defaultStateForkTTL, writes: shards.NewStateCache(32, stateForkReads)}
+	if ttl != nil {
+		fork.ttl = time.Duration(*ttl) * time.Second
+		if fork.ttl <= 0 || fork.ttl > maxStateForkTTL {
+			return "", fmt.Errorf("ttl %d out of range, the maximum is %d seconds", *ttl, uint64(maxStateForkTTL/time.Second))
+		}
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return "", err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	blockNumber, hash, _, err := rpchelper.GetCanonicalBlockNumber(*blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return "", err
+	}
+	parent, err := api._blockReader.Header(ctx, tx, hash, blockNumber)
+	if err != nil {
+		return "", err
+	}
+	if parent == nil {
+		return "", fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+
+	fork.hash = hash
+	fork.header = &types.Header{
+		ParentHash: hash,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).SetUint64(blockNumber + 1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 12,
+	}
+	if chainConfig.IsLondon(blockNumber + 1) {
+		fork.header.Eip1559 = true
+		fork.header.BaseFee = misc.CalcBaseFee(chainConfig, parent)
+	}
+	return api.forks.add(fork)
+}
+
+// DeleteFork implements erigon_deleteFork. Removes the fork, returns false if there is no such fork.
+func (api *ErigonImpl) DeleteFork(_ context.Context, id rpc.ID) (bool, error) {
+	return api.forks.remove(id), nil
+}
+
+// ForkSendTransaction implements erigon_forkSendTransaction. Executes the transaction in the fork, with no signature
+// required, keeps its writes in the fork and returns its result.
+func (api *ErigonImpl) ForkSendTransaction(ctx context.Context, id rpc.ID, args ethapi.CallArgs) (*SimulatedCall, error) {
+	var call *SimulatedCall
+	err := api.withFork(ctx, id, func(tx kv.Tx, fork *stateFork, ibs *state.IntraBlockState) error {
+		if size := datasize.ByteSize(fork.writes.WriteSize()); size > maxStateForkWrites {
+			return fmt.Errorf("the fork has written %s of the state, the maximum is %s", size.HR(), maxStateForkWrites.HR())
+		}
+		// The transactions have no hash: the logs are told apart by a hash of their position instead
+		var key [16]byte
+		binary.BigEndian.PutUint64(key[:8], fork.header.Number.Uint64())
+		binary.BigEndian.PutUint64(key[8:], uint64(fork.txs))
+		txHash := crypto.Keccak256Hash(key[:])
+		ibs.Prepare(txHash, common.Hash{}, fork.txs)
+
+		result, evm, err := api.forkApply(ctx, tx, fork, ibs, args)
+		if err != nil {
+			return err
+		}
+		if err := ibs.FinalizeTx(evm.ChainRules(), state.NewCachedWriter(state.NewNoopWriter(), fork.writes)); err != nil {
+			return err
+		}
+		fork.txs++
+
+		call = &SimulatedCall{
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			ReturnData: result.Return(),
+			Logs:       ibs.GetLogs(txHash),
+		}
+		for _, l := range call.Logs {
+			l.BlockNumber = fork.header.Number.Uint64()
+		}
+		if call.Logs == nil {
+			call.Logs = []*types.Log{}
+		}
+		if result.Err != nil {
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				revertErr := ethapi.NewRevertError(result)
+				call.Error = &SimulatedCallError{Code: revertErr.ErrorCode(), Message: revertErr.Error(), Data: hexutil.Encode(result.Revert())}
+			} else {
+				call.Error = &SimulatedCallError{Code: -32015, Message: result.Err.Error()}
+			}
+		}
+		return nil
+	})
+	return call, err
+}
+
+// ForkCall implements erigon_forkCall. Executes the call in the fork like eth_call, without keeping its writes.
+func (api *ErigonImpl) ForkCall(ctx context.Context, id rpc.ID, args ethapi.CallArgs) (hexutil.Bytes, error) {
+	var ret hexutil.Bytes
+	err := api.withFork(ctx, id, func(tx kv.Tx, fork *stateFork, ibs *state.IntraBlockState) error {
+		result, _, err := api.forkApply(ctx, tx, fork, ibs, args)
+		if err != nil {
+			return err
+		}
+		if len(result.Revert()) > 0 {
+			return ethapi.NewRevertError(result)
+		}
+		ret = result.Return()
+		return result.Err
+	})
+	return ret, err
+}
+
+// ForkGetBalance implements erigon_forkGetBalance. Returns the balance of the account in the fork.
+func (api *ErigonImpl) ForkGetBalance(ctx context.Context, id rpc.ID, address common.Address) (*hexutil.Big, error) {
+	var balance *hexutil.Big
+	err := api.withFork(ctx, id, func(_ kv.Tx, _ *stateFork, ibs *state.IntraBlockState) error {
+		balance = (*hexutil.Big)(ibs.GetBalance(address).ToBig())
+		return nil
+	})
+	return balance, err
+}
+
+// ForkGetTransactionCount implements erigon_forkGetTransactionCount. Returns the nonce of the account in the fork.
+func (api *ErigonImpl) ForkGetTransactionCount(ctx context.Context, id rpc.ID, address common.Address) (*hexutil.Uint64, error) {
+	var nonce hexutil.Uint64
+	err := api.withFork(ctx, id, func(_ kv.Tx, _ *stateFork, ibs *state.IntraBlockState) error {
+		nonce = hexutil.Uint64(ibs.GetNonce(address))
+		return nil
+	})
+	return &nonce, err
+}
+
+// ForkGetCode implements erigon_forkGetCode. Returns the code of the account in the fork.
+func (api *ErigonImpl) ForkGetCode(ctx context.Context, id rpc.ID, address common.Address) (hexutil.Bytes, error) {
+	var code hexutil.Bytes
+	err := api.withFork(ctx, id, func(_ kv.Tx, _ *stateFork, ibs *state.IntraBlockState) error {
+		code = ibs.GetCode(address)
+		return nil
+	})
+	return code, err
+}
+
+// ForkGetStorageAt implements erigon_forkGetStorageAt. Returns the value of the storage key of the account in the fork.
+func (api *ErigonImpl) ForkGetStorageAt(ctx context.Context, id rpc.ID, address common.Address, index string) (string, error) {
+	var value uint256.Int
+	err := api.withFork(ctx, id, func(_ kv.Tx, _ *stateFork, ibs *state.IntraBlockState) error {
+		key := common.HexToHash(index)
+		ibs.GetState(address, &key, &value)
+		return nil
+	})
+	return hexutil.Encode(common.LeftPadBytes(value.Bytes(), 32)), err
+}
+
+// withFork runs f with the state of the fork, holding the fork so no other request changes it meanwhile
+func (api *ErigonImpl) withFork(ctx context.Context, id rpc.ID, f func(tx kv.Tx, fork *stateFork, ibs *state.IntraBlockState) error) error {
+	fork, err := api.forks.get(id)
+	if err != nil {
+		return err
+	}
+	fork.lock.Lock()
+	defer fork.lock.Unlock()
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithHash(fork.hash, true), api.filters, api.stateCache, api.stateOverlay)
+	if err != nil {
+		return err
+	}
+	return f(tx, fork, state.New(state.NewCachedReader(stateReader, fork.writes)))
+}
+
+// forkApply executes the message of args on the state of the fork, in the block following the one it's forked at
+func (api *ErigonImpl) forkApply(ctx context.Context, tx kv.Tx, fork *stateFork, ibs *state.IntraBlockState, args ethapi.CallArgs) (*core.ExecutionResult, *vm.EVM, error) {
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var baseFee *uint256.Int
+	if fork.header.BaseFee != nil {
+		baseFee, _ = uint256.FromBig(fork.header.BaseFee)
+	}
+	// The transactions may use all the gas of the block
+	msg, err := args.ToMessage(fork.header.GasLimit, baseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+	contractHasTEVM := func(contractHash common.Hash) (bool, error) { return false, nil }
+	if api.TevmEnabled {
+		contractHasTEVM = ethdb.GetHasTEVM(tx)
+	}
+	blockCtx, txCtx := transactions.GetEvmContext(msg, fork.header, true, tx, contractHasTEVM, api._blockReader)
+	evm := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{NoBaseFee: true})
+
+	ctx, cancel := context.WithTimeout(ctx, forkCallTimeout)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	gp := new(core.GasPool).AddGas(msg.Gas())
+	result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+	if err != nil {
+		return nil, nil, err
+	}
+	if evm.Cancelled() {
+		return nil, nil, fmt.Errorf("execution aborted (timeout = %v)", forkCallTimeout)
+	}
+	return result, evm, nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateFork(t *testing.T) {
+	ctx := context.Background()
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil)
+
+	var (
+		from = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		// Stores 0x2a at the key 0, and deploys the runtime
+		initCode = hexutil.Bytes(common.FromHex("0x602a600055600b6011600039600b6000f360005460005260206000f3"))
+		// Returns the value of the key 0
+		runtime  = common.FromHex("0x60005460005260206000f3")
+		contract = crypto.CreateAddress(from, 0)
+		stored   = hexutil.Encode(common.LeftPadBytes([]byte{0x2a}, 32))
+	)
+
+	id, err := api.CreateFork(ctx, nil, nil)
+	require.NoError(t, err)
+	other, err := api.CreateFork(ctx, nil, nil)
+	require.NoError(t, err)
+
+	sent, err := api.ForkSendTransaction(ctx, id, ethapi.CallArgs{From: &from, Data: &initCode})
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), sent.Status)
+	require.Nil(t, sent.Error)
+
+	code, err := api.ForkGetCode(ctx, id, contract)
+	require.NoError(t, err)
+	require.Equal(t, runtime, []byte(code))
+	value, err := api.ForkGetStorageAt(ctx, id, contract, "0x0")
+	require.NoError(t, err)
+	require.Equal(t, stored, value)
+	nonce, err := api.ForkGetTransactionCount(ctx, id, from)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), *nonce)
+	ret, err := api.ForkCall(ctx, id, ethapi.CallArgs{From: &from, To: &contract})
+	require.NoError(t, err)
+	require.Equal(t, stored, hexutil.Encode(ret))
+
+	// The forks are isolated from each other and from the chain
+	code, err = api.ForkGetCode(ctx, other, contract)
+	require.NoError(t, err)
+	require.Empty(t, code)
+	nonce, err = api.ForkGetTransactionCount(ctx, other, from)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(0), *nonce)
+
+	// The calls don't change the fork
+	_, err = api.ForkCall(ctx, other, ethapi.CallArgs{From: &from, Data: &initCode})
+	require.NoError(t, err)
+	nonce, err = api.ForkGetTransactionCount(ctx, other, from)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(0), *nonce)
+
+	deleted, err := api.DeleteFork(ctx, id)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	_, err = api.ForkGetCode(ctx, id, contract)
+	require.Error(t, err)
+	deleted, err = api.DeleteFork(ctx, id)
+	require.NoError(t, err)
+	require.False(t, deleted)
+
+	// A fork is kept while it's used, and removed once it hasn't been used for its TTL
+	api.forks.expire(other)
+	_, err = api.ForkGetCode(ctx, other, contract)
+	require.NoError(t, err)
+	fork, err := api.forks.get(other)
+	require.NoError(t, err)
+	fork.used = time.Now().Add(-defaultStateForkTTL)
+	api.forks.expire(other)
+	_, err = api.ForkGetCode(ctx, other, contract)
+	require.Error(t, err)
+
+	ttl := hexutil.Uint64(2 * maxStateForkTTL / time.Second)
+	_, err = api.CreateFork(ctx, nil, &ttl)
+	require.Error(t, err)
+	_, err = api.CreateFork(ctx, &rpc.BlockNumberOrHash{BlockHash: &common.Hash{1}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
+)
+
+// Health implements erigon_health. Returns the liveness and the readiness of the node by the criteria of the
+// --health.* flags, the same status as /ready.
+func (api *ErigonImpl) Health(ctx context.Context) (*health.Status, error) {
+	if api.health == nil {
+		return nil, errors.New("the health checks are not available")
+	}
+	return api.health.Check(ctx), nil
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapshothashes"
+)
+
+// NodeStatus is the sync and storage status of the node, the counterpart of erigon_nodeInfo for the database side
+type NodeStatus struct {
+	Stages    []StageStatus    `json:"stages"`
+	Snapshots []SnapshotStatus `json:"snapshots"`
+	Prune     PruneStatus      `json:"prune"`
+	DB        *DBStatus        `json:"db,omitempty"`
+}
+
+type StageStatus struct {
+	Name          string          `json:"name"`
+	Progress      hexutil.Uint64  `json:"progress"`
+	PruneProgress *hexutil.Uint64 `json:"pruneProgress,omitempty"`
+}
+
+type SnapshotStatus struct {
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	From        hexutil.Uint64 `json:"from"`
+	To          hexutil.Uint64 `json:"to"`
+	Size        hexutil.Uint64 `json:"size"`
+	Indexed     bool           `json:"indexed"`
+	TorrentHash string         `json:"torrentHash,omitempty"`
+	Preverified bool           `json:"preverified"`
+	// Verified is whether the hash of the .torrent file is the preverified one, nil if one of them is missing
+	Verified *bool `json:"verified"`
+}
+
+type PruneStatus struct {
+	// Mode is the prune flags of the node, empty for archive nodes
+	Mode string `json:"mode"`
+	// KeptFrom is the first block of which the pruned data is kept, by kind of data
+	KeptFrom map[string]hexutil.Uint64 `json:"keptFrom"`
+}
+
+type DBStatus struct {
+	Size   hexutil.Uint64            `json:"size"`
+	Tables map[string]hexutil.Uint64 `json:"tables"`
+}
+
+// NodeStatus implements erigon_nodeStatus. Returns the progress of the stages, the snapshot files, the prune settings
+// and the sizes of the database tables. The snapshots are only listed with --datadir, and the database sizes are only
+// known when the database is local.
+func (api *ErigonImpl) NodeStatus(ctx context.Context) (*NodeStatus, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status := &NodeStatus{Stages: []StageStatus{}, Snapshots: []SnapshotStatus{}}
+	pruneProgress := map[string]hexutil.Uint64{}
+	if err := tx.ForEach(kv.SyncStageProgress, nil, func(k, v []byte) error {
+		progress, err := stages.GetStageProgress(tx, stages.SyncStage(k))
+		if err != nil {
+			return err
+		}
+		if name := bytes.TrimPrefix(k, []byte("prune_")); len(name) < len(k) {
+			pruneProgress[string(name)] = hexutil.Uint64(progress)
+		} else {
+			status.Stages = append(status.Stages, StageStatus{Name: string(k), Progress: hexutil.Uint64(progress)})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for i := range status.Stages {
+		if progress, ok := pruneProgress[status.Stages[i].Name]; ok {
+			status.Stages[i].PruneProgress = &progress
+		}
+	}
+
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	status.Prune = PruneStatus{Mode: pm.String(), KeptFrom: map[string]hexutil.Uint64{}}
+	for _, kind := range []prune.Kind{prune.KindHistory, prune.KindReceipts, prune.KindTxIndex, prune.KindCallTraces} {
+		keptFrom, err := pm.KeptFrom(tx, kind, executionProgress)
+		if err != nil {
+			return nil, err
+		}
+		if keptFrom > 0 {
+			status.Prune.KeptFrom[kind.String()] = hexutil.Uint64(keptFrom)
+		}
+	}
+
+	if api.snapDir != "" {
+		chainConfig, err := api.chainConfig(tx)
+		if err != nil {
+			return nil, err
+		}
+		if status.Snapshots, err = snapshotsStatus(api.snapDir, snapshothashes.KnownConfig(chainConfig.ChainName).Preverified); err != nil {
+			return nil, err
+		}
+	}
+
+	if mdbxTx, ok := rpchelper.UnwrapTx(tx).(*mdbx.MdbxTx); ok {
+		size, err := mdbxTx.DBSize()
+		if err != nil {
+			return nil, err
+		}
+		status.DB = &DBStatus{Size: hexutil.Uint64(size), Tables: map[string]hexutil.Uint64{}}
+		for _, table := range kv.ChaindataTables {
+			size, err := mdbxTx.BucketSize(table)
+			if err != nil { // tables of newer versions don't exist in the databases of older ones
+				continue
+			}
+			status.DB.Tables[table] = hexutil.Uint64(size)
+		}
+	}
+	return status, nil
+}
+
+// snapshotsStatus lists the segments of the snapshots directory, checking their .torrent files against the preverified
+// hashes
+func snapshotsStatus(snapDir string, preverified snapshothashes.Preverified) ([]SnapshotStatus, error) {
+	segments, err := snap.Segments(snapDir)
+	if err != nil {
+		return nil, err
+	}
+	preverifiedHashes := make(map[string]string, len(preverified))
+	for _, p := range preverified {
+		preverifiedHashes[p.Name] = p.Hash
+	}
+	res := make([]SnapshotStatus, 0, len(segments))
+	for _, f := range segments {
+		_, name := filepath.Split(f.Path)
+		s := SnapshotStatus{Name: name, Type: f.T.String(), From: hexutil.Uint64(f.From), To: hexutil.Uint64(f.To)}
+		if fi, err := os.Stat(f.Path); err == nil {
+			s.Size = hexutil.Uint64(fi.Size())
+		}
+		switch f.T {
+		case snap.Transactions:
+			s.Indexed = common.FileExist(filepath.Join(snapDir, snap.IdxFileName(f.From, f.To, f.T.String()))) &&
+				common.FileExist(filepath.Join(snapDir, snap.IdxFileName(f.From, f.To, snap.Transactions2Block.String())))
+		case snap.Headers, snap.Bodies:
+			s.Indexed = common.FileExist(filepath.Join(snapDir, snap.IdxFileName(f.From, f.To, f.T.String())))
+		}
+		if mi, err := metainfo.LoadFromFile(f.Path + ".torrent"); err == nil {
+			s.TorrentHash = mi.HashInfoBytes().String()
+		}
+		var expected string
+		expected, s.Preverified = preverifiedHashes[name]
+		if s.Preverified && s.TorrentHash != "" {
+			verified := s.TorrentHash == expected
+			s.Verified = &verified
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
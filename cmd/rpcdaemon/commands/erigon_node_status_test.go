@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapshothashes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeStatus(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil)
+
+	status, err := api.NodeStatus(context.Background())
+	require.NoError(t, err)
+	progress := map[string]hexutil.Uint64{}
+	for _, s := range status.Stages {
+		progress[s.Name] = s.Progress
+	}
+	require.Equal(t, hexutil.Uint64(10), progress["Execution"])
+	require.Empty(t, status.Snapshots)
+	// an archive node
+	require.Empty(t, status.Prune.Mode)
+	require.Empty(t, status.Prune.KeptFrom)
+	require.NotNil(t, status.DB)
+	require.NotZero(t, status.DB.Size)
+	require.Contains(t, status.DB.Tables, "PlainState")
+}
+
+func TestSnapshotsStatus(t *testing.T) {
+	dir := t.TempDir()
+	headers := snap.SegmentFileName(0, 500_000, snap.Headers)
+	bodies := snap.SegmentFileName(0, 500_000, snap.Bodies)
+	for _, name := range []string{headers, snap.IdxFileName(0, 500_000, snap.Headers.String()), bodies} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte{1}, 0644))
+	}
+
+	list, err := snapshotsStatus(dir, snapshothashes.Preverified{{Name: headers, Hash: "aa"}})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, SnapshotStatus{Name: headers, Type: "headers", To: 500_000, Size: 1, Indexed: true, Preverified: true}, list[0])
+	require.Equal(t, SnapshotStatus{Name: bodies, Type: "bodies", To: 500_000, Size: 1}, list[1])
+}
+
+func TestDbStats(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil)
+
+	stats, err := api.DbStats(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, stats.GrowthSince)
+	require.NotZero(t, stats.PageSize)
+	require.NotEmpty(t, stats.Tables)
+	for i := 1; i < len(stats.Tables); i++ {
+		require.GreaterOrEqual(t, stats.Tables[i-1].Size, stats.Tables[i].Size)
+	}
+
+	stats, err = api.DbStats(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, stats.GrowthSince)
+}
@@ -2,12 +2,21 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
+// errEnoughLogs stops the search of the logs once enough are found.
+var errEnoughLogs = errors.New("enough logs")
+
 // GetLogsByHash implements erigon_getLogsByHash. Returns an array of arrays of logs generated by the transactions in the block given by the block's hash.
 func (api *ErigonImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	tx, err := api.db.BeginRo(ctx)
@@ -40,6 +49,110 @@ func (api *ErigonImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][
 	return logs, nil
 }
 
+// GetLatestLogs implements erigon_getLatestLogs. Returns the last logCount logs matching a given filter object, in
+// order. The blocks are searched from the latest backwards, and from the genesis on unless the filter says otherwise.
+func (api *ErigonImpl) GetLatestLogs(ctx context.Context, crit filters.FilterCriteria, logCount uint64) ([]*types.Log, error) {
+	if logCount == 0 {
+		return nil, fmt.Errorf("logCount must be positive")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if crit.BlockHash == nil && crit.FromBlock == nil {
+		crit.FromBlock = big.NewInt(0)
+	}
+	blockNumbers, err := getLogsBlocks(tx, crit)
+	if err != nil {
+		return nil, err
+	}
+	var blocksLogs [][]*types.Log
+	count := uint64(0)
+	err = api.forEachBlockLogs(ctx, tx, crit, blockNumbers.ReverseIterator(), func(blockLogs []*types.Log) error {
+		if uint64(len(blockLogs)) > logCount-count {
+			blockLogs = blockLogs[uint64(len(blockLogs))-(logCount-count):]
+		}
+		blocksLogs = append(blocksLogs, blockLogs)
+		count += uint64(len(blockLogs))
+		if count == logCount {
+			return errEnoughLogs
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughLogs) {
+		return nil, err
+	}
+
+	logs := make([]*types.Log, 0, count)
+	for i := len(blocksLogs) - 1; i >= 0; i-- {
+		logs = append(logs, blocksLogs[i]...)
+	}
+	return logs, nil
+}
+
+// GetBlockReceiptsByBlockRange implements erigon_getBlockReceiptsByBlockRange. Returns an array with the receipts of each
+// block from fromBlock to toBlock included, as returned by eth_getBlockReceipts. The blocks are read in order within one
+// read transaction, and their receipts streamed block by block.
+func (api *ErigonImpl) GetBlockReceiptsByBlockRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(toBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("fromBlock %d is after toBlock %d", from, to)
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+
+	stream.WriteArrayStart()
+	for blockNum := from; blockNum <= to; blockNum++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		var block *types.Block
+		if block, err = api.blockByNumberWithSenders(tx, blockNum); err != nil {
+			break
+		}
+		if block == nil {
+			err = fmt.Errorf("block %d not found", blockNum)
+			break
+		}
+		var receipts []map[string]interface{}
+		if receipts, err = api.marshalBlockReceipts(ctx, tx, chainConfig, block); err != nil {
+			break
+		}
+		if blockNum > from {
+			stream.WriteMore()
+		}
+		stream.WriteVal(receipts)
+		if stream.Buffered() > 64*1024 {
+			if err = stream.Flush(); err != nil {
+				break
+			}
+		}
+	}
+	// The receipts written already are kept in the response, with the error if any
+	stream.WriteArrayEnd()
+	if err != nil {
+		return err
+	}
+	return stream.Flush()
+}
+
 // GetLogsByNumber implements erigon_getLogsByHash. Returns all the logs that appear in a block given the block's hash.
 // func (api *ErigonImpl) GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error) {
 // 	tx, err := api.db.Begin(ctx, false)
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLatestLogs(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethApi := NewEthAPI(base, db, nil, nil, nil, 5000000)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	all, err := ethApi.getLogs(ctx, tx, filters.FilterCriteria{FromBlock: big.NewInt(0)})
+	tx.Rollback()
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	// The last logs are found without a FromBlock, in order
+	logs, err := api.GetLatestLogs(ctx, filters.FilterCriteria{}, 1)
+	require.NoError(t, err)
+	require.Equal(t, all[len(all)-1:], logs)
+
+	logs, err = api.GetLatestLogs(ctx, filters.FilterCriteria{}, uint64(len(all)+10))
+	require.NoError(t, err)
+	require.Equal(t, all, logs)
+
+	last := all[len(all)-1]
+	logs, err = api.GetLatestLogs(ctx, filters.FilterCriteria{Addresses: []common.Address{last.Address}}, 1)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Log{last}, logs)
+
+	logs, err = api.GetLatestLogs(ctx, filters.FilterCriteria{ToBlock: new(big.Int).SetUint64(last.BlockNumber - 1)}, 1)
+	require.NoError(t, err)
+	require.Empty(t, logs)
+
+	_, err = api.GetLatestLogs(ctx, filters.FilterCriteria{}, 0)
+	require.Error(t, err)
+}
+
+func TestGetBlockReceiptsByBlockRange(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethApi := NewEthAPI(base, db, nil, nil, nil, 5000000)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.NoError(t, api.GetBlockReceiptsByBlockRange(ctx, 1, 3, stream))
+	var ranged []json.RawMessage
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &ranged))
+	require.Len(t, ranged, 3)
+
+	// The same receipts as eth_getBlockReceipts of each block
+	for i, blockNum := range []rpc.BlockNumber{1, 2, 3} {
+		receipts, err := ethApi.GetBlockReceipts(ctx, blockNum)
+		require.NoError(t, err)
+		expected, err := json.Marshal(receipts)
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(ranged[i]))
+	}
+
+	buf.Reset()
+	stream = jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.Error(t, api.GetBlockReceiptsByBlockRange(ctx, 3, 1, stream))
+}
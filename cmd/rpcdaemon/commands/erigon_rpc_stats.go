@@ -0,0 +1,14 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// RpcStats implements erigon_rpcStats. Returns the calls, the failures, the slow calls, the reads of the database and
+// the latencies of the methods served by the process since it started, or since the last call with reset, the
+// methods with the longest total time first.
+func (api *ErigonImpl) RpcStats(_ context.Context, reset *bool) (*rpc.Stats, error) {
+	return rpc.GetStats(reset != nil && *reset), nil
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+)
+
+var errTokensNotIndexed = errors.New("token transfers are not indexed, enable by adding `tokens` to --experiments of erigon")
+
+// TokenBalance - the balance of a holder implied by the indexed transfers up to BlockNumber
+type TokenBalance struct {
+	Balance     *hexutil.Big   `json:"balance"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// TokenTransfer - a transfer of an ERC-20 token (Value is the amount), or of an ERC-721 token (NFT, Value is its ID)
+type TokenTransfer struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	LogIndex         hexutil.Uint64 `json:"logIndex"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+	NFT              bool           `json:"nft"`
+}
+
+// TokenTransfers - a page of transfers, in order. NextBlock is the fromBlock of the next page, nil on the last one.
+type TokenTransfers struct {
+	Transfers []*TokenTransfer `json:"transfers"`
+	NextBlock *hexutil.Uint64  `json:"nextBlock"`
+}
+
+// GetTokenBalance implements erigon_getTokenBalance. Returns the balance of the holder implied by the Transfer events
+// of the ERC-20 token, or the number of the ERC-721 tokens it holds, as indexed by the TokenTransfers stage. The balance
+// changes without events (like the ones of rebasing tokens) aren't counted. Returns an error if the receipts of some
+// blocks were pruned before the stage indexed them: the balances are incomplete.
+func (api *ErigonImpl) GetTokenBalance(ctx context.Context, token common.Address, holder common.Address) (*TokenBalance, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	indexed, err := tokensIndexed(tx)
+	if err != nil {
+		return nil, err
+	}
+	// the genesis block has no logs
+	if indexedFrom, err := rawdb.ReadTokenTransfersIndexedFrom(tx); err != nil {
+		return nil, err
+	} else if indexedFrom > 1 {
+		return nil, fmt.Errorf("token balances are incomplete: the transfers are indexed from block %d, the receipts of the earlier blocks were pruned", indexedFrom)
+	}
+	balance, err := rawdb.ReadTokenBalance(tx, token, holder)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenBalance{Balance: (*hexutil.Big)(balance.ToBig()), BlockNumber: hexutil.Uint64(indexed)}, nil
+}
+
+// GetTokenTransfers implements erigon_getTokenTransfers. Returns the transfers of the token from or to the holder, from
+// fromBlock on. The pageSize indicates how many transfers may be returned, but the transfers of the last block are all
+// returned. The pageSize must be between 1 and MaxSearchPageSize.
+func (api *ErigonImpl) GetTokenTransfers(ctx context.Context, token common.Address, holder common.Address, fromBlock uint64, pageSize uint16) (*TokenTransfers, error) {
+	if err := checkSearchPageSize(pageSize); err != nil {
+		return nil, err
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tokensIndexed(tx); err != nil {
+		return nil, err
+	}
+	transfers, next, err := rawdb.ReadTokenTransfers(tx, token, holder, fromBlock, int(pageSize))
+	if err != nil {
+		return nil, err
+	}
+	res := &TokenTransfers{Transfers: make([]*TokenTransfer, 0, len(transfers))}
+	if next > 0 {
+		nextBlock := hexutil.Uint64(next)
+		res.NextBlock = &nextBlock
+	}
+	var block *types.Block
+	for _, t := range transfers {
+		if block == nil || block.NumberU64() != t.BlockNumber {
+			if block, err = api.blockByNumberWithSenders(tx, t.BlockNumber); err != nil {
+				return nil, err
+			}
+			if block == nil {
+				return nil, fmt.Errorf("block %d not found", t.BlockNumber)
+			}
+		}
+		if int(t.TxIndex) >= len(block.Transactions()) {
+			return nil, fmt.Errorf("transaction %d of block %d not found", t.TxIndex, t.BlockNumber)
+		}
+		transfer := &TokenTransfer{
+			BlockNumber:      hexutil.Uint64(t.BlockNumber),
+			TransactionIndex: hexutil.Uint64(t.TxIndex),
+			TransactionHash:  block.Transactions()[t.TxIndex].Hash(),
+			LogIndex:         hexutil.Uint64(t.LogIndex),
+			From:             t.Counterparty,
+			To:               t.Holder,
+			Value:            (*hexutil.Big)(t.Value.ToBig()),
+			NFT:              t.NFT,
+		}
+		if !t.Incoming {
+			transfer.From, transfer.To = t.Holder, t.Counterparty
+		}
+		res.Transfers = append(res.Transfers, transfer)
+	}
+	return res, nil
+}
+
+// tokensIndexed returns the last block indexed by the TokenTransfers stage, and an error if it's disabled
+func tokensIndexed(tx kv.Tx) (uint64, error) {
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return 0, err
+	}
+	if !pm.Experiments.Tokens {
+		return 0, errTokensNotIndexed
+	}
+	return stages.GetStageProgress(tx, stages.TokenTransfers)
+}
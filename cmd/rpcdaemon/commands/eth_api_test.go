@@ -1,15 +1,22 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"testing"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
@@ -36,6 +43,62 @@ func TestGetTransactionReceiptUnprotected(t *testing.T) {
 	}
 }
 
+func TestGetLogs(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+	getLogs := func(crit filters.FilterCriteria) ([]*types.Log, error) {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		if err := api.GetLogs(context.Background(), crit, stream); err != nil {
+			require.Zero(t, buf.Len(), "nothing is written on failure")
+			return nil, err
+		}
+		var logs []*types.Log
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &logs))
+		return logs, nil
+	}
+
+	logs, err := getLogs(filters.FilterCriteria{FromBlock: big.NewInt(0)})
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	for i := 1; i < len(logs); i++ {
+		require.LessOrEqual(t, logs[i-1].BlockNumber, logs[i].BlockNumber)
+	}
+
+	last := logs[len(logs)-1]
+	filtered, err := getLogs(filters.FilterCriteria{
+		FromBlock: new(big.Int).SetUint64(last.BlockNumber),
+		ToBlock:   new(big.Int).SetUint64(last.BlockNumber),
+		Addresses: []common.Address{last.Address},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, filtered)
+	for _, log := range filtered {
+		require.Equal(t, last.BlockNumber, log.BlockNumber)
+		require.Equal(t, last.Address, log.Address)
+	}
+
+	// The topics are looked up within the blocks of the addresses
+	filtered, err = getLogs(filters.FilterCriteria{
+		FromBlock: big.NewInt(0),
+		Addresses: []common.Address{last.Address},
+		Topics:    [][]common.Hash{{last.Topics[0]}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, filtered, last)
+	filtered, err = getLogs(filters.FilterCriteria{
+		FromBlock: big.NewInt(0),
+		Addresses: []common.Address{{1}},
+		Topics:    [][]common.Hash{{last.Topics[0]}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, filtered)
+
+	_, err = getLogs(filters.FilterCriteria{FromBlock: big.NewInt(2), ToBlock: big.NewInt(1)})
+	require.Error(t, err)
+}
+
 // EIP-1898 test cases
 
 func TestGetStorageAt_ByBlockNumber_WithRequireCanonicalDefault(t *testing.T) {
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// BalanceChange - the balance of an account before and after a block
+type BalanceChange struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// BalanceTimeline - the balance of an account before FromBlock, and after each block up to ToBlock changing it
+type BalanceTimeline struct {
+	FromBlock hexutil.Uint64         `json:"fromBlock"`
+	ToBlock   hexutil.Uint64         `json:"toBlock"`
+	Balance   *hexutil.Big           `json:"balance"`
+	Changes   []*BalanceTimelineItem `json:"changes"`
+}
+
+type BalanceTimelineItem struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Balance     *hexutil.Big   `json:"balance"`
+}
+
+// GetBalanceChangesInBlock implements eth_getBalanceChangesInBlock. Returns the accounts whose balance is changed by
+// the block, with their balance before and after it, read from the change sets of the block.
+func (api *APIImpl) GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*BalanceChange, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNumber, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkBalanceHistory(tx, blockNumber, blockNumber); err != nil {
+		return nil, err
+	}
+
+	after := state.NewPlainState(tx, blockNumber+1)
+	changes := map[common.Address]*BalanceChange{}
+	if err := changeset.ForPrefix(tx, kv.AccountChangeSet, dbutils.EncodeBlockNumber(blockNumber), func(_ uint64, k, v []byte) error {
+		from, err := balanceOf(v)
+		if err != nil {
+			return err
+		}
+		address := common.BytesToAddress(k)
+		acc, err := after.ReadAccountData(address)
+		if err != nil {
+			return err
+		}
+		to := new(big.Int)
+		if acc != nil {
+			to = acc.Balance.ToBig()
+		}
+		if from.Cmp(to) != 0 {
+			changes[address] = &BalanceChange{From: (*hexutil.Big)(from), To: (*hexutil.Big)(to)}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetBalanceTimeline implements eth_getBalanceTimeline. Returns the balance of the account before fromBlock, and its
+// balance after each block from fromBlock to toBlock which changes it. The blocks are found by the accounts history
+// index, and the balances read from the change sets.
+func (api *APIImpl) GetBalanceTimeline(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber) (*BalanceTimeline, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(toBlock), tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %d is after toBlock %d", from, to)
+	}
+	if err = checkBalanceHistory(tx, from, to); err != nil {
+		return nil, err
+	}
+
+	// the blocks changing the account: from the index, then from the change sets of the blocks it doesn't cover yet
+	indexed, err := stages.GetStageProgress(tx, stages.AccountHistoryIndex)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []uint64
+	if from <= indexed {
+		m, err := bitmapdb.Get64(tx, kv.AccountsHistory, address[:], from, to)
+		if err != nil {
+			return nil, err
+		}
+		m.RemoveRange(0, from)
+		m.RemoveRange(to+1, uint64(0x100000000))
+		blocks = m.ToArray()
+	}
+	changes, err := tx.CursorDupSort(kv.AccountChangeSet)
+	if err != nil {
+		return nil, err
+	}
+	defer changes.Close()
+	unindexed := indexed + 1
+	if unindexed < from {
+		unindexed = from
+	}
+	for n := unindexed; n <= to; n++ {
+		v, err := changeset.FindAccount(changes, n, address[:])
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			blocks = append(blocks, n)
+		}
+	}
+
+	startAcc, err := state.NewPlainState(tx, from).ReadAccountData(address)
+	if err != nil {
+		return nil, err
+	}
+	balance := new(big.Int)
+	if startAcc != nil {
+		balance = startAcc.Balance.ToBig()
+	}
+	res := &BalanceTimeline{FromBlock: hexutil.Uint64(from), ToBlock: hexutil.Uint64(to), Balance: (*hexutil.Big)(balance), Changes: []*BalanceTimelineItem{}}
+	for i, n := range blocks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// the balance after the block is the one before the next change, or the one after toBlock
+		var after *big.Int
+		if i+1 < len(blocks) {
+			v, err := changeset.FindAccount(changes, blocks[i+1], address[:])
+			if err != nil {
+				return nil, err
+			}
+			if after, err = balanceOf(v); err != nil {
+				return nil, err
+			}
+		} else {
+			acc, err := state.NewPlainState(tx, to+1).ReadAccountData(address)
+			if err != nil {
+				return nil, err
+			}
+			after = new(big.Int)
+			if acc != nil {
+				after = acc.Balance.ToBig()
+			}
+		}
+		if after.Cmp(balance) != 0 {
+			res.Changes = append(res.Changes, &BalanceTimelineItem{BlockNumber: hexutil.Uint64(n), Balance: (*hexutil.Big)(after)})
+			balance = after
+		}
+	}
+	return res, nil
+}
+
+// checkBalanceHistory - the blocks from-to must be executed, and their change sets not pruned
+func checkBalanceHistory(tx kv.Tx, from, to uint64) error {
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if to > executed {
+		return fmt.Errorf("block %d is not executed yet, executed blocks: %d", to, executed)
+	}
+	availableFrom, err := changeset.AvailableFrom(tx)
+	if err != nil {
+		return err
+	}
+	if from < availableFrom {
+		return fmt.Errorf("history of block %d is pruned, available from block %d", from, availableFrom)
+	}
+	return nil
+}
+
+// balanceOf - the balance of an account encoded for storage, zero if it doesn't exist
+func balanceOf(enc []byte) (*big.Int, error) {
+	if len(enc) == 0 {
+		return new(big.Int), nil
+	}
+	var acc accounts.Account
+	if err := acc.DecodeForStorage(enc); err != nil {
+		return nil, err
+	}
+	return acc.Balance.ToBig(), nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+// The balances before and after each block, read from the change sets, are the ones eth_getBalance returns at the
+// previous block and at the block
+func TestGetBalanceChangesInBlock(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+
+	for n := rpc.BlockNumber(1); n <= 10; n++ {
+		changes, err := api.GetBalanceChangesInBlock(context.Background(), rpc.BlockNumberOrHashWithNumber(n))
+		require.NoError(t, err)
+		require.NotEmpty(t, changes)
+		for address, change := range changes {
+			before, err := api.GetBalance(context.Background(), address, rpc.BlockNumberOrHashWithNumber(n-1))
+			require.NoError(t, err)
+			after, err := api.GetBalance(context.Background(), address, rpc.BlockNumberOrHashWithNumber(n))
+			require.NoError(t, err)
+			require.Equal(t, before.String(), change.From.String(), "block %d, account %x", n, address)
+			require.Equal(t, after.String(), change.To.String(), "block %d, account %x", n, address)
+		}
+	}
+	_, err := api.GetBalanceChangesInBlock(context.Background(), rpc.BlockNumberOrHashWithNumber(11))
+	require.Error(t, err)
+}
+
+// The timeline follows the balance eth_getBalance returns at each block of the range
+func TestGetBalanceTimeline(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+	address := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+
+	timeline, err := api.GetBalanceTimeline(context.Background(), address, 2, 9)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, timeline.FromBlock)
+	require.EqualValues(t, 9, timeline.ToBlock)
+	require.NotEmpty(t, timeline.Changes)
+
+	balance := timeline.Balance.String()
+	changes := timeline.Changes
+	for n := rpc.BlockNumber(1); n <= 9; n++ {
+		if len(changes) > 0 && rpc.BlockNumber(changes[0].BlockNumber) == n {
+			require.NotEqual(t, balance, changes[0].Balance.String(), "block %d", n)
+			balance = changes[0].Balance.String()
+			changes = changes[1:]
+		}
+		expected, err := api.GetBalance(context.Background(), address, rpc.BlockNumberOrHashWithNumber(n))
+		require.NoError(t, err)
+		require.Equal(t, expected.String(), balance, "block %d", n)
+	}
+	require.Empty(t, changes)
+
+	_, err = api.GetBalanceTimeline(context.Background(), address, 5, 4)
+	require.Error(t, err)
+}
@@ -1,20 +1,32 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands/contracts"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEstimateGas(t *testing.T) {
@@ -261,3 +273,122 @@ func TestGetBlockByTimestamp(t *testing.T) {
 		t.Errorf("Retrieved the wrong block.\nexpected block hash: %s expected timestamp: %d\nblock hash retrieved: %s timestamp retrieved: %d", response["hash"], response["timestamp"], block["hash"], block["timestamp"])
 	}
 }
+
+func TestGetProof(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+	ctx := context.Background()
+	sender := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	token := crypto.CreateAddress(sender, 2) // deployed by block 3, mints to address2 in block 4
+	key2, err := crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	require.NoError(t, err)
+	address2 := crypto.PubkeyToAddress(key2.PublicKey)
+	// the slot of the balance of address2, and the one of the total supply
+	balanceSlot := crypto.Keccak256Hash(common.LeftPadBytes(address2[:], 32), common.LeftPadBytes([]byte{1}, 32))
+	storageKeys := []string{balanceSlot.Hex(), "0x0"}
+
+	latest, err := api.BlockNumber(ctx)
+	require.NoError(t, err)
+	// the older blocks need the trie to be unwound in memory
+	for _, number := range []rpc.BlockNumber{rpc.BlockNumber(latest), 5, 4, 2} {
+		blockNrOrHash := rpc.BlockNumberOrHashWithNumber(number)
+		header, err := api.GetBlockByNumber(ctx, number, false)
+		require.NoError(t, err)
+		for _, address := range []common.Address{sender, token, {0xde, 0xad}} {
+			res, err := api.GetProof(ctx, address, storageKeys, blockNrOrHash)
+			require.NoError(t, err)
+			verifyProof(t, header["stateRoot"].(common.Hash), res.AccountProof)
+			balance, err := api.GetBalance(ctx, address, blockNrOrHash)
+			require.NoError(t, err)
+			require.Equal(t, balance.String(), res.Balance.String(), "block %d", number)
+			for i, key := range storageKeys {
+				value, err := api.GetStorageAt(ctx, address, key, blockNrOrHash)
+				require.NoError(t, err)
+				require.Equal(t, common.HexToHash(value).Big().String(), res.StorageProof[i].Value.ToInt().String(), "block %d", number)
+				if res.StorageHash != trie.EmptyRoot {
+					verifyProof(t, res.StorageHash, res.StorageProof[i].Proof)
+				}
+			}
+			if address == token && number >= 4 {
+				require.NotZero(t, res.StorageProof[0].Value.ToInt().Sign())
+			}
+		}
+	}
+
+	// the change sets of the blocks before 6 are pruned
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		pm, err := prune.Get(tx)
+		if err != nil {
+			return err
+		}
+		pm.History = prune.Distance(4)
+		return prune.Override(tx, pm)
+	}))
+	_, err = api.GetProof(ctx, sender, nil, rpc.BlockNumberOrHashWithNumber(4))
+	require.ErrorContains(t, err, "pruned")
+	_, err = api.GetProof(ctx, sender, nil, rpc.BlockNumberOrHashWithNumber(5))
+	require.NoError(t, err)
+}
+
+// verifyProof checks that the proof is a path of nodes from the root
+func verifyProof(t *testing.T, root common.Hash, proof []string) {
+	require.NotEmpty(t, proof)
+	hash := root[:]
+	for i, node := range proof {
+		enc := common.FromHex(node)
+		if i == 0 || len(enc) >= 32 {
+			require.Equal(t, hash, crypto.Keccak256(enc), "node %d", i)
+		} else {
+			require.True(t, bytes.Contains(hash, enc), "node %d", i)
+		}
+		if i+1 < len(proof) {
+			if next := common.FromHex(proof[i+1]); len(next) >= 32 {
+				hash = crypto.Keccak256(next)
+				require.True(t, bytes.Contains(enc, hash), "node %d", i)
+			} else {
+				hash = enc
+			}
+		}
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+	ctx := context.Background()
+	sender := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	token := crypto.CreateAddress(sender, 2)
+	tokenABI, err := abi.JSON(strings.NewReader(contracts.TokenABI))
+	require.NoError(t, err)
+	data, err := tokenABI.Pack("transfer", common.Address{0xaa}, big.NewInt(1))
+	require.NoError(t, err)
+	input := hexutil.Bytes(data)
+	// the sender got 3 tokens in block 5
+	args := ethapi.CallArgs{From: &sender, To: &token, Data: &input}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	optimize := false
+	res, err := api.CreateAccessList(ctx, args, &latest, &optimize)
+	require.NoError(t, err)
+	require.Empty(t, res.Error)
+	require.Len(t, *res.Accesslist, 1)
+	require.Equal(t, token, (*res.Accesslist)[0].Address)
+	require.Len(t, (*res.Accesslist)[0].StorageKeys, 2) // the balances of the sender and of the recipient
+
+	// the token is warm anyway, listing its two slots doesn't pay for it: the gas used is the one without it
+	optimize = true
+	optimized, err := api.CreateAccessList(ctx, args, &latest, &optimize)
+	require.NoError(t, err)
+	require.Empty(t, *optimized.Accesslist)
+	saving := params.TxAccessListAddressGas - 2*(params.ColdSloadCostEIP2929-params.WarmStorageReadCostEIP2929-params.TxAccessListStorageKeyGas)
+	require.Equal(t, uint64(res.GasUsed)-saving, uint64(optimized.GasUsed))
+
+	// the address of a created contract follows the nonce of the sender's account without a pool
+	create := ethapi.CallArgs{From: &sender, Data: &hexutil.Bytes{0x00}}
+	res, err = api.CreateAccessList(ctx, create, &latest, nil)
+	require.NoError(t, err)
+	require.Empty(t, res.Error)
+	require.Empty(t, *res.Accesslist)
+}
@@ -3,13 +3,17 @@ package commands
 import (
 	"context"
 
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/time/rate"
 )
 
 // NewPendingTransactionFilter new transaction filter
@@ -177,8 +181,13 @@ func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	return rpcSub, nil
 }
 
-// NewPendingTransactions send a notification each time a new (header) block is appended to the chain.
-func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// pendingTxsLimiterKey is the connection value of the rate limiter shared by the newPendingTransactions
+// subscriptions of a connection.
+type pendingTxsLimiterKey struct{}
+
+// NewPendingTransactions send a notification each time a transaction is added to the txpool: its hash, or the
+// transaction itself if fullTx is true. The transactions over the PendingTxsRate of the connection are dropped.
+func (api *APIImpl) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	if api.filters == nil {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
@@ -186,29 +195,186 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	var limiter *rate.Limiter
+	if api.PendingTxsRate > 0 {
+		limiter = notifier.ConnectionValue(pendingTxsLimiterKey{}, func() interface{} {
+			return rate.NewLimiter(rate.Limit(api.PendingTxsRate), api.PendingTxsRate)
+		}).(*rate.Limiter)
+	}
+	full := fullTx != nil && *fullTx
 
 	rpcSub := notifier.CreateSubscription()
 
+	// the transactions added once the subscription is returned are notified
+	txsCh := make(chan []types.Transaction, 1)
+	id := api.filters.SubscribePendingTxs(txsCh)
+
 	go func() {
 		defer debug.LogPanic()
-		txsCh := make(chan []types.Transaction, 1)
-		id := api.filters.SubscribePendingTxs(txsCh)
 		defer api.filters.UnsubscribePendingTxs(id)
 
 		for {
 			select {
 			case txs, ok := <-txsCh:
+				var dropped int
+				var current *types.Header
+				var cc *params.ChainConfig
+				if full && len(txs) > 0 {
+					var err error
+					if current, cc, err = api.pendingTxsContext(); err != nil {
+						log.Warn("error while reading the head of the pending transactions", "err", err)
+						return
+					}
+				}
 				for _, t := range txs {
-					if t != nil {
-						err := notifier.Notify(rpcSub.ID, t.Hash())
-						if err != nil {
+					if t == nil {
+						continue
+					}
+					// the filters don't wait for the slow subscribers, so the limit drops instead of waiting too
+					if limiter != nil && !limiter.Allow() {
+						dropped++
+						continue
+					}
+					var payload interface{} = t.Hash()
+					if full {
+						payload = newRPCPendingTransaction(t, current, cc)
+					}
+					if err := notifier.Notify(rpcSub.ID, payload); err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+						return
+					}
+				}
+				if dropped > 0 {
+					log.Debug("pending transactions over the rate limit of the connection dropped", "subscription", rpcSub.ID, "dropped", dropped)
+				}
+				if !ok {
+					log.Warn("new pending transactions channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// Reasons a transaction left the txpool for.
+const (
+	TxRemovedMined    = "mined"
+	TxRemovedReplaced = "replaced"
+	TxRemovedDropped  = "dropped"
+)
+
+// DroppedTransaction is the notification of a transaction which has left the txpool.
+type DroppedTransaction struct {
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	Reason     string         `json:"reason"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+}
+
+// ReorgNotification - see Reorgs
+type ReorgNotification struct {
+	CommonAncestor       common.Hash    `json:"commonAncestor"`
+	CommonAncestorNumber hexutil.Uint64 `json:"commonAncestorNumber"`
+	Removed              []common.Hash  `json:"removed"`
+	Added                []common.Hash  `json:"added"`
+}
+
+// Reorgs send a notification each time the canonical chain is unwound, with the common ancestor of the chains and
+// the hashes of the removed and added blocks, from the lowest. Removed blocks are only the ones seen since
+// rpcdaemon has started (up to 1024).
+func (api *APIImpl) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		reorgsCh := make(chan *rpchelper.Reorg, 1)
+		id := api.filters.SubscribeReorgs(reorgsCh)
+		defer api.filters.UnsubscribeReorgs(id)
+
+		for {
+			select {
+			case r, ok := <-reorgsCh:
+				if r != nil {
+					n := ReorgNotification{
+						CommonAncestor:       r.CommonAncestor,
+						CommonAncestorNumber: hexutil.Uint64(r.CommonAncestorNumber),
+						Removed:              r.Removed,
+						Added:                r.Added,
+					}
+					if n.Removed == nil {
+						n.Removed = []common.Hash{}
+					}
+					if n.Added == nil {
+						n.Added = []common.Hash{}
+					}
+					if err := notifier.Notify(rpcSub.ID, n); err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+						return
+					}
+				}
+				if !ok {
+					log.Warn("reorgs channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// DroppedPendingTransactions send a notification each time a transaction leaves the txpool, with the reason: mined,
+// replaced by another transaction with the same sender and nonce, or dropped. The txpool is checked at each new block.
+func (api *APIImpl) DroppedPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		removedCh := make(chan []rpchelper.RemovedTx, 1)
+		id := api.filters.SubscribeRemovedTxs(removedCh)
+		defer api.filters.UnsubscribeRemovedTxs(id)
+
+		for {
+			select {
+			case removed, ok := <-removedCh:
+				if len(removed) > 0 {
+					dropped, err := api.droppedTransactions(removed)
+					if err != nil {
+						log.Warn("error while reading the removed transactions", "err", err)
+						return
+					}
+					for _, d := range dropped {
+						if err := notifier.Notify(rpcSub.ID, d); err != nil {
 							log.Warn("error while notifying subscription", "err", err)
 							return
 						}
 					}
 				}
 				if !ok {
-					log.Warn("new pending transactions channel was closed")
+					log.Warn("dropped pending transactions channel was closed")
 					return
 				}
 			case <-rpcSub.Err():
@@ -220,6 +386,48 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 	return rpcSub, nil
 }
 
+// droppedTransactions returns the notifications of the given transactions removed from the txpool: those not
+// replaced are mined if the chain has them.
+func (api *APIImpl) droppedTransactions(removed []rpchelper.RemovedTx) ([]*DroppedTransaction, error) {
+	tx, err := api.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	dropped := make([]*DroppedTransaction, 0, len(removed))
+	for _, r := range removed {
+		d := &DroppedTransaction{Hash: r.Hash, From: r.Sender, Nonce: hexutil.Uint64(r.Nonce), ReplacedBy: r.ReplacedBy}
+		if r.ReplacedBy != nil {
+			d.Reason = TxRemovedReplaced
+		} else {
+			blockNum, err := rawdb.ReadTxLookupEntry(tx, r.Hash)
+			if err != nil {
+				return nil, err
+			}
+			d.Reason = TxRemovedDropped
+			if blockNum != nil {
+				d.Reason = TxRemovedMined
+			}
+		}
+		dropped = append(dropped, d)
+	}
+	return dropped, nil
+}
+
+// pendingTxsContext returns the head and the chain config the pending transactions are represented with.
+func (api *APIImpl) pendingTxsContext() (*types.Header, *params.ChainConfig, error) {
+	tx, err := api.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rawdb.ReadCurrentHeader(tx), cc, nil
+}
+
 // Logs send a notification each time a new log appears.
 func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
 	if api.filters == nil {
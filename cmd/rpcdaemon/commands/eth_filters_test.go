@@ -1,16 +1,26 @@
 package commands
 
 import (
+	"bytes"
+	"context"
 	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewFilters(t *testing.T) {
@@ -43,3 +53,45 @@ func TestNewFilters(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(ok, true)
 }
+
+func TestNewPendingTransactionsRate(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	var txs [][]byte
+	for nonce := uint64(0); nonce < 5; nonce++ {
+		txn := types.NewTransaction(nonce, common.Address{1}, uint256.NewInt(1), params.TxGas, uint256.NewInt(1), nil)
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, txn.MarshalBinary(buf))
+		txs = append(txs, buf.Bytes())
+	}
+
+	// without a rate all the transactions are notified, with one those over it are dropped
+	for _, tc := range []struct{ rate, notified int }{{0, 5}, {2, 2}} {
+		ff := rpchelper.New(context.Background(), nil, nil, nil, func() {})
+		api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+		api.PendingTxsRate = tc.rate
+		server := rpc.NewServer(50, false, true)
+		require.NoError(t, server.RegisterName("eth", api))
+		client := rpc.DialInProc(server)
+
+		hashes := make(chan common.Hash, len(txs))
+		sub, err := client.EthSubscribe(context.Background(), hashes, "newPendingTransactions")
+		require.NoError(t, err)
+		ff.OnNewTx(&txpool.OnAddReply{RplTxs: txs})
+		for i := 0; i < tc.notified; i++ {
+			select {
+			case <-hashes:
+			case <-time.After(time.Second):
+				t.Fatalf("rate %d: %d transactions notified, expected %d", tc.rate, i, tc.notified)
+			}
+		}
+		select {
+		case hash := <-hashes:
+			t.Fatalf("rate %d: transaction %x over the rate notified", tc.rate, hash)
+		case <-time.After(100 * time.Millisecond):
+		}
+		sub.Unsubscribe()
+		client.Close()
+		server.Stop()
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"math/big"
 
 	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/log/v3"
 
@@ -70,17 +71,60 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 	return receipts, nil
 }
 
-// GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object.
-func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
-	var begin, end uint64
-	logs := []*types.Log{}
-
-	tx, beginErr := api.db.BeginRo(ctx)
-	if beginErr != nil {
-		return logs, beginErr
+// GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object, streamed block by block.
+func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
 	}
 	defer tx.Rollback()
 
+	blockNumbers, err := getLogsBlocks(tx, crit)
+	if err != nil {
+		return err
+	}
+	first := true
+	stream.WriteArrayStart()
+	err = api.forEachBlockLogs(ctx, tx, crit, blockNumbers.Iterator(), func(blockLogs []*types.Log) error {
+		for _, log := range blockLogs {
+			if !first {
+				stream.WriteMore()
+			}
+			first = false
+			stream.WriteVal(log)
+		}
+		if stream.Buffered() > 64*1024 {
+			return stream.Flush()
+		}
+		return nil
+	})
+	// The logs written already are kept in the response, with the error if any
+	stream.WriteArrayEnd()
+	if err != nil {
+		return err
+	}
+	return stream.Flush()
+}
+
+func (api *APIImpl) getLogs(ctx context.Context, tx kv.Tx, crit filters.FilterCriteria) ([]*types.Log, error) {
+	blockNumbers, err := getLogsBlocks(tx, crit)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*types.Log{}
+	if err := api.forEachBlockLogs(ctx, tx, crit, blockNumbers.Iterator(), func(blockLogs []*types.Log) error {
+		logs = append(logs, blockLogs...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// getLogsBlocks returns the numbers of the blocks which may contain the logs matching the given filter, according to
+// the log indices.
+func getLogsBlocks(tx kv.Tx, crit filters.FilterCriteria) (*roaring.Bitmap, error) {
+	var begin, end uint64
 	if crit.BlockHash != nil {
 		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
 		if number == nil {
@@ -119,14 +163,6 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 	blockNumbers := roaring.New()
 	blockNumbers.AddRange(begin, end+1) // [min,max)
 
-	topicsBitmap, err := getTopicsBitmap(tx, crit.Topics, uint32(begin), uint32(end))
-	if err != nil {
-		return nil, err
-	}
-	if topicsBitmap != nil {
-		blockNumbers.And(topicsBitmap)
-	}
-
 	var addrBitmap *roaring.Bitmap
 	for _, addr := range crit.Addresses {
 		m, err := bitmapdb.Get(tx, kv.LogAddressIndex, addr[:], uint32(begin), uint32(end))
@@ -142,16 +178,29 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 
 	if addrBitmap != nil {
 		blockNumbers.And(addrBitmap)
+		if blockNumbers.IsEmpty() {
+			return blockNumbers, nil
+		}
+		// The shards of the topics outside of the blocks of the addresses aren't read: the common topics have many
+		begin, end = uint64(blockNumbers.Minimum()), uint64(blockNumbers.Maximum())
 	}
 
-	if blockNumbers.GetCardinality() == 0 {
-		return logs, nil
+	topicsBitmap, err := getTopicsBitmap(tx, crit.Topics, uint32(begin), uint32(end))
+	if err != nil {
+		return nil, err
 	}
+	if topicsBitmap != nil {
+		blockNumbers.And(topicsBitmap)
+	}
+	return blockNumbers, nil
+}
 
-	iter := blockNumbers.Iterator()
+// forEachBlockLogs calls f with the logs matching the given filter of each of the given blocks, in the order of the
+// iterator, skipping the blocks without any.
+func (api *BaseAPI) forEachBlockLogs(ctx context.Context, tx kv.Tx, crit filters.FilterCriteria, iter roaring.IntIterable, f func(blockLogs []*types.Log) error) error {
 	for iter.HasNext() {
-		if err = ctx.Err(); err != nil {
-			return nil, err
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
 		block := uint64(iter.Next())
@@ -179,7 +228,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 			return nil
 		})
 		if err != nil {
-			return logs, err
+			return err
 		}
 		if len(blockLogs) == 0 {
 			continue
@@ -187,10 +236,10 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 
 		b, err := api.blockByNumberWithSenders(tx, block)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if b == nil {
-			return nil, fmt.Errorf("block not found %d", block)
+			return fmt.Errorf("block not found %d", block)
 		}
 		blockHash := b.Hash()
 		for _, log := range blockLogs {
@@ -198,10 +247,11 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 			log.BlockHash = blockHash
 			log.TxHash = b.Transactions()[log.TxIndex].Hash()
 		}
-		logs = append(logs, blockLogs...)
+		if err := f(blockLogs); err != nil {
+			return err
+		}
 	}
-
-	return logs, nil
+	return nil
 }
 
 // The Topic list restricts matches to particular event topics. Each event has a list
@@ -350,6 +400,11 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, number rpc.BlockNumber
 	if err != nil {
 		return nil, err
 	}
+	return api.marshalBlockReceipts(ctx, tx, chainConfig, block)
+}
+
+// marshalBlockReceipts - the receipts of all the transactions of the block, as returned by eth_getBlockReceipts
+func (api *BaseAPI) marshalBlockReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block) ([]map[string]interface{}, error) {
 	receipts, err := api.getReceipts(ctx, tx, chainConfig, block, block.Body().SendersFromTxs())
 	if err != nil {
 		return nil, fmt.Errorf("getReceipts error: %w", err)
@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb"
+	rpcapi "github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks a single eth_simulateV1 request may simulate.
+	maxSimulateBlocks = 256
+	// simulateTimeout is the time a single eth_simulateV1 request may take.
+	simulateTimeout = 5 * time.Second
+)
+
+// SimulateBlock is a block of calls to simulate, with the overrides of its fields and of the state it starts from.
+type SimulateBlock struct {
+	BlockOverrides *rpcapi.BlockOverrides `json:"blockOverrides"`
+	StateOverrides *rpcapi.StateOverrides `json:"stateOverrides"`
+	Calls          []rpcapi.CallArgs      `json:"calls"`
+}
+
+// SimulateOptions are the blocks to simulate, one after another, on top of the requested block.
+type SimulateOptions struct {
+	BlockStateCalls []SimulateBlock `json:"blockStateCalls"`
+	Validation      bool            `json:"validation"`
+}
+
+// SimulatedBlock is the result of the simulation of a block.
+type SimulatedBlock struct {
+	Number        hexutil.Uint64  `json:"number"`
+	Hash          common.Hash     `json:"hash"`
+	ParentHash    common.Hash     `json:"parentHash"`
+	Timestamp     hexutil.Uint64  `json:"timestamp"`
+	GasLimit      hexutil.Uint64  `json:"gasLimit"`
+	GasUsed       hexutil.Uint64  `json:"gasUsed"`
+	Miner         common.Address  `json:"miner"`
+	BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	Calls         []SimulatedCall `json:"calls"`
+}
+
+// SimulatedCall is the result of a simulated call.
+type SimulatedCall struct {
+	Status     hexutil.Uint64      `json:"status"`
+	GasUsed    hexutil.Uint64      `json:"gasUsed"`
+	ReturnData hexutil.Bytes       `json:"returnData"`
+	Logs       []*types.Log        `json:"logs"`
+	Error      *SimulatedCallError `json:"error,omitempty"`
+}
+
+// SimulatedCallError is the reason a simulated call failed: the revert reason with code 3, or the EVM error.
+type SimulatedCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// SimulateV1 implements eth_simulateV1. Executes the calls of the given blocks on top of the given block, each block
+// on top of the previous one, and returns their results, logs and gas used.
+func (api *APIImpl) SimulateV1(ctx context.Context, opts SimulateOptions, blockNrOrHash *rpc.BlockNumberOrHash) ([]*SimulatedBlock, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks: %d, the maximum is %d", len(opts.BlockStateCalls), maxSimulateBlocks)
+	}
+	if opts.Validation {
+		return nil, fmt.Errorf("validation of the simulated transactions is not supported")
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) { log.Trace("Executing EVM simulateV1 finished", "runtime", time.Since(start)) }(time.Now())
+
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	blockNumber, hash, _, err := rpchelper.GetCanonicalBlockNumber(*blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := api._blockReader.Header(ctx, tx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, *blockNrOrHash, api.filters, api.stateCache, api.stateOverlay)
+	if err != nil {
+		return nil, err
+	}
+	ibs := state.New(stateReader)
+
+	contractHasTEVM := func(contractHash common.Hash) (bool, error) { return false, nil }
+	if api.TevmEnabled {
+		contractHasTEVM = ethdb.GetHasTEVM(tx)
+	}
+	// The hashes of the simulated blocks are known to the following ones
+	simulatedHashes := make(map[uint64]common.Hash)
+	getHash := func(i uint64) common.Hash {
+		if hash, ok := simulatedHashes[i]; ok {
+			return hash
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, i)
+		if err != nil {
+			log.Debug("Can't get block hash by number", "number", i, "only-canonical", true)
+		}
+		return hash
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+
+	results := make([]*SimulatedBlock, 0, len(opts.BlockStateCalls))
+	for _, block := range opts.BlockStateCalls {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   parent.Coinbase,
+			Difficulty: new(big.Int).Set(parent.Difficulty),
+			Number:     new(big.Int).SetUint64(parent.Number.Uint64() + 1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 12,
+		}
+		var baseFee *uint256.Int
+		if chainConfig.IsLondon(header.Number.Uint64()) {
+			baseFee, _ = uint256.FromBig(misc.CalcBaseFee(chainConfig, parent))
+		}
+		blockCtx := vm.BlockContext{
+			CanTransfer:     core.CanTransfer,
+			Transfer:        core.Transfer,
+			GetHash:         getHash,
+			ContractHasTEVM: contractHasTEVM,
+			Coinbase:        header.Coinbase,
+			BlockNumber:     header.Number.Uint64(),
+			Time:            header.Time,
+			Difficulty:      header.Difficulty,
+			GasLimit:        header.GasLimit,
+			BaseFee:         baseFee,
+		}
+		if block.BlockOverrides != nil {
+			if err := block.BlockOverrides.Override(&blockCtx); err != nil {
+				return nil, err
+			}
+		}
+		if blockCtx.BlockNumber <= parent.Number.Uint64() {
+			return nil, fmt.Errorf("block number %d not after the parent %d", blockCtx.BlockNumber, parent.Number.Uint64())
+		}
+		if blockCtx.Time <= parent.Time {
+			return nil, fmt.Errorf("block timestamp %d not after the parent %d", blockCtx.Time, parent.Time)
+		}
+		header.Number.SetUint64(blockCtx.BlockNumber)
+		header.Time = blockCtx.Time
+		header.GasLimit = blockCtx.GasLimit
+		header.Coinbase = blockCtx.Coinbase
+		header.Difficulty = blockCtx.Difficulty
+		if blockCtx.BaseFee != nil {
+			header.Eip1559 = true
+			header.BaseFee = blockCtx.BaseFee.ToBig()
+		}
+
+		if block.StateOverrides != nil {
+			if err := block.StateOverrides.Override(ibs); err != nil {
+				return nil, err
+			}
+		}
+
+		calls, gasUsed, err := api.simulateCalls(ctx, chainConfig, ibs, blockCtx, block.Calls)
+		if err != nil {
+			return nil, err
+		}
+		header.GasUsed = gasUsed
+
+		// The logs of the block only get its hash once all its calls are done
+		blockHash := header.Hash()
+		for _, call := range calls {
+			for _, l := range call.Logs {
+				l.BlockHash = blockHash
+				l.BlockNumber = blockCtx.BlockNumber
+			}
+		}
+		simulatedHashes[blockCtx.BlockNumber] = blockHash
+
+		result := &SimulatedBlock{
+			Number:     hexutil.Uint64(blockCtx.BlockNumber),
+			Hash:       blockHash,
+			ParentHash: header.ParentHash,
+			Timestamp:  hexutil.Uint64(header.Time),
+			GasLimit:   hexutil.Uint64(header.GasLimit),
+			GasUsed:    hexutil.Uint64(gasUsed),
+			Miner:      header.Coinbase,
+			Calls:      calls,
+		}
+		if header.BaseFee != nil {
+			result.BaseFeePerGas = (*hexutil.Big)(header.BaseFee)
+		}
+		results = append(results, result)
+		parent = header
+	}
+	return results, nil
+}
+
+// simulateCalls executes the calls of a simulated block in order, each on top of the state the previous one leaves,
+// and returns their results and the gas they used.
+func (api *APIImpl) simulateCalls(ctx context.Context, chainConfig *params.ChainConfig, ibs *state.IntraBlockState, blockCtx vm.BlockContext, args []rpcapi.CallArgs) ([]SimulatedCall, uint64, error) {
+	gp := new(core.GasPool).AddGas(blockCtx.GasLimit)
+	calls := make([]SimulatedCall, 0, len(args))
+	for i, call := range args {
+		// The calls without a gas limit may use all the gas the block has left
+		if call.Gas == nil || *call.Gas == 0 {
+			gas := hexutil.Uint64(gp.Gas())
+			call.Gas = &gas
+		}
+		msg, err := call.ToMessage(api.GasCap, blockCtx.BaseFee)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// The simulated calls have no transaction hash: the logs are told apart by a hash of their position instead
+		var key [16]byte
+		binary.BigEndian.PutUint64(key[:8], blockCtx.BlockNumber)
+		binary.BigEndian.PutUint64(key[8:], uint64(i))
+		txHash := crypto.Keccak256Hash(key[:])
+		ibs.Prepare(txHash, common.Hash{}, i)
+
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), ibs, chainConfig, vm.Config{NoBaseFee: true})
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		close(done)
+		if err != nil {
+			return nil, 0, fmt.Errorf("call %d: %w", i, err)
+		}
+		if evm.Cancelled() {
+			return nil, 0, fmt.Errorf("execution aborted (timeout = %v)", simulateTimeout)
+		}
+		if err := ibs.FinalizeTx(evm.ChainRules(), state.NewNoopWriter()); err != nil {
+			return nil, 0, err
+		}
+
+		simulated := SimulatedCall{
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			ReturnData: result.Return(),
+			Logs:       ibs.GetLogs(txHash),
+		}
+		if simulated.Logs == nil {
+			simulated.Logs = []*types.Log{}
+		}
+		if result.Err != nil {
+			simulated.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				revertErr := ethapi.NewRevertError(result)
+				simulated.Error = &SimulatedCallError{Code: revertErr.ErrorCode(), Message: revertErr.Error(), Data: hexutil.Encode(result.Revert())}
+			} else {
+				simulated.Error = &SimulatedCallError{Code: -32015, Message: result.Err.Error()}
+			}
+		}
+		calls = append(calls, simulated)
+	}
+	return calls, blockCtx.GasLimit - gp.Gas(), nil
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateV1(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+
+	var (
+		from     = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		logger   = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		reverter = common.HexToAddress("0x3000000000000000000000000000000000000003")
+		hasher   = common.HexToAddress("0x4000000000000000000000000000000000000004")
+		coinbase = common.HexToAddress("0x5000000000000000000000000000000000000005")
+		time     = hexutil.Uint64(1e9)
+		// Logs the timestamp, and returns the coinbase
+		loggerCode = hexutil.Bytes(common.FromHex("0x4260005260206000a04160005260206000f3"))
+		// Reverts with 0xaa
+		reverterCode = hexutil.Bytes(common.FromHex("0x60aa60005260206000fd"))
+		// Returns the hash of the previous block
+		hasherCode = hexutil.Bytes(common.FromHex("0x43600190034060005260206000f3"))
+	)
+	overrides := ethapi.StateOverrides{
+		logger:   {Code: &loggerCode},
+		reverter: {Code: &reverterCode},
+		hasher:   {Code: &hasherCode},
+	}
+
+	blocks, err := api.SimulateV1(context.Background(), SimulateOptions{BlockStateCalls: []SimulateBlock{
+		{
+			BlockOverrides: &ethapi.BlockOverrides{Time: &time, Coinbase: &coinbase},
+			StateOverrides: &overrides,
+			Calls:          []ethapi.CallArgs{{From: &from, To: &logger}, {From: &from, To: &reverter}},
+		},
+		{
+			Calls: []ethapi.CallArgs{{From: &from, To: &hasher}},
+		},
+	}}, nil)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+
+	first := blocks[0]
+	require.Equal(t, time, first.Timestamp)
+	require.Equal(t, coinbase, first.Miner)
+	require.Len(t, first.Calls, 2)
+
+	logged := first.Calls[0]
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), logged.Status)
+	require.Nil(t, logged.Error)
+	require.Equal(t, coinbase.Hash().Bytes(), []byte(logged.ReturnData))
+	require.Len(t, logged.Logs, 1)
+	require.Equal(t, logger, logged.Logs[0].Address)
+	require.Equal(t, common.BigToHash(new(big.Int).SetUint64(uint64(time))).Bytes(), logged.Logs[0].Data)
+	require.Equal(t, first.Hash, logged.Logs[0].BlockHash)
+	require.Equal(t, uint64(first.Number), logged.Logs[0].BlockNumber)
+
+	reverted := first.Calls[1]
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusFailed), reverted.Status)
+	require.Equal(t, 3, reverted.Error.Code)
+	require.Equal(t, hexutil.Encode(common.BigToHash(big.NewInt(0xaa)).Bytes()), reverted.Error.Data)
+	require.Empty(t, reverted.Logs)
+	require.Equal(t, first.Calls[0].GasUsed+first.Calls[1].GasUsed, first.GasUsed)
+
+	// The blocks are simulated one after another, with the hashes of the previous ones
+	second := blocks[1]
+	require.Equal(t, first.Number+1, second.Number)
+	require.Greater(t, second.Timestamp, first.Timestamp)
+	require.Equal(t, first.Hash, second.ParentHash)
+	require.Equal(t, first.Hash.Bytes(), []byte(second.Calls[0].ReturnData))
+
+	// The blocks may not go back in time
+	_, err = api.SimulateV1(context.Background(), SimulateOptions{BlockStateCalls: []SimulateBlock{
+		{BlockOverrides: &ethapi.BlockOverrides{Time: &time}},
+		{BlockOverrides: &ethapi.BlockOverrides{Time: &time}},
+	}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,291 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers/native"
+	"github.com/ledgerwatch/erigon/ethdb"
+	rpcapi "github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+)
+
+// validateUserOperationTimeout is the time the validation of a user operation may take.
+const validateUserOperationTimeout = 5 * time.Second
+
+// userOperationTuple is the ABI type of the user operations of the EntryPoint v0.6.
+const userOperationTuple = "(address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)"
+
+var (
+	// The EntryPoint ends simulateValidation with one of these errors when the validation succeeds
+	validationResultSelector                = crypto.Keccak256([]byte("ValidationResult((uint256,uint256,bool,uint48,uint48,bytes),(uint256,uint256),(uint256,uint256),(uint256,uint256))"))[:4]
+	validationResultWithAggregationSelector = crypto.Keccak256([]byte("ValidationResultWithAggregation((uint256,uint256,bool,uint48,uint48,bytes),(uint256,uint256),(uint256,uint256),(uint256,uint256),(address,(uint256,uint256)))"))[:4]
+
+	// The calls of the EntryPoint to the entities of a user operation
+	createSenderSelector            = crypto.Keccak256([]byte("createSender(bytes)"))[:4]
+	validateUserOpSelector          = crypto.Keccak256([]byte("validateUserOp(" + userOperationTuple + ",bytes32,uint256)"))[:4]
+	validatePaymasterUserOpSelector = crypto.Keccak256([]byte("validatePaymasterUserOp(" + userOperationTuple + ",bytes32,uint256)"))[:4]
+
+	// bannedOpcodes are the opcodes the entities may not use during the validation: their result may change before
+	// the user operation is included
+	bannedOpcodes = map[string]struct{}{}
+
+	entryPointABI = mustParseABI(`[{"name":"simulateValidation","type":"function","inputs":[{"name":"userOp","type":"tuple","components":[
+		{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},
+		{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},
+		{"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},
+		{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]}],"outputs":[]}]`)
+)
+
+func init() {
+	for _, op := range []vm.OpCode{vm.GASPRICE, vm.GASLIMIT, vm.DIFFICULTY, vm.TIMESTAMP, vm.BASEFEE, vm.BLOCKHASH, vm.NUMBER,
+		vm.SELFBALANCE, vm.BALANCE, vm.ORIGIN, vm.GAS, vm.CREATE, vm.COINBASE, vm.SELFDESTRUCT} {
+		bannedOpcodes[op.String()] = struct{}{}
+	}
+}
+
+func mustParseABI(def string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// UserOperation is an ERC-4337 user operation, as the EntryPoint v0.6 takes it.
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// UserOperationValidation is the result of the validation of a user operation: what the simulateValidation of the
+// EntryPoint reverted with, and the validation rules of ERC-4337 the user operation breaks.
+type UserOperationValidation struct {
+	Valid      bool          `json:"valid"`
+	Violations []string      `json:"violations"`
+	ReturnData hexutil.Bytes `json:"returnData"`
+}
+
+// ValidateUserOperation implements eth_validateUserOperation. Runs the simulateValidation of the given EntryPoint
+// with the given user operation, and checks that the validation follows the rules of ERC-4337 on the opcodes and the
+// storage accesses. The entities are taken as unstaked: they may only access the storage associated with the sender.
+func (api *APIImpl) ValidateUserOperation(ctx context.Context, op UserOperation, entryPoint common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*UserOperationValidation, error) {
+	data, err := entryPointABI.Pack("simulateValidation", op.toABI())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	blockNumber, hash, _, err := rpchelper.GetCanonicalBlockNumber(*blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, *blockNrOrHash, api.filters, api.stateCache, api.stateOverlay)
+	if err != nil {
+		return nil, err
+	}
+	ibs := state.New(stateReader)
+
+	var baseFee *uint256.Int
+	if header.BaseFee != nil {
+		baseFee, _ = uint256.FromBig(header.BaseFee)
+	}
+	args := rpcapi.CallArgs{To: &entryPoint, Data: (*hexutil.Bytes)(&data)}
+	msg, err := args.ToMessage(api.GasCap, baseFee)
+	if err != nil {
+		return nil, err
+	}
+	contractHasTEVM := func(contractHash common.Hash) (bool, error) { return false, nil }
+	if api.TevmEnabled {
+		contractHasTEVM = ethdb.GetHasTEVM(tx)
+	}
+	blockCtx, txCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, tx, contractHasTEVM, api._blockReader)
+
+	tracer := native.NewBundlerCollectorTracer()
+	evm := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
+	ctx, cancel := context.WithTimeout(ctx, validateUserOperationTimeout)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */, false /* gasBailout */)
+	if err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", validateUserOperationTimeout)
+	}
+
+	// simulateValidation always reverts, with the result of the validation or the reason it failed
+	validation := &UserOperationValidation{
+		Violations: userOperationViolations(&op, entryPoint, tracer.Result()),
+		ReturnData: result.Revert(),
+	}
+	succeeded := bytes.HasPrefix(validation.ReturnData, validationResultSelector) || bytes.HasPrefix(validation.ReturnData, validationResultWithAggregationSelector)
+	validation.Valid = succeeded && len(validation.Violations) == 0
+	return validation, nil
+}
+
+// userOperationABI is a user operation as the ABI packs it.
+type userOperationABI struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+func (op *UserOperation) toABI() userOperationABI {
+	toBig := func(v *hexutil.Big) *big.Int {
+		if v == nil {
+			return new(big.Int)
+		}
+		return v.ToInt()
+	}
+	return userOperationABI{
+		Sender:               op.Sender,
+		Nonce:                toBig(op.Nonce),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         toBig(op.CallGasLimit),
+		VerificationGasLimit: toBig(op.VerificationGasLimit),
+		PreVerificationGas:   toBig(op.PreVerificationGas),
+		MaxFeePerGas:         toBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: toBig(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// userOperationViolations returns the validation rules the validation of the given user operation breaks, according
+// to what the bundlerCollectorTracer collected.
+func userOperationViolations(op *UserOperation, entryPoint common.Address, collected *native.BundlerCollectorResult) []string {
+	// The storage associated with the sender: the slot of its address, and the 128 slots from the hash of anything
+	// starting with its address, like the mappings by address
+	senderWord := common.BytesToHash(op.Sender[:])
+	var associated []*big.Int
+	for _, preimage := range collected.Keccak {
+		if bytes.HasPrefix(preimage, senderWord[:]) {
+			associated = append(associated, new(big.Int).SetBytes(crypto.Keccak256(preimage)))
+		}
+	}
+	isAssociated := func(slot common.Hash) bool {
+		if slot == senderWord {
+			return true
+		}
+		s := new(big.Int).SetBytes(slot[:])
+		for _, base := range associated {
+			if diff := new(big.Int).Sub(s, base); diff.Sign() >= 0 && diff.Cmp(big.NewInt(128)) < 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	violations := []string{}
+	for _, call := range collected.CallsFromEntryPoint {
+		var entity string
+		switch {
+		case bytes.Equal(call.TopLevelMethodSig, createSenderSelector):
+			entity = "factory"
+		case bytes.Equal(call.TopLevelMethodSig, validateUserOpSelector):
+			entity = "account"
+		case bytes.Equal(call.TopLevelMethodSig, validatePaymasterUserOpSelector):
+			entity = "paymaster"
+		default:
+			continue
+		}
+
+		for opcode := range call.Opcodes {
+			if _, banned := bannedOpcodes[opcode]; banned {
+				violations = append(violations, fmt.Sprintf("%s uses the banned opcode %s", entity, opcode))
+			}
+		}
+		// The factory may deploy the sender, and nothing else
+		if creates := call.Opcodes[vm.CREATE2.String()]; creates > 1 || (creates == 1 && entity != "factory") {
+			violations = append(violations, fmt.Sprintf("%s uses CREATE2 %d times", entity, creates))
+		}
+		if call.OOG {
+			violations = append(violations, fmt.Sprintf("%s runs out of gas", entity))
+		}
+
+		for addr, access := range call.Access {
+			// The account's own storage, and the deposits in the EntryPoint
+			if addr == op.Sender || addr == entryPoint {
+				continue
+			}
+			slots := make(map[common.Hash]struct{}, len(access.Reads)+len(access.Writes))
+			for slot := range access.Reads {
+				slots[slot] = struct{}{}
+			}
+			for slot := range access.Writes {
+				slots[slot] = struct{}{}
+			}
+			for slot := range slots {
+				if !isAssociated(slot) {
+					violations = append(violations, fmt.Sprintf("%s accesses the storage slot %x of %x not associated with the sender", entity, slot, addr))
+				}
+			}
+		}
+
+		for addr, size := range call.ContractSize {
+			if size.ContractSize <= 2 && addr != op.Sender {
+				violations = append(violations, fmt.Sprintf("%s uses %s on %x without code", entity, size.Opcode, addr))
+			}
+		}
+	}
+	// The maps leave the violations in any order
+	sort.Strings(violations)
+	return violations
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers/native"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserOperationABI(t *testing.T) {
+	op := &UserOperation{Sender: common.Address{1}, Nonce: (*hexutil.Big)(big.NewInt(2)), Signature: hexutil.Bytes{3}}
+	data, err := entryPointABI.Pack("simulateValidation", op.toABI())
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Bytes{0xee, 0x21, 0x94, 0x23}, hexutil.Bytes(data[:4]))
+}
+
+func TestUserOperationViolations(t *testing.T) {
+	sender := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	entryPoint := common.HexToAddress("0x2000000000000000000000000000000000000002")
+	token := common.HexToAddress("0x3000000000000000000000000000000000000003")
+	paymaster := common.HexToAddress("0x4000000000000000000000000000000000000004")
+	op := &UserOperation{Sender: sender}
+
+	// The balance of the sender in a token: the slot of keccak(sender . 0)
+	preimage := append(common.BytesToHash(sender[:]).Bytes(), make([]byte, 32)...)
+	balanceSlot := crypto.Keccak256Hash(preimage)
+	nextSlot := common.BigToHash(new(big.Int).Add(balanceSlot.Big(), big.NewInt(1)))
+
+	level := func(sig []byte, opcodes map[string]uint64, access map[common.Address]*native.AccessInfo) *native.TopLevelCallInfo {
+		return &native.TopLevelCallInfo{
+			TopLevelMethodSig: sig,
+			Opcodes:           opcodes,
+			Access:            access,
+			ContractSize:      map[common.Address]*native.ContractSizeInfo{token: {ContractSize: 100, Opcode: "CALL"}},
+		}
+	}
+	collected := &native.BundlerCollectorResult{
+		Keccak: []hexutil.Bytes{preimage},
+		CallsFromEntryPoint: []*native.TopLevelCallInfo{
+			level(validateUserOpSelector, map[string]uint64{"SLOAD": 3, "CALL": 1}, map[common.Address]*native.AccessInfo{
+				sender:     {Reads: map[common.Hash]common.Hash{{31: 1}: {}}},
+				entryPoint: {Writes: map[common.Hash]uint64{{31: 1}: 1}},
+				token:      {Reads: map[common.Hash]common.Hash{balanceSlot: {}, nextSlot: {}, common.BytesToHash(sender[:]): {}}},
+			}),
+		},
+	}
+	require.Empty(t, userOperationViolations(op, entryPoint, collected))
+
+	// The paymaster may not read the time, nor its own storage without a stake
+	collected.CallsFromEntryPoint = append(collected.CallsFromEntryPoint,
+		level(validatePaymasterUserOpSelector, map[string]uint64{"TIMESTAMP": 1, "CREATE2": 1}, map[common.Address]*native.AccessInfo{
+			paymaster: {Reads: map[common.Hash]common.Hash{{31: 1}: {}}},
+		}),
+	)
+	collected.CallsFromEntryPoint[1].OOG = true
+	collected.CallsFromEntryPoint[1].ContractSize[common.Address{1}] = &native.ContractSizeInfo{Opcode: "EXTCODEHASH"}
+	require.Equal(t, []string{
+		"paymaster accesses the storage slot 0000000000000000000000000000000000000000000000000000000000000001 of 4000000000000000000000000000000000000004 not associated with the sender",
+		"paymaster runs out of gas",
+		"paymaster uses CREATE2 1 times",
+		"paymaster uses EXTCODEHASH on 0100000000000000000000000000000000000000 without code",
+		"paymaster uses the banned opcode TIMESTAMP",
+	}, userOperationViolations(op, entryPoint, collected))
+}
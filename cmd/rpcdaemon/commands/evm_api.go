@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+// errNoDevChain is returned when the evm namespace is served outside of the dev chain with mining enabled,
+// or by a standalone rpcdaemon
+var errNoDevChain = errors.New("dev chain is not available, run --chain=dev --mine and enable the evm API of the embedded RPC server")
+
+// DevChain is the developer chain of the node, see eth.DevChain
+type DevChain interface {
+	Mine(ctx context.Context, timestamp *uint64) error
+	SetNextBlockTimestamp(ctx context.Context, timestamp uint64) error
+	IncreaseTime(seconds uint64) int64
+	Snapshot(ctx context.Context) (uint64, error)
+	Revert(ctx context.Context, id uint64) (bool, error)
+}
+
+// EvmAPI the interface for the evm_* RPC commands of the dev chain, compatible with Hardhat and Anvil.
+type EvmAPI interface {
+	// Mine mines a block with the pending transactions, stamped with the timestamp if given.
+	Mine(ctx context.Context, timestamp *EvmQuantity) (string, error)
+
+	// SetNextBlockTimestamp sets the timestamp of the next block.
+	SetNextBlockTimestamp(ctx context.Context, timestamp EvmQuantity) error
+
+	// IncreaseTime moves the clock of the chain forward, and returns the total seconds it was moved by.
+	IncreaseTime(ctx context.Context, seconds EvmQuantity) (int64, error)
+
+	// Snapshot takes a snapshot of the chain, and returns its id.
+	Snapshot(ctx context.Context) (hexutil.Uint64, error)
+
+	// Revert reverts the chain to the snapshot, which is dropped along with the later ones.
+	Revert(ctx context.Context, id EvmQuantity) (bool, error)
+}
+
+// EvmQuantity is a number given as a JSON number, or a hex or decimal string, as the Hardhat clients do.
+type EvmQuantity uint64
+
+func (q *EvmQuantity) UnmarshalJSON(input []byte) error {
+	s := string(input)
+	var n uint64
+	var err error
+	if unquoted, uerr := strconv.Unquote(s); uerr == nil {
+		if strings.HasPrefix(unquoted, "0x") || strings.HasPrefix(unquoted, "0X") {
+			n, err = hexutil.DecodeUint64(unquoted)
+		} else {
+			n, err = strconv.ParseUint(unquoted, 10, 64)
+		}
+	} else {
+		n, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid quantity %s: %w", s, err)
+	}
+	*q = EvmQuantity(n)
+	return nil
+}
+
+// EvmAPIImpl data structure to store things needed for evm_* commands.
+type EvmAPIImpl struct {
+	dev DevChain
+}
+
+// NewEvmAPI returns EvmAPIImpl instance.
+func NewEvmAPI(dev DevChain) *EvmAPIImpl {
+	return &EvmAPIImpl{dev: dev}
+}
+
+func (api *EvmAPIImpl) Mine(ctx context.Context, timestamp *EvmQuantity) (string, error) {
+	if api.dev == nil {
+		return "", errNoDevChain
+	}
+	var ts *uint64
+	if timestamp != nil {
+		t := uint64(*timestamp)
+		ts = &t
+	}
+	if err := api.dev.Mine(ctx, ts); err != nil {
+		return "", err
+	}
+	return "0x0", nil
+}
+
+func (api *EvmAPIImpl) SetNextBlockTimestamp(ctx context.Context, timestamp EvmQuantity) error {
+	if api.dev == nil {
+		return errNoDevChain
+	}
+	return api.dev.SetNextBlockTimestamp(ctx, uint64(timestamp))
+}
+
+func (api *EvmAPIImpl) IncreaseTime(_ context.Context, seconds EvmQuantity) (int64, error) {
+	if api.dev == nil {
+		return 0, errNoDevChain
+	}
+	return api.dev.IncreaseTime(uint64(seconds)), nil
+}
+
+func (api *EvmAPIImpl) Snapshot(ctx context.Context) (hexutil.Uint64, error) {
+	if api.dev == nil {
+		return 0, errNoDevChain
+	}
+	id, err := api.dev.Snapshot(ctx)
+	return hexutil.Uint64(id), err
+}
+
+func (api *EvmAPIImpl) Revert(ctx context.Context, id EvmQuantity) (bool, error) {
+	if api.dev == nil {
+		return false, errNoDevChain
+	}
+	return api.dev.Revert(ctx, uint64(id))
+}
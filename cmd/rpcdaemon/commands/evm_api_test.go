@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvmQuantity(t *testing.T) {
+	for input, want := range map[string]EvmQuantity{
+		`3600`:     3600,
+		`"3600"`:   3600,
+		`"0xe10"`:  3600,
+		`"0XE10"`:  3600,
+		`"0x0"`:    0,
+		`1700000`:  1700000,
+		`"0x1a2b"`: 0x1a2b,
+	} {
+		var q EvmQuantity
+		require.NoError(t, json.Unmarshal([]byte(input), &q), input)
+		require.Equal(t, want, q, input)
+	}
+	for _, input := range []string{`-1`, `1.5`, `"foo"`, `"0x"`, `null`} {
+		var q EvmQuantity
+		require.Error(t, json.Unmarshal([]byte(input), &q), input)
+	}
+}
+
+func TestEvmAPINoDevChain(t *testing.T) {
+	api := NewEvmAPI(nil)
+	_, err := api.Mine(context.Background(), nil)
+	require.ErrorIs(t, err, errNoDevChain)
+	_, err = api.Snapshot(context.Background())
+	require.ErrorIs(t, err, errNoDevChain)
+	_, err = api.Revert(context.Background(), 1)
+	require.ErrorIs(t, err, errNoDevChain)
+}
@@ -0,0 +1,884 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/graphql"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// gqlSchema is the schema of the queries, served by their introspection: the subset of the schema of geth (EIP-1767)
+// resolved from the database. The pending state, the calls, the raw encodings and the mutations are not supported.
+// The Long scalars are JSON numbers, or decimal or hex strings in the arguments.
+var gqlSchema = graphql.MustParseSchema(`
+# Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+scalar Bytes32
+# Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
+scalar Address
+# Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+# An empty byte string is represented as '0x'. Byte strings must have an even number of hexadecimal nybbles.
+scalar Bytes
+# BigInt is a large integer. Input is accepted as either a JSON number or as a string.
+# Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
+# 0x-prefixed hexadecimal.
+scalar BigInt
+# Long is a 64 bit unsigned integer.
+scalar Long
+
+schema {
+	query: Query
+}
+
+# Account is an Ethereum account at a particular block.
+type Account {
+	# Address is the address owning the account.
+	address: Address!
+	# Balance is the balance of the account, in wei.
+	balance: BigInt!
+	# TransactionCount is the number of transactions sent from this account,
+	# or in the case of a contract, the number of contracts created. Otherwise
+	# known as the nonce.
+	transactionCount: Long!
+	# Code contains the smart contract code for this account, if the account
+	# is a (non-self-destructed) contract.
+	code: Bytes!
+	# Storage provides access to the storage of a contract account, indexed
+	# by its 32 byte slot identifier.
+	storage(slot: Bytes32!): Bytes32!
+}
+
+# Log is an Ethereum event log.
+type Log {
+	# Index is the index of this log in the block.
+	index: Int!
+	# Account is the account which generated this log - this will always
+	# be a contract account.
+	account(block: Long): Account!
+	# Topics is a list of 0-4 indexed topics for the log.
+	topics: [Bytes32!]!
+	# Data is unindexed data for this log.
+	data: Bytes!
+	# Transaction is the transaction that generated this log entry.
+	transaction: Transaction!
+}
+
+# Transaction is an Ethereum transaction.
+type Transaction {
+	# Hash is the hash of this transaction.
+	hash: Bytes32!
+	# Nonce is the nonce of the account this transaction was generated with.
+	nonce: Long!
+	# Index is the index of this transaction in the parent block. This will
+	# be null if the transaction has not yet been mined.
+	index: Int
+	# From is the account that sent this transaction - this will always be
+	# an externally owned account.
+	from(block: Long): Account!
+	# To is the account the transaction was sent to. This is null for
+	# contract-creating transactions.
+	to(block: Long): Account
+	# Value is the value, in wei, sent along with this transaction.
+	value: BigInt!
+	# GasPrice is the price offered to miners for gas, in wei per unit.
+	gasPrice: BigInt!
+	# MaxFeePerGas is the maximum fee per gas offered to include a transaction, in wei.
+	maxFeePerGas: BigInt
+	# MaxPriorityFeePerGas is the maximum miner tip per gas offered to include a transaction, in wei.
+	maxPriorityFeePerGas: BigInt
+	# EffectiveTip is the actual amount of reward going to miner after considering the max fee cap.
+	effectiveTip: BigInt
+	# Gas is the maximum amount of gas this transaction can consume.
+	gas: Long!
+	# InputData is the data supplied to the target of the transaction.
+	inputData: Bytes!
+	# Block is the block this transaction was mined in. This will be null if
+	# the transaction has not yet been mined.
+	block: Block
+	# Status is the return status of the transaction. This will be 1 if the
+	# transaction succeeded, or 0 if it failed (due to a revert, or due to
+	# running out of gas). If the transaction has not yet been mined, this
+	# field will be null.
+	status: Long
+	# GasUsed is the amount of gas that was used processing this transaction.
+	# If the transaction has not yet been mined, this field will be null.
+	gasUsed: Long
+	# CumulativeGasUsed is the total gas used in the block up to and including
+	# this transaction. If the transaction has not yet been mined, this field
+	# will be null.
+	cumulativeGasUsed: Long
+	# EffectiveGasPrice is actual value per gas deducted from the sender's
+	# account. Before EIP-1559, this is equal to the transaction's gas price.
+	# After EIP-1559, it is baseFeePerGas + min(maxFeePerGas - baseFeePerGas,
+	# maxPriorityFeePerGas). Legacy transactions and EIP-2930 transactions are
+	# coerced into the EIP-1559 format by setting both maxFeePerGas and
+	# maxPriorityFeePerGas as the transaction's gas price.
+	effectiveGasPrice: BigInt
+	# CreatedContract is the account that was created by a contract creation
+	# transaction. If the transaction was not a contract creation transaction,
+	# or it has not yet been mined, this field will be null.
+	createdContract(block: Long): Account
+	# Logs is a list of log entries emitted by this transaction. If the
+	# transaction has not yet been mined, this field will be null.
+	logs: [Log!]
+	r: BigInt!
+	s: BigInt!
+	v: BigInt!
+	# Envelope transaction support
+	type: Int
+}
+
+# BlockFilterCriteria encapsulates log filter criteria for a filter applied
+# to a single block.
+input BlockFilterCriteria {
+	# Addresses is list of addresses that are of interest. If this list is
+	# empty, results will not be filtered by address.
+	addresses: [Address!]
+	# Topics list restricts matches to particular event topics. Each event has a list
+	# of topics. Topics matches a prefix of that list. An empty element array matches any
+	# topic. Non-empty elements represent an alternative that matches any of the
+	# contained topics.
+	topics: [[Bytes32!]!]
+}
+
+# Block is an Ethereum block.
+type Block {
+	# Number is the number of this block, starting at 0 for the genesis block.
+	number: Long!
+	# Hash is the block hash of this block.
+	hash: Bytes32!
+	# Parent is the parent block of this block.
+	parent: Block
+	# Nonce is the block nonce, an 8 byte sequence determined by the miner.
+	nonce: Bytes!
+	# TransactionsRoot is the keccak256 hash of the root of the trie of transactions in this block.
+	transactionsRoot: Bytes32!
+	# TransactionCount is the number of transactions in this block. if
+	# transactions are not available for this block, this field will be null.
+	transactionCount: Int
+	# StateRoot is the keccak256 hash of the state trie after this block was processed.
+	stateRoot: Bytes32!
+	# ReceiptsRoot is the keccak256 hash of the trie of transaction receipts in this block.
+	receiptsRoot: Bytes32!
+	# Miner is the account that mined this block.
+	miner(block: Long): Account!
+	# ExtraData is an arbitrary data field supplied by the miner.
+	extraData: Bytes!
+	# GasLimit is the maximum amount of gas that was available to transactions in this block.
+	gasLimit: Long!
+	# GasUsed is the amount of gas that was used executing transactions in this block.
+	gasUsed: Long!
+	# BaseFeePerGas is the fee per unit of gas burned by the protocol in this block.
+	baseFeePerGas: BigInt
+	# Timestamp is the unix timestamp at which this block was mined.
+	timestamp: Long!
+	# LogsBloom is a bloom filter that can be used to check if a block may
+	# contain log entries matching a filter.
+	logsBloom: Bytes!
+	# MixHash is the hash that was used as an input to the PoW process.
+	mixHash: Bytes32!
+	# Difficulty is a measure of the difficulty of mining this block.
+	difficulty: BigInt!
+	# TotalDifficulty is the sum of all difficulty values up to and including
+	# this block.
+	totalDifficulty: BigInt!
+	# OmmerCount is the number of ommers (AKA uncles) associated with this
+	# block. If ommers are unavailable, this field will be null.
+	ommerCount: Int
+	# OmmerHash is the keccak256 hash of all the ommers (AKA uncles)
+	# associated with this block.
+	ommerHash: Bytes32!
+	# Transactions is a list of transactions associated with this block. If
+	# transactions are unavailable for this block, this field will be null.
+	transactions: [Transaction!]
+	# TransactionAt returns the transaction at the specified index. If
+	# transactions are unavailable for this block, or if the index is out of
+	# bounds, this field will be null.
+	transactionAt(index: Int!): Transaction
+	# Logs returns a filtered set of logs from this block.
+	logs(filter: BlockFilterCriteria!): [Log!]!
+	# Account fetches an Ethereum account at the current block's state.
+	account(address: Address!): Account!
+}
+
+# FilterCriteria encapsulates log filter criteria for searching log entries.
+input FilterCriteria {
+	# FromBlock is the block at which to start searching, inclusive. Defaults
+	# to the latest block if not supplied.
+	fromBlock: Long
+	# ToBlock is the block at which to stop searching, inclusive. Defaults
+	# to the latest block if not supplied.
+	toBlock: Long
+	# Addresses is a list of addresses that are of interest. If this list is
+	# empty, results will not be filtered by address.
+	addresses: [Address!]
+	# Topics list restricts matches to particular event topics. Each event has a list
+	# of topics. Topics matches a prefix of that list. An empty element array matches any
+	# topic. Non-empty elements represent an alternative that matches any of the
+	# contained topics.
+	topics: [[Bytes32!]!]
+}
+
+type Query {
+	# Block fetches an Ethereum block by number or by hash. If neither is
+	# supplied, the most recent known block is returned.
+	block(number: Long, hash: Bytes32): Block
+	# Blocks returns all the blocks between two numbers, inclusive. If
+	# to is not supplied, it defaults to the most recent known block.
+	blocks(from: Long, to: Long): [Block!]!
+	# Transaction returns a transaction specified by its hash.
+	transaction(hash: Bytes32!): Transaction
+	# Logs returns log entries matching the provided filter.
+	logs(filter: FilterCriteria!): [Log!]!
+	# GasPrice returns the node's estimate of a gas price sufficient to
+	# ensure a transaction is mined in a timely fashion.
+	gasPrice: BigInt!
+	# MaxPriorityFeePerGas returns the node's estimate of a gas tip sufficient
+	# to ensure a transaction is mined in a timely fashion.
+	maxPriorityFeePerGas: BigInt!
+	# ChainID returns the current chain ID for transaction replay protection.
+	chainID: BigInt!
+}
+`)
+
+// maxGraphQLBlocks is the maximum number of blocks of a blocks query.
+const maxGraphQLBlocks = 1000
+
+// GraphQLHandler returns the handler of the GraphQL queries, read from the database in one transaction per request.
+func (api *APIImpl) GraphQLHandler() http.Handler {
+	return graphql.NewHandler(gqlSchema, func(ctx context.Context) (graphql.Object, func(), error) {
+		tx, err := api.db.BeginRo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		cc, err := api.chainConfig(tx)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		r := &gqlRequest{api: api, tx: tx, cc: cc, receipts: map[common.Hash]types.Receipts{}, readers: map[uint64]state.StateReader{}}
+		return &gqlQuery{r}, tx.Rollback, nil
+	})
+}
+
+// gqlRequest is the state shared by the objects of a request.
+type gqlRequest struct {
+	api      *APIImpl
+	tx       kv.Tx
+	cc       *params.ChainConfig
+	receipts map[common.Hash]types.Receipts
+	readers  map[uint64]state.StateReader
+}
+
+func (r *gqlRequest) latestNumber() (uint64, error) {
+	n, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), r.tx, r.api.filters)
+	return n, err
+}
+
+// block returns the canonical block of the number, nil if there is none.
+func (r *gqlRequest) block(number uint64) (graphql.Object, error) {
+	block, err := r.api.blockByNumberWithSenders(r.tx, number)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return &gqlBlock{r, block}, nil
+}
+
+func (r *gqlRequest) blockReceipts(ctx context.Context, block *types.Block) (types.Receipts, error) {
+	if receipts, ok := r.receipts[block.Hash()]; ok {
+		return receipts, nil
+	}
+	receipts, err := r.api.getReceipts(ctx, r.tx, r.cc, block, block.Body().SendersFromTxs())
+	if err != nil {
+		return nil, err
+	}
+	r.receipts[block.Hash()] = receipts
+	return receipts, nil
+}
+
+// account returns the account at the state after the given block, or after the default one if the arguments have
+// no block.
+func (r *gqlRequest) account(address common.Address, args map[string]interface{}, defaultBlock uint64) (graphql.Object, error) {
+	if v, ok := args["block"]; ok && v != nil {
+		n, err := gqlLong(v)
+		if err != nil {
+			return nil, fmt.Errorf("block: %w", err)
+		}
+		defaultBlock = n
+	}
+	return &gqlAccount{r, address, defaultBlock}, nil
+}
+
+func (r *gqlRequest) stateReader(ctx context.Context, number uint64) (state.StateReader, error) {
+	if reader, ok := r.readers[number]; ok {
+		return reader, nil
+	}
+	reader, err := rpchelper.CreateStateReader(ctx, r.tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number)), r.api.filters, r.api.stateCache, r.api.stateOverlay)
+	if err != nil {
+		return nil, err
+	}
+	r.readers[number] = reader
+	return reader, nil
+}
+
+func (r *gqlRequest) logs(block *types.Block, logs []*types.Log) []graphql.Object {
+	res := make([]graphql.Object, len(logs))
+	for i, l := range logs {
+		res[i] = &gqlLog{r, block, l}
+	}
+	return res
+}
+
+type gqlQuery struct{ r *gqlRequest }
+
+func (q *gqlQuery) TypeName() string { return "Query" }
+
+func (q *gqlQuery) Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "block":
+		if v := args["hash"]; v != nil {
+			hash, err := gqlBytes32(v)
+			if err != nil {
+				return nil, fmt.Errorf("hash: %w", err)
+			}
+			block, err := q.r.api.blockByHashWithSenders(q.r.tx, hash)
+			if err != nil || block == nil {
+				return nil, err
+			}
+			return &gqlBlock{q.r, block}, nil
+		}
+		latest, err := q.r.latestNumber()
+		if err != nil {
+			return nil, err
+		}
+		number := latest
+		if v := args["number"]; v != nil {
+			if number, err = gqlLong(v); err != nil {
+				return nil, fmt.Errorf("number: %w", err)
+			}
+		}
+		if number > latest {
+			return nil, nil
+		}
+		return q.r.block(number)
+	case "blocks":
+		from, err := gqlLong(args["from"])
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		latest, err := q.r.latestNumber()
+		if err != nil {
+			return nil, err
+		}
+		to := latest
+		if v := args["to"]; v != nil {
+			if to, err = gqlLong(v); err != nil {
+				return nil, fmt.Errorf("to: %w", err)
+			}
+		}
+		if to >= from && to-from >= maxGraphQLBlocks {
+			return nil, fmt.Errorf("too many blocks: %d, the maximum is %d", to-from+1, maxGraphQLBlocks)
+		}
+		if latest < to {
+			to = latest
+		}
+		var blocks []graphql.Object
+		for n := from; n <= to; n++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			block, err := q.r.block(n)
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+		return blocks, nil
+	case "transaction":
+		hash, err := gqlBytes32(args["hash"])
+		if err != nil {
+			return nil, fmt.Errorf("hash: %w", err)
+		}
+		blockNum, ok, err := q.r.api.txnLookup(ctx, q.r.tx, hash)
+		if err != nil || !ok {
+			return nil, err
+		}
+		block, err := q.r.api.blockByNumberWithSenders(q.r.tx, blockNum)
+		if err != nil || block == nil {
+			return nil, err
+		}
+		for i, txn := range block.Transactions() {
+			if txn.Hash() == hash {
+				return &gqlTransaction{q.r, block, i}, nil
+			}
+		}
+		return nil, nil
+	case "logs":
+		filter, ok := args["filter"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("filter is required")
+		}
+		var crit filters.FilterCriteria
+		for name, v := range filter {
+			var err error
+			switch name {
+			case "fromBlock", "toBlock":
+				if v == nil {
+					continue
+				}
+				var n uint64
+				if n, err = gqlLong(v); err == nil {
+					if name == "fromBlock" {
+						crit.FromBlock = new(big.Int).SetUint64(n)
+					} else {
+						crit.ToBlock = new(big.Int).SetUint64(n)
+					}
+				}
+			case "addresses":
+				crit.Addresses, err = gqlAddresses(v)
+			case "topics":
+				crit.Topics, err = gqlTopics(v)
+			default:
+				err = fmt.Errorf("unknown field")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("filter %s: %w", name, err)
+			}
+		}
+		logs, err := q.r.api.getLogs(ctx, q.r.tx, crit)
+		if err != nil {
+			return nil, err
+		}
+		return q.r.logs(nil, logs), nil
+	case "chainID":
+		return (*hexutil.Big)(q.r.cc.ChainID), nil
+	case "gasPrice", "maxPriorityFeePerGas":
+		tipcap, err := q.r.api.gasPriceOracle(q.r.tx, q.r.cc).SuggestTipCap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if head := rawdb.ReadCurrentHeader(q.r.tx); field == "gasPrice" && head != nil && head.BaseFee != nil {
+			tipcap.Add(tipcap, head.BaseFee)
+		}
+		return (*hexutil.Big)(tipcap), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Query", field)
+}
+
+type gqlBlock struct {
+	r     *gqlRequest
+	block *types.Block
+}
+
+func (b *gqlBlock) TypeName() string { return "Block" }
+
+func (b *gqlBlock) Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	h := b.block.Header()
+	switch field {
+	case "number":
+		return h.Number.Uint64(), nil
+	case "hash":
+		return b.block.Hash(), nil
+	case "parent":
+		if h.Number.Sign() == 0 {
+			return nil, nil
+		}
+		return b.r.block(h.Number.Uint64() - 1)
+	case "nonce":
+		return hexutil.Bytes(h.Nonce[:]), nil
+	case "transactionsRoot":
+		return h.TxHash, nil
+	case "stateRoot":
+		return h.Root, nil
+	case "receiptsRoot":
+		return h.ReceiptHash, nil
+	case "miner":
+		return b.r.account(h.Coinbase, args, h.Number.Uint64())
+	case "extraData":
+		return hexutil.Bytes(h.Extra), nil
+	case "gasLimit":
+		return h.GasLimit, nil
+	case "gasUsed":
+		return h.GasUsed, nil
+	case "baseFeePerGas":
+		return (*hexutil.Big)(h.BaseFee), nil
+	case "timestamp":
+		return h.Time, nil
+	case "logsBloom":
+		return hexutil.Bytes(h.Bloom[:]), nil
+	case "mixHash":
+		return h.MixDigest, nil
+	case "difficulty":
+		return (*hexutil.Big)(h.Difficulty), nil
+	case "totalDifficulty":
+		td, err := rawdb.ReadTd(b.r.tx, b.block.Hash(), h.Number.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		if td == nil {
+			return nil, fmt.Errorf("total difficulty of block %d not found", h.Number.Uint64())
+		}
+		return (*hexutil.Big)(td), nil
+	case "ommerCount":
+		return len(b.block.Uncles()), nil
+	case "ommerHash":
+		return h.UncleHash, nil
+	case "transactionCount":
+		return len(b.block.Transactions()), nil
+	case "transactions":
+		txs := make([]graphql.Object, len(b.block.Transactions()))
+		for i := range txs {
+			txs[i] = &gqlTransaction{b.r, b.block, i}
+		}
+		return txs, nil
+	case "transactionAt":
+		index, ok := args["index"].(int64)
+		if !ok {
+			return nil, fmt.Errorf("index: integer required")
+		}
+		if index < 0 || index >= int64(len(b.block.Transactions())) {
+			return nil, nil
+		}
+		return &gqlTransaction{b.r, b.block, int(index)}, nil
+	case "logs":
+		filter, _ := args["filter"].(map[string]interface{})
+		addresses, err := gqlAddresses(filter["addresses"])
+		if err != nil {
+			return nil, fmt.Errorf("filter addresses: %w", err)
+		}
+		topics, err := gqlTopics(filter["topics"])
+		if err != nil {
+			return nil, fmt.Errorf("filter topics: %w", err)
+		}
+		receipts, err := b.r.blockReceipts(ctx, b.block)
+		if err != nil {
+			return nil, err
+		}
+		var logs []*types.Log
+		for _, receipt := range receipts {
+			logs = append(logs, filterLogs(receipt.Logs, addresses, topics)...)
+		}
+		return b.r.logs(b.block, logs), nil
+	case "account":
+		address, err := gqlAddress(args["address"])
+		if err != nil {
+			return nil, fmt.Errorf("address: %w", err)
+		}
+		return &gqlAccount{b.r, address, h.Number.Uint64()}, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Block", field)
+}
+
+type gqlTransaction struct {
+	r     *gqlRequest
+	block *types.Block
+	index int
+}
+
+func (t *gqlTransaction) TypeName() string { return "Transaction" }
+
+func (t *gqlTransaction) receipt(ctx context.Context) (*types.Receipt, error) {
+	receipts, err := t.r.blockReceipts(ctx, t.block)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= len(receipts) {
+		return nil, fmt.Errorf("receipt of transaction %d of block %d not found", t.index, t.block.NumberU64())
+	}
+	return receipts[t.index], nil
+}
+
+func (t *gqlTransaction) Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	txn := t.block.Transactions()[t.index]
+	blockNum := t.block.NumberU64()
+	var baseFee *uint256.Int
+	if t.block.BaseFee() != nil {
+		baseFee, _ = uint256.FromBig(t.block.BaseFee())
+	}
+	switch field {
+	case "hash":
+		return txn.Hash(), nil
+	case "nonce":
+		return txn.GetNonce(), nil
+	case "index":
+		return t.index, nil
+	case "from":
+		from, ok := txn.GetSender()
+		if !ok {
+			var err error
+			if from, err = txn.Sender(*types.MakeSigner(t.r.cc, blockNum)); err != nil {
+				return nil, err
+			}
+		}
+		return t.r.account(from, args, blockNum)
+	case "to":
+		if txn.GetTo() == nil {
+			return nil, nil
+		}
+		return t.r.account(*txn.GetTo(), args, blockNum)
+	case "value":
+		return (*hexutil.Big)(txn.GetValue().ToBig()), nil
+	case "gasPrice":
+		if baseFee == nil || txn.Type() != types.DynamicFeeTxType {
+			return (*hexutil.Big)(txn.GetPrice().ToBig()), nil
+		}
+		return (*hexutil.Big)(math.Min256(new(uint256.Int).Add(txn.GetTip(), baseFee), txn.GetFeeCap()).ToBig()), nil
+	case "maxFeePerGas":
+		if txn.Type() != types.DynamicFeeTxType {
+			return nil, nil
+		}
+		return (*hexutil.Big)(txn.GetFeeCap().ToBig()), nil
+	case "maxPriorityFeePerGas":
+		if txn.Type() != types.DynamicFeeTxType {
+			return nil, nil
+		}
+		return (*hexutil.Big)(txn.GetTip().ToBig()), nil
+	case "effectiveTip":
+		return (*hexutil.Big)(txn.GetEffectiveGasTip(baseFee).ToBig()), nil
+	case "gas":
+		return txn.GetGas(), nil
+	case "inputData":
+		return hexutil.Bytes(txn.GetData()), nil
+	case "block":
+		return &gqlBlock{t.r, t.block}, nil
+	case "status", "gasUsed", "cumulativeGasUsed", "effectiveGasPrice", "createdContract", "logs":
+		receipt, err := t.receipt(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case "status":
+			return receipt.Status, nil
+		case "gasUsed":
+			return receipt.GasUsed, nil
+		case "cumulativeGasUsed":
+			return receipt.CumulativeGasUsed, nil
+		case "effectiveGasPrice":
+			if baseFee == nil {
+				return (*hexutil.Big)(txn.GetPrice().ToBig()), nil
+			}
+			return (*hexutil.Big)(new(uint256.Int).Add(baseFee, txn.GetEffectiveGasTip(baseFee)).ToBig()), nil
+		case "createdContract":
+			if txn.GetTo() != nil {
+				return nil, nil
+			}
+			return t.r.account(receipt.ContractAddress, args, blockNum)
+		default:
+			return t.r.logs(t.block, receipt.Logs), nil
+		}
+	case "r", "s", "v":
+		v, r, s := txn.RawSignatureValues()
+		switch field {
+		case "r":
+			return (*hexutil.Big)(r.ToBig()), nil
+		case "s":
+			return (*hexutil.Big)(s.ToBig()), nil
+		default:
+			return (*hexutil.Big)(v.ToBig()), nil
+		}
+	case "type":
+		return int(txn.Type()), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Transaction", field)
+}
+
+type gqlAccount struct {
+	r        *gqlRequest
+	address  common.Address
+	blockNum uint64
+}
+
+func (a *gqlAccount) TypeName() string { return "Account" }
+
+func (a *gqlAccount) Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	if field == "address" {
+		return a.address, nil
+	}
+	reader, err := a.r.stateReader(ctx, a.blockNum)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := reader.ReadAccountData(a.address)
+	if err != nil {
+		return nil, err
+	}
+	switch field {
+	case "balance":
+		if acc == nil {
+			return (*hexutil.Big)(new(big.Int)), nil
+		}
+		return (*hexutil.Big)(acc.Balance.ToBig()), nil
+	case "transactionCount":
+		if acc == nil {
+			return uint64(0), nil
+		}
+		return acc.Nonce, nil
+	case "code":
+		if acc == nil {
+			return hexutil.Bytes{}, nil
+		}
+		code, err := reader.ReadAccountCode(a.address, acc.Incarnation, acc.CodeHash)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Bytes(code), nil
+	case "storage":
+		slot, err := gqlBytes32(args["slot"])
+		if err != nil {
+			return nil, fmt.Errorf("slot: %w", err)
+		}
+		if acc == nil {
+			return common.Hash{}, nil
+		}
+		value, err := reader.ReadAccountStorage(a.address, acc.Incarnation, &slot)
+		if err != nil {
+			return nil, err
+		}
+		return common.BytesToHash(value), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Account", field)
+}
+
+type gqlLog struct {
+	r     *gqlRequest
+	block *types.Block // nil until the transaction is resolved
+	log   *types.Log
+}
+
+func (l *gqlLog) TypeName() string { return "Log" }
+
+func (l *gqlLog) Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "index":
+		return l.log.Index, nil
+	case "account":
+		return l.r.account(l.log.Address, args, l.log.BlockNumber)
+	case "topics":
+		return l.log.Topics, nil
+	case "data":
+		return hexutil.Bytes(l.log.Data), nil
+	case "transaction":
+		if l.block == nil {
+			block, err := l.r.api.blockWithSenders(l.r.tx, l.log.BlockHash, l.log.BlockNumber)
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				return nil, fmt.Errorf("block %d of the log not found", l.log.BlockNumber)
+			}
+			l.block = block
+		}
+		return &gqlTransaction{l.r, l.block, int(l.log.TxIndex)}, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Log", field)
+}
+
+// gqlLong parses a Long argument: an integer, or a decimal or hex string.
+func gqlLong(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("negative value %d", v)
+		}
+		return uint64(v), nil
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return hexutil.DecodeUint64(v)
+		}
+		return strconv.ParseUint(v, 10, 64)
+	case nil:
+		return 0, fmt.Errorf("value required")
+	}
+	return 0, fmt.Errorf("invalid Long %v", v)
+}
+
+func gqlBytes32(v interface{}) (common.Hash, error) {
+	s, ok := v.(string)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("hex string required")
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("%d bytes instead of %d", len(b), common.HashLength)
+	}
+	return common.BytesToHash(b), nil
+}
+
+func gqlAddress(v interface{}) (common.Address, error) {
+	s, ok := v.(string)
+	if !ok {
+		return common.Address{}, fmt.Errorf("hex string required")
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("%d bytes instead of %d", len(b), common.AddressLength)
+	}
+	return common.BytesToAddress(b), nil
+}
+
+func gqlAddresses(v interface{}) ([]common.Address, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v} // a single value is coerced to a list
+	}
+	addresses := make([]common.Address, len(list))
+	for i, item := range list {
+		var err error
+		if addresses[i], err = gqlAddress(item); err != nil {
+			return nil, err
+		}
+	}
+	return addresses, nil
+}
+
+func gqlTopics(v interface{}) ([][]common.Hash, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("list required")
+	}
+	topics := make([][]common.Hash, len(list))
+	for i, item := range list {
+		sub, ok := item.([]interface{})
+		if !ok && item != nil {
+			sub = []interface{}{item}
+		}
+		for _, topic := range sub {
+			hash, err := gqlBytes32(topic)
+			if err != nil {
+				return nil, err
+			}
+			topics[i] = append(topics[i], hash)
+		}
+	}
+	return topics, nil
+}
@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/graphql"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQL(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), m.DB, nil, nil, nil, 5000000)
+	srv := httptest.NewServer(api.GraphQLHandler())
+	defer srv.Close()
+	ctx := context.Background()
+
+	do := func(q string, vars map[string]interface{}) (map[string]interface{}, []interface{}) {
+		body, err := json.Marshal(map[string]interface{}{"query": q, "variables": vars})
+		require.NoError(t, err)
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res struct {
+			Data   map[string]interface{}
+			Errors []interface{}
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return res.Data, res.Errors
+	}
+	query := func(q string, vars map[string]interface{}) map[string]interface{} {
+		data, errs := do(q, vars)
+		require.Empty(t, errs)
+		return data
+	}
+
+	expected, err := api.GetBlockByNumber(ctx, 3, false)
+	require.NoError(t, err)
+	data := query(`query($n: Long) { block(number: $n) { number hash parent { hash } transactionCount transactions { hash from { address } } } }`,
+		map[string]interface{}{"n": 3})
+	block := data["block"].(map[string]interface{})
+	require.Equal(t, float64(3), block["number"])
+	require.Equal(t, expected["hash"].(common.Hash).Hex(), block["hash"])
+	require.Equal(t, expected["parentHash"].(common.Hash).Hex(), block["parent"].(map[string]interface{})["hash"])
+	txs := block["transactions"].([]interface{})
+	require.Equal(t, float64(len(txs)), block["transactionCount"])
+	require.Equal(t, len(expected["transactions"].([]interface{})), len(txs))
+	for i, txn := range txs {
+		require.Equal(t, expected["transactions"].([]interface{})[i].(common.Hash).Hex(), txn.(map[string]interface{})["hash"])
+	}
+
+	// the accounts are read at the state of the block
+	from := common.HexToAddress(txs[0].(map[string]interface{})["from"].(map[string]interface{})["address"].(string))
+	for _, n := range []int64{0, 3} {
+		balance, err := api.GetBalance(ctx, from, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(n)))
+		require.NoError(t, err)
+		data = query(`query($n: Long, $a: Address!) { block(number: $n) { account(address: $a) { balance transactionCount } } }`,
+			map[string]interface{}{"n": n, "a": from.Hex()})
+		require.Equal(t, balance.String(), data["block"].(map[string]interface{})["account"].(map[string]interface{})["balance"])
+	}
+
+	hash := txs[0].(map[string]interface{})["hash"].(string)
+	receipt, err := api.GetTransactionReceipt(ctx, common.HexToHash(hash))
+	require.NoError(t, err)
+	data = query(`{ transaction(hash: "`+hash+`") { block { number } index status gasUsed } }`, nil)
+	txn := data["transaction"].(map[string]interface{})
+	require.Equal(t, float64(3), txn["block"].(map[string]interface{})["number"])
+	require.Equal(t, float64(0), txn["index"])
+	require.Equal(t, float64(receipt["status"].(hexutil.Uint64)), txn["status"])
+	require.Equal(t, float64(receipt["gasUsed"].(hexutil.Uint64)), txn["gasUsed"])
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.NoError(t, api.GetLogs(ctx, filters.FilterCriteria{}, stream))
+	var logs []*types.Log
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logs))
+	require.NotEmpty(t, logs)
+	data = query(`{ logs(filter: {fromBlock: 0}) { index account { address } transaction { hash } } }`, nil)
+	gqlLogs := data["logs"].([]interface{})
+	require.Equal(t, len(logs), len(gqlLogs))
+	for i, l := range gqlLogs {
+		l := l.(map[string]interface{})
+		require.Equal(t, float64(logs[i].Index), l["index"])
+		require.Equal(t, strings.ToLower(logs[i].Address.Hex()), l["account"].(map[string]interface{})["address"])
+		require.Equal(t, logs[i].TxHash.Hex(), l["transaction"].(map[string]interface{})["hash"])
+	}
+
+	data = query(`{ blocks(from: 8, to: 100) { number } block(number: 1000) { number } }`, nil)
+	require.Len(t, data["blocks"], 3)
+	require.Nil(t, data["block"])
+
+	data, errs := do(`{ blocks(from: 0, to: 5000) { number } }`, nil)
+	require.Nil(t, data["blocks"])
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].(map[string]interface{})["message"], "too many blocks")
+}
+
+// gqlIntrospectionQuery is the introspection query of the GraphQL clients, like GraphiQL.
+const gqlIntrospectionQuery = `query IntrospectionQuery {
+	__schema {
+		queryType { name }
+		mutationType { name }
+		subscriptionType { name }
+		types { ...FullType }
+		directives { name description locations args { ...InputValue } }
+	}
+}
+fragment FullType on __Type {
+	kind name description
+	fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+	inputFields { ...InputValue }
+	interfaces { ...TypeRef }
+	enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+	possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+	kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+type gqlTypeRef struct {
+	Kind   string
+	Name   *string
+	OfType *gqlTypeRef
+}
+
+type gqlInputValue struct {
+	Name         string
+	Type         gqlTypeRef
+	DefaultValue *string
+}
+
+type gqlField struct {
+	Name string
+	Args []gqlInputValue
+	Type gqlTypeRef
+}
+
+type gqlSchemaTypes struct {
+	Schema struct {
+		QueryType    struct{ Name string }
+		MutationType *struct{ Name string }
+		Types        []struct {
+			Kind        string
+			Name        string
+			Fields      []gqlField
+			InputFields []gqlInputValue
+		}
+	} `json:"__schema"`
+}
+
+// TestGraphQLSchema - the served schema is the one of geth, without what isn't supported, and its fields resolve
+func TestGraphQLSchema(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), m.DB, nil, nil, nil, 5000000)
+	srv := httptest.NewServer(api.GraphQLHandler())
+	defer srv.Close()
+	do := func(q string, data interface{}) {
+		body, err := json.Marshal(map[string]interface{}{"query": q})
+		require.NoError(t, err)
+		resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		res := struct {
+			Data   interface{}
+			Errors []interface{}
+		}{Data: data}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.Empty(t, res.Errors)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	var served gqlSchemaTypes
+	do(gqlIntrospectionQuery, &served)
+
+	gethSchemaText, err := os.ReadFile("testdata/geth_schema.graphql")
+	require.NoError(t, err)
+	gethSchema, err := graphql.ParseSchema(string(gethSchemaText))
+	require.NoError(t, err)
+	res, err := json.Marshal(graphql.Execute(context.Background(), gethSchema, nil, &graphql.Request{Query: gqlIntrospectionQuery}))
+	require.NoError(t, err)
+	var geth struct{ Data gqlSchemaTypes }
+	require.NoError(t, json.Unmarshal(res, &geth))
+
+	require.Equal(t, geth.Data.Schema.QueryType, served.Schema.QueryType)
+	require.Nil(t, served.Schema.MutationType)
+	gethTypes := map[string]int{}
+	for i, typ := range geth.Data.Schema.Types {
+		gethTypes[typ.Name] = i
+	}
+	scalarFields := map[string][]string{} // the fields of the objects without required arguments, resolving to scalars
+	for _, typ := range served.Schema.Types {
+		i, ok := gethTypes[typ.Name]
+		require.True(t, ok, typ.Name)
+		gethType := geth.Data.Schema.Types[i]
+		require.Equal(t, gethType.Kind, typ.Kind, typ.Name)
+		require.Equal(t, gethType.InputFields, typ.InputFields, typ.Name)
+		for _, f := range typ.Fields {
+			var gethField *gqlField
+			for j := range gethType.Fields {
+				if gethType.Fields[j].Name == f.Name {
+					gethField = &gethType.Fields[j]
+				}
+			}
+			require.NotNil(t, gethField, "%s.%s", typ.Name, f.Name)
+			require.Equal(t, *gethField, f, "%s.%s", typ.Name, f.Name)
+
+			leaf := &f.Type
+			for leaf.OfType != nil {
+				leaf = leaf.OfType
+			}
+			required := false
+			for _, arg := range f.Args {
+				required = required || arg.Type.Kind == "NON_NULL"
+			}
+			if leaf.Kind == "SCALAR" && !required {
+				scalarFields[typ.Name] = append(scalarFields[typ.Name], f.Name)
+			}
+		}
+	}
+
+	require.Len(t, scalarFields["Account"], 4) // all but storage
+	fields := func(typ string) string { return strings.Join(scalarFields[typ], " ") }
+	var data map[string]interface{}
+	do(fmt.Sprintf(`{ %s blocks(from: 0) { %s transactions { %s logs { %s account { %s } } } } }`,
+		fields("Query"), fields("Block"), fields("Transaction"), fields("Log"), fields("Account")), &data)
+	require.NotEmpty(t, data["blocks"])
+}
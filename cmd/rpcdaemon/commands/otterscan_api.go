@@ -25,7 +25,11 @@ import (
 )
 
 // API_LEVEL Must be incremented every time new additions are made
-const API_LEVEL = 8
+const API_LEVEL = 9
+
+// MaxSearchPageSize is the maximum number of transactions a search may be asked for: the blocks of a page are traced
+// concurrently.
+const MaxSearchPageSize = 100
 
 type TransactionsWithReceipts struct {
 	Txs       []*RPCTransaction        `json:"txs"`
@@ -169,7 +173,11 @@ func (api *OtterscanAPIImpl) GetInternalOperations(ctx context.Context, hash com
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
+// The pageSize must be between 1 and MaxSearchPageSize.
 func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+	if err := checkSearchPageSize(pageSize); err != nil {
+		return nil, err
+	}
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -252,7 +260,11 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
+// The pageSize must be between 1 and MaxSearchPageSize.
 func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+	if err := checkSearchPageSize(pageSize); err != nil {
+		return nil, err
+	}
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -329,6 +341,13 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 	return &TransactionsWithReceipts{txs, receipts, !hasMore, isLastPage}, nil
 }
 
+func checkSearchPageSize(pageSize uint16) error {
+	if pageSize == 0 || pageSize > MaxSearchPageSize {
+		return fmt.Errorf("page size %d not between 1 and %d", pageSize, MaxSearchPageSize)
+	}
+	return nil
+}
+
 func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Address, chainConfig *params.ChainConfig, pageSize, resultCount uint16, callFromToProvider BlockProvider) ([]*TransactionsWithReceipts, bool, error) {
 	var wg sync.WaitGroup
 
@@ -10,6 +10,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/log/v3"
@@ -43,6 +44,23 @@ func (api *OtterscanAPIImpl) GetContractCreator(ctx context.Context, addr common
 		return nil, nil
 	}
 
+	// Indexed by the execution, see rawdb.WriteContractCreations
+	creation, _, err := rawdb.ReadContractCreation(tx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if creation != nil {
+		return &ContractCreatorData{
+			Tx:      creation.TxHash,
+			Creator: creation.Creator,
+		}, nil
+	}
+	return api.searchContractCreator(ctx, tx, addr, plainStateAcc)
+}
+
+// searchContractCreator finds the creation of the contracts created before the index by tracing the block where the
+// incarnation of the account changed.
+func (api *OtterscanAPIImpl) searchContractCreator(ctx context.Context, tx kv.Tx, addr common.Address, plainStateAcc *accounts.Account) (*ContractCreatorData, error) {
 	// Contract; search for creation tx; navigate forward on AccountsHistory/ChangeSets
 	//
 	// We search shards in forward order on purpose because popular contracts may have
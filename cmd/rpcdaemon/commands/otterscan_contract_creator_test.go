@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContractCreator(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewOtterscanAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), false), m.DB)
+	ctx := context.Background()
+
+	var contracts []common.Address
+	for _, block := range chain.Blocks {
+		signer := types.MakeSigner(m.ChainConfig, block.NumberU64())
+		for _, txn := range block.Transactions() {
+			if txn.GetTo() != nil {
+				continue
+			}
+			sender, err := txn.Sender(*signer)
+			require.NoError(t, err)
+			contract := crypto.CreateAddress(sender, txn.GetNonce())
+			expected := &ContractCreatorData{Tx: txn.Hash(), Creator: sender}
+
+			// served from the index
+			creator, err := api.GetContractCreator(ctx, contract)
+			require.NoError(t, err)
+			require.Equal(t, expected, creator)
+
+			// the same as found in the history of the account
+			tx, err := m.DB.BeginRo(ctx)
+			require.NoError(t, err)
+			creation, blockNumber, err := rawdb.ReadContractCreation(tx, contract)
+			require.NoError(t, err)
+			require.NotNil(t, creation)
+			require.Equal(t, block.NumberU64(), blockNumber)
+			acc, err := state.NewPlainStateReader(tx).ReadAccountData(contract)
+			require.NoError(t, err)
+			creator, err = api.searchContractCreator(ctx, tx, contract, acc)
+			tx.Rollback()
+			require.NoError(t, err)
+			require.Equal(t, expected, creator)
+			contracts = append(contracts, contract)
+		}
+	}
+	require.Equal(t, 3, len(contracts))
+
+	// the creations of the unwound blocks are removed
+	tx, err := m.DB.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	require.NoError(t, rawdb.TruncateContractCreations(tx, 1))
+	for _, contract := range contracts {
+		creation, _, err := rawdb.ReadContractCreation(tx, contract)
+		require.NoError(t, err)
+		require.Nil(t, creation)
+	}
+}
@@ -29,3 +29,16 @@ func checkNext(t *testing.T, blockProvider BlockProvider, expectedBlock uint64,
 		t.Fatalf("Expected hasNext=%t, received=%t; at block=%d", expectedHasNext, hasNext, expectedBlock)
 	}
 }
+
+func TestCheckSearchPageSize(t *testing.T) {
+	for _, pageSize := range []uint16{1, 25, MaxSearchPageSize} {
+		if err := checkSearchPageSize(pageSize); err != nil {
+			t.Fatalf("Expected page size %d to be accepted, received %v", pageSize, err)
+		}
+	}
+	for _, pageSize := range []uint16{0, MaxSearchPageSize + 1} {
+		if err := checkSearchPageSize(pageSize); err == nil {
+			t.Fatalf("Expected page size %d to be rejected", pageSize)
+		}
+	}
+}
@@ -69,7 +69,10 @@ func (api *OtterscanAPIImpl) traceBlock(dbtx kv.Tx, ctx context.Context, blockNu
 	engine := ethash.NewFaker()
 	checkTEVM := ethdb.GetHasTEVM(dbtx)
 
-	blockReceipts := rawdb.ReadReceipts(dbtx, block, senders)
+	blockReceipts, err := api.getReceipts(ctx, dbtx, chainConfig, block, senders)
+	if err != nil {
+		return false, nil, err
+	}
 	header := block.Header()
 	rules := chainConfig.Rules(block.NumberU64())
 	found := false
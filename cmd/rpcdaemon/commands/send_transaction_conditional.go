@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// maxKnownAccountsSlots is the maximum number of storage slots and roots the knownAccounts of a conditional
+// transaction may expect.
+const maxKnownAccountsSlots = 1000
+
+// KnownAccount is the expected storage of an account: either its storage root, or the values of some of its slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+func (a *KnownAccount) UnmarshalJSON(data []byte) error {
+	var root common.Hash
+	if err := json.Unmarshal(data, &root); err == nil {
+		a.StorageRoot = &root
+		return nil
+	}
+	return json.Unmarshal(data, &a.StorageSlots)
+}
+
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// TransactionConditional are the conditions on the head of the chain a conditional transaction is only accepted with.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Uint64                 `json:"blockNumberMin"`
+	BlockNumberMax *hexutil.Uint64                 `json:"blockNumberMax"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax"`
+}
+
+// SendRawTransactionConditional implements eth_sendRawTransactionConditional. Sends the given transaction to the
+// txpool like eth_sendRawTransaction, as long as the head of the chain meets the given conditions. The txpool knows
+// nothing of the conditions: they aren't checked again when the transaction is included.
+func (api *APIImpl) SendRawTransactionConditional(ctx context.Context, encodedTx hexutil.Bytes, conditional TransactionConditional) (common.Hash, error) {
+	if err := api.checkConditional(ctx, conditional); err != nil {
+		return common.Hash{}, err
+	}
+	return api.SendRawTransaction(ctx, encodedTx)
+}
+
+// checkConditional returns the reason the head of the chain doesn't meet the given conditions, if any.
+func (api *APIImpl) checkConditional(ctx context.Context, conditional TransactionConditional) error {
+	slots := 0
+	for _, acc := range conditional.KnownAccounts {
+		if acc.StorageRoot != nil {
+			slots++
+		} else {
+			slots += len(acc.StorageSlots)
+		}
+	}
+	if slots > maxKnownAccountsSlots {
+		return fmt.Errorf("too many known accounts slots: %d, the maximum is %d", slots, maxKnownAccountsSlots)
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// The conditions are checked against the latest state
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNum)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("block %d not found", blockNum)
+	}
+
+	number := header.Number.Uint64()
+	if min := conditional.BlockNumberMin; min != nil && number < uint64(*min) {
+		return &conditionalError{fmt.Sprintf("block number %d before the minimum %d", number, uint64(*min))}
+	}
+	if max := conditional.BlockNumberMax; max != nil && number > uint64(*max) {
+		return &conditionalError{fmt.Sprintf("block number %d after the maximum %d", number, uint64(*max))}
+	}
+	if min := conditional.TimestampMin; min != nil && header.Time < uint64(*min) {
+		return &conditionalError{fmt.Sprintf("timestamp %d before the minimum %d", header.Time, uint64(*min))}
+	}
+	if max := conditional.TimestampMax; max != nil && header.Time > uint64(*max) {
+		return &conditionalError{fmt.Sprintf("timestamp %d after the maximum %d", header.Time, uint64(*max))}
+	}
+
+	for addr, expected := range conditional.KnownAccounts {
+		if err := checkKnownAccount(ctx, tx, header, addr, expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkKnownAccount returns the reason the storage of the given account at the given head isn't the expected one,
+// if any.
+func checkKnownAccount(ctx context.Context, tx kv.Tx, header *types.Header, addr common.Address, expected KnownAccount) error {
+	if expected.StorageRoot != nil {
+		proof, err := stagedsync.GenerateAccountProof(ctx, tx, header, addr, nil, "", GetProofMaxUnwind, GetProofMaxMemory)
+		if err != nil {
+			return err
+		}
+		root := trie.EmptyRoot
+		if proof.Account != nil {
+			root = proof.Account.Root
+		}
+		if root != *expected.StorageRoot {
+			return &conditionalError{fmt.Sprintf("storage root of %x is %x, not %x", addr, root, *expected.StorageRoot)}
+		}
+		return nil
+	}
+
+	reader := state.NewPlainStateReader(tx)
+	acc, err := reader.ReadAccountData(addr)
+	if err != nil {
+		return err
+	}
+	for key, value := range expected.StorageSlots {
+		var current common.Hash
+		if acc != nil {
+			key := key
+			enc, err := reader.ReadAccountStorage(addr, acc.Incarnation, &key)
+			if err != nil {
+				return err
+			}
+			current = new(uint256.Int).SetBytes(enc).Bytes32()
+		}
+		if current != value {
+			return &conditionalError{fmt.Sprintf("storage slot %x of %x is %x, not %x", key, addr, current, value)}
+		}
+	}
+	return nil
+}
+
+// conditionalError is the error of the conditional transactions whose conditions the chain doesn't meet.
+type conditionalError struct{ reason string }
+
+func (e *conditionalError) ErrorCode() int { return -32003 }
+
+func (e *conditionalError) Error() string { return "conditions not met: " + e.reason }
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownAccountJSON(t *testing.T) {
+	var accounts map[common.Address]KnownAccount
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"0x0000000000000000000000000000000000000001": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+		"0x0000000000000000000000000000000000000002": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"}
+	}`), &accounts))
+	require.Equal(t, trie.EmptyRoot, *accounts[common.Address{19: 1}].StorageRoot)
+	require.Nil(t, accounts[common.Address{19: 2}].StorageRoot)
+	require.Equal(t, common.Hash{31: 2}, accounts[common.Address{19: 2}].StorageSlots[common.Hash{31: 1}])
+}
+
+func TestCheckConditional(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, nil, nil, nil, 5000000)
+	ctx := context.Background()
+	num := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+	emptyRoot := trie.EmptyRoot
+	unknown := common.Address{19: 0xff}
+
+	// The head of the test chain is the block 10
+	require.NoError(t, api.checkConditional(ctx, TransactionConditional{BlockNumberMin: num(10), BlockNumberMax: num(10)}))
+	require.Error(t, api.checkConditional(ctx, TransactionConditional{BlockNumberMin: num(11)}))
+	require.Error(t, api.checkConditional(ctx, TransactionConditional{BlockNumberMax: num(9)}))
+	require.Error(t, api.checkConditional(ctx, TransactionConditional{TimestampMax: num(0)}))
+
+	// The accounts without storage have the empty root, and empty slots
+	require.NoError(t, api.checkConditional(ctx, TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{
+		unknown: {StorageRoot: &emptyRoot},
+	}}))
+	require.NoError(t, api.checkConditional(ctx, TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{
+		unknown: {StorageSlots: map[common.Hash]common.Hash{{}: {}}},
+	}}))
+	err := api.checkConditional(ctx, TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{
+		unknown: {StorageSlots: map[common.Hash]common.Hash{{}: {31: 1}}},
+	}})
+	require.Error(t, err)
+	require.Equal(t, -32003, err.(*conditionalError).ErrorCode())
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 )
 
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
@@ -23,6 +26,8 @@ type StorageEntry struct {
 	Value common.Hash  `json:"value"`
 }
 
+// StorageRangeAt returns the storage of contractAddress from location start, in order of locations (not of their
+// hashes, unlike geth). Use NextKey as start of the next page.
 func StorageRangeAt(stateReader *state.PlainState, contractAddress common.Address, start []byte, maxResult int) (StorageRangeResult, error) {
 	result := StorageRangeResult{Storage: StorageMap{}}
 	resultCount := 0
@@ -40,3 +45,27 @@ func StorageRangeAt(stateReader *state.PlainState, contractAddress common.Addres
 	}
 	return result, nil
 }
+
+// checkStateHistory returns error if the state at the beginning of block blockNum can't be read: the block is not
+// executed yet, or its state history (changesets) is pruned
+func checkStateHistory(tx kv.Tx, blockNum uint64) error {
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if blockNum > executionProgress+1 {
+		return fmt.Errorf("block %d is not executed yet, execution is at block %d", blockNum, executionProgress)
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	keptFrom, err := pm.KeptFrom(tx, prune.KindHistory, executionProgress)
+	if err != nil {
+		return err
+	}
+	if blockNum < keptFrom {
+		return fmt.Errorf("state history of block %d is pruned, the node keeps it from block %d", blockNum, keptFrom)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/stretchr/testify/require"
@@ -106,3 +108,40 @@ func TestReplayBlockTransactions(t *testing.T) {
 	v := addrDiff.Balance.(map[string]*hexutil.Big)["+"].ToInt().Uint64()
 	require.Equal(t, uint64(1_000_000_000_000_000), v)
 }
+
+func TestRawTransaction(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewTraceAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, &httpcfg.HttpCfg{})
+	var txn types.Transaction
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		b, err := rawdb.ReadBlockByNumber(tx, 6)
+		if err != nil {
+			return err
+		}
+		txn = b.Transactions()[0]
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, txn.MarshalBinary(&buf))
+
+	// The first transaction of the block, traced on top of its parent, gives the same traces as when replayed
+	expected, err := api.ReplayTransaction(context.Background(), txn.Hash(), []string{"trace", "stateDiff"})
+	require.NoError(t, err)
+	parent := rpc.BlockNumberOrHashWithNumber(5)
+	results, err := api.RawTransaction(context.Background(), buf.Bytes(), []string{"trace", "stateDiff"}, &parent)
+	require.NoError(t, err)
+	require.Equal(t, expected.Output, results.Output)
+	require.Equal(t, len(expected.Trace), len(results.Trace))
+	for i := range expected.Trace {
+		require.Equal(t, expected.Trace[i].Action, results.Trace[i].Action)
+		require.Equal(t, expected.Trace[i].Result, results.Trace[i].Result)
+	}
+	require.Equal(t, expected.StateDiff[*txn.GetTo()], results.StateDiff[*txn.GetTo()])
+
+	// The transaction has been mined: its nonce is too low for the latest state
+	_, err = api.RawTransaction(context.Background(), buf.Bytes(), []string{"trace"}, nil)
+	require.Error(t, err)
+}
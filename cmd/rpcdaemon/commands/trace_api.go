@@ -19,13 +19,17 @@ type TraceAPI interface {
 	ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) (*TraceCallResult, error)
 	Call(ctx context.Context, call TraceCallParam, types []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 	CallMany(ctx context.Context, calls json.RawMessage, blockNr *rpc.BlockNumberOrHash) ([]*TraceCallResult, error)
-	RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error)
+	RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, parentNrOrHash *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 
 	// Filtering (see ./trace_filtering.go)
 	Transaction(ctx context.Context, txHash common.Hash) (ParityTraces, error)
 	Get(ctx context.Context, txHash common.Hash, txIndicies []hexutil.Uint64) (*ParityTrace, error)
 	Block(ctx context.Context, blockNr rpc.BlockNumber) (ParityTraces, error)
 	Filter(ctx context.Context, req TraceFilterRequest, stream *jsoniter.Stream) error
+
+	// State diffs from the change sets (see ./trace_state_diff.go)
+	BlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*StateDiffAccount, error)
+	StateDiffs(ctx context.Context) (*rpc.Subscription, error)
 }
 
 // TraceAPIImpl is implementation of the TraceAPI interface based on remote Db access
@@ -7,6 +7,7 @@ import (
 
 	"github.com/RoaringBitmap/roaring/roaring64"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/kv"
 
 	"github.com/ledgerwatch/erigon/common"
@@ -14,8 +15,10 @@ import (
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -288,8 +291,25 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	if len(req.FromAddress) == 0 && len(req.ToAddress) == 0 {
 		allBlocks.AddRange(fromBlock, toBlock+1)
 	} else {
+		// The blocks the index doesn't cover yet are all replayed and their traces filtered. The pruned ones are
+		// refused if fromBlock asks for them, and skipped if it is omitted
+		indexedFrom, indexedTo, err := callTracesIndexed(dbtx)
+		if err != nil {
+			stream.WriteNil()
+			return err
+		}
+		if fromBlock < indexedFrom {
+			if req.FromBlock != nil {
+				stream.WriteNil()
+				return fmt.Errorf("call traces of block %d are pruned, available from block %d", fromBlock, indexedFrom)
+			}
+			fromBlock = indexedFrom
+		}
 		allBlocks.RemoveRange(0, fromBlock)
 		allBlocks.RemoveRange(toBlock+1, uint64(0x100000000))
+		if toBlock > indexedTo {
+			allBlocks.AddRange(cmp.Max(indexedTo+1, fromBlock), toBlock+1)
+		}
 	}
 
 	chainConfig, err := api.chainConfig(dbtx)
@@ -439,6 +459,23 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	return stream.Flush()
 }
 
+// callTracesIndexed returns the range of the blocks covered by the call traces index (kv.CallFromIndex and
+// kv.CallToIndex): from the prune point of the index to the progress of its stage.
+func callTracesIndexed(tx kv.Tx) (from, to uint64, err error) {
+	to, err = stages.GetStageProgress(tx, stages.CallTraces)
+	if err != nil {
+		return 0, 0, err
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from, err = pm.KeptFrom(tx, prune.KindCallTraces, to); err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
 func filter_trace(pt *ParityTrace, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}) bool {
 	switch action := pt.Action.(type) {
 	case *CallTraceAction:
@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// BlockStateDiff is the state diff of a whole block, notified by trace_subscribe("stateDiffs")
+type BlockStateDiff struct {
+	BlockNumber hexutil.Uint64                       `json:"blockNumber"`
+	BlockHash   common.Hash                          `json:"blockHash"`
+	StateDiff   map[common.Address]*StateDiffAccount `json:"stateDiff"`
+}
+
+// BlockStateDiff implements trace_blockStateDiff. Returns the state diff of the whole block, in the format of
+// trace_replayBlockTransactions(["stateDiff"]). It's read from the change sets of the block, without re-executing it.
+func (api *TraceAPIImpl) BlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*StateDiffAccount, error) {
+	tx, err := api.kv.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	blockNumber, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	return blockStateDiff(tx, blockNumber)
+}
+
+// StateDiffs send a notification with the state diff of each block added to the canonical chain, see BlockStateDiff.
+func (api *TraceAPIImpl) StateDiffs(ctx context.Context) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		headers := make(chan *types.Header, 1)
+		id := api.filters.SubscribeNewHeads(headers)
+		defer api.filters.UnsubscribeHeads(id)
+
+		for {
+			select {
+			case h, ok := <-headers:
+				if h != nil {
+					diff, err := api.canonicalBlockStateDiff(h)
+					if err != nil {
+						log.Warn("error while reading the state diff", "block", h.Number.Uint64(), "err", err)
+						return
+					}
+					if diff != nil {
+						if err := notifier.Notify(rpcSub.ID, diff); err != nil {
+							log.Warn("error while notifying subscription", "err", err)
+							return
+						}
+					}
+				}
+				if !ok {
+					log.Warn("new heads channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// canonicalBlockStateDiff - nil if the header isn't canonical (headers of side chains are notified too)
+func (api *TraceAPIImpl) canonicalBlockStateDiff(h *types.Header) (*BlockStateDiff, error) {
+	tx, err := api.kv.BeginRo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	number := h.Number.Uint64()
+	hash, err := rawdb.ReadCanonicalHash(tx, number)
+	if err != nil {
+		return nil, err
+	}
+	if hash != h.Hash() {
+		return nil, nil
+	}
+	diff, err := blockStateDiff(tx, number)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStateDiff{BlockNumber: hexutil.Uint64(number), BlockHash: hash, StateDiff: diff}, nil
+}
+
+// blockStateDiff - from the change sets of the block (the values before it), and the history of the state after it
+func blockStateDiff(tx kv.Tx, blockNumber uint64) (map[common.Address]*StateDiffAccount, error) {
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber == 0 || blockNumber > executed {
+		return nil, fmt.Errorf("block %d has no state changes, executed blocks: 1-%d", blockNumber, executed)
+	}
+	availableFrom, err := changeset.AvailableFrom(tx)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber < availableFrom {
+		return nil, fmt.Errorf("state changes of block %d are pruned, available from block %d", blockNumber, availableFrom)
+	}
+
+	after := state.NewPlainState(tx, blockNumber+1)
+	sd := map[common.Address]*StateDiffAccount{}
+	created := map[common.Address]bool{}
+	if err := changeset.ForPrefix(tx, kv.AccountChangeSet, dbutils.EncodeBlockNumber(blockNumber), func(_ uint64, k, v []byte) error {
+		address := common.BytesToAddress(k)
+		var from *accounts.Account
+		if len(v) > 0 {
+			from = new(accounts.Account)
+			if err := from.DecodeForStorage(v); err != nil {
+				return err
+			}
+		}
+		to, err := after.ReadAccountData(address)
+		if err != nil {
+			return err
+		}
+		accountDiff, err := stateDiffAccount(tx, from, to)
+		if err != nil {
+			return err
+		}
+		if accountDiff != nil {
+			sd[address] = accountDiff
+		}
+		created[address] = from == nil && to != nil
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := changeset.ForPrefix(tx, kv.StorageChangeSet, dbutils.EncodeBlockNumber(blockNumber), func(_ uint64, k, v []byte) error {
+		address := common.BytesToAddress(k[:length.Addr])
+		incarnation := binary.BigEndian.Uint64(k[length.Addr:])
+		location := common.BytesToHash(k[length.Addr+length.Incarnation:])
+		to, err := after.ReadAccountStorage(address, incarnation, &location)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(v, to) {
+			return nil
+		}
+		accountDiff := sd[address]
+		if accountDiff == nil {
+			// only the storage of the account has changed
+			accountDiff = &StateDiffAccount{Balance: "=", Code: "=", Nonce: "=", Storage: map[common.Hash]map[string]interface{}{}}
+			sd[address] = accountDiff
+		}
+		toHash := common.BytesToHash(to)
+		if created[address] {
+			accountDiff.Storage[location] = map[string]interface{}{"+": &toHash}
+		} else {
+			accountDiff.Storage[location] = map[string]interface{}{"*": &StateDiffStorage{From: common.BytesToHash(v), To: toHash}}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// stateDiffAccount - like StateDiff.CompareStates, nil if the account hasn't changed
+func stateDiffAccount(tx kv.Tx, from, to *accounts.Account) (*StateDiffAccount, error) {
+	code := func(acc *accounts.Account) (hexutil.Bytes, error) {
+		if acc.IsEmptyCodeHash() {
+			return hexutil.Bytes{}, nil
+		}
+		c, err := tx.GetOne(kv.Code, acc.CodeHash[:])
+		return common.CopyBytes(c), err
+	}
+	accountDiff := &StateDiffAccount{Storage: map[common.Hash]map[string]interface{}{}}
+	switch {
+	case from == nil && to == nil:
+		return nil, nil
+	case from == nil:
+		toCode, err := code(to)
+		if err != nil {
+			return nil, err
+		}
+		accountDiff.Balance = map[string]*hexutil.Big{"+": (*hexutil.Big)(to.Balance.ToBig())}
+		accountDiff.Code = map[string]hexutil.Bytes{"+": toCode}
+		accountDiff.Nonce = map[string]hexutil.Uint64{"+": hexutil.Uint64(to.Nonce)}
+	case to == nil:
+		fromCode, err := code(from)
+		if err != nil {
+			return nil, err
+		}
+		accountDiff.Balance = map[string]*hexutil.Big{"-": (*hexutil.Big)(from.Balance.ToBig())}
+		accountDiff.Code = map[string]hexutil.Bytes{"-": fromCode}
+		accountDiff.Nonce = map[string]hexutil.Uint64{"-": hexutil.Uint64(from.Nonce)}
+	default:
+		allEqual := true
+		if fromBalance, toBalance := from.Balance.ToBig(), to.Balance.ToBig(); fromBalance.Cmp(toBalance) == 0 {
+			accountDiff.Balance = "="
+		} else {
+			accountDiff.Balance = map[string]*StateDiffBalance{"*": {From: (*hexutil.Big)(fromBalance), To: (*hexutil.Big)(new(big.Int).Set(toBalance))}}
+			allEqual = false
+		}
+		if from.CodeHash == to.CodeHash {
+			accountDiff.Code = "="
+		} else {
+			fromCode, err := code(from)
+			if err != nil {
+				return nil, err
+			}
+			toCode, err := code(to)
+			if err != nil {
+				return nil, err
+			}
+			accountDiff.Code = map[string]*StateDiffCode{"*": {From: fromCode, To: toCode}}
+			allEqual = false
+		}
+		if from.Nonce == to.Nonce {
+			accountDiff.Nonce = "="
+		} else {
+			accountDiff.Nonce = map[string]*StateDiffNonce{"*": {From: hexutil.Uint64(from.Nonce), To: hexutil.Uint64(to.Nonce)}}
+			allEqual = false
+		}
+		if allEqual {
+			// the account may still have storage changes, see blockStateDiff
+			return nil, nil
+		}
+	}
+	return accountDiff, nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+// The balance changes of a block, read from the change sets, go from the balance before the first transaction to the
+// balance after the last one, as re-executed by trace_replayBlockTransactions
+func TestBlockStateDiff(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewTraceAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false), db, &httpcfg.HttpCfg{})
+
+	for _, n := range []rpc.BlockNumber{1, 2, 3} {
+		blockNr := rpc.BlockNumberOrHashWithNumber(n)
+		diff, err := api.BlockStateDiff(context.Background(), blockNr)
+		require.NoError(t, err)
+		require.NotEmpty(t, diff)
+		results, err := api.ReplayBlockTransactions(context.Background(), blockNr, []string{TraceTypeStateDiff})
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+
+		from, to := map[common.Address]string{}, map[common.Address]string{}
+		for _, res := range results {
+			for address, accountDiff := range res.StateDiff {
+				balance, ok := accountDiff.Balance.(map[string]*StateDiffBalance)
+				if !ok {
+					continue
+				}
+				if _, ok := from[address]; !ok {
+					from[address] = balance["*"].From.String()
+				}
+				to[address] = balance["*"].To.String()
+			}
+		}
+		for address, accountDiff := range diff {
+			balance, ok := accountDiff.Balance.(map[string]*StateDiffBalance)
+			if !ok || from[address] == "" || address == (common.Address{}) {
+				continue // the block reward of the coinbase isn't in the transactions
+			}
+			require.Equal(t, from[address], balance["*"].From.String(), "block %d, address %x", n, address)
+			require.Equal(t, to[address], balance["*"].To.String(), "block %d, address %x", n, address)
+		}
+	}
+
+	_, err := api.BlockStateDiff(context.Background(), rpc.BlockNumberOrHashWithNumber(1000))
+	require.Error(t, err)
+}
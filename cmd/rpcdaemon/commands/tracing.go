@@ -76,40 +76,46 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		return h
 	}
 
-	_, blockCtx, _, ibs, reader, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0)
-	if err != nil {
-		stream.WriteNil()
-		return err
-	}
-
-	signer := types.MakeSigner(chainConfig, block.NumberU64())
-	rules := chainConfig.Rules(block.NumberU64())
-	stream.WriteArrayStart()
-	for idx, tx := range block.Transactions() {
-		select {
-		default:
-		case <-ctx.Done():
+	return api.traceCache.trace(tx, block.NumberU64(), block.Hash(), "block", -1, config, stream, func(stream *jsoniter.Stream) (bool, error) {
+		_, blockCtx, _, ibs, reader, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0)
+		if err != nil {
 			stream.WriteNil()
-			return ctx.Err()
-		}
-		ibs.Prepare(tx.Hash(), block.Hash(), idx)
-		msg, _ := tx.AsMessage(*signer, block.BaseFee(), rules)
-		txCtx := vm.TxContext{
-			TxHash:   tx.Hash(),
-			Origin:   msg.From(),
-			GasPrice: msg.GasPrice().ToBig(),
+			return false, err
 		}
 
-		transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream)
-		_ = ibs.FinalizeTx(rules, reader)
-		if idx != len(block.Transactions())-1 {
-			stream.WriteMore()
+		signer := types.MakeSigner(chainConfig, block.NumberU64())
+		rules := chainConfig.Rules(block.NumberU64())
+		complete := true
+		stream.WriteArrayStart()
+		for idx, tx := range block.Transactions() {
+			select {
+			default:
+			case <-ctx.Done():
+				stream.WriteNil()
+				return false, ctx.Err()
+			}
+			ibs.Prepare(tx.Hash(), block.Hash(), idx)
+			msg, _ := tx.AsMessage(*signer, block.BaseFee(), rules)
+			txCtx := vm.TxContext{
+				TxHash:   tx.Hash(),
+				Origin:   msg.From(),
+				GasPrice: msg.GasPrice().ToBig(),
+			}
+
+			// the traces which fail, e.g. by a timeout, are written but not cached
+			if err := transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream); err != nil {
+				complete = false
+			}
+			_ = ibs.FinalizeTx(rules, reader)
+			if idx != len(block.Transactions())-1 {
+				stream.WriteMore()
+			}
+			stream.Flush()
 		}
+		stream.WriteArrayEnd()
 		stream.Flush()
-	}
-	stream.WriteArrayEnd()
-	stream.Flush()
-	return nil
+		return complete && ctx.Err() == nil, nil
+	})
 }
 
 // TraceTransaction implements debug_traceTransaction. Returns Geth style transaction traces.
@@ -177,13 +183,18 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 	if api.TevmEnabled {
 		contractHasTEVM = ethdb.GetHasTEVM(tx)
 	}
-	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, blockHash, txnIndex)
-	if err != nil {
-		stream.WriteNil()
-		return err
-	}
-	// Trace the transaction and return
-	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream)
+	return api.traceCache.trace(tx, blockNum, blockHash, "transaction", int(txnIndex), config, stream, func(stream *jsoniter.Stream) (bool, error) {
+		msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, blockHash, txnIndex)
+		if err != nil {
+			stream.WriteNil()
+			return false, err
+		}
+		// Trace the transaction and return
+		if err := transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream); err != nil {
+			return false, err
+		}
+		return ctx.Err() == nil, nil
+	})
 }
 
 func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig, stream *jsoniter.Stream) error {
@@ -236,6 +247,13 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 			return fmt.Errorf("header.BaseFee uint256 overflow")
 		}
 	}
+	if config != nil && config.BlockOverrides != nil && config.BlockOverrides.BaseFee != nil {
+		var overflow bool
+		baseFee, overflow = uint256.FromBig(config.BlockOverrides.BaseFee.ToInt())
+		if overflow {
+			return fmt.Errorf("blockOverrides.BaseFee uint256 overflow")
+		}
+	}
 	msg, err := args.ToMessage(api.GasCap, baseFee)
 	if err != nil {
 		return err
@@ -246,6 +264,11 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 		contractHasTEVM = ethdb.GetHasTEVM(dbtx)
 	}
 	blockCtx, txCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, dbtx, contractHasTEVM, api._blockReader)
+	if config != nil && config.BlockOverrides != nil {
+		if err := config.BlockOverrides.Override(&blockCtx); err != nil {
+			return err
+		}
+	}
 	// Trace the transaction and return
 	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream)
 }
@@ -317,7 +340,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 
 	replayTransactions = block.Transactions()[:transactionIndex]
 
-	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache)
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache, api.stateOverlay)
 
 	if err != nil {
 		stream.WriteNil()
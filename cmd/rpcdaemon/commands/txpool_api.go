@@ -18,6 +18,9 @@ import (
 // NetAPI the interface for the net_ RPC commands
 type TxPoolAPI interface {
 	Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error)
+	ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*RPCTransaction, error)
+	Inspect(ctx context.Context) (map[string]map[string]map[string]string, error)
+	Status(ctx context.Context) (map[string]hexutil.Uint, error)
 }
 
 // TxPoolAPIImpl data structure to store things needed for net_ commands
@@ -37,45 +40,46 @@ func NewTxPoolAPI(base *BaseAPI, db kv.RoDB, pool proto_txpool.TxpoolClient) *Tx
 }
 
 func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error) {
-	reply, err := api.pool.All(ctx, &proto_txpool.AllRequest{})
+	subPools, err := api.subPools(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	content := map[string]map[string]map[string]*RPCTransaction{
-		"pending": make(map[string]map[string]*RPCTransaction),
-		"baseFee": make(map[string]map[string]*RPCTransaction),
-		"queued":  make(map[string]map[string]*RPCTransaction),
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
 	}
 
-	pending := make(map[common.Address][]types.Transaction, 8)
-	baseFee := make(map[common.Address][]types.Transaction, 8)
-	queued := make(map[common.Address][]types.Transaction, 8)
-	for i := range reply.Txs {
-		stream := rlp.NewStream(bytes.NewReader(reply.Txs[i].RlpTx), 0)
-		txn, err := types.DecodeTransaction(stream)
-		if err != nil {
-			return nil, err
-		}
-		addr := gointerfaces.ConvertH160toAddress(reply.Txs[i].Sender)
-		switch reply.Txs[i].TxnType {
-		case proto_txpool.AllReply_PENDING:
-			if _, ok := pending[addr]; !ok {
-				pending[addr] = make([]types.Transaction, 0, 4)
-			}
-			pending[addr] = append(pending[addr], txn)
-		case proto_txpool.AllReply_BASE_FEE:
-			if _, ok := baseFee[addr]; !ok {
-				baseFee[addr] = make([]types.Transaction, 0, 4)
-			}
-			baseFee[addr] = append(baseFee[addr], txn)
-		case proto_txpool.AllReply_QUEUED:
-			if _, ok := queued[addr]; !ok {
-				queued[addr] = make([]types.Transaction, 0, 4)
+	curHeader := rawdb.ReadCurrentHeader(tx)
+	if curHeader == nil {
+		return nil, nil
+	}
+	content := make(map[string]map[string]map[string]*RPCTransaction, len(subPools))
+	// Flatten the transactions of each sub-pool
+	for name, subPool := range subPools {
+		content[name] = make(map[string]map[string]*RPCTransaction, len(subPool))
+		for account, txs := range subPool {
+			dump := make(map[string]*RPCTransaction)
+			for _, txn := range txs {
+				dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
 			}
-			queued[addr] = append(queued[addr], txn)
+			content[name][account.Hex()] = dump
 		}
 	}
+	return content, nil
+}
+
+// ContentFrom returns the transactions of the given account in the pool, by sub-pool and nonce.
+func (api *TxPoolAPIImpl) ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*RPCTransaction, error) {
+	subPools, err := api.subPools(ctx, &addr)
+	if err != nil {
+		return nil, err
+	}
 
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -91,33 +95,81 @@ func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]ma
 	if curHeader == nil {
 		return nil, nil
 	}
-	// Flatten the pending transactions
-	for account, txs := range pending {
+	content := make(map[string]map[string]*RPCTransaction, len(subPools))
+	for name, subPool := range subPools {
 		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
+		for _, txn := range subPool[addr] {
 			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
 		}
-		content["pending"][account.Hex()] = dump
+		content[name] = dump
 	}
-	// Flatten the baseFee transactions
-	for account, txs := range baseFee {
-		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
-			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
+	return content, nil
+}
+
+// Inspect returns the transactions in the pool, by sub-pool, account and nonce, flattened into short descriptions.
+func (api *TxPoolAPIImpl) Inspect(ctx context.Context) (map[string]map[string]map[string]string, error) {
+	subPools, err := api.subPools(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define a formatter to flatten a transaction into a string
+	format := func(txn types.Transaction) string {
+		if to := txn.GetTo(); to != nil {
+			return fmt.Sprintf("%s: %d wei + %d gas × %d wei", to.Hex(), txn.GetValue(), txn.GetGas(), txn.GetFeeCap())
 		}
-		content["baseFee"][account.Hex()] = dump
+		return fmt.Sprintf("contract creation: %d wei + %d gas × %d wei", txn.GetValue(), txn.GetGas(), txn.GetFeeCap())
 	}
-	// Flatten the queued transactions
-	for account, txs := range queued {
-		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
-			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
+	content := make(map[string]map[string]map[string]string, len(subPools))
+	for name, subPool := range subPools {
+		content[name] = make(map[string]map[string]string, len(subPool))
+		for account, txs := range subPool {
+			dump := make(map[string]string)
+			for _, txn := range txs {
+				dump[fmt.Sprintf("%d", txn.GetNonce())] = format(txn)
+			}
+			content[name][account.Hex()] = dump
 		}
-		content["queued"][account.Hex()] = dump
 	}
 	return content, nil
 }
 
+// subPools returns the transactions in the pool by sub-pool and sender, only those of the given sender if any.
+func (api *TxPoolAPIImpl) subPools(ctx context.Context, sender *common.Address) (map[string]map[common.Address][]types.Transaction, error) {
+	reply, err := api.pool.All(ctx, &proto_txpool.AllRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[common.Address][]types.Transaction, 8)
+	baseFee := make(map[common.Address][]types.Transaction, 8)
+	queued := make(map[common.Address][]types.Transaction, 8)
+	for i := range reply.Txs {
+		addr := gointerfaces.ConvertH160toAddress(reply.Txs[i].Sender)
+		if sender != nil && addr != *sender {
+			continue
+		}
+		stream := rlp.NewStream(bytes.NewReader(reply.Txs[i].RlpTx), 0)
+		txn, err := types.DecodeTransaction(stream)
+		if err != nil {
+			return nil, err
+		}
+		switch reply.Txs[i].TxnType {
+		case proto_txpool.AllReply_PENDING:
+			pending[addr] = append(pending[addr], txn)
+		case proto_txpool.AllReply_BASE_FEE:
+			baseFee[addr] = append(baseFee[addr], txn)
+		case proto_txpool.AllReply_QUEUED:
+			queued[addr] = append(queued[addr], txn)
+		}
+	}
+	return map[string]map[common.Address][]types.Transaction{
+		"pending": pending,
+		"baseFee": baseFee,
+		"queued":  queued,
+	}, nil
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (api *TxPoolAPIImpl) Status(ctx context.Context) (map[string]hexutil.Uint, error) {
 	reply, err := api.pool.Status(ctx, &proto_txpool.StatusRequest{})
@@ -130,41 +182,3 @@ func (api *TxPoolAPIImpl) Status(ctx context.Context) (map[string]hexutil.Uint,
 		"queued":  hexutil.Uint(reply.QueuedCount),
 	}, nil
 }
-
-/*
-
-// Inspect retrieves the content of the transaction pool and flattens it into an
-// easily inspectable list.
-func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
-	content := map[string]map[string]map[string]string{
-		"pending": make(map[string]map[string]string),
-		"queued":  make(map[string]map[string]string),
-	}
-	pending, queue := s.b.TxPoolContent()
-
-	// Define a formatter to flatten a transaction into a string
-	var format = func(tx *types.Transaction) string {
-		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
-		}
-		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
-	}
-	// Flatten the pending transactions
-	for account, txs := range pending {
-		dump := make(map[string]string)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
-		}
-		content["pending"][account.Hex()] = dump
-	}
-	// Flatten the queued transactions
-	for account, txs := range queue {
-		dump := make(map[string]string)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
-		}
-		content["queued"][account.Hex()] = dump
-	}
-	return content
-}
-*/
@@ -56,6 +56,18 @@ func TestTxPoolContent(t *testing.T) {
 	require.Equal(1, len(content["pending"][sender]))
 	require.Equal(expectValue, content["pending"][sender]["0"].Value.ToInt().Uint64())
 
+	contentFrom, err := api.ContentFrom(ctx, m.Address)
+	require.NoError(err)
+	require.Equal(1, len(contentFrom["pending"]))
+	require.Equal(txn.Hash(), contentFrom["pending"]["0"].Hash)
+	contentFrom, err = api.ContentFrom(ctx, common.Address{1})
+	require.NoError(err)
+	require.Empty(contentFrom["pending"])
+
+	inspect, err := api.Inspect(ctx)
+	require.NoError(err)
+	require.Equal(fmt.Sprintf("%s: 1234 wei + 21000 gas × 10000000000 wei", common.Address{1}.Hex()), inspect["pending"][sender]["0"])
+
 	status, err := api.Status(ctx)
 	require.NoError(err)
 	require.Len(status, 3)
@@ -0,0 +1,372 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// maxDepth is the maximum nesting of the fields of a query, so that a query can't walk the chain through the parents
+// of the blocks without bound.
+const maxDepth = 10
+
+// Object is a GraphQL object of the schema, resolving its fields. The values of the fields are nil, other objects,
+// lists of objects ([]Object) or scalars marshalled to JSON.
+type Object interface {
+	TypeName() string
+	// Resolve returns the value of the field with the arguments, parsed into nil, bool, int64, float64, string,
+	// []interface{} or map[string]interface{}. The unknown fields are errors.
+	Resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error)
+}
+
+// Request is a GraphQL request, as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the response to a request: the data is nil if the request can't be executed.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error of a request, at the path of the field it happened at if any.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Execute executes the query operation of the request against the root object, and the introspection against the
+// schema if any. The errors of the fields are returned with the data, their values are null.
+func Execute(ctx context.Context, schema *Schema, root Object, req *Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}}
+	}
+	if depth, err := doc.depth(op.selections, map[string]bool{}); err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	} else if depth > maxDepth {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("the query is too deep: %d, the maximum is %d", depth, maxDepth)}}}
+	}
+	vars := make(map[string]interface{}, len(op.vars))
+	for _, vd := range op.vars {
+		v, ok := req.Variables[vd.name]
+		if !ok {
+			v = vd.defValue
+		}
+		if v == nil && vd.nonNull {
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s is required", vd.name)}}}
+		}
+		if vars[vd.name], err = normalize(v); err != nil {
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s: %v", vd.name, err)}}}
+		}
+	}
+	e := &executor{doc: doc, vars: vars, schema: schema}
+	data, err := e.selectionSet(ctx, root, op.selections, nil)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("the operation name is required with several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// depth returns the nesting of the fields of the selections, expanding the fragments. The fragments that spread
+// themselves are errors. The introspection is bounded by the schema, its fields don't count.
+func (doc *document) depth(sels []*selection, spreading map[string]bool) (int, error) {
+	max := 0
+	for _, sel := range sels {
+		var d int
+		var err error
+		switch {
+		case sel.fragment != "":
+			if spreading[sel.fragment] {
+				return 0, fmt.Errorf("fragment %s spreads itself", sel.fragment)
+			}
+			f, ok := doc.fragments[sel.fragment]
+			if !ok {
+				return 0, fmt.Errorf("unknown fragment %s", sel.fragment)
+			}
+			spreading[sel.fragment] = true
+			d, err = doc.depth(f.selections, spreading)
+			delete(spreading, sel.fragment)
+		case sel.inline:
+			d, err = doc.depth(sel.selections, spreading)
+		default:
+			d, err = doc.depth(sel.selections, spreading)
+			d++
+			if sel.name == "__schema" || sel.name == "__type" {
+				d = 1
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max, nil
+}
+
+// normalize converts the JSON numbers of the variables to the types of the literals.
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case []interface{}:
+		for i := range v {
+			var err error
+			if v[i], err = normalize(v[i]); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for k := range v {
+			var err error
+			if v[k], err = normalize(v[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+type executor struct {
+	doc    *document
+	vars   map[string]interface{}
+	schema *Schema // nil if the introspection isn't supported
+	errors []*Error
+}
+
+// result is a JSON object keeping the order of the selections.
+type result struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (r *result) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(r.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// collectFields groups the fields of the selections by response key, expanding the fragments.
+func (e *executor) collectFields(sels []*selection, keys *[]string, fields map[string][]*selection, visited map[string]bool) error {
+	for _, sel := range sels {
+		include, err := e.included(sel)
+		if err != nil {
+			return err
+		}
+		if !include {
+			continue
+		}
+		switch {
+		case sel.fragment != "":
+			if visited[sel.fragment] {
+				continue
+			}
+			visited[sel.fragment] = true
+			f, ok := e.doc.fragments[sel.fragment]
+			if !ok {
+				return fmt.Errorf("unknown fragment %s", sel.fragment)
+			}
+			if err := e.collectFields(f.selections, keys, fields, visited); err != nil {
+				return err
+			}
+		case sel.inline:
+			if err := e.collectFields(sel.selections, keys, fields, visited); err != nil {
+				return err
+			}
+		default:
+			key := sel.responseKey()
+			if _, ok := fields[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+		}
+	}
+	return nil
+}
+
+func (e *executor) included(sel *selection) (bool, error) {
+	for _, d := range sel.directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		args, err := e.arguments(d.args)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := args["if"].(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a boolean if argument", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *executor) arguments(args []argument) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		v, err := e.resolveValue(arg.value)
+		if err != nil {
+			return nil, err
+		}
+		res[arg.name] = v
+	}
+	return res, nil
+}
+
+func (e *executor) resolveValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case variable:
+		value, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", string(v))
+		}
+		return value, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i := range v {
+			var err error
+			if list[i], err = e.resolveValue(v[i]); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k := range v {
+			var err error
+			if obj[k], err = e.resolveValue(v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// selectionSet resolves the selections on the object. The returned error is the one of the query itself, the errors
+// of the fields are collected by the executor.
+func (e *executor) selectionSet(ctx context.Context, obj Object, sels []*selection, path []interface{}) (*result, error) {
+	res := &result{values: map[string]interface{}{}}
+	fields := map[string][]*selection{}
+	if err := e.collectFields(sels, &res.keys, fields, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	for _, key := range res.keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		field := fields[key][0]
+		fieldPath := append(path[:len(path):len(path)], key)
+		if field.name == "__typename" {
+			res.values[key] = obj.TypeName()
+			continue
+		}
+		args, err := e.arguments(field.args)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		switch {
+		// the introspection is served at the root of the queries only
+		case len(path) == 0 && (field.name == "__schema" || field.name == "__type"):
+			if e.schema == nil {
+				err = fmt.Errorf("introspection is not supported")
+			} else {
+				value, err = e.schema.introspect(field.name, args)
+			}
+		default:
+			value, err = obj.Resolve(ctx, field.name, args)
+		}
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Path: fieldPath})
+			res.values[key] = nil
+			continue
+		}
+		var subSels []*selection
+		for _, f := range fields[key] {
+			subSels = append(subSels, f.selections...)
+		}
+		if res.values[key], err = e.complete(ctx, value, subSels, fieldPath); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (e *executor) complete(ctx context.Context, value interface{}, sels []*selection, path []interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case Object:
+		if len(sels) == 0 {
+			e.errors = append(e.errors, &Error{Message: fmt.Sprintf("a selection set is required on %s", v.TypeName()), Path: path})
+			return nil, nil
+		}
+		return e.selectionSet(ctx, v, sels, path)
+	case []Object:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = e.complete(ctx, item, sels, append(path[:len(path):len(path)], i)); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	if len(sels) > 0 {
+		e.errors = append(e.errors, &Error{Message: "selection set on a scalar", Path: path})
+		return nil, nil
+	}
+	return value, nil
+}
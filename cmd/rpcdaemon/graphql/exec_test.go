@@ -0,0 +1,156 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testObject is a node of a linked list: its value is its number, its next node is the following number.
+type testObject struct{ n int64 }
+
+func (o *testObject) TypeName() string { return "Node" }
+
+func (o *testObject) Resolve(_ context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "value":
+		return o.n, nil
+	case "next":
+		if o.n >= 3 {
+			return nil, nil
+		}
+		return &testObject{o.n + 1}, nil
+	case "add":
+		x, ok := args["x"].(int64)
+		if !ok {
+			return nil, fmt.Errorf("x: integer required")
+		}
+		return o.n + x, nil
+	case "list":
+		return []Object{&testObject{o.n}, &testObject{o.n + 1}}, nil
+	case "fail":
+		return nil, fmt.Errorf("failed")
+	}
+	return nil, fmt.Errorf("unknown field %s", field)
+}
+
+func TestExecute(t *testing.T) {
+	for _, tt := range []struct {
+		name, query, vars, expected string
+	}{
+		{"fields", `{ value next { value } }`, ``, `{"data":{"value":1,"next":{"value":2}}}`},
+		{"aliases and arguments", `{ a: add(x: 2) b: add(x: -1) }`, ``, `{"data":{"a":3,"b":0}}`},
+		{"null object", `{ next { next { next { value } } } }`, ``, `{"data":{"next":{"next":{"next":null}}}}`},
+		{"lists", `{ list { value __typename } }`, ``, `{"data":{"list":[{"value":1,"__typename":"Node"},{"value":2,"__typename":"Node"}]}}`},
+		{"variables", `query q($x: Int!, $y: Int = 5) { a: add(x: $x) b: add(x: $y) }`, `{"x": 10}`, `{"data":{"a":11,"b":6}}`},
+		{"fragments", `query { ...f next { ... on Node { value } } } fragment f on Node { value }`, ``, `{"data":{"value":1,"next":{"value":2}}}`},
+		{"merged fields", `{ next { value } next { a: add(x: 1) } }`, ``, `{"data":{"next":{"value":2,"a":3}}}`},
+		{"directives", `query($s: Boolean!) { value @skip(if: $s) next @include(if: $s) { value } }`, `{"s": true}`, `{"data":{"next":{"value":2}}}`},
+		{"field errors", `{ value fail }`, ``, `{"data":{"value":1,"fail":null},"errors":[{"message":"failed","path":["fail"]}]}`},
+		{"nested errors", `{ list { fail } }`, ``, `{"data":{"list":[{"fail":null},{"fail":null}]},"errors":[{"message":"failed","path":["list",0,"fail"]},{"message":"failed","path":["list",1,"fail"]}]}`},
+		{"syntax error", `{ value `, ``, `{"errors":[{"message":"unexpected end of document"}]}`},
+		{"missing variable", `query($x: Int!) { add(x: $x) }`, ``, `{"errors":[{"message":"variable $x is required"}]}`},
+		{"introspection", `{ __schema { types { name } } }`, ``, `{"data":{"__schema":null},"errors":[{"message":"introspection is not supported","path":["__schema"]}]}`},
+		{"depth", `{ next { next { next { next { next { next { next { next { next { next { value } } } } } } } } } } }`, ``, `{"errors":[{"message":"the query is too deep: 11, the maximum is 10"}]}`},
+		{"depth of fragments", `{ ...f } fragment f on Node { next { ...g } } fragment g on Node { next { next { next { next { next { next { next { next { next { value } } } } } } } } } }`, ``, `{"errors":[{"message":"the query is too deep: 11, the maximum is 10"}]}`},
+		{"fragment cycle", `{ ...f } fragment f on Node { next { ...f } }`, ``, `{"errors":[{"message":"fragment f spreads itself"}]}`},
+		{"mutation", `mutation { value }`, ``, `{"errors":[{"message":"mutation operations are not supported"}]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{Query: tt.query}
+			if tt.vars != "" {
+				require.NoError(t, decode([]byte(tt.vars), &req.Variables))
+			}
+			res, err := json.Marshal(Execute(context.Background(), nil, &testObject{1}, req))
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expected, string(res))
+		})
+	}
+}
+
+const testSchema = `
+# Node is a node of a linked list.
+type Node {
+	# Value is the number of the node.
+	value: Int!
+	next: Node
+	add(x: Int!, y: Int = 1): Int!
+	list: [Node!]!
+	fail: Int
+}
+
+input Filter {
+	from: Int = 0,
+	kind: Kind
+}
+
+enum Kind {
+	A
+	# the other kind
+	B
+}
+
+schema {
+	query: Node
+}
+`
+
+func TestIntrospection(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	require.NoError(t, err)
+	deep := `{ __type(name: "Node") { fields { type { ofType { ofType { ofType { ofType { ofType { ofType { ofType { ofType { ofType { name } } } } } } } } } } } } }`
+	for _, tt := range []struct {
+		name, query, expected string
+	}{
+		{"schema", `{ __schema { queryType { name } mutationType { name } types { name } directives { name locations args { name type { kind ofType { name } } } } } }`,
+			`{"data":{"__schema":{"queryType":{"name":"Node"},"mutationType":null,"types":[{"name":"Boolean"},{"name":"Filter"},{"name":"Float"},{"name":"ID"},{"name":"Int"},{"name":"Kind"},{"name":"Node"},{"name":"String"},{"name":"__Directive"},{"name":"__DirectiveLocation"},{"name":"__EnumValue"},{"name":"__Field"},{"name":"__InputValue"},{"name":"__Schema"},{"name":"__Type"},{"name":"__TypeKind"}],` +
+				`"directives":[{"name":"include","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]},{"name":"skip","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]}]}}}`},
+		{"object", `{ __type(name: "Node") { kind name description interfaces { name } fields { name description args { name defaultValue } type { kind name ofType { kind name ofType { kind name } } } } } }`,
+			`{"data":{"__type":{"kind":"OBJECT","name":"Node","description":"Node is a node of a linked list.","interfaces":[],"fields":[` +
+				`{"name":"value","description":"Value is the number of the node.","args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"Int","ofType":null}}},` +
+				`{"name":"next","description":null,"args":[],"type":{"kind":"OBJECT","name":"Node","ofType":null}},` +
+				`{"name":"add","description":null,"args":[{"name":"x","defaultValue":null},{"name":"y","defaultValue":"1"}],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"Int","ofType":null}}},` +
+				`{"name":"list","description":null,"args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"NON_NULL","name":null}}}},` +
+				`{"name":"fail","description":null,"args":[],"type":{"kind":"SCALAR","name":"Int","ofType":null}}]}}}`},
+		{"input and enum", `{ f: __type(name: "Filter") { kind fields { name } inputFields { name defaultValue type { name } } } k: __type(name: "Kind") { kind enumValues { name description } } u: __type(name: "Unknown") { name } }`,
+			`{"data":{"f":{"kind":"INPUT_OBJECT","fields":null,"inputFields":[{"name":"from","defaultValue":"0","type":{"name":"Int"}},{"name":"kind","defaultValue":null,"type":{"name":"Kind"}}]},` +
+				`"k":{"kind":"ENUM","enumValues":[{"name":"A","description":null},{"name":"B","description":"the other kind"}]},"u":null}}`},
+		{"typename", `{ __typename __schema { __typename queryType { __typename } } }`, `{"data":{"__typename":"Node","__schema":{"__typename":"__Schema","queryType":{"__typename":"__Type"}}}}`},
+		{"at the root only", `{ next { __schema { queryType { name } } } }`, `{"data":{"next":{"__schema":null}},"errors":[{"message":"unknown field __schema","path":["next","__schema"]}]}`},
+		{"depth", deep, ``},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Execute(context.Background(), schema, &testObject{1}, &Request{Query: tt.query})
+			if tt.expected == "" {
+				require.Empty(t, res.Errors)
+				return
+			}
+			data, err := json.Marshal(res)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expected, string(data))
+		})
+	}
+
+	for _, src := range []string{
+		`type Query { a: Unknown }`,
+		`type Query { a(x: Query): Int }`,
+		`input I { a: Int } type Query { a: I }`,
+		`type Query { a: Int } type Query { b: Int }`,
+		`type Other { a: Int }`,
+	} {
+		_, err := ParseSchema(src)
+		require.Error(t, err, src)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := parse(`{ f(a: "x\"é", b: [1, 2.5, true, null, ENUM], c: {d: -3}) }`)
+	require.NoError(t, err)
+	args := doc.operations[0].selections[0].args
+	require.Equal(t, "x\"é", args[0].value)
+	require.Equal(t, []interface{}{int64(1), 2.5, true, nil, "ENUM"}, args[1].value)
+	require.Equal(t, map[string]interface{}{"d": int64(-3)}, args[2].value)
+}
@@ -0,0 +1,85 @@
+// Package graphql executes the GraphQL queries of rpcdaemon (EIP-1767) against a Schema and the Objects resolving it.
+// It's a stopgap for github.com/graph-gophers/graphql-go, which geth serves its schema with: that module isn't a
+// dependency of erigon yet. Once it is, the schema of geth and its resolvers should replace this package.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+const maxRequestContentLength = 1024 * 1024 * 5
+
+// RootFunc returns the root object the queries of a request are resolved from, and the function releasing it once
+// the request is served.
+type RootFunc func(ctx context.Context) (Object, func(), error)
+
+// Handler serves the GraphQL requests over HTTP: as JSON in the body of the POST requests, or in the query string
+// of the GET ones.
+type Handler struct {
+	schema *Schema
+	root   RootFunc
+}
+
+func NewHandler(schema *Schema, root RootFunc) *Handler {
+	return &Handler{schema: schema, root: root}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := decode([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestContentLength+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxRequestContentLength {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := decode(body, &req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var res *Response
+	root, release, err := h.root(r.Context())
+	if err != nil {
+		res = &Response{Errors: []*Error{{Message: err.Error()}}}
+	} else {
+		res = Execute(r.Context(), h.schema, root, &req)
+		release()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if res.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Debug("[graphql] failed to write the response", "err", err)
+	}
+}
+
+func decode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,228 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// The objects of the introspection resolve the types of the introspection from the schema. The empty descriptions
+// are null, and nothing is deprecated.
+
+// introspect resolves the __schema and __type fields of the query type.
+func (s *Schema) introspect(field string, args map[string]interface{}) (interface{}, error) {
+	if field == "__schema" {
+		return &schemaObject{s}, nil
+	}
+	name, ok := args["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("name: string required")
+	}
+	return s.namedType(name), nil
+}
+
+// namedType returns the __Type of the type of the given name, nil if there is none.
+func (s *Schema) namedType(name string) interface{} {
+	if _, ok := s.types[name]; !ok {
+		return nil
+	}
+	return &typeObject{s, &typeRef{name: name}}
+}
+
+func description(desc string) interface{} {
+	if desc == "" {
+		return nil
+	}
+	return desc
+}
+
+func inputValueObjects(s *Schema, values []*schemaValue) []Object {
+	list := make([]Object, len(values))
+	for i, v := range values {
+		list[i] = &inputValueObject{s, v}
+	}
+	return list
+}
+
+type schemaObject struct{ s *Schema }
+
+func (o *schemaObject) TypeName() string { return "__Schema" }
+
+func (o *schemaObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "description", "subscriptionType":
+		return nil, nil
+	case "types":
+		list := make([]Object, len(o.s.names))
+		for i, name := range o.s.names {
+			list[i] = &typeObject{o.s, &typeRef{name: name}}
+		}
+		return list, nil
+	case "queryType":
+		return o.s.namedType(o.s.query), nil
+	case "mutationType":
+		return o.s.namedType(o.s.mutation), nil
+	case "directives":
+		list := make([]Object, len(o.s.directives))
+		for i, d := range o.s.directives {
+			list[i] = &directiveObject{o.s, d}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Schema", field)
+}
+
+// typeObject is a named type, or a list or non-null type wrapping another.
+type typeObject struct {
+	s   *Schema
+	ref *typeRef
+}
+
+func (o *typeObject) TypeName() string { return "__Type" }
+
+func (o *typeObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	if o.ref.kind != "" {
+		switch field {
+		case "kind":
+			return o.ref.kind, nil
+		case "ofType":
+			return &typeObject{o.s, o.ref.ofType}, nil
+		case "name", "description", "specifiedByURL", "fields", "interfaces", "possibleTypes", "enumValues", "inputFields":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unknown field %s on __Type", field)
+	}
+	t := o.s.types[o.ref.name]
+	switch field {
+	case "kind":
+		return t.kind, nil
+	case "name":
+		return t.name, nil
+	case "description":
+		return description(t.description), nil
+	case "specifiedByURL", "possibleTypes", "ofType":
+		return nil, nil
+	case "fields":
+		if t.kind != kindObject {
+			return nil, nil
+		}
+		list := make([]Object, len(t.fields))
+		for i, f := range t.fields {
+			list[i] = &fieldObject{o.s, f}
+		}
+		return list, nil
+	case "interfaces":
+		if t.kind != kindObject {
+			return nil, nil
+		}
+		return []Object{}, nil
+	case "enumValues":
+		if t.kind != kindEnum {
+			return nil, nil
+		}
+		list := make([]Object, len(t.enumValues))
+		for i, v := range t.enumValues {
+			list[i] = &enumValueObject{v}
+		}
+		return list, nil
+	case "inputFields":
+		if t.kind != kindInputObject {
+			return nil, nil
+		}
+		return inputValueObjects(o.s, t.inputFields), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Type", field)
+}
+
+type fieldObject struct {
+	s *Schema
+	f *schemaField
+}
+
+func (o *fieldObject) TypeName() string { return "__Field" }
+
+func (o *fieldObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return o.f.name, nil
+	case "description":
+		return description(o.f.description), nil
+	case "args":
+		return inputValueObjects(o.s, o.f.args), nil
+	case "type":
+		return &typeObject{o.s, o.f.typ}, nil
+	case "isDeprecated":
+		return false, nil
+	case "deprecationReason":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Field", field)
+}
+
+type inputValueObject struct {
+	s *Schema
+	v *schemaValue
+}
+
+func (o *inputValueObject) TypeName() string { return "__InputValue" }
+
+func (o *inputValueObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return o.v.name, nil
+	case "description":
+		return description(o.v.description), nil
+	case "type":
+		return &typeObject{o.s, o.v.typ}, nil
+	case "defaultValue":
+		if o.v.defValue == nil {
+			return nil, nil
+		}
+		return *o.v.defValue, nil
+	case "isDeprecated":
+		return false, nil
+	case "deprecationReason":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __InputValue", field)
+}
+
+type enumValueObject struct{ v *schemaEnumValue }
+
+func (o *enumValueObject) TypeName() string { return "__EnumValue" }
+
+func (o *enumValueObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return o.v.name, nil
+	case "description":
+		return description(o.v.description), nil
+	case "isDeprecated":
+		return false, nil
+	case "deprecationReason":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __EnumValue", field)
+}
+
+type directiveObject struct {
+	s *Schema
+	d *schemaDirective
+}
+
+func (o *directiveObject) TypeName() string { return "__Directive" }
+
+func (o *directiveObject) Resolve(_ context.Context, field string, _ map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return o.d.name, nil
+	case "description":
+		return description(o.d.description), nil
+	case "isRepeatable":
+		return false, nil
+	case "locations":
+		return o.d.locations, nil
+	case "args":
+		return inputValueObjects(o.s, o.d.args), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Directive", field)
+}
@@ -0,0 +1,622 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The subset of the GraphQL query language needed by the clients of the Ethereum schema: operations with variables,
+// fields with aliases and arguments, fragments and the @skip/@include directives. Block strings are not supported,
+// the type system definitions are parsed by ParseSchema.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+	desc  string // the comments right before the token, the descriptions of the schema definitions
+}
+
+type lexer struct {
+	src string
+	pos int
+	tok token
+}
+
+func (l *lexer) next() error {
+	// commas are insignificant, like white space
+	var comments []string
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			start := l.pos + 1
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			comments = append(comments, strings.TrimSpace(l.src[start:l.pos]))
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		l.pos++
+	}
+	err := l.scan()
+	l.tok.desc = strings.Join(comments, "\n")
+	return err
+}
+
+func (l *lexer) scan() error {
+	start := l.pos
+	if l.pos == len(l.src) {
+		l.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{|}", c) >= 0:
+		l.pos++
+		l.tok = token{kind: tokPunct, value: string(c), pos: start}
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return fmt.Errorf("unexpected character '.' at %d", start)
+		}
+		l.pos += 3
+		l.tok = token{kind: tokPunct, value: "...", pos: start}
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		l.tok = token{kind: tokName, value: l.src[start:l.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	default:
+		return fmt.Errorf("unexpected character %q at %d", c, start)
+	}
+	return nil
+}
+
+func (l *lexer) number() error {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	l.tok = token{kind: kind, value: l.src[start:l.pos], pos: start}
+	return nil
+}
+
+func (l *lexer) string() error {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' || l.src[l.pos] == '\r' {
+			return fmt.Errorf("unterminated string at %d", start)
+		}
+		c := l.src[l.pos]
+		if c == '"' {
+			l.pos++
+			break
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			sb.WriteRune(r)
+			l.pos += size
+			continue
+		}
+		if l.pos+1 >= len(l.src) {
+			return fmt.Errorf("unterminated string at %d", start)
+		}
+		switch e := l.src[l.pos+1]; e {
+		case '"', '\\', '/':
+			sb.WriteByte(e)
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			if l.pos+6 > len(l.src) {
+				return fmt.Errorf("invalid unicode escape at %d", l.pos)
+			}
+			r, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+			if err != nil {
+				return fmt.Errorf("invalid unicode escape at %d", l.pos)
+			}
+			sb.WriteRune(rune(r))
+			l.pos += 4
+		default:
+			return fmt.Errorf("invalid escape %q at %d", e, l.pos)
+		}
+		l.pos += 2
+	}
+	l.tok = token{kind: tokString, value: sb.String(), pos: start}
+	return nil
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []varDef
+	selections []*selection
+}
+
+type varDef struct {
+	name     string
+	nonNull  bool
+	defValue interface{} // nil if there is no default value
+}
+
+type fragment struct {
+	name       string
+	selections []*selection
+}
+
+// selection is a field, or a fragment spread if fragment is set, or an inline fragment if inline is set.
+type selection struct {
+	alias, name string
+	args        []argument
+	directives  []directive
+	selections  []*selection
+	fragment    string
+	inline      bool
+}
+
+func (s *selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+type directive struct {
+	name string
+	args []argument
+}
+
+// The values of the arguments are parsed into nil, bool, int64, float64, string (for the strings and the enum
+// values), []interface{}, map[string]interface{} or variable.
+type variable string
+
+type parser struct {
+	lexer
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lexer{src: src}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.isPunct("{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels})
+		case p.tok.kind == tokName && p.tok.value == "fragment":
+			f, err := p.fragmentDefinition()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, fmt.Errorf("fragment %s is defined twice", f.name)
+			}
+			doc.fragments[f.name] = f
+		case p.tok.kind == tokName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op, err := p.operationDefinition()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) isPunct(v string) bool { return p.tok.kind == tokPunct && p.tok.value == v }
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at %d", p.tok.value, p.tok.pos)
+}
+
+func (p *parser) expectPunct(v string) error {
+	if !p.isPunct(v) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+func (p *parser) operationDefinition() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.name = p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.isPunct(")") {
+			vd, err := p.varDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, vd)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) varDefinition() (varDef, error) {
+	var vd varDef
+	if err := p.expectPunct("$"); err != nil {
+		return vd, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return vd, err
+	}
+	vd.name = name
+	if err := p.expectPunct(":"); err != nil {
+		return vd, err
+	}
+	// the types of the variables aren't checked, the scalars of the resolvers are
+	ref, err := p.typeRef()
+	if err != nil {
+		return vd, err
+	}
+	vd.nonNull = ref.kind == kindNonNull
+	if p.isPunct("=") {
+		if err := p.next(); err != nil {
+			return vd, err
+		}
+		if vd.defValue, err = p.value(true); err != nil {
+			return vd, err
+		}
+	}
+	return vd, nil
+}
+
+// typeRef is a reference to a named type, wrapped in lists and non-null types.
+type typeRef struct {
+	kind   string // kindList, kindNonNull, or empty for a named type
+	name   string
+	ofType *typeRef
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	var ref *typeRef
+	if p.isPunct("[") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		ofType, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct("]"); err != nil {
+			return nil, err
+		}
+		ref = &typeRef{kind: kindList, ofType: ofType}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ref = &typeRef{name: name}
+	}
+	if p.isPunct("!") {
+		return &typeRef{kind: kindNonNull, ofType: ref}, p.next()
+	}
+	return ref, nil
+}
+
+func (p *parser) fragmentDefinition() (*fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("invalid fragment name: on")
+	}
+	if err := p.typeCondition(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, selections: sels}, nil
+}
+
+// typeCondition skips the type condition of a fragment: the schema has no interfaces nor unions, the fragments
+// apply to the type of their selection set.
+func (p *parser) typeCondition() error {
+	if p.tok.kind != tokName || p.tok.value != "on" {
+		return p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	_, err := p.name()
+	return err
+}
+
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var sels []*selection
+	for !p.isPunct("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("empty selection set at %d", p.tok.pos)
+	}
+	return sels, p.next()
+}
+
+func (p *parser) selection() (*selection, error) {
+	sel := &selection{}
+	var err error
+	if p.isPunct("...") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			sel.fragment = p.tok.value
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		sel.inline = true
+		if p.tok.kind == tokName {
+			if err = p.typeCondition(); err != nil {
+				return nil, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.selections, err = p.selectionSet()
+		return sel, err
+	}
+	if sel.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.isPunct(":") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		sel.alias = sel.name
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if sel.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.isPunct("{") {
+		if sel.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) arguments() ([]argument, error) {
+	if !p.isPunct("(") {
+		return nil, nil
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var args []argument
+	for !p.isPunct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argument{name: name, value: v})
+	}
+	return args, p.next()
+}
+
+func (p *parser) directives() ([]directive, error) {
+	var ds []directive
+	for p.isPunct("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, directive{name: name, args: args})
+	}
+	return ds, nil
+}
+
+func (p *parser) value(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokPunct && tok.value == "$" && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+	case tok.kind == tokInt:
+		v, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at %d", tok.value, tok.pos)
+		}
+		return v, p.next()
+	case tok.kind == tokFloat:
+		v, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at %d", tok.value, tok.pos)
+		}
+		return v, p.next()
+	case tok.kind == tokString:
+		return tok.value, p.next()
+	case tok.kind == tokName:
+		var v interface{}
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = tok.value
+		}
+		return v, p.next()
+	case tok.kind == tokPunct && tok.value == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.isPunct("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case tok.kind == tokPunct && tok.value == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		for !p.isPunct("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,453 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The kinds of the types, as named by the introspection.
+const (
+	kindScalar      = "SCALAR"
+	kindObject      = "OBJECT"
+	kindEnum        = "ENUM"
+	kindInputObject = "INPUT_OBJECT"
+	kindList        = "LIST"
+	kindNonNull     = "NON_NULL"
+)
+
+// Schema is the type system of the objects the queries are resolved from, served by the introspection of the
+// queries (__schema and __type). It's parsed from the GraphQL schema language: the scalars, the object, input and
+// enum types, the directives and the schema definition are supported, the comments before a definition are its
+// description.
+type Schema struct {
+	types      map[string]*schemaType
+	names      []string // sorted
+	directives []*schemaDirective
+	query      string
+	mutation   string
+}
+
+type schemaType struct {
+	kind        string
+	name        string
+	description string
+	fields      []*schemaField // of the objects
+	inputFields []*schemaValue // of the input objects
+	enumValues  []*schemaEnumValue
+}
+
+type schemaField struct {
+	name, description string
+	args              []*schemaValue
+	typ               *typeRef
+}
+
+// schemaValue is an argument, or a field of an input object.
+type schemaValue struct {
+	name, description string
+	typ               *typeRef
+	defValue          *string // as written in the schema, nil if there is no default value
+}
+
+type schemaEnumValue struct {
+	name, description string
+}
+
+type schemaDirective struct {
+	name, description string
+	args              []*schemaValue
+	locations         []string
+}
+
+// ParseSchema parses the definitions of the types of a schema. The built-in scalars, the introspection types and the
+// @skip and @include directives are defined too.
+func ParseSchema(src string) (*Schema, error) {
+	s := &Schema{types: map[string]*schemaType{}}
+	if err := s.parse(builtinSchema); err != nil {
+		return nil, fmt.Errorf("built-in schema: %w", err)
+	}
+	if err := s.parse(src); err != nil {
+		return nil, err
+	}
+	if s.query == "" {
+		s.query = "Query"
+	}
+	if s.mutation == "" && s.types["Mutation"] != nil {
+		s.mutation = "Mutation"
+	}
+	for _, name := range []string{s.query, s.mutation} {
+		if name != "" && (s.types[name] == nil || s.types[name].kind != kindObject) {
+			return nil, fmt.Errorf("the operation type %s is not an object type", name)
+		}
+	}
+	for name := range s.types {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustParseSchema is like ParseSchema, but panics if the schema can't be parsed.
+func MustParseSchema(src string) *Schema {
+	s, err := ParseSchema(src)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// check checks that the types referenced are defined, and can be used as outputs or inputs.
+func (s *Schema) check() error {
+	checkRef := func(ref *typeRef, input bool, where string) error {
+		for ref.kind != "" {
+			ref = ref.ofType
+		}
+		t, ok := s.types[ref.name]
+		switch {
+		case !ok:
+			return fmt.Errorf("unknown type %s of %s", ref.name, where)
+		case input && t.kind == kindObject:
+			return fmt.Errorf("the object type %s of %s is not an input type", ref.name, where)
+		case !input && t.kind == kindInputObject:
+			return fmt.Errorf("the input type %s of %s is not an output type", ref.name, where)
+		}
+		return nil
+	}
+	for _, name := range s.names {
+		t := s.types[name]
+		for _, f := range t.fields {
+			if err := checkRef(f.typ, false, name+"."+f.name); err != nil {
+				return err
+			}
+			for _, arg := range f.args {
+				if err := checkRef(arg.typ, true, name+"."+f.name+"("+arg.name+")"); err != nil {
+					return err
+				}
+			}
+		}
+		for _, f := range t.inputFields {
+			if err := checkRef(f.typ, true, name+"."+f.name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range s.directives {
+		for _, arg := range d.args {
+			if err := checkRef(arg.typ, true, "@"+d.name+"("+arg.name+")"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) parse(src string) error {
+	p := &parser{lexer{src: src}}
+	if err := p.next(); err != nil {
+		return err
+	}
+	for p.tok.kind != tokEOF {
+		if p.tok.kind != tokName {
+			return p.unexpected()
+		}
+		desc := p.tok.desc
+		switch p.tok.value {
+		case "schema":
+			if err := p.schemaDefinition(s); err != nil {
+				return err
+			}
+		case "directive":
+			d, err := p.directiveDefinition()
+			if err != nil {
+				return err
+			}
+			d.description = desc
+			s.directives = append(s.directives, d)
+		case "scalar", "type", "input", "enum":
+			t, err := p.typeDefinition()
+			if err != nil {
+				return err
+			}
+			if _, ok := s.types[t.name]; ok {
+				return fmt.Errorf("type %s is defined twice", t.name)
+			}
+			t.description = desc
+			s.types[t.name] = t
+		default:
+			return p.unexpected()
+		}
+	}
+	return nil
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		op, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err = p.expectPunct(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case "query":
+			s.query = name
+		case "mutation":
+			s.mutation = name
+		default:
+			return fmt.Errorf("%s operations are not supported", op)
+		}
+	}
+	return p.next()
+}
+
+func (p *parser) directiveDefinition() (*schemaDirective, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("@"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	d := &schemaDirective{name: name}
+	if p.isPunct("(") {
+		if d.args, err = p.inputValues("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind != tokName || p.tok.value != "on" {
+		return nil, p.unexpected()
+	}
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	for {
+		if p.isPunct("|") {
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+		}
+		location, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d.locations = append(d.locations, location)
+		if !p.isPunct("|") {
+			return d, nil
+		}
+	}
+}
+
+func (p *parser) typeDefinition() (*schemaType, error) {
+	t := &schemaType{kind: map[string]string{"scalar": kindScalar, "type": kindObject, "input": kindInputObject, "enum": kindEnum}[p.tok.value]}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var err error
+	if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case kindObject:
+		if err = p.expectPunct("{"); err != nil {
+			return nil, err
+		}
+		for !p.isPunct("}") {
+			f, err := p.fieldDefinition()
+			if err != nil {
+				return nil, err
+			}
+			t.fields = append(t.fields, f)
+		}
+		return t, p.next()
+	case kindInputObject:
+		t.inputFields, err = p.inputValues("{", "}")
+		return t, err
+	case kindEnum:
+		if err = p.expectPunct("{"); err != nil {
+			return nil, err
+		}
+		for !p.isPunct("}") {
+			v := &schemaEnumValue{description: p.tok.desc}
+			if v.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			t.enumValues = append(t.enumValues, v)
+		}
+		return t, p.next()
+	}
+	return t, nil
+}
+
+func (p *parser) fieldDefinition() (*schemaField, error) {
+	f := &schemaField{description: p.tok.desc}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.isPunct("(") {
+		if f.args, err = p.inputValues("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	if f.typ, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// inputValues parses the definitions of the arguments, or of the fields of an input object, between the delimiters.
+func (p *parser) inputValues(opening, closing string) ([]*schemaValue, error) {
+	if err := p.expectPunct(opening); err != nil {
+		return nil, err
+	}
+	var values []*schemaValue
+	for !p.isPunct(closing) {
+		v := &schemaValue{description: p.tok.desc}
+		var err error
+		if v.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if v.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if p.isPunct("=") {
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			start := p.tok.pos
+			if _, err = p.value(true); err != nil {
+				return nil, err
+			}
+			defValue := strings.TrimRight(p.src[start:p.tok.pos], " \t\r\n,")
+			v.defValue = &defValue
+		}
+		values = append(values, v)
+	}
+	return values, p.next()
+}
+
+// builtinSchema defines the built-in scalars, the types of the introspection and the directives of the executor.
+const builtinSchema = `
+scalar Int
+scalar Float
+scalar String
+scalar Boolean
+scalar ID
+
+# Directs the executor to include this field or fragment only when the argument is true.
+directive @include(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+# Directs the executor to skip this field or fragment when the argument is true.
+directive @skip(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+
+type __Schema {
+	description: String
+	types: [__Type!]!
+	queryType: __Type!
+	mutationType: __Type
+	subscriptionType: __Type
+	directives: [__Directive!]!
+}
+
+type __Type {
+	kind: __TypeKind!
+	name: String
+	description: String
+	specifiedByURL: String
+	fields(includeDeprecated: Boolean = false): [__Field!]
+	interfaces: [__Type!]
+	possibleTypes: [__Type!]
+	enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+	inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+	ofType: __Type
+}
+
+enum __TypeKind {
+	SCALAR
+	OBJECT
+	INTERFACE
+	UNION
+	ENUM
+	INPUT_OBJECT
+	LIST
+	NON_NULL
+}
+
+type __Field {
+	name: String!
+	description: String
+	args(includeDeprecated: Boolean = false): [__InputValue!]!
+	type: __Type!
+	isDeprecated: Boolean!
+	deprecationReason: String
+}
+
+type __InputValue {
+	name: String!
+	description: String
+	type: __Type!
+	defaultValue: String
+	isDeprecated: Boolean!
+	deprecationReason: String
+}
+
+type __EnumValue {
+	name: String!
+	description: String
+	isDeprecated: Boolean!
+	deprecationReason: String
+}
+
+type __Directive {
+	name: String!
+	description: String
+	isRepeatable: Boolean!
+	locations: [__DirectiveLocation!]!
+	args(includeDeprecated: Boolean = false): [__InputValue!]!
+}
+
+enum __DirectiveLocation {
+	QUERY
+	MUTATION
+	SUBSCRIPTION
+	FIELD
+	FRAGMENT_DEFINITION
+	FRAGMENT_SPREAD
+	INLINE_FRAGMENT
+	VARIABLE_DEFINITION
+	SCHEMA
+	SCALAR
+	OBJECT
+	FIELD_DEFINITION
+	ARGUMENT_DEFINITION
+	INTERFACE
+	UNION
+	ENUM
+	ENUM_VALUE
+	INPUT_OBJECT
+	INPUT_FIELD_DEFINITION
+}
+`
@@ -1,6 +1,7 @@
 package health
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -103,8 +104,17 @@ func processFromHeaders(headers []string, ethAPI EthAPI, netAPI NetAPI, w http.R
 }
 
 func processFromBody(w http.ResponseWriter, r *http.Request, netAPI NetAPI, ethAPI EthAPI) {
-	body, errParse := parseHealthCheckBody(r.Body)
-	defer r.Body.Close()
+	bodyBytes, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil && len(bytes.TrimSpace(bodyBytes)) == 0 {
+		// no check requested, the liveness
+		processLiveness(w, r, netAPI, ethAPI)
+		return
+	}
+	body, errParse := parseHealthCheckBody(bytes.NewReader(bodyBytes))
+	if err != nil {
+		errParse = err
+	}
 
 	var errMinPeerCount = errCheckDisabled
 	var errCheckBlock = errCheckDisabled
@@ -123,8 +133,7 @@ func processFromBody(w http.ResponseWriter, r *http.Request, netAPI NetAPI, ethA
 		// TODO add time from the last sync cycle
 	}
 
-	err := reportHealthFromBody(errParse, errMinPeerCount, errCheckBlock, w)
-	if err != nil {
+	if err := reportHealthFromBody(errParse, errMinPeerCount, errCheckBlock, w); err != nil {
 		log.Root().Warn("unable to process healthcheck request", "err", err)
 	}
 }
@@ -194,10 +203,10 @@ func reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errChe
 	return writeResponse(w, errs, statusCode)
 }
 
-func writeResponse(w http.ResponseWriter, errs map[string]string, statusCode int) error {
+func writeResponse(w http.ResponseWriter, body interface{}, statusCode int) error {
 	w.WriteHeader(statusCode)
 
-	bodyJson, err := json.Marshal(errs)
+	bodyJson, err := json.Marshal(body)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	readyPath       = "/ready"
+	database        = "database"
+	maxBlocksBehind = "max_blocks_behind"
+	engineTimeout   = "engine_timeout"
+)
+
+var (
+	errTooFarBehind      = errors.New("too far behind")
+	errNoBlockExecuted   = errors.New("no block executed yet")
+	errEngineNotCalled   = errors.New("the Engine API hasn't been called")
+	errUnexpectedSyncing = errors.New("unexpected eth_syncing result")
+)
+
+// Criteria of the readiness of the node, set by the --health.* flags. The zero values disable them.
+type Criteria struct {
+	MaxBlocksBehind uint64        // of the executed block behind the highest header known
+	MinPeers        uint          // connected peers
+	EngineTimeout   time.Duration // since the last call of the Engine API by the consensus client
+}
+
+var engineLastCalled int64 // unix nanoseconds, atomic
+
+// EngineCalled records a call of the Engine API served by the process, by the consensus client.
+func EngineCalled() {
+	atomic.StoreInt64(&engineLastCalled, time.Now().UnixNano())
+}
+
+// Status - the liveness and the readiness of the node, the answer of /health, /ready and erigon_health. The checks are
+// HEALTHY, DISABLED, or the error.
+type Status struct {
+	Live             bool              `json:"live"`
+	Ready            bool              `json:"ready"`
+	Checks           map[string]string `json:"checks"`
+	BlocksBehind     *hexutil.Uint64   `json:"blocksBehind,omitempty"`
+	Peers            *hexutil.Uint     `json:"peers,omitempty"`
+	EngineLastCalled *time.Time        `json:"engineLastCalled,omitempty"`
+}
+
+// Checker checks the node with its APIs: it's live if it reads its database, and ready if it's live and meets the
+// criteria.
+type Checker struct {
+	criteria Criteria
+	netAPI   NetAPI
+	ethAPI   EthAPI
+}
+
+func NewChecker(criteria Criteria, netAPI NetAPI, ethAPI EthAPI) *Checker {
+	return &Checker{criteria: criteria, netAPI: netAPI, ethAPI: ethAPI}
+}
+
+func (c *Checker) Check(ctx context.Context) *Status {
+	status := &Status{Checks: map[string]string{}}
+
+	var (
+		errDatabase = errCheckDisabled
+		errBehind   = errCheckDisabled
+		errPeers    = errCheckDisabled
+		errEngine   = errCheckDisabled
+	)
+	if c.ethAPI != nil {
+		var behind uint64
+		behind, errDatabase = blocksBehind(ctx, c.ethAPI)
+		if errDatabase == nil {
+			status.BlocksBehind = (*hexutil.Uint64)(&behind)
+			if c.criteria.MaxBlocksBehind > 0 {
+				errBehind = nil
+				if behind > c.criteria.MaxBlocksBehind {
+					errBehind = fmt.Errorf("%w: %d blocks (maximum %d)", errTooFarBehind, behind, c.criteria.MaxBlocksBehind)
+				}
+			}
+		} else if errors.Is(errDatabase, errNoBlockExecuted) {
+			errDatabase = nil
+			if c.criteria.MaxBlocksBehind > 0 {
+				errBehind = errNoBlockExecuted
+			}
+		}
+	}
+	if c.criteria.MinPeers > 0 {
+		errPeers = checkMinPeers(c.criteria.MinPeers, c.netAPI)
+	}
+	if c.netAPI != nil {
+		if peers, err := c.netAPI.PeerCount(ctx); err == nil {
+			status.Peers = &peers
+		}
+	}
+	if lastCalled := atomic.LoadInt64(&engineLastCalled); lastCalled != 0 {
+		t := time.Unix(0, lastCalled)
+		status.EngineLastCalled = &t
+	}
+	if c.criteria.EngineTimeout > 0 {
+		errEngine = nil
+		if status.EngineLastCalled == nil {
+			errEngine = errEngineNotCalled
+		} else if silence := time.Since(*status.EngineLastCalled); silence > c.criteria.EngineTimeout {
+			errEngine = fmt.Errorf("%w for %v (maximum %v)", errEngineNotCalled, silence.Truncate(time.Second), c.criteria.EngineTimeout)
+		}
+	}
+
+	status.Checks[database] = errorStringOrOK(errDatabase)
+	status.Checks[maxBlocksBehind] = errorStringOrOK(errBehind)
+	status.Checks[minPeerCount] = errorStringOrOK(errPeers)
+	status.Checks[engineTimeout] = errorStringOrOK(errEngine)
+	status.Live = !shouldChangeStatusCode(errDatabase)
+	status.Ready = status.Live && !shouldChangeStatusCode(errBehind) && !shouldChangeStatusCode(errPeers) && !shouldChangeStatusCode(errEngine)
+	return status
+}
+
+// blocksBehind returns the number of the blocks the executed block is behind the highest header known
+func blocksBehind(ctx context.Context, ethAPI EthAPI) (uint64, error) {
+	res, err := ethAPI.Syncing(ctx)
+	if err != nil {
+		return 0, err
+	}
+	switch res := res.(type) {
+	case bool:
+		if !res {
+			return 0, nil
+		}
+	case map[string]interface{}:
+		current, ok1 := res["currentBlock"].(hexutil.Uint64)
+		highest, ok2 := res["highestBlock"].(hexutil.Uint64)
+		if ok1 && ok2 {
+			if current == 0 {
+				return 0, errNoBlockExecuted
+			}
+			if highest < current {
+				return 0, nil
+			}
+			return uint64(highest - current), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %v", errUnexpectedSyncing, res)
+}
+
+// ProcessReadinessIfNeeded answers the requests of /ready: 200 if the node is ready by the criteria, 503 if it isn't,
+// with its status.
+func ProcessReadinessIfNeeded(w http.ResponseWriter, r *http.Request, rpcAPI []rpc.API, criteria Criteria) bool {
+	if !strings.EqualFold(r.URL.Path, readyPath) {
+		return false
+	}
+	netAPI, ethAPI := parseAPI(rpcAPI)
+	status := NewChecker(criteria, netAPI, ethAPI).Check(r.Context())
+	statusCode := http.StatusOK
+	if !status.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	if err := writeResponse(w, status, statusCode); err != nil {
+		log.Root().Warn("unable to process readiness request", "err", err)
+	}
+	return true
+}
+
+// processLiveness answers the requests of /health without a check: 200 if the node is live, 500 if it isn't
+func processLiveness(w http.ResponseWriter, r *http.Request, netAPI NetAPI, ethAPI EthAPI) {
+	status := NewChecker(Criteria{}, netAPI, ethAPI).Check(r.Context())
+	statusCode := http.StatusOK
+	if !status.Live {
+		statusCode = http.StatusInternalServerError
+	}
+	if err := writeResponse(w, status, statusCode); err != nil {
+		log.Root().Warn("unable to process healthcheck request", "err", err)
+	}
+}
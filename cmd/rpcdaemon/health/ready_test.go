@@ -0,0 +1,275 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+func syncingResult(current, highest uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"currentBlock": hexutil.Uint64(current),
+		"highestBlock": hexutil.Uint64(highest),
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	cases := []struct {
+		criteria            Criteria
+		netApiResponse      hexutil.Uint
+		netApiError         error
+		ethApiSyncingResult interface{}
+		ethApiSyncingError  error
+		engineLastCalled    time.Duration // ago, 0 if never
+		expectedLive        bool
+		expectedReady       bool
+		expectedChecks      map[string]string
+	}{
+		// 0 - no criteria - synced
+		{
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: false,
+			expectedLive:        true,
+			expectedReady:       true,
+			expectedChecks: map[string]string{
+				database:        "HEALTHY",
+				maxBlocksBehind: "DISABLED",
+				minPeerCount:    "DISABLED",
+				engineTimeout:   "DISABLED",
+			},
+		},
+		// 1 - database not readable
+		{
+			netApiResponse:     hexutil.Uint(1),
+			ethApiSyncingError: errors.New("problem reading the database"),
+			expectedLive:       false,
+			expectedReady:      false,
+			expectedChecks: map[string]string{
+				database: "ERROR: problem reading the database",
+			},
+		},
+		// 2 - max blocks behind - close enough
+		{
+			criteria:            Criteria{MaxBlocksBehind: 10},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: syncingResult(95, 100),
+			expectedLive:        true,
+			expectedReady:       true,
+			expectedChecks: map[string]string{
+				maxBlocksBehind: "HEALTHY",
+			},
+		},
+		// 3 - max blocks behind - too far
+		{
+			criteria:            Criteria{MaxBlocksBehind: 10},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: syncingResult(50, 100),
+			expectedLive:        true,
+			expectedReady:       false,
+			expectedChecks: map[string]string{
+				maxBlocksBehind: "ERROR: too far behind: 50 blocks",
+			},
+		},
+		// 4 - max blocks behind - nothing executed yet
+		{
+			criteria:            Criteria{MaxBlocksBehind: 10},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: syncingResult(0, 100),
+			expectedLive:        true,
+			expectedReady:       false,
+			expectedChecks: map[string]string{
+				database:        "HEALTHY",
+				maxBlocksBehind: "ERROR: no block executed yet",
+			},
+		},
+		// 5 - min peers - not enough peers
+		{
+			criteria:            Criteria{MinPeers: 3},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: false,
+			expectedLive:        true,
+			expectedReady:       false,
+			expectedChecks: map[string]string{
+				minPeerCount: "ERROR: not enough peers",
+			},
+		},
+		// 6 - engine timeout - never called
+		{
+			criteria:            Criteria{EngineTimeout: time.Minute},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: false,
+			expectedLive:        true,
+			expectedReady:       false,
+			expectedChecks: map[string]string{
+				engineTimeout: "ERROR: the Engine API hasn't been called",
+			},
+		},
+		// 7 - engine timeout - called recently
+		{
+			criteria:            Criteria{EngineTimeout: time.Minute},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: false,
+			engineLastCalled:    time.Second,
+			expectedLive:        true,
+			expectedReady:       true,
+			expectedChecks: map[string]string{
+				engineTimeout: "HEALTHY",
+			},
+		},
+		// 8 - engine timeout - silent for too long
+		{
+			criteria:            Criteria{EngineTimeout: time.Minute},
+			netApiResponse:      hexutil.Uint(1),
+			ethApiSyncingResult: false,
+			engineLastCalled:    time.Hour,
+			expectedLive:        true,
+			expectedReady:       false,
+			expectedChecks: map[string]string{
+				engineTimeout: "ERROR: the Engine API hasn't been called for 1h0m0s",
+			},
+		},
+	}
+
+	defer atomic.StoreInt64(&engineLastCalled, 0)
+	for idx, c := range cases {
+		var lastCalled int64
+		if c.engineLastCalled > 0 {
+			lastCalled = time.Now().Add(-c.engineLastCalled).UnixNano()
+		}
+		atomic.StoreInt64(&engineLastCalled, lastCalled)
+
+		checker := NewChecker(c.criteria,
+			&netApiStub{response: c.netApiResponse, error: c.netApiError},
+			&ethApiStub{syncingResult: c.ethApiSyncingResult, syncingError: c.ethApiSyncingError})
+		status := checker.Check(context.Background())
+
+		if status.Live != c.expectedLive {
+			t.Errorf("%v: expected live: %v, but got: %v", idx, c.expectedLive, status.Live)
+		}
+		if status.Ready != c.expectedReady {
+			t.Errorf("%v: expected ready: %v, but got: %v", idx, c.expectedReady, status.Ready)
+		}
+		for k, v := range c.expectedChecks {
+			val, found := status.Checks[k]
+			if !found {
+				t.Errorf("%v: expected the check: %s to be in the status but it wasn't there", idx, k)
+			}
+			if !strings.Contains(val, v) {
+				t.Errorf("%v: expected the check: %s to contain: %s, but it contained: %s", idx, k, v, val)
+			}
+		}
+	}
+}
+
+func TestProcessReadinessIfNeeded(t *testing.T) {
+	cases := []struct {
+		path                string
+		criteria            Criteria
+		ethApiSyncingResult interface{}
+		expectedProcessed   bool
+		expectedStatusCode  int
+	}{
+		// 0 - not the readiness path
+		{
+			path:              "/",
+			expectedProcessed: false,
+		},
+		// 1 - ready
+		{
+			path:                "/ready",
+			criteria:            Criteria{MaxBlocksBehind: 10, MinPeers: 1},
+			ethApiSyncingResult: syncingResult(95, 100),
+			expectedProcessed:   true,
+			expectedStatusCode:  http.StatusOK,
+		},
+		// 2 - not ready
+		{
+			path:                "/ready",
+			criteria:            Criteria{MaxBlocksBehind: 10, MinPeers: 1},
+			ethApiSyncingResult: syncingResult(50, 100),
+			expectedProcessed:   true,
+			expectedStatusCode:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for idx, c := range cases {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost:9090"+c.path, nil)
+		if err != nil {
+			t.Errorf("%v: creating request: %v", idx, err)
+		}
+
+		apis := []rpc.API{
+			{Service: &netApiStub{response: hexutil.Uint(1)}},
+			{Service: &ethApiStub{syncingResult: c.ethApiSyncingResult}},
+		}
+
+		processed := ProcessReadinessIfNeeded(w, r, apis, c.criteria)
+		if processed != c.expectedProcessed {
+			t.Errorf("%v: expected processed: %v, but got: %v", idx, c.expectedProcessed, processed)
+		}
+		if !processed {
+			continue
+		}
+
+		result := w.Result()
+		if result.StatusCode != c.expectedStatusCode {
+			t.Errorf("%v: expected status code: %v, but got: %v", idx, c.expectedStatusCode, result.StatusCode)
+		}
+		var status Status
+		if err := json.NewDecoder(result.Body).Decode(&status); err != nil {
+			t.Errorf("%v: decoding the status: %v", idx, err)
+		}
+		if status.Ready != (c.expectedStatusCode == http.StatusOK) {
+			t.Errorf("%v: expected ready: %v, but got: %v", idx, c.expectedStatusCode == http.StatusOK, status.Ready)
+		}
+	}
+}
+
+func TestProcessHealthcheckIfNeeded_Liveness(t *testing.T) {
+	cases := []struct {
+		ethApiSyncingError error
+		expectedStatusCode int
+	}{
+		// 0 - live
+		{
+			expectedStatusCode: http.StatusOK,
+		},
+		// 1 - database not readable
+		{
+			ethApiSyncingError: errors.New("problem reading the database"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for idx, c := range cases {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost:9090/health", nil)
+		if err != nil {
+			t.Errorf("%v: creating request: %v", idx, err)
+		}
+		r.Body = ioutil.NopCloser(strings.NewReader(""))
+
+		apis := []rpc.API{
+			{Service: &netApiStub{response: hexutil.Uint(1)}},
+			{Service: &ethApiStub{syncingResult: false, syncingError: c.ethApiSyncingError}},
+		}
+
+		ProcessHealthcheckIfNeeded(w, r, apis)
+
+		result := w.Result()
+		if result.StatusCode != c.expectedStatusCode {
+			t.Errorf("%v: expected status code: %v, but got: %v", idx, c.expectedStatusCode, result.StatusCode)
+		}
+	}
+}
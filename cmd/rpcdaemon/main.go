@@ -26,7 +26,7 @@ func main() {
 			defer borDb.Close()
 		}
 
-		apiList := commands.APIList(db, borDb, backend, txPool, mining, starknet, ff, stateCache, blockReader, *cfg)
+		apiList := commands.APIList(db, borDb, nil, nil, nil, backend, txPool, mining, starknet, ff, stateCache, blockReader, *cfg)
 		if err := cli.StartRpcServer(ctx, *cfg, apiList); err != nil {
 			log.Error(err.Error())
 			return nil
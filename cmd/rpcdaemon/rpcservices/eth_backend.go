@@ -12,6 +12,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
@@ -30,6 +31,7 @@ type RemoteBackend struct {
 	version          gointerfaces.Version
 	db               kv.RoDB
 	blockReader      services.FullBlockReader
+	sentryAdmin      sentryadmin.Admin
 }
 
 func NewRemoteBackend(client remote.ETHBACKENDClient, db kv.RoDB, blockReader services.FullBlockReader) *RemoteBackend {
@@ -42,6 +44,11 @@ func NewRemoteBackend(client remote.ETHBACKENDClient, db kv.RoDB, blockReader se
 	}
 }
 
+// SetSentryAdmin sets the admin service of the sentries, served by erigon next to its backend
+func (back *RemoteBackend) SetSentryAdmin(admin sentryadmin.Admin) { back.sentryAdmin = admin }
+
+func (back *RemoteBackend) SentryAdmin() sentryadmin.Admin { return back.sentryAdmin }
+
 func (back *RemoteBackend) EnsureVersionCompatibility() bool {
 	versionReply, err := back.remoteEthBackend.Version(context.Background(), &emptypb.Empty{}, grpc.WaitForReady(true))
 	if err != nil {
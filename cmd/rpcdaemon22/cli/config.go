@@ -2,15 +2,15 @@ package cli
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/big"
 	"net"
 	"net/http"
-	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,11 +32,12 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon22/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon22/health"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon22/rpcservices"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/erigon/cmd/utils"
-	"github.com/ledgerwatch/erigon/common"
-	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/paths"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/node"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
 	"github.com/ledgerwatch/erigon/params"
@@ -47,6 +48,7 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 	"google.golang.org/grpc"
 	grpcHealth "google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -62,11 +64,11 @@ const JwtDefaultFile = "jwt.hex"
 func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	utils.CobraFlags(rootCmd, append(debug.Flags, utils.MetricFlags...))
 
-	cfg := &httpcfg.HttpCfg{StateCache: kvcache.DefaultCoherentConfig}
+	cfg := &httpcfg.HttpCfg{StateCache: kvcache.DefaultCoherentConfig, GPO: ethconfig.Defaults.GPO}
 	rootCmd.PersistentFlags().StringVar(&cfg.PrivateApiAddr, "private.api.addr", "127.0.0.1:9090", "private api network address, for example: 127.0.0.1:9090")
 	rootCmd.PersistentFlags().StringVar(&cfg.DataDir, "datadir", "", "path to Erigon working directory")
 	rootCmd.PersistentFlags().StringVar(&cfg.HttpListenAddress, "http.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface")
-	rootCmd.PersistentFlags().StringVar(&cfg.EngineHTTPListenAddress, "engine.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface for engineAPI")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.EngineHTTPListenAddresses, utils.EngineAddr.Name, []string{nodecfg.DefaultHTTPHost}, utils.EngineAddr.Usage)
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCertfile, "tls.cert", "", "certificate for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSKeyFile, "tls.key", "", "key file for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCACert, "tls.cacert", "", "CA certificate for client side TLS handshake")
@@ -80,21 +82,33 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraces, "trace.maxtraces", 200, "Sets a limit on traces that can be returned in trace_filter")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
+	rootCmd.PersistentFlags().IntVar(&cfg.WebsocketPendingTxsRate, utils.WsPendingTxsRateFlag.Name, utils.WsPendingTxsRateFlag.Value, utils.WsPendingTxsRateFlag.Usage)
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcRateLimitsFilePath, utils.RpcRateLimitsFlag.Name, "", utils.RpcRateLimitsFlag.Usage)
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcBatchLimit, utils.RpcBatchLimitFlag.Name, utils.RpcBatchLimitFlag.Value, utils.RpcBatchLimitFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, "db.read.concurrency", runtime.GOMAXPROCS(-1), "Does limit amount of parallel db reads")
+	rootCmd.PersistentFlags().StringVar(&cfg.DBBackend, utils.DbBackendFlag.Name, utils.DbBackendFlag.Value, utils.DbBackendFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceCompatibility, "trace.compat", false, "Bug for bug compatibility with OE for trace_ routines")
 	rootCmd.PersistentFlags().StringVar(&cfg.TxPoolApiAddr, "txpool.api.addr", "", "txpool api network address, for example: 127.0.0.1:9090 (default: use value of --private.api.addr)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.TevmEnabled, utils.TevmFlag.Name, false, utils.TevmFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.Sync.UseSnapshots, "snapshot", true, utils.SnapshotFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.StateCache.KeysLimit, "state.cache", kvcache.DefaultCoherentConfig.KeysLimit, "Amount of keys to store in StateCache (enabled if no --datadir set). Set 0 to disable StateCache. 1_000_000 keys ~ equal to 2Gb RAM (maybe we will add RAM accounting in future versions).")
+	rootCmd.PersistentFlags().Uint64Var(&cfg.StateCacheWarmupBlocks, utils.StateCacheWarmupFlag.Name, utils.StateCacheWarmupFlag.Value, utils.StateCacheWarmupFlag.Usage)
+	var gpoMinPrice, gpoMaxPrice int64
+	rootCmd.PersistentFlags().StringVar(&cfg.GPO.Strategy, utils.GpoStrategyFlag.Name, utils.GpoStrategyFlag.Value, utils.GpoStrategyFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.GPO.Blocks, utils.GpoBlocksFlag.Name, utils.GpoBlocksFlag.Value, utils.GpoBlocksFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.GPO.Percentile, utils.GpoPercentileFlag.Name, utils.GpoPercentileFlag.Value, utils.GpoPercentileFlag.Usage)
+	rootCmd.PersistentFlags().Int64Var(&gpoMinPrice, utils.GpoMinGasPriceFlag.Name, utils.GpoMinGasPriceFlag.Value, utils.GpoMinGasPriceFlag.Usage)
+	rootCmd.PersistentFlags().Int64Var(&gpoMaxPrice, utils.GpoMaxGasPriceFlag.Name, utils.GpoMaxGasPriceFlag.Value, utils.GpoMaxGasPriceFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
 	rootCmd.PersistentFlags().StringVar(&cfg.GRPCListenAddress, "grpc.addr", nodecfg.DefaultGRPCHost, "GRPC server listening interface")
 	rootCmd.PersistentFlags().IntVar(&cfg.GRPCPort, "grpc.port", nodecfg.DefaultGRPCPort, "GRPC server listening port")
 	rootCmd.PersistentFlags().BoolVar(&cfg.GRPCHealthCheckEnabled, "grpc.healthcheck", false, "Enable GRPC health check")
 	rootCmd.PersistentFlags().StringVar(&cfg.StarknetGRPCAddress, "starknet.grpc.address", "127.0.0.1:6066", "Starknet GRPC address")
 	rootCmd.PersistentFlags().StringVar(&cfg.JWTSecretPath, utils.JWTSecretPath.Name, utils.JWTSecretPath.Value, "Token to ensure safe connection between CL and EL")
+	rootCmd.PersistentFlags().DurationVar(&cfg.JWTSecretRotation, utils.JWTSecretRotationFlag.Name, utils.JWTSecretRotationFlag.Value, utils.JWTSecretRotationFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceRequests, utils.HTTPTraceFlag.Name, false, "Trace HTTP requests with INFO level")
 
 	if err := rootCmd.MarkPersistentFlagFilename("rpc.accessList", "json"); err != nil {
@@ -118,6 +132,10 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 		if cfg.TxPoolApiAddr == "" {
 			cfg.TxPoolApiAddr = cfg.PrivateApiAddr
 		}
+		if !slices.Contains(gasprice.Strategies, cfg.GPO.Strategy) {
+			return fmt.Errorf("invalid %s: %s, expected one of: %s", utils.GpoStrategyFlag.Name, cfg.GPO.Strategy, strings.Join(gasprice.Strategies, ", "))
+		}
+		cfg.GPO.MinPrice, cfg.GPO.MaxPrice = big.NewInt(gpoMinPrice), big.NewInt(gpoMaxPrice)
 		return nil
 	}
 	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
@@ -134,7 +152,7 @@ type StateChangesClient interface {
 	StateChanges(ctx context.Context, in *remote.StateChangeRequest, opts ...grpc.CallOption) (remote.KV_StateChangesClient, error)
 }
 
-func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient, cache kvcache.Cache) {
+func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient, cache kvcache.Cache, warmer *rpchelper.CacheWarmer) {
 	go func() {
 		for {
 			select {
@@ -142,7 +160,7 @@ func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient,
 				return
 			default:
 			}
-			if err := subscribeToStateChanges(ctx, client, cache); err != nil {
+			if err := subscribeToStateChanges(ctx, client, cache, warmer); err != nil {
 				if grpcutil.IsRetryLater(err) || grpcutil.IsEndOfStream(err) {
 					time.Sleep(3 * time.Second)
 					continue
@@ -153,7 +171,7 @@ func subscribeToStateChangesLoop(ctx context.Context, client StateChangesClient,
 	}()
 }
 
-func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cache kvcache.Cache) error {
+func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cache kvcache.Cache, warmer *rpchelper.CacheWarmer) error {
 	streamCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	stream, err := client.StateChanges(streamCtx, &remote.StateChangeRequest{WithStorage: true, WithTransactions: false}, grpc.WaitForReady(true))
@@ -169,6 +187,9 @@ func subscribeToStateChanges(ctx context.Context, client StateChangesClient, cac
 		}
 
 		cache.OnNewBlock(req)
+		if warmer != nil {
+			warmer.OnNewBlock(ctx, req)
+		}
 	}
 }
 
@@ -209,20 +230,24 @@ func checkDbCompatibility(ctx context.Context, db kv.RoDB) error {
 	return nil
 }
 
-func EmbeddedServices(ctx context.Context, erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig, blockReader services.FullBlockReader, ethBackendServer remote.ETHBACKENDServer,
+func EmbeddedServices(ctx context.Context, erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig, stateCacheWarmupBlocks uint64, blockReader services.FullBlockReader, ethBackendServer remote.ETHBACKENDServer,
 	txPoolServer txpool.TxpoolServer, miningServer txpool.MiningServer,
 ) (
 	eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, starknet *rpcservices.StarknetService, stateCache kvcache.Cache, ff *rpchelper.Filters, err error,
 ) {
+	var warmer *rpchelper.CacheWarmer
 	if stateCacheCfg.KeysLimit > 0 {
 		stateCache = kvcache.New(stateCacheCfg)
+		if stateCacheWarmupBlocks > 0 {
+			warmer = rpchelper.NewCacheWarmer(erigonDB, stateCache, stateCacheWarmupBlocks)
+		}
 	} else {
 		stateCache = kvcache.NewDummy()
 	}
 	kvRPC := remotedbserver.NewKvServer(ctx, erigonDB)
 	stateDiffClient := direct.NewStateDiffClientDirect(kvRPC)
 	_ = stateDiffClient
-	subscribeToStateChangesLoop(ctx, stateDiffClient, stateCache)
+	subscribeToStateChangesLoop(ctx, stateDiffClient, stateCache, warmer)
 
 	directClient := direct.NewEthBackendClientDirect(ethBackendServer)
 
@@ -254,7 +279,11 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		var rwKv kv.RwDB
 		log.Trace("Creating chain db", "path", cfg.Dirs.Chaindata)
 		limiter := make(chan struct{}, cfg.DBReadConcurrency)
-		rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
+		if cfg.DBBackend != "" && cfg.DBBackend != node.DefaultDBBackend {
+			rwKv, err = node.OpenDBBackend(cfg.DBBackend, logger, kv.ChainDB, cfg.Dirs.Chaindata, node.DBOpenReadonly)
+		} else {
+			rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
+		}
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 		}
@@ -270,14 +299,14 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		borDbPath := filepath.Join(cfg.DataDir, "bor")
 		{
 			// ensure db exist
-			tmpDb, err := kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Open()
+			tmpDb, err := node.OpenDBBackend(cfg.DBBackend, logger, kv.ConsensusDB, borDbPath, node.DBOpenDefault)
 			if err != nil {
 				return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 			}
 			tmpDb.Close()
 		}
 		log.Trace("Creating consensus db", "path", borDbPath)
-		borKv, err = kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Readonly().Open()
+		borKv, err = node.OpenDBBackend(cfg.DBBackend, logger, kv.ConsensusDB, borDbPath, node.DBOpenReadonly)
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 		}
@@ -391,12 +420,17 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to remoteKv: %w", err)
 	}
 
-	subscribeToStateChangesLoop(ctx, kvClient, stateCache)
+	var warmer *rpchelper.CacheWarmer
+	if !cfg.WithDatadir && cfg.StateCache.KeysLimit > 0 && cfg.StateCacheWarmupBlocks > 0 {
+		warmer = rpchelper.NewCacheWarmer(remoteKv, stateCache, cfg.StateCacheWarmupBlocks)
+	}
+	subscribeToStateChangesLoop(ctx, kvClient, stateCache, warmer)
 
 	if !cfg.WithDatadir {
 		blockReader = snapshotsync.NewRemoteBlockReader(remote.NewETHBACKENDClient(conn))
 	}
 	remoteEth := rpcservices.NewRemoteBackend(remote.NewETHBACKENDClient(conn), db, blockReader)
+	remoteEth.SetSentryAdmin(sentryadmin.NewClient(conn))
 	blockReader = remoteEth
 
 	txpoolConn := conn
@@ -449,14 +483,15 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 }
 
 func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API) error {
-	var engineListener *http.Server
+	var engineListeners []*http.Server
 	var engineSrv *rpc.Server
-	var engineHttpEndpoint string
+	var engineHttpEndpoints []string
 
 	// register apis and create handler stack
 	httpEndpoint := fmt.Sprintf("%s:%d", cfg.HttpListenAddress, cfg.HttpPort)
 
 	srv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, cfg.RpcStreamingDisable)
+	srv.SetBatchLimit(cfg.RpcBatchLimit)
 
 	allowListForRPC, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
 	if err != nil {
@@ -464,6 +499,12 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 	}
 	srv.SetAllowList(allowListForRPC)
 
+	rateLimits, err := parseRateLimitsForRPC(cfg.RpcRateLimitsFilePath)
+	if err != nil {
+		return err
+	}
+	srv.SetRateLimits(rateLimits)
+
 	var defaultAPIList []rpc.API
 	var engineAPI []rpc.API
 
@@ -514,7 +555,7 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 		"ws.compression", cfg.WebsocketCompression, "grpc", cfg.GRPCServerEnabled}
 
 	if len(engineAPI) > 0 {
-		engineListener, engineSrv, engineHttpEndpoint, err = createEngineListener(cfg, engineAPI)
+		engineListeners, engineSrv, engineHttpEndpoints, err = createEngineListeners(ctx, cfg, engineAPI)
 		if err != nil {
 			return fmt.Errorf("could not start RPC api for engine: %w", err)
 		}
@@ -552,9 +593,9 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API)
 		_ = listener.Shutdown(shutdownCtx)
 		log.Info("HTTP endpoint closed", "url", httpEndpoint)
 
-		if engineListener != nil {
+		for i, engineListener := range engineListeners {
 			_ = engineListener.Shutdown(shutdownCtx)
-			log.Info("Engine HTTP endpoint close", "url", engineHttpEndpoint)
+			log.Info("Engine HTTP endpoint close", "url", engineHttpEndpoints[i])
 		}
 
 		if cfg.GRPCServerEnabled {
@@ -577,36 +618,7 @@ func isWebsocket(r *http.Request) bool {
 		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
 }
 
-// obtainJWTSecret loads the jwt-secret, either from the provided config,
-// or from the default location. If neither of those are present, it generates
-// a new secret and stores to the default location.
-func obtainJWTSecret(cfg httpcfg.HttpCfg) ([]byte, error) {
-	// try reading from file
-	log.Info("Reading JWT secret", "path", cfg.JWTSecretPath)
-	// If we run the rpcdaemon and datadir is not specified we just use jwt.hex in current directory.
-	if len(cfg.JWTSecretPath) == 0 {
-		cfg.JWTSecretPath = "jwt.hex"
-	}
-	if data, err := os.ReadFile(cfg.JWTSecretPath); err == nil {
-		jwtSecret := common.FromHex(strings.TrimSpace(string(data)))
-		if len(jwtSecret) == 32 {
-			return jwtSecret, nil
-		}
-		log.Error("Invalid JWT secret", "path", cfg.JWTSecretPath, "length", len(jwtSecret))
-		return nil, errors.New("invalid JWT secret")
-	}
-	// Need to generate one
-	jwtSecret := make([]byte, 32)
-	rand.Read(jwtSecret)
-
-	if err := os.WriteFile(cfg.JWTSecretPath, []byte(hexutil.Encode(jwtSecret)), 0600); err != nil {
-		return nil, err
-	}
-	log.Info("Generated JWT secret", "path", cfg.JWTSecretPath)
-	return jwtSecret, nil
-}
-
-func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Handler, wsHandler http.Handler, jwtSecret []byte) (http.Handler, error) {
+func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Handler, wsHandler http.Handler, jwtSecrets func() [][]byte) (http.Handler, error) {
 	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// adding a healthcheck here
 		if health.ProcessHealthcheckIfNeeded(w, r, apiList) {
@@ -617,7 +629,7 @@ func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Hand
 			return
 		}
 
-		if jwtSecret != nil && !rpc.CheckJwtSecret(w, r, jwtSecret) {
+		if jwtSecrets != nil && !rpc.CheckJwtSecrets(w, r, jwtSecrets()) {
 			return
 		}
 
@@ -627,45 +639,73 @@ func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Hand
 	return handler, nil
 }
 
-func createEngineListener(cfg httpcfg.HttpCfg, engineApi []rpc.API) (*http.Server, *rpc.Server, string, error) {
-	engineHttpEndpoint := fmt.Sprintf("%s:%d", cfg.EngineHTTPListenAddress, cfg.EnginePort)
+// engineEndpoints returns the addresses the Engine API listens on. The interfaces given without a port listen on engine.port.
+func engineEndpoints(cfg httpcfg.HttpCfg) []string {
+	endpoints := make([]string, 0, len(cfg.EngineHTTPListenAddresses))
+	for _, addr := range cfg.EngineHTTPListenAddresses {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, strconv.Itoa(cfg.EnginePort))
+		}
+		endpoints = append(endpoints, addr)
+	}
+	return endpoints
+}
+
+func createEngineListeners(ctx context.Context, cfg httpcfg.HttpCfg, engineApi []rpc.API) ([]*http.Server, *rpc.Server, []string, error) {
+	engineHttpEndpoints := engineEndpoints(cfg)
+	if len(engineHttpEndpoints) == 0 {
+		return nil, nil, nil, errors.New("no listening address for the engine API")
+	}
 
 	engineSrv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, true)
 
 	allowListForRPC, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
 	engineSrv.SetAllowList(allowListForRPC)
 
 	if err := node.RegisterApisFromWhitelist(engineApi, nil, engineSrv, true); err != nil {
-		return nil, nil, "", fmt.Errorf("could not start register RPC engine api: %w", err)
+		return nil, nil, nil, fmt.Errorf("could not start register RPC engine api: %w", err)
 	}
 
-	jwtSecret, err := obtainJWTSecret(cfg)
+	jwtSecrets, err := obtainJWTSecrets(cfg)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
+	go jwtSecrets.watch(ctx)
 
 	var wsHandler http.Handler
 	if cfg.WebsocketEnabled {
-		wsHandler = engineSrv.WebsocketHandler([]string{"*"}, jwtSecret, cfg.WebsocketCompression)
+		wsHandler = engineSrv.WebsocketHandler([]string{"*"}, jwtSecrets.Secrets, cfg.WebsocketCompression)
 	}
 
 	engineHttpHandler := node.NewHTTPHandlerStack(engineSrv, cfg.HttpCORSDomain, cfg.HttpVirtualHost, cfg.HttpCompression)
 
-	engineApiHandler, err := createHandler(cfg, engineApi, engineHttpHandler, wsHandler, jwtSecret)
+	engineApiHandler, err := createHandler(cfg, engineApi, engineHttpHandler, wsHandler, jwtSecrets.Secrets)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, nil, nil, err
 	}
 
-	engineListener, _, err := node.StartHTTPEndpoint(engineHttpEndpoint, rpccfg.DefaultHTTPTimeouts, engineApiHandler)
-	if err != nil {
-		return nil, nil, "", fmt.Errorf("could not start RPC api: %w", err)
-	}
+	// every consensus client may be given its own endpoint, they all share the engine API
+	engineListeners := make([]*http.Server, 0, len(engineHttpEndpoints))
+	for _, engineHttpEndpoint := range engineHttpEndpoints {
+		engineListener, _, err := node.StartHTTPEndpoint(engineHttpEndpoint, rpccfg.DefaultHTTPTimeouts, engineApiHandler)
+		if err != nil {
+			for _, l := range engineListeners {
+				_ = l.Close()
+			}
+			return nil, nil, nil, fmt.Errorf("could not start RPC api: %w", err)
+		}
+		engineListeners = append(engineListeners, engineListener)
 
-	engineInfo := []interface{}{"url", engineHttpEndpoint, "ws", cfg.WebsocketEnabled}
-	log.Info("HTTP endpoint opened for Engine API", engineInfo...)
+		engineInfo := []interface{}{"url", engineHttpEndpoint, "ws", cfg.WebsocketEnabled}
+		log.Info("HTTP endpoint opened for Engine API", engineInfo...)
+	}
 
-	return engineListener, engineSrv, engineHttpEndpoint, nil
+	return engineListeners, engineSrv, engineHttpEndpoints, nil
 }
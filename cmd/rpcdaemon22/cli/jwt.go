@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon22/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// jwtSecretReloadInterval is how often the secret file is checked for changes
+const jwtSecretReloadInterval = 5 * time.Second
+
+// jwtSecrets are the secrets of the Engine API authentication, read from the secret file (one hex secret per line).
+// The file is re-read when it changes, and the secrets it held before remain accepted for the rotation window,
+// so that the consensus clients can be switched to the new secret one by one.
+type jwtSecrets struct {
+	path           string
+	rotationWindow time.Duration
+
+	lock     sync.RWMutex
+	data     []byte // content of the file the current secrets were read from
+	current  [][]byte
+	previous [][]byte
+	expiry   time.Time // of the previous secrets
+}
+
+// obtainJWTSecrets loads the jwt-secrets, either from the provided config,
+// or from the default location. If neither of those are present, it generates
+// a new secret and stores to the default location.
+func obtainJWTSecrets(cfg httpcfg.HttpCfg) (*jwtSecrets, error) {
+	// If we run the rpcdaemon and datadir is not specified we just use jwt.hex in current directory.
+	if len(cfg.JWTSecretPath) == 0 {
+		cfg.JWTSecretPath = JwtDefaultFile
+	}
+	if _, err := os.Stat(cfg.JWTSecretPath); errors.Is(err, os.ErrNotExist) {
+		// Need to generate one
+		jwtSecret := make([]byte, 32)
+		rand.Read(jwtSecret)
+
+		if err := os.WriteFile(cfg.JWTSecretPath, []byte(hexutil.Encode(jwtSecret)), 0600); err != nil {
+			return nil, err
+		}
+		log.Info("Generated JWT secret", "path", cfg.JWTSecretPath)
+	}
+
+	log.Info("Reading JWT secret", "path", cfg.JWTSecretPath)
+	s := &jwtSecrets{path: cfg.JWTSecretPath, rotationWindow: cfg.JWTSecretRotation}
+	if _, err := s.reload(); err != nil {
+		log.Error("Invalid JWT secret", "path", cfg.JWTSecretPath, "err", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Secrets returns the secrets accepted at the moment.
+func (s *jwtSecrets) Secrets() [][]byte {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if len(s.previous) == 0 || time.Now().After(s.expiry) {
+		return s.current
+	}
+	secrets := make([][]byte, 0, len(s.current)+len(s.previous))
+	return append(append(secrets, s.current...), s.previous...)
+}
+
+// reload reads the secret file and reports whether the secrets have changed.
+// An invalid file is rejected, the secrets in use are kept.
+func (s *jwtSecrets) reload() (bool, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	s.lock.RLock()
+	unchanged := s.current != nil && bytes.Equal(data, s.data)
+	s.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	secrets, err := parseJWTSecrets(data)
+	if err != nil {
+		return false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.current != nil {
+		s.previous, s.expiry = s.current, time.Now().Add(s.rotationWindow)
+	}
+	s.data, s.current = data, secrets
+	return true, nil
+}
+
+// watch reloads the secret file when it changes, until the context is cancelled.
+func (s *jwtSecrets) watch(ctx context.Context) {
+	ticker := time.NewTicker(jwtSecretReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := s.reload()
+		if err != nil {
+			log.Warn("Failed to reload JWT secret", "path", s.path, "err", err)
+		} else if changed {
+			log.Info("Reloaded JWT secret", "path", s.path, "previous accepted for", s.rotationWindow)
+		}
+	}
+}
+
+func parseJWTSecrets(data []byte) ([][]byte, error) {
+	var secrets [][]byte
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		jwtSecret := common.FromHex(line)
+		if len(jwtSecret) != 32 {
+			return nil, fmt.Errorf("invalid JWT secret, length %d", len(jwtSecret))
+		}
+		secrets = append(secrets, jwtSecret)
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("no JWT secret")
+	}
+	return secrets, nil
+}
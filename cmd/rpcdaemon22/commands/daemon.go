@@ -22,6 +22,11 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 		base.EnableTevmExperiment()
 	}
 	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl.GPO = cfg.GPO
+	ethImpl.PendingTxsRate = cfg.WebsocketPendingTxsRate
+	if cfg.WithDatadir {
+		ethImpl.tmpDir = cfg.Dirs.Tmp
+	}
 	erigonImpl := NewErigonAPI(base, db, eth)
 	starknetImpl := NewStarknetAPI(base, db, starknet, txPool)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
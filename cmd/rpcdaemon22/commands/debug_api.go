@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
@@ -13,6 +14,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/eth/tracers"
 	"github.com/ledgerwatch/erigon/ethdb"
@@ -25,6 +27,9 @@ import (
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
+// BlockWitnessMaxUnwind is the maximum number of blocks the trie is unwound by to produce a block witness
+const BlockWitnessMaxUnwind = 1024
+
 // PrivateDebugAPI Exposed RPC endpoints for debugging use
 type PrivateDebugAPI interface {
 	StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex uint64, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error)
@@ -36,6 +41,7 @@ type PrivateDebugAPI interface {
 	GetModifiedAccountsByHash(_ context.Context, startHash common.Hash, endHash *common.Hash) ([]common.Address, error)
 	TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig, stream *jsoniter.Stream) error
 	AccountAt(ctx context.Context, blockHash common.Hash, txIndex uint64, account common.Address) (*AccountResult, error)
+	GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, stream *jsoniter.Stream) error
 }
 
 // PrivateDebugAPIImpl is implementation of the PrivateDebugAPI interface based on remote Db access
@@ -271,3 +277,79 @@ type AccountResult struct {
 	Code     hexutil.Bytes  `json:"code"`
 	CodeHash common.Hash    `json:"codeHash"`
 }
+
+// GetBlockWitness implements debug_getBlockWitness. Returns the witness of the block for stateless clients, in the
+// binary format of trie.Witness: the proofs of the state of the parent read by the block, and the codes it read.
+// The witness is streamed, as it can be large.
+func (api *PrivateDebugAPIImpl) GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	defer tx.Rollback()
+	var block *types.Block
+	if number, ok := blockNrOrHash.Number(); ok {
+		block, err = api.blockByRPCNumber(number, tx)
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = api.blockByHashWithSenders(tx, hash)
+	} else {
+		err = fmt.Errorf("invalid arguments; neither block nor hash specified")
+	}
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	if block == nil {
+		stream.WriteNil()
+		return fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+
+	w := &hexStreamWriter{stream: stream}
+	cfg := stagedsync.BlockWitnessCfg{
+		ChainConfig: chainConfig,
+		Engine:      ethash.NewFaker(),
+		BlockReader: api._blockReader,
+		MaxUnwind:   BlockWitnessMaxUnwind,
+	}
+	if _, err = stagedsync.GenerateBlockWitness(ctx, tx, block, cfg, w); err != nil {
+		if !w.started {
+			stream.WriteNil()
+		}
+		return err
+	}
+	return w.close()
+}
+
+// hexStreamWriter writes the bytes to the stream as a hex string, flushing it as it grows.
+type hexStreamWriter struct {
+	stream  *jsoniter.Stream
+	started bool
+}
+
+func (w *hexStreamWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.stream.WriteRaw(`"0x`)
+		w.started = true
+	}
+	w.stream.WriteRaw(hex.EncodeToString(p))
+	if w.stream.Buffered() > 64*1024 {
+		if err := w.stream.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), w.stream.Error
+}
+
+func (w *hexStreamWriter) close() error {
+	if !w.started {
+		w.stream.WriteRaw(`"0x`)
+	}
+	w.stream.WriteRaw(`"`)
+	return w.stream.Flush()
+}
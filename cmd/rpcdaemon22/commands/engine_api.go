@@ -14,6 +14,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -36,6 +37,11 @@ type ExecutionPayload struct {
 	Transactions  []hexutil.Bytes `json:"transactions"  gencodec:"required"`
 }
 
+// ExecutionPayloadBodyV1 represents the transactions of an execution payload
+type ExecutionPayloadBodyV1 struct {
+	Transactions []hexutil.Bytes `json:"transactions" gencodec:"required"`
+}
+
 // PayloadAttributes represent the attributes required to start assembling a payload
 type ForkChoiceState struct {
 	HeadHash           common.Hash `json:"headBlockHash"             gencodec:"required"`
@@ -63,8 +69,16 @@ type EngineAPI interface {
 	NewPayloadV1(context.Context, *ExecutionPayload) (map[string]interface{}, error)
 	GetPayloadV1(ctx context.Context, payloadID hexutil.Bytes) (*ExecutionPayload, error)
 	ExchangeTransitionConfigurationV1(ctx context.Context, transitionConfiguration TransitionConfiguration) (TransitionConfiguration, error)
+	GetPayloadBodiesByHashV1(ctx context.Context, hashes []common.Hash) ([]*ExecutionPayloadBodyV1, error)
+	GetPayloadBodiesByRangeV1(ctx context.Context, start, count hexutil.Uint64) ([]*ExecutionPayloadBodyV1, error)
 }
 
+// maxPayloadBodies is the maximum number of payload bodies which can be requested at once
+const maxPayloadBodies = 1024
+
+var tooLargeRequestErr = rpc.CustomError{Code: -38004, Message: "Too large request"}
+var invalidRangeErr = rpc.CustomError{Code: -32602, Message: "Invalid params: start and count must be positive"}
+
 // EngineImpl is implementation of the EngineAPI interface
 type EngineImpl struct {
 	*BaseAPI
@@ -252,6 +266,92 @@ func (e *EngineImpl) ExchangeTransitionConfigurationV1(ctx context.Context, beac
 	}, nil
 }
 
+// GetPayloadBodiesByHashV1 returns the bodies of the given blocks, canonical or not. The body of an
+// unknown block is null.
+// See https://github.com/ethereum/execution-apis/blob/main/src/engine/shanghai.md#engine_getpayloadbodiesbyhashv1
+func (e *EngineImpl) GetPayloadBodiesByHashV1(ctx context.Context, hashes []common.Hash) ([]*ExecutionPayloadBodyV1, error) {
+	if len(hashes) > maxPayloadBodies {
+		return nil, &tooLargeRequestErr
+	}
+	tx, err := e.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bodies := make([]*ExecutionPayloadBodyV1, len(hashes))
+	for i, hash := range hashes {
+		header, err := e._blockReader.HeaderByHash(ctx, tx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			continue
+		}
+		if bodies[i], err = e.payloadBody(ctx, tx, hash, header.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+	return bodies, nil
+}
+
+// GetPayloadBodiesByRangeV1 returns the bodies of count canonical blocks starting from start. The body of
+// a missing block is null, the bodies past the latest block are omitted.
+// See https://github.com/ethereum/execution-apis/blob/main/src/engine/shanghai.md#engine_getpayloadbodiesbyrangev1
+func (e *EngineImpl) GetPayloadBodiesByRangeV1(ctx context.Context, start, count hexutil.Uint64) ([]*ExecutionPayloadBodyV1, error) {
+	if start == 0 || count == 0 {
+		return nil, &invalidRangeErr
+	}
+	if count > maxPayloadBodies {
+		return nil, &tooLargeRequestErr
+	}
+	tx, err := e.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	latest, err := rpchelper.GetLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	end := uint64(start) + uint64(count) - 1
+	if end > latest {
+		end = latest
+	}
+	bodies := make([]*ExecutionPayloadBodyV1, 0, count)
+	for number := uint64(start); number <= end; number++ {
+		hash, err := e._blockReader.CanonicalHash(ctx, tx, number)
+		if err != nil {
+			return nil, err
+		}
+		var body *ExecutionPayloadBodyV1
+		if hash != (common.Hash{}) {
+			if body, err = e.payloadBody(ctx, tx, hash, number); err != nil {
+				return nil, err
+			}
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+func (e *EngineImpl) payloadBody(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64) (*ExecutionPayloadBodyV1, error) {
+	body, err := e._blockReader.BodyWithTransactions(ctx, tx, hash, number)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	encodedTransactions, err := types.MarshalTransactionsBinary(body.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]hexutil.Bytes, len(encodedTransactions))
+	for i, transaction := range encodedTransactions {
+		transactions[i] = transaction
+	}
+	return &ExecutionPayloadBodyV1{Transactions: transactions}, nil
+}
+
 // NewEngineAPI returns EngineImpl instance
 func NewEngineAPI(base *BaseAPI, db kv.RoDB, api rpchelper.ApiBackend) *EngineImpl {
 	return &EngineImpl{
@@ -18,7 +18,9 @@ import (
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -81,7 +83,7 @@ type EthAPI interface {
 	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	Sign(ctx context.Context, _ common.Address, _ hexutil.Bytes) (hexutil.Bytes, error)
 	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
-	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error)
+	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error)
 	CreateAccessList(ctx context.Context, args ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error)
 
 	// Mining related (see ./eth_mining.go)
@@ -237,6 +239,13 @@ type APIImpl struct {
 	mining     txpool.MiningClient
 	db         kv.RoDB
 	GasCap     uint64
+	GPO        gasprice.Config // of eth_gasPrice and eth_maxPriorityFeePerGas
+	// PendingTxsRate limits the transactions notified per second to the newPendingTransactions subscriptions of
+	// a connection, 0 for no limit
+	PendingTxsRate int
+	tmpDir         string // of the unwinds of eth_getProof, the temp dir of the system if empty
+
+	gasPriceCache gasprice.Cache
 }
 
 // NewEthAPI returns APIImpl instance
@@ -252,6 +261,9 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		txPool:     txPool,
 		mining:     mining,
 		GasCap:     gascap,
+		GPO:        ethconfig.Defaults.GPO,
+
+		gasPriceCache: gasprice.NewCache(),
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
@@ -18,6 +19,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/tracers/logger"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
@@ -25,6 +27,7 @@ import (
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 )
@@ -63,7 +66,7 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHas
 		return nil, nil
 	}
 
-	result, err := transactions.DoCall(ctx, args, tx, blockNrOrHash, block, overrides, api.GasCap, chainConfig, api.filters, api.stateCache, contractHasTEVM, api._blockReader)
+	result, err := transactions.DoCall(ctx, args, tx, blockNrOrHash, block, overrides, api.GasCap, chainConfig, api.filters, api.stateCache, nil, contractHasTEVM, api._blockReader)
 	if err != nil {
 		return nil, err
 	}
@@ -234,7 +237,7 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi.CallArgs,
 		}
 
 		result, err := transactions.DoCall(ctx, args, dbtx, numOrHash, block, nil,
-			api.GasCap, chainConfig, api.filters, api.stateCache, contractHasTEVM, api._blockReader)
+			api.GasCap, chainConfig, api.filters, api.stateCache, nil, contractHasTEVM, api._blockReader)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				// Special case, raise gas limit
@@ -283,10 +286,72 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi.CallArgs,
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+// GetProofMaxUnwind is the maximum number of blocks the trie is unwound by to produce a proof
+const GetProofMaxUnwind = 1024
+
+// GetProofMaxMemory is the maximum size of the state changes unwound in memory to produce a proof
+const GetProofMaxMemory = 256 * datasize.MB
+
+// GetProof implements eth_getProof. Returns the Merkle proof of the account and of its storage keys at the given
+// block. The proofs of the blocks behind the trie are built by unwinding it in memory, as long as the node keeps
+// the state history of the block.
+func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", blockNum)
+	}
+	keys := make([]common.Hash, len(storageKeys))
+	for i, key := range storageKeys {
+		keys[i] = common.HexToHash(key)
+	}
+	proof, err := stagedsync.GenerateAccountProof(ctx, tx, header, address, keys, api.tmpDir, GetProofMaxUnwind, GetProofMaxMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ethapi.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(proof.Proof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     trie.EmptyCodeHash,
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: make([]ethapi.StorageResult, len(storageKeys)),
+	}
+	if acc := proof.Account; acc != nil {
+		res.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		res.CodeHash = acc.CodeHash
+		res.Nonce = hexutil.Uint64(acc.Nonce)
+		res.StorageHash = acc.Root
+	}
+	for i, key := range storageKeys {
+		res.StorageProof[i] = ethapi.StorageResult{
+			Key:   key,
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(proof.Storage[i].Value)),
+			Proof: toHexSlice(proof.Storage[i].Proof),
+		}
+	}
+	return res, nil
+}
+
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
 }
 
 // accessListResult returns an optional accesslist
@@ -348,84 +413,120 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi.CallArgs,
 	// lists and we'll need to reestimate every time
 	nogas := args.Gas == nil
 
+	if args.From == nil {
+		args.From = &common.Address{}
+	}
 	var to common.Address
 	if args.To != nil {
 		to = *args.To
 	} else {
 		// Require nonce to calculate address of created contract
 		if args.Nonce == nil {
-			var nonce uint64
-			reply, err := api.txPool.Nonce(ctx, &txpool_proto.NonceRequest{
-				Address: gointerfaces.ConvertAddressToH160(*args.From),
-			}, &grpc.EmptyCallOption{})
+			nonce, err := api.nextNonce(ctx, *args.From, stateReader)
 			if err != nil {
 				return nil, err
 			}
-			if reply.Found {
-				nonce = reply.Nonce + 1
-			}
 			args.Nonce = (*hexutil.Uint64)(&nonce)
 		}
 		to = crypto.CreateAddress(*args.From, uint64(*args.Nonce))
 	}
 
 	// Retrieve the precompiles since they don't need to be added to the access list
-	precompiles := vm.ActivePrecompiles(chainConfig.Rules(blockNumber))
-
-	// Create an initial tracer
-	prevTracer := logger.NewAccessListTracer(nil, *args.From, to, precompiles)
-	if args.AccessList != nil {
-		prevTracer = logger.NewAccessListTracer(*args.AccessList, *args.From, to, precompiles)
+	precompiles := vm.ActivePrecompiles(chainConfig.Rules(blockNumber), blockNumber)
+	var baseFee *uint256.Int
+	if header.BaseFee != nil {
+		var overflow bool
+		if baseFee, overflow = uint256.FromBig(header.BaseFee); overflow {
+			return nil, fmt.Errorf("header.BaseFee uint256 overflow")
+		}
 	}
-	for {
-		state := state.New(stateReader)
-		// Retrieve the current access list to expand
-		accessList := prevTracer.AccessList()
-		log.Trace("Creating access list", "input", accessList)
 
+	// Apply the transaction with the access list, tracing the accesses it makes
+	apply := func(accessList types.AccessList) (*core.ExecutionResult, *logger.AccessListTracer, error) {
 		// If no gas amount was specified, each unique access list needs it's own
 		// gas calculation. This is quite expensive, but we need to be accurate
 		// and it's convered by the sender only anyway.
 		if nogas {
 			args.Gas = nil
 		}
-		// Set the accesslist to the last al
 		args.AccessList = &accessList
-		baseFee, _ := uint256.FromBig(header.BaseFee)
 		msg, err := args.ToMessage(api.GasCap, baseFee)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		// Apply the transaction with the access list tracer
 		tracer := logger.NewAccessListTracer(accessList, *args.From, to, precompiles)
 		config := vm.Config{Tracer: tracer, Debug: true, NoBaseFee: true}
 		blockCtx, txCtx := transactions.GetEvmContext(msg, header, bNrOrHash.RequireCanonical, tx, contractHasTEVM, api._blockReader)
 
-		evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, config)
+		evm := vm.NewEVM(blockCtx, txCtx, state.New(stateReader), chainConfig, config)
 		gp := new(core.GasPool).AddGas(msg.Gas())
 		res, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res, tracer, nil
+	}
+
+	// Create an initial tracer
+	prevTracer := logger.NewAccessListTracer(nil, *args.From, to, precompiles)
+	if args.AccessList != nil {
+		prevTracer = logger.NewAccessListTracer(*args.AccessList, *args.From, to, precompiles)
+	}
+	for {
+		// Retrieve the current access list to expand
+		accessList := prevTracer.AccessList()
+		log.Trace("Creating access list", "input", accessList)
+		res, tracer, err := apply(accessList)
 		if err != nil {
 			return nil, err
 		}
-		if tracer.Equal(prevTracer) {
-			var errString string
-			if res.Err != nil {
-				errString = res.Err.Error()
-			}
-			accessList := &accessListResult{Accesslist: &accessList, Error: errString, GasUsed: hexutil.Uint64(res.UsedGas)}
-			if optimizeGas != nil && *optimizeGas {
-				optimizeToInAccessList(accessList, to)
+		if !tracer.Equal(prevTracer) {
+			prevTracer = tracer
+			continue
+		}
+		result := &accessListResult{Accesslist: &accessList}
+		// The gas used is the one of the list returned, so the transaction is executed again if the list is optimized
+		if optimizeGas != nil && *optimizeGas && optimizeToInAccessList(result, to) {
+			if res, _, err = apply(*result.Accesslist); err != nil {
+				return nil, err
 			}
-			return accessList, nil
 		}
-		prevTracer = tracer
+		if res.Err != nil {
+			result.Error = res.Err.Error()
+		}
+		result.GasUsed = hexutil.Uint64(res.UsedGas)
+		return result, nil
+	}
+}
+
+// nextNonce returns the nonce of the next transaction of the address: the one following its transactions
+// in the pool, or the one of its account.
+func (api *APIImpl) nextNonce(ctx context.Context, address common.Address, stateReader state.StateReader) (uint64, error) {
+	if api.txPool != nil {
+		reply, err := api.txPool.Nonce(ctx, &txpool_proto.NonceRequest{
+			Address: gointerfaces.ConvertAddressToH160(address),
+		}, &grpc.EmptyCallOption{})
+		if err != nil {
+			return 0, err
+		}
+		if reply.Found {
+			return reply.Nonce + 1, nil
+		}
+	}
+	acc, err := stateReader.ReadAccountData(address)
+	if err != nil {
+		return 0, err
+	}
+	if acc == nil {
+		return 0, nil
 	}
+	return acc.Nonce, nil
 }
 
 // to address is warm already, so we can save by adding it to the access list
-// only if we are adding a lot of its storage slots as well
-func optimizeToInAccessList(accessList *accessListResult, to common.Address) {
+// only if we are adding a lot of its storage slots as well. Returns whether it was removed from the list.
+func optimizeToInAccessList(accessList *accessListResult, to common.Address) bool {
 	indexToRemove := -1
 
 	for i := 0; i < len(*accessList.Accesslist); i++ {
@@ -445,7 +546,9 @@ func optimizeToInAccessList(accessList *accessListResult, to common.Address) {
 
 	if indexToRemove >= 0 {
 		*accessList.Accesslist = removeIndex(*accessList.Accesslist, indexToRemove)
+		return true
 	}
+	return false
 }
 
 func removeIndex(s types.AccessList, index int) types.AccessList {
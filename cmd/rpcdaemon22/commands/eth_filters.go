@@ -5,11 +5,14 @@ import (
 
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/time/rate"
 )
 
 // NewPendingTransactionFilter new transaction filter
@@ -178,8 +181,13 @@ func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	return rpcSub, nil
 }
 
-// NewPendingTransactions send a notification each time a new (header) block is appended to the chain.
-func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// pendingTxsLimiterKey is the connection value of the rate limiter shared by the newPendingTransactions
+// subscriptions of a connection.
+type pendingTxsLimiterKey struct{}
+
+// NewPendingTransactions send a notification each time a transaction is added to the txpool: its hash, or the
+// transaction itself if fullTx is true. The transactions over the PendingTxsRate of the connection are dropped.
+func (api *APIImpl) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	if api.filters == nil {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
@@ -187,27 +195,58 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	var limiter *rate.Limiter
+	if api.PendingTxsRate > 0 {
+		limiter = notifier.ConnectionValue(pendingTxsLimiterKey{}, func() interface{} {
+			return rate.NewLimiter(rate.Limit(api.PendingTxsRate), api.PendingTxsRate)
+		}).(*rate.Limiter)
+	}
+	full := fullTx != nil && *fullTx
 
 	rpcSub := notifier.CreateSubscription()
 
+	// the transactions added once the subscription is returned are notified
+	txsCh := make(chan []types.Transaction, 1)
+	id := api.filters.SubscribePendingTxs(txsCh)
+
 	go func() {
 		defer debug.LogPanic()
-		txsCh := make(chan []types.Transaction, 1)
-		id := api.filters.SubscribePendingTxs(txsCh)
 		defer api.filters.UnsubscribePendingTxs(id)
 
 		for {
 			select {
 			case txs, ok := <-txsCh:
+				var dropped int
+				var current *types.Header
+				var cc *params.ChainConfig
+				if full && len(txs) > 0 {
+					var err error
+					if current, cc, err = api.pendingTxsContext(); err != nil {
+						log.Warn("error while reading the head of the pending transactions", "err", err)
+						return
+					}
+				}
 				for _, t := range txs {
-					if t != nil {
-						err := notifier.Notify(rpcSub.ID, t.Hash())
-						if err != nil {
-							log.Warn("error while notifying subscription", "err", err)
-							return
-						}
+					if t == nil {
+						continue
+					}
+					// the filters don't wait for the slow subscribers, so the limit drops instead of waiting too
+					if limiter != nil && !limiter.Allow() {
+						dropped++
+						continue
+					}
+					var payload interface{} = t.Hash()
+					if full {
+						payload = newRPCPendingTransaction(t, current, cc)
+					}
+					if err := notifier.Notify(rpcSub.ID, payload); err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+						return
 					}
 				}
+				if dropped > 0 {
+					log.Debug("pending transactions over the rate limit of the connection dropped", "subscription", rpcSub.ID, "dropped", dropped)
+				}
 				if !ok {
 					log.Warn("new pending transactions channel was closed")
 					return
@@ -221,6 +260,20 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 	return rpcSub, nil
 }
 
+// pendingTxsContext returns the head and the chain config the pending transactions are represented with.
+func (api *APIImpl) pendingTxsContext() (*types.Header, *params.ChainConfig, error) {
+	tx, err := api.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rawdb.ReadCurrentHeader(tx), cc, nil
+}
+
 // Logs send a notification each time a new log appears.
 func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
 	if api.filters == nil {
@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
@@ -12,6 +14,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 )
 
@@ -112,8 +115,7 @@ func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
-	tipcap, err := oracle.SuggestTipCap(ctx)
+	tipcap, err := api.gasPriceOracle(tx, cc).SuggestTipCap(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -134,14 +136,21 @@ func (api *APIImpl) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, err
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
-	tipcap, err := oracle.SuggestTipCap(ctx)
+	tipcap, err := api.gasPriceOracle(tx, cc).SuggestTipCap(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return (*hexutil.Big)(tipcap), err
 }
 
+// gasPriceOracle returns the oracle of eth_gasPrice and eth_maxPriorityFeePerGas, configured by the gpo flags and
+// aware of the txpool for its txpool strategy.
+func (api *APIImpl) gasPriceOracle(tx kv.Tx, cc *params.ChainConfig) *gasprice.Oracle {
+	backend := NewGasPriceOracleBackend(tx, cc, api.BaseAPI)
+	backend.txPool = api.txPool
+	return gasprice.NewOracle(backend, api.GPO, api.gasPriceCache)
+}
+
 type feeHistoryResult struct {
 	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
@@ -159,7 +168,7 @@ func (api *APIImpl) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex,
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
+	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO, nil)
 
 	oldest, reward, baseFee, gasUsed, err := oracle.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
@@ -191,6 +200,7 @@ type GasPriceOracleBackend struct {
 	tx      kv.Tx
 	cc      *params.ChainConfig
 	baseApi *BaseAPI
+	txPool  txpool.TxpoolClient // nil if the pending transactions aren't considered
 }
 
 func NewGasPriceOracleBackend(tx kv.Tx, cc *params.ChainConfig, baseApi *BaseAPI) *GasPriceOracleBackend {
@@ -216,6 +226,30 @@ func (b *GasPriceOracleBackend) ChainConfig() *params.ChainConfig {
 func (b *GasPriceOracleBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	return rawdb.ReadReceiptsByHash(b.tx, hash)
 }
+func (b *GasPriceOracleBackend) TipSummary(ctx context.Context, number uint64) (types.TipSummary, error) {
+	return rawdb.ReadTipSummary(b.tx, number)
+}
 func (b *GasPriceOracleBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return nil, nil
 }
+func (b *GasPriceOracleBackend) PendingTransactions(ctx context.Context) ([]types.Transaction, error) {
+	if b.txPool == nil {
+		return nil, nil
+	}
+	reply, err := b.txPool.All(ctx, &txpool.AllRequest{})
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]types.Transaction, 0, len(reply.Txs))
+	for _, t := range reply.Txs {
+		if t.TxnType != txpool.AllReply_PENDING {
+			continue
+		}
+		txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(t.RlpTx), 0))
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, txn)
+	}
+	return txs, nil
+}
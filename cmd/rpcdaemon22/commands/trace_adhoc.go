@@ -22,6 +22,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/shards"
@@ -1217,8 +1218,48 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 	return results, nil
 }
 
-// RawTransaction implements trace_rawTransaction.
-func (api *TraceAPIImpl) RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_rawTransaction")
+// RawTransaction implements trace_rawTransaction. Traces a signed transaction on top of the state of the given block,
+// latest by default.
+func (api *TraceAPIImpl) RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, parentNrOrHash *rpc.BlockNumberOrHash) (*TraceCallResult, error) {
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
+	if err != nil {
+		return nil, err
+	}
+
+	dbtx, err := api.kv.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	if parentNrOrHash == nil {
+		var num = rpc.LatestBlockNumber
+		parentNrOrHash = &rpc.BlockNumberOrHash{BlockNumber: &num}
+	}
+	blockNumber, hash, _, err := rpchelper.GetBlockNumber(*parentNrOrHash, dbtx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	parentHeader, err := api._blockReader.Header(ctx, dbtx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if parentHeader == nil {
+		return nil, fmt.Errorf("parent header %d(%x) not found", blockNumber, hash)
+	}
+
+	msg, err := txn.AsMessage(*types.MakeSigner(chainConfig, blockNumber), parentHeader.BaseFee, chainConfig.Rules(blockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("convert tx into msg: %w", err)
+	}
+	txHash := txn.Hash()
+	traces, err := api.doCallMany(ctx, dbtx, []types.Message{msg}, []TraceCallParam{{txHash: &txHash, traceTypes: traceTypes}}, parentNrOrHash, nil, false /* gasBailout */, -1 /* all tx indices */)
+	if err != nil {
+		return nil, err
+	}
+	return traces[0], nil
 }
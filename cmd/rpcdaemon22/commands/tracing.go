@@ -230,6 +230,13 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 			return fmt.Errorf("header.BaseFee uint256 overflow")
 		}
 	}
+	if config != nil && config.BlockOverrides != nil && config.BlockOverrides.BaseFee != nil {
+		var overflow bool
+		baseFee, overflow = uint256.FromBig(config.BlockOverrides.BaseFee.ToInt())
+		if overflow {
+			return fmt.Errorf("blockOverrides.BaseFee uint256 overflow")
+		}
+	}
 	msg, err := args.ToMessage(api.GasCap, baseFee)
 	if err != nil {
 		return err
@@ -240,6 +247,11 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 		contractHasTEVM = ethdb.GetHasTEVM(dbtx)
 	}
 	blockCtx, txCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, dbtx, contractHasTEVM, api._blockReader)
+	if config != nil && config.BlockOverrides != nil {
+		if err := config.BlockOverrides.Override(&blockCtx); err != nil {
+			return err
+		}
+	}
 	// Trace the transaction and return
 	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream)
 }
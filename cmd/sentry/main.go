@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
@@ -32,6 +33,9 @@ var (
 	maxPeers     int
 	maxPendPeers int
 	healthCheck  bool
+
+	peerBanThreshold int
+	peerBanTTL       time.Duration
 )
 
 func init() {
@@ -50,6 +54,8 @@ func init() {
 	rootCmd.Flags().IntVar(&maxPeers, utils.MaxPeersFlag.Name, utils.MaxPeersFlag.Value, utils.MaxPeersFlag.Usage)
 	rootCmd.Flags().IntVar(&maxPendPeers, utils.MaxPendingPeersFlag.Name, utils.MaxPendingPeersFlag.Value, utils.MaxPendingPeersFlag.Usage)
 	rootCmd.Flags().BoolVar(&healthCheck, utils.HealthCheckFlag.Name, false, utils.HealthCheckFlag.Usage)
+	rootCmd.Flags().IntVar(&peerBanThreshold, utils.PeerBanThresholdFlag.Name, utils.PeerBanThresholdFlag.Value, utils.PeerBanThresholdFlag.Usage)
+	rootCmd.Flags().DurationVar(&peerBanTTL, utils.PeerBanTTLFlag.Name, utils.PeerBanTTLFlag.Value, utils.PeerBanTTLFlag.Usage)
 
 	if err := rootCmd.MarkFlagDirname(utils.DataDirFlag.Name); err != nil {
 		panic(err)
@@ -88,6 +94,8 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		p2pConfig.PeerBanThreshold = peerBanThreshold
+		p2pConfig.PeerBanTTL = peerBanTTL
 
 		return sentry.Sentry(cmd.Context(), dirs, sentryAddr, discoveryDNS, p2pConfig, uint(p), healthCheck)
 	},
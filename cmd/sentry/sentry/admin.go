@@ -0,0 +1,215 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+// Methods of sentry called by the admin RPC
+
+// ethPeerInfo is the eth protocol detail of a peer, reported by admin_peers
+type ethPeerInfo struct {
+	Version    uint     `json:"version"`
+	Difficulty *big.Int `json:"difficulty"`
+	Head       string   `json:"head"`
+	Height     uint64   `json:"height"` // the highest block the peer is known to have
+}
+
+// peerLists are the static and the trusted peers added by the admin RPC, persisted across restarts
+type peerLists struct {
+	Static  []string `json:"static"`
+	Trusted []string `json:"trusted"`
+}
+
+// loadPeerLists adds the static and trusted peers persisted by the admin RPC to the config of the p2p server
+func (ss *GrpcServer) loadPeerLists() error {
+	if ss.peerListsPath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(ss.peerListsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, &ss.peerLists); err != nil {
+		return fmt.Errorf("peer lists %s: %w", ss.peerListsPath, err)
+	}
+	for _, url := range ss.peerLists.Static {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return fmt.Errorf("peer lists %s: %w", ss.peerListsPath, err)
+		}
+		ss.p2p.StaticNodes = append(ss.p2p.StaticNodes, node)
+	}
+	for _, url := range ss.peerLists.Trusted {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return fmt.Errorf("peer lists %s: %w", ss.peerListsPath, err)
+		}
+		ss.p2p.TrustedNodes = append(ss.p2p.TrustedNodes, node)
+	}
+	return nil
+}
+
+func (ss *GrpcServer) savePeerLists() error {
+	if ss.peerListsPath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(&ss.peerLists, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ss.peerListsPath + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ss.peerListsPath)
+}
+
+// updatePeerList adds the node to the static or trusted peers, or removes it from them, in the running p2p server,
+// or in its config if it isn't started yet, and in the persisted lists
+func (ss *GrpcServer) updatePeerList(url string, trusted, add bool) error {
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return fmt.Errorf("invalid enode: %w", err)
+	}
+	ss.lock.Lock()
+	list, configured := &ss.peerLists.Static, &ss.p2p.StaticNodes
+	if trusted {
+		list, configured = &ss.peerLists.Trusted, &ss.p2p.TrustedNodes
+	}
+	*configured = updateNodes(*configured, node, add)
+	*list = updateURLs(*list, node, add)
+	err = ss.savePeerLists()
+	srv := ss.P2pServer
+	ss.lock.Unlock()
+	if srv != nil {
+		switch {
+		case trusted && add:
+			srv.AddTrustedPeer(node)
+		case trusted:
+			srv.RemoveTrustedPeer(node)
+		case add:
+			srv.AddPeer(node)
+		default:
+			srv.RemovePeer(node)
+		}
+	}
+	return err
+}
+
+func updateNodes(nodes []*enode.Node, node *enode.Node, add bool) []*enode.Node {
+	updated := make([]*enode.Node, 0, len(nodes)+1)
+	for _, n := range nodes {
+		if n.ID() != node.ID() {
+			updated = append(updated, n)
+		}
+	}
+	if add {
+		updated = append(updated, node)
+	}
+	return updated
+}
+
+func updateURLs(urls []string, node *enode.Node, add bool) []string {
+	updated := make([]string, 0, len(urls)+1)
+	for _, url := range urls {
+		if n, err := enode.Parse(enode.ValidSchemes, url); err != nil || n.ID() != node.ID() {
+			updated = append(updated, url)
+		}
+	}
+	if add {
+		updated = append(updated, node.URLv4())
+	}
+	return updated
+}
+
+// AddPeer adds the node to the static peers, which are kept connected
+func (ss *GrpcServer) AddPeer(_ context.Context, url string) error {
+	return ss.updatePeerList(url, false /* trusted */, true /* add */)
+}
+
+// RemovePeer removes the node from the static peers, and disconnects it
+func (ss *GrpcServer) RemovePeer(_ context.Context, url string) error {
+	return ss.updatePeerList(url, false /* trusted */, false /* add */)
+}
+
+// AddTrustedPeer adds the node to the trusted peers, which are always allowed to connect, even above the peer limit
+func (ss *GrpcServer) AddTrustedPeer(_ context.Context, url string) error {
+	return ss.updatePeerList(url, true /* trusted */, true /* add */)
+}
+
+// RemoveTrustedPeer removes the node from the trusted peers
+func (ss *GrpcServer) RemoveTrustedPeer(_ context.Context, url string) error {
+	return ss.updatePeerList(url, true /* trusted */, false /* add */)
+}
+
+// PeersInfo returns the connected peers, with the detail of their protocols
+func (ss *GrpcServer) PeersInfo(context.Context) ([]*p2p.PeerInfo, error) {
+	ss.lock.RLock()
+	srv := ss.P2pServer
+	ss.lock.RUnlock()
+	if srv == nil {
+		return nil, errors.New("p2p server was not started")
+	}
+	return srv.PeersInfo(), nil
+}
+
+// PeerScores implements sentryadmin.Admin
+func (ss *GrpcServer) PeerScores(context.Context) ([]sentryadmin.PeerReputation, error) {
+	return ss.reputation.Peers(), nil
+}
+
+// AdjustPeerScore implements sentryadmin.Admin
+func (ss *GrpcServer) AdjustPeerScore(_ context.Context, peer string, delta int) (*sentryadmin.PeerReputation, error) {
+	peerID, err := ParsePeerID(peer)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err = ss.reputation.Adjust(peerID, delta); err != nil {
+		return nil, err
+	}
+	reputation := ss.reputation.Peer(peerID)
+	return &reputation, nil
+}
+
+// UnbanPeer implements sentryadmin.Admin
+func (ss *GrpcServer) UnbanPeer(_ context.Context, peer string) (*sentryadmin.PeerReputation, error) {
+	peerID, err := ParsePeerID(peer)
+	if err != nil {
+		return nil, err
+	}
+	if err = ss.reputation.Unban(peerID); err != nil {
+		return nil, err
+	}
+	reputation := ss.reputation.Peer(peerID)
+	return &reputation, nil
+}
+
+// ethPeerInfo returns the eth protocol detail of the peer, nil before its handshake
+func (ss *GrpcServer) ethPeerInfo(peerID [64]byte) interface{} {
+	peerInfo := ss.getPeer(peerID)
+	if peerInfo == nil {
+		return nil
+	}
+	status := peerInfo.Status()
+	if status == nil {
+		return nil
+	}
+	return &ethPeerInfo{
+		Version:    uint(status.ProtocolVersion),
+		Difficulty: status.TD,
+		Head:       status.Head.Hex(),
+		Height:     peerInfo.Height(),
+	}
+}
@@ -0,0 +1,67 @@
+package sentry
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerLists(t *testing.T) {
+	nodeDB := filepath.Join(t.TempDir(), "eth66")
+	newServer := func() (*GrpcServer, *p2p.Config) {
+		cfg := &p2p.Config{NodeDatabase: nodeDB}
+		return NewGrpcServer(context.Background(), nil, func() *eth.NodeInfo { return nil }, cfg, eth.ETH66), cfg
+	}
+	url := func() string {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		return enode.NewV4(&key.PublicKey, nil, 30303, 30303).URLv4()
+	}
+	static1, static2, trusted := url(), url(), url()
+	ctx := context.Background()
+
+	ss, cfg := newServer()
+	require.NoError(t, ss.AddPeer(ctx, static1))
+	require.NoError(t, ss.AddPeer(ctx, static2))
+	require.NoError(t, ss.AddPeer(ctx, static2))
+	require.NoError(t, ss.AddTrustedPeer(ctx, trusted))
+	require.Len(t, cfg.StaticNodes, 2)
+	require.Len(t, cfg.TrustedNodes, 1)
+	require.Error(t, ss.AddPeer(ctx, "enode://invalid"))
+
+	// the peers are added to the config of the p2p server after a restart
+	ss, cfg = newServer()
+	require.Len(t, cfg.StaticNodes, 2)
+	require.Equal(t, static1, cfg.StaticNodes[0].URLv4())
+	require.Equal(t, []string{trusted}, ss.peerLists.Trusted)
+	require.NoError(t, ss.RemovePeer(ctx, static1))
+	require.NoError(t, ss.RemoveTrustedPeer(ctx, trusted))
+
+	_, cfg = newServer()
+	require.Len(t, cfg.StaticNodes, 1)
+	require.Equal(t, static2, cfg.StaticNodes[0].URLv4())
+	require.Len(t, cfg.TrustedNodes, 0)
+}
+
+func TestEthPeerInfo(t *testing.T) {
+	ss := &GrpcServer{p2p: &p2p.Config{}}
+	peerID := [64]byte{1}
+	require.Nil(t, ss.ethPeerInfo(peerID))
+
+	peerInfo := NewPeerInfo(nil, nil)
+	defer peerInfo.Close()
+	ss.GoodPeers.Store(peerID, peerInfo)
+	require.Nil(t, ss.ethPeerInfo(peerID))
+
+	peerInfo.setStatus(&eth.StatusPacket{ProtocolVersion: eth.ETH66, TD: big.NewInt(17), Head: common.Hash{2}})
+	peerInfo.SetIncreasedHeight(5)
+	require.Equal(t, &ethPeerInfo{Version: eth.ETH66, Difficulty: big.NewInt(17), Head: common.Hash{2}.Hex(), Height: 5}, ss.ethPeerInfo(peerID))
+}
@@ -0,0 +1,338 @@
+package sentry
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// PeerEvent is a misbehaviour of a peer, lowering its score
+type PeerEvent int
+
+const (
+	InvalidResponse   PeerEvent = iota // a response penalized by the core, e.g. a bad header or block
+	ProtocolViolation                  // a message breaking the eth protocol
+	Timeout                            // requests, or the handshake, not answered before their deadline
+)
+
+func (e PeerEvent) String() string {
+	switch e {
+	case InvalidResponse:
+		return "InvalidResponse"
+	case ProtocolViolation:
+		return "ProtocolViolation"
+	case Timeout:
+		return "Timeout"
+	default:
+		return fmt.Sprintf("PeerEvent(%d)", int(e))
+	}
+}
+
+// ReputationConfig sets the score adjustments of the peer events, and the banning of the peers whose score
+// drops to the threshold
+type ReputationConfig struct {
+	InvalidResponse   int
+	ProtocolViolation int
+	Timeout           int
+
+	BanThreshold int
+	BanTTL       time.Duration
+	// Recovery is the time in which a negative score recovers by one point
+	Recovery time.Duration
+	// BanListPath is the file the bans are persisted to, they are only kept in memory if it's empty
+	BanListPath string
+}
+
+var DefaultReputationConfig = ReputationConfig{
+	InvalidResponse:   -25,
+	ProtocolViolation: -50,
+	Timeout:           -1,
+	BanThreshold:      -100,
+	BanTTL:            time.Hour,
+	Recovery:          time.Minute,
+}
+
+func (cfg ReputationConfig) adjustment(event PeerEvent) int {
+	switch event {
+	case InvalidResponse:
+		return cfg.InvalidResponse
+	case ProtocolViolation:
+		return cfg.ProtocolViolation
+	case Timeout:
+		return cfg.Timeout
+	default:
+		return 0
+	}
+}
+
+// maxScoredPeers is the number of scores above which the recovered ones are dropped
+const maxScoredPeers = 10_000
+
+type peerScore struct {
+	score   int
+	updated time.Time
+}
+
+// current is the score recovered since its last update
+func (s peerScore) current(now time.Time, recovery time.Duration) int {
+	if s.score >= 0 || recovery <= 0 {
+		return s.score
+	}
+	if recovered := now.Sub(s.updated) / recovery; recovered < time.Duration(-s.score) {
+		return s.score + int(recovered)
+	}
+	return 0
+}
+
+// Reputation keeps the scores of the peers, lowered by their misbehaviours, and bans the peers whose score drops
+// to the threshold for a while. Scores recover over time, so that only the peers misbehaving often get banned.
+type Reputation struct {
+	cfg    ReputationConfig
+	lock   sync.Mutex
+	scores map[[64]byte]peerScore
+	bans   map[[64]byte]time.Time
+	onBan  func(peerID [64]byte) // disconnects the banned peer
+}
+
+// NewReputation creates the reputation of the peers, loading the bans persisted to cfg.BanListPath
+func NewReputation(cfg ReputationConfig) (*Reputation, error) {
+	r := &Reputation{cfg: cfg, scores: map[[64]byte]peerScore{}, bans: map[[64]byte]time.Time{}}
+	if cfg.BanListPath == "" {
+		return r, nil
+	}
+	b, err := os.ReadFile(cfg.BanListPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+	var bans map[string]time.Time
+	if err = json.Unmarshal(b, &bans); err != nil {
+		return r, fmt.Errorf("ban list %s: %w", cfg.BanListPath, err)
+	}
+	now := time.Now()
+	for id, until := range bans {
+		peerID, err := ParsePeerID(id)
+		if err != nil {
+			return r, fmt.Errorf("ban list %s: %w", cfg.BanListPath, err)
+		}
+		if until.After(now) {
+			r.bans[peerID] = until
+		}
+	}
+	return r, nil
+}
+
+// Penalize lowers the score of the peer for the event, and returns whether the peer is banned
+func (r *Reputation) Penalize(peerID [64]byte, event PeerEvent) bool {
+	_, banned, err := r.Adjust(peerID, r.cfg.adjustment(event))
+	if err != nil {
+		log.Warn("Could not persist the ban list", "err", err)
+	}
+	return banned
+}
+
+// Adjust changes the score of the peer by delta, banning it if the score drops to the threshold.
+// It returns the new score, which is reset by the ban.
+func (r *Reputation) Adjust(peerID [64]byte, delta int) (score int, banned bool, err error) {
+	score, banned, newBan, err := r.adjust(peerID, delta)
+	if newBan && r.onBan != nil {
+		r.onBan(peerID)
+	}
+	return score, banned, err
+}
+
+func (r *Reputation) adjust(peerID [64]byte, delta int) (score int, banned, newBan bool, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	if r.banned(peerID, now) {
+		return 0, true, false, nil
+	}
+	score = r.scores[peerID].current(now, r.cfg.Recovery) + delta
+	if score > r.cfg.BanThreshold {
+		if score == 0 {
+			delete(r.scores, peerID)
+		} else {
+			r.scores[peerID] = peerScore{score: score, updated: now}
+		}
+		if len(r.scores) > maxScoredPeers {
+			r.prune(now)
+		}
+		return score, false, false, nil
+	}
+	delete(r.scores, peerID)
+	r.bans[peerID] = now.Add(r.cfg.BanTTL)
+	log.Debug("Banned peer", "id", peerID, "until", r.bans[peerID])
+	return 0, true, true, r.save(now)
+}
+
+// Ban bans the peer for ttl
+func (r *Reputation) Ban(peerID [64]byte, ttl time.Duration) error {
+	r.lock.Lock()
+	now := time.Now()
+	delete(r.scores, peerID)
+	r.bans[peerID] = now.Add(ttl)
+	err := r.save(now)
+	r.lock.Unlock()
+	if r.onBan != nil {
+		r.onBan(peerID)
+	}
+	return err
+}
+
+// Unban lifts the ban of the peer, and resets its score
+func (r *Reputation) Unban(peerID [64]byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.scores, peerID)
+	if _, ok := r.bans[peerID]; !ok {
+		return nil
+	}
+	delete(r.bans, peerID)
+	return r.save(time.Now())
+}
+
+// Banned returns whether the peer is banned
+func (r *Reputation) Banned(peerID [64]byte) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.banned(peerID, time.Now())
+}
+
+func (r *Reputation) banned(peerID [64]byte, now time.Time) bool {
+	until, ok := r.bans[peerID]
+	if ok && !until.After(now) {
+		delete(r.bans, peerID)
+		return false
+	}
+	return ok
+}
+
+// Score returns the current score of the peer
+func (r *Reputation) Score(peerID [64]byte) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.scores[peerID].current(time.Now(), r.cfg.Recovery)
+}
+
+// Peers returns the reputation of the peers with a non zero score, and of the banned peers, the lowest scores first
+func (r *Reputation) Peers() []sentryadmin.PeerReputation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	r.prune(now)
+	peers := make([]sentryadmin.PeerReputation, 0, len(r.scores)+len(r.bans))
+	for peerID, s := range r.scores {
+		peers = append(peers, newPeerReputation(peerID, s.current(now, r.cfg.Recovery), nil))
+	}
+	for peerID, until := range r.bans {
+		until := until
+		peers = append(peers, newPeerReputation(peerID, 0, &until))
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if (peers[i].BannedUntil != nil) != (peers[j].BannedUntil != nil) {
+			return peers[i].BannedUntil != nil
+		}
+		if peers[i].Score != peers[j].Score {
+			return peers[i].Score < peers[j].Score
+		}
+		return peers[i].Pubkey < peers[j].Pubkey
+	})
+	return peers
+}
+
+// Peer returns the reputation of the peer
+func (r *Reputation) Peer(peerID [64]byte) sentryadmin.PeerReputation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	if r.banned(peerID, now) {
+		until := r.bans[peerID]
+		return newPeerReputation(peerID, 0, &until)
+	}
+	return newPeerReputation(peerID, r.scores[peerID].current(now, r.cfg.Recovery), nil)
+}
+
+func newPeerReputation(peerID [64]byte, score int, bannedUntil *time.Time) sentryadmin.PeerReputation {
+	return sentryadmin.PeerReputation{
+		ID:          enode.ID(crypto.Keccak256Hash(peerID[:])),
+		Pubkey:      hex.EncodeToString(peerID[:]),
+		Score:       score,
+		BannedUntil: bannedUntil,
+	}
+}
+
+// prune drops the recovered scores and the expired bans
+func (r *Reputation) prune(now time.Time) {
+	for peerID, s := range r.scores {
+		if s.current(now, r.cfg.Recovery) == 0 {
+			delete(r.scores, peerID)
+		}
+	}
+	for peerID, until := range r.bans {
+		if !until.After(now) {
+			delete(r.bans, peerID)
+		}
+	}
+}
+
+// save persists the bans, which aren't expired, to the ban list
+func (r *Reputation) save(now time.Time) error {
+	if r.cfg.BanListPath == "" {
+		return nil
+	}
+	bans := make(map[string]time.Time, len(r.bans))
+	for peerID, until := range r.bans {
+		if until.After(now) {
+			bans[hex.EncodeToString(peerID[:])] = until
+		}
+	}
+	b, err := json.MarshalIndent(bans, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.cfg.BanListPath + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.cfg.BanListPath)
+}
+
+// ParsePeerID parses the ID of a peer given by its enode URL or by its hex public key
+func ParsePeerID(s string) ([64]byte, error) {
+	var peerID [64]byte
+	if strings.HasPrefix(s, "enode://") {
+		node, err := enode.ParseV4(s)
+		if err != nil {
+			return peerID, err
+		}
+		pubkey := node.Pubkey()
+		if pubkey == nil {
+			return peerID, fmt.Errorf("enode %s without a public key", s)
+		}
+		copy(peerID[:], crypto.MarshalPubkey(pubkey))
+		return peerID, nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return peerID, fmt.Errorf("invalid peer public key %s: %w", s, err)
+	}
+	if len(b) != len(peerID) {
+		return peerID, fmt.Errorf("invalid peer public key %s: %d bytes instead of %d", s, len(b), len(peerID))
+	}
+	copy(peerID[:], b)
+	return peerID, nil
+}
@@ -0,0 +1,87 @@
+package sentry
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReputation(t *testing.T) {
+	cfg := DefaultReputationConfig
+	cfg.BanListPath = filepath.Join(t.TempDir(), "bans.json")
+	r, err := NewReputation(cfg)
+	require.NoError(t, err)
+	var disconnected [][64]byte
+	r.onBan = func(peerID [64]byte) { disconnected = append(disconnected, peerID) }
+
+	good, bad := [64]byte{1}, [64]byte{2}
+	require.False(t, r.Penalize(good, Timeout))
+	require.Equal(t, cfg.Timeout, r.Score(good))
+	require.False(t, r.Penalize(bad, ProtocolViolation))
+	require.True(t, r.Penalize(bad, ProtocolViolation))
+	require.True(t, r.Banned(bad))
+	require.Equal(t, [][64]byte{bad}, disconnected)
+	// penalties of a banned peer don't extend its ban
+	require.True(t, r.Penalize(bad, InvalidResponse))
+	require.Len(t, disconnected, 1)
+
+	peers := r.Peers()
+	require.Len(t, peers, 2)
+	require.Equal(t, hex.EncodeToString(bad[:]), peers[0].Pubkey)
+	require.NotNil(t, peers[0].BannedUntil)
+	require.Equal(t, enode.ID(crypto.Keccak256Hash(good[:])), peers[1].ID)
+	require.Equal(t, cfg.Timeout, peers[1].Score)
+
+	// the bans survive a restart, the scores don't
+	r, err = NewReputation(cfg)
+	require.NoError(t, err)
+	require.True(t, r.Banned(bad))
+	require.Equal(t, 0, r.Score(good))
+	require.NoError(t, r.Unban(bad))
+	r, err = NewReputation(cfg)
+	require.NoError(t, err)
+	require.False(t, r.Banned(bad))
+
+	// the scores can be raised above zero, and lowered until the ban
+	score, banned, err := r.Adjust(good, 10)
+	require.NoError(t, err)
+	require.Equal(t, 10, score)
+	require.False(t, banned)
+	_, banned, err = r.Adjust(good, cfg.BanThreshold-10)
+	require.NoError(t, err)
+	require.True(t, banned)
+
+	// expired bans are lifted
+	require.NoError(t, r.Ban(bad, -time.Second))
+	require.False(t, r.Banned(bad))
+}
+
+func TestScoreRecovery(t *testing.T) {
+	now := time.Now()
+	s := peerScore{score: -10, updated: now}
+	require.Equal(t, -10, s.current(now, time.Minute))
+	require.Equal(t, -7, s.current(now.Add(3*time.Minute+time.Second), time.Minute))
+	require.Equal(t, 0, s.current(now.Add(time.Hour), time.Minute))
+	require.Equal(t, 5, peerScore{score: 5, updated: now}.current(now.Add(time.Hour), time.Minute))
+}
+
+func TestParsePeerID(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	var expected [64]byte
+	copy(expected[:], crypto.MarshalPubkey(&key.PublicKey))
+
+	peerID, err := ParsePeerID(enode.NewV4(&key.PublicKey, nil, 30303, 30303).URLv4())
+	require.NoError(t, err)
+	require.Equal(t, expected, peerID)
+	peerID, err = ParsePeerID("0x" + hex.EncodeToString(expected[:]))
+	require.NoError(t, err)
+	require.Equal(t, expected, peerID)
+	_, err = ParsePeerID(hex.EncodeToString(expected[:32]))
+	require.Error(t, err)
+}
@@ -37,8 +37,8 @@ func (cs *MultiClient) UpdateHead(ctx context.Context, height uint64, hash commo
 }
 
 func (cs *MultiClient) SendBodyRequest(ctx context.Context, req *bodydownload.BodyRequest) (peerID [64]byte, ok bool) {
-	// if sentry not found peers to send such message, try next one. stop if found.
-	for i, ok, next := cs.randSentryIndex(); ok; i, ok = next() {
+	// if sentry not found peers to send such message, or failed, try next one. stop if found.
+	for _, i := range cs.mux.order() {
 		if !cs.sentries[i].Ready() {
 			continue
 		}
@@ -66,21 +66,24 @@ func (cs *MultiClient) SendBodyRequest(ctx context.Context, req *bodydownload.Bo
 
 			sentPeers, err1 := cs.sentries[i].SendMessageByMinBlock(ctx, &outreq, &grpc.EmptyCallOption{})
 			if err1 != nil {
-				log.Error("Could not send block bodies request", "err", err1)
-				return [64]byte{}, false
+				cs.mux.failed(i)
+				log.Warn("Could not send block bodies request", "err", err1)
+				continue
 			}
 			if sentPeers == nil || len(sentPeers.Peers) == 0 {
 				continue
 			}
-			return ConvertH512ToPeerID(sentPeers.Peers[0]), true
+			peerID := ConvertH512ToPeerID(sentPeers.Peers[0])
+			cs.mux.sent(i, peerID)
+			return peerID, true
 		}
 	}
 	return [64]byte{}, false
 }
 
 func (cs *MultiClient) SendHeaderRequest(ctx context.Context, req *headerdownload.HeaderRequest) (peerID [64]byte, ok bool) {
-	// if sentry not found peers to send such message, try next one. stop if found.
-	for i, ok, next := cs.randSentryIndex(); ok; i, ok = next() {
+	// if sentry not found peers to send such message, or failed, try next one. stop if found.
+	for _, i := range cs.mux.order() {
 		if !cs.sentries[i].Ready() {
 			continue
 		}
@@ -115,13 +118,16 @@ func (cs *MultiClient) SendHeaderRequest(ctx context.Context, req *headerdownloa
 			}
 			sentPeers, err1 := cs.sentries[i].SendMessageByMinBlock(ctx, &outreq, &grpc.EmptyCallOption{})
 			if err1 != nil {
-				log.Error("Could not send header request", "err", err1)
-				return [64]byte{}, false
+				cs.mux.failed(i)
+				log.Warn("Could not send header request", "err", err1)
+				continue
 			}
 			if sentPeers == nil || len(sentPeers.Peers) == 0 {
 				continue
 			}
-			return ConvertH512ToPeerID(sentPeers.Peers[0]), true
+			peerID := ConvertH512ToPeerID(sentPeers.Peers[0])
+			cs.mux.sent(i, peerID)
+			return peerID, true
 		}
 	}
 	return [64]byte{}, false
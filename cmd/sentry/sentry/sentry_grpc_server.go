@@ -22,6 +22,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	proto_types "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
@@ -55,6 +56,8 @@ type PeerInfo struct {
 	deadlines []time.Time // Request deadlines
 	height    uint64
 	rw        p2p.MsgReadWriter
+	status    *eth.StatusPacket // sent by the peer in the handshake
+	onTimeout func()            // called when deadlines pass, set before the peer is run
 
 	removed    chan struct{} // close this channel on remove
 	ctx        context.Context
@@ -101,6 +104,19 @@ func (pi *PeerInfo) AddDeadline(deadline time.Time) {
 	pi.deadlines = append(pi.deadlines, deadline)
 }
 
+// Status returns the status the peer sent in the handshake
+func (pi *PeerInfo) Status() *eth.StatusPacket {
+	pi.lock.RLock()
+	defer pi.lock.RUnlock()
+	return pi.status
+}
+
+func (pi *PeerInfo) setStatus(status *eth.StatusPacket) {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	pi.status = status
+}
+
 func (pi *PeerInfo) Height() uint64 {
 	return atomic.LoadUint64(&pi.height)
 }
@@ -121,7 +137,6 @@ func (pi *PeerInfo) SetIncreasedHeight(newHeight uint64) {
 // It returns the number of deadlines left
 func (pi *PeerInfo) ClearDeadlines(now time.Time, givePermit bool) int {
 	pi.lock.Lock()
-	defer pi.lock.Unlock()
 	// Look for the first deadline which is not passed yet
 	firstNotPassed := sort.Search(len(pi.deadlines), func(i int) bool {
 		return pi.deadlines[i].After(now)
@@ -131,7 +146,13 @@ func (pi *PeerInfo) ClearDeadlines(now time.Time, givePermit bool) int {
 		cutOff++
 	}
 	pi.deadlines = pi.deadlines[cutOff:]
-	return len(pi.deadlines)
+	left := len(pi.deadlines)
+	pi.lock.Unlock()
+	// the requests timed out together count once
+	if firstNotPassed > 0 && pi.onTimeout != nil {
+		pi.onTimeout()
+	}
+	return left
 }
 
 func (pi *PeerInfo) Remove() {
@@ -189,7 +210,7 @@ func ConvertH512ToPeerID(h512 *proto_types.H512) [64]byte {
 func makeP2PServer(
 	p2pConfig p2p.Config,
 	genesisHash common.Hash,
-	protocol p2p.Protocol,
+	protocols []p2p.Protocol,
 ) (*p2p.Server, error) {
 	var urls []string
 	chainConfig := params.ChainConfigByGenesisHash(genesisHash)
@@ -204,7 +225,7 @@ func makeP2PServer(
 		p2pConfig.BootstrapNodes = bootstrapNodes
 		p2pConfig.BootstrapNodesV5 = bootstrapNodes
 	}
-	p2pConfig.Protocols = []p2p.Protocol{protocol}
+	p2pConfig.Protocols = protocols
 	return &p2p.Server{Config: p2pConfig}, nil
 }
 
@@ -215,7 +236,7 @@ func handShake(
 	rw p2p.MsgReadWriter,
 	version uint,
 	minVersion uint,
-	startSync func(bestHash common.Hash) error,
+	onStatus func(reply *eth.StatusPacket) error,
 ) error {
 	if status == nil {
 		return fmt.Errorf("could not get status message from core for peer %s connection", peerID)
@@ -241,13 +262,10 @@ func handShake(
 		errc <- p2p.Send(rw, eth.StatusMsg, s)
 	}()
 
+	var reply *eth.StatusPacket
 	go func() {
-		reply, err := readAndValidatePeerStatusMessage(rw, status, version, minVersion)
-
-		if (err == nil) && (startSync != nil) {
-			err = startSync(reply.Head)
-		}
-
+		var err error
+		reply, err = readAndValidatePeerStatusMessage(rw, status, version, minVersion)
 		errc <- err
 	}()
 
@@ -266,6 +284,10 @@ func handShake(
 		}
 	}
 
+	// onStatus may send messages to the peer, which must follow our status
+	if onStatus != nil {
+		return onStatus(reply)
+	}
 	return nil
 }
 
@@ -277,6 +299,7 @@ func runPeer(
 	peerInfo *PeerInfo,
 	send func(msgId proto_sentry.MessageId, peerID [64]byte, b []byte),
 	hasSubscribers func(msgId proto_sentry.MessageId) bool,
+	penalize func(peerID [64]byte, event PeerEvent),
 ) error {
 	printTime := time.Now().Add(time.Minute)
 	peerPrinted := false
@@ -309,6 +332,7 @@ func runPeer(
 		}
 		if msg.Size > eth.ProtocolMaxMsgSize {
 			msg.Discard()
+			penalize(peerID, ProtocolViolation)
 			return fmt.Errorf("message is too large %d, limit %d", msg.Size, eth.ProtocolMaxMsgSize)
 		}
 		givePermit := false
@@ -316,6 +340,7 @@ func runPeer(
 		case eth.StatusMsg:
 			msg.Discard()
 			// Status messages should never arrive after the handshake
+			penalize(peerID, ProtocolViolation)
 			return fmt.Errorf("uncontrolled status message")
 		case eth.GetBlockHeadersMsg:
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
@@ -446,6 +471,7 @@ func runPeer(
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		default:
 			log.Error(fmt.Sprintf("[%s] Unknown message code: %d", peerID, msg.Code))
+			penalize(peerID, ProtocolViolation)
 		}
 		msg.Discard()
 		peerInfo.ClearDeadlines(time.Now(), givePermit)
@@ -467,6 +493,7 @@ func grpcSentryServer(ctx context.Context, sentryAddr string, ss *GrpcServer, he
 	}
 	grpcServer := grpcutil.NewServer(100, nil)
 	proto_sentry.RegisterSentryServer(grpcServer, ss)
+	sentryadmin.RegisterAdminServer(grpcServer, ss)
 	var healthServer *health.Server
 	if healthCheck {
 		healthServer = health.NewServer()
@@ -490,6 +517,27 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 		p2p:          cfg,
 		peersStreams: NewPeersStreams(),
 	}
+	reputationCfg := DefaultReputationConfig
+	if cfg.PeerBanThreshold != 0 {
+		reputationCfg.BanThreshold = cfg.PeerBanThreshold
+	}
+	if cfg.PeerBanTTL != 0 {
+		reputationCfg.BanTTL = cfg.PeerBanTTL
+	}
+	if cfg.NodeDatabase != "" {
+		reputationCfg.BanListPath = cfg.NodeDatabase + "-bans.json"
+	}
+	var err error
+	if ss.reputation, err = NewReputation(reputationCfg); err != nil {
+		log.Warn("Could not load the ban list of the peers", "err", err)
+	}
+	ss.reputation.onBan = ss.removePeer
+	if cfg.NodeDatabase != "" {
+		ss.peerListsPath = cfg.NodeDatabase + "-peers.json"
+	}
+	if err = ss.loadPeerLists(); err != nil {
+		log.Warn("Could not load the static and trusted peers added by the admin RPC", "err", err)
+	}
 
 	if protocol != eth.ETH66 {
 		panic(fmt.Errorf("unexpected p2p protocol: %d", protocol))
@@ -506,18 +554,27 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 				log.Trace(fmt.Sprintf("[%s] Peer already has connection", peerID))
 				return nil
 			}
+			if ss.reputation.Banned(peerID) {
+				log.Trace(fmt.Sprintf("[%s] Peer is banned", peerID))
+				return p2p.DiscUselessPeer
+			}
 			log.Trace(fmt.Sprintf("[%s] Start with peer", peerID))
 
 			peerInfo := NewPeerInfo(peer, rw)
+			peerInfo.onTimeout = func() { ss.penalize(peerID, Timeout) }
 			defer peerInfo.Close()
 
 			defer ss.GoodPeers.Delete(peerID)
-			err := handShake(ctx, ss.GetStatus(), peerID, rw, protocol, protocol, func(bestHash common.Hash) error {
+			err := handShake(ctx, ss.GetStatus(), peerID, rw, protocol, protocol, func(reply *eth.StatusPacket) error {
+				peerInfo.setStatus(reply)
 				ss.GoodPeers.Store(peerID, peerInfo)
 				ss.sendNewPeerToClients(gointerfaces.ConvertHashToH512(peerID))
-				return ss.startSync(ctx, bestHash, peerID)
+				return ss.startSync(ctx, reply.Head, peerID)
 			})
 			if err != nil {
+				if errors.Is(err, p2p.DiscReadTimeout) {
+					ss.penalize(peerID, Timeout)
+				}
 				return fmt.Errorf("handshake to peer %s: %w", peerID, err)
 			}
 			log.Trace(fmt.Sprintf("[%s] Received status message OK", peerID), "name", peer.Name())
@@ -530,6 +587,7 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 				peerInfo,
 				ss.send,
 				ss.hasSubscribers,
+				ss.penalize,
 			) // runPeer never returns a nil error
 			log.Trace(fmt.Sprintf("[%s] Error while running peer: %v", peerID, err))
 			ss.sendGonePeerToClients(gointerfaces.ConvertHashToH512(peerID))
@@ -538,10 +596,7 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 		NodeInfo: func() interface{} {
 			return readNodeInfo()
 		},
-		PeerInfo: func(peerID [64]byte) interface{} {
-			// TODO: remember handshake reply per peer ID and return eth-related Status info (see ethPeerInfo in geth)
-			return nil
-		},
+		PeerInfo: ss.ethPeerInfo,
 		//Attributes: []enr.Entry{eth.CurrentENREntry(chainConfig, genesisHash, headHeight)},
 	}
 
@@ -569,6 +624,7 @@ type GrpcServer struct {
 	proto_sentry.UnimplementedSentryServer
 	ctx                  context.Context
 	Protocol             p2p.Protocol
+	SatelliteProtocols   []p2p.Protocol // run besides the eth protocol, like snap; set before the p2p server starts
 	discoveryDNS         []string
 	GoodPeers            sync.Map
 	statusData           *proto_sentry.StatusData
@@ -580,6 +636,21 @@ type GrpcServer struct {
 	messageStreamsLock   sync.RWMutex
 	peersStreams         *PeersStreams
 	p2p                  *p2p.Config
+	reputation           *Reputation
+	peerLists            peerLists // added by the admin RPC
+	peerListsPath        string
+}
+
+// Reputation returns the scores and the bans of the peers
+func (ss *GrpcServer) Reputation() *Reputation {
+	return ss.reputation
+}
+
+// penalize lowers the score of the peer, which is disconnected if it gets banned
+func (ss *GrpcServer) penalize(peerID [64]byte, event PeerEvent) {
+	if ss.reputation.Penalize(peerID, event) {
+		log.Trace(fmt.Sprintf("[%s] Peer is banned", peerID), "event", event)
+	}
 }
 
 func (ss *GrpcServer) rangePeers(f func(peerInfo *PeerInfo) bool) {
@@ -660,6 +731,8 @@ func (ss *GrpcServer) startSync(ctx context.Context, bestHash common.Hash, peerI
 func (ss *GrpcServer) PenalizePeer(_ context.Context, req *proto_sentry.PenalizePeerRequest) (*emptypb.Empty, error) {
 	//log.Warn("Received penalty", "kind", req.GetPenalty().Descriptor().FullName, "from", fmt.Sprintf("%s", req.GetPeerId()))
 	peerID := ConvertH512ToPeerID(req.PeerId)
+	// the core only penalizes for invalid data, the only kind of penalty is a kick
+	ss.penalize(peerID, InvalidResponse)
 	ss.removePeer(peerID)
 	return &emptypb.Empty{}, nil
 }
@@ -759,25 +832,24 @@ func (ss *GrpcServer) SendMessageToRandomPeers(ctx context.Context, req *proto_s
 		return reply, fmt.Errorf("sendMessageToRandomPeers not implemented for message Id: %s", req.Data.Id)
 	}
 
-	amount := uint64(0)
+	var peers []*PeerInfo
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
-		amount++
+		peers = append(peers, peerInfo)
 		return true
 	})
+	amount := uint64(len(peers))
 	if req.MaxPeers < amount {
 		amount = req.MaxPeers
 	}
 
-	// Send the block to a subset of our peers
+	// Send the block to a subset of our peers, the range order of a small map is far from random
 	sendToAmount := int(math.Sqrt(float64(amount)))
-	i := 0
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
 	var lastErr error
-	ss.rangePeers(func(peerInfo *PeerInfo) bool {
+	for _, peerInfo := range peers[:sendToAmount] {
 		ss.writePeer("sendMessageToRandomPeers", peerInfo, msgcode, req.Data.Data, 0)
 		reply.Peers = append(reply.Peers, gointerfaces.ConvertHashToH512(peerInfo.ID()))
-		i++
-		return i < sendToAmount
-	})
+	}
 	return reply, lastErr
 }
 
@@ -830,7 +902,7 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 			}
 		}
 
-		srv, err := makeP2PServer(*ss.p2p, genesisHash, ss.Protocol)
+		srv, err := makeP2PServer(*ss.p2p, genesisHash, append([]p2p.Protocol{ss.Protocol}, ss.SatelliteProtocols...))
 		if err != nil {
 			return reply, err
 		}
@@ -17,6 +17,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/stretchr/testify/require"
 )
@@ -148,6 +149,54 @@ func testForkIDSplit(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that onStatus runs after the handshake, so that the messages it sends follow our status.
+func TestHandShakeOnStatus(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		db      = memdb.NewTestDB(t)
+		gspec   = &core.Genesis{Config: &params.ChainConfig{HomesteadBlock: big.NewInt(1), ChainID: big.NewInt(1)}}
+		genesis = gspec.MustCommit(db)
+	)
+	var s *GrpcServer
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		s = testSentryServer(tx, gspec, genesis.Hash())
+		return nil
+	}))
+
+	p2pLocal, p2pRemote := p2p.MsgPipe()
+	defer p2pLocal.Close()
+
+	var reply *eth.StatusPacket
+	localErr, remoteErr := make(chan error, 1), make(chan error, 1)
+	go func() {
+		localErr <- handShake(ctx, s.GetStatus(), [64]byte{1}, p2pLocal, eth.ETH66, eth.ETH66, func(status *eth.StatusPacket) error {
+			reply = status
+			// like the initial header request of the sentry
+			return p2p.Send(p2pLocal, eth.GetBlockHeadersMsg, &eth.GetBlockHeadersPacket66{RequestId: 1, GetBlockHeadersPacket: &eth.GetBlockHeadersPacket{Amount: 1}})
+		})
+	}()
+	go func() { remoteErr <- handShake(ctx, s.GetStatus(), [64]byte{2}, p2pRemote, eth.ETH66, eth.ETH66, nil) }()
+
+	// The remote reads our status first, and then the message of onStatus
+	select {
+	case err := <-remoteErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("remote handshake timeout")
+	}
+	msg, err := p2pRemote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(eth.GetBlockHeadersMsg), msg.Code)
+	msg.Discard()
+	select {
+	case err := <-localErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("local handshake timeout")
+	}
+	require.Equal(t, genesis.Hash(), reply.Genesis)
+}
+
 func TestSentryServerImpl_SetStatusInitPanic(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -176,3 +225,40 @@ func TestSentryServerImpl_SetStatusInitPanic(t *testing.T) {
 		t.Fatalf("error expected")
 	}
 }
+
+// Tests that the messages for random peers go to a different subset of the peers each time.
+func TestSendMessageToRandomPeers(t *testing.T) {
+	ss := &GrpcServer{Protocol: p2p.Protocol{Version: eth.ETH66}}
+	for i := 1; i <= 4; i++ {
+		rw, remote := p2p.MsgPipe()
+		defer rw.Close()
+		go func() {
+			for {
+				msg, err := remote.ReadMsg()
+				if err != nil {
+					return
+				}
+				msg.Discard()
+			}
+		}()
+		peerInfo := NewPeerInfo(p2p.NewPeer(enode.ID{byte(i)}, [64]byte{byte(i)}, "peer", nil), rw)
+		defer peerInfo.Close()
+		ss.GoodPeers.Store(peerInfo.ID(), peerInfo)
+	}
+
+	picked := make(map[[64]byte]int)
+	for i := 0; i < 100; i++ {
+		reply, err := ss.SendMessageToRandomPeers(context.Background(), &proto_sentry.SendMessageToRandomPeersRequest{
+			Data:     &proto_sentry.OutboundMessageData{Id: proto_sentry.MessageId_NEW_BLOCK_66, Data: []byte{0xc0}},
+			MaxPeers: 4,
+		})
+		require.NoError(t, err)
+		// the square root of the peers
+		require.Len(t, reply.Peers, 2)
+		require.NotEqual(t, gointerfaces.ConvertH512ToHash(reply.Peers[0]), gointerfaces.ConvertH512ToHash(reply.Peers[1]))
+		for _, peerID := range reply.Peers {
+			picked[gointerfaces.ConvertH512ToHash(peerID)]++
+		}
+	}
+	require.Len(t, picked, 4)
+}
@@ -256,6 +256,7 @@ type MultiClient struct {
 	Engine      consensus.Engine
 	blockReader services.HeaderAndCanonicalReader
 	logPeerInfo bool
+	mux         *sentryMux // balances the header and body requests among the sentries
 }
 
 func NewMultiClient(
@@ -287,6 +288,7 @@ func NewMultiClient(
 		Hd:          hd,
 		Bd:          bd,
 		sentries:    sentries,
+		mux:         newSentryMux(len(sentries)),
 		db:          db,
 		Engine:      engine,
 		blockReader: blockReader,
@@ -306,6 +308,16 @@ func NewMultiClient(
 
 func (cs *MultiClient) Sentries() []direct.SentryClient { return cs.sentries }
 
+// delivered records the response received by the sentry, for the balancing of the requests
+func (cs *MultiClient) delivered(sentry direct.SentryClient, in *proto_sentry.InboundMessage) {
+	for i := range cs.sentries {
+		if cs.sentries[i] == sentry {
+			cs.mux.delivered(i, ConvertH512ToPeerID(in.PeerId), len(in.Data))
+			return
+		}
+	}
+}
+
 func (cs *MultiClient) newBlockHashes66(ctx context.Context, req *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
 	if !cs.Hd.RequestChaining() && !cs.Hd.FetchingNew() {
 		return nil
@@ -357,6 +369,7 @@ func (cs *MultiClient) blockHeaders66(ctx context.Context, in *proto_sentry.Inbo
 	if err := rlp.DecodeBytes(in.Data, &pkt); err != nil {
 		return fmt.Errorf("decode 1 BlockHeadersPacket66: %w", err)
 	}
+	cs.delivered(sentry, in)
 
 	// Prepare to extract raw headers from the block
 	rlpStream := rlp.NewStream(bytes.NewReader(in.Data), uint64(len(in.Data)))
@@ -494,11 +507,12 @@ func (cs *MultiClient) newBlock66(ctx context.Context, inreq *proto_sentry.Inbou
 	return nil
 }
 
-func (cs *MultiClient) blockBodies66(inreq *proto_sentry.InboundMessage, _ direct.SentryClient) error {
+func (cs *MultiClient) blockBodies66(inreq *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
 	var request eth.BlockRawBodiesPacket66
 	if err := rlp.DecodeBytes(inreq.Data, &request); err != nil {
 		return fmt.Errorf("decode BlockBodiesPacket66: %w", err)
 	}
+	cs.delivered(sentry, inreq)
 	txs, uncles := request.BlockRawBodiesPacket.Unpack()
 	cs.Bd.DeliverBodies(txs, uncles, uint64(len(inreq.Data)), ConvertH512ToPeerID(inreq.PeerId))
 	return nil
@@ -723,6 +737,15 @@ func (cs *MultiClient) makeStatusData() *proto_sentry.StatusData {
 }
 
 func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRemote, error) {
+	conn, err := GrpcClientConn(ctx, sentryAddr)
+	if err != nil {
+		return nil, err
+	}
+	return direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)), nil
+}
+
+// GrpcClientConn connects to the gRPC server of the sentry, serving the sentry service and sentryadmin
+func GrpcClientConn(ctx context.Context, sentryAddr string) (*grpc.ClientConn, error) {
 	// creating grpc client connection
 	var dialOpts []grpc.DialOption
 
@@ -740,5 +763,5 @@ func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRem
 	if err != nil {
 		return nil, fmt.Errorf("creating client connection to sentry P2P: %w", err)
 	}
-	return direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)), nil
+	return conn, nil
 }
@@ -0,0 +1,161 @@
+package sentry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// muxEWMAWeight is the weight of a new observation in the moving averages of the sentry throughput
+	muxEWMAWeight = 0.2
+	// muxMinBackoff and muxMaxBackoff bound the time a failed sentry is only tried after the healthy ones
+	muxMinBackoff = time.Second
+	muxMaxBackoff = 30 * time.Second
+	// muxRequestTTL is the time after which a request is considered unanswered
+	muxRequestTTL = time.Minute
+	// muxMaxPending is the number of the outstanding requests of a sentry, above which the unanswered ones are dropped
+	muxMaxPending = 4096
+)
+
+// sentryHealth is what the multiplexer knows about a sentry
+type sentryHealth struct {
+	failures    int                    // consecutive failures of the requests
+	failedUntil time.Time              // the sentry is tried after the healthy ones until then
+	throughput  float64                // moving average of the bytes per second of the responses, 0 if none yet
+	pending     map[[64]byte]time.Time // when the last request was sent to the peer
+}
+
+// sentryMux distributes the header and body requests among several sentries: the sentries are tried in a random
+// order weighted by the throughput observed from the responses of their peers, and the sentries whose requests
+// recently failed are only tried after the healthy ones, so that the requests fail over to the other sentries.
+type sentryMux struct {
+	lock   sync.Mutex
+	health []sentryHealth
+}
+
+func newSentryMux(sentries int) *sentryMux {
+	m := &sentryMux{health: make([]sentryHealth, sentries)}
+	for i := range m.health {
+		m.health[i].pending = map[[64]byte]time.Time{}
+	}
+	return m
+}
+
+// order returns the indices of the sentries in the order to try them
+func (m *sentryMux) order() []int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	// the sentries without responses yet get the average weight, so that they are tried too
+	var sum float64
+	var known int
+	for _, h := range m.health {
+		if h.throughput > 0 {
+			sum += h.throughput
+			known++
+		}
+	}
+	unknown := 1.0
+	if known > 0 {
+		unknown = sum / float64(known)
+	}
+	var healthy, failed []int
+	weights := make([]float64, len(m.health))
+	for i, h := range m.health {
+		weights[i] = h.throughput
+		if weights[i] == 0 {
+			weights[i] = unknown
+		}
+		if h.failedUntil.After(now) {
+			failed = append(failed, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(weightedShuffle(healthy, weights), weightedShuffle(failed, weights)...)
+}
+
+// weightedShuffle orders the indices at random, the ones with the higher weights more likely first
+func weightedShuffle(indices []int, weights []float64) []int {
+	shuffled := make([]int, 0, len(indices))
+	left := append([]int(nil), indices...)
+	for len(left) > 0 {
+		var sum float64
+		for _, i := range left {
+			sum += weights[i]
+		}
+		pick, r := len(left)-1, rand.Float64()*sum
+		for j, i := range left {
+			if r -= weights[i]; r < 0 {
+				pick = j
+				break
+			}
+		}
+		shuffled = append(shuffled, left[pick])
+		left = append(left[:pick], left[pick+1:]...)
+	}
+	return shuffled
+}
+
+// sent records the request sent by the sentry to the peer
+func (m *sentryMux) sent(i int, peerID [64]byte) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	h := &m.health[i]
+	h.failures, h.failedUntil = 0, time.Time{}
+	if at, ok := h.pending[peerID]; ok && now.Sub(at) > muxRequestTTL {
+		h.unanswered()
+	}
+	if len(h.pending) >= muxMaxPending {
+		for id, at := range h.pending {
+			if now.Sub(at) > muxRequestTTL {
+				delete(h.pending, id)
+				h.unanswered()
+			}
+		}
+	}
+	h.pending[peerID] = now
+}
+
+// unanswered lowers the throughput of the sentry for a request which wasn't answered
+func (h *sentryHealth) unanswered() {
+	h.throughput -= muxEWMAWeight * h.throughput
+}
+
+// failed records the failure of the sentry to send a request, and backs off from it
+func (m *sentryMux) failed(i int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	h := &m.health[i]
+	backoff := muxMinBackoff << h.failures
+	if backoff > muxMaxBackoff || backoff <= 0 {
+		backoff = muxMaxBackoff
+	} else {
+		h.failures++
+	}
+	h.failedUntil = time.Now().Add(backoff)
+}
+
+// delivered records the response of the peer to the request sent by the sentry
+func (m *sentryMux) delivered(i int, peerID [64]byte, size int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	h := &m.health[i]
+	at, ok := h.pending[peerID]
+	if !ok {
+		return
+	}
+	delete(h.pending, peerID)
+	latency := time.Since(at)
+	if latency <= 0 || latency > muxRequestTTL {
+		return
+	}
+	throughput := float64(size) / latency.Seconds()
+	if h.throughput == 0 {
+		h.throughput = throughput
+		return
+	}
+	h.throughput += muxEWMAWeight * (throughput - h.throughput)
+}
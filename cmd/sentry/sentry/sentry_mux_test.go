@@ -0,0 +1,97 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	proto_types "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestSentryMuxOrder(t *testing.T) {
+	m := newSentryMux(3)
+	m.health[0].throughput = 100
+	m.health[1].throughput = 1
+	m.health[2].failedUntil = time.Now().Add(time.Minute)
+
+	var first [3]int
+	for i := 0; i < 1000; i++ {
+		order := m.order()
+		require.Len(t, order, 3)
+		require.Equal(t, 2, order[2], "the failed sentry is tried last")
+		first[order[0]]++
+	}
+	require.Greater(t, first[0], 900)
+	require.Greater(t, first[1], 0)
+}
+
+func TestSentryMuxThroughput(t *testing.T) {
+	m := newSentryMux(1)
+	peerID := [64]byte{1}
+	m.delivered(0, peerID, 1000) // not requested
+	require.Zero(t, m.health[0].throughput)
+
+	m.sent(0, peerID)
+	m.health[0].pending[peerID] = time.Now().Add(-time.Second)
+	m.delivered(0, peerID, 1000)
+	require.InDelta(t, 1000, m.health[0].throughput, 10)
+	require.Empty(t, m.health[0].pending)
+
+	// an unanswered request lowers the throughput
+	m.sent(0, peerID)
+	m.health[0].pending[peerID] = time.Now().Add(-2 * muxRequestTTL)
+	m.sent(0, peerID)
+	require.Less(t, m.health[0].throughput, 900.0)
+
+	m.failed(0)
+	m.failed(0)
+	require.Equal(t, 2, m.health[0].failures)
+	require.True(t, m.health[0].failedUntil.After(time.Now().Add(muxMinBackoff)))
+	m.sent(0, peerID)
+	require.Zero(t, m.health[0].failures)
+}
+
+type testSentryClient struct {
+	*proto_sentry.SentryClientMock
+}
+
+func (testSentryClient) Protocol() uint    { return eth.ETH66 }
+func (testSentryClient) Ready() bool       { return true }
+func (testSentryClient) MarkDisconnected() {}
+
+func TestSentryFailover(t *testing.T) {
+	peerID := [64]byte{1}
+	var dead, alive int
+	sentries := []direct.SentryClient{
+		testSentryClient{&proto_sentry.SentryClientMock{
+			SendMessageByMinBlockFunc: func(context.Context, *proto_sentry.SendMessageByMinBlockRequest, ...grpc.CallOption) (*proto_sentry.SentPeers, error) {
+				dead++
+				return nil, errors.New("connection refused")
+			},
+		}},
+		testSentryClient{&proto_sentry.SentryClientMock{
+			SendMessageByMinBlockFunc: func(context.Context, *proto_sentry.SendMessageByMinBlockRequest, ...grpc.CallOption) (*proto_sentry.SentPeers, error) {
+				alive++
+				return &proto_sentry.SentPeers{Peers: []*proto_types.H512{gointerfaces.ConvertHashToH512(peerID)}}, nil
+			},
+		}},
+	}
+	cs := &MultiClient{sentries: sentries, mux: newSentryMux(len(sentries))}
+	for i := 0; i < 10; i++ {
+		sentTo, ok := cs.SendHeaderRequest(context.Background(), &headerdownload.HeaderRequest{Number: 1, Length: 1})
+		require.True(t, ok)
+		require.Equal(t, peerID, sentTo)
+	}
+	require.Equal(t, 10, alive)
+	// the dead sentry is only tried again after its backoff
+	require.LessOrEqual(t, dead, 1)
+	require.Len(t, cs.mux.health[1].pending, 1)
+}
@@ -0,0 +1,278 @@
+// Package sentryadmin is the gRPC service managing the peers of the sentries, which the sentry service of erigon-lib
+// doesn't cover. The sentry serves it next to the sentry service, and erigon serves it on its private API for the
+// admin RPC, applying the calls to all its sentries. The messages are JSON, wrapped in BytesValue.
+package sentryadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// PeerReputation is the score of a peer, and the end of its ban if it's banned
+type PeerReputation struct {
+	ID          enode.ID   `json:"id"`
+	Pubkey      string     `json:"pubkey"`
+	Score       int        `json:"score"`
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"`
+}
+
+// Admin manages the peers of a sentry, or of all the sentries of erigon. The peers are given by their enode URL, or
+// by their hex public key.
+type Admin interface {
+	// PeerScores returns the reputation of the peers which misbehaved, and of the banned peers
+	PeerScores(ctx context.Context) ([]PeerReputation, error)
+	// AdjustPeerScore changes the score of the peer, which is banned if the score drops to the ban threshold
+	AdjustPeerScore(ctx context.Context, peer string, delta int) (*PeerReputation, error)
+	// UnbanPeer lifts the ban of the peer, and resets its score
+	UnbanPeer(ctx context.Context, peer string) (*PeerReputation, error)
+	// AddPeer adds the node to the static peers, which are kept connected
+	AddPeer(ctx context.Context, url string) error
+	// RemovePeer removes the node from the static peers, and disconnects it
+	RemovePeer(ctx context.Context, url string) error
+	// AddTrustedPeer adds the node to the trusted peers, which are always allowed to connect
+	AddTrustedPeer(ctx context.Context, url string) error
+	// RemoveTrustedPeer removes the node from the trusted peers
+	RemoveTrustedPeer(ctx context.Context, url string) error
+	// PeersInfo returns the connected peers, with the detail of their protocols
+	PeersInfo(ctx context.Context) ([]*p2p.PeerInfo, error)
+}
+
+// request is the union of the arguments of the methods
+type request struct {
+	Peer  string `json:"peer,omitempty"` // enode URL, or hex public key
+	Delta int    `json:"delta,omitempty"`
+}
+
+func unaryHandler(call func(srv Admin, ctx context.Context, req *request) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.BytesValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		req := new(request)
+		if err := json.Unmarshal(in.Value, req); err != nil {
+			return nil, err
+		}
+		res, err := call(srv.(Admin), ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		out, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		return wrapperspb.Bytes(out), nil
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "sentry.SentryAdmin",
+	HandlerType: (*Admin)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PeerScores",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, _ *request) (interface{}, error) {
+				return srv.PeerScores(ctx)
+			}),
+		},
+		{
+			MethodName: "AdjustPeerScore",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return srv.AdjustPeerScore(ctx, req.Peer, req.Delta)
+			}),
+		},
+		{
+			MethodName: "UnbanPeer",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return srv.UnbanPeer(ctx, req.Peer)
+			}),
+		},
+		{
+			MethodName: "AddPeer",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return struct{}{}, srv.AddPeer(ctx, req.Peer)
+			}),
+		},
+		{
+			MethodName: "RemovePeer",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return struct{}{}, srv.RemovePeer(ctx, req.Peer)
+			}),
+		},
+		{
+			MethodName: "AddTrustedPeer",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return struct{}{}, srv.AddTrustedPeer(ctx, req.Peer)
+			}),
+		},
+		{
+			MethodName: "RemoveTrustedPeer",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, req *request) (interface{}, error) {
+				return struct{}{}, srv.RemoveTrustedPeer(ctx, req.Peer)
+			}),
+		},
+		{
+			MethodName: "PeersInfo",
+			Handler: unaryHandler(func(srv Admin, ctx context.Context, _ *request) (interface{}, error) {
+				return srv.PeersInfo(ctx)
+			}),
+		},
+	},
+	Metadata: "admin.go",
+}
+
+func RegisterAdminServer(s *grpc.Server, srv Admin) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// Client calls the admin service of a sentry, or of erigon
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+func (c *Client) invoke(ctx context.Context, method string, req *request, res interface{}) error {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	out := new(wrapperspb.BytesValue)
+	if err := c.cc.Invoke(ctx, "/sentry.SentryAdmin/"+method, wrapperspb.Bytes(in), out); err != nil {
+		return err
+	}
+	return json.Unmarshal(out.Value, res)
+}
+
+func (c *Client) PeerScores(ctx context.Context) (peers []PeerReputation, err error) {
+	err = c.invoke(ctx, "PeerScores", &request{}, &peers)
+	return peers, err
+}
+
+func (c *Client) AdjustPeerScore(ctx context.Context, peer string, delta int) (*PeerReputation, error) {
+	res := new(PeerReputation)
+	return res, c.invoke(ctx, "AdjustPeerScore", &request{Peer: peer, Delta: delta}, res)
+}
+
+func (c *Client) UnbanPeer(ctx context.Context, peer string) (*PeerReputation, error) {
+	res := new(PeerReputation)
+	return res, c.invoke(ctx, "UnbanPeer", &request{Peer: peer}, res)
+}
+
+func (c *Client) AddPeer(ctx context.Context, url string) error {
+	return c.invoke(ctx, "AddPeer", &request{Peer: url}, &struct{}{})
+}
+
+func (c *Client) RemovePeer(ctx context.Context, url string) error {
+	return c.invoke(ctx, "RemovePeer", &request{Peer: url}, &struct{}{})
+}
+
+func (c *Client) AddTrustedPeer(ctx context.Context, url string) error {
+	return c.invoke(ctx, "AddTrustedPeer", &request{Peer: url}, &struct{}{})
+}
+
+func (c *Client) RemoveTrustedPeer(ctx context.Context, url string) error {
+	return c.invoke(ctx, "RemoveTrustedPeer", &request{Peer: url}, &struct{}{})
+}
+
+func (c *Client) PeersInfo(ctx context.Context) (peers []*p2p.PeerInfo, err error) {
+	err = c.invoke(ctx, "PeersInfo", &request{}, &peers)
+	return peers, err
+}
+
+// Multi applies the calls to all the sentries, and returns the results of the first one, or the peers of all of them
+type Multi []Admin
+
+var errNoSentry = errors.New("no sentry")
+
+func (m Multi) PeerScores(ctx context.Context) ([]PeerReputation, error) {
+	if len(m) == 0 {
+		return nil, errNoSentry
+	}
+	var peers []PeerReputation
+	for _, s := range m {
+		res, err := s.PeerScores(ctx)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, res...)
+	}
+	return peers, nil
+}
+
+func (m Multi) AdjustPeerScore(ctx context.Context, peer string, delta int) (*PeerReputation, error) {
+	return m.forAll(func(s Admin) (*PeerReputation, error) { return s.AdjustPeerScore(ctx, peer, delta) })
+}
+
+func (m Multi) UnbanPeer(ctx context.Context, peer string) (*PeerReputation, error) {
+	return m.forAll(func(s Admin) (*PeerReputation, error) { return s.UnbanPeer(ctx, peer) })
+}
+
+func (m Multi) AddPeer(ctx context.Context, url string) error {
+	return m.forEach(func(s Admin) error { return s.AddPeer(ctx, url) })
+}
+
+func (m Multi) RemovePeer(ctx context.Context, url string) error {
+	return m.forEach(func(s Admin) error { return s.RemovePeer(ctx, url) })
+}
+
+func (m Multi) AddTrustedPeer(ctx context.Context, url string) error {
+	return m.forEach(func(s Admin) error { return s.AddTrustedPeer(ctx, url) })
+}
+
+func (m Multi) RemoveTrustedPeer(ctx context.Context, url string) error {
+	return m.forEach(func(s Admin) error { return s.RemoveTrustedPeer(ctx, url) })
+}
+
+func (m Multi) PeersInfo(ctx context.Context) ([]*p2p.PeerInfo, error) {
+	if len(m) == 0 {
+		return nil, errNoSentry
+	}
+	var peers []*p2p.PeerInfo
+	for _, s := range m {
+		res, err := s.PeersInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, res...)
+	}
+	return peers, nil
+}
+
+func (m Multi) forEach(f func(s Admin) error) error {
+	if len(m) == 0 {
+		return errNoSentry
+	}
+	for _, s := range m {
+		if err := f(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Multi) forAll(f func(s Admin) (*PeerReputation, error)) (*PeerReputation, error) {
+	if len(m) == 0 {
+		return nil, errNoSentry
+	}
+	var first *PeerReputation
+	for i, s := range m {
+		res, err := f(s)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = res
+		}
+	}
+	return first, nil
+}
@@ -0,0 +1,130 @@
+package sentryadmin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testAdmin struct {
+	scores  map[string]int
+	static  map[string]bool
+	trusted map[string]bool
+}
+
+func newTestAdmin() *testAdmin {
+	return &testAdmin{scores: map[string]int{}, static: map[string]bool{}, trusted: map[string]bool{}}
+}
+
+func (a *testAdmin) PeerScores(context.Context) ([]PeerReputation, error) {
+	var peers []PeerReputation
+	for peer, score := range a.scores {
+		peers = append(peers, PeerReputation{Pubkey: peer, Score: score})
+	}
+	return peers, nil
+}
+
+func (a *testAdmin) AdjustPeerScore(_ context.Context, peer string, delta int) (*PeerReputation, error) {
+	a.scores[peer] += delta
+	res := &PeerReputation{Pubkey: peer, Score: a.scores[peer]}
+	if a.scores[peer] <= -100 {
+		until := time.Unix(1000, 0).UTC()
+		res.BannedUntil = &until
+	}
+	return res, nil
+}
+
+func (a *testAdmin) UnbanPeer(_ context.Context, peer string) (*PeerReputation, error) {
+	delete(a.scores, peer)
+	return &PeerReputation{Pubkey: peer}, nil
+}
+
+func (a *testAdmin) AddPeer(_ context.Context, url string) error {
+	if url == "invalid" {
+		return errors.New("invalid enode")
+	}
+	a.static[url] = true
+	return nil
+}
+
+func (a *testAdmin) RemovePeer(_ context.Context, url string) error {
+	delete(a.static, url)
+	return nil
+}
+
+func (a *testAdmin) AddTrustedPeer(_ context.Context, url string) error {
+	a.trusted[url] = true
+	return nil
+}
+
+func (a *testAdmin) RemoveTrustedPeer(_ context.Context, url string) error {
+	delete(a.trusted, url)
+	return nil
+}
+
+func (a *testAdmin) PeersInfo(context.Context) ([]*p2p.PeerInfo, error) {
+	var peers []*p2p.PeerInfo
+	for url := range a.static {
+		peers = append(peers, &p2p.PeerInfo{Enode: url, Protocols: map[string]interface{}{"eth": map[string]interface{}{"version": 66}}})
+	}
+	return peers, nil
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	first, second := newTestAdmin(), newTestAdmin()
+	server := grpc.NewServer()
+	RegisterAdminServer(server, Multi{first, second})
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener) //nolint:errcheck
+	defer server.Stop()
+	conn, err := grpc.DialContext(ctx, "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := NewClient(conn)
+
+	res, err := client.AdjustPeerScore(ctx, "aa", -10)
+	require.NoError(t, err)
+	require.Equal(t, PeerReputation{Pubkey: "aa", Score: -10}, *res)
+	res, err = client.AdjustPeerScore(ctx, "aa", -90)
+	require.NoError(t, err)
+	require.NotNil(t, res.BannedUntil)
+	require.Equal(t, time.Unix(1000, 0).UTC(), *res.BannedUntil)
+	require.Equal(t, -100, second.scores["aa"])
+
+	peers, err := client.PeerScores(ctx)
+	require.NoError(t, err)
+	require.Len(t, peers, 2) // of both sentries
+
+	_, err = client.UnbanPeer(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, first.scores)
+	require.Empty(t, second.scores)
+
+	require.NoError(t, client.AddPeer(ctx, "enode://a"))
+	require.NoError(t, client.AddTrustedPeer(ctx, "enode://b"))
+	require.True(t, second.static["enode://a"])
+	require.True(t, second.trusted["enode://b"])
+	infos, err := client.PeersInfo(ctx)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "enode://a", infos[0].Enode)
+	require.Equal(t, map[string]interface{}{"version": float64(66)}, infos[0].Protocols["eth"])
+	require.NoError(t, client.RemovePeer(ctx, "enode://a"))
+	require.NoError(t, client.RemoveTrustedPeer(ctx, "enode://b"))
+	require.Empty(t, first.static)
+	require.Empty(t, first.trusted)
+	require.ErrorContains(t, client.AddPeer(ctx, "invalid"), "invalid enode")
+
+	_, err = Multi{}.PeerScores(ctx)
+	require.ErrorIs(t, err, errNoSentry)
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/txpoolmetrics"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
 )
@@ -47,6 +49,7 @@ var (
 	priceLimit   uint64
 	accountSlots uint64
 	priceBump    uint64
+	noJournal    bool
 )
 
 func init() {
@@ -68,6 +71,7 @@ func init() {
 	rootCmd.PersistentFlags().Uint64Var(&priceLimit, "txpool.pricelimit", txpool.DefaultConfig.MinFeeCap, "Minimum gas price (fee cap) limit to enforce for acceptance into the pool")
 	rootCmd.PersistentFlags().Uint64Var(&accountSlots, "txpool.accountslots", txpool.DefaultConfig.AccountSlots, "Minimum number of executable transaction slots guaranteed per account")
 	rootCmd.PersistentFlags().Uint64Var(&priceBump, "txpool.pricebump", txpool.DefaultConfig.PriceBump, "Price bump percentage to replace an already existing transaction")
+	rootCmd.PersistentFlags().BoolVar(&noJournal, utils.TxPoolNoJournalFlag.Name, false, utils.TxPoolNoJournalFlag.Usage)
 	rootCmd.Flags().StringSliceVar(&traceSenders, utils.TxPoolTraceSendersFlag.Name, []string{}, utils.TxPoolTraceSendersFlag.Usage)
 }
 
@@ -117,6 +121,12 @@ var rootCmd = &cobra.Command{
 		dirs := datadir.New(datadirCli)
 
 		cfg.DBDir = dirs.TxPool
+		if noJournal {
+			log.Info("Dropping the transactions stored by the pool", "dir", cfg.DBDir)
+			if err := os.RemoveAll(cfg.DBDir); err != nil {
+				return err
+			}
+		}
 		cfg.CommitEvery = 30 * time.Second
 		cfg.PendingSubPoolLimit = pendingPoolLimit
 		cfg.BaseFeeSubPoolLimit = baseFeePoolLimit
@@ -127,6 +137,7 @@ var rootCmd = &cobra.Command{
 
 		cacheConfig := kvcache.DefaultCoherentConfig
 		cacheConfig.MetricsLabel = "txpool"
+		rpchelper.RegisterCacheHitRatio(cacheConfig.MetricsLabel)
 
 		cfg.TracedSenders = make([]string, len(traceSenders))
 		for i, senderHex := range traceSenders {
@@ -157,6 +168,8 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		go txpoolmetrics.Loop(cmd.Context(), txpoolGrpcServer, txpoolmetrics.DefaultInterval)
+
 		notifyMiner := func() {}
 		txpool.MainLoop(cmd.Context(), txPoolDB, coreDB, txPool, newTxs, send, txpoolGrpcServer.NewSlotsStreams, notifyMiner)
 
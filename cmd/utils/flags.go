@@ -28,6 +28,7 @@ import (
 	"strings"
 	"text/tabwriter"
 	"text/template"
+	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
@@ -39,12 +40,14 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/urfave/cli"
+	"golang.org/x/exp/slices"
 
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/params/networkname"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/paths"
+	"github.com/ledgerwatch/erigon/consensus/aura/consensusconfig"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -116,11 +119,35 @@ var (
 		Name:  "dev.period",
 		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
 	}
+	DeveloperManualFlag = cli.BoolFlag{
+		Name:  "dev.manual",
+		Usage: "Mine only the blocks requested by evm_mine in developer mode",
+	}
+	ForkURLFlag = cli.StringFlag{
+		Name:  "fork.url",
+		Usage: "Fork the developer chain from the network of the JSON-RPC endpoint, reading its state on demand",
+	}
+	ForkDatadirFlag = cli.StringFlag{
+		Name:  "fork.datadir",
+		Usage: "Fork the developer chain from the network of the datadir of an Erigon node, reading its state on demand",
+	}
+	ForkBlockFlag = cli.Uint64Flag{
+		Name:  "fork.block",
+		Usage: "Block the developer chain is forked at (default: the latest block of the network)",
+	}
 	ChainFlag = cli.StringFlag{
 		Name:  "chain",
 		Usage: "Name of the testnet to join",
 		Value: networkname.MainnetChainName,
 	}
+	ChainSpecFlag = cli.StringFlag{
+		Name:  "chain.spec",
+		Usage: "Path to a chain spec in the OpenEthereum JSON format, to join a network not known to Erigon (overrides --chain)",
+	}
+	ChainConfigFlag = cli.StringFlag{
+		Name:  "chain.config",
+		Usage: "Path to a JSON chain definition (name, network id, bootnodes, genesis with fork blocks, engine params) registering a network not known to Erigon",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -133,10 +160,17 @@ var (
 		Name:  "override.terminaltotaldifficulty",
 		Usage: "Manually specify TerminalTotalDifficulty, overriding the bundled setting",
 	}
-	OverrideMergeNetsplitBlock = BigFlag{
-		Name:  "override.mergeNetsplitBlock",
-		Usage: "Manually specify FORK_NEXT_VALUE (see EIP-3675), overriding the bundled setting",
-	}
+	// OverrideForkBlockFlags are the --override.<fork>Block flags of params.OverridableForks, for the devnets
+	OverrideForkBlockFlags = func() []cli.Flag {
+		flags := make([]cli.Flag, len(params.OverridableForks))
+		for i, fork := range params.OverridableForks {
+			flags[i] = BigFlag{
+				Name:  overrideForkBlockFlagName(fork),
+				Usage: fmt.Sprintf("Manually specify the %s fork block, overriding the bundled setting", fork),
+			}
+		}
+		return flags
+	}()
 	// Ethash settings
 	EthashCachesInMemoryFlag = cli.IntFlag{
 		Name:  "ethash.cachesinmem",
@@ -173,6 +207,10 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolNoJournalFlag = cli.BoolFlag{
+		Name:  "txpool.nojournal",
+		Usage: "Drop the transactions the pool stored at the last shutdown, local ones included, instead of restoring them",
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price (fee cap) limit to enforce for acceptance into the pool",
@@ -222,6 +260,10 @@ var (
 		Name:  "watch-the-burn",
 		Usage: "Enable WatchTheBurn stage to keep track of ETH issuance",
 	}
+	FirehoseFlag = cli.BoolFlag{
+		Name:  "firehose",
+		Usage: "Stream header, transactions, receipts, balance and storage changes and call addresses of each executed block (and undo of unwound blocks) to indexers, by gRPC service erigon.Firehose of the private api",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -292,6 +334,18 @@ var (
 		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
 		Value: "",
 	}
+	LightClientBeaconAPIFlag = cli.StringFlag{
+		Name:  "lightclient.beacon.api",
+		Usage: "Follow the beacon chain by the embedded light client, from the beacon API at the URL, instead of a consensus layer client",
+	}
+	LightClientCheckpointFlag = cli.StringFlag{
+		Name:  "lightclient.checkpoint",
+		Usage: "Trusted beacon block root the embedded light client starts from, preferably a recent finalized one",
+	}
+	LightClientCheckpointSyncURLFlag = cli.StringFlag{
+		Name:  "lightclient.checkpoint.url",
+		Usage: "Beacon API of the checkpoint sync provider, whose finalized block the embedded light client starts from if --lightclient.checkpoint isn't set",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -321,7 +375,7 @@ var (
 	}
 	EngineAddr = cli.StringFlag{
 		Name:  "engine.addr",
-		Usage: "HTTP-RPC server listening interface for engineAPI",
+		Usage: "Comma separated list of HTTP-RPC server listening interfaces for engineAPI, as host or host:port (engine.port is used if omitted)",
 		Value: nodecfg.DefaultHTTPHost,
 	}
 	EnginePort = cli.UintFlag{
@@ -332,9 +386,14 @@ var (
 
 	JWTSecretPath = cli.StringFlag{
 		Name:  "authrpc.jwtsecret",
-		Usage: "Path to the token that ensures safe connection between CL and EL",
+		Usage: "Path to the token that ensures safe connection between CL and EL. The file may hold several secrets, one per line, and is reloaded when changed",
 		Value: "",
 	}
+	JWTSecretRotationFlag = cli.DurationFlag{
+		Name:  "authrpc.jwtsecret.rotation",
+		Usage: "How long the secrets replaced in the authrpc.jwtsecret file remain valid",
+		Value: 5 * time.Minute,
+	}
 
 	HttpCompressionFlag = cli.BoolFlag{
 		Name:  "http.compression",
@@ -344,6 +403,15 @@ var (
 		Name:  "ws.compression",
 		Usage: "Enable compression over WebSocket",
 	}
+	WsPendingTxsRateFlag = cli.IntFlag{
+		Name:  "ws.pendingtxs.rate",
+		Usage: "Maximum number of pending transactions notified per second to a WebSocket connection, the others are dropped (0 for no limit)",
+		Value: 0,
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL endpoint, served at /graphql on the HTTP-RPC server",
+	}
 	HTTPCORSDomainFlag = cli.StringFlag{
 		Name:  "http.corsdomain",
 		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
@@ -364,6 +432,31 @@ var (
 		Usage: "Does limit amount of goroutines to process 1 batch request. Means 1 bach request can't overload server. 1 batch still can have unlimited amount of request",
 		Value: 2,
 	}
+	RpcBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batch.limit",
+		Usage: "Maximum number of requests in a batch, the larger batches are rejected. 0 means no limit",
+		Value: 0,
+	}
+	RpcSlowThresholdFlag = cli.DurationFlag{
+		Name:  "rpc.slow",
+		Usage: "Log the requests taking longer at the WARN level, with their reads of the database. 0 disables it",
+		Value: 0,
+	}
+	HealthMaxBlocksBehindFlag = cli.Uint64Flag{
+		Name:  "health.max.blocks.behind",
+		Usage: "The node isn't ready (/ready) if its executed block is more blocks behind the highest header known. 0 disables the check",
+		Value: 0,
+	}
+	HealthMinPeersFlag = cli.UintFlag{
+		Name:  "health.min.peers",
+		Usage: "The node isn't ready (/ready) with fewer peers. 0 disables the check",
+		Value: 0,
+	}
+	HealthEngineTimeoutFlag = cli.DurationFlag{
+		Name:  "health.engine.timeout",
+		Usage: "The node isn't ready (/ready) if the consensus client hasn't called the Engine API for longer. 0 disables the check",
+		Value: 0,
+	}
 	RpcStreamingDisableFlag = cli.BoolFlag{
 		Name:  "rpc.streaming.disable",
 		Usage: "Erigon has enalbed json streamin for some heavy endpoints (like trace_*). It's treadoff: greatly reduce amount of RAM (in some cases from 30GB to 30mb), but it produce invalid json format if error happened in the middle of streaming (because json is not streaming-friendly format)",
@@ -381,6 +474,10 @@ var (
 		Name:  "rpc.accessList",
 		Usage: "Specify granular (method-by-method) API allowlist",
 	}
+	RpcRateLimitsFlag = cli.StringFlag{
+		Name:  "rpc.ratelimits",
+		Usage: "JSON file of the rate limits of the calls: method weights, per-IP and per-API-key budgets",
+	}
 
 	RpcGasCapFlag = cli.UintFlag{
 		Name:  "rpc.gascap",
@@ -416,6 +513,15 @@ var (
 		Usage: "Sets a limit on traces that can be returned in trace_filter",
 		Value: 200,
 	}
+	TraceCacheSizeFlag = cli.StringFlag{
+		Name:  "trace.cache.size",
+		Usage: "Size of the on-disk cache of the debug_traceBlock* and debug_traceTransaction outputs, 0 disables it",
+		Value: "0",
+	}
+	TraceCacheDirFlag = cli.StringFlag{
+		Name:  "trace.cache.dir",
+		Usage: "Directory of the trace cache (default: <datadir>/tracecache)",
+	}
 
 	HTTPPathPrefixFlag = cli.StringFlag{
 		Name:  "http.rpcprefix",
@@ -487,6 +593,11 @@ var (
 		Value: kvcache.DefaultCoherentConfig.KeysLimit,
 		Usage: "Amount of keys to store in StateCache (enabled if no --datadir set). Set 0 to disable StateCache. 1_000_000 keys ~ equal to 2Gb RAM (maybe we will add RAM accounting in future versions).",
 	}
+	StateCacheWarmupFlag = cli.Uint64Flag{
+		Name:  "state.cache.warmup",
+		Value: 64,
+		Usage: "Amount of recent blocks which changed state is loaded into StateCache at startup and after long reorgs. Set 0 to disable the warm-up.",
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -565,6 +676,39 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	PeerBanThresholdFlag = cli.IntFlag{
+		Name:  "p2p.ban.threshold",
+		Usage: "Score of a peer, lowered by its invalid responses, protocol violations and timeouts, at which the sentry bans it",
+		Value: nodecfg.DefaultConfig.P2P.PeerBanThreshold,
+	}
+	PeerBanTTLFlag = cli.DurationFlag{
+		Name:  "p2p.ban.ttl",
+		Usage: "How long the sentry bans the peers",
+		Value: nodecfg.DefaultConfig.P2P.PeerBanTTL,
+	}
+	SnapServeFlag = cli.BoolFlag{
+		Name:  "p2p.snap",
+		Usage: "Serve the snap/1 protocol, so that the peers can snap sync the state of the recent blocks (internal sentry only)",
+	}
+	SnapServeRateFlag = cli.Float64Flag{
+		Name:  "p2p.snap.rate",
+		Usage: "Requests of the snap protocol served per second to a peer, the requests above wait",
+		Value: ethconfig.Defaults.SnapServeRate,
+	}
+	LightServeFlag = cli.BoolFlag{
+		Name:  "light.serve",
+		Usage: "Serve the les/4 protocol to the light clients: headers, bodies, receipts, and the code and proofs of the recent blocks (internal sentry only)",
+	}
+	LightMaxPeersFlag = cli.IntFlag{
+		Name:  "light.maxpeers",
+		Usage: "Maximum number of the light clients served",
+		Value: ethconfig.Defaults.LightMaxPeers,
+	}
+	LightCapacityFlag = cli.Uint64Flag{
+		Name:  "light.capacity",
+		Usage: "Request cost units served per second, shared by the light clients; serving a header costs about a unit",
+		Value: ethconfig.Defaults.LightCapacity,
+	}
 	DNSDiscoveryFlag = cli.StringFlag{
 		Name:  "discovery.dns",
 		Usage: "Sets DNS discovery entry points (use \"\" to disable DNS)",
@@ -593,6 +737,15 @@ var (
 		Usage: "Maximum gas price will be recommended by gpo",
 		Value: ethconfig.Defaults.GPO.MaxPrice.Int64(),
 	}
+	GpoMinGasPriceFlag = cli.Int64Flag{
+		Name:  "gpo.minprice",
+		Usage: "Minimum gas price will be recommended by gpo (0 for none)",
+	}
+	GpoStrategyFlag = cli.StringFlag{
+		Name:  "gpo.strategy",
+		Usage: "Strategy of gpo: percentile (of the tips of recent transactions), txpool (the higher of percentile and of the tips of pending transactions)",
+		Value: gasprice.PercentileStrategy,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
@@ -668,6 +821,14 @@ var (
 		Value: 3,
 		Usage: "amount of files to download in parallel. If network has enough seeders 1-3 slot enough, if network has lack of seeders increase to 5-7 (too big value will slow down everything).",
 	}
+	TorrentRateScheduleFlag = cli.StringFlag{
+		Name:  "torrent.rate.schedule",
+		Usage: "time-of-day (local) rate limits, used instead of --torrent.download.rate and --torrent.upload.rate inside of given windows, format: HH:MM-HH:MM=<download rate>/<upload rate>, example: 09:00-18:00=8mb/1mb,22:00-06:00=512mb/64mb",
+	}
+	TorrentWebSeedsFlag = cli.StringFlag{
+		Name:  "torrent.webseeds",
+		Usage: "comma separated list of HTTP(S) base URLs serving the snapshots and their .torrent files (for example a public S3-compatible bucket), used alongside BitTorrent peers - or instead of them behind restrictive firewalls",
+	}
 	NoDownloaderFlag = cli.BoolFlag{
 		Name:  "no-downloader",
 		Usage: "to disable downloader component",
@@ -676,6 +837,10 @@ var (
 		Name:  "downloader.verify",
 		Usage: "verify snapshots on startup. it will not report founded problems but just re-download broken pieces",
 	}
+	DownloaderVerifyIntervalFlag = cli.DurationFlag{
+		Name:  "downloader.verify.interval",
+		Usage: "re-hash downloaded snapshots periodically (for example: 24h), corrupted files are re-downloaded in background. 0 - disabled",
+	}
 	TorrentPortFlag = cli.IntFlag{
 		Name:  "torrent.port",
 		Value: 42069,
@@ -696,6 +861,11 @@ var (
 		Usage: "set mdbx pagesize on db creation: must be power of 2 and '256b <= pagesize <= 64kb' ",
 		Value: "4kb",
 	}
+	DbBackendFlag = cli.StringFlag{
+		Name:  "db.backend",
+		Usage: "database engine: mdbx, bolt (a single process can open the db for writing: rpcdaemon must be embedded), or one of engines compiled in by build tags. Datadir must be created by the same engine",
+		Value: "mdbx",
+	}
 
 	HealthCheckFlag = cli.BoolFlag{
 		Name:  "healthcheck",
@@ -750,7 +920,7 @@ func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 		return
 	}
 
-	nodes, err := GetBootnodesFromFlags(ctx.GlobalString(BootnodesFlag.Name), ctx.GlobalString(ChainFlag.Name))
+	nodes, err := getBootnodes(ctx)
 	if err != nil {
 		Fatalf("Option %s: %v", BootnodesFlag.Name, err)
 	}
@@ -764,7 +934,7 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 		return
 	}
 
-	nodes, err := GetBootnodesFromFlags(ctx.GlobalString(BootnodesFlag.Name), ctx.GlobalString(ChainFlag.Name))
+	nodes, err := getBootnodes(ctx)
 	if err != nil {
 		Fatalf("Option %s: %v", BootnodesFlag.Name, err)
 	}
@@ -772,6 +942,45 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 	cfg.BootstrapNodesV5 = nodes
 }
 
+func getBootnodes(ctx *cli.Context) ([]*enode.Node, error) {
+	if !ctx.GlobalIsSet(BootnodesFlag.Name) && ctx.GlobalIsSet(ChainSpecFlag.Name) {
+		return ParseNodesFromURLs(mustReadChainSpec(ctx).Nodes)
+	}
+	return GetBootnodesFromFlags(ctx.GlobalString(BootnodesFlag.Name), ctx.GlobalString(ChainFlag.Name))
+}
+
+// RegisterCustomChain registers the network defined by --chain.config, so the rest of the flags
+// treat it as a built-in one. It's selected as the --chain unless another one is given explicitly.
+func RegisterCustomChain(ctx *cli.Context) {
+	if !ctx.GlobalIsSet(ChainConfigFlag.Name) {
+		return
+	}
+	def, err := core.ReadChainDefinition(ctx.GlobalString(ChainConfigFlag.Name))
+	if err != nil {
+		Fatalf("Option %s: %v", ChainConfigFlag.Name, err)
+	}
+	if err := core.RegisterChain(def); err != nil {
+		Fatalf("Option %s: %v", ChainConfigFlag.Name, err)
+	}
+	if len(def.EngineParams) > 0 {
+		consensusconfig.Register(def.Name, def.EngineParams)
+	}
+	if !ctx.GlobalIsSet(ChainFlag.Name) {
+		if err := ctx.GlobalSet(ChainFlag.Name, def.Name); err != nil {
+			Fatalf("Option %s: %v", ChainConfigFlag.Name, err)
+		}
+	}
+}
+
+// mustReadChainSpec reads the chain spec given by --chain.spec.
+func mustReadChainSpec(ctx *cli.Context) *params.OpenEthereumSpec {
+	spec, err := params.ReadOpenEthereumSpec(ctx.GlobalString(ChainSpecFlag.Name))
+	if err != nil {
+		Fatalf("Option %s: %v", ChainSpecFlag.Name, err)
+	}
+	return spec
+}
+
 // GetBootnodesFromFlags makes a list of bootnodes from command line flags.
 // If urlsStr is given, it is used and parsed as a comma-separated list of enode:// urls,
 // otherwise a list of preconfigured bootnodes of the specified chain is returned.
@@ -1005,6 +1214,12 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config, nodeName, datadir string) {
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.NoDiscovery = true
 	}
+	if ctx.GlobalIsSet(PeerBanThresholdFlag.Name) {
+		cfg.PeerBanThreshold = ctx.GlobalInt(PeerBanThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerBanTTLFlag.Name) {
+		cfg.PeerBanTTL = ctx.GlobalDuration(PeerBanTTLFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(DiscoveryV5Flag.Name) {
 		cfg.DiscoveryV5 = ctx.GlobalBool(DiscoveryV5Flag.Name)
@@ -1066,6 +1281,8 @@ func DataDirForNetwork(datadir string, network string) string {
 		filepath.Join(datadir, "kiln-devnet")
 	case networkname.SokolChainName:
 		return filepath.Join(datadir, "sokol")
+	case networkname.GnosisChainName:
+		return filepath.Join(datadir, "gnosis")
 	case networkname.FermionChainName:
 		return filepath.Join(datadir, "fermion")
 	case networkname.MumbaiChainName:
@@ -1077,6 +1294,9 @@ func DataDirForNetwork(datadir string, network string) string {
 	case networkname.SepoliaChainName:
 		return filepath.Join(datadir, "sepolia")
 	default:
+		if params.IsCustomChain(network) {
+			return filepath.Join(datadir, network)
+		}
 		return datadir
 	}
 
@@ -1086,6 +1306,13 @@ func DataDirForNetwork(datadir string, network string) string {
 func setDataDir(ctx *cli.Context, cfg *nodecfg.Config) {
 	if ctx.GlobalIsSet(DataDirFlag.Name) {
 		cfg.Dirs.DataDir = ctx.GlobalString(DataDirFlag.Name)
+	} else if ctx.GlobalIsSet(ChainSpecFlag.Name) {
+		spec := mustReadChainSpec(ctx)
+		dir := spec.DataDir
+		if dir == "" {
+			dir = strings.ToLower(spec.Name)
+		}
+		cfg.Dirs.DataDir = filepath.Join(cfg.Dirs.DataDir, dir)
 	} else {
 		cfg.Dirs.DataDir = DataDirForNetwork(cfg.Dirs.DataDir, ctx.GlobalString(ChainFlag.Name))
 	}
@@ -1098,6 +1325,7 @@ func setDataDir(ctx *cli.Context, cfg *nodecfg.Config) {
 	if !isPowerOfTwo(sz) || sz < 256 || sz > 64*1024 {
 		panic("invalid --db.pagesize: " + DbPageSizeFlag.Usage)
 	}
+	cfg.DBBackend = ctx.GlobalString(DbBackendFlag.Name)
 }
 
 func isPowerOfTwo(n uint64) bool {
@@ -1126,7 +1354,14 @@ func setDataDirCobra(f *pflag.FlagSet, cfg *nodecfg.Config) {
 	cfg.Dirs = datadir.New(cfg.Dirs.DataDir)
 }
 
-func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
+// SetGPO applies the gpo flags to the gas price oracle config.
+func SetGPO(ctx *cli.Context, cfg *gasprice.Config) {
+	if ctx.GlobalIsSet(GpoStrategyFlag.Name) {
+		cfg.Strategy = ctx.GlobalString(GpoStrategyFlag.Name)
+		if !slices.Contains(gasprice.Strategies, cfg.Strategy) {
+			Fatalf("Invalid gpo.strategy provided: %s, expected one of: %s", cfg.Strategy, strings.Join(gasprice.Strategies, ", "))
+		}
+	}
 	if ctx.GlobalIsSet(GpoBlocksFlag.Name) {
 		cfg.Blocks = ctx.GlobalInt(GpoBlocksFlag.Name)
 	}
@@ -1136,6 +1371,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
 		cfg.MaxPrice = big.NewInt(ctx.GlobalInt64(GpoMaxGasPriceFlag.Name))
 	}
+	if ctx.GlobalIsSet(GpoMinGasPriceFlag.Name) {
+		cfg.MinPrice = big.NewInt(ctx.GlobalInt64(GpoMinGasPriceFlag.Name))
+	}
 }
 
 //nolint
@@ -1149,6 +1387,12 @@ func setGPOCobra(f *pflag.FlagSet, cfg *gasprice.Config) {
 	if v := f.Int64(GpoMaxGasPriceFlag.Name, GpoMaxGasPriceFlag.Value, GpoMaxGasPriceFlag.Usage); v != nil {
 		cfg.MaxPrice = big.NewInt(*v)
 	}
+	if v := f.Int64(GpoMinGasPriceFlag.Name, GpoMinGasPriceFlag.Value, GpoMinGasPriceFlag.Usage); v != nil {
+		cfg.MinPrice = big.NewInt(*v)
+	}
+	if v := f.String(GpoStrategyFlag.Name, GpoStrategyFlag.Value, GpoStrategyFlag.Usage); v != nil {
+		cfg.Strategy = *v
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1168,6 +1412,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolNoJournalFlag.Name) {
+		cfg.NoJournal = ctx.GlobalBool(TxPoolNoJournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1414,10 +1661,15 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 		if err != nil {
 			panic(err)
 		}
-		cfg.Downloader, err = downloadercfg.New(cfg.Dirs.Snap, lvl, dbg, nodeConfig.P2P.NAT, downloadRate, uploadRate, ctx.GlobalInt(TorrentPortFlag.Name), ctx.GlobalInt(TorrentConnsPerFileFlag.Name), ctx.GlobalInt(TorrentDownloadSlotsFlag.Name))
+		rateSchedule, err := downloadercfg.ParseRateSchedule(ctx.GlobalString(TorrentRateScheduleFlag.Name))
+		if err != nil {
+			panic(err)
+		}
+		cfg.Downloader, err = downloadercfg.New(cfg.Dirs.Snap, lvl, dbg, nodeConfig.P2P.NAT, downloadRate, uploadRate, ctx.GlobalInt(TorrentPortFlag.Name), ctx.GlobalInt(TorrentConnsPerFileFlag.Name), ctx.GlobalInt(TorrentDownloadSlotsFlag.Name), rateSchedule, SplitAndTrim(ctx.GlobalString(TorrentWebSeedsFlag.Name)))
 		if err != nil {
 			panic(err)
 		}
+		cfg.Downloader.VerifyInterval = ctx.GlobalDuration(DownloaderVerifyIntervalFlag.Name)
 	}
 
 	nodeConfig.Http.Snap = cfg.Snapshot
@@ -1427,7 +1679,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	}
 
 	setEtherbase(ctx, cfg)
-	setGPO(ctx, &cfg.GPO)
+	SetGPO(ctx, &cfg.GPO)
 
 	setTxPool(ctx, &cfg.DeprecatedTxPool)
 	cfg.TxPool = core.DefaultTxPool2Config(cfg.DeprecatedTxPool)
@@ -1442,8 +1694,26 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	setBorConfig(ctx, cfg)
 
 	cfg.Ethstats = ctx.GlobalString(EthStatsURLFlag.Name)
+	if ctx.GlobalIsSet(LightClientBeaconAPIFlag.Name) {
+		cfg.LightClientBeaconAPI = ctx.GlobalString(LightClientBeaconAPIFlag.Name)
+		cfg.LightClientCheckpointSyncURL = ctx.GlobalString(LightClientCheckpointSyncURLFlag.Name)
+		if checkpoint := ctx.GlobalString(LightClientCheckpointFlag.Name); checkpoint != "" {
+			if len(common.FromHex(checkpoint)) != common.HashLength {
+				Fatalf("--%s: invalid beacon block root %q", LightClientCheckpointFlag.Name, checkpoint)
+			}
+			cfg.LightClientCheckpoint = common.HexToHash(checkpoint)
+		} else if cfg.LightClientCheckpointSyncURL == "" {
+			Fatalf("--%s requires --%s or --%s", LightClientBeaconAPIFlag.Name, LightClientCheckpointFlag.Name, LightClientCheckpointSyncURLFlag.Name)
+		}
+	}
 	cfg.P2PEnabled = len(nodeConfig.P2P.SentryAddr) == 0
 	cfg.EnabledIssuance = ctx.GlobalIsSet(EnabledIssuance.Name)
+	cfg.Firehose = ctx.GlobalIsSet(FirehoseFlag.Name)
+	cfg.SnapServe = ctx.GlobalBool(SnapServeFlag.Name)
+	cfg.SnapServeRate = ctx.GlobalFloat64(SnapServeRateFlag.Name)
+	cfg.LightServe = ctx.GlobalBool(LightServeFlag.Name)
+	cfg.LightMaxPeers = ctx.GlobalInt(LightMaxPeersFlag.Name)
+	cfg.LightCapacity = ctx.GlobalUint64(LightCapacityFlag.Name)
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkID = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1469,6 +1739,26 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 			cfg.EthDiscoveryURLs = SplitAndTrim(urls)
 		}
 	}
+	if ctx.GlobalIsSet(OverrideTerminalTotalDifficulty.Name) {
+		cfg.OverrideTerminalTotalDifficulty = GlobalBig(ctx, OverrideTerminalTotalDifficulty.Name)
+	}
+	for _, fork := range params.OverridableForks {
+		if name := overrideForkBlockFlagName(fork); ctx.GlobalIsSet(name) {
+			if cfg.OverrideForkBlocks == nil {
+				cfg.OverrideForkBlocks = map[string]*big.Int{}
+			}
+			cfg.OverrideForkBlocks[fork] = GlobalBig(ctx, name)
+		}
+	}
+
+	if (ctx.GlobalIsSet(ForkURLFlag.Name) || ctx.GlobalIsSet(ForkDatadirFlag.Name)) && ctx.GlobalString(ChainFlag.Name) != networkname.DevChainName {
+		Fatalf("Only the developer chain can be forked, use --%s=%s", ChainFlag.Name, networkname.DevChainName)
+	}
+	if ctx.GlobalIsSet(ChainSpecFlag.Name) {
+		setChainSpec(ctx, cfg)
+		return
+	}
+
 	// Override any default configs for hard coded networks.
 	chain := ctx.GlobalString(ChainFlag.Name)
 
@@ -1507,14 +1797,39 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 		if !ctx.GlobalIsSet(MinerGasPriceFlag.Name) {
 			cfg.Miner.GasPrice = big.NewInt(1)
 		}
+		cfg.Miner.Dev = true
+		cfg.Miner.DevManual = ctx.GlobalBool(DeveloperManualFlag.Name)
+		cfg.Fork.URL = ctx.GlobalString(ForkURLFlag.Name)
+		cfg.Fork.Datadir = ctx.GlobalString(ForkDatadirFlag.Name)
+		if ctx.GlobalIsSet(ForkBlockFlag.Name) {
+			block := ctx.GlobalUint64(ForkBlockFlag.Name)
+			cfg.Fork.Block = &block
+		}
 	}
+}
 
-	if ctx.GlobalIsSet(OverrideTerminalTotalDifficulty.Name) {
-		cfg.OverrideTerminalTotalDifficulty = GlobalBig(ctx, OverrideTerminalTotalDifficulty.Name)
+func overrideForkBlockFlagName(fork string) string {
+	return "override." + fork + "Block"
+}
+
+// setChainSpec configures a network which is described by the OpenEthereum chain spec given by --chain.spec.
+func setChainSpec(ctx *cli.Context, cfg *ethconfig.Config) {
+	spec := mustReadChainSpec(ctx)
+	genesis, err := core.OpenEthereumGenesis(spec)
+	if err != nil {
+		Fatalf("Option %s: %v", ChainSpecFlag.Name, err)
+	}
+	if spec.Engine.AuthorityRound != nil {
+		consensusconfig.Register(genesis.Config.ChainName, spec.Engine.AuthorityRound.Params)
+	}
+	if err := core.RegisterOpenEthereumPrecompiles(spec, genesis.Config.ChainID); err != nil {
+		Fatalf("Option %s: %v", ChainSpecFlag.Name, err)
 	}
-	if ctx.GlobalIsSet(OverrideMergeNetsplitBlock.Name) {
-		cfg.OverrideMergeNetsplitBlock = GlobalBig(ctx, OverrideMergeNetsplitBlock.Name)
+	cfg.Genesis = genesis
+	if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+		cfg.NetworkID = spec.NetworkID()
 	}
+	log.Info("Using chain spec", "name", spec.Name, "consensus", genesis.Config.Consensus, "networkID", cfg.NetworkID)
 }
 
 // SetDNSDiscoveryDefaults configures DNS discovery with the given URL if
@@ -1572,6 +1887,8 @@ func CobraFlags(cmd *cobra.Command, urfaveCliFlags []cli.Flag) {
 			flags.String(f.Name, f.Value, f.Usage)
 		case cli.BoolFlag:
 			flags.Bool(f.Name, false, f.Usage)
+		case cli.Float64Flag:
+			flags.Float64(f.Name, f.Value, f.Usage)
 		default:
 			panic(fmt.Errorf("unexpected type: %T", flag))
 		}
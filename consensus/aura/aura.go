@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
@@ -32,12 +33,14 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/accounts/abi"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/consensus/aura/aurainterfaces"
 	"github.com/ledgerwatch/erigon/consensus/aura/contracts"
 	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
@@ -49,11 +52,21 @@ import (
 
 const DEBUG_LOG_FROM = 999_999_999
 
-/*
-Not implemented features from OS:
- - two_thirds_majority_transition - because no chains in OE where this is != MaxUint64 - means 1/2 majority used everywhere
- - emptyStepsTransition - same
+var (
+	// errUnknownBlock is returned when sealing of the genesis block is requested.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errSignerNotSet is returned when sealing is requested before Authorize was called.
+	errSignerNotSet = errors.New("aura signer is not set")
+
+	// ErrInvalidSealFields is returned if a header doesn't carry the step and signature seal fields.
+	ErrInvalidSealFields = errors.New("invalid seal fields")
+
+	// errInvalidGasLimit is returned if the gas limit of a block differs from the one set by the gas limit contract.
+	errInvalidGasLimit = errors.New("invalid gas limit")
+)
 
+/*
 Repo with solidity sources: https://github.com/poanetwork/posdao-contracts
 */
 
@@ -110,7 +123,7 @@ func (s *Step) doCalibrate() {
 
 // optCalibrate Calibrates the AuRa step number according to the current time.
 func (s *Step) optCalibrate() bool {
-	now := time.Now().Second()
+	now := time.Now().Unix()
 	var info StepDurationInfo
 	i := 0
 	for _, d := range s.durations {
@@ -133,6 +146,18 @@ func (s *Step) optCalibrate() bool {
 	return true
 }
 
+// stepStart returns the unix timestamp at which the given step begins.
+func (s *Step) stepStart(step uint64) uint64 {
+	info := s.durations[0]
+	for _, d := range s.durations {
+		if d.TransitionStep > step {
+			break
+		}
+		info = d
+	}
+	return info.TransitionTimestamp + (step-info.TransitionStep)*info.StepDuration
+}
+
 type PermissionedStep struct {
 	inner      *Step
 	canPropose *atomic.Bool
@@ -175,15 +200,41 @@ type EpochManager struct {
 	epochTransitionNumber uint64      // BlockNumber
 	finalityChecker       *RollingFinality
 	force                 bool
+	snapshots             *epochSnapshots // validator sets of already seen epochs
+
+	// Block number from which 2/3 of validators signatures are required for finality.
+	twoThirdsMajorityTransition uint64
+
+	finalizedLock   sync.RWMutex
+	finalizedNumber uint64
+	finalizedHash   common.Hash
 }
 
-func NewEpochManager() *EpochManager {
+func NewEpochManager(twoThirdsMajorityTransition uint64, db kv.RwDB) *EpochManager {
 	return &EpochManager{
-		finalityChecker: NewRollingFinality([]common.Address{}),
-		force:           true,
+		finalityChecker:             NewRollingFinality([]common.Address{}, twoThirdsMajorityTransition),
+		force:                       true,
+		snapshots:                   newEpochSnapshots(db),
+		twoThirdsMajorityTransition: twoThirdsMajorityTransition,
 	}
 }
 
+// noteFinalized remembers the highest block finalized so far.
+func (e *EpochManager) noteFinalized(number uint64, hash common.Hash) {
+	e.finalizedLock.Lock()
+	defer e.finalizedLock.Unlock()
+	if number >= e.finalizedNumber {
+		e.finalizedNumber, e.finalizedHash = number, hash
+	}
+}
+
+// finalized returns the highest block finalized so far.
+func (e *EpochManager) finalized() (uint64, common.Hash) {
+	e.finalizedLock.RLock()
+	defer e.finalizedLock.RUnlock()
+	return e.finalizedNumber, e.finalizedHash
+}
+
 func (e *EpochManager) noteNewEpoch() { e.force = true }
 
 // zoomValidators - Zooms to the epoch after the header with the given hash. Returns true if succeeded, false otherwise.
@@ -216,25 +267,35 @@ func (e *EpochManager) zoomToAfter(chain consensus.ChainHeaderReader, er consens
 
 	// extract other epoch set if it's not the same as the last.
 	if lastTransition.BlockHash != e.epochTransitionHash {
-		proof := &EpochTransitionProof{}
-		if err := rlp.DecodeBytes(lastTransition.ProofRlp, proof); err != nil {
-			panic(err)
-		}
-		first := proof.SignalNumber == 0
-		if lastTransition.BlockNumber > DEBUG_LOG_FROM {
-			fmt.Printf("zoom2: %d,%d\n", lastTransition.BlockNumber, len(proof.SetProof))
+		snap, err := e.snapshots.get(lastTransition.BlockNumber, lastTransition.BlockHash)
+		if err != nil {
+			log.Warn("[aura] can't load epoch snapshot", "num", lastTransition.BlockNumber, "err", err)
 		}
+		if snap == nil {
+			proof := &EpochTransitionProof{}
+			if err := rlp.DecodeBytes(lastTransition.ProofRlp, proof); err != nil {
+				panic(err)
+			}
+			first := proof.SignalNumber == 0
+			if lastTransition.BlockNumber > DEBUG_LOG_FROM {
+				fmt.Printf("zoom2: %d,%d\n", lastTransition.BlockNumber, len(proof.SetProof))
+			}
 
-		// use signal number so multi-set first calculation is correct.
-		list, _, err := validators.epochSet(first, proof.SignalNumber, proof.SetProof, call)
-		if err != nil {
-			panic(fmt.Errorf("proof produced by this engine is invalid: %w", err))
+			// use signal number so multi-set first calculation is correct.
+			list, _, err := validators.epochSet(first, proof.SignalNumber, proof.SetProof, call)
+			if err != nil {
+				panic(fmt.Errorf("proof produced by this engine is invalid: %w", err))
+			}
+			snap = &epochSnapshot{Number: lastTransition.BlockNumber, Hash: lastTransition.BlockHash, Validators: list.validators}
+			if err := e.snapshots.put(snap); err != nil {
+				log.Warn("[aura] can't store epoch snapshot", "num", lastTransition.BlockNumber, "err", err)
+			}
 		}
-		epochSet := list.validators
+		epochSet := snap.Validators
 		log.Trace("[aura] Updating finality checker with new validator set extracted from epoch", "num", lastTransition.BlockNumber)
-		e.finalityChecker = NewRollingFinality(epochSet)
-		if proof.SignalNumber >= DEBUG_LOG_FROM {
-			fmt.Printf("new rolling finality: %d\n", proof.SignalNumber)
+		e.finalityChecker = NewRollingFinality(epochSet, e.twoThirdsMajorityTransition)
+		if lastTransition.BlockNumber >= DEBUG_LOG_FROM {
+			fmt.Printf("new rolling finality: %d\n", lastTransition.BlockNumber)
 			for i := 0; i < len(epochSet); i++ {
 				fmt.Printf("\t%x\n", epochSet[i])
 			}
@@ -331,11 +392,18 @@ type AuRa struct {
 	// History of step hashes recently received from peers.
 	receivedStepHashes ReceivedStepHashes
 
-	OurSigningAddress common.Address // Same as Etherbase in Mining
+	OurSigningAddress common.Address  // Same as Etherbase in Mining
+	signer            common.Address  // Ethereum address of the signing key
+	signFn            clique.SignerFn // Signer function to authorize hashes with
 	cfg               AuthorityRoundParams
 	EmptyStepsSet     *EmptyStepSet
 	EpochManager      *EpochManager // Mutex<EpochManager>,
 
+	// Called with every empty step message generated by this node, to gossip it to other authorities.
+	emptyStepBroadcaster func(msg []byte)
+	// Memoized gas limit overrides, by parent block hash.
+	gasLimitOverrideCache *GasLimitOverride
+
 	//Validators                     ValidatorSet
 	//ValidateScoreTransition        uint64
 	//ValidateStepTransition         uint64
@@ -348,10 +416,6 @@ type AuRa struct {
 	////machine: EthereumMachine,
 	//// If set, enables random number contract integration. It maps the transition block to the contract address.
 	//randomnessContractAddress map[uint64]common.Address
-	//// The addresses of contracts that determine the block gas limit.
-	//blockGasLimitContractTransitions map[uint64]common.Address
-	//// Memoized gas limit overrides, by block hash.
-	//gasLimitOverrideCache *GasLimitOverride //Mutex<LruCache<H256, Option<U256>>>,
 	//// The block number at which the consensus engine switches from AuRa to AuRa with POSDAO
 	//// modifications. For details about POSDAO, see the whitepaper:
 	//// https://www.xdaichain.com/for-validators/posdao-whitepaper
@@ -373,21 +437,19 @@ func NewGasLimitOverride() *GasLimitOverride {
 	return &GasLimitOverride{cache: cache}
 }
 
-func (pb *GasLimitOverride) Pop(hash common.Hash) *uint256.Int {
-	if val, ok := pb.cache.Get(hash); ok && val != nil {
-		pb.cache.Remove(hash)
-		if v, ok := val.(*uint256.Int); ok {
-			return v
-		}
+// Get returns the gas limit override of the block with the given parent, nil if it has none, and whether it's known
+func (pb *GasLimitOverride) Get(hash common.Hash) (*uint256.Int, bool) {
+	val, ok := pb.cache.Get(hash)
+	if !ok {
+		return nil, false
 	}
-	return nil
+	b, _ := val.(*uint256.Int)
+	return b, true
 }
 
+// Add keeps the gas limit override of the block with the given parent, nil if it has none
 func (pb *GasLimitOverride) Add(hash common.Hash, b *uint256.Int) {
-	if b == nil {
-		return
-	}
-	pb.cache.ContainsOrAdd(hash, b)
+	pb.cache.Add(hash, b)
 }
 
 func NewAuRa(config *params.AuRaConfig, db kv.RwDB, ourSigningAddress common.Address, engineParamsJson []byte) (*AuRa, error) {
@@ -474,7 +536,10 @@ func NewAuRa(config *params.AuRaConfig, db kv.RwDB, ourSigningAddress common.Add
 		OurSigningAddress:  ourSigningAddress,
 		cfg:                auraParams,
 		receivedStepHashes: ReceivedStepHashes{},
-		EpochManager:       NewEpochManager(),
+		EpochManager:       NewEpochManager(auraParams.TwoThirdsMajorityTransition, db),
+		EmptyStepsSet:      &EmptyStepSet{},
+
+		gasLimitOverrideCache: NewGasLimitOverride(),
 	}
 	_ = config
 
@@ -519,7 +584,67 @@ func (c *AuRa) Author(header *types.Header) (common.Address, error) {
 
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (c *AuRa) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, _ bool) error {
-	return nil
+	number := header.Number.Uint64()
+	if number == 0 || number < c.cfg.EmptyStepsTransition {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	step, err := headerStep(header)
+	if err != nil {
+		return err
+	}
+	parentStep, err := headerStep(parent)
+	if err != nil {
+		return err
+	}
+	// signatures can be checked only if the validator set of the parent is already known
+	validators, err := c.sealingValidators(header.ParentHash)
+	if err != nil {
+		validators = nil
+	}
+	_, err = c.verifyEmptySteps(header, step, parentStep, validators)
+	return err
+}
+
+// verifyEmptySteps validates the empty step messages included in the seal and returns their count.
+// If validators is nil, the signatures of the messages are not checked.
+func (c *AuRa) verifyEmptySteps(header *types.Header, step, parentStep uint64, validators ValidatorSet) (int, error) {
+	emptySteps, err := headerEmptySteps(header)
+	if err != nil {
+		return 0, err
+	}
+	if uint(len(emptySteps)) > c.cfg.MaximumEmptySteps {
+		return 0, fmt.Errorf("too many empty steps: %d > %d", len(emptySteps), c.cfg.MaximumEmptySteps)
+	}
+	strictEmptySteps := header.Number.Uint64() >= c.cfg.StrictEmptyStepsTransition
+	prevEmptyStep := uint64(0)
+	for i := range emptySteps {
+		emptyStep := &emptySteps[i]
+		if emptyStep.step <= parentStep || emptyStep.step >= step {
+			return 0, fmt.Errorf("empty step proof for invalid step: %d", emptyStep.step)
+		}
+		if emptyStep.parentHash != header.ParentHash {
+			return 0, fmt.Errorf("empty step proof for invalid parent hash: %x", emptyStep.parentHash)
+		}
+		if validators != nil {
+			if ok, err := emptyStep.verify(validators); err != nil || !ok {
+				return 0, fmt.Errorf("invalid empty step proof: step=%d, err=%v", emptyStep.step, err)
+			}
+		}
+		if strictEmptySteps {
+			if emptyStep.step == prevEmptyStep {
+				return 0, fmt.Errorf("duplicate empty step: %d", emptyStep.step)
+			}
+			if emptyStep.step < prevEmptyStep {
+				return 0, fmt.Errorf("unordered empty step: %d", emptyStep.step)
+			}
+			prevEmptyStep = emptyStep.step
+		}
+	}
+	return len(emptySteps), nil
 }
 
 //nolint
@@ -724,72 +849,114 @@ func (c *AuRa) VerifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 }
 
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
-// header for running the transactions on top.
+// header for running the transactions on top: the step the block is going to be
+// sealed in, the score (difficulty) derived from it and a placeholder for the signature.
 func (c *AuRa) Prepare(chain consensus.ChainHeaderReader, header *types.Header, state *state.IntraBlockState) error {
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	parentStep, err := headerStep(parent)
+	if err != nil {
+		return err
+	}
+
+	prevStep := c.step.inner.inner.Load()
+	c.step.inner.doCalibrate()
+	step := c.step.inner.inner.Load()
+	if step > prevStep {
+		// new step - re-arm proposing, analog of OE's step timeout handler
+		c.step.canPropose.Store(true)
+	}
+	if step <= parentStep {
+		return fmt.Errorf("aura: current step %d is not after parent step %d", step, parentStep)
+	}
+
+	var emptySteps []EmptyStep
+	if number >= c.cfg.EmptyStepsTransition {
+		emptySteps = c.emptySteps(parentStep, step, header.ParentHash)
+	}
+	header.Difficulty = calculateScore(parentStep, step, uint64(len(emptySteps))).ToBig()
+	if stepStart := c.step.inner.stepStart(step); header.Time < stepStart {
+		header.Time = stepStart
+	}
+
+	stepRlp, err := rlp.EncodeToBytes(step)
+	if err != nil {
+		return err
+	}
+	sigRlp, err := rlp.EncodeToBytes(make([]byte, crypto.SignatureLength))
+	if err != nil {
+		return err
+	}
+	header.Seal = []rlp.RawValue{stepRlp, sigRlp}
+	if number >= c.cfg.EmptyStepsTransition {
+		emptyStepsRlp, err := emptyStepsSealRlp(emptySteps)
+		if err != nil {
+			return err
+		}
+		header.Seal = append(header.Seal, emptyStepsRlp)
+	}
+	header.WithSeal = true
 	return nil
-	/// If the block isn't a checkpoint, cast a random vote (good enough for now)
-	//header.Coinbase = common.Address{}
-	//header.Nonce = types.BlockNonce{}
-	//
-	//number := header.Number.Uint64()
-	/// Assemble the voting snapshot to check which votes make sense
-	//snap, err := c.Snapshot(chain, number-1, header.ParentHash, nil)
-	//if err != nil {
-	//	return err
-	//}
-	//if number%c.config.Epoch != 0 {
-	//	c.lock.RLock()
-	//
-	//	// Gather all the proposals that make sense voting on
-	//	addresses := make([]common.Address, 0, len(c.proposals))
-	//	for address, authorize := range c.proposals {
-	//		if snap.validVote(address, authorize) {
-	//			addresses = append(addresses, address)
-	//		}
-	//	}
-	//	// If there's pending proposals, cast a vote on them
-	//	if len(addresses) > 0 {
-	//		header.Coinbase = addresses[rand.Intn(len(addresses))]
-	//		if c.proposals[header.Coinbase] {
-	//			copy(header.Nonce[:], NonceAuthVote)
-	//		} else {
-	//			copy(header.Nonce[:], nonceDropVote)
-	//		}
-	//	}
-	//	c.lock.RUnlock()
-	//}
-	/// Set the correct difficulty
-	//header.Difficulty = calcDifficulty(snap, c.signer)
-	//
-	/// Ensure the extra data has all its components
-	//if len(header.Extra) < ExtraVanity {
-	//	header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, ExtraVanity-len(header.Extra))...)
-	//}
-	//header.Extra = header.Extra[:ExtraVanity]
-	//
-	//if number%c.config.Epoch == 0 {
-	//	for _, signer := range snap.GetSigners() {
-	//		header.Extra = append(header.Extra, signer[:]...)
-	//	}
-	//}
-	//header.Extra = append(header.Extra, make([]byte, ExtraSeal)...)
-	//
-	/// Mix digest is reserved for now, set to empty
-	//header.MixDigest = common.Hash{}
-	//
-	/// Ensure the timestamp has the correct delay
-	//parent := chain.GetHeader(header.ParentHash, number-1)
-	//if parent == nil {
-	//	return consensus.ErrUnknownAncestor
-	//}
-	//header.Time = parent.Time + c.config.Period
-	//
-	//now := uint64(time.Now().Unix())
-	//if header.Time < now {
-	//	header.Time = now
-	//}
-	//
-	//return nil
+}
+
+// GasLimitOverride implements consensus.GasLimitProvider: since the first of BlockGasLimitContractTransitions
+// the block gas limit is read from the configured contract, a zero value means "no override".
+func (c *AuRa) GasLimitOverride(header *types.Header, syscall consensus.SystemCall) (uint64, bool) {
+	gasLimit := c.gasLimitOverride(header, syscall)
+	if gasLimit == nil {
+		return 0, false
+	}
+	return gasLimit.Uint64(), true
+}
+
+// gasLimitOverride returns the gas limit override of the block, read at its start by Initialize. The value
+// depends on the state of the parent only, so it's kept per parent hash: the mined block has no hash yet.
+func (c *AuRa) gasLimitOverride(header *types.Header, syscall consensus.SystemCall) *uint256.Int {
+	if gasLimit, ok := c.gasLimitOverrideCache.Get(header.ParentHash); ok {
+		return gasLimit
+	}
+	gasLimit := c.readGasLimitOverride(header, syscall)
+	c.gasLimitOverrideCache.Add(header.ParentHash, gasLimit)
+	return gasLimit
+}
+
+// readGasLimitOverride calls the block gas limit contract of the block, if any
+func (c *AuRa) readGasLimitOverride(header *types.Header, syscall consensus.SystemCall) *uint256.Int {
+	contract, ok := c.blockGasLimitContract(header.Number.Uint64())
+	if !ok {
+		return nil
+	}
+	gasLimit, err := callBlockGasLimitAbi(contract, syscall)
+	if err != nil {
+		log.Warn("[aura] contract call failed, not overriding the block gas limit", "contract", contract, "err", err)
+		return nil
+	}
+	if gasLimit.IsZero() {
+		return nil
+	}
+	if !gasLimit.IsUint64() {
+		log.Warn("[aura] block gas limit from contract overflows uint64, not overriding it", "contract", contract, "gasLimit", gasLimit)
+		return nil
+	}
+	return gasLimit
+}
+
+// blockGasLimitContract returns the address of the contract which determines the gas limit of the given block.
+func (c *AuRa) blockGasLimitContract(number uint64) (common.Address, bool) {
+	var (
+		contract   common.Address
+		transition uint64
+		found      bool
+	)
+	for n, addr := range c.cfg.BlockGasLimitContractTransitions {
+		if n <= number && (!found || n > transition) {
+			contract, transition, found = addr, n, true
+		}
+	}
+	return contract, found
 }
 
 func (c *AuRa) Initialize(config *params.ChainConfig, chain consensus.ChainHeaderReader, e consensus.EpochReader, header *types.Header, txs []types.Transaction, uncles []*types.Header, syscall consensus.SystemCall) {
@@ -807,6 +974,9 @@ func (c *AuRa) Initialize(config *params.ChainConfig, chain consensus.ChainHeade
 		}
 	}
 
+	// the gas limit must be read before any transaction of the block is applied, it's verified in Finalize
+	c.gasLimitOverrideCache.Add(header.ParentHash, c.readGasLimitOverride(header, syscall))
+
 	//if err := c.verifyFamily(chain, e, header, call, syscall); err != nil { //TODO: OE has it as a separate engine call? why?
 	//	panic(err)
 	//}
@@ -835,6 +1005,10 @@ func (c *AuRa) Finalize(config *params.ChainConfig, header *types.Header, state
 	txs types.Transactions, uncles []*types.Header, receipts types.Receipts, e consensus.EpochReader,
 	chain consensus.ChainHeaderReader, syscall consensus.SystemCall,
 ) (types.Transactions, types.Receipts, error) {
+	// the transactions of the block may have changed the answer of the contract, the one read by Initialize counts
+	if gasLimit, _ := c.gasLimitOverrideCache.Get(header.ParentHash); gasLimit != nil && header.GasLimit != gasLimit.Uint64() {
+		return nil, nil, fmt.Errorf("%w: have %d, want %d", errInvalidGasLimit, header.GasLimit, gasLimit.Uint64())
+	}
 	// accumulateRewards retrieves rewards for a block and applies them to the coinbase accounts for miner and uncle miners
 	beneficiaries, _, rewards, err := AccumulateRewards(config, c, header, uncles, syscall)
 	if err != nil {
@@ -904,6 +1078,12 @@ func buildFinality(e *EpochManager, chain consensus.ChainHeaderReader, er consen
 		//log.Warn("[aura] finalityChecker.push", "err", err)
 		return []unAssembledHeader{}
 	}
+	if len(res) > 0 {
+		e.noteFinalized(res[len(res)-1].number, res[len(res)-1].hash)
+	}
+	if e.finalityChecker.lastFinalized != nil {
+		e.noteFinalized(e.finalityChecker.lastFinalized.number, e.finalityChecker.lastFinalized.hash)
+	}
 	return res
 }
 
@@ -996,8 +1176,8 @@ func (c *AuRa) Authorize(signer common.Address, signFn clique.SignerFn) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	//c.signer = signer
-	//c.signFn = signFn
+	c.signer = signer
+	c.signFn = signFn
 }
 
 func (c *AuRa) GenesisEpochData(header *types.Header, caller consensus.SystemCall) ([]byte, error) {
@@ -1015,75 +1195,141 @@ func (c *AuRa) GenesisEpochData(header *types.Header, caller consensus.SystemCal
 	return res, nil
 }
 
+// Seal implements consensus.Engine, attempting to create a sealed block using
+// the local signing credentials. The block is only sealed if we are the primary
+// of the step that was chosen in Prepare, and is released not before that step starts.
 func (c *AuRa) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	// Don't hold the signer fields for the entire sealing procedure
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+	if signFn == nil {
+		return errSignerNotSet
+	}
+
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if len(header.Seal) < 2 {
+		return ErrInvalidSealFields
+	}
+	step, err := headerStep(header)
+	if err != nil {
+		return err
+	}
+	parentStep, err := headerStep(parent)
+	if err != nil {
+		return err
+	}
+	// this is guarded against by `can_propose` unless the block was signed
+	// on the same step (implies same key) and on a different node.
+	if step <= parentStep {
+		log.Warn("[aura] Attempted to seal block on the same step as parent. Is this authority sealing with more than one node?", "step", step, "parentStep", parentStep)
+		return nil
+	}
+
+	validators, err := c.sealingValidators(header.ParentHash)
+	if err != nil {
+		return err
+	}
+	proposer, err := stepProposer(validators, header.ParentHash, step, nil)
+	if err != nil {
+		return err
+	}
+	if proposer != signer {
+		log.Trace("[aura] Not a proposer for step, skip sealing", "step", step, "proposer", proposer)
+		return nil
+	}
+	if header.Coinbase != signer {
+		return fmt.Errorf("aura: coinbase %x doesn't match signer %x", header.Coinbase, signer)
+	}
+	// If we are building a block with no transactions and empty steps are enabled, generate an
+	// empty step message instead of sealing unless we have reached the max number of empty steps
+	if number >= c.cfg.EmptyStepsTransition && len(block.Transactions()) == 0 {
+		emptySteps, err := headerEmptySteps(header)
+		if err != nil {
+			return err
+		}
+		if uint(len(emptySteps)) < c.cfg.MaximumEmptySteps {
+			if c.step.canPropose.CAS(true, false) {
+				return c.generateEmptyStep(signer, signFn, header.ParentHash, step)
+			}
+			return nil
+		}
+	}
+	// only issue the seal if we were the first to reach the compare_exchange.
+	if !c.step.canPropose.CAS(true, false) {
+		log.Trace("[aura] Aborting seal generation. Can't propose.")
+		return nil
+	}
+	// we can drop all accumulated empty step messages that are
+	// older than the parent step since we're including them in the seal
+	c.EmptyStepsSet.RemoveUpTo(parentStep)
+
+	sighash, err := signFn(signer, accounts.MimetypeAuRa, auraRLP(header))
+	if err != nil {
+		return err
+	}
+	sigRlp, err := rlp.EncodeToBytes(sighash)
+	if err != nil {
+		return err
+	}
+	header.Seal = append([]rlp.RawValue{header.Seal[0], sigRlp}, header.Seal[2:]...)
+	header.WithSeal = true
+
+	// Wait until sealing is terminated or the step starts.
+	delay := time.Until(time.Unix(int64(c.step.inner.stepStart(step)), 0))
+	log.Trace("[aura] Waiting for step to sign and propagate", "step", step, "delay", common.PrettyDuration(delay))
+	go func() {
+		defer debug.LogPanic()
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		select {
+		case results <- block.WithSeal(header):
+		default:
+			log.Warn("[aura] Sealing result is not read by miner", "sealhash", c.SealHash(header))
+		}
+	}()
 	return nil
-	//header := block.Header()
-	//
-	/// Sealing the genesis block is not supported
-	//number := header.Number.Uint64()
-	//if number == 0 {
-	//	return errUnknownBlock
-	//}
-	/// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	//if c.config.Period == 0 && len(block.Transactions()) == 0 {
-	//	log.Info("Sealing paused, waiting for transactions")
-	//	return nil
-	//}
-	/// Don't hold the signer fields for the entire sealing procedure
-	//c.lock.RLock()
-	//signer, signFn := c.signer, c.signFn
-	//c.lock.RUnlock()
-	//
-	/// Bail out if we're unauthorized to sign a block
-	//snap, err := c.Snapshot(chain, number-1, header.ParentHash, nil)
-	//if err != nil {
-	//	return err
-	//}
-	//if _, authorized := snap.Signers[signer]; !authorized {
-	//	return ErrUnauthorizedSigner
-	//}
-	/// If we're amongst the recent signers, wait for the next block
-	//for seen, recent := range snap.Recents {
-	//	if recent == signer {
-	//		// Signer is among RecentsRLP, only wait if the current block doesn't shift it out
-	//		if limit := uint64(len(snap.Signers)/2 + 1); number < limit || seen > number-limit {
-	//			log.Info("Signed recently, must wait for others")
-	//			return nil
-	//		}
-	//	}
-	//}
-	/// Sweet, the protocol permits us to sign the block, wait for our time
-	//delay := time.Unix(int64(header.Time), 0).Sub(time.Now()) // nolint: gosimple
-	//if header.Difficulty.Cmp(diffNoTurn) == 0 {
-	//	// It's not our turn explicitly to sign, delay it a bit
-	//	wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
-	//	delay += time.Duration(rand.Int63n(int64(wiggle)))
-	//
-	//	log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
-	//}
-	/// Sign all the things!
-	//sighash, err := signFn(signer, accounts.MimetypeClique, CliqueRLP(header))
-	//if err != nil {
-	//	return err
-	//}
-	//copy(header.Extra[len(header.Extra)-ExtraSeal:], sighash)
-	/// Wait until sealing is terminated or delay timeout.
-	//log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
-	//go func() {
-	//	select {
-	//	case <-stop:
-	//		return
-	//	case <-time.After(delay):
-	//	}
-	//
-	//	select {
-	//	case results <- block.WithSeal(header):
-	//	default:
-	//		log.Warn("Sealing result is not read by miner", "sealhash", SealHash(header))
-	//	}
-	//}()
-	//
-	//return nil
+}
+
+// sealingValidators returns the validator set which is active for children of the given parent.
+// With non-immediate transitions it relies on the finality checker being already zoomed to
+// the parent, which happens when the parent is executed.
+func (c *AuRa) sealingValidators(parentHash common.Hash) (ValidatorSet, error) {
+	if c.cfg.ImmediateTransitions {
+		return c.cfg.Validators, nil
+	}
+	checker := c.EpochManager.finalityChecker
+	if checker.lastPushed == nil || *checker.lastPushed != parentHash {
+		return nil, fmt.Errorf("aura: validator set is not known for parent %x", parentHash)
+	}
+	return checker.signers, nil
+}
+
+// auraRLP returns the rlp bytes of the header without the seal fields. The signature
+// in the seal is made over the hash of these bytes (bare hash).
+func auraRLP(header *types.Header) []byte {
+	bare := types.CopyHeader(header)
+	bare.Seal = nil
+	bare.WithSeal = true
+	b, err := rlp.EncodeToBytes(bare)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return b
 }
 
 func stepProposer(validators ValidatorSet, blockHash common.Hash, step uint64, call consensus.Call) (common.Address, error) {
@@ -1245,8 +1491,15 @@ func calculateScore(parentStep, currentStep, currentEmptySteps uint64) *uint256.
 	return res
 }
 
+// FinalizedBlock implements consensus.Finality, returning the latest block finalized
+// by the rolling finality checker. Nothing is finalized until the first blocks are executed.
+func (c *AuRa) FinalizedBlock() (uint64, common.Hash) {
+	return c.EpochManager.finalized()
+}
+
+// SealHash returns the hash of a block prior to it being sealed (bare hash).
 func (c *AuRa) SealHash(header *types.Header) common.Hash {
-	return clique.SealHash(header)
+	return crypto.Keccak256Hash(auraRLP(header))
 }
 
 // Close implements consensus.Engine. It's a noop for clique as there are no background threads.
@@ -1279,7 +1532,7 @@ func (c *AuRa) emptySteps(fromStep, toStep uint64, parentHash common.Hash) []Emp
 
 	c.EmptyStepsSet.Sort()
 	c.EmptyStepsSet.ForEach(func(i int, step *EmptyStep) {
-		if step.Less(&from) || (&to).Less(step) {
+		if step.Less(&from) || to.LessOrEqual(step) {
 			return
 		}
 		if step.parentHash != parentHash {
@@ -1290,6 +1543,71 @@ func (c *AuRa) emptySteps(fromStep, toStep uint64, parentHash common.Hash) []Emp
 	return res
 }
 
+// generateEmptyStep signs an empty step message for the given step, stores it for inclusion
+// into the next block and gossips it to the other authorities.
+func (c *AuRa) generateEmptyStep(signer common.Address, signFn clique.SignerFn, parentHash common.Hash, step uint64) error {
+	message, err := EmptyStepRlp(step, parentHash)
+	if err != nil {
+		return err
+	}
+	signature, err := signFn(signer, accounts.MimetypeAuRa, message)
+	if err != nil {
+		return fmt.Errorf("aura: unable to sign empty step: %w", err)
+	}
+	emptyStep := &EmptyStep{signature: signature, step: step, parentHash: parentHash}
+	log.Trace("[aura] Broadcasting empty step message", "step", step, "parentHash", parentHash)
+	c.EmptyStepsSet.Insert(emptyStep)
+
+	c.lock.RLock()
+	broadcast := c.emptyStepBroadcaster
+	c.lock.RUnlock()
+	if broadcast == nil {
+		return nil
+	}
+	msg, err := emptyStep.fullRlp()
+	if err != nil {
+		return err
+	}
+	broadcast(msg)
+	return nil
+}
+
+// SetEmptyStepBroadcaster registers the function used to gossip empty step messages generated
+// by this node to other authorities.
+func (c *AuRa) SetEmptyStepBroadcaster(f func(msg []byte)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.emptyStepBroadcaster = f
+}
+
+// HandleEmptyStepMessage accumulates an empty step message received from another authority.
+// The message is accepted only if it's signed by the primary of its step and isn't from the future.
+func (c *AuRa) HandleEmptyStepMessage(msg []byte) error {
+	emptyStep, err := decodeEmptyStepFullRlp(msg)
+	if err != nil {
+		return fmt.Errorf("aura: invalid empty step message: %w", err)
+	}
+	c.step.inner.doCalibrate()
+	if currentStep := c.step.inner.inner.Load(); emptyStep.step > currentStep+1 {
+		return fmt.Errorf("aura: empty step message from the future: step=%d, current=%d", emptyStep.step, currentStep)
+	}
+	validators, err := c.sealingValidators(emptyStep.parentHash)
+	if err != nil {
+		return err
+	}
+	ok, err := emptyStep.verify(validators)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aura: empty step message is not signed by the step primary: step=%d", emptyStep.step)
+	}
+	if c.EmptyStepsSet.Insert(emptyStep) {
+		log.Trace("[aura] Accepted empty step message", "step", emptyStep.step, "parentHash", emptyStep.parentHash)
+	}
+	return nil
+}
+
 // AccumulateRewards returns rewards for a given block. The mining reward consists
 // of the static blockReward plus a reward for each included uncle (if any). Individual
 // uncle rewards are also returned in an array.
@@ -1297,6 +1615,21 @@ func AccumulateRewards(_ *params.ChainConfig, aura *AuRa, header *types.Header,
 	beneficiaries = append(beneficiaries, header.Coinbase)
 	rewardKind = append(rewardKind, aurainterfaces.RewardAuthor)
 
+	if header.Number.Uint64() >= aura.cfg.EmptyStepsTransition {
+		emptySteps, err := headerEmptySteps(header)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for i := range emptySteps {
+			author, err := emptySteps[i].author()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			beneficiaries = append(beneficiaries, author)
+			rewardKind = append(rewardKind, aurainterfaces.RewardEmptyStep)
+		}
+	}
+
 	var rewardContractAddress BlockRewardContract
 	var foundContract bool
 	for _, c := range aura.cfg.BlockRewardContractTransitions {
@@ -1364,6 +1697,37 @@ func callBlockRewardAbi(contractAddr common.Address, syscall consensus.SystemCal
 	return nil, nil
 }
 
+func callBlockGasLimitAbi(contractAddr common.Address, syscall consensus.SystemCall) (*uint256.Int, error) {
+	packed, err := blockGasLimitAbi().Pack("blockGasLimit")
+	if err != nil {
+		return nil, err
+	}
+	out, err := syscall(contractAddr, packed)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty result of blockGasLimit call")
+	}
+	res, err := blockGasLimitAbi().Unpack("blockGasLimit", out)
+	if err != nil {
+		return nil, err
+	}
+	gasLimit, overflow := uint256.FromBig(res[0].(*big.Int))
+	if overflow {
+		return nil, fmt.Errorf("blockGasLimit overflows uint256")
+	}
+	return gasLimit, nil
+}
+
+func blockGasLimitAbi() abi.ABI {
+	a, err := abi.JSON(bytes.NewReader(contracts.BlockGasLimit))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
 func blockRewardAbi() abi.ABI {
 	a, err := abi.JSON(bytes.NewReader(contracts.BlockReward))
 	if err != nil {
@@ -1375,21 +1739,20 @@ func blockRewardAbi() abi.ABI {
 // An empty step message that is included in a seal, the only difference is that it doesn't include
 // the `parent_hash` in order to save space. The included signature is of the original empty step
 // message, which can be reconstructed by using the parent hash of the block in which this sealed
-// empty message is inc    luded.
-//nolint
+// empty message is included.
 type SealedEmptyStep struct {
-	signature []byte // H520
-	step      uint64
+	Signature []byte // H520
+	Step      uint64
 }
 
-/*
 // extracts the empty steps from the header seal. should only be called when there are 3 fields in the seal
 // (i.e. header.number() >= self.empty_steps_transition).
 func headerEmptySteps(header *types.Header) ([]EmptyStep, error) {
-	s := headerEmptyStepsRaw(header)
+	if len(header.Seal) < 3 {
+		return nil, ErrInvalidSealFields
+	}
 	sealedSteps := []SealedEmptyStep{}
-	err := rlp.DecodeBytes(s, &sealedSteps)
-	if err != nil {
+	if err := rlp.DecodeBytes(header.Seal[2], &sealedSteps); err != nil {
 		return nil, err
 	}
 	steps := make([]EmptyStep, len(sealedSteps))
@@ -1401,21 +1764,20 @@ func headerEmptySteps(header *types.Header) ([]EmptyStep, error) {
 
 func newEmptyStepFromSealed(step SealedEmptyStep, parentHash common.Hash) EmptyStep {
 	return EmptyStep{
-		signature:  step.signature,
-		step:       step.step,
+		signature:  step.Signature,
+		step:       step.Step,
 		parentHash: parentHash,
 	}
 }
 
-// extracts the raw empty steps vec from the header seal. should only be called when there are 3 fields in the seal
-// (i.e. header.number() >= self.empty_steps_transition)
-func headerEmptyStepsRaw(header *types.Header) []byte {
-	if len(header.Seal) < 3 {
-		panic("was checked with verify_block_basic; has 3 fields; qed")
+// emptyStepsSealRlp encodes the empty steps as the third seal field.
+func emptyStepsSealRlp(steps []EmptyStep) ([]byte, error) {
+	sealed := make([]SealedEmptyStep, len(steps))
+	for i := range steps {
+		sealed[i] = steps[i].sealed()
 	}
-	return header.Seal[2]
+	return rlp.EncodeToBytes(sealed)
 }
-*/
 
 // A message broadcast by authorities when it's their turn to seal a block but there are no
 // transactions. Other authorities accumulate these messages and later include them in the seal as
@@ -1438,49 +1800,38 @@ type EmptyStep struct {
 	parentHash common.Hash //     H256
 }
 
-func (s *EmptyStep) Less(other *EmptyStep) bool {
-	if s.step < other.step {
-		return true
-	}
-	if bytes.Compare(s.parentHash[:], other.parentHash[:]) < 0 {
-		return true
+func (s *EmptyStep) compare(other *EmptyStep) int {
+	if s.step != other.step {
+		if s.step < other.step {
+			return -1
+		}
+		return 1
 	}
-	if bytes.Compare(s.signature, other.signature) < 0 {
-		return true
+	if c := bytes.Compare(s.parentHash[:], other.parentHash[:]); c != 0 {
+		return c
 	}
-	return false
+	return bytes.Compare(s.signature, other.signature)
 }
-func (s *EmptyStep) LessOrEqual(other *EmptyStep) bool {
-	if s.step <= other.step {
-		return true
-	}
-	if bytes.Compare(s.parentHash[:], other.parentHash[:]) <= 0 {
-		return true
-	}
-	if bytes.Compare(s.signature, other.signature) <= 0 {
-		return true
-	}
-	return false
+func (s *EmptyStep) Less(other *EmptyStep) bool        { return s.compare(other) < 0 }
+func (s *EmptyStep) LessOrEqual(other *EmptyStep) bool { return s.compare(other) <= 0 }
+
+func (s *EmptyStep) sealed() SealedEmptyStep {
+	return SealedEmptyStep{Signature: s.signature, Step: s.step}
 }
 
 // Returns `true` if the message has a valid signature by the expected proposer in the message's step.
-func (s *EmptyStep) verify(validators ValidatorSet) (bool, error) { //nolint
-	//sRlp, err := EmptyStepRlp(s.step, s.parentHash)
-	//if err != nil {
-	//	return false, err
-	//}
-	//message := crypto.Keccak256(sRlp)
-
-	/*
-		let correct_proposer = step_proposer(validators, &self.parent_hash, self.step);
-
-		publickey::verify_address(&correct_proposer, &self.signature.into(), &message)
-		.map_err(|e| e.into())
-	*/
-	return true, nil
+func (s *EmptyStep) verify(validators ValidatorSet) (bool, error) {
+	author, err := s.author()
+	if err != nil {
+		return false, err
+	}
+	correctProposer, err := stepProposer(validators, s.parentHash, s.step, nil)
+	if err != nil {
+		return false, err
+	}
+	return author == correctProposer, nil
 }
 
-//nolint
 func (s *EmptyStep) author() (common.Address, error) {
 	sRlp, err := EmptyStepRlp(s.step, s.parentHash)
 	if err != nil {
@@ -1498,6 +1849,28 @@ func (s *EmptyStep) author() (common.Address, error) {
 	return crypto.PubkeyToAddress(*ecdsa), nil
 }
 
+// fullRlp encodes the message the way it's gossiped between authorities.
+func (s *EmptyStep) fullRlp() ([]byte, error) {
+	sRlp, err := EmptyStepRlp(s.step, s.parentHash)
+	if err != nil {
+		return nil, err
+	}
+	return EmptyStepFullRlp(s.signature, sRlp)
+}
+
+// decodeEmptyStepFullRlp is the reverse of EmptyStep.fullRlp.
+func decodeEmptyStepFullRlp(data []byte) (*EmptyStep, error) {
+	var full emptyStepFull
+	if err := rlp.DecodeBytes(data, &full); err != nil {
+		return nil, err
+	}
+	var msg emptyStepMessage
+	if err := rlp.DecodeBytes(full.Message, &msg); err != nil {
+		return nil, err
+	}
+	return &EmptyStep{signature: full.Signature, step: msg.Step, parentHash: msg.ParentHash}, nil
+}
+
 type EmptyStepSet struct {
 	lock sync.Mutex
 	list []*EmptyStep
@@ -1521,21 +1894,48 @@ func (s *EmptyStepSet) ForEach(f func(int, *EmptyStep)) {
 	}
 }
 
-func EmptyStepFullRlp(signature []byte, emptyStepRlp []byte) ([]byte, error) {
-	type A struct {
-		s []byte
-		r []byte
+// Insert adds the message to the set, returns false if the same message is already there.
+func (s *EmptyStepSet) Insert(step *EmptyStep) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, el := range s.list {
+		if el.compare(step) == 0 {
+			return false
+		}
 	}
+	s.list = append(s.list, step)
+	return true
+}
 
-	return rlp.EncodeToBytes(A{s: signature, r: emptyStepRlp})
+// RemoveUpTo drops all messages with step less than or equal to the given one.
+func (s *EmptyStepSet) RemoveUpTo(step uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	kept := s.list[:0]
+	for _, el := range s.list {
+		if el.step > step {
+			kept = append(kept, el)
+		}
+	}
+	s.list = kept
+}
+
+type emptyStepFull struct {
+	Signature []byte
+	Message   rlp.RawValue
+}
+
+type emptyStepMessage struct {
+	Step       uint64
+	ParentHash common.Hash
+}
+
+func EmptyStepFullRlp(signature []byte, emptyStepRlp []byte) ([]byte, error) {
+	return rlp.EncodeToBytes(emptyStepFull{Signature: signature, Message: emptyStepRlp})
 }
 
 func EmptyStepRlp(step uint64, parentHash common.Hash) ([]byte, error) {
-	type A struct {
-		s uint64
-		h common.Hash
-	}
-	return rlp.EncodeToBytes(A{s: step, h: parentHash})
+	return rlp.EncodeToBytes(emptyStepMessage{Step: step, ParentHash: parentHash})
 }
 
 //nolint
@@ -1574,14 +1974,19 @@ type RollingFinality struct {
 	signers    *SimpleList
 	signCount  map[common.Address]uint
 	lastPushed *common.Hash // Option<H256>,
+	// First block for which a 2/3 quorum (instead of 1/2) is required.
+	twoThirdsMajorityTransition uint64
+	// The block found to be already finalized while building the ancestry sub-chain.
+	lastFinalized *unAssembledHeader
 }
 
 // NewRollingFinality creates a blank finality checker under the given validator set.
-func NewRollingFinality(signers []common.Address) *RollingFinality {
+func NewRollingFinality(signers []common.Address, twoThirdsMajorityTransition uint64) *RollingFinality {
 	return &RollingFinality{
-		signers:   NewSimpleList(signers),
-		headers:   unAssembledHeaders{l: list.New()},
-		signCount: map[common.Address]uint{},
+		signers:                     NewSimpleList(signers),
+		headers:                     unAssembledHeaders{l: list.New()},
+		signCount:                   map[common.Address]uint{},
+		twoThirdsMajorityTransition: twoThirdsMajorityTransition,
 	}
 }
 
@@ -1606,6 +2011,7 @@ func (f *RollingFinality) clear() {
 	f.headers = unAssembledHeaders{l: list.New()}
 	f.signCount = map[common.Address]uint{}
 	f.lastPushed = nil
+	f.lastFinalized = nil
 }
 
 // Push a hash onto the rolling finality checker (implying `subchain_head` == head.parent)
@@ -1635,11 +2041,16 @@ func (f *RollingFinality) push(head common.Hash, num uint64, signers []common.Ad
 }
 
 // isFinalized returns whether the first entry in `self.headers` is finalized.
+// Since twoThirdsMajorityTransition more than 2/3 of validators have to sign on top of
+// the block, before it - more than 1/2.
 func (f *RollingFinality) isFinalized() bool {
 	e := f.headers.Front()
 	if e == nil {
 		return false
 	}
+	if e.number >= f.twoThirdsMajorityTransition {
+		return len(f.signCount)*3 > len(f.signers.validators)*2
+	}
 	return len(f.signCount)*2 > len(f.signers.validators)
 }
 func (f *RollingFinality) hasSigner(signer common.Address) bool {
@@ -1705,6 +2116,7 @@ func (f *RollingFinality) buildAncestrySubChain(get func(hash common.Hash) ([]co
 				panic("we just pushed a block")
 			}
 			f.removeSigners(e.signers)
+			f.lastFinalized = e
 			//log.Info("[aura] finality encountered already finalized block", "hash", e.hash.String(), "number", e.number)
 			break
 		}
@@ -2,13 +2,16 @@ package aura_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/aura"
+	"github.com/ledgerwatch/erigon/consensus/aura/consensusconfig"
 	"github.com/ledgerwatch/erigon/consensus/aura/test"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
@@ -187,3 +190,25 @@ func TestRewardContract(t *testing.T) {
 	              )
 	*/
 }
+
+func TestGnosisConfig(t *testing.T) {
+	require := require.New(t)
+	var spec aura.JsonSpec
+	require.NoError(json.Unmarshal(consensusconfig.Gnosis, &spec))
+	params, err := aura.FromJson(spec)
+	require.NoError(err)
+	// the single reward contract is followed by the POSDAO one
+	require.Len(params.BlockRewardContractTransitions, 2)
+	require.NotNil(params.PosdaoTransition)
+	require.Equal(uint64(9186425), *params.PosdaoTransition)
+	// without maximumEmptySteps, the empty steps aren't limited, as in OpenEthereum
+	require.Equal(uint(math.MaxUint), params.MaximumEmptySteps)
+
+	_, err = aura.NewAuRa(nil, memdb.NewTestDB(t), common.Address{}, consensusconfig.Gnosis)
+	require.NoError(err)
+
+	// the single transition must precede the map of transitions
+	spec.BlockRewardContractTransitions[1000] = common.Address{1}
+	_, err = aura.FromJson(spec)
+	require.Error(err)
+}
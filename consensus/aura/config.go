@@ -18,6 +18,8 @@
 package aura
 
 import (
+	"fmt"
+	"math"
 	"sort"
 
 	"github.com/holiman/uint256"
@@ -57,8 +59,8 @@ func newValidatorSetFromJson(j *ValidatorSetJson, posdaoTransition *uint64) Vali
 	}
 	if j.Contract != nil {
 		return &ValidatorContract{
-			contractAddress:  *j.SafeContract,
-			validators:       ValidatorSafeContract{contractAddress: *j.SafeContract, posdaoTransition: posdaoTransition},
+			contractAddress:  *j.Contract,
+			validators:       NewValidatorSafeContract(*j.Contract, posdaoTransition, nil),
 			posdaoTransition: posdaoTransition,
 		}
 	}
@@ -109,6 +111,10 @@ type JsonSpec struct {
 	MaximumUncleCountTransition *uint64 `json:"maximumUncleCountTransition"`
 	// Maximum number of accepted uncles.
 	MaximumUncleCount *uint `json:"maximumUncleCount"`
+	// Block from which empty step messages are generated and included into seals.
+	EmptyStepsTransition *uint64 `json:"emptyStepsTransition"`
+	// Maximum number of consecutive empty steps before a validator has to seal a block without transactions.
+	MaximumEmptySteps *uint `json:"maximumEmptySteps"`
 	// Strict validation of empty steps transition block.
 	StrictEmptyStepsTransition *uint `json:"strictEmptyStepsTransition"`
 	// The random number contract's address, or a map of contract transitions.
@@ -119,6 +125,8 @@ type JsonSpec struct {
 	// The block number at which the consensus engine switches from AuRa to AuRa with POSDAO
 	// modifications.
 	PosdaoTransition *uint64 `json:"PosdaoTransition"`
+	// Block from which 2/3 of validators (instead of 1/2) have to sign on top of a block to finalize it.
+	TwoThirdsMajorityTransition *uint64 `json:"twoThirdsMajorityTransition"`
 }
 
 type Code struct {
@@ -178,6 +186,10 @@ type AuthorityRoundParams struct {
 	MaximumUncleCountTransition uint64
 	// Number of accepted uncles.
 	MaximumUncleCount uint
+	// Empty step messages transition block.
+	EmptyStepsTransition uint64
+	// Number of accepted empty steps.
+	MaximumEmptySteps uint
 	// Transition block to strict empty steps validation.
 	StrictEmptyStepsTransition uint64
 	// If set, enables random number contract integration. It maps the transition block to the contract address.
@@ -188,6 +200,8 @@ type AuthorityRoundParams struct {
 	// If set, this is the block number at which the consensus engine switches from AuRa to AuRa
 	// with POSDAO modifications.
 	PosdaoTransition *uint64
+	// Finality quorum transition block: from it on 2/3 of validators are required instead of 1/2.
+	TwoThirdsMajorityTransition uint64
 }
 
 func FromJson(jsonParams JsonSpec) (AuthorityRoundParams, error) {
@@ -203,36 +217,21 @@ func FromJson(jsonParams JsonSpec) (AuthorityRoundParams, error) {
 		params.StepDurations[0] = *jsonParams.StepDuration
 	}
 
-	//TODO: jsonParams.BlockRewardContractTransitions
-	/*
-			   let mut br_transitions: BTreeMap<_, _> = p
-		           .block_reward_contract_transitions
-		           .unwrap_or_default()
-		           .into_iter()
-		           .map(|(block_num, address)| {
-		               (
-		                   block_num.into(),
-		                   BlockRewardContract::new_from_address(address.into()),
-		               )
-		           })
-		           .collect();
-	*/
+	for blockNum, address := range jsonParams.BlockRewardContractTransitions {
+		params.BlockRewardContractTransitions = append(params.BlockRewardContractTransitions, BlockRewardContract{blockNum: uint64(blockNum), address: address})
+	}
 
 	transitionBlockNum := uint64(0)
 	if jsonParams.BlockRewardContractTransition != nil {
 		transitionBlockNum = *jsonParams.BlockRewardContractTransition
 	}
-	/*
-	   if (p.block_reward_contract_code.is_some() || p.block_reward_contract_address.is_some())
-	        && br_transitions
-	            .keys()
-	            .next()
-	            .map_or(false, |&block_num| block_num <= transition_block_num)
-	    {
-	        let s = "blockRewardContractTransition";
-	        panic!("{} should be less than any of the keys in {}s", s, s);
-	    }
-	*/
+	if jsonParams.BlockRewardContractCode != nil || jsonParams.BlockRewardContractAddress != nil {
+		for _, c := range params.BlockRewardContractTransitions {
+			if c.blockNum <= transitionBlockNum {
+				return params, fmt.Errorf("blockRewardContractTransition should be less than any of the keys in blockRewardContractTransitions")
+			}
+		}
+	}
 	if jsonParams.BlockRewardContractCode != nil {
 		/* TODO: support hard-coded reward contract
 		    br_transitions.insert(
@@ -260,6 +259,24 @@ func FromJson(jsonParams JsonSpec) (AuthorityRoundParams, error) {
 	if jsonParams.MaximumUncleCountTransition != nil {
 		params.MaximumUncleCountTransition = *jsonParams.MaximumUncleCountTransition
 	}
+	params.TwoThirdsMajorityTransition = math.MaxUint64
+	if jsonParams.TwoThirdsMajorityTransition != nil {
+		params.TwoThirdsMajorityTransition = *jsonParams.TwoThirdsMajorityTransition
+	}
+	params.EmptyStepsTransition = math.MaxUint64
+	if jsonParams.EmptyStepsTransition != nil {
+		params.EmptyStepsTransition = *jsonParams.EmptyStepsTransition
+		if params.EmptyStepsTransition < 1 {
+			params.EmptyStepsTransition = 1
+		}
+	}
+	params.MaximumEmptySteps = math.MaxUint
+	if jsonParams.MaximumEmptySteps != nil {
+		params.MaximumEmptySteps = *jsonParams.MaximumEmptySteps
+	}
+	if jsonParams.StrictEmptyStepsTransition != nil {
+		params.StrictEmptyStepsTransition = uint64(*jsonParams.StrictEmptyStepsTransition)
+	}
 
 	if jsonParams.BlockReward == nil {
 		params.BlockReward = append(params.BlockReward, BlockReward{blockNum: 0, amount: u256.Num0})
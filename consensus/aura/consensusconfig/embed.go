@@ -2,6 +2,7 @@ package consensusconfig
 
 import (
 	_ "embed"
+	"sync"
 
 	"github.com/ledgerwatch/erigon/params/networkname"
 )
@@ -9,10 +10,33 @@ import (
 //go:embed poasokol.json
 var Sokol []byte
 
+//go:embed poagnosis.json
+var Gnosis []byte
+
+var (
+	customLock sync.RWMutex
+	custom     = map[string][]byte{}
+)
+
+// Register sets engine params of a chain which isn't known in advance (e.g. loaded from a chain spec).
+func Register(chainName string, engineParams []byte) {
+	customLock.Lock()
+	defer customLock.Unlock()
+	custom[chainName] = engineParams
+}
+
 func GetConfigByChain(chainName string) []byte {
+	customLock.RLock()
+	engineParams, ok := custom[chainName]
+	customLock.RUnlock()
+	if ok {
+		return engineParams
+	}
 	switch chainName {
 	case networkname.SokolChainName:
 		return Sokol
+	case networkname.GnosisChainName:
+		return Gnosis
 	default:
 		return Sokol
 	}
@@ -6,3 +6,6 @@ import (
 
 //go:embed block_reward.json
 var BlockReward []byte
+
+//go:embed block_gas_limit.json
+var BlockGasLimit []byte
@@ -1,6 +1,7 @@
 package aura
 
 import (
+	"math"
 	"testing"
 
 	"github.com/ledgerwatch/erigon/common"
@@ -9,7 +10,7 @@ import (
 
 func TestRollingFinality(t *testing.T) {
 	t.Run("RejectsUnknownSigners", func(t *testing.T) {
-		f := NewRollingFinality([]common.Address{{1}, {2}, {3}})
+		f := NewRollingFinality([]common.Address{{1}, {2}, {3}}, math.MaxUint64)
 		_, err := f.push(common.Hash{}, 0, []common.Address{{0}, {4}})
 		assert.Error(t, err)
 		_, err = f.push(common.Hash{}, 0, []common.Address{{0}, {1}, {4}})
@@ -17,7 +18,7 @@ func TestRollingFinality(t *testing.T) {
 	})
 	t.Run("FinalizeMultiple", func(t *testing.T) {
 		signers := []common.Address{{0}, {1}, {2}, {3}, {4}, {5}}
-		f := NewRollingFinality(signers)
+		f := NewRollingFinality(signers, math.MaxUint64)
 		// 3 / 6 signers is < 51% so no finality.
 		for i := 0; i < 6; i++ {
 			l, err := f.push(common.Hash{byte(i)}, uint64(i%3), []common.Address{signers[i%3]})
@@ -33,9 +34,27 @@ func TestRollingFinality(t *testing.T) {
 		}
 		assert.Equal(t, 4, len(l))
 	})
+	t.Run("FinalizeMultipleTwoThirds", func(t *testing.T) {
+		signers := []common.Address{{0}, {1}, {2}, {3}, {4}, {5}}
+		f := NewRollingFinality(signers, 0)
+		// 4 / 6 signers is not > 2/3 so no finality.
+		for i := 0; i < 8; i++ {
+			l, err := f.push(common.Hash{byte(i)}, uint64(i), []common.Address{signers[i%4]})
+			assert.NoError(t, err)
+			assert.Equal(t, 0, len(l))
+		}
+		// after pushing a block signed by a fifth validator, the first five
+		// blocks of the unverified chain become verified.
+		l, err := f.push(common.Hash{byte(8)}, 8, []common.Address{signers[4]})
+		assert.NoError(t, err)
+		for i := uint64(0); i < 5; i++ {
+			assert.Equal(t, common.Hash{byte(i)}, l[i].hash)
+		}
+		assert.Equal(t, 5, len(l))
+	})
 	t.Run("FromAncestry", func(t *testing.T) {
 		signers := []common.Address{{0}, {1}, {2}, {3}, {4}, {5}}
-		f := NewRollingFinality(signers)
+		f := NewRollingFinality(signers, math.MaxUint64)
 		i := 12
 		get := func(hash common.Hash) ([]common.Address, common.Hash, common.Hash, uint64, bool) {
 			i--
@@ -51,7 +70,7 @@ func TestRollingFinality(t *testing.T) {
 	})
 	t.Run("FromAncestryMultipleSigners", func(t *testing.T) {
 		signers := []common.Address{{0}, {1}, {2}, {3}, {4}, {5}}
-		f := NewRollingFinality(signers)
+		f := NewRollingFinality(signers, math.MaxUint64)
 		i := 12
 		get := func(hash common.Hash) ([]common.Address, common.Hash, common.Hash, uint64, bool) {
 			i--
@@ -0,0 +1,285 @@
+package aura
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+type headerReader struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (r headerReader) Config() *params.ChainConfig  { return params.TestChainAuraConfig }
+func (r headerReader) CurrentHeader() *types.Header { return nil }
+func (r headerReader) GetHeader(hash common.Hash, _ uint64) *types.Header {
+	return r.headers[hash]
+}
+func (r headerReader) GetHeaderByNumber(number uint64) *types.Header { return nil }
+func (r headerReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.headers[hash]
+}
+func (r headerReader) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+func sealedHeader(t *testing.T, number, step uint64) *types.Header {
+	stepRlp, err := rlp.EncodeToBytes(step)
+	require.NoError(t, err)
+	sigRlp, err := rlp.EncodeToBytes(make([]byte, crypto.SignatureLength))
+	require.NoError(t, err)
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: big.NewInt(0x20000),
+		Seal:       []rlp.RawValue{stepRlp, sigRlp},
+		WithSeal:   true,
+	}
+}
+
+func TestSeal(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+
+	// step 3 belongs to the second validator in the list
+	spec := []byte(`{"stepDuration": 1, "startStep": 3, "immediateTransitions": true,
+		"validators": {"list": ["` + addr1.Hex() + `", "` + addr2.Hex() + `"]}}`)
+	engine, err := NewAuRa(nil, nil, addr2, spec)
+	require.NoError(t, err)
+
+	parent := sealedHeader(t, 0, 1)
+	chain := headerReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+	prepare := func(coinbase common.Address) *types.Header {
+		header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), Coinbase: coinbase}
+		require.NoError(t, engine.Prepare(chain, header, nil))
+		return header
+	}
+	signWith := func(key *ecdsa.PrivateKey) func(common.Address, string, []byte) ([]byte, error) {
+		return func(_ common.Address, _ string, message []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(message), key)
+		}
+	}
+
+	header := prepare(addr2)
+	step, err := headerStep(header)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), step)
+	require.Equal(t, calculateScore(1, 3, 0).ToBig(), header.Difficulty)
+
+	t.Run("not a proposer", func(t *testing.T) {
+		engine.Authorize(addr1, signWith(key1))
+		results := make(chan *types.Block, 1)
+		require.NoError(t, engine.Seal(chain, types.NewBlockWithHeader(prepare(addr1)), results, nil))
+		require.Empty(t, results)
+	})
+
+	t.Run("proposer", func(t *testing.T) {
+		engine.Authorize(addr2, signWith(key2))
+		results := make(chan *types.Block, 1)
+		require.NoError(t, engine.Seal(chain, types.NewBlockWithHeader(header), results, nil))
+
+		var sealed *types.Block
+		select {
+		case sealed = <-results:
+		case <-time.After(time.Second):
+			t.Fatal("block was not sealed")
+		}
+		var sig []byte
+		require.NoError(t, rlp.DecodeBytes(sealed.Header().Seal[1], &sig))
+		pub, err := crypto.Ecrecover(engine.SealHash(sealed.Header()).Bytes(), sig)
+		require.NoError(t, err)
+		pubKey, err := crypto.UnmarshalPubkeyStd(pub)
+		require.NoError(t, err)
+		require.Equal(t, addr2, crypto.PubkeyToAddress(*pubKey))
+
+		// only one block per step
+		require.NoError(t, engine.Seal(chain, types.NewBlockWithHeader(header), results, nil))
+		require.Empty(t, results)
+	})
+}
+
+func TestEmptySteps(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+	signWith := func(key *ecdsa.PrivateKey) func(common.Address, string, []byte) ([]byte, error) {
+		return func(_ common.Address, _ string, message []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(message), key)
+		}
+	}
+	newEngine := func(startStep uint64, signer common.Address) *AuRa {
+		spec := []byte(`{"stepDuration": 1, "startStep": ` + big.NewInt(int64(startStep)).String() + `, "immediateTransitions": true,
+			"emptyStepsTransition": 1, "maximumEmptySteps": 1,
+			"validators": {"list": ["` + addr1.Hex() + `", "` + addr2.Hex() + `"]}}`)
+		engine, err := NewAuRa(nil, nil, signer, spec)
+		require.NoError(t, err)
+		return engine
+	}
+
+	parent := sealedHeader(t, 0, 1)
+	chain := headerReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+
+	// validator 2 is the primary of step 3, but has no transactions
+	engine2 := newEngine(3, addr2)
+	engine2.Authorize(addr2, signWith(key2))
+	var gossip [][]byte
+	engine2.SetEmptyStepBroadcaster(func(msg []byte) { gossip = append(gossip, msg) })
+
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), Coinbase: addr2}
+	require.NoError(t, engine2.Prepare(chain, header, nil))
+	require.Equal(t, 3, len(header.Seal))
+	results := make(chan *types.Block, 1)
+	require.NoError(t, engine2.Seal(chain, types.NewBlockWithHeader(header), results, nil))
+	require.Empty(t, results)
+	require.Equal(t, 1, len(gossip))
+
+	// validator 1 accumulates the message and, as the limit of empty steps is reached,
+	// seals a block without transactions in step 4
+	engine1 := newEngine(4, addr1)
+	engine1.Authorize(addr1, signWith(key1))
+	require.NoError(t, engine1.HandleEmptyStepMessage(gossip[0]))
+	require.NoError(t, engine1.HandleEmptyStepMessage(gossip[0]))
+	require.Equal(t, 1, engine1.EmptyStepsSet.Len())
+
+	header = &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), Coinbase: addr1}
+	require.NoError(t, engine1.Prepare(chain, header, nil))
+	require.Equal(t, calculateScore(1, 4, 1).ToBig(), header.Difficulty)
+	require.NoError(t, engine1.Seal(chain, types.NewBlockWithHeader(header), results, nil))
+	var sealed *types.Block
+	select {
+	case sealed = <-results:
+	case <-time.After(time.Second):
+		t.Fatal("block was not sealed")
+	}
+
+	emptySteps, err := headerEmptySteps(sealed.Header())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(emptySteps))
+	author, err := emptySteps[0].author()
+	require.NoError(t, err)
+	require.Equal(t, addr2, author)
+	require.NoError(t, engine1.VerifyHeader(chain, sealed.Header(), true))
+
+	// a message signed by somebody else than the step primary is rejected
+	forged, err := EmptyStepRlp(3, parent.Hash())
+	require.NoError(t, err)
+	sig, err := crypto.Sign(crypto.Keccak256(forged), key1)
+	require.NoError(t, err)
+	msg, err := EmptyStepFullRlp(sig, forged)
+	require.NoError(t, err)
+	require.Error(t, engine1.HandleEmptyStepMessage(msg))
+
+	// and so are the blocks with such empty steps in their seal, or with more empty steps than the maximum
+	emptyStep := func(key *ecdsa.PrivateKey) EmptyStep {
+		msg, err := EmptyStepRlp(3, parent.Hash())
+		require.NoError(t, err)
+		sig, err := crypto.Sign(crypto.Keccak256(msg), key)
+		require.NoError(t, err)
+		return EmptyStep{signature: sig, step: 3, parentHash: parent.Hash()}
+	}
+	withEmptySteps := func(steps ...EmptyStep) *types.Header {
+		header := sealedHeader(t, 1, 4)
+		header.ParentHash = parent.Hash()
+		sealRlp, err := emptyStepsSealRlp(steps)
+		require.NoError(t, err)
+		header.Seal = append(header.Seal, sealRlp)
+		return header
+	}
+	require.NoError(t, engine1.VerifyHeader(chain, withEmptySteps(emptyStep(key2)), true))
+	require.Error(t, engine1.VerifyHeader(chain, withEmptySteps(emptyStep(key1)), true))
+	require.Error(t, engine1.VerifyHeader(chain, withEmptySteps(emptyStep(key2), emptyStep(key2)), true))
+}
+
+func TestGasLimitOverride(t *testing.T) {
+	contract := common.HexToAddress("0x1000000000000000000000000000000000000005")
+	spec := []byte(`{"stepDuration": 1, "validators": {"list": ["0x0000000000000000000000000000000000000001"]},
+		"blockGasLimitContractTransitions": {"2": "` + contract.Hex() + `"}}`)
+	engine, err := NewAuRa(nil, nil, common.Address{}, spec)
+	require.NoError(t, err)
+
+	calls := 0
+	gasLimit := uint64(10_000_000)
+	syscall := func(addr common.Address, data []byte) ([]byte, error) {
+		calls++
+		require.Equal(t, contract, addr)
+		return common.LeftPadBytes(new(big.Int).SetUint64(gasLimit).Bytes(), 32), nil
+	}
+
+	// before the transition block there is no override
+	_, ok := engine.GasLimitOverride(&types.Header{Number: big.NewInt(1)}, syscall)
+	require.False(t, ok)
+	require.Equal(t, 0, calls)
+
+	header := &types.Header{Number: big.NewInt(2), ParentHash: common.Hash{1}, GasLimit: gasLimit}
+	limit, ok := engine.GasLimitOverride(header, syscall)
+	require.True(t, ok)
+	require.Equal(t, gasLimit, limit)
+
+	// the value is memoized by parent hash
+	_, ok = engine.GasLimitOverride(header, syscall)
+	require.True(t, ok)
+	require.Equal(t, 1, calls)
+
+	// zero means no override
+	gasLimit = 0
+	_, ok = engine.GasLimitOverride(&types.Header{Number: big.NewInt(3), ParentHash: common.Hash{2}}, syscall)
+	require.False(t, ok)
+
+	// a block with another gas limit is rejected
+	gasLimit = 8_000_000
+	header = &types.Header{Number: big.NewInt(3), ParentHash: common.Hash{3}, GasLimit: 10_000_000}
+	engine.Initialize(params.TestChainAuraConfig, nil, noEpochs{}, header, nil, nil, syscall)
+	_, _, err = engine.Finalize(params.TestChainAuraConfig, header, nil, nil, nil, nil, nil, nil, syscall)
+	require.ErrorIs(t, err, errInvalidGasLimit)
+}
+
+// noEpochs is an EpochReader without any epoch transition
+type noEpochs struct{}
+
+func (noEpochs) GetEpoch(common.Hash, uint64) ([]byte, error)        { return nil, nil }
+func (noEpochs) PutEpoch(common.Hash, uint64, []byte) error          { return nil }
+func (noEpochs) GetPendingEpoch(common.Hash, uint64) ([]byte, error) { return nil, nil }
+func (noEpochs) PutPendingEpoch(common.Hash, uint64, []byte) error   { return nil }
+func (noEpochs) FindBeforeOrEqualNumber(uint64) (uint64, common.Hash, []byte, error) {
+	return 0, common.Hash{}, nil, nil
+}
+
+func TestGasLimitOverrideChangedInBlock(t *testing.T) {
+	contract := common.HexToAddress("0x1000000000000000000000000000000000000005")
+	spec := []byte(`{"stepDuration": 1, "validators": {"list": ["0x0000000000000000000000000000000000000001"]},
+		"blockGasLimitContractTransitions": {"2": "` + contract.Hex() + `"}}`)
+	engine, err := NewAuRa(nil, nil, common.Address{}, spec)
+	require.NoError(t, err)
+
+	calls := 0
+	gasLimit := uint64(10_000_000)
+	syscall := func(addr common.Address, data []byte) ([]byte, error) {
+		calls++
+		return common.LeftPadBytes(new(big.Int).SetUint64(gasLimit).Bytes(), 32), nil
+	}
+
+	// a transaction of the block changes the answer of the contract after Initialize, the block is verified
+	// against the answer at its start
+	header := &types.Header{Number: big.NewInt(2), ParentHash: common.Hash{1}, GasLimit: 8_000_000}
+	engine.Initialize(params.TestChainAuraConfig, nil, noEpochs{}, header, nil, nil, syscall)
+	gasLimit = 8_000_000
+	_, _, err = engine.Finalize(params.TestChainAuraConfig, header, nil, nil, nil, nil, nil, nil, syscall)
+	require.ErrorIs(t, err, errInvalidGasLimit)
+	require.Contains(t, err.Error(), "want 10000000")
+	require.Equal(t, 1, calls)
+
+	// so is a block without an override at its start
+	gasLimit = 0
+	header = &types.Header{Number: big.NewInt(3), ParentHash: common.Hash{2}, GasLimit: 8_000_000}
+	engine.Initialize(params.TestChainAuraConfig, nil, noEpochs{}, header, nil, nil, syscall)
+	gasLimit = 10_000_000
+	_, ok := engine.GasLimitOverride(header, syscall)
+	require.False(t, ok)
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,88 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+// AuRa has its own consensus database, so the generic snapshots table of it is reused for epoch snapshots.
+const epochSnapshotsTable = kv.CliqueSeparate
+
+// The number of recent epoch snapshots kept in memory.
+const inmemoryEpochSnapshots = 128
+
+// epochSnapshot is the validator set which is in effect since an epoch transition block.
+type epochSnapshot struct {
+	Number     uint64           `json:"number"`     // Number of the epoch transition block
+	Hash       common.Hash      `json:"hash"`       // Hash of the epoch transition block
+	Validators []common.Address `json:"validators"` // Validator set of the epoch
+}
+
+// epochSnapshots stores validator sets by epoch transition, so the validator set contract
+// doesn't have to be called again for epochs which were already seen (after restart or on reorg).
+type epochSnapshots struct {
+	db     kv.RwDB    // Database to persist snapshots to, can be nil
+	recent *lru.Cache // Recently used snapshots, by epoch transition hash
+}
+
+func newEpochSnapshots(db kv.RwDB) *epochSnapshots {
+	recent, err := lru.New(inmemoryEpochSnapshots)
+	if err != nil {
+		panic("error creating epoch snapshots cache")
+	}
+	return &epochSnapshots{db: db, recent: recent}
+}
+
+// get returns the snapshot of the epoch started at the given transition block, nil if there is none.
+func (s *epochSnapshots) get(number uint64, hash common.Hash) (*epochSnapshot, error) {
+	if snap, ok := s.recent.Get(hash); ok {
+		return snap.(*epochSnapshot), nil
+	}
+	if s.db == nil {
+		return nil, nil
+	}
+	tx, err := s.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	blob, err := tx.GetOne(epochSnapshotsTable, epochSnapshotKey(number, hash))
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	snap := new(epochSnapshot)
+	if err := json.Unmarshal(blob, snap); err != nil {
+		return nil, err
+	}
+	s.recent.Add(hash, snap)
+	return snap, nil
+}
+
+// put stores the snapshot in memory and in the database.
+func (s *epochSnapshots) put(snap *epochSnapshot) error {
+	s.recent.Add(snap.Hash, snap)
+	if s.db == nil {
+		return nil
+	}
+	blob, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(epochSnapshotsTable, epochSnapshotKey(snap.Number, snap.Hash), blob)
+	})
+}
+
+// epochSnapshotKey = num (uint64 big endian) + hash
+func epochSnapshotKey(number uint64, hash common.Hash) []byte {
+	return append(dbutils.EncodeBlockNumber(number), hash.Bytes()...)
+}
@@ -0,0 +1,37 @@
+package aura
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEpochSnapshots(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	snap := &epochSnapshot{Number: 10, Hash: common.Hash{10}, Validators: []common.Address{{1}, {2}}}
+
+	snapshots := newEpochSnapshots(db)
+	missing, err := snapshots.get(snap.Number, snap.Hash)
+	require.NoError(t, err)
+	require.Nil(t, missing)
+	require.NoError(t, snapshots.put(snap))
+
+	// after restart the snapshot is loaded from the database
+	loaded, err := newEpochSnapshots(db).get(snap.Number, snap.Hash)
+	require.NoError(t, err)
+	require.Equal(t, snap, loaded)
+
+	// a transition block on another fork has its own snapshot
+	missing, err = newEpochSnapshots(db).get(snap.Number, common.Hash{11})
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	// without a database snapshots are kept in memory only
+	snapshots = newEpochSnapshots(nil)
+	require.NoError(t, snapshots.put(snap))
+	loaded, err = snapshots.get(snap.Number, snap.Hash)
+	require.NoError(t, err)
+	require.Equal(t, snap, loaded)
+}
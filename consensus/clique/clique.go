@@ -141,6 +141,14 @@ var (
 // SignerFn hashes and signs the data to be signed by a backing account.
 type SignerFn func(signer common.Address, mimeType string, message []byte) ([]byte, error)
 
+// DevMode lets a developer chain control the clock of the engine and the sealing of empty blocks.
+type DevMode interface {
+	// Now is the time the blocks are stamped and verified against
+	Now() time.Time
+	// SealEmpty reports if an empty block is sealed on a 0-period chain, because it was requested
+	SealEmpty() bool
+}
+
 // ecrecover extracts the Ethereum account address from a signed header.
 func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
 	// If the signature's already cached, return that
@@ -187,6 +195,8 @@ type Clique struct {
 	signFn SignerFn       // Signer function to authorize hashes with
 	lock   sync.RWMutex   // Protects the signer and proposals fields
 
+	dev DevMode // Developer chain controls, nil outside of it
+
 	// The fields below are for testing only
 	FakeDiff bool // Skip difficulty verifications
 
@@ -349,10 +359,14 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	}
 	header.Time = parent.Time + c.config.Period
 
-	now := uint64(time.Now().Unix())
+	now := uint64(c.now().Unix())
 	if header.Time < now {
 		header.Time = now
 	}
+	// on the developer chain the timestamps increase even with a 0 period, as on Hardhat and Anvil
+	if c.dev != nil && header.Time == parent.Time {
+		header.Time++
+	}
 
 	return nil
 }
@@ -394,6 +408,19 @@ func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// SetDevMode hands the clock and the sealing of empty blocks to a developer chain.
+// It is set before the engine is used.
+func (c *Clique) SetDevMode(dev DevMode) {
+	c.dev = dev
+}
+
+func (c *Clique) now() time.Time {
+	if c.dev != nil {
+		return c.dev.Now()
+	}
+	return time.Now()
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
@@ -406,7 +433,7 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 		return errUnknownBlock
 	}
 	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if c.config.Period == 0 && len(block.Transactions()) == 0 {
+	if c.config.Period == 0 && len(block.Transactions()) == 0 && (c.dev == nil || !c.dev.SealEmpty()) {
 		log.Info("Sealing paused, waiting for transactions")
 		return nil
 	}
@@ -434,7 +461,7 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 		}
 	}
 	// Sweet, the protocol permits us to sign the block, wait for our time
-	delay := time.Unix(int64(header.Time), 0).Sub(time.Now()) // nolint: gosimple
+	delay := time.Unix(int64(header.Time), 0).Sub(c.now()) // nolint: gosimple
 	if header.Difficulty.Cmp(diffNoTurn) == 0 {
 		// It's not our turn explicitly to sign, delay it a bit
 		wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
@@ -137,3 +137,22 @@ func TestReimportMirroredState(t *testing.T) {
 	}
 
 }
+
+func TestProposals(t *testing.T) {
+	config := params.AllCliqueProtocolChanges
+	engine := clique.New(config, params.CliqueSnapshot, memdb.NewTestDB(t))
+	defer engine.Close()
+
+	a, b := common.Address{1}, common.Address{2}
+	engine.Propose(a, true)
+	engine.Propose(b, false)
+	if got := engine.Proposals(); len(got) != 2 || !got[a] || got[b] {
+		t.Fatalf("unexpected proposals %v", got)
+	}
+	// the returned map is a copy
+	engine.Proposals()[a] = false
+	engine.Discard(b)
+	if got := engine.Proposals(); len(got) != 1 || !got[a] {
+		t.Fatalf("unexpected proposals after discard %v", got)
+	}
+}
@@ -3,7 +3,6 @@ package clique
 import (
 	"bytes"
 	"fmt"
-	"time"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
@@ -23,8 +22,7 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	}
 	number := header.Number.Uint64()
 
-	now := time.Now()
-	nowUnix := now.Unix()
+	nowUnix := c.now().Unix()
 
 	// Don't waste time checking blocks from the future
 	if header.Time > uint64(nowUnix) {
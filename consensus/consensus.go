@@ -168,3 +168,21 @@ type AsyncEngine interface {
 
 	WithExecutionContext(context.Context) AsyncEngine
 }
+
+// GasLimitProvider is an engine which may take the block gas limit from a contract instead of the miner config.
+type GasLimitProvider interface {
+	Engine
+
+	// GasLimitOverride returns the gas limit the given block must have, if it's enforced by the engine.
+	// The syscall must be executed on top of the parent block state.
+	GasLimitOverride(header *types.Header, syscall SystemCall) (uint64, bool)
+}
+
+// Finality is an engine which decides on block finality by itself (e.g. AuRa rolling finality).
+// Stages may use it to avoid pruning data which is still needed to unwind non-final blocks.
+type Finality interface {
+	Engine
+
+	// FinalizedBlock returns number and hash of the latest known finalized block.
+	FinalizedBlock() (uint64, common.Hash)
+}
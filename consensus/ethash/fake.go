@@ -8,9 +8,16 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/log/v3"
 )
 
+func init() {
+	consensus.Register(string(params.NoProofConsensus), func(_ *params.ChainConfig, _ string) (consensus.Engine, error) {
+		return NewFaker(), nil
+	})
+}
+
 type FakeEthash struct {
 	Ethash
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
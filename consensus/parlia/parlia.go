@@ -138,6 +138,10 @@ var (
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
+
+	// errMissingSignFn is returned when a block is sealed, or a system transaction signed,
+	// before the validator was authorized with its signing key.
+	errMissingSignFn = errors.New("validator is not authorized with a signing key")
 )
 
 // SignFn is a signer callback function to request a header to be signed by a
@@ -804,6 +808,9 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	p.lock.RLock()
 	val, signFn := p.val, p.signFn
 	p.lock.RUnlock()
+	if signFn == nil {
+		return errMissingSignFn
+	}
 
 	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil, false /* verify */)
 	if err != nil {
@@ -1144,6 +1151,9 @@ func (p *Parlia) applyTransaction(from common.Address, to common.Address, value
 	expectedTx := types.Transaction(types.NewTransaction(nonce, to, value, math.MaxUint64/2, u256.Num0, data))
 	expectedHash := expectedTx.SigningHash(p.chainConfig.ChainID)
 	if from == p.val && mining {
+		if p.signFn == nil {
+			return nil, nil, nil, errMissingSignFn
+		}
 		signature, err := p.signFn(from, expectedTx.SigningHash(p.chainConfig.ChainID).Bytes(), p.chainConfig.ChainID)
 		if err != nil {
 			return nil, nil, nil, err
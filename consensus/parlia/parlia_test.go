@@ -0,0 +1,62 @@
+package parlia
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiningSystemTransactions(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	val := crypto.PubkeyToAddress(key.PublicKey)
+	spoiledVal := common.HexToAddress("0x1234")
+
+	config := *params.BSCChainConfig
+	p := New(&config, nil, nil)
+	header := &types.Header{Number: big.NewInt(100), Coinbase: val, Difficulty: new(big.Int).Set(diffNoTurn), GasLimit: 30_000_000}
+
+	// without the signing key the validator can't produce the slash transaction
+	unauthorized := &types.Header{Number: header.Number, Difficulty: header.Difficulty, GasLimit: header.GasLimit}
+	_, _, _, err = p.slash(spoiledVal, ibs, unauthorized, 0, nil, &unauthorized.GasUsed, true)
+	require.ErrorIs(t, err, errMissingSignFn)
+
+	p.Authorize(val, func(_ common.Address, payload []byte, _ *big.Int) ([]byte, error) {
+		return crypto.Sign(payload, key)
+	})
+	systemTxs, slashTx, receipt, err := p.slash(spoiledVal, ibs, header, 3, nil, &header.GasUsed, true)
+	require.NoError(t, err)
+	require.Empty(t, systemTxs)
+	require.Equal(t, systemcontracts.SlashContract, *slashTx.GetTo())
+	require.Equal(t, uint64(0), slashTx.GetNonce())
+	require.Equal(t, uint(3), receipt.TransactionIndex)
+	require.Equal(t, slashTx.Hash(), receipt.TxHash)
+
+	sender, err := slashTx.Sender(*types.LatestSignerForChainID(config.ChainID))
+	require.NoError(t, err)
+	require.Equal(t, val, sender)
+	isSystemTx, err := p.IsSystemTransaction(slashTx, header)
+	require.NoError(t, err)
+	require.True(t, isSystemTx)
+
+	// the next system transaction of the block gets the next nonce
+	_, depositTx, _, err := p.distributeToValidator(u256.Num0, val, ibs, header, 4, nil, &header.GasUsed, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), depositTx.GetNonce())
+
+	// when importing, the system transactions of the block must match the expected ones
+	_, _, _, err = p.slash(spoiledVal, ibs, header, 3, nil, &header.GasUsed, false)
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// EngineFactory creates a consensus engine for the given chain. datadir is the node's data
+// directory, engines which keep their own database should put it there.
+type EngineFactory func(chainConfig *params.ChainConfig, datadir string) (Engine, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]EngineFactory{}
+)
+
+// Register makes a consensus engine available by the provided name, which is matched against
+// the `consensus` field of the chain config. Engines registered this way take precedence over
+// the built-in ones. If Register is called twice with the same name or if factory is nil, it panics.
+func Register(name string, factory EngineFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("consensus: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic(fmt.Sprintf("consensus: Register called twice for engine %q", name))
+	}
+	factories[name] = factory
+}
+
+// Lookup returns the factory of the consensus engine registered by the provided name.
+func Lookup(name string) (EngineFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	factory, ok := factories[name]
+	return factory, ok
+}
+
+// Engines returns a sorted list of the names of the registered consensus engines.
+func Engines() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
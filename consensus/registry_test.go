@@ -0,0 +1,31 @@
+package consensus_test
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	factory := func(_ *params.ChainConfig, _ string) (consensus.Engine, error) {
+		return ethash.NewFullFaker(), nil
+	}
+	consensus.Register("test-engine", factory)
+	require.Panics(t, func() { consensus.Register("test-engine", factory) })
+	require.Panics(t, func() { consensus.Register("test-nil", nil) })
+
+	_, ok := consensus.Lookup("unknown")
+	require.False(t, ok)
+	f, ok := consensus.Lookup("test-engine")
+	require.True(t, ok)
+	engine, err := f(params.AllEthashProtocolChanges, "")
+	require.NoError(t, err)
+	require.IsType(t, &ethash.FullFakeEthash{}, engine)
+
+	// the engines of this repo register themselves
+	require.Contains(t, consensus.Engines(), string(params.NoProofConsensus))
+	require.Contains(t, consensus.Engines(), "test-engine")
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// ChainDefinition describes a network which isn't built into Erigon (see --chain.config).
+// The fork blocks and the consensus engine are given by the config of the genesis,
+// the engine params are passed to the engine as if they were embedded for the chain.
+type ChainDefinition struct {
+	Name         string          `json:"name"`
+	NetworkID    uint64          `json:"networkId"`
+	Bootnodes    []string        `json:"bootnodes"`
+	EngineParams json.RawMessage `json:"engineParams"`
+	Genesis      *Genesis        `json:"genesis"`
+}
+
+var (
+	customGenesisLock sync.RWMutex
+	customGenesis     = map[string]*Genesis{}
+)
+
+// ReadChainDefinition reads the chain definition from the JSON file.
+func ReadChainDefinition(filename string) (*ChainDefinition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	def := &ChainDefinition{}
+	if err := json.Unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("parsing chain definition %s: %w", filename, err)
+	}
+	return def, nil
+}
+
+// RegisterChain registers the network, so it can be selected by its name like the built-in ones.
+func RegisterChain(def *ChainDefinition) error {
+	if def.Name == "" {
+		return fmt.Errorf("chain definition has no name")
+	}
+	if def.Genesis == nil || def.Genesis.Config == nil {
+		return fmt.Errorf("chain definition %s has no genesis config", def.Name)
+	}
+	def.Genesis.Config.ChainName = def.Name
+	if err := def.Genesis.Config.CheckConfigForkOrder(); err != nil {
+		return fmt.Errorf("chain definition %s: %w", def.Name, err)
+	}
+	block, _, err := def.Genesis.ToBlock()
+	if err != nil {
+		return fmt.Errorf("chain definition %s: %w", def.Name, err)
+	}
+	if err := params.RegisterChain(&params.CustomChain{
+		Name:        def.Name,
+		Config:      def.Genesis.Config,
+		GenesisHash: block.Hash(),
+		NetworkID:   def.NetworkID,
+		Bootnodes:   def.Bootnodes,
+	}); err != nil {
+		return err
+	}
+	customGenesisLock.Lock()
+	defer customGenesisLock.Unlock()
+	customGenesis[def.Name] = def.Genesis
+	return nil
+}
+
+func customGenesisBlock(network string) *Genesis {
+	customGenesisLock.RLock()
+	defer customGenesisLock.RUnlock()
+	return customGenesis[network]
+}
@@ -0,0 +1,99 @@
+// Package forkstate forks the dev chain from another network at a block: the state of the network is read on
+// demand, from a JSON-RPC endpoint or the datadir of an Erigon node, see state.ForkBackend.
+package forkstate
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Config of the network the chain is forked from, either URL or Datadir is set
+type Config struct {
+	URL     string  // JSON-RPC endpoint of a node of the network
+	Datadir string  // Datadir of an Erigon node of the network, the node may be running
+	Block   *uint64 // Block the chain is forked at, the latest block of the network if nil
+}
+
+func (c Config) Enabled() bool { return c.URL != "" || c.Datadir != "" }
+
+// forkBlockKey is the key of the block the chain is forked at in kv.DatabaseInfo
+var forkBlockKey = []byte("forkBlock")
+
+// Fork is the state of the network the chain is forked from
+type Fork struct {
+	*state.ForkBackend
+	Block uint64
+	src   source
+}
+
+// Open opens the network the chain of db is forked from. A chain is forked before it has any block but the
+// genesis, the block is kept in db and the chain can't be forked at another block later.
+func Open(ctx context.Context, cfg Config, db kv.RwDB, logger log.Logger) (*Fork, error) {
+	if cfg.URL != "" && cfg.Datadir != "" {
+		return nil, errors.New("the chain is forked either from a JSON-RPC endpoint or from a datadir, not both")
+	}
+	var src source
+	var err error
+	if cfg.URL != "" {
+		src, err = openRPCSource(ctx, cfg.URL)
+	} else {
+		src, err = openDBSource(cfg.Datadir, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, err := forkBlock(ctx, cfg, db, src)
+	if err != nil {
+		src.close()
+		return nil, err
+	}
+	return &Fork{ForkBackend: state.NewForkBackend(src.reader(block)), Block: block, src: src}, nil
+}
+
+func (f *Fork) Close() { f.src.close() }
+
+// forkBlock returns the block kept in db, or keeps the block of cfg or the latest block of the network
+func forkBlock(ctx context.Context, cfg Config, db kv.RwDB, src source) (block uint64, err error) {
+	err = db.Update(ctx, func(tx kv.RwTx) error {
+		v, err := tx.GetOne(kv.DatabaseInfo, forkBlockKey)
+		if err != nil {
+			return err
+		}
+		if len(v) == 8 {
+			block = binary.BigEndian.Uint64(v)
+			if cfg.Block != nil && *cfg.Block != block {
+				return fmt.Errorf("the chain is forked at block %d, not %d", block, *cfg.Block)
+			}
+			return nil
+		}
+		progress, err := stages.GetStageProgress(tx, stages.Finish)
+		if err != nil {
+			return err
+		}
+		if progress > 0 {
+			return fmt.Errorf("the chain has %d blocks already and can't be forked, start with a new datadir", progress)
+		}
+		head, err := src.head(ctx)
+		if err != nil {
+			return err
+		}
+		block = head
+		if cfg.Block != nil {
+			if *cfg.Block > head {
+				return fmt.Errorf("can't fork at block %d, the latest block of the network is %d", *cfg.Block, head)
+			}
+			block = *cfg.Block
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], block)
+		return tx.Put(kv.DatabaseInfo, forkBlockKey, b[:])
+	})
+	return block, err
+}
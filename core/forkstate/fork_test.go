@@ -0,0 +1,43 @@
+package forkstate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+type testSource struct{ latest uint64 }
+
+func (s testSource) head(context.Context) (uint64, error) { return s.latest, nil }
+func (s testSource) reader(uint64) state.StateReader      { return nil }
+func (s testSource) close()                               {}
+
+func TestForkBlock(t *testing.T) {
+	ctx := context.Background()
+	block := func(b uint64) *uint64 { return &b }
+
+	db := memdb.NewTestDB(t)
+	_, err := forkBlock(ctx, Config{Block: block(101)}, db, testSource{latest: 100})
+	require.Error(t, err)
+	forked, err := forkBlock(ctx, Config{}, db, testSource{latest: 100})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), forked)
+
+	// the chain stays forked at the block
+	forked, err = forkBlock(ctx, Config{}, db, testSource{latest: 200})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), forked)
+	_, err = forkBlock(ctx, Config{Block: block(50)}, db, testSource{latest: 200})
+	require.Error(t, err)
+
+	// a chain with blocks can't be forked
+	db = memdb.NewTestDB(t)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return stages.SaveStageProgress(tx, stages.Finish, 1) }))
+	_, err = forkBlock(ctx, Config{Block: block(50)}, db, testSource{latest: 200})
+	require.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package forkstate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// source is a node of the network the chain is forked from
+type source interface {
+	head(ctx context.Context) (uint64, error)
+	// reader reads the state after the block, it's safe for concurrent use
+	reader(block uint64) state.StateReader
+	close()
+}
+
+// rpcTimeout is how long a request to the JSON-RPC endpoint may take
+const rpcTimeout = 30 * time.Second
+
+type rpcSource struct {
+	client *rpc.Client
+}
+
+func openRPCSource(ctx context.Context, url string) (*rpcSource, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", url, err)
+	}
+	return &rpcSource{client: client}, nil
+}
+
+func (s *rpcSource) head(ctx context.Context) (uint64, error) {
+	var head hexutil.Uint64
+	if err := s.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return 0, fmt.Errorf("eth_blockNumber: %w", err)
+	}
+	return uint64(head), nil
+}
+
+func (s *rpcSource) reader(block uint64) state.StateReader {
+	return &rpcReader{client: s.client, block: hexutil.EncodeUint64(block)}
+}
+
+func (s *rpcSource) close() { s.client.Close() }
+
+// rpcReader reads the state by eth_getBalance, eth_getTransactionCount, eth_getCode and eth_getStorageAt. The
+// contracts have the first incarnation, the accounts with neither balance, nonce nor code don't exist.
+type rpcReader struct {
+	client *rpc.Client
+	block  string
+}
+
+func (r *rpcReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	var balance hexutil.Big
+	var nonce hexutil.Uint64
+	var code hexutil.Bytes
+	batch := []rpc.BatchElem{
+		{Method: "eth_getBalance", Args: []interface{}{address, r.block}, Result: &balance},
+		{Method: "eth_getTransactionCount", Args: []interface{}{address, r.block}, Result: &nonce},
+		{Method: "eth_getCode", Args: []interface{}{address, r.block}, Result: &code},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	if err := r.client.BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("read account %x: %w", address, err)
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("%s %x: %w", elem.Method, address, elem.Error)
+		}
+	}
+	if balance.ToInt().Sign() == 0 && nonce == 0 && len(code) == 0 {
+		return nil, nil
+	}
+	acc := accounts.NewAccount()
+	acc.Nonce = uint64(nonce)
+	if overflow := acc.Balance.SetFromBig(balance.ToInt()); overflow {
+		return nil, fmt.Errorf("balance of %x overflows: %s", address, balance.ToInt())
+	}
+	if len(code) > 0 {
+		acc.Incarnation = state.FirstContractIncarnation
+		acc.CodeHash = crypto.Keccak256Hash(code)
+	}
+	return &acc, nil
+}
+
+func (r *rpcReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	var v hexutil.Bytes
+	if err := r.call(&v, "eth_getStorageAt", address, key, r.block); err != nil {
+		return nil, err
+	}
+	value := new(uint256.Int).SetBytes(v).Bytes()
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (r *rpcReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	var code hexutil.Bytes
+	if err := r.call(&code, "eth_getCode", address, r.block); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *rpcReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (r *rpcReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (r *rpcReader) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	if err := r.client.CallContext(ctx, result, method, args...); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+type dbSource struct {
+	db kv.RoDB
+}
+
+func openDBSource(dir string, logger log.Logger) (*dbSource, error) {
+	db, err := mdbx.NewMDBX(logger).Path(datadir.New(dir).Chaindata).Readonly().Open()
+	if err != nil {
+		return nil, fmt.Errorf("open the datadir %s: %w", dir, err)
+	}
+	return &dbSource{db: db}, nil
+}
+
+func (s *dbSource) head(ctx context.Context) (head uint64, err error) {
+	err = s.db.View(ctx, func(tx kv.Tx) (err error) {
+		head, err = stages.GetStageProgress(tx, stages.Finish)
+		return err
+	})
+	return head, err
+}
+
+func (s *dbSource) reader(block uint64) state.StateReader {
+	return &dbReader{db: s.db, block: block}
+}
+
+func (s *dbSource) close() { s.db.Close() }
+
+// dbReader reads the state from the history of the node, in a transaction of its own per read
+type dbReader struct {
+	db    kv.RoDB
+	block uint64
+}
+
+func (r *dbReader) view(f func(reader state.StateReader) error) error {
+	return r.db.View(context.Background(), func(tx kv.Tx) error {
+		return f(state.NewPlainState(tx, r.block+1))
+	})
+}
+
+func (r *dbReader) ReadAccountData(address common.Address) (acc *accounts.Account, err error) {
+	err = r.view(func(reader state.StateReader) (err error) {
+		acc, err = reader.ReadAccountData(address)
+		return err
+	})
+	return acc, err
+}
+
+func (r *dbReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) (v []byte, err error) {
+	err = r.view(func(reader state.StateReader) (err error) {
+		v, err = reader.ReadAccountStorage(address, incarnation, key)
+		v = common.CopyBytes(v) // valid in the transaction only
+		return err
+	})
+	return v, err
+}
+
+func (r *dbReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) (code []byte, err error) {
+	err = r.view(func(reader state.StateReader) (err error) {
+		code, err = reader.ReadAccountCode(address, incarnation, codeHash)
+		code = common.CopyBytes(code)
+		return err
+	})
+	return code, err
+}
+
+func (r *dbReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (r *dbReader) ReadAccountIncarnation(address common.Address) (inc uint64, err error) {
+	err = r.view(func(reader state.StateReader) (err error) {
+		inc, err = reader.ReadAccountIncarnation(address)
+		return err
+	})
+	return inc, err
+}
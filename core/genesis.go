@@ -18,6 +18,7 @@ package core
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"embed"
 	"encoding/binary"
@@ -25,7 +26,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"strings"
 	"sync"
 
 	"github.com/holiman/uint256"
@@ -176,13 +179,15 @@ func CommitGenesisBlock(db kv.RwDB, genesis *Genesis) (*params.ChainConfig, *typ
 	return CommitGenesisBlockWithOverride(db, genesis, nil, nil)
 }
 
-func CommitGenesisBlockWithOverride(db kv.RwDB, genesis *Genesis, overrideMergeNetsplitBlock, overrideTerminalTotalDifficulty *big.Int) (*params.ChainConfig, *types.Block, error) {
+// CommitGenesisBlockWithOverride is CommitGenesisBlock overriding the blocks of the forks, by the names of
+// params.OverridableForks, and the terminal total difficulty
+func CommitGenesisBlockWithOverride(db kv.RwDB, genesis *Genesis, overrideForkBlocks map[string]*big.Int, overrideTerminalTotalDifficulty *big.Int) (*params.ChainConfig, *types.Block, error) {
 	tx, err := db.BeginRw(context.Background())
 	if err != nil {
 		return nil, nil, err
 	}
 	defer tx.Rollback()
-	c, b, err := WriteGenesisBlock(tx, genesis, overrideMergeNetsplitBlock, overrideTerminalTotalDifficulty)
+	c, b, err := WriteGenesisBlock(tx, genesis, overrideForkBlocks, overrideTerminalTotalDifficulty)
 	if err != nil {
 		return c, b, err
 	}
@@ -201,7 +206,7 @@ func MustCommitGenesisBlock(db kv.RwDB, genesis *Genesis) (*params.ChainConfig,
 	return c, b
 }
 
-func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideMergeNetsplitBlock, overrideTerminalTotalDifficulty *big.Int) (*params.ChainConfig, *types.Block, error) {
+func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideForkBlocks map[string]*big.Int, overrideTerminalTotalDifficulty *big.Int) (*params.ChainConfig, *types.Block, error) {
 	if genesis != nil && genesis.Config == nil {
 		return params.AllEthashProtocolChanges, nil, ErrGenesisNoConfig
 	}
@@ -211,13 +216,16 @@ func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideMergeNetsplitBlock,
 		return nil, nil, storedErr
 	}
 
-	applyOverrides := func(config *params.ChainConfig) {
-		if overrideMergeNetsplitBlock != nil {
-			config.MergeNetsplitBlock = overrideMergeNetsplitBlock
+	applyOverrides := func(config *params.ChainConfig) error {
+		for fork, block := range overrideForkBlocks {
+			if err := config.OverrideForkBlock(fork, block); err != nil {
+				return err
+			}
 		}
 		if overrideTerminalTotalDifficulty != nil {
 			config.TerminalTotalDifficulty = overrideTerminalTotalDifficulty
 		}
+		return nil
 	}
 
 	if (storedHash == common.Hash{}) {
@@ -227,7 +235,12 @@ func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideMergeNetsplitBlock,
 			genesis = DefaultGenesisBlock()
 			custom = false
 		}
-		applyOverrides(genesis.Config)
+		if err := applyOverrides(genesis.Config); err != nil {
+			return genesis.Config, nil, err
+		}
+		if err := genesis.Config.CheckConfigForkOrder(); err != nil {
+			return genesis.Config, nil, err
+		}
 		block, _, err1 := genesis.Write(db)
 		if err1 != nil {
 			return genesis.Config, nil, err1
@@ -255,7 +268,9 @@ func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideMergeNetsplitBlock,
 	}
 	// Get the existing chain configuration.
 	newCfg := genesis.configOrDefault(storedHash)
-	applyOverrides(newCfg)
+	if err := applyOverrides(newCfg); err != nil {
+		return newCfg, nil, err
+	}
 	if err := newCfg.CheckConfigForkOrder(); err != nil {
 		return newCfg, nil, err
 	}
@@ -276,7 +291,9 @@ func WriteGenesisBlock(db kv.RwTx, genesis *Genesis, overrideMergeNetsplitBlock,
 	// In that case, only apply the overrides.
 	if genesis == nil && params.ChainConfigByGenesisHash(storedHash) == nil {
 		newCfg = storedCfg
-		applyOverrides(newCfg)
+		if err := applyOverrides(newCfg); err != nil {
+			return newCfg, nil, err
+		}
 	}
 	// Check config compatibility and write the config. Compatibility errors
 	// are returned to the caller unless we're already at block zero.
@@ -545,7 +562,7 @@ func DefaultGenesisBlock() *Genesis {
 		ExtraData:  hexutil.MustDecode("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa"),
 		GasLimit:   5000,
 		Difficulty: big.NewInt(17179869184),
-		Alloc:      readPrealloc("allocs/mainnet.json"),
+		Alloc:      readPrealloc("allocs/mainnet.json.gz"),
 	}
 }
 
@@ -631,6 +648,24 @@ func DefaultSokolGenesisBlock() *Genesis {
 	}
 }
 
+func DefaultGnosisGenesisBlock() *Genesis {
+	sealRlp, err := rlp.EncodeToBytes([][]byte{
+		common.FromHex(""),
+		common.FromHex("0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return &Genesis{
+		Config:     params.GnosisChainConfig,
+		Timestamp:  0x0,
+		SealRlp:    sealRlp,
+		GasLimit:   0x989680,
+		Difficulty: big.NewInt(0x20000),
+		Alloc:      readPrealloc("allocs/gnosis.json"),
+	}
+}
+
 func DefaultBSCGenesisBlock() *Genesis {
 	return &Genesis{
 		Config:     params.BSCChainConfig,
@@ -641,7 +676,7 @@ func DefaultBSCGenesisBlock() *Genesis {
 		Difficulty: big.NewInt(0x1),
 		Mixhash:    common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000"),
 		Coinbase:   common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE"),
-		Alloc:      readPrealloc("allocs/bsc.json"),
+		Alloc:      readPrealloc("allocs/bsc.json.gz"),
 		Number:     0x00,
 		GasUsed:    0x00,
 	}
@@ -657,7 +692,7 @@ func DefaultChapelGenesisBlock() *Genesis {
 		Difficulty: big.NewInt(0x1),
 		Mixhash:    common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000"),
 		Coinbase:   common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE"),
-		Alloc:      readPrealloc("allocs/chapel.json"),
+		Alloc:      readPrealloc("allocs/chapel.json.gz"),
 		Number:     0x00,
 		GasUsed:    0x00,
 	}
@@ -673,7 +708,7 @@ func DefaultRialtoGenesisBlock() *Genesis {
 		Difficulty: big.NewInt(0x1),
 		Mixhash:    common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000"),
 		Coinbase:   common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE"),
-		Alloc:      readPrealloc("allocs/bsc.json"),
+		Alloc:      readPrealloc("allocs/bsc.json.gz"),
 		Number:     0x00,
 		GasUsed:    0x00,
 	}
@@ -765,13 +800,24 @@ func DefaultKilnDevnetGenesisBlock() *Genesis {
 	}
 }
 
+// readPrealloc reads the genesis allocation from the embedded JSON file. Large allocations
+// are stored gzipped (gzip -9n allocs/<network>.json) to keep the binary small.
 func readPrealloc(filename string) GenesisAlloc {
 	f, err := allocs.Open(filename)
 	if err != nil {
 		panic(fmt.Sprintf("Could not open genesis preallocation for %s: %v", filename, err))
 	}
 	defer f.Close()
-	decoder := json.NewDecoder(f)
+	var r io.Reader = f
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			panic(fmt.Sprintf("Could not decompress genesis preallocation for %s: %v", filename, err))
+		}
+		defer gz.Close()
+		r = gz
+	}
+	decoder := json.NewDecoder(r)
 	ga := make(GenesisAlloc)
 	err = decoder.Decode(&ga)
 	if err != nil {
@@ -796,6 +842,8 @@ func DefaultGenesisBlockByChainName(chain string) *Genesis {
 		return DefaultUVMGenesisBlock()
 	case networkname.SokolChainName:
 		return DefaultSokolGenesisBlock()
+	case networkname.GnosisChainName:
+		return DefaultGnosisGenesisBlock()
 	case networkname.FermionChainName:
 		return DefaultFermionGenesisBlock()
 	case networkname.BSCChainName:
@@ -813,6 +861,6 @@ func DefaultGenesisBlockByChainName(chain string) *Genesis {
 	case networkname.KilnDevnetChainName:
 		return DefaultKilnDevnetGenesisBlock()
 	default:
-		return nil
+		return customGenesisBlock(chain)
 	}
 }
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// Precompiled contracts supported by Erigon, by the builtin names of OpenEthereum.
+// They are activated by the fork rules, so the activation blocks of the spec are not used.
+var openEthereumBuiltins = map[string]common.Address{
+	"ecrecover":         common.BytesToAddress([]byte{1}),
+	"sha256":            common.BytesToAddress([]byte{2}),
+	"ripemd160":         common.BytesToAddress([]byte{3}),
+	"identity":          common.BytesToAddress([]byte{4}),
+	"modexp":            common.BytesToAddress([]byte{5}),
+	"alt_bn128_add":     common.BytesToAddress([]byte{6}),
+	"alt_bn128_mul":     common.BytesToAddress([]byte{7}),
+	"alt_bn128_pairing": common.BytesToAddress([]byte{8}),
+	"blake2_f":          common.BytesToAddress([]byte{9}),
+}
+
+// OpenEthereumGenesis converts a chain spec in the OpenEthereum format to the genesis block.
+func OpenEthereumGenesis(spec *params.OpenEthereumSpec) (*Genesis, error) {
+	config, err := spec.ChainConfig()
+	if err != nil {
+		return nil, err
+	}
+	g := &Genesis{
+		Config:     config,
+		ExtraData:  spec.Genesis.ExtraData,
+		Coinbase:   spec.Genesis.Author,
+		ParentHash: spec.Genesis.ParentHash,
+		Alloc:      GenesisAlloc{},
+	}
+	if spec.Genesis.Difficulty != nil {
+		g.Difficulty = new(big.Int).Set(&spec.Genesis.Difficulty.Int)
+	}
+	if spec.Genesis.GasLimit != nil {
+		g.GasLimit = spec.Genesis.GasLimit.Uint64()
+	}
+	if spec.Genesis.Timestamp != nil {
+		g.Timestamp = spec.Genesis.Timestamp.Uint64()
+	}
+
+	seal := spec.Genesis.Seal
+	switch {
+	case seal.AuthorityRound != nil:
+		if g.SealRlp, err = rlp.EncodeToBytes([]interface{}{seal.AuthorityRound.Step.Uint64(), []byte(seal.AuthorityRound.Signature)}); err != nil {
+			return nil, err
+		}
+	case seal.Ethereum != nil:
+		g.Nonce = new(big.Int).SetBytes(seal.Ethereum.Nonce).Uint64()
+		g.Mixhash = seal.Ethereum.MixHash
+	case len(seal.Generic) > 0:
+		g.SealRlp = seal.Generic
+	}
+
+	for key, account := range spec.Accounts {
+		if !common.IsHexAddress(key) {
+			return nil, fmt.Errorf("invalid account address %q", key)
+		}
+		addr := common.HexToAddress(key)
+		if account.Builtin != nil && !isOpenEthereumBuiltin(account.Builtin, addr) {
+			if _, ok := vm.NamedPrecompile(account.Builtin.Name); !ok {
+				return nil, fmt.Errorf("unsupported builtin %q at %x", account.Builtin.Name, addr)
+			}
+		}
+		if len(account.Constructor) > 0 {
+			return nil, fmt.Errorf("account %x: genesis constructors are not supported, use code and storage", addr)
+		}
+		// accounts which only declare a builtin don't exist in the state
+		if account.Balance == nil && account.Nonce == nil && len(account.Code) == 0 && len(account.Storage) == 0 {
+			continue
+		}
+		genesisAccount := GenesisAccount{Code: account.Code, Storage: account.Storage, Balance: new(big.Int)}
+		if account.Balance != nil {
+			genesisAccount.Balance.Set(&account.Balance.Int)
+		}
+		if account.Nonce != nil {
+			genesisAccount.Nonce = account.Nonce.Uint64()
+		}
+		g.Alloc[addr] = genesisAccount
+	}
+	return g, nil
+}
+
+// RegisterOpenEthereumPrecompiles registers the builtins of the chain spec which are custom precompiles, registered
+// by name with vm.RegisterNamedPrecompile, for the chain from their activate_at block.
+func RegisterOpenEthereumPrecompiles(spec *params.OpenEthereumSpec, chainID *big.Int) error {
+	for key, account := range spec.Accounts {
+		if account.Builtin == nil || !common.IsHexAddress(key) {
+			continue
+		}
+		addr := common.HexToAddress(key)
+		if isOpenEthereumBuiltin(account.Builtin, addr) {
+			continue
+		}
+		p, ok := vm.NamedPrecompile(account.Builtin.Name)
+		if !ok {
+			return fmt.Errorf("unsupported builtin %q at %x", account.Builtin.Name, addr)
+		}
+		var activation uint64
+		if account.Builtin.ActivateAt != nil {
+			activation = account.Builtin.ActivateAt.Uint64()
+		}
+		vm.RegisterPrecompile(chainID, activation, addr, p)
+	}
+	return nil
+}
+
+// isOpenEthereumBuiltin - whether the builtin is a precompile of the fork rules, at its address
+func isOpenEthereumBuiltin(builtin *params.OpenEthereumBuiltin, addr common.Address) bool {
+	builtinAddr, ok := openEthereumBuiltins[strings.ToLower(builtin.Name)]
+	return ok && builtinAddr == addr
+}
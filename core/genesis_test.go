@@ -2,10 +2,18 @@ package core
 
 import (
 	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
 	"github.com/stretchr/testify/require"
@@ -31,6 +39,22 @@ func TestDefaultBSCGenesisBlock(t *testing.T) {
 	}
 }
 
+func TestGnosisGenesisBlock(t *testing.T) {
+	block, _, err := DefaultGnosisGenesisBlock().ToBlock()
+	require.NoError(t, err)
+	require.Equal(t, params.GnosisGenesisStateRoot, block.Root())
+	require.Equal(t, params.GnosisGenesisHash, block.Hash())
+	require.Equal(t, uint64(100), params.NetworkIDByChainName(networkname.GnosisChainName))
+}
+
+func TestCompressedPrealloc(t *testing.T) {
+	for _, network := range []string{networkname.MainnetChainName, networkname.BSCChainName, networkname.ChapelChainName} {
+		block, _, err := DefaultGenesisBlockByChainName(network).ToBlock()
+		require.NoError(t, err)
+		require.Equal(t, *params.GenesisHashByChainName(network), block.Hash(), network)
+	}
+}
+
 func TestCommitGenesisIdempotency(t *testing.T) {
 	_, tx := memdb.NewTestTx(t)
 	genesis := DefaultGenesisBlockByChainName(networkname.MainnetChainName)
@@ -46,3 +70,139 @@ func TestCommitGenesisIdempotency(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, uint64(2), seq)
 }
+
+func TestOpenEthereumGenesis(t *testing.T) {
+	// Sokol genesis expressed as an OpenEthereum spec must have the same hash
+	accounts := map[string]interface{}{
+		"0000000000000000000000000000000000000001": map[string]interface{}{"builtin": map[string]interface{}{"name": "ecrecover"}},
+	}
+	for addr, account := range readPrealloc("allocs/sokol.json") {
+		accounts[addr.Hex()] = map[string]interface{}{
+			"balance": account.Balance.String(),
+			"nonce":   hexutil.Uint64(account.Nonce),
+			"code":    hexutil.Bytes(account.Code),
+			"storage": account.Storage,
+		}
+	}
+	spec := map[string]interface{}{
+		"name":   "Sokol",
+		"engine": map[string]interface{}{"authorityRound": map[string]interface{}{"params": map[string]interface{}{"stepDuration": 5}}},
+		"params": map[string]interface{}{
+			"networkID": "0x4D", "eip140Transition": "0x0", "eip211Transition": "0x0", "eip214Transition": "0x0", "eip658Transition": "0x0",
+			"eip145Transition": 6464300, "eip1014Transition": 6464300, "eip1052Transition": 6464300,
+		},
+		"genesis": map[string]interface{}{
+			"seal": map[string]interface{}{"authorityRound": map[string]interface{}{
+				"step":      "0x0",
+				"signature": "0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+			}},
+			"difficulty": "0x20000",
+			"gasLimit":   "0x663BE0",
+		},
+		"accounts": accounts,
+	}
+	specFile := filepath.Join(t.TempDir(), "spec.json")
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(specFile, data, 0600))
+
+	oeSpec, err := params.ReadOpenEthereumSpec(specFile)
+	require.NoError(t, err)
+	require.Equal(t, uint64(77), oeSpec.NetworkID())
+	genesis, err := OpenEthereumGenesis(oeSpec)
+	require.NoError(t, err)
+	require.Equal(t, params.AuRaConsensus, genesis.Config.Consensus)
+	require.Equal(t, "sokol", genesis.Config.ChainName)
+	require.Equal(t, big.NewInt(77), genesis.Config.ChainID)
+	require.Equal(t, big.NewInt(0), genesis.Config.ByzantiumBlock)
+	require.Equal(t, big.NewInt(6464300), genesis.Config.ConstantinopleBlock)
+	require.Equal(t, big.NewInt(6464300), genesis.Config.PetersburgBlock)
+	require.Nil(t, genesis.Config.IstanbulBlock)
+
+	block, _, err := genesis.ToBlock()
+	require.NoError(t, err)
+	require.Equal(t, params.SokolGenesisHash, block.Hash())
+
+	// builtins which Erigon doesn't have can't be supported
+	oeSpec.Accounts["0000000000000000000000000000000000000001"] = params.OpenEthereumAccount{Builtin: &params.OpenEthereumBuiltin{Name: "bls12_381_g1_add"}}
+	_, err = OpenEthereumGenesis(oeSpec)
+	require.Error(t, err)
+	delete(oeSpec.Accounts, "0000000000000000000000000000000000000001")
+
+	// custom builtins are the precompiles registered by name, activated from activate_at
+	customAddr := common.HexToAddress("0x1000")
+	oeSpec.Accounts[customAddr.Hex()] = params.OpenEthereumAccount{Builtin: &params.OpenEthereumBuiltin{Name: "sokol_test", ActivateAt: &params.OpenEthereumNumber{Int: *big.NewInt(5)}}}
+	_, err = OpenEthereumGenesis(oeSpec)
+	require.Error(t, err)
+	vm.RegisterNamedPrecompile("sokol_test", vm.PrecompiledContractsBerlin[common.BytesToAddress([]byte{4})])
+	genesis, err = OpenEthereumGenesis(oeSpec)
+	require.NoError(t, err)
+	require.NoError(t, RegisterOpenEthereumPrecompiles(oeSpec, genesis.Config.ChainID))
+	require.NotContains(t, vm.ActivePrecompiles(genesis.Config.Rules(4), 4), customAddr)
+	require.Contains(t, vm.ActivePrecompiles(genesis.Config.Rules(5), 5), customAddr)
+}
+
+func TestRegisterChain(t *testing.T) {
+	definition := `{
+		"name": "test-custom",
+		"networkId": 4321,
+		"bootnodes": ["enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:30303"],
+		"engineParams": {"stepDuration": 5},
+		"genesis": {
+			"config": {"chainId": 1234, "consensus": "noproof", "homesteadBlock": 0, "eip150Block": 0, "eip155Block": 0, "byzantiumBlock": 0, "constantinopleBlock": 0, "petersburgBlock": 0, "istanbulBlock": 10},
+			"difficulty": "0x1",
+			"gasLimit": "0x1000000",
+			"alloc": {"0x0000000000000000000000000000000000000100": {"balance": "0x100"}}
+		}
+	}`
+	file := filepath.Join(t.TempDir(), "chain.json")
+	require.NoError(t, os.WriteFile(file, []byte(definition), 0600))
+
+	def, err := ReadChainDefinition(file)
+	require.NoError(t, err)
+	require.NoError(t, RegisterChain(def))
+	block, _, err := def.Genesis.ToBlock()
+	require.NoError(t, err)
+
+	require.True(t, params.IsCustomChain("test-custom"))
+	require.Equal(t, def.Genesis, DefaultGenesisBlockByChainName("test-custom"))
+	require.Equal(t, block.Hash(), *params.GenesisHashByChainName("test-custom"))
+	config := params.ChainConfigByGenesisHash(block.Hash())
+	require.NotNil(t, config)
+	require.Equal(t, "test-custom", config.ChainName)
+	require.Equal(t, big.NewInt(10), config.IstanbulBlock)
+	require.Equal(t, config, params.ChainConfigByChainName("test-custom"))
+	require.Equal(t, uint64(4321), params.NetworkIDByChainName("test-custom"))
+	require.Equal(t, def.Bootnodes, params.BootnodeURLsOfChain("test-custom"))
+
+	// built-in and already registered networks can't be redefined
+	require.Error(t, RegisterChain(def))
+	def.Name = networkname.SokolChainName
+	require.Error(t, RegisterChain(def))
+}
+
+func TestGenesisForkBlockOverrides(t *testing.T) {
+	newGenesis := func() *Genesis {
+		config := *params.AllEthashProtocolChanges
+		return &Genesis{Config: &config, Difficulty: big.NewInt(1)}
+	}
+
+	_, tx := memdb.NewTestTx(t)
+	config, _, err := WriteGenesisBlock(tx, newGenesis(), map[string]*big.Int{"london": big.NewInt(100)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), config.LondonBlock)
+	stored, err := rawdb.ReadChainConfig(tx, rawdb.ReadHeadHeaderHash(tx))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), stored.LondonBlock)
+
+	// overridden again on restart
+	config, _, err = WriteGenesisBlock(tx, newGenesis(), map[string]*big.Int{"london": big.NewInt(200)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(200), config.LondonBlock)
+
+	_, tx = memdb.NewTestTx(t)
+	_, _, err = WriteGenesisBlock(tx, newGenesis(), map[string]*big.Int{"shanghai": big.NewInt(1)}, nil)
+	require.Error(t, err)
+	_, _, err = WriteGenesisBlock(tx, newGenesis(), map[string]*big.Int{"berlin": big.NewInt(10), "london": big.NewInt(5)}, nil)
+	require.ErrorContains(t, err, "unsupported fork ordering")
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	metrics2 "github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+var txReexecutionCounter = metrics2.GetOrCreateCounter("chain_execution_reexecuted_txs_total")
+
+// ParallelTracer is a block tracer supporting the parallel execution: every transaction executed speculatively
+// is traced by a tracer of its own, which is merged into the block one if the transaction is applied.
+type ParallelTracer interface {
+	vm.Tracer
+	TxTracer() vm.Tracer
+	Merge(txTracer vm.Tracer)
+}
+
+// speculativeTx is a transaction executed by a worker against the state of the block at some point.
+type speculativeTx struct {
+	done   chan struct{}
+	reader *state.TxStateReader
+	state  *state.RecordingState
+	tracer vm.Tracer
+	msg    types.Message
+	result *ExecutionResult
+	err    error
+}
+
+// ExecuteBlockParallel executes the block like ExecuteBlockEphemerally, running its transactions speculatively
+// on several workers. Each of them reads the state of the block left by the transactions applied so far, and
+// records what it read and changed. The transactions are applied in order: if the state still holds the values
+// a transaction has read, its changes are replayed, otherwise it conflicts with an earlier transaction and is
+// re-executed sequentially. Blocks which can't be executed this way are executed by ExecuteBlockEphemerally.
+func ExecuteBlockParallel(
+	workers int,
+	chainConfig *params.ChainConfig,
+	vmConfig *vm.Config,
+	getHeader func(hash common.Hash, number uint64) *types.Header,
+	engine consensus.Engine,
+	block *types.Block,
+	stateReader state.StateReader,
+	stateWriter state.WriterWithChangeSets,
+	epochReader consensus.EpochReader,
+	chainReader consensus.ChainHeaderReader,
+	contractHasTEVM func(codeHash common.Hash) (bool, error),
+) (types.Receipts, *types.ReceiptForStorage, error) {
+	if workers < 2 || !canExecuteInParallel(chainConfig, vmConfig, block, contractHasTEVM) {
+		return ExecuteBlockEphemerally(chainConfig, vmConfig, getHeader, engine, block, stateReader, stateWriter, epochReader, chainReader, contractHasTEVM)
+	}
+	defer blockExecutionTimer.UpdateDuration(time.Now())
+	ibs := state.New(stateReader)
+	header := block.Header()
+	var receipts types.Receipts
+	usedGas := new(uint64)
+	gp := new(GasPool)
+	gp.AddGas(block.GasLimit())
+
+	if !vmConfig.ReadOnly {
+		if err := InitializeBlockExecution(engine, chainReader, epochReader, block.Header(), block.Transactions(), block.Uncles(), chainConfig, ibs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	view := state.NewBlockStateView(ibs)
+	txs, stop := executeSpeculatively(workers, chainConfig, vmConfig, getHeader, engine, block, view)
+	defer stop()
+
+	rules := chainConfig.Rules(header.Number.Uint64())
+	noop := state.NewNoopWriter()
+	blockTracer, _ := vmConfig.Tracer.(ParallelTracer)
+	for i, tx := range block.Transactions() {
+		spec := txs[i]
+		view.Serve(spec.done)
+		receipt, err := func() (*types.Receipt, error) {
+			view.Lock()
+			defer view.Unlock()
+			ibs.Prepare(tx.Hash(), block.Hash(), i)
+			if spec.err != nil || spec.state.Error() != nil || gp.Gas() < tx.GetGas() || !spec.reader.ReadsValid() {
+				txReexecutionCounter.Inc()
+				receipt, _, err := ApplyTransaction(chainConfig, getHeader, engine, nil, gp, ibs, noop, header, tx, usedGas, *vmConfig, contractHasTEVM)
+				return receipt, err
+			}
+			spec.state.Replay(ibs)
+			if err := ibs.FinalizeTx(rules, noop); err != nil {
+				return nil, err
+			}
+			if err := gp.SubGas(spec.result.UsedGas); err != nil {
+				return nil, err
+			}
+			*usedGas += spec.result.UsedGas
+			if blockTracer != nil {
+				blockTracer.Merge(spec.tracer)
+			}
+			if vmConfig.NoReceipts {
+				return nil, nil
+			}
+			return makeReceipt(tx, spec.msg, spec.result, *usedGas, ibs, header), nil
+		}()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d from block %d [%v]: %w", i, block.NumberU64(), tx.Hash().Hex(), err)
+		}
+		if !vmConfig.NoReceipts {
+			receipts = append(receipts, receipt)
+		}
+	}
+
+	if chainConfig.IsByzantium(header.Number.Uint64()) && !vmConfig.NoReceipts {
+		receiptSha := types.DeriveSha(receipts)
+		if receiptSha != block.ReceiptHash() {
+			return nil, nil, fmt.Errorf("mismatched receipt headers for block %d", block.NumberU64())
+		}
+	}
+
+	if *usedGas != header.GasUsed {
+		return nil, nil, fmt.Errorf("gas used by execution: %d, in header: %d", *usedGas, header.GasUsed)
+	}
+	if !vmConfig.NoReceipts {
+		bloom := types.CreateBloom(receipts)
+		if bloom != header.Bloom {
+			return nil, nil, fmt.Errorf("bloom computed by execution: %x, in header: %x", bloom, header.Bloom)
+		}
+	}
+	if !vmConfig.ReadOnly {
+		txs := block.Transactions()
+		if _, _, _, err := FinalizeBlockExecution(engine, stateReader, block.Header(), txs, block.Uncles(), stateWriter, chainConfig, ibs, receipts, epochReader, chainReader, false); err != nil {
+			return nil, nil, err
+		}
+	}
+	return receipts, nil, nil
+}
+
+func canExecuteInParallel(chainConfig *params.ChainConfig, vmConfig *vm.Config, block *types.Block, contractHasTEVM func(codeHash common.Hash) (bool, error)) bool {
+	switch chainConfig.Consensus {
+	case params.AuRaConsensus, params.ParliaConsensus, params.BorConsensus:
+		return false // system calls and state sync transactions
+	}
+	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(block.Number()) == 0 {
+		return false
+	}
+	if contractHasTEVM != nil {
+		return false
+	}
+	if vmConfig.Tracer == nil && vmConfig.Debug {
+		return false // the transactions are traced into files
+	}
+	if _, ok := vmConfig.Tracer.(ParallelTracer); vmConfig.Tracer != nil && !ok {
+		return false
+	}
+	for _, tx := range block.Transactions() {
+		if tx.IsStarkNet() {
+			return false
+		}
+	}
+	return len(block.Transactions()) > 1
+}
+
+// executeSpeculatively starts the workers executing the transactions of the block, in order, as long as
+// the returned function isn't called.
+func executeSpeculatively(
+	workers int,
+	chainConfig *params.ChainConfig,
+	vmConfig *vm.Config,
+	getHeader func(hash common.Hash, number uint64) *types.Header,
+	engine consensus.Engine,
+	block *types.Block,
+	view *state.BlockStateView,
+) ([]*speculativeTx, func()) {
+	header := block.Header()
+	author, _ := engine.Author(header)
+	signer := types.MakeSigner(chainConfig, header.Number.Uint64())
+	// the headers are read from the same database transaction as the state
+	readerGetHeader := func(hash common.Hash, number uint64) (h *types.Header) {
+		_ = view.RunOnReader(func() {
+			h = getHeader(hash, number)
+		})
+		return h
+	}
+	blockTracer, _ := vmConfig.Tracer.(ParallelTracer)
+
+	txs := make([]*speculativeTx, block.Transactions().Len())
+	work := make(chan int, len(txs))
+	for i := range txs {
+		txs[i] = &speculativeTx{done: make(chan struct{})}
+		work <- i
+	}
+	close(work)
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(txs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				select {
+				case <-quit:
+				default:
+					cfg := *vmConfig
+					if blockTracer != nil {
+						txs[i].tracer = blockTracer.TxTracer()
+						cfg.Tracer = txs[i].tracer
+					}
+					blockContext := NewEVMBlockContext(header, readerGetHeader, engine, &author, nil)
+					txs[i].execute(chainConfig, cfg, blockContext, signer, block, i, view)
+				}
+				close(txs[i].done)
+			}
+		}()
+	}
+	return txs, func() {
+		close(quit)
+		view.Close()
+		wg.Wait()
+	}
+}
+
+func (tx *speculativeTx) execute(chainConfig *params.ChainConfig, cfg vm.Config, blockContext vm.BlockContext, signer *types.Signer, block *types.Block, txIndex int, view *state.BlockStateView) {
+	defer func() {
+		// the state read may be inconsistent, the transaction is re-executed then
+		if r := recover(); r != nil {
+			tx.err = fmt.Errorf("speculative execution: %v", r)
+		}
+	}()
+	txn := block.Transactions()[txIndex]
+	tx.reader = view.NewTxReader()
+	tx.state = state.NewRecordingState(state.New(tx.reader))
+	tx.state.Prepare(txn.Hash(), block.Hash(), txIndex)
+
+	cfg.SkipAnalysis = SkipAnalysis(chainConfig, block.NumberU64())
+	evm := vm.NewEVM(blockContext, vm.TxContext{}, tx.state, chainConfig, cfg)
+	msg, err := txn.AsMessage(*signer, block.BaseFee(), evm.ChainRules())
+	if err != nil {
+		tx.err = err
+		return
+	}
+	txContext := NewEVMTxContext(msg)
+	if cfg.TraceJumpDest || cfg.TraceTxHash {
+		txContext.TxHash = txn.Hash()
+	}
+	evm.Reset(txContext, tx.state)
+	tx.msg = msg
+	tx.result, tx.err = ApplyMessage(evm, msg, new(GasPool).AddGas(txn.GetGas()), true /* refunds */, false /* gasBailout */)
+}
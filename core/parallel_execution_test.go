@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// increments the slot 0 and emits a log
+	counterCode = common.FromHex("0x600f600c600039600f6000f3" + "600054600101600055" + "60006000a0" + "00")
+	// self-destructs, sending the balance to the caller
+	selfDestructCode = common.FromHex("0x6002600c60003960026000f3" + "33ff")
+)
+
+func TestExecuteBlockParallel(t *testing.T) {
+	config, engine := params.TestChainConfig, ethash.NewFaker()
+	keys := make([]*ecdsa.PrivateKey, 4)
+	addrs := make([]common.Address, len(keys))
+	alloc := GenesisAlloc{}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &Genesis{Config: config, Alloc: alloc}
+	counter, selfDestruct := crypto.CreateAddress(addrs[0], 0), crypto.CreateAddress(addrs[0], 1)
+
+	signer := types.LatestSignerForChainID(config.ChainID)
+	genDb := memdb.NewTestDB(t)
+	genesis := gspec.MustCommit(genDb)
+	chain, err := GenerateChain(config, genesis, engine, genDb, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(addrs[3])
+		send := func(from int, to *common.Address, value uint64, gas uint64, data []byte) {
+			var txn types.Transaction
+			if to == nil {
+				txn = types.NewContractCreation(b.TxNonce(addrs[from]), uint256.NewInt(value), gas, uint256.NewInt(1), data)
+			} else {
+				txn = types.NewTransaction(b.TxNonce(addrs[from]), *to, uint256.NewInt(value), gas, uint256.NewInt(1), data)
+			}
+			signed, err := types.SignTx(txn, *signer, keys[from])
+			require.NoError(t, err)
+			b.AddTx(signed)
+		}
+		switch i {
+		case 0:
+			send(0, nil, 0, 100_000, counterCode)
+			send(0, nil, 0, 100_000, selfDestructCode)
+			send(1, &addrs[2], 1000, 21000, nil)
+			send(2, &common.Address{0xa}, 1000, 21000, nil)
+		case 1:
+			// the calls of the counter conflict, the transfers don't
+			send(1, &counter, 0, 50_000, nil)
+			send(2, &counter, 0, 50_000, nil)
+			send(0, &common.Address{0xb}, 1000, 21000, nil)
+			send(1, &common.Address{0xc}, 1000, 21000, nil)
+			send(3, &counter, 0, 50_000, nil) // the coinbase reads its fees
+			send(2, &selfDestruct, 5, 50_000, nil)
+		case 2:
+			send(1, &selfDestruct, 0, 50_000, nil)
+			send(2, &selfDestruct, 7, 21000, nil) // re-creates the account
+			send(0, &counter, 0, 50_000, nil)
+			send(1, &common.Address{0xa}, 1000, 21000, nil)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+
+	reexecutedBefore := txReexecutionCounter.Get()
+	sequential := executeChain(t, gspec, engine, chain, 1)
+	parallel := executeChain(t, gspec, engine, chain, 4)
+	for _, table := range []string{kv.PlainState, kv.Code, kv.PlainContractCode, kv.IncarnationMap, kv.AccountChangeSet, kv.StorageChangeSet, kv.Receipts, kv.Log} {
+		require.Equal(t, sequential[table], parallel[table], table)
+	}
+	require.NotEmpty(t, parallel[kv.StorageChangeSet])
+	reexecuted := txReexecutionCounter.Get() - reexecutedBefore
+	require.Greater(t, reexecuted, uint64(0))
+	require.Less(t, reexecuted, uint64(14))
+}
+
+// executeChain executes the chain on a fresh database, and returns the tables written
+func executeChain(t *testing.T, gspec *Genesis, engine consensus.Engine, chain *ChainPack, workers int) map[string]map[string]string {
+	db := memdb.NewTestDB(t)
+	genesis := gspec.MustCommit(db)
+	headers := map[common.Hash]*types.Header{genesis.Hash(): genesis.Header()}
+	for _, h := range chain.Headers {
+		headers[h.Hash()] = h
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header { return headers[hash] }
+
+	tables := map[string]map[string]string{}
+	err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		for _, block := range chain.Blocks {
+			reader := state.NewPlainStateReader(tx)
+			writer := state.NewPlainStateWriter(tx, tx, block.NumberU64())
+			receipts, _, err := ExecuteBlockParallel(workers, gspec.Config, &vm.Config{}, getHeader, engine, block, reader, writer, nil, nil, nil)
+			if err != nil {
+				return err
+			}
+			if err := rawdb.AppendReceipts(tx, block.NumberU64(), receipts); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{kv.PlainState, kv.Code, kv.PlainContractCode, kv.IncarnationMap, kv.AccountChangeSet, kv.StorageChangeSet, kv.Receipts, kv.Log} {
+			tables[table] = map[string]string{}
+			if err := tx.ForEach(table, nil, func(k, v []byte) error {
+				tables[table][string(k)+string(v)] = string(v)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return tables
+}
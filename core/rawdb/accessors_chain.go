@@ -62,7 +62,7 @@ func WriteCanonicalHash(db kv.Putter, hash common.Hash, number uint64) error {
 func TruncateCanonicalHash(tx kv.RwTx, blockFrom uint64, deleteHeaders bool) error {
 	if err := tx.ForEach(kv.HeaderCanonical, dbutils.EncodeBlockNumber(blockFrom), func(k, v []byte) error {
 		if deleteHeaders {
-			deleteHeader(tx, common.BytesToHash(v), blockFrom)
+			deleteHeader(tx, common.BytesToHash(v), binary.BigEndian.Uint64(k))
 		}
 		return tx.Delete(kv.HeaderCanonical, k, nil)
 	}); err != nil {
@@ -1034,6 +1034,25 @@ func WriteReceipts(tx kv.Putter, number uint64, receipts types.Receipts) error {
 
 // AppendReceipts stores all the transaction receipts belonging to a block.
 func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.Receipts) error {
+	if err := AppendLogs(tx, blockNumber, receipts); err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	err := cbor.Marshal(buf, receipts)
+	if err != nil {
+		return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
+	}
+
+	if err = tx.Append(kv.Receipts, dbutils.EncodeBlockNumber(blockNumber), buf.Bytes()); err != nil {
+		return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
+	}
+	return nil
+}
+
+// AppendLogs stores the logs of all the transaction receipts belonging to a block, without the receipts: they are
+// regenerated by re-executing the block when needed.
+func AppendLogs(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.Receipts) error {
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 
 	for txId, r := range receipts {
@@ -1051,19 +1070,14 @@ func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.R
 			return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
 		}
 	}
-
-	buf.Reset()
-	err := cbor.Marshal(buf, receipts)
-	if err != nil {
-		return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
-	}
-
-	if err = tx.Append(kv.Receipts, dbutils.EncodeBlockNumber(blockNumber), buf.Bytes()); err != nil {
-		return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
-	}
 	return nil
 }
 
+// DropReceipts removes all the receipts, keeping their logs.
+func DropReceipts(tx kv.RwTx) error {
+	return tx.ClearBucket(kv.Receipts)
+}
+
 // TruncateReceipts removes all receipt for given block number or newer
 func TruncateReceipts(db kv.RwTx, number uint64) error {
 	if err := db.ForEach(kv.Receipts, dbutils.EncodeBlockNumber(number), func(k, _ []byte) error {
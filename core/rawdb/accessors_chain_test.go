@@ -315,6 +315,33 @@ func TestCanonicalMappingStorage(t *testing.T) {
 	}
 }
 
+// Tests that the truncated canonical headers are deleted at their own block number.
+func TestTruncateCanonicalHashDeleteHeaders(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	var headers []*types.Header
+	for number := int64(1); number <= 3; number++ {
+		header := &types.Header{Number: big.NewInt(number), Extra: []byte("test header")}
+		WriteHeader(tx, header)
+		require.NoError(t, WriteCanonicalHash(tx, header.Hash(), header.Number.Uint64()))
+		headers = append(headers, header)
+	}
+	require.NoError(t, TruncateCanonicalHash(tx, 2, true /* deleteHeaders */))
+
+	require.NotNil(t, ReadHeader(tx, headers[0].Hash(), 1))
+	for _, header := range headers[1:] {
+		if entry := ReadHeader(tx, header.Hash(), header.Number.Uint64()); entry != nil {
+			t.Fatalf("Deleted header %d returned", header.Number.Uint64())
+		}
+		if number := ReadHeaderNumber(tx, header.Hash()); number != nil {
+			t.Fatalf("Deleted header number %d returned", *number)
+		}
+		hash, err := ReadCanonicalHash(tx, header.Number.Uint64())
+		require.NoError(t, err)
+		require.Equal(t, common.Hash{}, hash)
+	}
+}
+
 // Tests that head headers and head blocks can be assigned, individually.
 func TestHeadStorage(t *testing.T) {
 	_, db := memdb.NewTestTx(t)
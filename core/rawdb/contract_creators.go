@@ -0,0 +1,111 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+// The index of the contract creations, written by the execution with the call traces, is kept besides the issuance
+// like the tip summaries. Keys:
+//
+//	"creator" + contract + block_num_u64 -> creator + tx_hash, the last one of a contract is its current incarnation
+//	"creation" + block_num_u64 + contract -> empty, to unwind the creations of a block
+var (
+	contractCreationPrefix      = []byte("creator")
+	contractCreationBlockPrefix = []byte("creation")
+)
+
+// ContractCreation is the creation of a contract by a transaction, directly or by one of its internal calls.
+type ContractCreation struct {
+	Contract common.Address
+	Creator  common.Address // the sender of the transaction, or the contract creating it
+	TxHash   common.Hash
+}
+
+func contractCreationKey(contract common.Address, blockNumber uint64) []byte {
+	k := make([]byte, 0, len(contractCreationPrefix)+length.Addr+8)
+	k = append(k, contractCreationPrefix...)
+	k = append(k, contract[:]...)
+	return append(k, dbutils.EncodeBlockNumber(blockNumber)...)
+}
+
+func contractCreationBlockKey(blockNumber uint64, contract common.Address) []byte {
+	k := make([]byte, 0, len(contractCreationBlockPrefix)+8+length.Addr)
+	k = append(k, contractCreationBlockPrefix...)
+	k = append(k, dbutils.EncodeBlockNumber(blockNumber)...)
+	return append(k, contract[:]...)
+}
+
+// WriteContractCreations indexes the contracts created by the block, in the order of the creations: the last creation
+// of a contract re-created in the block wins.
+func WriteContractCreations(db kv.Putter, blockNumber uint64, creations []ContractCreation) error {
+	for _, c := range creations {
+		v := make([]byte, 0, length.Addr+length.Hash)
+		v = append(v, c.Creator[:]...)
+		v = append(v, c.TxHash[:]...)
+		if err := db.Put(kv.Issuance, contractCreationKey(c.Contract, blockNumber), v); err != nil {
+			return err
+		}
+		if err := db.Put(kv.Issuance, contractCreationBlockKey(blockNumber, c.Contract), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadContractCreation returns the last indexed creation of the contract and its block, nil if the contract has none:
+// the contracts created before the index, or while the call traces weren't written, aren't indexed.
+func ReadContractCreation(tx kv.Tx, contract common.Address) (creation *ContractCreation, blockNumber uint64, err error) {
+	c, err := tx.Cursor(kv.Issuance)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer c.Close()
+	prefix := append(append([]byte{}, contractCreationPrefix...), contract[:]...)
+	for k, v, err := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v, err = c.Next() {
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(k) != len(prefix)+8 || len(v) != length.Addr+length.Hash {
+			return nil, 0, fmt.Errorf("invalid contract creation %x: %x", k, v)
+		}
+		creation = &ContractCreation{
+			Contract: contract,
+			Creator:  common.BytesToAddress(v[:length.Addr]),
+			TxHash:   common.BytesToHash(v[length.Addr:]),
+		}
+		blockNumber = binary.BigEndian.Uint64(k[len(prefix):])
+	}
+	return creation, blockNumber, nil
+}
+
+// TruncateContractCreations removes the creations of the given block number and newer.
+func TruncateContractCreations(tx kv.RwTx, blockNumber uint64) error {
+	c, err := tx.RwCursor(kv.Issuance)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(contractCreationBlockKey(blockNumber, common.Address{})); k != nil && bytes.HasPrefix(k, contractCreationBlockPrefix); k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if len(k) != len(contractCreationBlockPrefix)+8+length.Addr {
+			return fmt.Errorf("invalid contract creation block key %x", k)
+		}
+		number := binary.BigEndian.Uint64(k[len(contractCreationBlockPrefix):])
+		if err = tx.Delete(kv.Issuance, contractCreationKey(common.BytesToAddress(k[len(k)-length.Addr:]), number), nil); err != nil {
+			return err
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// The tip summaries are kept besides the issuance, which has the burnt fees too.
+var tipSummaryPrefix = []byte("tips")
+
+func tipSummaryKey(number uint64) []byte {
+	return append(append([]byte{}, tipSummaryPrefix...), dbutils.EncodeBlockNumber(number)...)
+}
+
+// TipSummaryNumber returns the block number of the key of kv.Issuance, ok=false if it isn't a key of a tip summary.
+func TipSummaryNumber(k []byte) (number uint64, ok bool) {
+	if len(k) != len(tipSummaryPrefix)+8 || !bytes.HasPrefix(k, tipSummaryPrefix) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(k[len(tipSummaryPrefix):]), true
+}
+
+// ReadTipSummary retrieves the tip summary of the canonical block, nil if it isn't stored.
+func ReadTipSummary(db kv.Getter, number uint64) (types.TipSummary, error) {
+	data, err := db.GetOne(kv.Issuance, tipSummaryKey(number))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return types.DecodeTipSummary(data)
+}
+
+func WriteTipSummary(db kv.Putter, number uint64, summary types.TipSummary) error {
+	return db.Put(kv.Issuance, tipSummaryKey(number), summary.EncodeBinary())
+}
+
+// TruncateTipSummaries removes the tip summaries of the given block number and newer.
+func TruncateTipSummaries(tx kv.RwTx, number uint64) error {
+	c, err := tx.RwCursor(kv.Issuance)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(tipSummaryKey(number)); k != nil && bytes.HasPrefix(k, tipSummaryPrefix); k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTipSummaries removes the tip summaries of the blocks older than the given block number.
+func PruneTipSummaries(tx kv.RwTx, pruneTo uint64) error {
+	c, err := tx.RwCursor(kv.Issuance)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	to := tipSummaryKey(pruneTo)
+	for k, _, err := c.Seek(tipSummaryPrefix); k != nil && bytes.Compare(k, to) < 0; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
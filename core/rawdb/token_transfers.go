@@ -0,0 +1,152 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// The index of the token transfers is kept besides the issuance like the tip summaries. Keys:
+//
+//	"tokenTransfer" + token + holder + block_num_u64 + log_index_u32 -> flags_u8 + tx_index_u32 + counterparty + value
+//	"tokenBalance" + token + holder -> balance
+//	"tokenIndexedFrom" -> block_num_u64, the first block indexed when the receipts of the earlier blocks were pruned
+var (
+	tokenTransferPrefix    = []byte("tokenTransfer")
+	tokenBalancePrefix     = []byte("tokenBalance")
+	tokenIndexedFromPrefix = []byte("tokenIndexedFrom")
+)
+
+const (
+	tokenTransferIncoming byte = 1 << iota
+	tokenTransferNFT
+)
+
+// TokenTransfer is a Transfer event of an ERC-20 token, or of an ERC-721 token (NFT), seen by one of its holders:
+// Value is the amount of ERC-20 tokens, or the ID of the ERC-721 token.
+type TokenTransfer struct {
+	Token        common.Address
+	Holder       common.Address
+	BlockNumber  uint64
+	LogIndex     uint32 // in the block
+	TxIndex      uint32
+	Counterparty common.Address // the sender of an incoming transfer, the recipient of an outgoing one
+	Incoming     bool
+	NFT          bool
+	Value        *uint256.Int
+}
+
+func tokenHolderKey(prefix []byte, token, holder common.Address) []byte {
+	k := make([]byte, 0, len(prefix)+2*length.Addr+8+4)
+	k = append(k, prefix...)
+	k = append(k, token[:]...)
+	return append(k, holder[:]...)
+}
+
+func tokenTransferKey(token, holder common.Address, blockNumber uint64, logIndex uint32) []byte {
+	k := tokenHolderKey(tokenTransferPrefix, token, holder)
+	k = append(k, make([]byte, 12)...)
+	binary.BigEndian.PutUint64(k[len(k)-12:], blockNumber)
+	binary.BigEndian.PutUint32(k[len(k)-4:], logIndex)
+	return k
+}
+
+func WriteTokenTransfer(db kv.Putter, t *TokenTransfer) error {
+	v := make([]byte, 1+4+length.Addr, 1+4+length.Addr+32)
+	if t.Incoming {
+		v[0] |= tokenTransferIncoming
+	}
+	if t.NFT {
+		v[0] |= tokenTransferNFT
+	}
+	binary.BigEndian.PutUint32(v[1:], t.TxIndex)
+	copy(v[5:], t.Counterparty[:])
+	v = append(v, t.Value.Bytes()...)
+	return db.Put(kv.Issuance, tokenTransferKey(t.Token, t.Holder, t.BlockNumber, t.LogIndex), v)
+}
+
+func DeleteTokenTransfer(db kv.Deleter, t *TokenTransfer) error {
+	return db.Delete(kv.Issuance, tokenTransferKey(t.Token, t.Holder, t.BlockNumber, t.LogIndex), nil)
+}
+
+// ReadTokenTransfers returns the transfers of the token seen by the holder, from the block fromBlock, in order. Once
+// limit transfers are read, the other transfers of the same block are read too, and next is the block of the next
+// transfer (0 if there is none).
+func ReadTokenTransfers(tx kv.Tx, token, holder common.Address, fromBlock uint64, limit int) (transfers []*TokenTransfer, next uint64, err error) {
+	c, err := tx.Cursor(kv.Issuance)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer c.Close()
+	prefix := tokenHolderKey(tokenTransferPrefix, token, holder)
+	for k, v, err := c.Seek(tokenTransferKey(token, holder, fromBlock, 0)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, 0, err
+		}
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		if len(k) != len(prefix)+12 || len(v) < 1+4+length.Addr {
+			return nil, 0, fmt.Errorf("invalid token transfer %x: %x", k, v)
+		}
+		blockNumber := binary.BigEndian.Uint64(k[len(prefix):])
+		if len(transfers) >= limit && blockNumber != transfers[len(transfers)-1].BlockNumber {
+			return transfers, blockNumber, nil
+		}
+		transfers = append(transfers, &TokenTransfer{
+			Token:        token,
+			Holder:       holder,
+			BlockNumber:  blockNumber,
+			LogIndex:     binary.BigEndian.Uint32(k[len(prefix)+8:]),
+			TxIndex:      binary.BigEndian.Uint32(v[1:]),
+			Counterparty: common.BytesToAddress(v[5 : 5+length.Addr]),
+			Incoming:     v[0]&tokenTransferIncoming != 0,
+			NFT:          v[0]&tokenTransferNFT != 0,
+			Value:        new(uint256.Int).SetBytes(v[5+length.Addr:]),
+		})
+	}
+	return transfers, 0, nil
+}
+
+// ReadTokenBalance returns the balance of the holder implied by the indexed transfers of the token: the amount of an
+// ERC-20 token, or the number of the ERC-721 tokens.
+func ReadTokenBalance(db kv.Getter, token, holder common.Address) (*uint256.Int, error) {
+	v, err := db.GetOne(kv.Issuance, tokenHolderKey(tokenBalancePrefix, token, holder))
+	if err != nil {
+		return nil, err
+	}
+	return new(uint256.Int).SetBytes(v), nil
+}
+
+// WriteTokenBalance stores the balance of the holder, the zero balances are deleted.
+func WriteTokenBalance(db kv.StatelessWriteTx, token, holder common.Address, balance *uint256.Int) error {
+	k := tokenHolderKey(tokenBalancePrefix, token, holder)
+	if balance.IsZero() {
+		return db.Delete(kv.Issuance, k, nil)
+	}
+	return db.Put(kv.Issuance, k, balance.Bytes())
+}
+
+// ReadTokenTransfersIndexedFrom returns the first block whose transfers are indexed, after the blocks whose receipts
+// were pruned, 0 if the transfers of all blocks are.
+func ReadTokenTransfersIndexedFrom(db kv.Getter) (uint64, error) {
+	v, err := db.GetOne(kv.Issuance, tokenIndexedFromPrefix)
+	if err != nil {
+		return 0, err
+	}
+	if len(v) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func WriteTokenTransfersIndexedFrom(db kv.Putter, blockNumber uint64) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, blockNumber)
+	return db.Put(kv.Issuance, tokenIndexedFromPrefix, v)
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+// BackendDB is the database the blocks are executed against, usually a batch of the changes
+// not committed to the database transaction yet.
+type BackendDB interface {
+	kv.Getter
+	kv.Putter
+	kv.Deleter
+}
+
+// Backend is a storage engine of the latest state, which the execution stage reads and writes.
+// The change sets are common to all the backends: they are written by the ChangeSetWriter of the
+// writers, and read back when the state is unwound.
+type Backend interface {
+	// NewReader reads the latest state from db.
+	NewReader(db BackendDB) StateReader
+	// NewWriter writes the state changes of a block to db. Unless changeSetsTx is nil, the change sets
+	// of the block are written to it as well. The changes are reported to accumulator if it isn't nil.
+	NewWriter(db BackendDB, changeSetsTx kv.RwTx, blockNum uint64, accumulator *shards.Accumulator) WriterWithChangeSets
+	// Unwind reverts the state to the values collected from the change sets (see changeset.RewindData):
+	// the account and storage keys with their values at the unwind point, empty if they didn't exist.
+	Unwind(logPrefix string, tx kv.RwTx, changes *etl.Collector, accumulator *shards.Accumulator, quit <-chan struct{}) error
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{PlainBackendName: PlainBackend}
+)
+
+// RegisterBackend makes a state backend available by the provided name, e.g. for the --state.backend flag.
+// If RegisterBackend is called twice with the same name or if backend is nil, it panics.
+func RegisterBackend(name string, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if backend == nil {
+		panic("state: RegisterBackend backend is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic(fmt.Sprintf("state: RegisterBackend called twice for backend %q", name))
+	}
+	backends[name] = backend
+}
+
+// LookupBackend returns the state backend registered by the provided name. The empty name
+// stands for the plain state.
+func LookupBackend(name string) (Backend, bool) {
+	if name == "" {
+		return PlainBackend, true
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	backend, ok := backends[name]
+	return backend, ok
+}
+
+// Backends returns a sorted list of the names of the registered state backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+// wrappedBackend is a backend implemented outside of the package, on top of the plain one
+type wrappedBackend struct {
+	Backend
+}
+
+func TestBackendRegistry(t *testing.T) {
+	backend := wrappedBackend{PlainBackend}
+	RegisterBackend("test-backend", backend)
+	require.Panics(t, func() { RegisterBackend("test-backend", backend) })
+	require.Panics(t, func() { RegisterBackend("test-nil", nil) })
+
+	_, ok := LookupBackend("unknown")
+	require.False(t, ok)
+	b, ok := LookupBackend("test-backend")
+	require.True(t, ok)
+	require.Equal(t, backend, b)
+	b, ok = LookupBackend("")
+	require.True(t, ok)
+	require.Equal(t, PlainBackend, b)
+	require.Equal(t, []string{PlainBackendName, "test-backend"}, Backends())
+}
+
+func TestPlainBackendUnwind(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	address, key := common.Address{1}, common.Hash{2}
+	write := func(blockNum uint64, balance, value uint64) {
+		w := PlainBackend.NewWriter(tx, tx, blockNum, nil)
+		original, err := PlainBackend.NewReader(tx).ReadAccountData(address)
+		require.NoError(t, err)
+		if original == nil {
+			original = &accounts.Account{}
+		}
+		acc := accounts.NewAccount()
+		acc.Incarnation = 1
+		acc.Balance.SetUint64(balance)
+		require.NoError(t, w.UpdateAccountData(address, original, &acc))
+		prev, err := PlainBackend.NewReader(tx).ReadAccountStorage(address, 1, &key)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteAccountStorage(address, 1, &key, new(uint256.Int).SetBytes(prev), uint256.NewInt(value)))
+		require.NoError(t, w.WriteChangeSets())
+	}
+	write(1, 10, 100)
+	write(2, 20, 0)
+
+	changes := etl.NewCollector("", t.TempDir(), etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
+	defer changes.Close()
+	require.NoError(t, changeset.RewindData(tx, 2, 1, changes, nil))
+	require.NoError(t, PlainBackend.Unwind("", tx, changes, nil, nil))
+
+	acc, err := PlainBackend.NewReader(tx).ReadAccountData(address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), acc.Balance.Uint64())
+	value, err := PlainBackend.NewReader(tx).ReadAccountStorage(address, 1, &key)
+	require.NoError(t, err)
+	require.Equal(t, []byte{100}, value)
+}
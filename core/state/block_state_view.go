@@ -0,0 +1,260 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+var ErrViewClosed = errors.New("block state view closed")
+
+// BlockStateView reads the state of an IntraBlockState between the transactions of a block: its live objects,
+// on top of the state reader it was created with. It serves the transactions executed speculatively in parallel,
+// which record the values they read, so that they can be validated against the state they are applied to.
+// The IntraBlockState may only be changed while the view is locked, and only between the transactions,
+// when the balance increases have been transferred to the objects (see FinalizeTx).
+//
+// The state reader usually reads a database transaction bound to the thread of the block execution, so the
+// reads of the other goroutines are handed over to it, and served while it waits for them (see Serve).
+type BlockStateView struct {
+	ibs      *IntraBlockState
+	lock     sync.RWMutex // the ibs is changed with the write lock held
+	requests chan func()
+	closed   chan struct{}
+}
+
+func NewBlockStateView(ibs *IntraBlockState) *BlockStateView {
+	return &BlockStateView{ibs: ibs, requests: make(chan func()), closed: make(chan struct{})}
+}
+
+// Lock is held while the IntraBlockState is changed.
+func (v *BlockStateView) Lock() {
+	v.lock.Lock()
+}
+
+func (v *BlockStateView) Unlock() {
+	v.lock.Unlock()
+}
+
+// Serve runs the reads of the other goroutines on the calling one, the goroutine of the block execution,
+// until done is closed.
+func (v *BlockStateView) Serve(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case f := <-v.requests:
+			f()
+		}
+	}
+}
+
+// Close fails the reads of the other goroutines which aren't served anymore.
+func (v *BlockStateView) Close() {
+	close(v.closed)
+}
+
+// RunOnReader runs f on the goroutine of the block execution, e.g. when f reads from the same database
+// transaction as the state reader. Returns ErrViewClosed if the view is closed before f is run.
+func (v *BlockStateView) RunOnReader(f func()) error {
+	done := make(chan struct{})
+	select {
+	case v.requests <- func() { f(); close(done) }:
+	case <-v.closed:
+		return ErrViewClosed
+	}
+	<-done
+	return nil
+}
+
+// NewTxReader returns a reader of the view for a single transaction.
+func (v *BlockStateView) NewTxReader() *TxStateReader {
+	return &TxStateReader{view: v}
+}
+
+const (
+	readAccount = iota
+	readStorage
+	readCode
+	readCodeSize
+	readIncarnation
+)
+
+type stateRead struct {
+	kind        int
+	address     common.Address
+	incarnation uint64
+	key         common.Hash // storage key or code hash
+	value       []byte
+}
+
+// readLive reads the live objects of the IntraBlockState, and returns false if the value
+// has to be read from the state reader.
+func (v *BlockStateView) readLive(r *stateRead) ([]byte, bool) {
+	switch r.kind {
+	case readAccount:
+		if so, known := v.liveObject(r.address); known {
+			if so == nil {
+				return nil, true
+			}
+			return encodeAccount(&so.data), true
+		}
+		if _, ok := v.ibs.nilAccounts[r.address]; ok {
+			return nil, true
+		}
+	case readStorage:
+		if so, _ := v.liveObject(r.address); so != nil && so.data.Incarnation == r.incarnation {
+			if value, ok := so.dirtyStorage[r.key]; ok {
+				return value.Bytes(), true
+			}
+			if value, ok := so.originStorage[r.key]; ok {
+				return value.Bytes(), true
+			}
+			if so.created {
+				return nil, true
+			}
+		}
+	case readCode:
+		if so, _ := v.liveObject(r.address); so != nil && so.code != nil && so.data.CodeHash == r.key {
+			return so.code, true
+		}
+	case readCodeSize:
+		if so, _ := v.liveObject(r.address); so != nil && so.code != nil && so.data.CodeHash == r.key {
+			return encodeUint64(uint64(len(so.code))), true
+		}
+	case readIncarnation:
+		// the same as in CreateAccount: the incarnation of a self-destructed object is still known
+		if so, ok := v.ibs.stateObjects[r.address]; ok && so.suicided {
+			return encodeUint64(so.data.Incarnation), true
+		}
+	}
+	return nil, false
+}
+
+// readBase reads the state reader, on the goroutine of the block execution.
+func (v *BlockStateView) readBase(r *stateRead) ([]byte, error) {
+	reader := v.ibs.stateReader
+	switch r.kind {
+	case readAccount:
+		a, err := reader.ReadAccountData(r.address)
+		if err != nil || a == nil {
+			return nil, err
+		}
+		return encodeAccount(a), nil
+	case readStorage:
+		return reader.ReadAccountStorage(r.address, r.incarnation, &r.key)
+	case readCode:
+		return reader.ReadAccountCode(r.address, r.incarnation, r.key)
+	case readCodeSize:
+		size, err := reader.ReadAccountCodeSize(r.address, r.incarnation, r.key)
+		return encodeUint64(uint64(size)), err
+	default:
+		inc, err := reader.ReadAccountIncarnation(r.address)
+		return encodeUint64(inc), err
+	}
+}
+
+// liveObject returns the object of the IntraBlockState, if it is loaded and not deleted.
+func (v *BlockStateView) liveObject(address common.Address) (*stateObject, bool) {
+	so, ok := v.ibs.stateObjects[address]
+	if !ok || so.deleted {
+		return nil, ok
+	}
+	return so, true
+}
+
+func encodeAccount(a *accounts.Account) []byte {
+	enc := make([]byte, a.EncodingLengthForStorage())
+	a.EncodeForStorage(enc)
+	return enc
+}
+
+func encodeUint64(n uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	return b[:]
+}
+
+// TxStateReader reads the view for a single transaction, and remembers what it read.
+type TxStateReader struct {
+	view  *BlockStateView
+	reads []stateRead
+}
+
+func (r *TxStateReader) record(read stateRead) ([]byte, error) {
+	r.view.lock.RLock()
+	value, live := r.view.readLive(&read)
+	r.view.lock.RUnlock()
+	if !live {
+		// the object may be loaded in the meantime, the read is invalidated then
+		var err error
+		if runErr := r.view.RunOnReader(func() { value, err = r.view.readBase(&read) }); runErr != nil {
+			return nil, runErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	read.value = value
+	r.reads = append(r.reads, read)
+	return value, nil
+}
+
+// ReadsValid reports whether the view still holds the values the transaction has read, i.e. whether the
+// transaction would be executed the same way at this point. Has to be called on the goroutine of the block
+// execution, with the view locked.
+func (r *TxStateReader) ReadsValid() bool {
+	for i := range r.reads {
+		value, live := r.view.readLive(&r.reads[i])
+		if !live {
+			var err error
+			if value, err = r.view.readBase(&r.reads[i]); err != nil {
+				return false
+			}
+		}
+		if !bytes.Equal(value, r.reads[i].value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *TxStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	enc, err := r.record(stateRead{kind: readAccount, address: address})
+	if err != nil || enc == nil {
+		return nil, err
+	}
+	var a accounts.Account
+	if err := a.DecodeForStorage(enc); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *TxStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	return r.record(stateRead{kind: readStorage, address: address, incarnation: incarnation, key: *key})
+}
+
+func (r *TxStateReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	return r.record(stateRead{kind: readCode, address: address, incarnation: incarnation, key: codeHash})
+}
+
+func (r *TxStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	enc, err := r.record(stateRead{kind: readCodeSize, address: address, incarnation: incarnation, key: codeHash})
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint64(enc)), nil
+}
+
+func (r *TxStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	enc, err := r.record(stateRead{kind: readIncarnation, address: address})
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(enc), nil
+}
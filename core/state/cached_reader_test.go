@@ -0,0 +1,56 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader is a reader of another type than PlainStateReader, e.g. a historical or remote one
+type countingReader struct {
+	StateReader
+	accounts, storage int
+}
+
+func (r *countingReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	r.accounts++
+	return r.StateReader.ReadAccountData(address)
+}
+
+func (r *countingReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	r.storage++
+	return r.StateReader.ReadAccountStorage(address, incarnation, key)
+}
+
+func TestCachedReaderWrapsAnyReader(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addr := common.Address{1}
+	account := &accounts.Account{Initialised: true, Incarnation: 1}
+	account.Balance.SetUint64(7)
+	key := common.Hash{2}
+	w := NewPlainStateWriterNoHistory(tx)
+	require.NoError(t, w.UpdateAccountData(addr, &accounts.Account{}, account))
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &key, uint256.NewInt(0), uint256.NewInt(3)))
+
+	r := &countingReader{StateReader: NewPlainStateReader(tx)}
+	cr := NewCachedReader(r, shards.NewStateCache(32, 0 /* no limit */))
+	for i := 0; i < 2; i++ {
+		a, err := cr.ReadAccountData(addr)
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), a.Balance.Uint64())
+		absent, err := cr.ReadAccountData(common.Address{3})
+		require.NoError(t, err)
+		require.Nil(t, absent)
+		v, err := cr.ReadAccountStorage(addr, 1, &key)
+		require.NoError(t, err)
+		require.Equal(t, []byte{3}, v)
+	}
+	// the second round is served by the cache
+	require.Equal(t, 2, r.accounts)
+	require.Equal(t, 1, r.storage)
+}
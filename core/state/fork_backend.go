@@ -0,0 +1,292 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+// ForkBackend keeps the state of a chain forked from another network at a block: the state written by the chain
+// is kept in the plain state, the rest is read from the forked network on demand and cached in memory.
+//
+// The keys read from the forked network are written to the plain state with the first block that writes them,
+// as if they didn't exist before: their original value in the change sets is empty, so the state root of the
+// chain covers only the keys it has written, and the unwinds remove them. The keys of the forked network deleted
+// by the chain are found in the history of the chain (the history indexes and the change sets), and aren't
+// read from the forked network again. The chain must keep its history, and write the change sets of all blocks.
+type ForkBackend struct {
+	source StateReader // State of the forked network at the fork block, safe for concurrent use
+
+	lock     sync.Mutex
+	accounts map[common.Address]*accounts.Account // nil for the accounts the forked network doesn't have
+	storage  map[string][]byte                    // Address, incarnation and location to value
+	code     map[common.Hash][]byte
+}
+
+func NewForkBackend(source StateReader) *ForkBackend {
+	return &ForkBackend{
+		source:   source,
+		accounts: map[common.Address]*accounts.Account{},
+		storage:  map[string][]byte{},
+		code:     map[common.Hash][]byte{},
+	}
+}
+
+func (f *ForkBackend) NewReader(db BackendDB) StateReader {
+	return f.Reader(db, NewPlainStateReader(db), ^uint64(0))
+}
+
+func (f *ForkBackend) NewWriter(db BackendDB, changeSetsTx kv.RwTx, blockNum uint64, accumulator *shards.Accumulator) WriterWithChangeSets {
+	w := PlainBackend.NewWriter(db, changeSetsTx, blockNum, accumulator)
+	return &forkWriter{WriterWithChangeSets: w, db: db, csw: w.(*PlainStateWriter).ChangeSetWriter()}
+}
+
+func (f *ForkBackend) Unwind(logPrefix string, tx kv.RwTx, changes *etl.Collector, accumulator *shards.Accumulator, quit <-chan struct{}) error {
+	return PlainBackend.Unwind(logPrefix, tx, changes, accumulator, quit)
+}
+
+// Reader reads the state of the chain by the local reader, and the keys the chain hasn't written by the blocks
+// before blockNum from the forked network, e.g. for the RPC reading the state at a block.
+func (f *ForkBackend) Reader(db kv.Getter, local StateReader, blockNum uint64) StateReader {
+	return &forkReader{fork: f, db: db, local: local, blockNum: blockNum}
+}
+
+func (f *ForkBackend) account(address common.Address) (*accounts.Account, error) {
+	f.lock.Lock()
+	acc, ok := f.accounts[address]
+	f.lock.Unlock()
+	if !ok {
+		var err error
+		if acc, err = f.source.ReadAccountData(address); err != nil {
+			return nil, err
+		}
+		f.lock.Lock()
+		f.accounts[address] = acc
+		f.lock.Unlock()
+	}
+	if acc == nil {
+		return nil, nil
+	}
+	return acc.SelfCopy(), nil
+}
+
+func (f *ForkBackend) accountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	compositeKey := string(dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes()))
+	f.lock.Lock()
+	v, ok := f.storage[compositeKey]
+	f.lock.Unlock()
+	if ok {
+		return v, nil
+	}
+	v, err := f.source.ReadAccountStorage(address, incarnation, key)
+	if err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	f.storage[compositeKey] = v
+	f.lock.Unlock()
+	return v, nil
+}
+
+func (f *ForkBackend) accountCode(address common.Address, codeHash common.Hash) ([]byte, error) {
+	f.lock.Lock()
+	code, ok := f.code[codeHash]
+	f.lock.Unlock()
+	if ok {
+		return code, nil
+	}
+	acc, err := f.account(address)
+	if err != nil || acc == nil || acc.CodeHash != codeHash {
+		return nil, err
+	}
+	if code, err = f.source.ReadAccountCode(address, acc.Incarnation, codeHash); err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	f.code[codeHash] = code
+	f.lock.Unlock()
+	return code, nil
+}
+
+type forkReader struct {
+	fork     *ForkBackend
+	db       kv.Getter
+	local    StateReader
+	blockNum uint64
+}
+
+func (r *forkReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	acc, err := r.local.ReadAccountData(address)
+	if acc != nil || err != nil {
+		return acc, err
+	}
+	if written, err := r.accountWritten(address); written || err != nil {
+		return nil, err
+	}
+	return r.fork.account(address)
+}
+
+func (r *forkReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	v, err := r.local.ReadAccountStorage(address, incarnation, key)
+	if len(v) > 0 || err != nil {
+		return v, err
+	}
+	// the contracts created by the chain don't have the storage of the forked network
+	acc, err := r.fork.account(address)
+	if err != nil || acc == nil || acc.Incarnation != incarnation {
+		return nil, err
+	}
+	if written, err := r.storageWritten(address, key); written || err != nil {
+		return nil, err
+	}
+	return r.fork.accountStorage(address, incarnation, key)
+}
+
+func (r *forkReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	code, err := r.local.ReadAccountCode(address, incarnation, codeHash)
+	if len(code) > 0 || err != nil || bytes.Equal(codeHash.Bytes(), emptyCodeHash) {
+		return code, err
+	}
+	return r.fork.accountCode(address, codeHash)
+}
+
+func (r *forkReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (r *forkReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return r.local.ReadAccountIncarnation(address)
+}
+
+// accountWritten reports whether a block before r.blockNum has written the account
+func (r *forkReader) accountWritten(address common.Address) (bool, error) {
+	written, err := r.indexed(kv.AccountsHistory, address[:])
+	if written || err != nil {
+		return written, err
+	}
+	return r.changed(stages.AccountHistoryIndex, kv.AccountChangeSet, func(k, v []byte) bool {
+		return bytes.HasPrefix(v, address[:])
+	})
+}
+
+// storageWritten reports whether a block before r.blockNum has written the storage key, of any incarnation
+func (r *forkReader) storageWritten(address common.Address, key *common.Hash) (bool, error) {
+	written, err := r.indexed(kv.StorageHistory, append(common.CopyBytes(address[:]), key[:]...))
+	if written || err != nil {
+		return written, err
+	}
+	return r.changed(stages.StorageHistoryIndex, kv.StorageChangeSet, func(k, v []byte) bool {
+		return bytes.Equal(k[8:8+length.Addr], address[:]) && bytes.HasPrefix(v, key[:])
+	})
+}
+
+// indexed looks the key up in the chunks of the history index, keyed by the key and the last block of the chunk
+func (r *forkReader) indexed(table string, key []byte) (bool, error) {
+	var written bool
+	err := r.db.ForPrefix(table, key, func(k, v []byte) error {
+		if written || len(k) != len(key)+8 {
+			return nil
+		}
+		bm := roaring64.New()
+		if _, err := bm.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+		written = !bm.IsEmpty() && bm.Minimum() < r.blockNum
+		return nil
+	})
+	return written, err
+}
+
+// changed looks the key up in the change sets of the blocks the history index stage hasn't indexed yet
+func (r *forkReader) changed(indexStage stages.SyncStage, table string, match func(k, v []byte) bool) (bool, error) {
+	indexed, err := stages.GetStageProgress(r.db, indexStage)
+	if err != nil {
+		return false, err
+	}
+	var written bool
+	err = r.db.ForEach(table, dbutils.EncodeBlockNumber(indexed+1), func(k, v []byte) error {
+		if !written && binary.BigEndian.Uint64(k) < r.blockNum && match(k, v) {
+			written = true
+		}
+		return nil
+	})
+	return written, err
+}
+
+// forkWriter writes the keys of the forked network, missing from the plain state, with the empty original value
+type forkWriter struct {
+	WriterWithChangeSets
+	db  BackendDB
+	csw *ChangeSetWriter // nil if the change sets aren't written, the deletions aren't kept then
+}
+
+func (w *forkWriter) forked(key []byte) (bool, error) {
+	has, err := w.db.Has(kv.PlainState, key)
+	return !has, err
+}
+
+func (w *forkWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	if original.Initialised {
+		forked, err := w.forked(address[:])
+		if err != nil {
+			return err
+		}
+		if forked {
+			original = &accounts.Account{}
+		}
+	}
+	return w.WriterWithChangeSets.UpdateAccountData(address, original, account)
+}
+
+func (w *forkWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	var forked bool
+	if original.Initialised {
+		var err error
+		if forked, err = w.forked(address[:]); err != nil {
+			return err
+		}
+	}
+	// the incarnation of the deleted contract is kept, for the contract created at the address later
+	if err := w.WriterWithChangeSets.DeleteAccount(address, original); err != nil {
+		return err
+	}
+	if forked && w.csw != nil {
+		w.csw.accountChanges[address] = []byte{}
+	}
+	return nil
+}
+
+func (w *forkWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	if original.IsZero() {
+		return w.WriterWithChangeSets.WriteAccountStorage(address, incarnation, key, original, value)
+	}
+	compositeKey := dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes())
+	forked, err := w.forked(compositeKey)
+	if err != nil {
+		return err
+	}
+	if !forked {
+		return w.WriterWithChangeSets.WriteAccountStorage(address, incarnation, key, original, value)
+	}
+	if err := w.WriterWithChangeSets.WriteAccountStorage(address, incarnation, key, new(uint256.Int), value); err != nil {
+		return err
+	}
+	// the deletion is kept in the change sets, with no change of the plain state
+	if value.IsZero() && w.csw != nil {
+		w.csw.storageChanges[string(compositeKey)] = []byte{}
+		w.csw.storageChanged[address] = true
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkBackend(t *testing.T) {
+	address, key1, key2 := common.Address{1}, common.Hash{1}, common.Hash{2}
+	code := []byte{0x60, 0x00}
+
+	// the forked network has a contract with two storage keys
+	_, forked := memdb.NewTestTx(t)
+	contract := accounts.NewAccount()
+	contract.Incarnation = FirstContractIncarnation
+	contract.Balance.SetUint64(10)
+	contract.CodeHash = crypto.Keccak256Hash(code)
+	w := NewPlainStateWriterNoHistory(forked)
+	require.NoError(t, w.UpdateAccountData(address, &accounts.Account{}, &contract))
+	require.NoError(t, w.UpdateAccountCode(address, contract.Incarnation, contract.CodeHash, code))
+	require.NoError(t, w.WriteAccountStorage(address, 1, &key1, new(uint256.Int), uint256.NewInt(100)))
+	require.NoError(t, w.WriteAccountStorage(address, 1, &key2, new(uint256.Int), uint256.NewInt(200)))
+	fork := NewForkBackend(NewPlainStateReader(forked))
+
+	_, tx := memdb.NewTestTx(t)
+	storage := func(key common.Hash) []byte {
+		v, err := fork.NewReader(tx).ReadAccountStorage(address, 1, &key)
+		require.NoError(t, err)
+		return v
+	}
+	acc, err := fork.NewReader(tx).ReadAccountData(address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), acc.Balance.Uint64())
+	c, err := fork.NewReader(tx).ReadAccountCode(address, 1, contract.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, code, c)
+	require.Equal(t, []byte{100}, storage(key1))
+	v, err := fork.NewReader(tx).ReadAccountStorage(address, 2, &key1)
+	require.NoError(t, err)
+	require.Nil(t, v, "a contract created at the address doesn't have the storage of the forked network")
+
+	// block 1 changes the first key and deletes the second one
+	w1 := fork.NewWriter(tx, tx, 1, nil)
+	require.NoError(t, w1.WriteAccountStorage(address, 1, &key1, uint256.NewInt(100), uint256.NewInt(101)))
+	require.NoError(t, w1.WriteAccountStorage(address, 1, &key2, uint256.NewInt(200), new(uint256.Int)))
+	changed := acc.SelfCopy()
+	changed.Balance.SetUint64(11)
+	require.NoError(t, w1.UpdateAccountData(address, acc, changed))
+	require.NoError(t, w1.WriteChangeSets())
+
+	require.Equal(t, []byte{101}, storage(key1))
+	require.Nil(t, storage(key2))
+	has, err := tx.Has(kv.PlainState, dbutils.PlainGenerateCompositeStorageKey(address[:], 1, key2[:]))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// the keys of the forked network are new to the chain
+	originals := map[string]int{}
+	require.NoError(t, changeset.ForPrefix(tx, kv.StorageChangeSet, dbutils.EncodeBlockNumber(1), func(_ uint64, k, v []byte) error {
+		originals[string(k)] = len(v)
+		return nil
+	}))
+	require.Equal(t, map[string]int{
+		string(dbutils.PlainGenerateCompositeStorageKey(address[:], 1, key1[:])): 0,
+		string(dbutils.PlainGenerateCompositeStorageKey(address[:], 1, key2[:])): 0,
+	}, originals)
+
+	// the state before block 1 is the state of the forked network
+	v, err = fork.Reader(tx, NewPlainState(tx, 1), 1).ReadAccountStorage(address, 1, &key2)
+	require.NoError(t, err)
+	require.Equal(t, []byte{200}, v)
+
+	changes := etl.NewCollector("", t.TempDir(), etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
+	defer changes.Close()
+	require.NoError(t, changeset.RewindData(tx, 1, 0, changes, nil))
+	require.NoError(t, fork.Unwind("", tx, changes, nil, nil))
+	require.NoError(t, changeset.Truncate(tx, 1))
+
+	has, err = tx.Has(kv.PlainState, address[:])
+	require.NoError(t, err)
+	require.False(t, has, "the unwind removes the keys of the forked network from the plain state")
+	require.Equal(t, []byte{100}, storage(key1))
+	require.Equal(t, []byte{200}, storage(key2))
+}
@@ -107,35 +107,38 @@ func (s *PlainState) ForEachStorage(addr common.Address, startLocation common.Ha
 		})
 	}
 	numDeletes := st.Len() - overrideCounter
-	if err := WalkAsOfStorage(s.tx, addr, acc.Incarnation, startLocation, s.blockNr, func(kAddr, kLoc, vs []byte) (bool, error) {
-		if !bytes.Equal(kAddr, addr[:]) {
-			return false, nil
-		}
-		if len(vs) == 0 {
-			// Skip deleted entries
-			return true, nil
-		}
-		keyHash, err1 := common.HashData(kLoc)
-		if err1 != nil {
-			return false, err1
-		}
-		//fmt.Printf("seckey: %x\n", seckey)
-		si := storageItem{}
-		copy(si.key[:], kLoc)
-		copy(si.seckey[:], keyHash[:])
-		if st.Has(&si) {
-			return true, nil
-		}
-		si.value.SetBytes(vs)
-		st.ReplaceOrInsert(&si)
-		if bytes.Compare(kLoc, lastKey[:]) > 0 {
-			// Beyond overrides
-			return st.Len() < maxResults+numDeletes, nil
+	// incarnation 0 - no contract at this block: history of its locations belongs to contracts of other blocks
+	if acc.Incarnation > 0 {
+		if err := WalkAsOfStorage(s.tx, addr, acc.Incarnation, startLocation, s.blockNr, func(kAddr, kLoc, vs []byte) (bool, error) {
+			if !bytes.Equal(kAddr, addr[:]) {
+				return false, nil
+			}
+			if len(vs) == 0 {
+				// Skip deleted entries
+				return true, nil
+			}
+			keyHash, err1 := common.HashData(kLoc)
+			if err1 != nil {
+				return false, err1
+			}
+			//fmt.Printf("seckey: %x\n", seckey)
+			si := storageItem{}
+			copy(si.key[:], kLoc)
+			copy(si.seckey[:], keyHash[:])
+			if st.Has(&si) {
+				return true, nil
+			}
+			si.value.SetBytes(vs)
+			st.ReplaceOrInsert(&si)
+			if bytes.Compare(kLoc, lastKey[:]) > 0 {
+				// Beyond overrides
+				return st.Len() < maxResults+numDeletes, nil
+			}
+			return st.Len() < maxResults+overrideCounter+numDeletes, nil
+		}); err != nil {
+			log.Error("ForEachStorage walk error", "err", err)
+			return err
 		}
-		return st.Len() < maxResults+overrideCounter+numDeletes, nil
-	}); err != nil {
-		log.Error("ForEachStorage walk error", "err", err)
-		return err
 	}
 	results := 0
 	var innerErr error
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+const PlainBackendName = "plain"
+
+// PlainBackend keeps the state in the PlainState, PlainContractCode and Code tables, which the stages
+// following the execution (hashed state, history indexes) read.
+var PlainBackend Backend = plainBackend{}
+
+type plainBackend struct{}
+
+func (plainBackend) NewReader(db BackendDB) StateReader {
+	return NewPlainStateReader(db)
+}
+
+func (plainBackend) NewWriter(db BackendDB, changeSetsTx kv.RwTx, blockNum uint64, accumulator *shards.Accumulator) WriterWithChangeSets {
+	if changeSetsTx == nil {
+		return NewPlainStateWriterNoHistory(db).SetAccumulator(accumulator)
+	}
+	return NewPlainStateWriter(db, changeSetsTx, blockNum).SetAccumulator(accumulator)
+}
+
+func (plainBackend) Unwind(logPrefix string, tx kv.RwTx, changes *etl.Collector, accumulator *shards.Accumulator, quit <-chan struct{}) error {
+	storageKeyLength := length.Addr + length.Incarnation + length.Hash
+	return changes.Load(tx, kv.PlainState, func(k, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
+		if len(k) == 20 {
+			if len(v) > 0 {
+				var acc accounts.Account
+				if err := acc.DecodeForStorage(v); err != nil {
+					return err
+				}
+
+				// Fetch the code hash
+				recoverCodeHashPlain(&acc, tx, k)
+				var address common.Address
+				copy(address[:], k)
+
+				// cleanup contract code bucket
+				original, err := NewPlainStateReader(tx).ReadAccountData(address)
+				if err != nil {
+					return fmt.Errorf("read account for %x: %w", address, err)
+				}
+				if original != nil {
+					// clean up all the code incarnations original incarnation and the new one
+					for incarnation := original.Incarnation; incarnation > acc.Incarnation && incarnation > 0; incarnation-- {
+						err = tx.Delete(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], incarnation), nil)
+						if err != nil {
+							return fmt.Errorf("writeAccountPlain for %x: %w", address, err)
+						}
+					}
+				}
+
+				newV := make([]byte, acc.EncodingLengthForStorage())
+				acc.EncodeForStorage(newV)
+				if accumulator != nil {
+					accumulator.ChangeAccount(address, acc.Incarnation, newV)
+				}
+				if err := next(k, k, newV); err != nil {
+					return err
+				}
+			} else {
+				if accumulator != nil {
+					var address common.Address
+					copy(address[:], k)
+					accumulator.DeleteAccount(address)
+				}
+				if err := next(k, k, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if accumulator != nil {
+			var address common.Address
+			var incarnation uint64
+			var location common.Hash
+			copy(address[:], k[:length.Addr])
+			incarnation = binary.BigEndian.Uint64(k[length.Addr:])
+			copy(location[:], k[length.Addr+length.Incarnation:])
+			accumulator.ChangeStorage(address, incarnation, location, libcommon.Copy(v))
+		}
+		if len(v) > 0 {
+			if err := next(k, k[:storageKeyLength], v); err != nil {
+				return err
+			}
+		} else {
+			if err := next(k, k[:storageKeyLength], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	}, etl.TransformArgs{Quit: quit})
+}
+
+func recoverCodeHashPlain(acc *accounts.Account, db kv.Tx, key []byte) {
+	var address common.Address
+	copy(address[:], key)
+	if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
+		if codeHash, err2 := db.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], acc.Incarnation)); err2 == nil {
+			copy(acc.CodeHash[:], codeHash)
+		}
+	}
+}
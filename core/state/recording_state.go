@@ -0,0 +1,102 @@
+package state
+
+import (
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// RecordingState is the IntraBlockState of a transaction executed speculatively. It records the changes made
+// to the state, so that they can be replayed on the IntraBlockState of the block once the transaction has been
+// validated. Access lists and refunds only affect the gas, which is already known then, and are not recorded.
+type RecordingState struct {
+	*IntraBlockState
+	changes []func(ibs *IntraBlockState, snapshots map[int]int)
+}
+
+func NewRecordingState(ibs *IntraBlockState) *RecordingState {
+	return &RecordingState{IntraBlockState: ibs}
+}
+
+// Replay applies the recorded changes to the given state.
+func (s *RecordingState) Replay(ibs *IntraBlockState) {
+	snapshots := map[int]int{} // recorded id -> id in ibs
+	for _, change := range s.changes {
+		change(ibs, snapshots)
+	}
+}
+
+func (s *RecordingState) CreateAccount(addr common.Address, contractCreation bool) {
+	s.IntraBlockState.CreateAccount(addr, contractCreation)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.CreateAccount(addr, contractCreation)
+	})
+}
+
+func (s *RecordingState) SubBalance(addr common.Address, amount *uint256.Int) {
+	s.IntraBlockState.SubBalance(addr, amount)
+	value := *amount
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.SubBalance(addr, &value)
+	})
+}
+
+func (s *RecordingState) AddBalance(addr common.Address, amount *uint256.Int) {
+	s.IntraBlockState.AddBalance(addr, amount)
+	value := *amount
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.AddBalance(addr, &value)
+	})
+}
+
+func (s *RecordingState) SetNonce(addr common.Address, nonce uint64) {
+	s.IntraBlockState.SetNonce(addr, nonce)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.SetNonce(addr, nonce)
+	})
+}
+
+func (s *RecordingState) SetCode(addr common.Address, code []byte) {
+	s.IntraBlockState.SetCode(addr, code)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.SetCode(addr, code)
+	})
+}
+
+func (s *RecordingState) SetState(addr common.Address, key *common.Hash, value uint256.Int) {
+	s.IntraBlockState.SetState(addr, key, value)
+	k := *key
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.SetState(addr, &k, value)
+	})
+}
+
+func (s *RecordingState) Suicide(addr common.Address) bool {
+	suicided := s.IntraBlockState.Suicide(addr)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.Suicide(addr)
+	})
+	return suicided
+}
+
+func (s *RecordingState) Snapshot() int {
+	id := s.IntraBlockState.Snapshot()
+	s.changes = append(s.changes, func(ibs *IntraBlockState, snapshots map[int]int) {
+		snapshots[id] = ibs.Snapshot()
+	})
+	return id
+}
+
+func (s *RecordingState) RevertToSnapshot(id int) {
+	s.IntraBlockState.RevertToSnapshot(id)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, snapshots map[int]int) {
+		ibs.RevertToSnapshot(snapshots[id])
+	})
+}
+
+func (s *RecordingState) AddLog(log *types.Log) {
+	s.IntraBlockState.AddLog(log)
+	s.changes = append(s.changes, func(ibs *IntraBlockState, _ map[int]int) {
+		ibs.AddLog(log)
+	})
+}
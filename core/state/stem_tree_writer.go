@@ -0,0 +1,76 @@
+package state
+
+import (
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+var _ StateWriter = (*StemTreeStateWriter)(nil)
+
+// StemTreeStateWriter writes the state to a stem tree, with the layout of the verkle proposal. The storage of
+// the self-destructed accounts is kept, as the keys of the tree don't have the incarnations of the accounts.
+type StemTreeStateWriter struct {
+	tree *trie.StemTree
+}
+
+func NewStemTreeStateWriter(tree *trie.StemTree) *StemTreeStateWriter {
+	return &StemTreeStateWriter{tree: tree}
+}
+
+func stemTreeUint(v *uint256.Int) []byte {
+	b := v.Bytes32()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 { // little-endian
+		b[i], b[j] = b[j], b[i]
+	}
+	return b[:]
+}
+
+func (w *StemTreeStateWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	c := w.tree.Committer()
+	w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemVersionLeafKey), make([]byte, 32))
+	w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemBalanceLeafKey), stemTreeUint(&account.Balance))
+	w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemNonceLeafKey), stemTreeUint(uint256.NewInt(account.Nonce)))
+	if account.IsEmptyCodeHash() {
+		w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemCodeKeccakLeafKey), common.CopyBytes(emptyCodeHash))
+		w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemCodeSizeLeafKey), make([]byte, 32))
+	}
+	return nil
+}
+
+func (w *StemTreeStateWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	c := w.tree.Committer()
+	w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemCodeKeccakLeafKey), common.CopyBytes(codeHash[:]))
+	w.tree.Insert(trie.StemHeaderKey(c, address[:], trie.StemCodeSizeLeafKey), stemTreeUint(uint256.NewInt(uint64(len(code)))))
+	for i, chunk := range trie.ChunkifyCode(code) {
+		w.tree.Insert(trie.StemCodeChunkKey(c, address[:], uint64(i)), chunk)
+	}
+	return nil
+}
+
+func (w *StemTreeStateWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	c := w.tree.Committer()
+	for _, leafKey := range []byte{trie.StemVersionLeafKey, trie.StemBalanceLeafKey, trie.StemNonceLeafKey, trie.StemCodeKeccakLeafKey, trie.StemCodeSizeLeafKey} {
+		w.tree.Insert(trie.StemHeaderKey(c, address[:], leafKey), nil)
+	}
+	return nil
+}
+
+func (w *StemTreeStateWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	if *original == *value {
+		return nil
+	}
+	slotKey := trie.StemStorageKey(w.tree.Committer(), address[:], new(uint256.Int).SetBytes(key[:]))
+	if value.IsZero() {
+		w.tree.Insert(slotKey, nil)
+	} else {
+		b := value.Bytes32()
+		w.tree.Insert(slotKey, b[:])
+	}
+	return nil
+}
+
+func (w *StemTreeStateWriter) CreateContract(address common.Address) error {
+	return nil
+}
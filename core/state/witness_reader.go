@@ -0,0 +1,78 @@
+package state
+
+import (
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+// WitnessReader records the state read through it, to build the witness of the execution from: the keys of the
+// accounts and storage slots in the trie, and the codes.
+type WitnessReader struct {
+	StateReader
+	accounts map[common.Hash]struct{} // hashed addresses
+	storage  map[string]struct{}      // hashed address + incarnation + hashed location, as in HashedStorage
+	codes    map[common.Hash][]byte   // hashed address -> code
+}
+
+func NewWitnessReader(r StateReader) *WitnessReader {
+	return &WitnessReader{
+		StateReader: r,
+		accounts:    map[common.Hash]struct{}{},
+		storage:     map[string]struct{}{},
+		codes:       map[common.Hash][]byte{},
+	}
+}
+
+func (r *WitnessReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	r.accounts[crypto.Keccak256Hash(address[:])] = struct{}{}
+	return r.StateReader.ReadAccountData(address)
+}
+
+func (r *WitnessReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	addrHash := crypto.Keccak256Hash(address[:])
+	r.accounts[addrHash] = struct{}{}
+	r.storage[string(dbutils.GenerateCompositeStorageKey(addrHash, incarnation, crypto.Keccak256Hash(key[:])))] = struct{}{}
+	return r.StateReader.ReadAccountStorage(address, incarnation, key)
+}
+
+func (r *WitnessReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	code, err := r.StateReader.ReadAccountCode(address, incarnation, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) > 0 {
+		r.codes[crypto.Keccak256Hash(address[:])] = code
+	}
+	return code, nil
+}
+
+// ReadAccountCodeSize reads the code, the witness holds the codes whose size is read as well.
+func (r *WitnessReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+// Accounts returns the hashed addresses of the accounts read.
+func (r *WitnessReader) Accounts() []common.Hash {
+	keys := make([]common.Hash, 0, len(r.accounts))
+	for k := range r.accounts {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Storage returns the keys of the storage slots read, as in HashedStorage.
+func (r *WitnessReader) Storage() [][]byte {
+	keys := make([][]byte, 0, len(r.storage))
+	for k := range r.storage {
+		keys = append(keys, []byte(k))
+	}
+	return keys
+}
+
+// Codes returns the codes read by the hashed addresses of their accounts.
+func (r *WitnessReader) Codes() map[common.Hash][]byte {
+	return r.codes
+}
@@ -92,7 +92,7 @@ func applyTransaction(config *params.ChainConfig, gp *GasPool, statedb *state.In
 	}
 
 	txContext := NewEVMTxContext(msg)
-	if cfg.TraceJumpDest {
+	if cfg.TraceJumpDest || cfg.TraceTxHash {
 		txContext.TxHash = tx.Hash()
 	}
 
@@ -113,29 +113,34 @@ func applyTransaction(config *params.ChainConfig, gp *GasPool, statedb *state.In
 	// based on the eip phase, we're passing whether the root touch-delete accounts.
 	var receipt *types.Receipt
 	if !cfg.NoReceipts {
-		// by the tx.
-		receipt = &types.Receipt{Type: tx.Type(), CumulativeGasUsed: *usedGas}
-		if result.Failed() {
-			receipt.Status = types.ReceiptStatusFailed
-		} else {
-			receipt.Status = types.ReceiptStatusSuccessful
-		}
-		receipt.TxHash = tx.Hash()
-		receipt.GasUsed = result.UsedGas
-		// if the transaction created a contract, store the creation address in the receipt.
-		if msg.To() == nil {
-			receipt.ContractAddress = crypto.CreateAddress(evm.TxContext().Origin, tx.GetNonce())
-		}
-		// Set the receipt logs and create a bloom for filtering
-		receipt.Logs = statedb.GetLogs(tx.Hash())
-		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
-		receipt.BlockNumber = header.Number
-		receipt.TransactionIndex = uint(statedb.TxIndex())
+		receipt = makeReceipt(tx, msg, result, *usedGas, statedb, header)
 	}
 
 	return receipt, result.ReturnData, err
 }
 
+func makeReceipt(tx types.Transaction, msg types.Message, result *ExecutionResult, usedGas uint64, statedb *state.IntraBlockState, header *types.Header) *types.Receipt {
+	// by the tx.
+	receipt := &types.Receipt{Type: tx.Type(), CumulativeGasUsed: usedGas}
+	if result.Failed() {
+		receipt.Status = types.ReceiptStatusFailed
+	} else {
+		receipt.Status = types.ReceiptStatusSuccessful
+	}
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = result.UsedGas
+	// if the transaction created a contract, store the creation address in the receipt.
+	if msg.To() == nil {
+		receipt.ContractAddress = crypto.CreateAddress(msg.From(), tx.GetNonce())
+	}
+	// Set the receipt logs and create a bloom for filtering
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.BlockNumber = header.Number
+	receipt.TransactionIndex = uint(statedb.TxIndex())
+	return receipt
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
@@ -397,7 +397,7 @@ func (st *StateTransition) TransitionDb(refunds bool, gasBailout bool) (*Executi
 
 	// Set up the initial access list.
 	if st.evm.ChainRules().IsBerlin {
-		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(st.evm.ChainRules()), msg.AccessList())
+		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(st.evm.ChainRules(), st.evm.Context().BlockNumber), msg.AccessList())
 	}
 
 	var (
@@ -435,6 +435,10 @@ func (st *StateTransition) TransitionDb(refunds bool, gasBailout bool) (*Executi
 	} else {
 		st.state.AddBalance(st.evm.Context().Coinbase, amount)
 	}
+	if london && st.evm.ChainConfig().IsEip1559FeeCollector(st.evm.Context().BlockNumber) {
+		burnAmount := new(uint256.Int).Mul(new(uint256.Int).SetUint64(st.gasUsed()), st.evm.Context().BaseFee)
+		st.state.AddBalance(*st.evm.ChainConfig().Eip1559FeeCollector, burnAmount)
+	}
 	if st.isBor {
 		if london {
 			burntContractAddress := common.HexToAddress(st.evm.ChainConfig().Bor.CalculateBurntContract(st.evm.Context().BlockNumber))
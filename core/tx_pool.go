@@ -63,6 +63,9 @@ type TxPoolConfig struct {
 	Disable  bool
 	Locals   []common.Address // Addresses that should be treated by default as local
 	NoLocals bool             // Whether local transaction handling should be disabled
+	// Whether the transactions the pool stores in its database, the local ones included, are dropped at startup
+	// instead of restored
+	NoJournal bool
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -30,6 +30,7 @@ const (
 	MimetypeClique            = "application/x-clique-header"
 	MimetypeParlia            = "application/x-parlia-header"
 	MimetypeBor               = "application/x-bor-header"
+	MimetypeAuRa              = "application/x-aura-header"
 	MimetypeTextPlain         = "text/plain"
 )
 
@@ -643,6 +643,12 @@ type BodyForStorage struct {
 	Uncles   []*Header
 }
 
+// BlockWithReceipts is a block together with the receipts of its transactions
+type BlockWithReceipts struct {
+	Block    *Block
+	Receipts Receipts
+}
+
 // Block represents an entire block in the Ethereum blockchain.
 type Block struct {
 	header       *Header
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/holiman/uint256"
+)
+
+// TipGas is the gas used by the transactions of a block paying the same effective tip.
+type TipGas struct {
+	Tip     *uint256.Int
+	GasUsed uint64
+}
+
+// TipSummary is the distribution of the gas used by the transactions of a block over their effective tips, in
+// ascending tip order. It is all the fee history needs to compute the reward percentiles of a block, without
+// reading its transactions and receipts.
+type TipSummary []TipGas
+
+// NewTipSummary computes the summary of the transactions of a block from their receipts.
+func NewTipSummary(txs Transactions, receipts Receipts, baseFee *big.Int) (TipSummary, error) {
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("%d receipts for %d transactions", len(receipts), len(txs))
+	}
+	fee := uint256.NewInt(0)
+	if baseFee != nil {
+		fee.SetFromBig(baseFee)
+	}
+	s := make(TipSummary, len(txs))
+	for i, tx := range txs {
+		s[i] = TipGas{Tip: tx.GetEffectiveGasTip(fee), GasUsed: receipts[i].GasUsed}
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Tip.Lt(s[j].Tip) })
+	// the transactions paying the same tip are merged
+	merged := s[:0]
+	for _, tg := range s {
+		if len(merged) > 0 && merged[len(merged)-1].Tip.Eq(tg.Tip) {
+			merged[len(merged)-1].GasUsed += tg.GasUsed
+			continue
+		}
+		merged = append(merged, tg)
+	}
+	return merged, nil
+}
+
+// Percentiles returns the rewards of the percentiles (in ascending order, between 0 and 100) of the gas used by
+// the block: the tip of the first transactions reaching the percentile of the gas, all zero for an empty block.
+func (s TipSummary) Percentiles(gasUsed uint64, percentiles []float64) []*big.Int {
+	rewards := make([]*big.Int, len(percentiles))
+	if len(s) == 0 {
+		for i := range rewards {
+			rewards[i] = new(big.Int)
+		}
+		return rewards
+	}
+	var idx int
+	sumGasUsed := s[0].GasUsed
+	for i, p := range percentiles {
+		thresholdGasUsed := uint64(float64(gasUsed) * p / 100)
+		for sumGasUsed < thresholdGasUsed && idx < len(s)-1 {
+			idx++
+			sumGasUsed += s[idx].GasUsed
+		}
+		rewards[i] = s[idx].Tip.ToBig()
+	}
+	return rewards
+}
+
+// EncodeBinary encodes the summary as the number of its entries, followed by the length and the bytes of the tip
+// and by the gas used of each entry.
+func (s TipSummary) EncodeBinary() []byte {
+	buf := make([]byte, binary.MaxVarintLen64+(1+32+binary.MaxVarintLen64)*len(s))
+	n := binary.PutUvarint(buf, uint64(len(s)))
+	for _, tg := range s {
+		tip := tg.Tip.Bytes()
+		buf[n] = byte(len(tip))
+		n += 1 + copy(buf[n+1:], tip)
+		n += binary.PutUvarint(buf[n:], tg.GasUsed)
+	}
+	return buf[:n]
+}
+
+// DecodeTipSummary decodes a summary encoded by EncodeBinary.
+func DecodeTipSummary(enc []byte) (TipSummary, error) {
+	n, l := binary.Uvarint(enc)
+	if l <= 0 {
+		return nil, fmt.Errorf("tip summary: invalid length")
+	}
+	enc = enc[l:]
+	s := make(TipSummary, 0, n)
+	for i := uint64(0); i < n; i++ {
+		if len(enc) == 0 || len(enc) < 1+int(enc[0]) {
+			return nil, fmt.Errorf("tip summary: entry %d is truncated", i)
+		}
+		tip := new(uint256.Int).SetBytes(enc[1 : 1+enc[0]])
+		enc = enc[1+enc[0]:]
+		gasUsed, l := binary.Uvarint(enc)
+		if l <= 0 {
+			return nil, fmt.Errorf("tip summary: entry %d is truncated", i)
+		}
+		enc = enc[l:]
+		s = append(s, TipGas{Tip: tip, GasUsed: gasUsed})
+	}
+	if len(enc) != 0 {
+		return nil, fmt.Errorf("tip summary: %d trailing bytes", len(enc))
+	}
+	return s, nil
+}
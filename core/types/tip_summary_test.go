@@ -0,0 +1,45 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTipSummary(t *testing.T) {
+	var txs Transactions
+	var receipts Receipts
+	for i, price := range []uint64{30, 10, 20, 10, 300} {
+		txs = append(txs, NewTransaction(uint64(i), [20]byte{}, uint256.NewInt(0), 21000, uint256.NewInt(price), nil))
+		receipts = append(receipts, &Receipt{GasUsed: 21000 * uint64(i+1)})
+	}
+	s, err := NewTipSummary(txs, receipts, big.NewInt(10))
+	require.NoError(t, err)
+	// the tips are the prices above the base fee, the equal ones are merged
+	require.Equal(t, TipSummary{
+		{Tip: uint256.NewInt(0), GasUsed: 21000 * (2 + 4)},
+		{Tip: uint256.NewInt(10), GasUsed: 21000 * 3},
+		{Tip: uint256.NewInt(20), GasUsed: 21000 * 1},
+		{Tip: uint256.NewInt(290), GasUsed: 21000 * 5},
+	}, s)
+
+	rewards := s.Percentiles(21000*15, []float64{0, 40, 45, 60, 66.7, 100})
+	require.Equal(t, []string{"0", "0", "10", "10", "290", "290"}, []string{rewards[0].String(), rewards[1].String(), rewards[2].String(), rewards[3].String(), rewards[4].String(), rewards[5].String()})
+
+	decoded, err := DecodeTipSummary(s.EncodeBinary())
+	require.NoError(t, err)
+	require.Equal(t, s, decoded)
+
+	empty, err := NewTipSummary(nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "0", empty.Percentiles(0, []float64{50})[0].String())
+	decoded, err = DecodeTipSummary(empty.EncodeBinary())
+	require.NoError(t, err)
+	require.NotNil(t, decoded)
+	require.Empty(t, decoded)
+
+	_, err = DecodeTipSummary(s.EncodeBinary()[:5])
+	require.Error(t, err)
+}
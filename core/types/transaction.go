@@ -293,29 +293,34 @@ func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // TxByPriceAndTime implements both the sort and the heap interface, making it useful
 // for all at once sorting as well as individually adding and removing elements.
-type TxByPriceAndTime Transactions
+// Transactions are compared by the tip they pay on top of the base fee, or by the
+// gas price if there is no base fee.
+type TxByPriceAndTime struct {
+	txs     Transactions
+	baseFee *uint256.Int
+}
 
-func (s TxByPriceAndTime) Len() int { return len(s) }
+func (s TxByPriceAndTime) Len() int { return len(s.txs) }
 func (s TxByPriceAndTime) Less(i, j int) bool {
 	// If the prices are equal, use the time the transaction was first seen for
 	// deterministic sorting
-	cmp := s[i].GetPrice().Cmp(s[j].GetPrice())
+	cmp := s.txs[i].GetEffectiveGasTip(s.baseFee).Cmp(s.txs[j].GetEffectiveGasTip(s.baseFee))
 	if cmp == 0 {
-		return s[i].Time().Before(s[j].Time())
+		return s.txs[i].Time().Before(s.txs[j].Time())
 	}
 	return cmp > 0
 }
-func (s TxByPriceAndTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s TxByPriceAndTime) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
 
 func (s *TxByPriceAndTime) Push(x interface{}) {
-	*s = append(*s, x.(Transaction))
+	s.txs = append(s.txs, x.(Transaction))
 }
 
 func (s *TxByPriceAndTime) Pop() interface{} {
-	old := *s
+	old := s.txs
 	n := len(old)
 	x := old[n-1]
-	*s = old[0 : n-1]
+	s.txs = old[0 : n-1]
 	return x
 }
 
@@ -337,13 +342,14 @@ type TransactionsByPriceAndNonce struct {
 }
 
 // NewTransactionsByPriceAndNonce creates a transaction set that can retrieve
-// price sorted transactions in a nonce-honouring way.
+// price sorted transactions in a nonce-honouring way. The price is the effective
+// tip for the given base fee, which may be nil before London.
 //
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providing it to the constructor.
-func NewTransactionsByPriceAndNonce(signer Signer, txs TransactionsGroupedBySender) *TransactionsByPriceAndNonce {
+func NewTransactionsByPriceAndNonce(signer Signer, txs TransactionsGroupedBySender, baseFee *uint256.Int) *TransactionsByPriceAndNonce {
 	// Initialize a price and received time based heap with the head transactions
-	heads := make(TxByPriceAndTime, 0, len(txs))
+	heads := TxByPriceAndTime{txs: make(Transactions, 0, len(txs)), baseFee: baseFee}
 	idx := make(map[common.Address]int, len(txs))
 	for i, accTxs := range txs {
 		from, _ := accTxs[0].Sender(signer)
@@ -355,7 +361,7 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs TransactionsGroupedBySend
 		//txs = txs[:len(txs)-1]
 		//continue
 		//}
-		heads = append(heads, accTxs[0])
+		heads.txs = append(heads.txs, accTxs[0])
 		idx[from] = i
 		txs[i] = accTxs[1:]
 	}
@@ -371,20 +377,20 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs TransactionsGroupedBySend
 }
 
 func (t *TransactionsByPriceAndNonce) Empty() bool {
-	return len(t.idx) == 0
+	return len(t.heads.txs) == 0
 }
 
 // Peek returns the next transaction by price.
 func (t *TransactionsByPriceAndNonce) Peek() Transaction {
-	if len(t.heads) == 0 {
+	if len(t.heads.txs) == 0 {
 		return nil
 	}
-	return t.heads[0]
+	return t.heads.txs[0]
 }
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *TransactionsByPriceAndNonce) Shift() {
-	acc, _ := t.heads[0].Sender(t.signer)
+	acc, _ := t.heads.txs[0].Sender(t.signer)
 	idx, ok := t.idx[acc]
 	if !ok {
 		heap.Pop(&t.heads)
@@ -395,7 +401,7 @@ func (t *TransactionsByPriceAndNonce) Shift() {
 		heap.Pop(&t.heads)
 		return
 	}
-	t.heads[0], t.txs[idx] = txs[0], txs[1:]
+	t.heads.txs[0], t.txs[idx] = txs[0], txs[1:]
 	heap.Fix(&t.heads, 0)
 }
 
@@ -344,7 +344,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 		}
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset := NewTransactionsByPriceAndNonce(*signer, groups)
+	txset := NewTransactionsByPriceAndNonce(*signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -402,7 +402,7 @@ func TestTransactionTimeSort(t *testing.T) {
 		}
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset := NewTransactionsByPriceAndNonce(*signer, groups)
+	txset := NewTransactionsByPriceAndNonce(*signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -429,6 +429,43 @@ func TestTransactionTimeSort(t *testing.T) {
 	}
 }
 
+// Tests that with a base fee the transactions are sorted by the tip the miner actually gets,
+// which is capped by the fee cap.
+func TestTransactionEffectiveTipSort(t *testing.T) {
+	signer := LatestSignerForChainID(common.Big1)
+	newTx := func(tip, feeCap uint64) Transaction {
+		key, _ := crypto.GenerateKey()
+		tx, err := SignTx(&DynamicFeeTransaction{
+			CommonTx: CommonTx{ChainID: u256.Num1, To: &testAddr, Value: u256.Num0, Gas: 21000},
+			Tip:      uint256.NewInt(tip),
+			FeeCap:   uint256.NewInt(feeCap),
+		}, *signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	highTip, highFeeCap := newTx(5, 10), newTx(3, 100)
+
+	txset := NewTransactionsByPriceAndNonce(*signer, TransactionsGroupedBySender{{highTip}, {highFeeCap}}, nil)
+	if txset.Peek() != highTip {
+		t.Errorf("without base fee the transaction with the highest tip should be first")
+	}
+	// with base fee 8 the effective tips are 2 and 3
+	txset = NewTransactionsByPriceAndNonce(*signer, TransactionsGroupedBySender{{highTip}, {highFeeCap}}, uint256.NewInt(8))
+	if txset.Peek() != highFeeCap {
+		t.Errorf("with base fee the transaction with the highest effective tip should be first")
+	}
+	txset.Shift()
+	if txset.Peek() != highTip {
+		t.Errorf("expected the other transaction second")
+	}
+	txset.Shift()
+	if !txset.Empty() || txset.Peek() != nil {
+		t.Errorf("expected no transactions left")
+	}
+}
+
 // TestTransactionCoding tests serializing/de-serializing to/from rlp and JSON.
 func TestTransactionCoding(t *testing.T) {
 	key, err := crypto.GenerateKey()
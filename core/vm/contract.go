@@ -60,6 +60,12 @@ type Contract struct {
 
 	Gas   uint64
 	value *uint256.Int
+
+	// EOF code: the container, the code section executed, and the stack items it can access, above stackBase
+	eof         *eofContainer
+	section     uint16
+	stackBase   int
+	returnStack []eofFrame
 }
 
 // NewContract returns a new contract environment for the execution of EVM.
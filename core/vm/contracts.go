@@ -149,21 +149,25 @@ func init() {
 	}
 }
 
-// ActivePrecompiles returns the precompiles enabled with the current configuration.
-func ActivePrecompiles(rules *params.Rules) []common.Address {
+// ActivePrecompiles returns the precompiles enabled with the current configuration, and the custom precompiles of the
+// chain active at the block.
+func ActivePrecompiles(rules *params.Rules, blockNumber uint64) []common.Address {
+	var addresses []common.Address
 	switch {
 	case rules.IsBerlin:
-		return PrecompiledAddressesBerlin
+		addresses = PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
 		if rules.IsParlia {
-			return PrecompiledAddressesIstanbulForBSC
+			addresses = PrecompiledAddressesIstanbulForBSC
+		} else {
+			addresses = PrecompiledAddressesIstanbul
 		}
-		return PrecompiledAddressesIstanbul
 	case rules.IsByzantium:
-		return PrecompiledAddressesByzantium
+		addresses = PrecompiledAddressesByzantium
 	default:
-		return PrecompiledAddressesHomestead
+		addresses = PrecompiledAddressesHomestead
 	}
+	return withCustomPrecompiles(rules, blockNumber, addresses)
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
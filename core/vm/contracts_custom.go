@@ -0,0 +1,106 @@
+package vm
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// Custom chains (e.g. AuRa networks) may have precompiled contracts of their own, besides the ones of the fork rules.
+// They are registered for the chain ID, at an address, from an activation block.
+
+type customPrecompile struct {
+	activation uint64
+	contract   PrecompiledContract
+}
+
+var (
+	customPrecompilesLock sync.RWMutex
+	customPrecompiles     = map[uint64]map[common.Address][]customPrecompile{} // chain ID -> address -> by activation
+	namedPrecompiles      = map[string]PrecompiledContract{}
+)
+
+// RegisterPrecompile activates the precompiled contract at the address on the chain from the activation block. It
+// takes precedence over the precompile of the fork rules at the same address, and over the contract registered at the
+// address from an earlier block.
+func RegisterPrecompile(chainID *big.Int, activation uint64, addr common.Address, p PrecompiledContract) {
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+	chain, ok := customPrecompiles[chainID.Uint64()]
+	if !ok {
+		chain = map[common.Address][]customPrecompile{}
+		customPrecompiles[chainID.Uint64()] = chain
+	}
+	precompiles := chain[addr]
+	i := sort.Search(len(precompiles), func(i int) bool { return precompiles[i].activation >= activation })
+	if i < len(precompiles) && precompiles[i].activation == activation {
+		precompiles[i].contract = p
+		return
+	}
+	precompiles = append(precompiles, customPrecompile{})
+	copy(precompiles[i+1:], precompiles[i:])
+	precompiles[i] = customPrecompile{activation: activation, contract: p}
+	chain[addr] = precompiles
+}
+
+// RegisterNamedPrecompile makes the precompiled contract available to the chain specs, by its builtin name.
+func RegisterNamedPrecompile(name string, p PrecompiledContract) {
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+	namedPrecompiles[name] = p
+}
+
+// NamedPrecompile returns the precompiled contract registered with the builtin name.
+func NamedPrecompile(name string) (PrecompiledContract, bool) {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+	p, ok := namedPrecompiles[name]
+	return p, ok
+}
+
+// customPrecompileAt returns the custom precompile of the chain at the address, if it's active at the block.
+func customPrecompileAt(rules *params.Rules, blockNumber uint64, addr common.Address) (PrecompiledContract, bool) {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+	precompiles := customPrecompiles[rules.ChainID.Uint64()][addr]
+	for i := len(precompiles) - 1; i >= 0; i-- {
+		if precompiles[i].activation <= blockNumber {
+			return precompiles[i].contract, true
+		}
+	}
+	return nil, false
+}
+
+// withCustomPrecompiles adds to the addresses of the fork rules the ones of the custom precompiles of the chain which
+// are active at the block.
+func withCustomPrecompiles(rules *params.Rules, blockNumber uint64, addresses []common.Address) []common.Address {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+	chain := customPrecompiles[rules.ChainID.Uint64()]
+	if len(chain) == 0 {
+		return addresses
+	}
+	res := addresses
+	for addr, precompiles := range chain {
+		if precompiles[0].activation > blockNumber || containsAddress(addresses, addr) {
+			continue
+		}
+		if len(res) == len(addresses) {
+			res = append(make([]common.Address, 0, len(addresses)+len(chain)), addresses...)
+		}
+		res = append(res, addr)
+	}
+	return res
+}
+
+func containsAddress(addresses []common.Address, addr common.Address) bool {
+	for _, a := range addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+type echoPrecompile struct{ gas uint64 }
+
+func (p *echoPrecompile) RequiredGas(input []byte) uint64  { return p.gas }
+func (p *echoPrecompile) Run(input []byte) ([]byte, error) { return input, nil }
+
+func TestCustomPrecompiles(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ChainID = big.NewInt(4242)
+	addr := common.HexToAddress("0x1000")
+	first, second, ecrecover := &echoPrecompile{gas: 1}, &echoPrecompile{gas: 2}, &echoPrecompile{gas: 3}
+	RegisterPrecompile(config.ChainID, 20, addr, second)
+	RegisterPrecompile(config.ChainID, 10, addr, first)
+	RegisterPrecompile(config.ChainID, 0, common.BytesToAddress([]byte{1}), ecrecover)
+
+	precompileAt := func(blockNumber uint64, addr common.Address) PrecompiledContract {
+		evm := NewEVM(BlockContext{BlockNumber: blockNumber}, TxContext{}, &dummyStatedb{}, &config, Config{})
+		p, _ := evm.precompile(addr)
+		return p
+	}
+	for _, tt := range []struct {
+		blockNumber uint64
+		expected    PrecompiledContract
+	}{{9, nil}, {10, first}, {19, first}, {20, second}, {100, second}} {
+		if p := precompileAt(tt.blockNumber, addr); p != tt.expected {
+			t.Errorf("block %d: unexpected precompile %v", tt.blockNumber, p)
+		}
+		active := containsAddress(ActivePrecompiles(config.Rules(tt.blockNumber), tt.blockNumber), addr)
+		if active != (tt.expected != nil) {
+			t.Errorf("block %d: precompile active %t", tt.blockNumber, active)
+		}
+	}
+	// the custom precompile replaces the one of the fork rules
+	if p := precompileAt(0, common.BytesToAddress([]byte{1})); p != ecrecover {
+		t.Errorf("unexpected precompile %v replacing ecrecover", p)
+	}
+	if n := len(ActivePrecompiles(config.Rules(100), 100)); n != len(PrecompiledAddressesBerlin)+1 {
+		t.Errorf("expected %d active precompiles, got %d", len(PrecompiledAddressesBerlin)+1, n)
+	}
+	// other chains are not affected
+	if p, _ := NewEVM(BlockContext{BlockNumber: 100}, TxContext{}, &dummyStatedb{}, params.TestChainConfig, Config{}).precompile(addr); p != nil {
+		t.Errorf("unexpected precompile %v on another chain", p)
+	}
+}
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sort"
 
@@ -171,3 +172,108 @@ func opBaseFee(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContex
 	callContext.Stack.Push(baseFee)
 	return nil, nil
 }
+
+// enableEOF applies EIP-3540, EIP-3670, EIP-4200 and EIP-4750 to the instruction set of the EOF code
+// - Removes the dynamic jumps JUMP and JUMPI, and PC
+// - Adds the relative jumps RJUMP, RJUMPI and RJUMPV
+// - Adds the code sections calls CALLF and RETF
+func enableEOF(jt *JumpTable) {
+	jt[JUMP], jt[JUMPI], jt[PC] = nil, nil, nil
+	jt[RJUMP] = &operation{
+		execute:     opRjump,
+		constantGas: GasQuickStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+		jumps:       true,
+	}
+	jt[RJUMPI] = &operation{
+		execute:     opRjumpi,
+		constantGas: 4,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+		jumps:       true,
+	}
+	jt[RJUMPV] = &operation{
+		execute:     opRjumpv,
+		constantGas: 4,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+		jumps:       true,
+	}
+	jt[CALLF] = &operation{
+		execute:     opCallf,
+		constantGas: GasFastStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+		jumps:       true,
+	}
+	jt[RETF] = &operation{
+		execute:     opRetf,
+		constantGas: GasFastestStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+		jumps:       true,
+	}
+}
+
+// opRjump implements RJUMP opcode, the offset is relative to the next instruction
+func opRjump(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContext) ([]byte, error) {
+	offset := int16(binary.BigEndian.Uint16(callContext.Contract.Code[*pc+1:]))
+	*pc = uint64(int64(*pc+3) + int64(offset))
+	return nil, nil
+}
+
+// opRjumpi implements RJUMPI opcode
+func opRjumpi(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContext) ([]byte, error) {
+	cond := callContext.Stack.Pop()
+	if cond.IsZero() {
+		*pc += 3
+		return nil, nil
+	}
+	return opRjump(pc, interpreter, callContext)
+}
+
+// opRjumpv implements RJUMPV opcode, jumping by the offset of the case, or to the next instruction if it's out of
+// the jump table
+func opRjumpv(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContext) ([]byte, error) {
+	code := callContext.Contract.Code
+	c := callContext.Stack.Pop()
+	count := uint64(code[*pc+1])
+	next := *pc + 2 + 2*count
+	if c.LtUint64(count) {
+		offset := int16(binary.BigEndian.Uint16(code[*pc+2+2*c.Uint64():]))
+		next = uint64(int64(next) + int64(offset))
+	}
+	*pc = next
+	return nil, nil
+}
+
+// opCallf implements CALLF opcode: the code section called only sees the stack items it takes as inputs
+func opCallf(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContext) ([]byte, error) {
+	contract := callContext.Contract
+	section := binary.BigEndian.Uint16(contract.Code[*pc+1:])
+	inputs := int(contract.eof.types[section].inputs)
+	if sLen := callContext.Stack.Len() - contract.stackBase; sLen < inputs {
+		return nil, &ErrStackUnderflow{stackLen: sLen, required: inputs}
+	}
+	if len(contract.returnStack) >= eofReturnStackMax {
+		return nil, ErrReturnStackExceeded
+	}
+	contract.returnStack = append(contract.returnStack, eofFrame{section: contract.section, pc: *pc + 3, stackBase: contract.stackBase})
+	contract.section, contract.stackBase = section, callContext.Stack.Len()-inputs
+	*pc = contract.eof.codeOffsets[section]
+	return nil, nil
+}
+
+// opRetf implements RETF opcode: the code section leaves exactly its outputs on the stack
+func opRetf(pc *uint64, interpreter *EVMInterpreter, callContext *ScopeContext) ([]byte, error) {
+	contract := callContext.Contract
+	outputs := int(contract.eof.types[contract.section].outputs)
+	if callContext.Stack.Len() != contract.stackBase+outputs || len(contract.returnStack) == 0 {
+		return nil, ErrInvalidRetf
+	}
+	frame := contract.returnStack[len(contract.returnStack)-1]
+	contract.returnStack = contract.returnStack[:len(contract.returnStack)-1]
+	contract.section, contract.stackBase, *pc = frame.section, frame.stackBase, frame.pc
+	return nil, nil
+}
@@ -0,0 +1,236 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// EVM Object Format (EOF) v1, EIP-3540, EIP-3670, EIP-4200 and EIP-4750. The container is:
+//
+//	magic (0xEF00) + version (0x01) + header + body
+//	header: [types_kind (0x03) + types_size] + (code_kind (0x01) + code_size)... + [data_kind (0x02) + data_size] + 0x00
+//	body: [types] + code sections + [data]
+//
+// The types section has the inputs and outputs of each code section, 1 byte each. It's required if there are
+// several code sections, the first one taking no inputs and returning no outputs.
+
+const (
+	eofFormatByte byte = 0xEF
+	eofMagicByte  byte = 0x00
+	eofVersion1   byte = 0x01
+
+	eofKindCode       byte = 0x01
+	eofKindData       byte = 0x02
+	eofKindTypes      byte = 0x03
+	eofTerminatorByte byte = 0x00
+
+	eofMaxCodeSections = 1024
+	eofMaxFunctionIO   = 0x7f // inputs or outputs of a code section
+	eofReturnStackMax  = 1024 // depth of the CALLF frames
+)
+
+// eofFunctionType - the stack items taken and returned by a code section
+type eofFunctionType struct {
+	inputs, outputs uint8
+}
+
+// eofContainer - the sections of an EOF container, by their offsets in the code
+type eofContainer struct {
+	types       []eofFunctionType
+	codeOffsets []uint64
+	codeSizes   []uint64
+	dataOffset  uint64
+	dataSize    uint64
+}
+
+// eofFrame - the caller of a code section, restored by RETF
+type eofFrame struct {
+	section   uint16
+	pc        uint64
+	stackBase int
+}
+
+// hasEOFMagic - whether the code is meant to be an EOF container
+func hasEOFMagic(code []byte) bool {
+	return len(code) >= 2 && code[0] == eofFormatByte && code[1] == eofMagicByte
+}
+
+func eofError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidEOF}, args...)...)
+}
+
+// parseEOF reads the header of the container, and checks the sizes of its sections (EIP-3540, EIP-4750)
+func parseEOF(code []byte) (*eofContainer, error) {
+	if !hasEOFMagic(code) {
+		return nil, eofError("missing magic")
+	}
+	if len(code) < 3 || code[2] != eofVersion1 {
+		return nil, eofError("unsupported version")
+	}
+	i := 3
+	section := func(kind byte) (uint64, bool, error) {
+		if i >= len(code) || code[i] != kind {
+			return 0, false, nil
+		}
+		if i+3 > len(code) {
+			return 0, false, eofError("truncated header")
+		}
+		size := uint64(binary.BigEndian.Uint16(code[i+1:]))
+		if size == 0 {
+			return 0, false, eofError("empty section of kind %d", kind)
+		}
+		i += 3
+		return size, true, nil
+	}
+
+	c := &eofContainer{}
+	typesSize, hasTypes, err := section(eofKindTypes)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		size, ok, err := section(eofKindCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if len(c.codeSizes) == eofMaxCodeSections {
+			return nil, eofError("too many code sections")
+		}
+		c.codeSizes = append(c.codeSizes, size)
+	}
+	if len(c.codeSizes) == 0 {
+		return nil, eofError("missing code section")
+	}
+	if c.dataSize, _, err = section(eofKindData); err != nil {
+		return nil, err
+	}
+	if i >= len(code) || code[i] != eofTerminatorByte {
+		return nil, eofError("missing header terminator")
+	}
+	offset := uint64(i + 1)
+
+	switch {
+	case hasTypes:
+		if typesSize != 2*uint64(len(c.codeSizes)) {
+			return nil, eofError("types section size %d for %d code sections", typesSize, len(c.codeSizes))
+		}
+		if offset+typesSize > uint64(len(code)) {
+			return nil, eofError("truncated types section")
+		}
+		for j := uint64(0); j < typesSize; j += 2 {
+			t := eofFunctionType{inputs: code[offset+j], outputs: code[offset+j+1]}
+			if t.inputs > eofMaxFunctionIO || t.outputs > eofMaxFunctionIO {
+				return nil, eofError("too many inputs or outputs of code section %d", j/2)
+			}
+			c.types = append(c.types, t)
+		}
+		if c.types[0] != (eofFunctionType{}) {
+			return nil, eofError("first code section with inputs or outputs")
+		}
+		offset += typesSize
+	case len(c.codeSizes) > 1:
+		return nil, eofError("missing types section")
+	default:
+		c.types = []eofFunctionType{{}}
+	}
+	for _, size := range c.codeSizes {
+		c.codeOffsets = append(c.codeOffsets, offset)
+		offset += size
+	}
+	c.dataOffset = offset
+	if offset+c.dataSize != uint64(len(code)) {
+		return nil, eofError("container size %d, sections size %d", len(code), offset+c.dataSize)
+	}
+	return c, nil
+}
+
+// validateEOF parses the container, and validates its code sections against the instructions of the jump table
+// (EIP-3670, EIP-4200, EIP-4750)
+func validateEOF(code []byte, jt *JumpTable) (*eofContainer, error) {
+	c, err := parseEOF(code)
+	if err != nil {
+		return nil, err
+	}
+	for section, offset := range c.codeOffsets {
+		if err := validateEOFCode(code[offset:offset+c.codeSizes[section]], section, len(c.codeSizes), jt); err != nil {
+			return nil, fmt.Errorf("code section %d: %w", section, err)
+		}
+	}
+	return c, nil
+}
+
+// validateEOFCode checks that the instructions of the code section are defined, with their immediates, that the
+// relative jumps land on instructions of the section, and that the last instruction terminates the execution. The
+// first code section, where the execution starts, can't return.
+func validateEOFCode(code []byte, section, sections int, jt *JumpTable) error {
+	var (
+		op           OpCode
+		instructions = make([]bool, len(code))
+		targets      []int
+	)
+	for i := 0; i < len(code); {
+		op = OpCode(code[i])
+		if jt[op] == nil && op != INVALID {
+			return eofError("undefined instruction %#x at %d", byte(op), i)
+		}
+		if op == RETF && section == 0 {
+			return eofError("return from the first code section at %d", i)
+		}
+		instructions[i] = true
+		next := i + 1
+		switch {
+		case op >= PUSH1 && op <= PUSH32:
+			next += int(op-PUSH1) + 1
+		case op == RJUMP || op == RJUMPI:
+			next += 2
+			if next <= len(code) {
+				targets = append(targets, next+int(int16(binary.BigEndian.Uint16(code[i+1:]))))
+			}
+		case op == RJUMPV:
+			if next >= len(code) {
+				return eofError("truncated immediate of %v at %d", op, i)
+			}
+			count := int(code[next])
+			if count == 0 {
+				return eofError("empty jump table at %d", i)
+			}
+			next += 1 + 2*count
+			if next <= len(code) {
+				for j := 0; j < count; j++ {
+					targets = append(targets, next+int(int16(binary.BigEndian.Uint16(code[i+2+2*j:]))))
+				}
+			}
+		case op == CALLF:
+			next += 2
+			if next <= len(code) {
+				if callee := int(binary.BigEndian.Uint16(code[i+1:])); callee >= sections {
+					return eofError("call to missing code section %d at %d", callee, i)
+				}
+			}
+		}
+		if next > len(code) {
+			return eofError("truncated immediate of %v at %d", op, i)
+		}
+		i = next
+	}
+	switch op {
+	case STOP, RETURN, REVERT, INVALID, SELFDESTRUCT, RETF, RJUMP:
+	default:
+		return eofError("code section ends with %v", op)
+	}
+	for _, target := range targets {
+		if target < 0 || target >= len(code) || !instructions[target] {
+			return eofError("invalid relative jump destination %d", target)
+		}
+	}
+	return nil
+}
+
+// ValidateEOF validates the EOF container, as done for the contracts created once EOF is enabled.
+func ValidateEOF(code []byte) error {
+	_, err := validateEOF(code, &eofInstructionSet)
+	return err
+}
@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+func TestValidateEOF(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"stop", "ef00010100010000", true},
+		{"data", "ef00010100010200020000aabb", true},
+		{"first section not terminating", "ef000103000401000301000300" + "00000001" + "b00001" + "602ab1", false},
+		{"callf", "ef000103000401000401000300" + "00000001" + "b0000100" + "602ab1", true},
+		{"rjumpi", "ef000101000700" + "60015d00010000", true},
+		{"rjumpv", "ef000101000900" + "60005e01000100fe00", true},
+		{"no magic", "ef01010100010000", false},
+		{"version", "ef00020100010000", false},
+		{"no code section", "ef000100", false},
+		{"empty code section", "ef00010100000000", false},
+		{"no terminator", "ef0001010001", false},
+		{"trailing bytes", "ef0001010001000000", false},
+		{"truncated body", "ef000101000200", false},
+		{"no types section", "ef000101000101000100" + "00" + "00", false},
+		{"types section size", "ef000103000201000101000100" + "0000" + "00" + "00", false},
+		{"first section outputs", "ef000103000401000101000100" + "00010000" + "00" + "b1", false},
+		{"undefined instruction", "ef0001010002000c00", false},
+		{"jump", "ef000101000400" + "60005600", false},
+		{"truncated push", "ef000101000100" + "61", false},
+		{"truncated rjump", "ef0001010002005c00", false},
+		{"not terminating", "ef000101000100" + "01", false},
+		{"rjump into immediate", "ef000101000500" + "5cfffe" + "5b00", false},
+		{"rjump out of section", "ef000101000300" + "5c0001", false},
+		{"empty jump table", "ef000101000500" + "60005e0000", false},
+		{"callf missing section", "ef000101000400" + "b0000100", false},
+		{"retf in first section", "ef000101000100" + "b1", false},
+	} {
+		err := ValidateEOF(common.FromHex(tt.code))
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidEOF) {
+			t.Errorf("%s: expected invalid container, got %v", tt.name, err)
+		}
+	}
+}
@@ -44,6 +44,9 @@ type (
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
+	if p, ok := customPrecompileAt(evm.chainRules, evm.context.BlockNumber, addr); ok {
+		return p, true
+	}
 	var precompiles map[common.Address]PrecompiledContract
 	switch {
 	case evm.chainRules.IsBerlin:
@@ -541,14 +544,28 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 		return nil, address, gas, nil
 	}
 
-	ret, err = run(evm, contract, nil, false)
+	// EOF initcode must be valid, and deploy valid EOF code (EIP-3540)
+	eofInitcode := evm.chainRules.IsEOF && hasEOFMagic(codeAndHash.code)
+	if eofInitcode {
+		_, err = validateEOF(codeAndHash.code, &eofInstructionSet)
+	}
+	if err == nil {
+		ret, err = run(evm, contract, nil, false)
+	}
 
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := evm.chainRules.IsSpuriousDragon && len(ret) > params.MaxCodeSize
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
+	// Reject code starting with 0xEF if EIP-3541 is enabled, unless it's EOF code deployed by EOF initcode.
 	if err == nil && !maxCodeSizeExceeded {
-		if evm.chainRules.IsLondon && len(ret) >= 1 && ret[0] == 0xEF {
+		switch {
+		case eofInitcode:
+			if !hasEOFMagic(ret) {
+				err = ErrInvalidCode
+			} else {
+				_, err = validateEOF(ret, &eofInstructionSet)
+			}
+		case evm.chainRules.IsLondon && len(ret) >= 1 && ret[0] == 0xEF:
 			err = ErrInvalidCode
 		}
 	}
@@ -34,6 +34,7 @@ type Config struct {
 	NoBaseFee     bool   // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
 	SkipAnalysis  bool   // Whether we can skip jumpdest analysis based on the checked history
 	TraceJumpDest bool   // Print transaction hashes where jumpdest analysis was useful
+	TraceTxHash   bool   // Provide the transaction hashes to the tracer, to index the contract creators
 	NoReceipts    bool   // Do not calculate receipts
 	ReadOnly      bool   // Do no perform any block finalisation
 	EnableTEMV    bool   // true if execution with TEVM enable flag
@@ -73,7 +74,8 @@ type EVMInterpreter struct {
 	jt *JumpTable // EVM instruction table
 }
 
-//structcheck doesn't see embedding
+// structcheck doesn't see embedding
+//
 //nolint:structcheck
 type VM struct {
 	evm *EVM
@@ -212,6 +214,10 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
 		res     []byte // result of the opcode execution function
+		// gas accounting for GasTracer
+		gasTracer     GasTracer
+		refundCopy    uint64
+		memoryGasCopy uint64
 	)
 	// Don't move this deferrred function, it's placed before the capturestate-deferred method,
 	// so that it get's executed _after_: the capturestate needs the stacks before
@@ -221,7 +227,17 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	jt := in.jt
+	if in.evm.chainRules.IsEOF && hasEOFMagic(contract.Code) {
+		// the container was validated when the contract was created, the execution starts at the first code section
+		if contract.eof, err = parseEOF(contract.Code); err != nil {
+			return nil, err
+		}
+		jt, pc = &eofInstructionSet, contract.eof.codeOffsets[0]
+	}
+
 	if in.cfg.Debug {
+		gasTracer, _ = in.cfg.Tracer.(GasTracer)
 		defer func() {
 			if err != nil {
 				if !logged {
@@ -250,15 +266,15 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
-		operation := in.jt[op]
+		operation := jt[op]
 
 		if operation == nil {
 			return nil, &ErrInvalidOpCode{opcode: op}
 		}
 		// Validate stack
-		if sLen := locStack.Len(); sLen < operation.minStack {
+		if sLen := locStack.Len() - contract.stackBase; sLen < operation.minStack {
 			return nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
-		} else if sLen > operation.maxStack {
+		} else if sLen = locStack.Len(); sLen > operation.maxStack {
 			return nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
 		}
 		// If the operation is valid, enforce and write restrictions
@@ -294,6 +310,10 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 				return nil, ErrGasUintOverflow
 			}
 		}
+		if gasTracer != nil {
+			// the refunds and the memory expansion are accounted by the dynamic gas functions
+			refundCopy, memoryGasCopy = in.evm.IntraBlockState().GetRefund(), mem.lastGasCost
+		}
 		// Dynamic portion of gas
 		// consume the gas and return an error if not enough gas is available.
 		// cost is explicitly set so that the capture state defer method can get the proper cost
@@ -309,6 +329,16 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			mem.Resize(memorySize)
 		}
 
+		if gasTracer != nil {
+			refund := in.evm.IntraBlockState().GetRefund()
+			gasTracer.CaptureGas(in.evm, pc, op, StepGas{
+				Refund:      int64(refund) - int64(refundCopy),
+				RefundTotal: refund,
+				MemoryCost:  mem.lastGasCost - memoryGasCopy,
+				MemorySize:  uint64(mem.Len()),
+				ReturnData:  in.returnData,
+			}, in.evm.depth)
+		}
 		if in.cfg.Debug {
 			in.cfg.Tracer.CaptureState(in.evm, pc, op, gasCopy, cost, callContext, in.returnData, in.evm.depth, err) //nolint:errcheck
 			logged = true
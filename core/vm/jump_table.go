@@ -65,11 +65,24 @@ var (
 	istanbulInstructionSet         = newIstanbulInstructionSet()
 	berlinInstructionSet           = newBerlinInstructionSet()
 	londonInstructionSet           = newLondonInstructionSet()
+	eofInstructionSet              JumpTable // initialised by init, as the contract creation validates the EOF code
 )
 
+func init() {
+	eofInstructionSet = newEOFInstructionSet()
+}
+
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// newEOFInstructionSet returns the instructions of the EOF code: the london ones without the dynamic jumps, and the
+// relative jumps and functions of EOF. The legacy code keeps the london instructions.
+func newEOFInstructionSet() JumpTable {
+	instructionSet := newLondonInstructionSet()
+	enableEOF(&instructionSet) // EVM Object Format https://eips.ethereum.org/EIPS/eip-3540
+	return instructionSet
+}
+
 // newLondonInstructionSet returns the frontier, homestead, byzantium,
 // contantinople, istanbul, petersburg, berlin, and london instructions.
 func newLondonInstructionSet() JumpTable {
@@ -128,6 +128,22 @@ type Tracer interface {
 	CaptureAccountWrite(account common.Address) error
 }
 
+// StepGas is the gas accounting of a step of the VM, besides its cost
+type StepGas struct {
+	Refund      int64  // change of the refund counter by the step, negative if it decreases
+	RefundTotal uint64 // refund counter after the step
+	MemoryCost  uint64 // part of the cost paying for the memory expansion
+	MemorySize  uint64 // size of the memory after the expansion
+	ReturnData  []byte // return data of the last call, like rData of CaptureState
+}
+
+// GasTracer is a Tracer which also receives the gas accounting of each step: CaptureGas is called before
+// CaptureState, once the gas of the step is charged and the memory expanded, for the steps which don't fail there.
+type GasTracer interface {
+	Tracer
+	CaptureGas(env *EVM, pc uint64, op OpCode, gas StepGas, depth int)
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
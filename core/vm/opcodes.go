@@ -120,6 +120,9 @@ const (
 	MSIZE    OpCode = 0x59
 	GAS      OpCode = 0x5a
 	JUMPDEST OpCode = 0x5b
+	RJUMP    OpCode = 0x5c
+	RJUMPI   OpCode = 0x5d
+	RJUMPV   OpCode = 0x5e
 )
 
 // 0x60 range.
@@ -199,11 +202,10 @@ const (
 	LOG4
 )
 
-// unofficial opcodes used for parsing.
+// 0xb0 range - functions.
 const (
-	PUSH OpCode = 0xb0 + iota
-	DUP
-	SWAP
+	CALLF OpCode = 0xb0
+	RETF  OpCode = 0xb1
 )
 
 // 0xf0 range - closures.
@@ -216,6 +218,7 @@ const (
 	CREATE2
 	STATICCALL   OpCode = 0xfa
 	REVERT       OpCode = 0xfd
+	INVALID      OpCode = 0xfe // designated invalid instruction (EIP-141), not in the jump tables
 	SELFDESTRUCT OpCode = 0xff
 )
 
@@ -284,9 +287,7 @@ var opCodeToString = map[OpCode]string{
 	BASEFEE:     "BASEFEE",
 
 	// 0x50 range - 'storage' and execution.
-	POP: "POP",
-	//DUP:     "DUP",
-	//SWAP:    "SWAP",
+	POP:      "POP",
 	MLOAD:    "MLOAD",
 	MSTORE:   "MSTORE",
 	MSTORE8:  "MSTORE8",
@@ -298,6 +299,9 @@ var opCodeToString = map[OpCode]string{
 	MSIZE:    "MSIZE",
 	GAS:      "GAS",
 	JUMPDEST: "JUMPDEST",
+	RJUMP:    "RJUMP",
+	RJUMPI:   "RJUMPI",
+	RJUMPV:   "RJUMPV",
 
 	// 0x60 range - push.
 	PUSH1:  "PUSH1",
@@ -383,9 +387,9 @@ var opCodeToString = map[OpCode]string{
 	REVERT:       "REVERT",
 	SELFDESTRUCT: "SELFDESTRUCT",
 
-	PUSH: "PUSH",
-	DUP:  "DUP",
-	SWAP: "SWAP",
+	// 0xb0 range - functions.
+	CALLF: "CALLF",
+	RETF:  "RETF",
 }
 
 func (op OpCode) String() string {
@@ -464,6 +468,9 @@ var stringToOp = map[string]OpCode{
 	"MSIZE":          MSIZE,
 	"GAS":            GAS,
 	"JUMPDEST":       JUMPDEST,
+	"RJUMP":          RJUMP,
+	"RJUMPI":         RJUMPI,
+	"RJUMPV":         RJUMPV,
 	"PUSH1":          PUSH1,
 	"PUSH2":          PUSH2,
 	"PUSH3":          PUSH3,
@@ -540,6 +547,8 @@ var stringToOp = map[string]OpCode{
 	"CALLCODE":       CALLCODE,
 	"REVERT":         REVERT,
 	"SELFDESTRUCT":   SELFDESTRUCT,
+	"CALLF":          CALLF,
+	"RETF":           RETF,
 }
 
 // StringToOp finds the opcode whose name is stored in `str`.
@@ -17,6 +17,8 @@
 package runtime
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -593,3 +595,85 @@ func TestEip2929Cases(t *testing.T) {
 			"account (cheap)", code)
 	}
 }
+
+type stepGasTracer struct {
+	*vm.StructLogger
+	steps map[vm.OpCode][]vm.StepGas
+}
+
+func (t *stepGasTracer) CaptureGas(env *vm.EVM, pc uint64, op vm.OpCode, gas vm.StepGas, depth int) {
+	t.steps[op] = append(t.steps[op], gas)
+}
+
+// TestGasTracer checks the refund counter changes and the memory expansion costs given to a GasTracer
+func TestGasTracer(t *testing.T) {
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), // SSTORE(loc: 0x00, val: 0x01)
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), // SSTORE(loc: 0x00, val: 0x00), refunded
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.MSTORE), // MSTORE(0x00, 0x01), expands the memory
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.MSTORE), // MSTORE(0x00, 0x01)
+	}
+	tracer := &stepGasTracer{StructLogger: vm.NewStructLogger(nil), steps: map[vm.OpCode][]vm.StepGas{}}
+	if _, _, err := Execute(code, nil, &Config{EVMConfig: vm.Config{Debug: true, Tracer: tracer}}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	sstores := tracer.steps[vm.SSTORE]
+	if len(sstores) != 2 {
+		t.Fatalf("expected 2 SSTORE steps, got %d", len(sstores))
+	}
+	if sstores[0].Refund != 0 || sstores[1].Refund <= 0 || sstores[1].RefundTotal != uint64(sstores[1].Refund) {
+		t.Errorf("unexpected SSTORE refunds: %+v", sstores)
+	}
+	mstores := tracer.steps[vm.MSTORE]
+	if len(mstores) != 2 {
+		t.Fatalf("expected 2 MSTORE steps, got %d", len(mstores))
+	}
+	if mstores[0].MemoryCost != params.MemoryGas || mstores[0].MemorySize != 32 {
+		t.Errorf("unexpected memory expansion of the first MSTORE: %+v", mstores[0])
+	}
+	if mstores[1].MemoryCost != 0 || mstores[1].MemorySize != 32 {
+		t.Errorf("unexpected memory expansion of the second MSTORE: %+v", mstores[1])
+	}
+	steps := 0
+	for _, s := range tracer.steps {
+		steps += len(s)
+	}
+	if steps != len(tracer.StructLogs()) {
+		t.Errorf("expected CaptureGas for each step, got %d steps and %d logs", steps, len(tracer.StructLogs()))
+	}
+}
+
+// TestEOF checks the execution of EOF code sections, and the validation of the EOF contracts created
+func TestEOF(t *testing.T) {
+	eofConfig := func() *Config {
+		cfg := &Config{}
+		setDefaults(cfg)
+		cfg.ChainConfig.EOFBlock = new(big.Int)
+		return cfg
+	}
+	code := common.FromHex("ef000103000401000b01000b00" + "00000001" +
+		"b00001" + "600052" + "60206000f3" + // CALLF 1, MSTORE(0x00, result), RETURN(0x00, 0x20)
+		"60015d0003" + "6007b1" + "602ab1") // RJUMPI(1) over RETF(7), RETF(42)
+	ret, _, err := Execute(code, nil, eofConfig(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, common.LeftPadBytes([]byte{42}, 32)) {
+		t.Errorf("unexpected return data %x", ret)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		initcode string
+		err      error
+	}{
+		{"valid", "ef000101001100" + "67ef00010100010000" + "600052" + "60086018f3", nil},
+		{"invalid initcode", "ef000101000100" + "01", vm.ErrInvalidEOF},
+		{"legacy code deployed", "ef000101000a00" + "60ef600053" + "60016000f3", vm.ErrInvalidCode},
+	} {
+		if _, _, _, err := Create(common.FromHex(tt.initcode), eofConfig(), 0); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
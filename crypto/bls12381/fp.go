@@ -165,3 +165,9 @@ func isQuadraticNonResidue(elem *fe) bool {
 	exp(result, elem, pMinus1Over2)
 	return !result.isOne()
 }
+
+// isLexicographicallyLargest returns true if the element is larger than its negation, which is the sign of the
+// y coordinate of the compressed points
+func isLexicographicallyLargest(e *fe) bool {
+	return toBig(e).Cmp(pMinus1Over2) > 0
+}
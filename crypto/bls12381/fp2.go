@@ -250,3 +250,12 @@ func (e *fp2) isQuadraticNonResidue(a *fe2) bool {
 	add(c1, c1, c0)
 	return isQuadraticNonResidue(c1)
 }
+
+// isLexicographicallyLargest2 compares the imaginary parts of the element and of its negation, and then the real
+// parts if the imaginary part is zero
+func isLexicographicallyLargest2(e *fe2) bool {
+	if e[1].isZero() {
+		return isLexicographicallyLargest(&e[0])
+	}
+	return isLexicographicallyLargest(&e[1])
+}
@@ -159,6 +159,67 @@ func (g *G1) EncodePoint(p *PointG1) []byte {
 	return out
 }
 
+// FromCompressed constructs a new point given compressed 48 bytes input in the zcash format: the flags in the three
+// most significant bits are compression, infinity and the sign of y. The point is checked to be in the subgroup.
+func (g *G1) FromCompressed(compressed []byte) (*PointG1, error) {
+	if len(compressed) != 48 {
+		return nil, errors.New("input string should be equal to 48 bytes")
+	}
+	in := make([]byte, 48)
+	copy(in, compressed)
+	if in[0]&(1<<7) == 0 {
+		return nil, errors.New("compression flag should be set")
+	}
+	if in[0]&(1<<6) != 0 {
+		// infinity: all the other bits should be zero
+		in[0] &= 0x3f
+		for i := range in {
+			if in[i] != 0 {
+				return nil, errors.New("input string should be zero when infinity flag is set")
+			}
+		}
+		return g.Zero(), nil
+	}
+	largest := in[0]&(1<<5) != 0
+	in[0] &= 0x1f
+	x, err := fromBytes(in)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b
+	y := new(fe)
+	square(y, x)
+	mul(y, y, x)
+	add(y, y, b)
+	if !sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLexicographicallyLargest(y) != largest {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a point into the compressed 48 bytes form of the zcash format.
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] |= 1 << 6
+	} else {
+		g.Affine(p)
+		copy(out, toBytes(&p[0]))
+		if isLexicographicallyLargest(&p[1]) {
+			out[0] |= 1 << 5
+		}
+	}
+	out[0] |= 1 << 7
+	return out
+}
+
 // New creates a new G1 Point which is equal to zero in other words point at infinity.
 func (g *G1) New() *PointG1 {
 	return g.Zero()
@@ -180,6 +180,67 @@ func (g *G2) EncodePoint(p *PointG2) []byte {
 	return out
 }
 
+// FromCompressed constructs a new point given compressed 96 bytes input in the zcash format: the flags in the three
+// most significant bits are compression, infinity and the sign of y. The point is checked to be in the subgroup.
+func (g *G2) FromCompressed(compressed []byte) (*PointG2, error) {
+	if len(compressed) != 96 {
+		return nil, errors.New("input string should be equal to 96 bytes")
+	}
+	in := make([]byte, 96)
+	copy(in, compressed)
+	if in[0]&(1<<7) == 0 {
+		return nil, errors.New("compression flag should be set")
+	}
+	if in[0]&(1<<6) != 0 {
+		// infinity: all the other bits should be zero
+		in[0] &= 0x3f
+		for i := range in {
+			if in[i] != 0 {
+				return nil, errors.New("input string should be zero when infinity flag is set")
+			}
+		}
+		return g.Zero(), nil
+	}
+	largest := in[0]&(1<<5) != 0
+	in[0] &= 0x1f
+	x, err := g.f.fromBytes(in)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b
+	y := new(fe2)
+	g.f.square(y, x)
+	g.f.mul(y, y, x)
+	g.f.add(y, y, b2)
+	if !g.f.sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLexicographicallyLargest2(y) != largest {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a point into the compressed 96 bytes form of the zcash format.
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] |= 1 << 6
+	} else {
+		g.Affine(p)
+		copy(out, g.f.toBytes(&p[0]))
+		if isLexicographicallyLargest2(&p[1]) {
+			out[0] |= 1 << 5
+		}
+	}
+	out[0] |= 1 << 7
+	return out
+}
+
 // New creates a new G2 Point which is equal to zero in other words point at infinity.
 func (g *G2) New() *PointG2 {
 	return new(PointG2).Zero()
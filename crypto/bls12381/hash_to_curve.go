@@ -0,0 +1,105 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// HashToCurve hashes the message to a G2 point with the domain separation tag, by the BLS12381G2_XMD:SHA-256_SSWU_RO_
+// suite of https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-hash-to-curve-16, which is used by the BLS
+// signatures of the beacon chain.
+func (g *G2) HashToCurve(msg, domain []byte) (*PointG2, error) {
+	u, err := hashToFp2XMDSHA256(msg, domain, 2)
+	if err != nil {
+		return nil, err
+	}
+	q0, q1 := g.mapToCurveNoClear(u[0]), g.mapToCurveNoClear(u[1])
+	p := g.New()
+	g.Add(p, q0, q1)
+	g.ClearCofactor(p)
+	return g.Affine(p), nil
+}
+
+func (g *G2) mapToCurveNoClear(u *fe2) *PointG2 {
+	x, y := swuMapG2(g.f, u)
+	isogenyMapG2(g.f, x, y)
+	return &PointG2{*x, *y, *new(fe2).one()}
+}
+
+// hashToFp2XMDSHA256 is hash_to_field of the field elements of Fp2, with expand_message_xmd and SHA-256
+func hashToFp2XMDSHA256(msg, domain []byte, count int) ([]*fe2, error) {
+	const l = 64 // ceil((ceil(log2(p)) + k) / 8), where k is the security parameter 128
+	uniform, err := expandMsgXMDSHA256(msg, domain, count*2*l)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]*fe2, count)
+	for i := 0; i < count; i++ {
+		e := new(fe2)
+		for j := 0; j < 2; j++ {
+			offset := l * (j + i*2)
+			c, err := fromBig(new(big.Int).Mod(new(big.Int).SetBytes(uniform[offset:offset+l]), modulus.big()))
+			if err != nil {
+				return nil, err
+			}
+			e[j].set(c)
+		}
+		elems[i] = e
+	}
+	return elems, nil
+}
+
+func expandMsgXMDSHA256(msg, domain []byte, outLen int) ([]byte, error) {
+	const blockSize = 64 // the input block size of SHA-256
+	ell := (outLen + sha256.Size - 1) / sha256.Size
+	if ell > 255 || outLen > 65535 || len(domain) > 255 {
+		return nil, errors.New("invalid expand_message_xmd length")
+	}
+	domainPrime := append(append([]byte{}, domain...), byte(len(domain)))
+
+	h := sha256.New()
+	h.Write(make([]byte, blockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(outLen >> 8), byte(outLen), 0})
+	h.Write(domainPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(domainPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*sha256.Size)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		xored := make([]byte, sha256.Size)
+		for j := range xored {
+			xored[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(xored)
+		h.Write([]byte{byte(i)})
+		h.Write(domainPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:outLen], nil
+}
@@ -0,0 +1,87 @@
+package bls12381
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestExpandMsgXMDSHA256(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-hash-to-curve-16#appendix-K.1
+	out, err := expandMsgXMDSHA256(nil, []byte("QUUX-V01-CS02-with-expander-SHA256-128"), 0x20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"; hex.EncodeToString(out) != want {
+		t.Fatalf("got %x, want %s", out, want)
+	}
+}
+
+func TestHashToCurveG2(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-hash-to-curve-16#appendix-J.10.1
+	domain := []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_")
+	for _, v := range []struct {
+		msg            string
+		x1, x0, y1, y0 string
+	}{
+		{
+			msg: "",
+			x0:  "0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a",
+			x1:  "05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d",
+			y0:  "0503921d7f6a12805e72940b963c0cf3471c7b2a524950ca195d11062ee75ec076daf2d4bc358c4b190c0c98064fdd92",
+			y1:  "12424ac32561493f3fe3c260708a12b7c620e7be00099a974e259ddc7d1f6395c3c811cdd19f1e8dbf3e9ecfdcbab8d6",
+		},
+		{
+			msg: "abc",
+			x0:  "02c2d18e033b960562aae3cab37a27ce00d80ccd5ba4b7fe0e7a210245129dbec7780ccc7954725f4168aff2787776e6",
+			x1:  "139cddbccdc5e91b9623efd38c49f81a6f83f175e80b06fc374de9eb4b41dfe4ca3a230ed250fbe3a2acf73a41177fd8",
+			y0:  "1787327b68159716a37440985269cf584bcb1e621d3a7202be6ea05c4cfe244aeb197642555a0645fb87bf7466b2ba48",
+			y1:  "00aa65dae3c8d732d10ecd2c50f8a1baf3001578f71c694e03866e9f3d49ac1e1ce70dd94a733534f106d4cec0eddd16",
+		},
+	} {
+		g := NewG2()
+		p, err := g.HashToCurve([]byte(v.msg), domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := hex.DecodeString(v.x1 + v.x0 + v.y1 + v.y0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.ToBytes(p); !bytes.Equal(got, want) {
+			t.Fatalf("msg %q: got %x, want %x", v.msg, got, want)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	g1, g2 := NewG1(), NewG2()
+	for i := 0; i < fuz; i++ {
+		a := g1.rand()
+		b, err := g1.FromCompressed(g1.ToCompressed(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g1.Equal(a, b) {
+			t.Fatal("bad g1 compression")
+		}
+		c := g2.rand()
+		d, err := g2.FromCompressed(g2.ToCompressed(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g2.Equal(c, d) {
+			t.Fatal("bad g2 compression")
+		}
+	}
+	if p, err := g1.FromCompressed(g1.ToCompressed(g1.Zero())); err != nil || !g1.IsZero(p) {
+		t.Fatal("bad g1 infinity compression", err)
+	}
+	if p, err := g2.FromCompressed(g2.ToCompressed(g2.Zero())); err != nil || !g2.IsZero(p) {
+		t.Fatal("bad g2 infinity compression", err)
+	}
+	// the compressed generator of G1 is well known
+	if want := "97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"; hex.EncodeToString(g1.ToCompressed(g1.One())) != want {
+		t.Fatalf("got %x, want %s", g1.ToCompressed(g1.One()), want)
+	}
+}
@@ -53,22 +53,29 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/aura"
 	"github.com/ledgerwatch/erigon/consensus/bor"
 	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/consensus/parlia"
 	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/forkstate"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/ethutils"
+	auraproto "github.com/ledgerwatch/erigon/eth/protocols/aura"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/eth/protocols/les"
+	snapproto "github.com/ledgerwatch/erigon/eth/protocols/snap"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
@@ -77,10 +84,13 @@ import (
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/lightclient"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
 	stages2 "github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/ledgerwatch/erigon/turbo/txpoolmetrics"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -118,6 +128,7 @@ type Ethereum struct {
 	sentryCancel   context.CancelFunc
 	sentriesClient *sentry.MultiClient
 	sentryServers  []*sentry.GrpcServer
+	sentryAdmin    sentryadmin.Multi
 
 	stagedSync *stagedsync.Sync
 
@@ -137,6 +148,11 @@ type Ethereum struct {
 	notifyMiningAboutNewTxs chan struct{}
 
 	downloader *downloader.Downloader
+
+	lightClient *lightclient.LightClient
+
+	devChain *DevChain       // Set on the dev chain with mining enabled
+	fork     *forkstate.Fork // Set on the dev chain forked from another network
 }
 
 // New creates a new Ethereum object (including the
@@ -175,7 +191,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		panic(err)
 	}
 
-	chainConfig, genesis, genesisErr := core.CommitGenesisBlockWithOverride(chainKv, config.Genesis, config.OverrideMergeNetsplitBlock, config.OverrideTerminalTotalDifficulty)
+	chainConfig, genesis, genesisErr := core.CommitGenesisBlockWithOverride(chainKv, config.Genesis, config.OverrideForkBlocks, config.OverrideTerminalTotalDifficulty)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
@@ -215,14 +231,26 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	}
 	backend.gasPrice, _ = uint256.FromBig(config.Miner.GasPrice)
 
+	// the state of the forked network is the state of the chain, unless the chain has written it
+	var stateBackend state.Backend
+	if config.Fork.Enabled() {
+		fork, err := forkstate.Open(ctx, config.Fork, chainKv, logger)
+		if err != nil {
+			return nil, fmt.Errorf("fork: %w", err)
+		}
+		backend.fork, stateBackend = fork, fork.ForkBackend
+		log.Info("Forked the chain", "url", config.Fork.URL, "datadir", config.Fork.Datadir, "block", fork.Block)
+	}
+
 	var sentries []direct.SentryClient
 	if len(stack.Config().P2P.SentryAddr) > 0 {
 		for _, addr := range stack.Config().P2P.SentryAddr {
-			sentryClient, err := sentry.GrpcClient(backend.sentryCtx, addr)
+			conn, err := sentry.GrpcClientConn(backend.sentryCtx, addr)
 			if err != nil {
 				return nil, err
 			}
-			sentries = append(sentries, sentryClient)
+			sentries = append(sentries, direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)))
+			backend.sentryAdmin = append(backend.sentryAdmin, sentryadmin.NewClient(conn))
 		}
 	} else {
 		var readNodeInfo = func() *eth.NodeInfo {
@@ -243,7 +271,11 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		cfg66 := stack.Config().P2P
 		cfg66.NodeDatabase = filepath.Join(stack.Config().Dirs.Nodes, "eth66")
 		server66 := sentry.NewGrpcServer(backend.sentryCtx, d66, readNodeInfo, &cfg66, eth.ETH66)
+		if config.SnapServe {
+			server66.SatelliteProtocols = append(server66.SatelliteProtocols, snapproto.NewHandler(backend.sentryCtx, chainKv, tmpdir, config.SnapServeRate).MakeProtocol())
+		}
 		backend.sentryServers = append(backend.sentryServers, server66)
+		backend.sentryAdmin = sentryadmin.Multi{server66}
 		sentries = []direct.SentryClient{direct.NewSentryClientDirect(eth.ETH66, server66)}
 
 		go func() {
@@ -290,10 +322,22 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			return err
 		}
 
+		stored, err := prune.Get(tx)
+		if err != nil {
+			return err
+		}
 		config.Prune, err = prune.EnsureNotChanged(tx, config.Prune)
 		if err != nil {
 			return err
 		}
+		if config.Prune.RegenerateReceipts && !stored.RegenerateReceipts {
+			// The receipts stored before switching to the regenerate mode are dropped, once: only with an explicit
+			// --receipts.mode=regenerate, the mode of the database is kept without it
+			log.Warn("Dropping the stored receipts", "mode", "--receipts.mode=regenerate")
+			if err = rawdb.DropReceipts(tx); err != nil {
+				return err
+			}
+		}
 		isCorrectSync, useSnapshots, err := snap.EnsureNotChanged(tx, config.Snapshot)
 		if err != nil {
 			return err
@@ -332,6 +376,10 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	if err != nil {
 		return nil, err
 	}
+	if clq, ok := backend.engine.(*clique.Clique); ok && config.Miner.Dev && config.Miner.Enabled {
+		backend.devChain = NewDevChain(chainKv, backend.sentriesClient.Hd, chainConfig.Clique.Period)
+		clq.SetDevMode(backend.devChain)
+	}
 
 	var miningRPC txpool_proto.MiningServer
 	if config.DeprecatedTxPool.Disable {
@@ -343,6 +391,12 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		stateDiffClient := direct.NewStateDiffClientDirect(kvRPC)
 		backend.newTxs2 = make(chan types2.Hashes, 1024)
 		//defer close(newTxs)
+		if config.DeprecatedTxPool.NoJournal && config.TxPool.DBDir != "" {
+			log.Info("Dropping the transactions stored by the pool", "dir", config.TxPool.DBDir)
+			if err := os.RemoveAll(config.TxPool.DBDir); err != nil {
+				return nil, err
+			}
+		}
 		backend.txPool2DB, backend.txPool2, backend.txPool2Fetch, backend.txPool2Send, backend.txPool2GrpcServer, err = txpooluitl.AllComponents(
 			ctx, config.TxPool, kvcache.NewDummy(), backend.newTxs2, backend.chainDB, backend.sentriesClient.Sentries(), stateDiffClient,
 		)
@@ -351,6 +405,42 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		}
 	}
 
+	if config.LightServe {
+		var lightTxPool txpool_proto.TxpoolServer
+		if !config.DeprecatedTxPool.Disable {
+			lightTxPool = backend.txPool2GrpcServer
+		}
+		lightCfg := les.Config{
+			NetworkID:   config.NetworkID,
+			ChainConfig: chainConfig,
+			Genesis:     backend.genesisHash,
+			MaxPeers:    config.LightMaxPeers,
+			Capacity:    config.LightCapacity,
+			TmpDir:      tmpdir,
+		}
+		for _, srv := range backend.sentryServers {
+			lightServer := les.NewHandler(backend.sentryCtx, lightCfg, chainKv, blockReader, lightTxPool, backend.notifications.Events)
+			srv.SatelliteProtocols = append(srv.SatelliteProtocols, lightServer.MakeProtocol())
+		}
+	}
+
+	// the empty step messages of the AuRa authorities are gossiped besides the eth protocol
+	var auraEngine *aura.AuRa
+	if a, ok := backend.engine.(*aura.AuRa); ok {
+		auraEngine = a
+	} else if cl, ok := backend.engine.(*serenity.Serenity); ok {
+		if a, ok := cl.InnerEngine().(*aura.AuRa); ok {
+			auraEngine = a
+		}
+	}
+	if auraEngine != nil && len(backend.sentryServers) > 0 {
+		emptySteps := auraproto.NewHandler(auraEngine)
+		for _, srv := range backend.sentryServers {
+			srv.SatelliteProtocols = append(srv.SatelliteProtocols, emptySteps.MakeProtocol())
+		}
+		auraEngine.SetEmptyStepBroadcaster(emptySteps.Broadcast)
+	}
+
 	backend.notifyMiningAboutNewTxs = make(chan struct{}, 1)
 	backend.miningSealingQuit = make(chan struct{})
 	backend.pendingBlocks = make(chan *types.Block, 1)
@@ -364,7 +454,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	mining := stagedsync.New(
 		stagedsync.MiningStages(backend.sentryCtx,
 			stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miner, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, nil, tmpdir),
-			stagedsync.StageMiningExecCfg(backend.chainDB, miner, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, nil),
+			stagedsync.StageMiningExecCfg(backend.chainDB, miner, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, nil, stateBackend),
 			stagedsync.StageHashStateCfg(backend.chainDB, tmpdir),
 			stagedsync.StageTrieCfg(backend.chainDB, false, true, true, tmpdir, blockReader, nil),
 			stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miner, backend.miningSealingQuit),
@@ -376,13 +466,13 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	}
 
 	// proof-of-stake mining
-	assembleBlockPOS := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.Block, error) {
+	assembleBlockPOS := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.BlockWithReceipts, error) {
 		miningStatePos := stagedsync.NewProposingState(&config.Miner)
 		miningStatePos.MiningConfig.Etherbase = param.SuggestedFeeRecipient
 		proposingSync := stagedsync.New(
 			stagedsync.MiningStages(backend.sentryCtx,
 				stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miningStatePos, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, param, tmpdir),
-				stagedsync.StageMiningExecCfg(backend.chainDB, miningStatePos, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, interrupt),
+				stagedsync.StageMiningExecCfg(backend.chainDB, miningStatePos, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, interrupt, stateBackend),
 				stagedsync.StageHashStateCfg(backend.chainDB, tmpdir),
 				stagedsync.StageTrieCfg(backend.chainDB, false, true, true, tmpdir, blockReader, nil),
 				stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miningStatePos, backend.miningSealingQuit),
@@ -396,7 +486,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	}
 
 	inMemoryExecution := func(batch kv.RwTx, header *types.Header, body *types.RawBody, unwindPoint uint64, headersChain []*types.Header, bodiesChain []*types.RawBody) error {
-		stateSync, err := stages2.NewInMemoryExecution(backend.sentryCtx, backend.log, backend.chainDB, *config, backend.sentriesClient, tmpdir, backend.notifications, allSnapshots)
+		stateSync, err := stages2.NewInMemoryExecution(backend.sentryCtx, backend.log, backend.chainDB, *config, backend.sentriesClient, tmpdir, backend.notifications, allSnapshots, stateBackend)
 		if err != nil {
 			return err
 		}
@@ -413,6 +503,18 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		blockReader, chainConfig, backend.sentriesClient.Hd.BeaconRequestList, backend.sentriesClient.Hd.PayloadStatusCh,
 		assembleBlockPOS, config.Miner.EnabledPOS)
 	miningRPC = privateapi.NewMiningServer(ctx, backend, ethashApi)
+	if config.LightClientBeaconAPI != "" {
+		beaconConfig := lightclient.BeaconConfigByChainName(chainConfig.ChainName)
+		if beaconConfig == nil {
+			return nil, fmt.Errorf("light client: unknown beacon chain of %s", chainConfig.ChainName)
+		}
+		backend.lightClient = lightclient.New(beaconConfig, config.LightClientBeaconAPI, config.LightClientCheckpoint,
+			config.LightClientCheckpointSyncURL, filepath.Join(config.Dirs.DataDir, "lightclient"), ethBackendRPC)
+	}
+	var firehoseRPC *privateapi.FirehoseServer
+	if config.Firehose {
+		firehoseRPC = privateapi.NewFirehoseServer(ctx, backend.chainDB, backend.notifications.Events, blockReader)
+	}
 
 	if stack.Config().PrivateApiAddr != "" {
 		var creds credentials.TransportCredentials
@@ -427,6 +529,8 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			ethBackendRPC,
 			backend.txPool2GrpcServer,
 			miningRPC,
+			firehoseRPC,
+			backend.sentryAdmin,
 			stack.Config().PrivateApiAddr,
 			stack.Config().PrivateApiRateLimit,
 			creds,
@@ -470,12 +574,16 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 				default:
 				}
 			})
+		go txpoolmetrics.Loop(backend.sentryCtx, backend.txPool2GrpcServer, txpoolmetrics.DefaultInterval)
 	}
 	go func() {
 		defer debug.LogPanic()
 		for {
 			select {
 			case b := <-backend.minedBlocks:
+				if backend.devChain != nil {
+					backend.devChain.mined(b.Header())
+				}
 				//p2p
 				//backend.sentriesClient.BroadcastNewBlock(context.Background(), b, b.Difficulty())
 				//rpcdaemon
@@ -508,7 +616,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		headCh = make(chan *types.Block, 1)
 	}
 
-	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.log, backend.chainDB, stack.Config().P2P, *config, backend.sentriesClient, tmpdir, backend.notifications, backend.downloaderClient, allSnapshots, headCh, inMemoryExecution)
+	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.log, backend.chainDB, stack.Config().P2P, *config, backend.sentriesClient, tmpdir, backend.notifications, backend.downloaderClient, allSnapshots, headCh, inMemoryExecution, stateBackend)
 	if err != nil {
 		return nil, err
 	}
@@ -547,10 +655,11 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	httpRpcCfg := stack.Config().Http
 	if httpRpcCfg.Enabled {
 		ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, stateCache, ff, err := cli.EmbeddedServices(
-			ctx, chainKv, httpRpcCfg.StateCache, blockReader,
+			ctx, chainKv, httpRpcCfg.StateCache, httpRpcCfg.StateCacheWarmupBlocks, blockReader,
 			ethBackendRPC,
 			backend.txPool2GrpcServer,
 			miningRPC,
+			backend.sentryAdmin,
 		)
 		if err != nil {
 			return nil, err
@@ -560,7 +669,15 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		if casted, ok := backend.engine.(*bor.Bor); ok {
 			borDb = casted.DB
 		}
-		apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, ff, stateCache, blockReader, httpRpcCfg)
+		var devChain commands.DevChain
+		if backend.devChain != nil {
+			devChain = backend.devChain
+		}
+		var stateOverlay rpchelper.StateOverlay
+		if backend.fork != nil {
+			stateOverlay = backend.fork
+		}
+		apiList := commands.APIList(chainKv, borDb, backend.engine, devChain, stateOverlay, ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, ff, stateCache, blockReader, httpRpcCfg)
 		go func() {
 			if err := cli.StartRpcServer(ctx, httpRpcCfg, apiList); err != nil {
 				log.Error(err.Error())
@@ -699,6 +816,25 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 		})
 	}
 
+	var auraEngine *aura.AuRa
+	if a, ok := s.engine.(*aura.AuRa); ok {
+		auraEngine = a
+	} else if cl, ok := s.engine.(*serenity.Serenity); ok {
+		if a, ok := cl.InnerEngine().(*aura.AuRa); ok {
+			auraEngine = a
+		}
+	}
+	if auraEngine != nil {
+		if cfg.SigKey == nil {
+			log.Error("Etherbase account unavailable locally", "err", err)
+			return fmt.Errorf("signer missing: %w", err)
+		}
+
+		auraEngine.Authorize(eb, func(_ common.Address, mimeType string, message []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(message), cfg.SigKey)
+		})
+	}
+
 	go func() {
 		defer debug.LogPanic()
 		defer close(s.waitForMiningStop)
@@ -710,16 +846,24 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 		var hasWork bool
 		errc := make(chan error, 1)
 
+		// on the dev chain evm_mine requests the blocks, with --dev.manual only the requested blocks are mined
+		var mineRequests <-chan struct{}
+		if s.devChain != nil {
+			mineRequests = s.devChain.MineRequests()
+		}
+		manual := s.devChain != nil && cfg.DevManual
+
 		for {
 			mineEvery.Reset(3 * time.Second)
 			select {
 			case <-s.notifyMiningAboutNewTxs:
-				hasWork = true
+				hasWork = hasWork || !manual
 			case <-mineEvery.C:
+				hasWork = hasWork || !manual
+			case <-mineRequests:
 				hasWork = true
 			case err := <-errc:
 				works = false
-				hasWork = false
 				if errors.Is(err, libcommon.ErrStopped) {
 					return
 				}
@@ -732,6 +876,7 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 
 			if !works && hasWork {
 				works = true
+				hasWork = false
 				go func() { errc <- stages2.MiningStep(ctx, db, mining) }()
 			}
 		}
@@ -852,6 +997,9 @@ func (s *Ethereum) Start() error {
 	time.Sleep(10 * time.Millisecond) // just to reduce logs order confusion
 
 	go stages2.StageLoop(s.sentryCtx, s.chainDB, s.stagedSync, s.sentriesClient.Hd, s.notifications, s.sentriesClient.UpdateHead, s.waitForStageLoopStop, s.config.Sync.LoopThrottle)
+	if s.lightClient != nil {
+		go s.lightClient.Run(s.sentryCtx)
+	}
 
 	return nil
 }
@@ -887,6 +1035,9 @@ func (s *Ethereum) Stop() error {
 		sentryServer.Close()
 	}
 	s.chainDB.Close()
+	if s.fork != nil {
+		s.fork.Close()
+	}
 	if s.txPool2DB != nil {
 		s.txPool2DB.Close()
 	}
@@ -912,3 +1063,8 @@ func (s *Ethereum) SentryCtx() context.Context {
 func (s *Ethereum) SentryControlServer() *sentry.MultiClient {
 	return s.sentriesClient
 }
+
+// SentryServers are the internal sentries, empty if the sentries are external
+func (s *Ethereum) SentryServers() []*sentry.GrpcServer {
+	return s.sentryServers
+}
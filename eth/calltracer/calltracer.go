@@ -9,6 +9,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -19,6 +20,9 @@ type CallTracer struct {
 	froms   map[common.Address]struct{}
 	tos     map[common.Address]bool // address -> isCreated
 	hasTEVM func(contractHash common.Hash) (bool, error)
+
+	creations []rawdb.ContractCreation
+	frames    []int // the number of the creations before each of the calls in progress, to drop the reverted ones
 }
 
 func NewCallTracer(hasTEVM func(contractHash common.Hash) (bool, error)) *CallTracer {
@@ -31,6 +35,10 @@ func NewCallTracer(hasTEVM func(contractHash common.Hash) (bool, error)) *CallTr
 
 func (ct *CallTracer) CaptureStart(evm *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
 	ct.froms[from] = struct{}{}
+	ct.frames = append(ct.frames, len(ct.creations))
+	if create {
+		ct.creations = append(ct.creations, rawdb.ContractCreation{Contract: to, Creator: from, TxHash: evm.TxContext().TxHash})
+	}
 
 	created, ok := ct.tos[to]
 	if !ok {
@@ -55,6 +63,11 @@ func (ct *CallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, co
 func (ct *CallTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
 }
 func (ct *CallTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, t time.Duration, err error) {
+	mark := ct.frames[len(ct.frames)-1]
+	ct.frames = ct.frames[:len(ct.frames)-1]
+	if err != nil {
+		ct.creations = ct.creations[:mark]
+	}
 }
 func (ct *CallTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
 	ct.froms[from] = struct{}{}
@@ -67,6 +80,28 @@ func (ct *CallTracer) CaptureAccountWrite(account common.Address) error {
 	return nil
 }
 
+// TxTracer returns a tracer for a transaction executed in parallel, see core.ParallelTracer
+func (ct *CallTracer) TxTracer() vm.Tracer {
+	return NewCallTracer(ct.hasTEVM)
+}
+
+// Merge adds the addresses traced by the tracer of a transaction
+func (ct *CallTracer) Merge(txTracer vm.Tracer) {
+	other := txTracer.(*CallTracer)
+	for addr := range other.froms {
+		ct.froms[addr] = struct{}{}
+	}
+	for addr, created := range other.tos {
+		ct.tos[addr] = ct.tos[addr] || created
+	}
+	ct.creations = append(ct.creations, other.creations...)
+}
+
+// WriteContractCreations indexes the contracts created by the block, see rawdb.WriteContractCreations
+func (ct *CallTracer) WriteContractCreations(tx kv.Putter, block *types.Block) error {
+	return rawdb.WriteContractCreations(tx, block.NumberU64(), ct.creations)
+}
+
 func (ct *CallTracer) WriteToDb(tx kv.StatelessWriteTx, block *types.Block, vmConfig vm.Config) error {
 	ct.tos[block.Coinbase()] = false
 	for _, uncle := range block.Uncles() {
@@ -0,0 +1,256 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+)
+
+// DevChain is the developer chain (--chain=dev) controlled by the evm_* rpc, in the way of Hardhat and Anvil:
+// it mines blocks on request, moves the clock of the clique engine, and takes and reverts to snapshots of the chain.
+type DevChain struct {
+	db     kv.RoDB
+	hd     *headerdownload.HeaderDownload
+	period uint64
+	mine   chan struct{} // Requests to the mining loop
+
+	opLock sync.Mutex // Serializes the operations that wait for the chain
+
+	lock      sync.Mutex // Protects the fields below, read by the engine
+	offset    time.Duration
+	next      uint64 // Timestamp of the next block, 0 if not set
+	requested bool   // Whether a block was requested by Mine and isn't mined yet
+	snapshots []devSnapshot
+	lastID    uint64
+}
+
+type devSnapshot struct {
+	id     uint64
+	number uint64
+	offset time.Duration
+	next   uint64
+}
+
+// devMineRetry is how long Mine waits for the block, on top of the period, before requesting it again
+const devMineRetry = 3 * time.Second
+
+func NewDevChain(db kv.RoDB, hd *headerdownload.HeaderDownload, period uint64) *DevChain {
+	return &DevChain{db: db, hd: hd, period: period, mine: make(chan struct{}, 1)}
+}
+
+// Now implements clique.DevMode: the timestamp set for the next block, or the wall clock moved by IncreaseTime
+func (d *DevChain) Now() time.Time {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.next != 0 {
+		return time.Unix(int64(d.next), 0)
+	}
+	return time.Now().Add(d.offset)
+}
+
+// SealEmpty implements clique.DevMode: an empty block is sealed when Mine asks for it
+func (d *DevChain) SealEmpty() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.requested
+}
+
+// MineRequests are the requests to mine a block, for the mining loop
+func (d *DevChain) MineRequests() <-chan struct{} { return d.mine }
+
+// mined is called with each block mined by the node. A block stamped with the timestamp set for it moves the clock
+// there, the following blocks are stamped from it.
+func (d *DevChain) mined(header *types.Header) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.next != 0 && header.Time == d.next {
+		d.offset = time.Until(time.Unix(int64(d.next), 0))
+		d.next = 0
+	}
+	d.requested = false
+}
+
+// Mine mines a block, stamped with the given timestamp if any, and returns once the node has executed it.
+func (d *DevChain) Mine(ctx context.Context, timestamp *uint64) error {
+	d.opLock.Lock()
+	defer d.opLock.Unlock()
+	head, err := d.head(ctx)
+	if err != nil {
+		return err
+	}
+	if timestamp != nil {
+		if err := d.setNext(head, *timestamp); err != nil {
+			return err
+		}
+	}
+	d.lock.Lock()
+	d.requested = true
+	d.lock.Unlock()
+
+	retry := time.NewTicker(time.Duration(d.period)*time.Second + devMineRetry)
+	defer retry.Stop()
+	poll := time.NewTicker(50 * time.Millisecond)
+	defer poll.Stop()
+	d.requestMining()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-retry.C:
+			d.requestMining()
+		case <-poll.C:
+			progress, err := d.progress(ctx)
+			if err != nil {
+				return err
+			}
+			if progress > head.Number.Uint64() {
+				return nil
+			}
+		}
+	}
+}
+
+func (d *DevChain) requestMining() {
+	select {
+	case d.mine <- struct{}{}:
+	default:
+	}
+}
+
+// SetNextBlockTimestamp sets the timestamp of the next block, the clock continues from there.
+func (d *DevChain) SetNextBlockTimestamp(ctx context.Context, timestamp uint64) error {
+	d.opLock.Lock()
+	defer d.opLock.Unlock()
+	head, err := d.head(ctx)
+	if err != nil {
+		return err
+	}
+	return d.setNext(head, timestamp)
+}
+
+func (d *DevChain) setNext(head *types.Header, timestamp uint64) error {
+	if timestamp <= head.Time {
+		return fmt.Errorf("timestamp %d is lower than or equal to the timestamp %d of the latest block", timestamp, head.Time)
+	}
+	if timestamp < head.Time+d.period {
+		return fmt.Errorf("timestamp %d is less than the period of %d seconds after the timestamp %d of the latest block", timestamp, d.period, head.Time)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.next = timestamp
+	return nil
+}
+
+// IncreaseTime moves the clock forward, and returns the total seconds it was moved by.
+func (d *DevChain) IncreaseTime(seconds uint64) int64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.offset += time.Duration(seconds) * time.Second
+	return int64(d.offset / time.Second)
+}
+
+// Snapshot takes a snapshot of the chain and the clock, and returns its id. The ids start at 1 and aren't reused.
+func (d *DevChain) Snapshot(ctx context.Context) (uint64, error) {
+	d.opLock.Lock()
+	defer d.opLock.Unlock()
+	head, err := d.head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.lastID++
+	d.snapshots = append(d.snapshots, devSnapshot{id: d.lastID, number: head.Number.Uint64(), offset: d.offset, next: d.next})
+	return d.lastID, nil
+}
+
+// Revert reverts the chain and the clock to the snapshot, and returns once the node has unwound the blocks mined
+// since. The snapshot and the later ones are dropped, false is returned for an unknown snapshot. The transactions
+// of the reverted blocks don't return to the txpool.
+func (d *DevChain) Revert(ctx context.Context, id uint64) (bool, error) {
+	d.opLock.Lock()
+	defer d.opLock.Unlock()
+	d.lock.Lock()
+	i := sort.Search(len(d.snapshots), func(i int) bool { return d.snapshots[i].id >= id })
+	if i == len(d.snapshots) || d.snapshots[i].id != id {
+		d.lock.Unlock()
+		return false, nil
+	}
+	snapshot := d.snapshots[i]
+	d.snapshots = d.snapshots[:i]
+	d.offset, d.next, d.requested = snapshot.offset, snapshot.next, false
+	d.lock.Unlock()
+
+	head, err := d.head(ctx)
+	if err != nil {
+		return false, err
+	}
+	if head.Number.Uint64() <= snapshot.number {
+		return true, nil
+	}
+	var reverted common.Hash
+	if err := d.db.View(ctx, func(tx kv.Tx) (err error) {
+		reverted, err = rawdb.ReadCanonicalHash(tx, snapshot.number+1)
+		return err
+	}); err != nil {
+		return false, err
+	}
+	d.hd.RequestUnwind(snapshot.number)
+
+	poll := time.NewTicker(50 * time.Millisecond)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-poll.C:
+			// the chain may have been extended already, past a new block at the first reverted height
+			var progress uint64
+			var hash common.Hash
+			if err := d.db.View(ctx, func(tx kv.Tx) (err error) {
+				if progress, err = stages.GetStageProgress(tx, stages.Finish); err != nil {
+					return err
+				}
+				hash, err = rawdb.ReadCanonicalHash(tx, snapshot.number+1)
+				return err
+			}); err != nil {
+				return false, err
+			}
+			if progress <= snapshot.number || hash != reverted {
+				return true, nil
+			}
+		}
+	}
+}
+
+// head is the latest block executed by the node
+func (d *DevChain) head(ctx context.Context) (head *types.Header, err error) {
+	err = d.db.View(ctx, func(tx kv.Tx) error {
+		progress, err := stages.GetStageProgress(tx, stages.Finish)
+		if err != nil {
+			return err
+		}
+		if head = rawdb.ReadHeaderByNumber(tx, progress); head == nil {
+			return fmt.Errorf("no header of the latest block %d", progress)
+		}
+		return nil
+	})
+	return head, err
+}
+
+func (d *DevChain) progress(ctx context.Context) (progress uint64, err error) {
+	err = d.db.View(ctx, func(tx kv.Tx) (err error) {
+		progress, err = stages.GetStageProgress(tx, stages.Finish)
+		return err
+	})
+	return progress, err
+}
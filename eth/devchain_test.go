@@ -0,0 +1,75 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDevChain(t *testing.T, headTime uint64) *DevChain {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		header := &types.Header{Number: big.NewInt(1), Time: headTime}
+		rawdb.WriteHeader(tx, header)
+		if err := rawdb.WriteCanonicalHash(tx, header.Hash(), 1); err != nil {
+			return err
+		}
+		return stages.SaveStageProgress(tx, stages.Finish, 1)
+	}))
+	return NewDevChain(db, nil, 0)
+}
+
+func TestDevChainClock(t *testing.T) {
+	ctx := context.Background()
+	headTime := uint64(time.Now().Unix())
+	d := newTestDevChain(t, headTime)
+
+	require.Error(t, d.SetNextBlockTimestamp(ctx, headTime))
+	require.NoError(t, d.SetNextBlockTimestamp(ctx, headTime+3600))
+	require.Equal(t, int64(headTime+3600), d.Now().Unix())
+
+	// the clock continues from the block stamped with the timestamp set for it
+	d.mined(&types.Header{Number: big.NewInt(2), Time: headTime + 3600})
+	require.InDelta(t, headTime+3600, d.Now().Unix(), 1)
+
+	require.InDelta(t, 3600+60, d.IncreaseTime(60), 1)
+	require.InDelta(t, headTime+3600+60, d.Now().Unix(), 1)
+}
+
+func TestDevChainSnapshots(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDevChain(t, uint64(time.Now().Unix()))
+
+	first, err := d.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), first)
+	d.IncreaseTime(60)
+	second, err := d.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), second)
+	d.IncreaseTime(60)
+
+	ok, err := d.Revert(ctx, 3)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// reverting to the first snapshot drops the second one, and the ids aren't reused
+	ok, err = d.Revert(ctx, first)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(0), d.IncreaseTime(0))
+	ok, err = d.Revert(ctx, second)
+	require.NoError(t, err)
+	require.False(t, ok)
+	third, err := d.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), third)
+}
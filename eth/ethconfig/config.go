@@ -32,6 +32,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/forkstate"
 	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
@@ -85,6 +86,9 @@ var Defaults = Config{
 	RPCGasCap:        50000000,
 	GPO:              FullNodeGPO,
 	RPCTxFeeCap:      1, // 1 ether
+	SnapServeRate:    10,
+	LightMaxPeers:    10,
+	LightCapacity:    100_000,
 
 	ImportMode: false,
 	Snapshot: Snapshot{
@@ -169,8 +173,24 @@ type Config struct {
 
 	P2PEnabled bool
 
-	Prune     prune.Mode
-	BatchSize datasize.ByteSize // Batch size for execution stage
+	SnapServe     bool    // serve the snap protocol to the peers of the internal sentry
+	SnapServeRate float64 // requests of the snap protocol served per second to a peer
+
+	LightServe    bool   // serve the les protocol to the light clients of the internal sentry
+	LightMaxPeers int    // maximum number of the light clients
+	LightCapacity uint64 // request cost units served per second to the light clients, see les.Config
+
+	Prune       prune.Mode
+	BatchSize   datasize.ByteSize // Batch size for execution stage
+	ExecWorkers int               // Number of workers executing the transactions of a block in parallel, 1 - sequentially
+	ExecProfile bool              // Profile the execution of the blocks, see profiler.Enable
+	ExecCache   bool              // Cache the state read by the sequential execution across the blocks, see shards.StateCache
+	// Promote the state changes to the hashed state during the execution ("stream" commitment mode),
+	// instead of in the HashState stage ("batch" mode)
+	StreamCommitment bool
+	StateBackend     string // Name of the storage engine of the state, see state.RegisterBackend
+
+	Fork forkstate.Config // The network the dev chain is forked from
 
 	ImportMode bool
 
@@ -220,6 +240,9 @@ type Config struct {
 	// Enable WatchTheBurn stage
 	EnabledIssuance bool
 
+	// Stream the data of executed blocks on the private api, see privateapi.FirehoseServer
+	Firehose bool
+
 	// URL to connect to Heimdall node
 	HeimdallURL string
 
@@ -228,10 +251,19 @@ type Config struct {
 	// Ethstats service
 	Ethstats string
 
-	// FORK_NEXT_VALUE (see EIP-3675) block override
-	OverrideMergeNetsplitBlock *big.Int `toml:",omitempty"`
+	// OverrideForkBlocks are the overridden blocks of the forks, by the names of params.OverridableForks
+	OverrideForkBlocks map[string]*big.Int `toml:",omitempty"`
 
 	OverrideTerminalTotalDifficulty *big.Int `toml:",omitempty"`
+
+	// LightClientBeaconAPI is the URL of the beacon API followed by the embedded light client, which drives the fork
+	// choice instead of a consensus layer client. Disabled if empty.
+	LightClientBeaconAPI string
+	// LightClientCheckpoint is the trusted beacon block root the light client starts from
+	LightClientCheckpoint common.Hash
+	// LightClientCheckpointSyncURL is the beacon API of the checkpoint sync provider whose finalized block the light
+	// client starts from, if there is no trusted checkpoint
+	LightClientCheckpointSyncURL string
 }
 
 type Sync struct {
@@ -241,6 +273,9 @@ type Sync struct {
 
 	BlockDownloaderWindow      int
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+	// BackfillFromTip trusts the head given by the consensus layer, and downloads the headers backwards from it
+	// instead of forwards from the local head, also before the terminal total difficulty is reached
+	BackfillFromTip bool
 }
 
 // Chains where snapshots are enabled by default
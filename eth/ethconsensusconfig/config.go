@@ -21,6 +21,15 @@ import (
 func CreateConsensusEngine(chainConfig *params.ChainConfig, logger log.Logger, config interface{}, notify []string, noverify bool, HeimdallURL string, WithoutHeimdall bool, datadir string, snapshots *snapshotsync.RoSnapshots) consensus.Engine {
 	var eng consensus.Engine
 
+	if factory, ok := consensus.Lookup(string(chainConfig.Consensus)); ok {
+		var err error
+		if eng, err = factory(chainConfig, datadir); err != nil {
+			panic(err)
+		}
+		log.Info("Using registered consensus engine", "name", chainConfig.Consensus)
+		config = nil // skip the built-in engines
+	}
+
 	switch consensusCfg := config.(type) {
 	case *ethash.Config:
 		switch consensusCfg.PowMode {
@@ -21,10 +21,8 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"sort"
 	"sync/atomic"
 
-	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/misc"
@@ -49,7 +47,8 @@ type blockFees struct {
 	// set by the caller
 	blockNumber uint64
 	header      *types.Header
-	block       *types.Block // only set if reward percentiles are requested
+	tips        types.TipSummary // only set if reward percentiles are requested, or
+	block       *types.Block     // if the backend doesn't have the tip summary of the block
 	receipts    types.Receipts
 	// filled by processBlock
 	reward               []*big.Int
@@ -58,26 +57,8 @@ type blockFees struct {
 	err                  error
 }
 
-// txGasAndReward is sorted in ascending order based on reward
-type (
-	txGasAndReward struct {
-		gasUsed uint64
-		reward  *big.Int
-	}
-	sortGasAndReward []txGasAndReward
-)
-
-func (s sortGasAndReward) Len() int { return len(s) }
-func (s sortGasAndReward) Swap(i, j int) {
-	s[i], s[j] = s[j], s[i]
-}
-func (s sortGasAndReward) Less(i, j int) bool {
-	return s[i].reward.Cmp(s[j].reward) < 0
-}
-
 // processBlock takes a blockFees structure with the blockNumber, the header and optionally
-// the block field filled in, retrieves the block from the backend if not present yet and
-// fills in the rest of the fields.
+// the tip summary or the block and its receipts filled in, and fills in the rest of the fields.
 func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 	chainconfig := oracle.backend.ChainConfig()
 	if bf.baseFee = bf.header.BaseFee; bf.baseFee == nil {
@@ -93,42 +74,16 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		// rewards were not requested, return null
 		return
 	}
-	if bf.block == nil || (bf.receipts == nil && len(bf.block.Transactions()) != 0) {
-		log.Error("Block or receipts are missing while reward percentiles are requested")
-		return
-	}
-
-	bf.reward = make([]*big.Int, len(percentiles))
-	if len(bf.block.Transactions()) == 0 {
-		// return an all zero row if there are no transactions to gather data from
-		for i := range bf.reward {
-			bf.reward[i] = new(big.Int)
+	if bf.tips == nil {
+		if bf.block == nil || (bf.receipts == nil && len(bf.block.Transactions()) != 0) {
+			log.Error("Block or receipts are missing while reward percentiles are requested")
+			return
 		}
-		return
-	}
-
-	sorter := make(sortGasAndReward, len(bf.block.Transactions()))
-	baseFee := uint256.NewInt(0)
-	if bf.block.BaseFee() != nil {
-		baseFee.SetFromBig(bf.block.BaseFee())
-	}
-	for i, tx := range bf.block.Transactions() {
-		reward := tx.GetEffectiveGasTip(baseFee)
-		sorter[i] = txGasAndReward{gasUsed: bf.receipts[i].GasUsed, reward: reward.ToBig()}
-	}
-	sort.Sort(sorter)
-
-	var txIndex int
-	sumGasUsed := sorter[0].gasUsed
-
-	for i, p := range percentiles {
-		thresholdGasUsed := uint64(float64(bf.block.GasUsed()) * p / 100)
-		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.block.Transactions())-1 {
-			txIndex++
-			sumGasUsed += sorter[txIndex].gasUsed
+		if bf.tips, bf.err = types.NewTipSummary(bf.block.Transactions(), bf.receipts, bf.block.BaseFee()); bf.err != nil {
+			return
 		}
-		bf.reward[i] = sorter[txIndex].reward
 	}
+	bf.reward = bf.tips.Percentiles(bf.header.GasUsed, percentiles)
 }
 
 // resolveBlockRange resolves the specified block range to absolute block numbers while also
@@ -255,13 +210,15 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 		if pendingBlock != nil && blockNumber >= pendingBlock.NumberU64() {
 			fees.block, fees.receipts = pendingBlock, pendingReceipts
 		} else {
-			if len(rewardPercentiles) != 0 {
-				fees.block, fees.err = oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNumber))
-				if fees.block != nil && fees.err == nil {
-					fees.receipts, fees.err = oracle.backend.GetReceipts(ctx, fees.block.Hash())
+			fees.header, fees.err = oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
+			if len(rewardPercentiles) != 0 && fees.header != nil && fees.err == nil {
+				fees.tips, fees.err = oracle.backend.TipSummary(ctx, blockNumber)
+				if fees.tips == nil && fees.err == nil {
+					fees.block, fees.err = oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNumber))
+					if fees.block != nil && fees.err == nil {
+						fees.receipts, fees.err = oracle.backend.GetReceipts(ctx, fees.block.Hash())
+					}
 				}
-			} else {
-				fees.header, fees.err = oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
 			}
 		}
 		if fees.block != nil {
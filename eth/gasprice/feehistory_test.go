@@ -21,8 +21,10 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFeeHistory(t *testing.T) {
@@ -57,7 +59,7 @@ func TestFeeHistory(t *testing.T) {
 			MaxBlockHistory:  c.maxBlock,
 		}
 		backend := newTestBackend(t) //, big.NewInt(16), c.pending)
-		oracle := gasprice.NewOracle(backend, config)
+		oracle := gasprice.NewOracle(backend, config, nil)
 
 		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
 
@@ -87,3 +89,39 @@ func TestFeeHistory(t *testing.T) {
 		}
 	}
 }
+
+// noTipSummaries is a backend without the tip summaries, reading the blocks and their receipts instead.
+type noTipSummaries struct {
+	*testBackend
+}
+
+func (b noTipSummaries) TipSummary(ctx context.Context, number uint64) (types.TipSummary, error) {
+	return nil, nil
+}
+
+func TestFeeHistoryTipSummaries(t *testing.T) {
+	backend := newTestBackend(t)
+	for n := uint64(1); n <= 32; n++ {
+		tips, err := backend.TipSummary(context.Background(), n)
+		require.NoError(t, err)
+		require.Len(t, tips, 1, "block %d", n)
+	}
+
+	percentiles := []float64{0, 12.5, 50, 99.9, 100}
+	first, reward, baseFee, ratio, err := gasprice.NewOracle(backend, gasprice.Config{}, nil).FeeHistory(context.Background(), 1024, rpc.LatestBlockNumber, percentiles)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), first.Uint64())
+	require.Len(t, reward, 33)
+
+	first2, reward2, baseFee2, ratio2, err := gasprice.NewOracle(noTipSummaries{backend}, gasprice.Config{}, nil).FeeHistory(context.Background(), 1024, rpc.LatestBlockNumber, percentiles)
+	require.NoError(t, err)
+	require.Equal(t, first.Uint64(), first2.Uint64())
+	require.Equal(t, baseFee, baseFee2)
+	require.Equal(t, ratio, ratio2)
+	require.Equal(t, len(reward), len(reward2))
+	for i := range reward {
+		for j := range percentiles {
+			require.Equal(t, reward2[i][j].String(), reward[i][j].String(), "block %d, percentile %f", i, percentiles[j])
+		}
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"context"
 	"errors"
 	"math/big"
-	"sync"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon/common"
@@ -29,6 +28,7 @@ import (
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/exp/slices"
 )
 
 const sampleNumber = 3 // Number of transactions sampled in a block
@@ -39,11 +39,13 @@ var (
 )
 
 type Config struct {
+	Strategy         string // PercentileStrategy if empty
 	Blocks           int
 	Percentile       int
 	MaxHeaderHistory int
 	MaxBlockHistory  int
 	Default          *big.Int `toml:",omitempty"`
+	MinPrice         *big.Int `toml:",omitempty"` // the floor of the suggested tips, none if nil
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
 }
@@ -55,18 +57,22 @@ type OracleBackend interface {
 	ChainConfig() *params.ChainConfig
 
 	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
+	// TipSummary returns the tip summary of the canonical block, nil if the backend doesn't keep it: the block and
+	// its receipts are read instead.
+	TipSummary(ctx context.Context, number uint64) (types.TipSummary, error)
 	PendingBlockAndReceipts() (*types.Block, types.Receipts)
 }
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend     OracleBackend
-	lastHead    common.Hash
-	lastPrice   *big.Int
-	maxPrice    *big.Int
-	ignorePrice *big.Int
-	cacheLock   sync.RWMutex
+	backend      OracleBackend
+	cache        Cache
+	strategy     string
+	defaultPrice *big.Int
+	minPrice     *big.Int
+	maxPrice     *big.Int
+	ignorePrice  *big.Int
 
 	checkBlocks                       int
 	percentile                        int
@@ -74,8 +80,16 @@ type Oracle struct {
 }
 
 // NewOracle returns a new gasprice oracle which can recommend suitable
-// gasprice for newly created transaction.
-func NewOracle(backend OracleBackend, params Config) *Oracle {
+// gasprice for newly created transaction. The cache keeps the last suggestion
+// across the oracles of the backend, the oracle has its own one if it's nil.
+func NewOracle(backend OracleBackend, params Config, cache Cache) *Oracle {
+	strategy := params.Strategy
+	if strategy == "" {
+		strategy = PercentileStrategy
+	} else if !slices.Contains(Strategies, strategy) {
+		strategy = PercentileStrategy
+		log.Warn("Sanitizing invalid gasprice oracle strategy", "provided", params.Strategy, "updated", strategy)
+	}
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -95,14 +109,28 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxPrice = DefaultMaxPrice
 		log.Warn("Sanitizing invalid gasprice oracle price cap", "provided", params.MaxPrice, "updated", maxPrice)
 	}
+	minPrice := params.MinPrice
+	if minPrice != nil && minPrice.Sign() <= 0 {
+		minPrice = nil
+	}
+	if minPrice != nil && minPrice.Cmp(maxPrice) > 0 {
+		minPrice = maxPrice
+		log.Warn("Sanitizing invalid gasprice oracle price floor", "provided", params.MinPrice, "updated", minPrice)
+	}
 	ignorePrice := params.IgnorePrice
 	if ignorePrice == nil || ignorePrice.Int64() < 0 {
 		ignorePrice = DefaultIgnorePrice
 		log.Warn("Sanitizing invalid gasprice oracle ignore price", "provided", params.IgnorePrice, "updated", ignorePrice)
 	}
+	if cache == nil {
+		cache = NewCache()
+	}
 	return &Oracle{
 		backend:          backend,
-		lastPrice:        params.Default,
+		cache:            cache,
+		strategy:         strategy,
+		defaultPrice:     params.Default,
+		minPrice:         minPrice,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
 		checkBlocks:      blocks,
@@ -119,27 +147,48 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 func (gpo *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	if err != nil {
-		return gpo.lastPrice, err
+		return gpo.lastPrice(), err
 	}
 	if head == nil {
-		return gpo.lastPrice, nil
+		return gpo.lastPrice(), nil
+	}
+	price, err := gpo.suggestFromBlocks(ctx, head)
+	if err != nil {
+		return price, err
+	}
+	if gpo.strategy == TxPoolStrategy {
+		if price, err = gpo.suggestFromTxPool(ctx, head, price); err != nil {
+			return price, err
+		}
+	}
+	if gpo.minPrice != nil && price.Cmp(gpo.minPrice) < 0 {
+		price = new(big.Int).Set(gpo.minPrice)
+	}
+	if price.Cmp(gpo.maxPrice) > 0 {
+		price = new(big.Int).Set(gpo.maxPrice)
 	}
+	return price, nil
+}
+
+// lastPrice returns the last suggestion from the blocks, or the default price.
+func (gpo *Oracle) lastPrice() *big.Int {
+	if _, price := gpo.cache.GetLatest(); price != nil {
+		return price
+	}
+	return gpo.defaultPrice
+}
+
+// suggestFromBlocks returns the percentile of the tips paid by the transactions of the last blocks up to the head.
+func (gpo *Oracle) suggestFromBlocks(ctx context.Context, head *types.Header) (*big.Int, error) {
 	headHash := head.Hash()
 
 	// If the latest gasprice is still available, return it.
-	gpo.cacheLock.RLock()
-	lastHead, lastPrice := gpo.lastHead, gpo.lastPrice
-	gpo.cacheLock.RUnlock()
-	if headHash == lastHead {
+	lastHead, lastPrice := gpo.cache.GetLatest()
+	if headHash == lastHead && lastPrice != nil {
 		return lastPrice, nil
 	}
-
-	// Try checking the cache again, maybe the last fetch fetched what we need
-	gpo.cacheLock.RLock()
-	lastHead, lastPrice = gpo.lastHead, gpo.lastPrice
-	gpo.cacheLock.RUnlock()
-	if headHash == lastHead {
-		return lastPrice, nil
+	if lastPrice == nil {
+		lastPrice = gpo.defaultPrice
 	}
 	number := head.Number.Uint64()
 	txPrices := make(sortingHeap, 0, sampleNumber*gpo.checkBlocks)
@@ -163,13 +212,7 @@ func (gpo *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		// Don't need to pop it, just take from the top of the heap
 		price = txPrices[0].ToBig()
 	}
-	if price.Cmp(gpo.maxPrice) > 0 {
-		price = new(big.Int).Set(gpo.maxPrice)
-	}
-	gpo.cacheLock.Lock()
-	gpo.lastHead = headHash
-	gpo.lastPrice = price
-	gpo.cacheLock.Unlock()
+	gpo.cache.SetLatest(headHash, price)
 	return price, nil
 }
 
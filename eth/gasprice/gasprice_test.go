@@ -49,6 +49,15 @@ func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.
 	return rawdb.ReadReceiptsByHash(tx, hash)
 }
 
+func (b *testBackend) TipSummary(ctx context.Context, number uint64) (types.TipSummary, error) {
+	tx, err := b.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return rawdb.ReadTipSummary(tx, number)
+}
+
 func (b *testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return nil, nil
 	//if b.pending {
@@ -144,7 +153,7 @@ func TestSuggestPrice(t *testing.T) {
 		Default:    big.NewInt(params.GWei),
 	}
 	backend := newTestBackend(t)
-	oracle := gasprice.NewOracle(backend, config)
+	oracle := gasprice.NewOracle(backend, config, nil)
 
 	// The gas price sampled is: 32G, 31G, 30G, 29G, 28G, 27G
 	got, err := oracle.SuggestTipCap(context.Background())
@@ -156,3 +165,74 @@ func TestSuggestPrice(t *testing.T) {
 		t.Fatalf("Gas price mismatch, want %d, got %d", expect, got)
 	}
 }
+
+// txPoolBackend is a backend with a txpool holding pending transactions paying the given tip.
+type txPoolBackend struct {
+	*testBackend
+	tip uint64
+}
+
+func (b txPoolBackend) PendingTransactions(ctx context.Context) ([]types.Transaction, error) {
+	feeCap := uint256.NewInt(1000 * params.GWei)
+	return []types.Transaction{
+		types.NewEIP1559Transaction(*uint256.NewInt(1), 0, common.Address{}, uint256.NewInt(0), 21000, nil, uint256.NewInt(b.tip), feeCap, nil),
+		types.NewEIP1559Transaction(*uint256.NewInt(1), 1, common.Address{}, uint256.NewInt(0), 21000, nil, uint256.NewInt(b.tip), feeCap, nil),
+	}, nil
+}
+
+func TestSuggestPriceStrategies(t *testing.T) {
+	backend := newTestBackend(t)
+	var cases = []struct {
+		strategy           string
+		tip                uint64 // of the pending transactions
+		minPrice, maxPrice int64
+		expect             int64
+	}{
+		{gasprice.PercentileStrategy, 100 * params.GWei, 0, 0, 30 * params.GWei},
+		{gasprice.PercentileStrategy, 0, 40 * params.GWei, 0, 40 * params.GWei},
+		{gasprice.PercentileStrategy, 0, 0, 20 * params.GWei, 20 * params.GWei},
+		{gasprice.TxPoolStrategy, 100 * params.GWei, 0, 0, 100 * params.GWei},
+		{gasprice.TxPoolStrategy, 100 * params.GWei, 0, 50 * params.GWei, 50 * params.GWei},
+		{gasprice.TxPoolStrategy, params.GWei, 0, 0, 30 * params.GWei},
+	}
+	for i, c := range cases {
+		config := gasprice.Config{
+			Strategy:   c.strategy,
+			Blocks:     2,
+			Percentile: 60,
+			Default:    big.NewInt(params.GWei),
+		}
+		if c.minPrice != 0 {
+			config.MinPrice = big.NewInt(c.minPrice)
+		}
+		if c.maxPrice != 0 {
+			config.MaxPrice = big.NewInt(c.maxPrice)
+		}
+		got, err := gasprice.NewOracle(txPoolBackend{backend, c.tip}, config, nil).SuggestTipCap(context.Background())
+		if err != nil {
+			t.Fatalf("Test case %d: failed to retrieve recommended gas price: %v", i, err)
+		}
+		if got.Cmp(big.NewInt(c.expect)) != 0 {
+			t.Fatalf("Test case %d: gas price mismatch, want %d, got %d", i, c.expect, got)
+		}
+	}
+
+	// the oracles sharing a cache don't sample the blocks again for the same head
+	cache := gasprice.NewCache()
+	config := gasprice.Config{Blocks: 2, Percentile: 60, Default: big.NewInt(params.GWei)}
+	if _, err := gasprice.NewOracle(backend, config, cache).SuggestTipCap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	head, price := cache.GetLatest()
+	if head != backend.CurrentHeader().Hash() || price.Cmp(big.NewInt(30*params.GWei)) != 0 {
+		t.Fatalf("Cache mismatch, got %x %d", head, price)
+	}
+	cache.SetLatest(head, big.NewInt(7))
+	got, err := gasprice.NewOracle(backend, config, cache).SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Int64() != 7 {
+		t.Fatalf("Gas price mismatch, want the cached price, got %d", got)
+	}
+}
@@ -0,0 +1,94 @@
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core/types"
+	"golang.org/x/exp/slices"
+)
+
+// The strategies of the oracle, see Config.Strategy. The tips they suggest are bound by the MinPrice and the
+// MaxPrice of the config.
+const (
+	// PercentileStrategy suggests the percentile of the tips paid by the transactions of the last blocks.
+	PercentileStrategy = "percentile"
+	// TxPoolStrategy suggests the higher of the PercentileStrategy and of the percentile of the tips the
+	// transactions pending in the txpool would pay in the next block, for the pools filling up faster than
+	// the blocks reflect.
+	TxPoolStrategy = "txpool"
+)
+
+// Strategies lists the strategies of the oracle.
+var Strategies = []string{PercentileStrategy, TxPoolStrategy}
+
+// TxPoolBackend is implemented by the oracle backends having a txpool, for the TxPoolStrategy. The oracle falls
+// back to the PercentileStrategy with the other backends.
+type TxPoolBackend interface {
+	PendingTransactions(ctx context.Context) ([]types.Transaction, error)
+}
+
+// Cache keeps the last tip suggested from the blocks, and the head it was suggested at.
+type Cache interface {
+	GetLatest() (common.Hash, *big.Int)
+	SetLatest(hash common.Hash, price *big.Int)
+}
+
+type priceCache struct {
+	lock  sync.RWMutex
+	head  common.Hash
+	price *big.Int
+}
+
+// NewCache returns a cache to share between the oracles created for each request of a backend.
+func NewCache() Cache {
+	return &priceCache{}
+}
+
+func (c *priceCache) GetLatest() (common.Hash, *big.Int) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.head, c.price
+}
+
+func (c *priceCache) SetLatest(hash common.Hash, price *big.Int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.head, c.price = hash, price
+}
+
+// suggestFromTxPool returns the percentile of the tips of the pending transactions at the base fee of the block
+// after the head, if it is higher than the price.
+func (gpo *Oracle) suggestFromTxPool(ctx context.Context, head *types.Header, price *big.Int) (*big.Int, error) {
+	pool, ok := gpo.backend.(TxPoolBackend)
+	if !ok {
+		return price, nil
+	}
+	txs, err := pool.PendingTransactions(ctx)
+	if err != nil {
+		return price, err
+	}
+	var baseFee *uint256.Int
+	if cc := gpo.backend.ChainConfig(); cc.IsLondon(head.Number.Uint64() + 1) {
+		baseFee, _ = uint256.FromBig(misc.CalcBaseFee(cc, head))
+	}
+	ignoreUnder, _ := uint256.FromBig(gpo.ignorePrice)
+	tips := make([]*uint256.Int, 0, len(txs))
+	for _, tx := range txs {
+		if tip := tx.GetEffectiveGasTip(baseFee); !tip.Lt(ignoreUnder) {
+			tips = append(tips, tip)
+		}
+	}
+	if len(tips) == 0 {
+		return price, nil
+	}
+	slices.SortFunc(tips, func(a, b *uint256.Int) bool { return a.Lt(b) })
+	if poolPrice := tips[(len(tips)-1)*gpo.percentile/100].ToBig(); price == nil || poolPrice.Cmp(price) > 0 {
+		return poolPrice, nil
+	}
+	return price, nil
+}
@@ -0,0 +1,268 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// The execution profiler records the gas and the wall time of the opcodes, of the contracts and of the transactions
+// executed by the execution stage, for the performance analysis of the chain workload. It's opt-in: the time of every
+// step of the EVM is measured. The aggregates are served by debug_getExecutionProfile, and exported as metrics: the
+// histograms of the transactions and of the opcodes (not of the contracts, which are too many).
+
+var (
+	txTime = metrics.GetOrCreateHistogram("evm_tx_seconds")
+	txGas  = metrics.GetOrCreateHistogram("evm_tx_gas")
+
+	opcodeTime [256]*metrics.Histogram
+	opcodeGas  [256]*metrics.Histogram
+)
+
+func init() {
+	for i := range opcodeTime {
+		opcodeTime[i] = metrics.GetOrCreateHistogram(fmt.Sprintf(`evm_opcode_seconds{op="%s"}`, vm.OpCode(i)))
+		opcodeGas[i] = metrics.GetOrCreateHistogram(fmt.Sprintf(`evm_opcode_gas{op="%s"}`, vm.OpCode(i)))
+	}
+}
+
+var (
+	activeLock sync.RWMutex
+	active     *Profiler
+)
+
+// Enable starts profiling the execution stage, and returns the profiler of the process.
+func Enable() *Profiler {
+	activeLock.Lock()
+	defer activeLock.Unlock()
+	if active == nil {
+		active = New()
+	}
+	return active
+}
+
+// Active returns the profiler of the process, nil if the profiling isn't enabled.
+func Active() *Profiler {
+	activeLock.RLock()
+	defer activeLock.RUnlock()
+	return active
+}
+
+type stats struct {
+	count uint64
+	gas   uint64
+	time  time.Duration
+}
+
+func (s *stats) add(o *stats) {
+	s.count += o.count
+	s.gas += o.gas
+	s.time += o.time
+}
+
+// Profiler aggregates the profiles of the transactions traced by its tracers.
+type Profiler struct {
+	lock      sync.Mutex
+	since     time.Time
+	txs       stats
+	opcodes   [256]stats
+	contracts map[common.Address]*stats
+}
+
+func New() *Profiler {
+	return &Profiler{since: time.Now(), contracts: map[common.Address]*stats{}}
+}
+
+func (p *Profiler) add(tx *stats, opcodes *[256]stats, contracts map[common.Address]*stats) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.txs.add(tx)
+	for i := range opcodes {
+		p.opcodes[i].add(&opcodes[i])
+	}
+	for addr, s := range contracts {
+		c, ok := p.contracts[addr]
+		if !ok {
+			c = &stats{}
+			p.contracts[addr] = c
+		}
+		c.add(s)
+	}
+}
+
+// Profile - the aggregates of the transactions executed since the profiling started, or was reset. The gas of the
+// call opcodes doesn't include the gas given to the callee.
+type Profile struct {
+	Since        time.Time          `json:"since"`
+	Transactions uint64             `json:"transactions"`
+	Gas          uint64             `json:"gas"`
+	Time         time.Duration      `json:"time"` // nanoseconds
+	Opcodes      []*OpcodeProfile   `json:"opcodes"`
+	Contracts    []*ContractProfile `json:"contracts"`
+}
+
+type OpcodeProfile struct {
+	Op    string        `json:"op"`
+	Count uint64        `json:"count"`
+	Gas   uint64        `json:"gas"`
+	Time  time.Duration `json:"time"`
+}
+
+// ContractProfile - the steps executed in the code of a contract, including the calls delegated to it
+type ContractProfile struct {
+	Address common.Address `json:"address"`
+	Steps   uint64         `json:"steps"`
+	Gas     uint64         `json:"gas"`
+	Time    time.Duration  `json:"time"`
+}
+
+// Profile returns the aggregates, the opcodes and the contracts (the limit of them with the longest time, all of them
+// if limit is 0) sorted by time. With reset, the profiling starts over.
+func (p *Profiler) Profile(limit int, reset bool) *Profile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	res := &Profile{Since: p.since, Transactions: p.txs.count, Gas: p.txs.gas, Time: p.txs.time}
+	for i, s := range p.opcodes {
+		if s.count > 0 {
+			res.Opcodes = append(res.Opcodes, &OpcodeProfile{Op: vm.OpCode(i).String(), Count: s.count, Gas: s.gas, Time: s.time})
+		}
+	}
+	sort.SliceStable(res.Opcodes, func(i, j int) bool { return res.Opcodes[i].Time > res.Opcodes[j].Time })
+	for addr, s := range p.contracts {
+		res.Contracts = append(res.Contracts, &ContractProfile{Address: addr, Steps: s.count, Gas: s.gas, Time: s.time})
+	}
+	sort.Slice(res.Contracts, func(i, j int) bool {
+		if res.Contracts[i].Time != res.Contracts[j].Time {
+			return res.Contracts[i].Time > res.Contracts[j].Time
+		}
+		return bytes.Compare(res.Contracts[i].Address[:], res.Contracts[j].Address[:]) < 0
+	})
+	if limit > 0 && len(res.Contracts) > limit {
+		res.Contracts = res.Contracts[:limit]
+	}
+	if reset {
+		p.since, p.txs, p.opcodes, p.contracts = time.Now(), stats{}, [256]stats{}, map[common.Address]*stats{}
+	}
+	return res
+}
+
+// Tracer returns a tracer profiling the transactions it traces, besides the given tracer.
+func (p *Profiler) Tracer(inner core.ParallelTracer) *Tracer {
+	return &Tracer{inner: inner, p: p, contracts: map[common.Address]*stats{}}
+}
+
+// Tracer profiles the steps of the transactions, until the end of each transaction adds its profile to the
+// profiler. The time of a step lasts until the next one, or until the call or the end of the frame. The transactions
+// re-executed by the parallel execution are profiled each time they run.
+type Tracer struct {
+	inner core.ParallelTracer
+	p     *Profiler
+
+	opcodes   [256]stats
+	contracts map[common.Address]*stats
+
+	stepping  bool // a step is in progress
+	op        vm.OpCode
+	cost      uint64
+	contract  common.Address
+	stepStart time.Time
+}
+
+// endStep accounts the step in progress, given the gas used by the frame it calls if any
+func (t *Tracer) endStep(calleeGas uint64) {
+	if !t.stepping {
+		return
+	}
+	t.stepping = false
+	d := time.Since(t.stepStart)
+	gas := t.cost
+	if calleeGas <= gas {
+		gas -= calleeGas
+	}
+	opcodeTime[t.op].Update(d.Seconds())
+	opcodeGas[t.op].Update(float64(gas))
+	s := &t.opcodes[t.op]
+	s.count++
+	s.gas += gas
+	s.time += d
+	c, ok := t.contracts[t.contract]
+	if !ok {
+		c = &stats{}
+		t.contracts[t.contract] = c
+	}
+	c.count++
+	c.gas += gas
+	c.time += d
+}
+
+func (t *Tracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	calleeGas := gas
+	switch {
+	case create:
+		calleeGas = 0 // not included in the cost of the create opcodes
+	case (callType == vm.CALLT || callType == vm.CALLCODET) && value != nil && value.Sign() > 0:
+		calleeGas -= params.CallStipend // not included in the cost of the call opcodes either
+	}
+	t.endStep(calleeGas)
+	t.inner.CaptureStart(env, depth, from, to, precompile, create, callType, input, gas, value, code)
+}
+
+func (t *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.endStep(0)
+	t.stepping, t.op, t.cost = true, op, cost
+	if scope.Contract.CodeAddr != nil {
+		t.contract = *scope.Contract.CodeAddr
+	} else {
+		t.contract = scope.Contract.Address()
+	}
+	t.inner.CaptureState(env, pc, op, gas, cost, scope, rData, depth, err)
+	t.stepStart = time.Now()
+}
+
+func (t *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.inner.CaptureFault(env, pc, op, gas, cost, scope, depth, err)
+}
+
+func (t *Tracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+	t.endStep(0)
+	t.inner.CaptureEnd(depth, output, startGas, endGas, d, err)
+	if depth != 0 {
+		return
+	}
+	tx := &stats{count: 1, gas: startGas - endGas, time: d}
+	txTime.Update(d.Seconds())
+	txGas.Update(float64(tx.gas))
+	t.p.add(tx, &t.opcodes, t.contracts)
+	t.opcodes, t.contracts = [256]stats{}, map[common.Address]*stats{}
+}
+
+func (t *Tracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+	t.inner.CaptureSelfDestruct(from, to, value)
+}
+
+func (t *Tracer) CaptureAccountRead(account common.Address) error {
+	return t.inner.CaptureAccountRead(account)
+}
+
+func (t *Tracer) CaptureAccountWrite(account common.Address) error {
+	return t.inner.CaptureAccountWrite(account)
+}
+
+// TxTracer returns a tracer for a transaction executed in parallel, see core.ParallelTracer
+func (t *Tracer) TxTracer() vm.Tracer {
+	return t.p.Tracer(t.inner.TxTracer().(core.ParallelTracer))
+}
+
+// Merge merges the traces of a transaction into the inner tracer, its profile being added to the profiler already
+func (t *Tracer) Merge(txTracer vm.Tracer) {
+	t.inner.Merge(txTracer.(*Tracer).inner)
+}
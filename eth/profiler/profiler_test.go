@@ -0,0 +1,52 @@
+package profiler
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/runtime"
+	"github.com/ledgerwatch/erigon/eth/calltracer"
+)
+
+func TestProfiler(t *testing.T) {
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), // SSTORE(loc: 0x00, val: 0x01)
+		byte(vm.PUSH1), 0x00, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), // CALL(gas, 0xff, 0, 0, 0, 0, 0)
+		byte(vm.PUSH1), 0xff, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	p := New()
+	tracer := p.Tracer(calltracer.NewCallTracer(nil))
+	for i := 0; i < 2; i++ {
+		if _, _, err := runtime.Execute(code, nil, &runtime.Config{EVMConfig: vm.Config{Debug: true, Tracer: tracer}}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profile := p.Profile(0, true)
+	if profile.Transactions != 2 || profile.Gas == 0 || profile.Time == 0 {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	var steps, gas uint64
+	for _, op := range profile.Opcodes {
+		steps += op.Count
+		gas += op.Gas
+		if op.Op == vm.SSTORE.String() && op.Count != 2 {
+			t.Errorf("expected 2 SSTORE steps, got %d", op.Count)
+		}
+	}
+	if steps != 2*13 {
+		t.Errorf("expected %d steps, got %d", 2*13, steps)
+	}
+	// the gas given to the callee isn't included in the cost of the CALL
+	if gas != profile.Gas {
+		t.Errorf("gas of the opcodes %d, of the transactions %d", gas, profile.Gas)
+	}
+	if len(profile.Contracts) != 1 || profile.Contracts[0].Address != common.BytesToAddress([]byte("contract")) || profile.Contracts[0].Steps != steps {
+		t.Errorf("unexpected contracts: %+v", profile.Contracts)
+	}
+
+	if profile = p.Profile(0, false); profile.Transactions != 0 || len(profile.Opcodes) != 0 || len(profile.Contracts) != 0 {
+		t.Errorf("profile not reset: %+v", profile)
+	}
+}
@@ -0,0 +1,136 @@
+package aura
+
+import (
+	"fmt"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// seenMessages is the number of the recent messages remembered, not to handle nor relay them twice
+const seenMessages = 1024
+
+// peerQueueSize is the number of the messages queued for a peer, the messages to a slower peer are dropped
+const peerQueueSize = 64
+
+// EmptyStepHandler accumulates the empty step messages of the other authorities, see aura.AuRa
+type EmptyStepHandler interface {
+	HandleEmptyStepMessage(msg []byte) error
+}
+
+// Handler gossips the empty step messages between the authorities: the messages generated by this node are
+// broadcast to the peers, and the ones received are handed to the engine and relayed to the other peers if valid.
+type Handler struct {
+	engine EmptyStepHandler
+	seen   *lru.Cache
+
+	lock  sync.Mutex
+	peers map[*p2p.Peer]chan []byte // the send queue of each peer
+}
+
+func NewHandler(engine EmptyStepHandler) *Handler {
+	seen, err := lru.New(seenMessages)
+	if err != nil {
+		panic(err)
+	}
+	return &Handler{engine: engine, seen: seen, peers: map[*p2p.Peer]chan []byte{}}
+}
+
+func (h *Handler) MakeProtocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: AURA1,
+		Length:  ProtocolLength,
+		Run:     h.runPeer,
+	}
+}
+
+// Broadcast sends an empty step message generated by this node to all the peers, see aura.AuRa.SetEmptyStepBroadcaster
+func (h *Handler) Broadcast(msg []byte) {
+	h.seen.Add(crypto.Keccak256Hash(msg), struct{}{})
+	h.send(nil, msg)
+}
+
+// PeerCount returns the number of the peers the messages are gossiped with
+func (h *Handler) PeerCount() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.peers)
+}
+
+// send queues the message for all the peers but the given one, without waiting for the slow peers
+func (h *Handler) send(except *p2p.Peer, msg []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for peer, queue := range h.peers {
+		if peer == except {
+			continue
+		}
+		select {
+		case queue <- msg:
+		default:
+			log.Trace("[aura] Dropped empty step, send queue full", "peer", peer.ID())
+		}
+	}
+}
+
+func (h *Handler) runPeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	queue := make(chan []byte, peerQueueSize)
+	h.lock.Lock()
+	h.peers[peer] = queue
+	h.lock.Unlock()
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, peer)
+		close(queue)
+		h.lock.Unlock()
+	}()
+	go func() {
+		for msg := range queue {
+			if err := p2p.Send(rw, EmptyStepMsg, rlp.RawValue(msg)); err != nil {
+				log.Trace("[aura] Failed to send empty step", "peer", peer.ID(), "err", err)
+			}
+		}
+	}()
+	for {
+		if err := h.handleMessage(peer, rw); err != nil {
+			log.Trace("[aura] Message handling failed", "peer", peer.ID(), "err", err)
+			return err
+		}
+	}
+}
+
+func (h *Handler) handleMessage(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("message too large: %v > %v", msg.Size, maxMessageSize)
+	}
+	switch msg.Code {
+	case EmptyStepMsg:
+		var emptyStep rlp.RawValue
+		if err = msg.Decode(&emptyStep); err != nil {
+			return fmt.Errorf("decoding EmptyStep: %w", err)
+		}
+		if seen, _ := h.seen.ContainsOrAdd(crypto.Keccak256Hash(emptyStep), struct{}{}); seen {
+			return nil
+		}
+		// the messages of the steps the node isn't in yet, or of another parent, are dropped without
+		// disconnecting the peer
+		if err = h.engine.HandleEmptyStepMessage(emptyStep); err != nil {
+			log.Debug("[aura] Dropped empty step", "peer", peer.ID(), "err", err)
+			return nil
+		}
+		h.send(peer, emptyStep)
+		return nil
+	default:
+		return fmt.Errorf("invalid message code: %d", msg.Code)
+	}
+}
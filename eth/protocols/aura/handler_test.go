@@ -0,0 +1,122 @@
+package aura_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/eth/protocols/aura"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+type engine struct {
+	lock     sync.Mutex
+	received [][]byte
+}
+
+func (e *engine) HandleEmptyStepMessage(msg []byte) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(msg) == 0 || msg[len(msg)-1] != 1 {
+		return errors.New("not signed by the step primary")
+	}
+	e.received = append(e.received, msg)
+	return nil
+}
+
+func (e *engine) count() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.received)
+}
+
+// connect runs the protocol with a new peer, returns the end of the peer and the error the protocol stops with
+func connect(t *testing.T, protocol p2p.Protocol, id byte) (p2p.MsgReadWriter, chan error) {
+	local, remote := p2p.MsgPipe()
+	t.Cleanup(func() { local.Close() })
+	errc := make(chan error, 1)
+	go func() {
+		errc <- protocol.Run(p2p.NewPeer(enode.ID{id}, [64]byte{id}, "test", nil), remote)
+	}()
+	return local, errc
+}
+
+func emptyStep(t *testing.T, valid byte) []byte {
+	msg, err := rlp.EncodeToBytes([]interface{}{[]byte{0xaa, 0xbb}, []byte{valid}})
+	require.NoError(t, err)
+	return msg
+}
+
+func expectEmptyStep(t *testing.T, rw p2p.MsgReadWriter, expected []byte) {
+	require.NoError(t, p2p.ExpectMsg(rw, aura.EmptyStepMsg, rlp.RawValue(expected)))
+}
+
+func TestGossipEmptySteps(t *testing.T) {
+	e := &engine{}
+	handler := aura.NewHandler(e)
+	protocol := handler.MakeProtocol()
+	peer1, errc := connect(t, protocol, 1)
+	peer2, _ := connect(t, protocol, 2)
+	require.Eventually(t, func() bool { return handler.PeerCount() == 2 }, time.Second, 10*time.Millisecond)
+
+	// a message generated by this node is sent to all the peers
+	handler.Broadcast(emptyStep(t, 1))
+	expectEmptyStep(t, peer1, emptyStep(t, 1))
+	expectEmptyStep(t, peer2, emptyStep(t, 1))
+
+	// a message received from a peer is handed to the engine and relayed to the other peers
+	valid, err := rlp.EncodeToBytes([]interface{}{[]byte{0xcc}, []byte{1}})
+	require.NoError(t, err)
+	require.NoError(t, p2p.Send(peer1, aura.EmptyStepMsg, rlp.RawValue(valid)))
+	expectEmptyStep(t, peer2, valid)
+	require.Equal(t, 1, e.count())
+
+	// the messages seen already are neither handled nor relayed again, and neither are the invalid ones
+	require.NoError(t, p2p.Send(peer2, aura.EmptyStepMsg, rlp.RawValue(valid)))
+	require.NoError(t, p2p.Send(peer2, aura.EmptyStepMsg, rlp.RawValue(emptyStep(t, 0))))
+	require.NoError(t, p2p.Send(peer2, aura.EmptyStepMsg, rlp.RawValue(emptyStep(t, 1))))
+	next, err := rlp.EncodeToBytes([]interface{}{[]byte{0xdd}, []byte{1}})
+	require.NoError(t, err)
+	require.NoError(t, p2p.Send(peer2, aura.EmptyStepMsg, rlp.RawValue(next)))
+	expectEmptyStep(t, peer1, next)
+	require.Equal(t, 2, e.count())
+
+	// the peers sending something else are disconnected
+	require.NoError(t, p2p.Send(peer1, 0x01, []uint{}))
+	require.Error(t, <-errc, "invalid message code")
+	require.Eventually(t, func() bool { return handler.PeerCount() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSlowPeer(t *testing.T) {
+	handler := aura.NewHandler(&engine{})
+	slow, _ := connect(t, handler.MakeProtocol(), 1)
+	require.Eventually(t, func() bool { return handler.PeerCount() == 1 }, time.Second, 10*time.Millisecond)
+	numbered := func(i int) []byte {
+		msg, err := rlp.EncodeToBytes([]interface{}{[]byte{byte(i)}, []byte{1}})
+		require.NoError(t, err)
+		return msg
+	}
+
+	// the broadcast doesn't wait for a peer not reading, the messages beyond its send queue of 64 are dropped
+	for i := 0; i < 200; i++ {
+		handler.Broadcast(numbered(i))
+	}
+	for i := 0; i < 64; i++ {
+		expectEmptyStep(t, slow, numbered(i))
+	}
+	// one more message may have been taken from the queue before it was full
+	handler.Broadcast(numbered(200))
+	msg, err := slow.ReadMsg()
+	require.NoError(t, err)
+	var received rlp.RawValue
+	require.NoError(t, msg.Decode(&received))
+	if string(received) == string(numbered(64)) {
+		expectEmptyStep(t, slow, numbered(200))
+	} else {
+		require.Equal(t, numbered(200), []byte(received))
+	}
+}
@@ -0,0 +1,23 @@
+package aura
+
+// Constants to match up protocol versions and messages
+const (
+	AURA1 = 1
+)
+
+// ProtocolName is the short name of the protocol gossiping the consensus messages of the AuRa authorities,
+// used during devp2p capability negotiation. Only Erigon nodes run it: OpenEthereum and Nethermind gossip the
+// empty steps over sub-protocols of their own, so the empty steps of their authorities aren't received.
+const ProtocolName = "aura"
+
+// ProtocolLength is the number of implemented message codes of the `aura` protocol.
+const ProtocolLength = 1
+
+// maxMessageSize is the maximum cap on the size of a protocol message, an empty step is about 100 bytes.
+const maxMessageSize = 1024
+
+const (
+	// EmptyStepMsg carries an empty step message signed by the primary of its step, encoded the way it's gossiped
+	// by OpenEthereum: rlp([signature, rlp([step, parent_hash])])
+	EmptyStepMsg = 0x00
+)
@@ -0,0 +1,93 @@
+package les
+
+import (
+	"time"
+)
+
+// requestCost is the cost of a request in capacity units: the base cost, and the cost of every item requested
+type requestCost struct {
+	base, item uint64
+}
+
+// requestCosts are the costs of the requests, announced to the clients. A unit is about the cost of serving a header,
+// the code and the proofs need the state of the block.
+var requestCosts = map[uint64]requestCost{
+	GetBlockHeadersMsg:     {base: 10, item: 1},
+	GetBlockBodiesMsg:      {base: 10, item: 10},
+	GetReceiptsMsg:         {base: 10, item: 20},
+	GetCodeMsg:             {base: 500, item: 20},
+	GetProofsV2Msg:         {base: 500, item: 50},
+	GetHelperTrieProofsMsg: {base: 10, item: 1},
+	SendTxV2Msg:            {base: 0, item: 50},
+	GetTxStatusMsg:         {base: 0, item: 5},
+}
+
+// maxRequestItems are the maximum numbers of the items of the requests
+var maxRequestItems = map[uint64]uint64{
+	GetBlockHeadersMsg:     MaxHeaderFetch,
+	GetBlockBodiesMsg:      MaxBodyFetch,
+	GetReceiptsMsg:         MaxReceiptFetch,
+	GetCodeMsg:             MaxCodeFetch,
+	GetProofsV2Msg:         MaxProofsFetch,
+	GetHelperTrieProofsMsg: MaxHelperTrieProofsFetch,
+	SendTxV2Msg:            MaxTxSend,
+	GetTxStatusMsg:         MaxTxStatus,
+}
+
+// costList returns the cost table announced to the clients
+func costList() RequestCostList {
+	var list RequestCostList
+	for code := uint64(0); code < ProtocolLength; code++ {
+		if cost, ok := requestCosts[code]; ok {
+			list = append(list, requestCostListItem{MsgCode: code, BaseCost: cost.base, ReqCost: cost.item})
+		}
+	}
+	return list
+}
+
+// bufferLimit is the buffer of the clients, which fits twice the most costly request
+func bufferLimit() uint64 {
+	var limit uint64
+	for code, cost := range requestCosts {
+		if max := cost.base + cost.item*maxRequestItems[code]; 2*max > limit {
+			limit = 2 * max
+		}
+	}
+	return limit
+}
+
+// flowControl is the buffer of a client, from which the costs of its requests are deducted, and which recharges at
+// the minimum rate up to its limit. The client tracks the buffer too, from the value returned in the responses, and
+// the requests which don't fit in the buffer are rejected.
+type flowControl struct {
+	bufLimit    uint64
+	minRecharge uint64 // per millisecond
+	value       uint64
+	updated     time.Time
+}
+
+func newFlowControl(bufLimit, minRecharge uint64, now time.Time) *flowControl {
+	return &flowControl{bufLimit: bufLimit, minRecharge: minRecharge, value: bufLimit, updated: now}
+}
+
+func (fc *flowControl) recharge(now time.Time) {
+	ms := uint64(now.Sub(fc.updated) / time.Millisecond)
+	if ms == 0 {
+		return
+	}
+	fc.updated = fc.updated.Add(time.Duration(ms) * time.Millisecond)
+	if fc.value += ms * fc.minRecharge; fc.value > fc.bufLimit || fc.value < ms*fc.minRecharge {
+		fc.value = fc.bufLimit
+	}
+}
+
+// accept deducts the cost of a request from the buffer, and returns the value of the buffer for the response. It is
+// false if the buffer doesn't have enough left.
+func (fc *flowControl) accept(cost uint64, now time.Time) (uint64, bool) {
+	fc.recharge(now)
+	if cost > fc.value {
+		return fc.value, false
+	}
+	fc.value -= cost
+	return fc.value, true
+}
@@ -0,0 +1,39 @@
+package les
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowControl(t *testing.T) {
+	now := time.Now()
+	fc := newFlowControl(100, 2, now)
+	bv, ok := fc.accept(70, now)
+	require.True(t, ok)
+	require.Equal(t, uint64(30), bv)
+	_, ok = fc.accept(40, now)
+	require.False(t, ok, "the buffer isn't recharged yet")
+
+	// recharged by 2 per millisecond
+	bv, ok = fc.accept(40, now.Add(5*time.Millisecond+time.Microsecond))
+	require.True(t, ok)
+	require.Equal(t, uint64(0), bv)
+	bv, ok = fc.accept(0, now.Add(time.Hour))
+	require.True(t, ok)
+	require.Equal(t, uint64(100), bv, "up to the limit")
+}
+
+func TestCostList(t *testing.T) {
+	list := costList()
+	require.Len(t, list, len(requestCosts))
+	limit := bufferLimit()
+	for i, item := range list {
+		if i > 0 {
+			require.Greater(t, item.MsgCode, list[i-1].MsgCode)
+		}
+		// every request fits in the buffer of the clients
+		require.LessOrEqual(t, item.BaseCost+item.ReqCost*maxRequestItems[item.MsgCode], limit)
+	}
+}
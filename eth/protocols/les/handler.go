@@ -0,0 +1,764 @@
+package les
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/forkid"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// stateLookback is the number of the recent blocks whose state is served, the hashed state is unwound in memory to
+// the older ones
+const stateLookback = 128
+
+var errRequestRejected = errors.New("request exceeds the flow control buffer")
+
+// Config is the configuration of the light server
+type Config struct {
+	NetworkID   uint64
+	ChainConfig *params.ChainConfig
+	Genesis     common.Hash
+	MaxPeers    int    // maximum number of the light clients served
+	Capacity    uint64 // request cost units served per second, shared by the light clients
+	TmpDir      string
+}
+
+// Handler serves the les/4 protocol to the light clients: the headers, bodies and receipts of the blocks, the code
+// and the proofs from the state of the recent blocks, and the transactions relayed to the txpool. The requests are
+// limited by the flow control of les, sharing the capacity between the clients. The helper tries (CHT and bloom
+// trie) aren't computed, their proofs are answered empty.
+type Handler struct {
+	ctx         context.Context
+	cfg         Config
+	db          kv.RoDB
+	blockReader services.HeaderAndCanonicalReader
+	txPool      txpool_proto.TxpoolServer // nil if the transactions aren't relayed
+	forkFilter  forkid.Filter
+	bufLimit    uint64
+	minRecharge uint64
+
+	lock  sync.Mutex
+	peers map[*peer]struct{}
+}
+
+// peer is a light client
+type peer struct {
+	id           string
+	rw           p2p.MsgReadWriter
+	fc           *flowControl
+	announceType uint64
+	heads        chan *announceData
+}
+
+// NewHandler returns the light server. The new heads are announced to the clients from the events.
+func NewHandler(ctx context.Context, cfg Config, db kv.RoDB, blockReader services.HeaderAndCanonicalReader, txPool txpool_proto.TxpoolServer, events *privateapi.Events) *Handler {
+	h := &Handler{
+		ctx:         ctx,
+		cfg:         cfg,
+		db:          db,
+		blockReader: blockReader,
+		txPool:      txPool,
+		bufLimit:    bufferLimit(),
+		peers:       map[*peer]struct{}{},
+	}
+	if cfg.MaxPeers > 0 {
+		h.minRecharge = cfg.Capacity / uint64(cfg.MaxPeers) / 1000
+	}
+	if h.minRecharge == 0 {
+		h.minRecharge = 1
+	}
+	h.forkFilter = forkid.NewFilter(cfg.ChainConfig, cfg.Genesis, func() uint64 {
+		var head uint64
+		_ = db.View(ctx, func(tx kv.Tx) (err error) {
+			head, err = stages.GetStageProgress(tx, stages.Finish)
+			return err
+		})
+		return head
+	})
+	if events != nil {
+		go h.announceLoop(events)
+	}
+	return h
+}
+
+// MakeProtocol returns the les protocol, run by the sentry besides the eth protocol
+func (h *Handler) MakeProtocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: LPV4,
+		Length:  ProtocolLength,
+		Run:     h.runPeer,
+	}
+}
+
+func (h *Handler) runPeer(p2pPeer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	p := &peer{
+		id:           p2pPeer.ID().String(),
+		rw:           rw,
+		fc:           newFlowControl(h.bufLimit, h.minRecharge, time.Now()),
+		announceType: announceTypeSimple,
+		heads:        make(chan *announceData, 1),
+	}
+	if err := h.handshake(p); err != nil {
+		log.Trace("[les] Handshake failed", "peer", p.id, "err", err)
+		return err
+	}
+	if !h.register(p) {
+		return p2p.DiscTooManyPeers
+	}
+	defer h.unregister(p)
+
+	done := make(chan struct{})
+	defer close(done)
+	if p.announceType != announceTypeNone {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case announce := <-p.heads:
+					if err := p2p.Send(p.rw, AnnounceMsg, announce); err != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+	for {
+		if err := h.handleMessage(p); err != nil {
+			log.Trace("[les] Message handling failed", "peer", p.id, "err", err)
+			return err
+		}
+	}
+}
+
+func (h *Handler) register(p *peer) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.peers) >= h.cfg.MaxPeers {
+		return false
+	}
+	h.peers[p] = struct{}{}
+	return true
+}
+
+func (h *Handler) unregister(p *peer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.peers, p)
+}
+
+// head returns the header of the block whose state is served, and its total difficulty
+func head(tx kv.Tx) (*types.Header, *big.Int, error) {
+	number, err := stages.GetStageProgress(tx, stages.Finish)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := rawdb.ReadHeaderByNumber(tx, number)
+	if header == nil {
+		return nil, nil, fmt.Errorf("header of the head block %d not found", number)
+	}
+	td, err := rawdb.ReadTd(tx, header.Hash(), number)
+	if err != nil {
+		return nil, nil, err
+	}
+	if td == nil {
+		return nil, nil, fmt.Errorf("total difficulty of the head block %d not found", number)
+	}
+	return header, td, nil
+}
+
+// handshake exchanges the status with the client, which includes the parameters of its flow control
+func (h *Handler) handshake(p *peer) error {
+	var send keyValueList
+	if err := h.db.View(h.ctx, func(tx kv.Tx) error {
+		header, td, err := head(tx)
+		if err != nil {
+			return err
+		}
+		send = send.add("protocolVersion", uint64(LPV4))
+		send = send.add("networkId", h.cfg.NetworkID)
+		send = send.add("headTd", td)
+		send = send.add("headHash", header.Hash())
+		send = send.add("headNum", header.Number.Uint64())
+		send = send.add("genesisHash", h.cfg.Genesis)
+		send = send.add("forkID", forkid.NewID(h.cfg.ChainConfig, h.cfg.Genesis, header.Number.Uint64()))
+		send = send.add("serveHeaders", nil)
+		send = send.add("serveChainSince", uint64(0))
+		send = send.add("serveRecentState", uint64(stateLookback))
+		if h.txPool != nil {
+			send = send.add("txRelay", nil)
+		}
+		send = send.add("flowControl/BL", h.bufLimit)
+		send = send.add("flowControl/MRR", h.minRecharge)
+		send = send.add("flowControl/MRC", costList())
+		send = send.add("recentTxLookup", uint64(0))
+		return nil
+	}); err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p2p.Send(p.rw, StatusMsg, send)
+	}()
+
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+	if msg.Code != StatusMsg {
+		return fmt.Errorf("first message must be the status, got %d", msg.Code)
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("message too large: %v > %v", msg.Size, maxMessageSize)
+	}
+	var recv keyValueList
+	if err = msg.Decode(&recv); err != nil {
+		return fmt.Errorf("decoding status: %w", err)
+	}
+	status := recv.decode()
+	var (
+		version, networkID uint64
+		genesis            common.Hash
+		forkID             forkid.ID
+	)
+	if err = status.get("protocolVersion", &version); err != nil || version != LPV4 {
+		return fmt.Errorf("protocol version mismatch: %d (%v)", version, err)
+	}
+	if err = status.get("networkId", &networkID); err != nil || networkID != h.cfg.NetworkID {
+		return fmt.Errorf("network id mismatch: %d (%v)", networkID, err)
+	}
+	if err = status.get("genesisHash", &genesis); err != nil || genesis != h.cfg.Genesis {
+		return fmt.Errorf("genesis mismatch: %x (%v)", genesis, err)
+	}
+	if err = status.get("forkID", &forkID); err != nil {
+		return fmt.Errorf("fork id: %w", err)
+	}
+	if err = h.forkFilter(forkID); err != nil {
+		return fmt.Errorf("fork id: %w", err)
+	}
+	if status.get("announceType", &p.announceType) != nil {
+		p.announceType = announceTypeSimple
+	}
+	return <-errc
+}
+
+func (h *Handler) handleMessage(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("message too large: %v > %v", msg.Size, maxMessageSize)
+	}
+
+	// accept checks the number of the items requested, and deducts the cost of the request from the buffer
+	accept := func(items int) (uint64, error) {
+		if uint64(items) > maxRequestItems[msg.Code] {
+			return 0, fmt.Errorf("too many items requested by message %d: %d", msg.Code, items)
+		}
+		cost := requestCosts[msg.Code]
+		bv, ok := p.fc.accept(cost.base+cost.item*uint64(items), time.Now())
+		if !ok {
+			return 0, errRequestRejected
+		}
+		return bv, nil
+	}
+
+	switch msg.Code {
+	case GetBlockHeadersMsg:
+		var req GetBlockHeadersPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetBlockHeaders: %w", err)
+		}
+		bv, err := accept(int(req.Query.Amount))
+		if err != nil {
+			return err
+		}
+		res := &BlockHeadersPacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Headers, err = eth.AnswerGetBlockHeadersQuery(tx, &req.Query, h.blockReader)
+			return err
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, BlockHeadersMsg, res)
+
+	case GetBlockBodiesMsg:
+		var req GetBlockBodiesPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetBlockBodies: %w", err)
+		}
+		bv, err := accept(len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		res := &BlockBodiesPacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) error {
+			res.Bodies = eth.AnswerGetBlockBodiesQuery(tx, req.Hashes)
+			return nil
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, BlockBodiesMsg, res)
+
+	case GetReceiptsMsg:
+		var req GetReceiptsPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetReceipts: %w", err)
+		}
+		bv, err := accept(len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		res := &ReceiptsPacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Receipts, err = eth.AnswerGetReceiptsQuery(tx, req.Hashes)
+			return err
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, ReceiptsMsg, res)
+
+	case GetCodeMsg:
+		var req GetCodePacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetCode: %w", err)
+		}
+		bv, err := accept(len(req.Reqs))
+		if err != nil {
+			return err
+		}
+		res := &CodePacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Data, err = h.answerGetCode(tx, req.Reqs)
+			return err
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, CodeMsg, res)
+
+	case GetProofsV2Msg:
+		var req GetProofsPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetProofsV2: %w", err)
+		}
+		bv, err := accept(len(req.Reqs))
+		if err != nil {
+			return err
+		}
+		res := &ProofsPacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Nodes, err = h.answerGetProofs(tx, req.Reqs)
+			return err
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, ProofsV2Msg, res)
+
+	case GetHelperTrieProofsMsg:
+		var req GetHelperTrieProofsPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetHelperTrieProofs: %w", err)
+		}
+		bv, err := accept(len(req.Reqs))
+		if err != nil {
+			return err
+		}
+		// the helper tries aren't computed
+		return p2p.Send(p.rw, HelperTrieProofsMsg, &HelperTrieProofsPacket{ReqID: req.ReqID, BV: bv})
+
+	case SendTxV2Msg:
+		var req SendTxPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding SendTxV2: %w", err)
+		}
+		bv, err := accept(len(req.Txs))
+		if err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, TxStatusMsg, &TxStatusPacket{ReqID: req.ReqID, BV: bv, Status: h.relayTxs(req.Txs)})
+
+	case GetTxStatusMsg:
+		var req GetTxStatusPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetTxStatus: %w", err)
+		}
+		bv, err := accept(len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		res := &TxStatusPacket{ReqID: req.ReqID, BV: bv}
+		if err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Status, err = h.answerGetTxStatus(tx, req.Hashes)
+			return err
+		}); err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, TxStatusMsg, res)
+
+	case AnnounceMsg, BlockHeadersMsg, BlockBodiesMsg, ReceiptsMsg, CodeMsg, ProofsV2Msg, HelperTrieProofsMsg, TxStatusMsg, StopMsg, ResumeMsg:
+		// the announcements of the clients and the responses to requests never sent are ignored
+		return nil
+
+	default:
+		return fmt.Errorf("invalid message code: %v", msg.Code)
+	}
+}
+
+// states are the hashed states of the blocks of a request
+type states map[common.Hash]*memdb.MemoryMutation
+
+func (s states) rollback() {
+	for _, batch := range s {
+		if batch != nil {
+			batch.Rollback()
+		}
+	}
+}
+
+// stateAt returns the hashed state after the canonical block with the hash, nil if the block is unknown or its
+// state isn't served. rl is the one of stagedsync.HashedStateAt, the keys to load in the trie are added to it.
+func (h *Handler) stateAt(tx kv.Tx, hash common.Hash, rl *trie.RetainList) (*memdb.MemoryMutation, *types.Header, error) {
+	number := rawdb.ReadHeaderNumber(tx, hash)
+	if number == nil {
+		return nil, nil, nil
+	}
+	canonical, err := rawdb.ReadCanonicalHash(tx, *number)
+	if err != nil || canonical != hash {
+		return nil, nil, err
+	}
+	header := rawdb.ReadHeader(tx, hash, *number)
+	if header == nil {
+		return nil, nil, nil
+	}
+	batch, err := stagedsync.HashedStateAt(h.ctx, tx, header, h.cfg.TmpDir, stateLookback, 0, rl)
+	if err != nil {
+		log.Trace("[les] State not served", "block", *number, "err", err)
+		return nil, nil, nil
+	}
+	return batch, header, nil
+}
+
+// account returns the account with the hash in the hashed state, nil if it doesn't exist
+func account(batch kv.Getter, accKey []byte) (*accounts.Account, error) {
+	enc, err := batch.GetOne(kv.HashedAccounts, accKey)
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	var acc accounts.Account
+	if err = acc.DecodeForStorage(enc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// answerGetCode returns the codes of the accounts in the state of the blocks, skipping the unknown ones
+func (h *Handler) answerGetCode(tx kv.Tx, reqs []CodeReq) ([][]byte, error) {
+	batches := states{}
+	defer batches.rollback()
+	var codes [][]byte
+	for _, req := range reqs {
+		batch, ok := batches[req.BHash]
+		if !ok {
+			var err error
+			if batch, _, err = h.stateAt(tx, req.BHash, trie.NewRetainList(0)); err != nil {
+				return nil, err
+			}
+			batches[req.BHash] = batch
+		}
+		if batch == nil || len(req.AccKey) != common.HashLength {
+			continue
+		}
+		acc, err := account(batch, req.AccKey)
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			continue
+		}
+		if acc.IsEmptyCodeHash() {
+			codes = append(codes, []byte{})
+			continue
+		}
+		code, err := tx.GetOne(kv.Code, acc.CodeHash[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(code) > 0 {
+			codes = append(codes, common.CopyBytes(code))
+		}
+	}
+	return codes, nil
+}
+
+// answerGetProofs returns the nodes of the proofs in the tries of the blocks, without the duplicates, skipping the
+// proofs of the unknown blocks and accounts
+func (h *Handler) answerGetProofs(tx kv.Tx, reqs []ProofReq) ([]rlp.RawValue, error) {
+	var blocks []common.Hash
+	byBlock := map[common.Hash][]ProofReq{}
+	for _, req := range reqs {
+		if len(req.Key) != common.HashLength || (len(req.AccKey) != 0 && len(req.AccKey) != common.HashLength) {
+			continue
+		}
+		if _, ok := byBlock[req.BHash]; !ok {
+			blocks = append(blocks, req.BHash)
+		}
+		byBlock[req.BHash] = append(byBlock[req.BHash], req)
+	}
+
+	var nodes []rlp.RawValue
+	seen := map[string]struct{}{}
+	for _, hash := range blocks {
+		proofs, err := h.blockProofs(tx, hash, byBlock[hash])
+		if err != nil {
+			return nil, err
+		}
+		for _, proof := range proofs {
+			for _, node := range proof {
+				if _, ok := seen[string(node)]; !ok {
+					seen[string(node)] = struct{}{}
+					nodes = append(nodes, node)
+				}
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// blockProofs returns the proofs of the requests in the trie of the block
+func (h *Handler) blockProofs(tx kv.Tx, hash common.Hash, reqs []ProofReq) ([][][]byte, error) {
+	rl := trie.NewRetainList(0)
+	batch, header, err := h.stateAt(tx, hash, rl)
+	if err != nil || batch == nil {
+		return nil, err
+	}
+	defer batch.Rollback()
+	exists := make([]bool, len(reqs))
+	for i, req := range reqs {
+		if len(req.AccKey) == 0 {
+			rl.AddKey(req.Key)
+			exists[i] = true
+			continue
+		}
+		acc, err := account(batch, req.AccKey)
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			continue
+		}
+		rl.AddKey(req.AccKey)
+		rl.AddKey(append(dbutils.GenerateStoragePrefix(req.AccKey, acc.Incarnation), req.Key...))
+		exists[i] = true
+	}
+
+	loader := trie.NewFlatDBTrieLoader("les")
+	if err = loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.LoadTrie(batch, h.ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if t.Hash() != header.Root {
+		return nil, fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", header.Number.Uint64(), t.Hash(), header.Root)
+	}
+	var proofs [][][]byte
+	for i, req := range reqs {
+		if !exists[i] {
+			continue
+		}
+		var proof [][]byte
+		if len(req.AccKey) == 0 {
+			proof, err = t.Prove(req.Key, 0, false)
+		} else {
+			proof, err = t.Prove(append(common.CopyBytes(req.AccKey), req.Key...), 2*common.HashLength, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if req.FromLevel < uint(len(proof)) {
+			proofs = append(proofs, proof[req.FromLevel:])
+		}
+	}
+	return proofs, nil
+}
+
+// relayTxs adds the transactions to the txpool, and returns their status
+func (h *Handler) relayTxs(txs []rlp.RawValue) []TxStatus {
+	status := make([]TxStatus, len(txs))
+	if h.txPool == nil {
+		for i := range status {
+			status[i] = TxStatus{Status: TxStatusError, Error: "transactions aren't relayed"}
+		}
+		return status
+	}
+	var add [][]byte
+	var added []int
+	for i, raw := range txs {
+		txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(raw), 0))
+		if err != nil {
+			status[i] = TxStatus{Status: TxStatusError, Error: err.Error()}
+			continue
+		}
+		var buf bytes.Buffer
+		if err = txn.MarshalBinary(&buf); err != nil {
+			status[i] = TxStatus{Status: TxStatusError, Error: err.Error()}
+			continue
+		}
+		add = append(add, buf.Bytes())
+		added = append(added, i)
+	}
+	if len(add) == 0 {
+		return status
+	}
+	reply, err := h.txPool.Add(h.ctx, &txpool_proto.AddRequest{RlpTxs: add})
+	for j, i := range added {
+		switch {
+		case err != nil:
+			status[i] = TxStatus{Status: TxStatusError, Error: err.Error()}
+		case j < len(reply.Imported) && (reply.Imported[j] == txpool_proto.ImportResult_SUCCESS || reply.Imported[j] == txpool_proto.ImportResult_ALREADY_EXISTS):
+			status[i] = TxStatus{Status: TxStatusPending}
+		case j < len(reply.Errors):
+			status[i] = TxStatus{Status: TxStatusError, Error: reply.Errors[j]}
+		default:
+			status[i] = TxStatus{Status: TxStatusError}
+		}
+	}
+	return status
+}
+
+// answerGetTxStatus returns the status of the transactions, included in the canonical chain or in the txpool
+func (h *Handler) answerGetTxStatus(tx kv.Tx, hashes []common.Hash) ([]TxStatus, error) {
+	status := make([]TxStatus, len(hashes))
+	var pooled []*types2.H256
+	var pooledIdx []int
+	for i, hash := range hashes {
+		txn, blockHash, blockNum, txIndex, err := rawdb.ReadTransactionByHash(tx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if txn != nil {
+			status[i] = TxStatus{Status: TxStatusIncluded, Lookup: &TxLookup{BlockHash: blockHash, BlockIndex: blockNum, Index: txIndex}}
+			continue
+		}
+		pooled = append(pooled, gointerfaces.ConvertHashToH256(hash))
+		pooledIdx = append(pooledIdx, i)
+	}
+	if h.txPool == nil || len(pooled) == 0 {
+		return status, nil
+	}
+	reply, err := h.txPool.Transactions(h.ctx, &txpool_proto.TransactionsRequest{Hashes: pooled})
+	if err != nil {
+		log.Debug("[les] Failed to look up transactions in the txpool", "err", err)
+		return status, nil
+	}
+	for j, i := range pooledIdx {
+		if j < len(reply.RlpTxs) && len(reply.RlpTxs[j]) > 0 {
+			status[i] = TxStatus{Status: TxStatusPending}
+		}
+	}
+	return status, nil
+}
+
+// announceLoop announces the new heads to the clients
+func (h *Handler) announceLoop(events *privateapi.Events) {
+	headers, unsubscribe := events.AddHeaderSubscription()
+	defer unsubscribe()
+	var prev *types.Header
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case batch, ok := <-headers:
+			if !ok {
+				return
+			}
+			if len(batch) == 0 {
+				continue
+			}
+			var header types.Header
+			if err := rlp.DecodeBytes(batch[len(batch)-1], &header); err != nil {
+				log.Debug("[les] Failed to decode the new head", "err", err)
+				continue
+			}
+			announce := &announceData{Hash: header.Hash(), Number: header.Number.Uint64()}
+			if err := h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+				if announce.Td, err = rawdb.ReadTd(tx, announce.Hash, announce.Number); err != nil {
+					return err
+				}
+				announce.ReorgDepth, err = reorgDepth(tx, prev)
+				return err
+			}); err != nil || announce.Td == nil {
+				log.Debug("[les] Failed to announce the new head", "block", announce.Number, "err", err)
+				continue
+			}
+			prev = &header
+			h.broadcast(announce)
+		}
+	}
+}
+
+// reorgDepth returns the number of the blocks of the previous head which aren't canonical anymore
+func reorgDepth(tx kv.Tx, prev *types.Header) (uint64, error) {
+	if prev == nil {
+		return 0, nil
+	}
+	var depth uint64
+	for header := prev; header != nil && depth < stateLookback; depth++ {
+		number := header.Number.Uint64()
+		canonical, err := rawdb.ReadCanonicalHash(tx, number)
+		if err != nil {
+			return 0, err
+		}
+		if canonical == header.Hash() || number == 0 {
+			break
+		}
+		header = rawdb.ReadHeader(tx, header.ParentHash, number-1)
+	}
+	return depth, nil
+}
+
+func (h *Handler) broadcast(announce *announceData) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for p := range h.peers {
+		if p.announceType == announceTypeNone {
+			continue
+		}
+		// only the latest head is announced to the slow clients
+		select {
+		case <-p.heads:
+		default:
+		}
+		p.heads <- announce
+	}
+}
@@ -0,0 +1,139 @@
+package les
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/forkid"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightServer(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	ctx := context.Background()
+	var (
+		header, second *types.Header
+		td             *big.Int
+		block          *types.Block
+		contractKey    []byte
+		code           []byte
+	)
+	// the block of the head, with a transaction, and a contract in its state
+	require.NoError(t, m.DB.View(ctx, func(tx kv.Tx) (err error) {
+		if header, td, err = head(tx); err != nil {
+			return err
+		}
+		second = rawdb.ReadHeaderByNumber(tx, 2)
+		if block, _, err = rawdb.ReadBlockWithSenders(tx, header.Hash(), header.Number.Uint64()); err != nil {
+			return err
+		}
+		return tx.ForEach(kv.HashedAccounts, nil, func(k, v []byte) error {
+			if acc, _ := account(tx, k); contractKey == nil && acc != nil && !acc.IsEmptyCodeHash() {
+				contractKey = common.CopyBytes(k)
+				code, err = tx.GetOne(kv.Code, acc.CodeHash[:])
+			}
+			return err
+		})
+	}))
+	require.NotEmpty(t, block.Transactions())
+	require.NotNil(t, contractKey)
+
+	cfg := Config{NetworkID: 1, ChainConfig: m.ChainConfig, Genesis: m.Genesis.Hash(), MaxPeers: 1, Capacity: 1000, TmpDir: t.TempDir()}
+	h := NewHandler(ctx, cfg, m.DB, snapshotsync.NewBlockReader(), nil, nil)
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.MakeProtocol().Run(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), remote)
+	}()
+
+	// the handshake
+	msg, err := local.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(StatusMsg), msg.Code)
+	var recv keyValueList
+	require.NoError(t, msg.Decode(&recv))
+	status := recv.decode()
+	var headNum, bufLimit, minRecharge uint64
+	require.NoError(t, status.get("headNum", &headNum))
+	require.Equal(t, header.Number.Uint64(), headNum)
+	require.NoError(t, status.get("flowControl/BL", &bufLimit))
+	require.Equal(t, bufferLimit(), bufLimit)
+	require.NoError(t, status.get("flowControl/MRR", &minRecharge))
+	require.Equal(t, uint64(1), minRecharge)
+	require.Error(t, status.get("txRelay", nil), "no txpool")
+	var send keyValueList
+	send = send.add("protocolVersion", uint64(LPV4))
+	send = send.add("networkId", uint64(1))
+	send = send.add("headTd", td)
+	send = send.add("headHash", header.Hash())
+	send = send.add("headNum", header.Number.Uint64())
+	send = send.add("genesisHash", m.Genesis.Hash())
+	send = send.add("forkID", forkid.NewID(m.ChainConfig, m.Genesis.Hash(), header.Number.Uint64()))
+	send = send.add("announceType", uint64(announceTypeNone))
+	require.NoError(t, p2p.Send(local, StatusMsg, send))
+
+	// the headers
+	query := &GetBlockHeadersPacket{ReqID: 1, Query: eth.GetBlockHeadersPacket{Origin: eth.HashOrNumber{Number: 1}, Amount: 2}}
+	require.NoError(t, p2p.Send(local, GetBlockHeadersMsg, query))
+	var headers BlockHeadersPacket
+	readMsg(t, local, BlockHeadersMsg, &headers)
+	require.Equal(t, uint64(1), headers.ReqID)
+	require.Len(t, headers.Headers, 2)
+	require.Equal(t, second.Hash(), headers.Headers[1].Hash())
+	require.Equal(t, bufLimit-12, headers.BV)
+
+	// the code and the proof of the sender of a transaction, and of a contract, in the state of the head
+	sender, _ := block.Transactions()[0].GetSender()
+	senderKey := crypto.Keccak256(sender[:])
+	require.NoError(t, p2p.Send(local, GetCodeMsg, &GetCodePacket{ReqID: 2, Reqs: []CodeReq{{BHash: header.Hash(), AccKey: contractKey}, {BHash: common.Hash{1}, AccKey: contractKey}}}))
+	var codes CodePacket
+	readMsg(t, local, CodeMsg, &codes)
+	require.Equal(t, [][]byte{code}, codes.Data)
+
+	require.NoError(t, p2p.Send(local, GetProofsV2Msg, &GetProofsPacket{ReqID: 3, Reqs: []ProofReq{{BHash: header.Hash(), Key: senderKey}}}))
+	var proofs ProofsPacket
+	readMsg(t, local, ProofsV2Msg, &proofs)
+	require.NotEmpty(t, proofs.Nodes)
+	require.Equal(t, header.Root, crypto.Keccak256Hash(proofs.Nodes[0]))
+
+	// the status of an included transaction
+	txHash := block.Transactions()[0].Hash()
+	require.NoError(t, p2p.Send(local, GetTxStatusMsg, &GetTxStatusPacket{ReqID: 4, Hashes: []common.Hash{txHash, {1}}}))
+	var txStatus TxStatusPacket
+	readMsg(t, local, TxStatusMsg, &txStatus)
+	require.Equal(t, []TxStatus{
+		{Status: TxStatusIncluded, Lookup: &TxLookup{BlockHash: header.Hash(), BlockIndex: header.Number.Uint64()}},
+		{Status: TxStatusUnknown},
+	}, txStatus.Status)
+
+	// the requests above the buffer of the flow control disconnect the client
+	require.NoError(t, p2p.Send(local, GetProofsV2Msg, &GetProofsPacket{ReqID: 5, Reqs: make([]ProofReq, MaxProofsFetch)}))
+	readMsg(t, local, ProofsV2Msg, &proofs)
+	require.NoError(t, p2p.Send(local, GetProofsV2Msg, &GetProofsPacket{ReqID: 6, Reqs: make([]ProofReq, MaxProofsFetch)}))
+	select {
+	case err := <-errc:
+		require.ErrorIs(t, err, errRequestRejected)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the client isn't disconnected")
+	}
+}
+
+func readMsg(t *testing.T, r p2p.MsgReader, code uint64, val interface{}) {
+	msg, err := r.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, code, msg.Code)
+	require.NoError(t, msg.Decode(val))
+}
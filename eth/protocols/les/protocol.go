@@ -0,0 +1,287 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	LPV4 = 4
+)
+
+// ProtocolName is the official short name of the `les` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "les"
+
+// ProtocolLength is the number of implemented message codes of the `les/4` protocol.
+const ProtocolLength = 24
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 10 * 1024 * 1024
+
+const (
+	StatusMsg              = 0x00
+	AnnounceMsg            = 0x01
+	GetBlockHeadersMsg     = 0x02
+	BlockHeadersMsg        = 0x03
+	GetBlockBodiesMsg      = 0x04
+	BlockBodiesMsg         = 0x05
+	GetReceiptsMsg         = 0x06
+	ReceiptsMsg            = 0x07
+	GetCodeMsg             = 0x0a
+	CodeMsg                = 0x0b
+	GetProofsV2Msg         = 0x0f
+	ProofsV2Msg            = 0x10
+	GetHelperTrieProofsMsg = 0x11
+	HelperTrieProofsMsg    = 0x12
+	SendTxV2Msg            = 0x13
+	GetTxStatusMsg         = 0x14
+	TxStatusMsg            = 0x15
+	StopMsg                = 0x16
+	ResumeMsg              = 0x17
+)
+
+// Maximum number of the items of a request
+const (
+	MaxHeaderFetch           = 192
+	MaxBodyFetch             = 32
+	MaxReceiptFetch          = 128
+	MaxCodeFetch             = 64
+	MaxProofsFetch           = 64
+	MaxHelperTrieProofsFetch = 64
+	MaxTxSend                = 64
+	MaxTxStatus              = 256
+)
+
+// Announcement types requested by the clients
+const (
+	announceTypeNone = iota
+	announceTypeSimple
+	announceTypeSigned
+)
+
+var errKeyNotFound = errors.New("key not found")
+
+// keyValueEntry is an entry of the status and of the announcements
+type keyValueEntry struct {
+	Key   string
+	Value rlp.RawValue
+}
+
+type keyValueList []keyValueEntry
+type keyValueMap map[string]rlp.RawValue
+
+func (l keyValueList) add(key string, val interface{}) keyValueList {
+	var entry keyValueEntry
+	entry.Key = key
+	if val == nil {
+		val = uint64(0)
+	}
+	enc, err := rlp.EncodeToBytes(val)
+	if err == nil {
+		entry.Value = enc
+	}
+	return append(l, entry)
+}
+
+func (l keyValueList) decode() keyValueMap {
+	m := make(keyValueMap)
+	for _, entry := range l {
+		m[entry.Key] = entry.Value
+	}
+	return m
+}
+
+func (m keyValueMap) get(key string, val interface{}) error {
+	enc, ok := m[key]
+	if !ok {
+		return errKeyNotFound
+	}
+	if val == nil {
+		return nil
+	}
+	return rlp.DecodeBytes(enc, val)
+}
+
+// announceData is the network packet for the block announcements.
+type announceData struct {
+	Hash       common.Hash // Hash of one particular block being announced
+	Number     uint64      // Number of one particular block being announced
+	Td         *big.Int    // Total difficulty of one particular block being announced
+	ReorgDepth uint64
+	Update     keyValueList
+}
+
+// requestCostListItem is the cost of a request, announced to the clients by the flow control
+type requestCostListItem struct {
+	MsgCode, BaseCost, ReqCost uint64
+}
+
+// RequestCostList is the table of the costs of the requests
+type RequestCostList []requestCostListItem
+
+// GetBlockHeadersPacket represents a block header query.
+type GetBlockHeadersPacket struct {
+	ReqID uint64
+	Query eth.GetBlockHeadersPacket
+}
+
+// BlockHeadersPacket represents a block header response.
+type BlockHeadersPacket struct {
+	ReqID, BV uint64
+	Headers   []*types.Header
+}
+
+// GetBlockBodiesPacket represents a block body query.
+type GetBlockBodiesPacket struct {
+	ReqID  uint64
+	Hashes []common.Hash
+}
+
+// BlockBodiesPacket represents a block body response.
+type BlockBodiesPacket struct {
+	ReqID, BV uint64
+	Bodies    []rlp.RawValue
+}
+
+// GetReceiptsPacket represents a block receipts query.
+type GetReceiptsPacket struct {
+	ReqID  uint64
+	Hashes []common.Hash
+}
+
+// ReceiptsPacket represents a block receipts response.
+type ReceiptsPacket struct {
+	ReqID, BV uint64
+	Receipts  []rlp.RawValue
+}
+
+// CodeReq is a request of the code of an account in the state of a block.
+type CodeReq struct {
+	BHash  common.Hash
+	AccKey []byte // hash of the address of the account
+}
+
+// GetCodePacket represents a contract code query.
+type GetCodePacket struct {
+	ReqID uint64
+	Reqs  []CodeReq
+}
+
+// CodePacket represents a contract code response.
+type CodePacket struct {
+	ReqID, BV uint64
+	Data      [][]byte
+}
+
+// ProofReq is a request of the proof of a key in the state trie of a block, or in the storage trie of an account
+// if AccKey is set.
+type ProofReq struct {
+	BHash     common.Hash
+	AccKey    []byte // hash of the address of the account, empty for the state trie
+	Key       []byte // hash of the address or of the storage key
+	FromLevel uint   // number of the nodes of the proof to skip from the root
+}
+
+// GetProofsPacket represents a proof query.
+type GetProofsPacket struct {
+	ReqID uint64
+	Reqs  []ProofReq
+}
+
+// ProofsPacket represents a proof response, the nodes of all the proofs requested.
+type ProofsPacket struct {
+	ReqID, BV uint64
+	Nodes     []rlp.RawValue
+}
+
+// HelperTrieReq is a request of a proof in a helper trie.
+type HelperTrieReq struct {
+	Type              uint
+	TrieIdx           uint64
+	Key               []byte
+	FromLevel, AuxReq uint
+}
+
+// GetHelperTrieProofsPacket represents a helper trie proof query.
+type GetHelperTrieProofsPacket struct {
+	ReqID uint64
+	Reqs  []HelperTrieReq
+}
+
+// HelperTrieResps are the proofs of the helper trie requests, and their auxiliary data.
+type HelperTrieResps struct {
+	Proofs  []rlp.RawValue
+	AuxData [][]byte
+}
+
+// HelperTrieProofsPacket represents a helper trie proof response.
+type HelperTrieProofsPacket struct {
+	ReqID, BV uint64
+	Data      HelperTrieResps
+}
+
+// SendTxPacket represents a transaction relay request.
+type SendTxPacket struct {
+	ReqID uint64
+	Txs   []rlp.RawValue
+}
+
+// GetTxStatusPacket represents a transaction status query.
+type GetTxStatusPacket struct {
+	ReqID  uint64
+	Hashes []common.Hash
+}
+
+// TxStatusCode is the status of a transaction.
+type TxStatusCode uint
+
+const (
+	TxStatusUnknown TxStatusCode = iota
+	TxStatusQueued
+	TxStatusPending
+	TxStatusIncluded
+	TxStatusError
+)
+
+// TxLookup is the position of an included transaction.
+type TxLookup struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64
+}
+
+// TxStatus is the status of a transaction, sent or looked up.
+type TxStatus struct {
+	Status TxStatusCode
+	Lookup *TxLookup `rlp:"nil"`
+	Error  string
+}
+
+// TxStatusPacket represents a transaction status response.
+type TxStatusPacket struct {
+	ReqID, BV uint64
+	Status    []TxStatus
+}
@@ -0,0 +1,673 @@
+package snap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+
+	// maxTrieNodeLookups is the maximum number of state trie nodes to serve.
+	maxTrieNodeLookups = 1024
+
+	// stateLookback is the number of the recent blocks whose state is served, the hashed state is unwound in memory
+	// to the older ones
+	stateLookback = 128
+
+	// maxConcurrentRequests is the number of requests served at once, of all the peers
+	maxConcurrentRequests = 4
+
+	// maxCachedStates is the number of the states unwound in memory which are kept for the next requests of their root,
+	// each one keeps a read transaction open
+	maxCachedStates = 2
+
+	// stateCacheTTL is how long a state unwound in memory is kept
+	stateCacheTTL = time.Minute
+)
+
+// maxHash is the limit of the storage ranges which aren't limited by the request
+var maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+// Handler serves the snap protocol from the hashed state, and the trie computed from it. Erigon keeps no trie nodes,
+// the ones requested by the peers to heal their trie are computed from the hashed state too.
+type Handler struct {
+	ctx     context.Context
+	db      kv.RoDB
+	tmpDir  string
+	rate    rate.Limit
+	serving *semaphore.Weighted // the requests served at once, of all the peers
+
+	lock   sync.Mutex
+	states map[common.Hash]*stateWorker // by root
+}
+
+// NewHandler returns the handler serving the state of db, and at most requestsPerSecond requests to each peer, without
+// a limit if it isn't positive
+func NewHandler(ctx context.Context, db kv.RoDB, tmpDir string, requestsPerSecond float64) *Handler {
+	limit := rate.Inf
+	if requestsPerSecond > 0 {
+		limit = rate.Limit(requestsPerSecond)
+	}
+	return &Handler{ctx: ctx, db: db, tmpDir: tmpDir, rate: limit, serving: semaphore.NewWeighted(maxConcurrentRequests),
+		states: map[common.Hash]*stateWorker{}}
+}
+
+// MakeProtocol returns the snap protocol, run by the sentry besides the eth protocol
+func (h *Handler) MakeProtocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: SNAP1,
+		Length:  ProtocolLength,
+		Run:     h.runPeer,
+	}
+}
+
+func (h *Handler) runPeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	// the requests above the rate of the peer wait, which delays the following ones too
+	burst := 1
+	if h.rate != rate.Inf && h.rate > 1 {
+		burst = int(math.Ceil(float64(h.rate)))
+	}
+	limiter := rate.NewLimiter(h.rate, burst)
+	for {
+		if err := h.handleMessage(limiter, rw); err != nil {
+			log.Trace("[snap] Message handling failed", "peer", peer.ID(), "err", err)
+			return err
+		}
+	}
+}
+
+func (h *Handler) handleMessage(limiter *rate.Limiter, rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("message too large: %v > %v", msg.Size, maxMessageSize)
+	}
+	if err = limiter.Wait(h.ctx); err != nil {
+		return err
+	}
+	if err = h.serving.Acquire(h.ctx, 1); err != nil {
+		return err
+	}
+	defer h.serving.Release(1)
+	start := time.Now()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetAccountRange: %w", err)
+		}
+		res := &AccountRangePacket{ID: req.ID}
+		err = h.withState(req.Root, func(state *servedState) (err error) {
+			res.Accounts, res.Proof, err = state.accountRange(h.ctx, &req)
+			return err
+		})
+		if err != nil {
+			log.Debug("[snap] Failed to serve accounts", "root", req.Root, "origin", req.Origin, "err", err)
+			res.Accounts, res.Proof = nil, nil
+		}
+		log.Trace("[snap] Served accounts", "accounts", len(res.Accounts), "in", time.Since(start))
+		return p2p.Send(rw, AccountRangeMsg, res)
+
+	case GetStorageRangesMsg:
+		var req GetStorageRangesPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetStorageRanges: %w", err)
+		}
+		res := &StorageRangesPacket{ID: req.ID}
+		err = h.withState(req.Root, func(state *servedState) (err error) {
+			res.Slots, res.Proof, err = state.storageRanges(h.ctx, &req)
+			return err
+		})
+		if err != nil {
+			log.Debug("[snap] Failed to serve storage", "root", req.Root, "accounts", len(req.Accounts), "err", err)
+			res.Slots, res.Proof = nil, nil
+		}
+		log.Trace("[snap] Served storage", "accounts", len(res.Slots), "in", time.Since(start))
+		return p2p.Send(rw, StorageRangesMsg, res)
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetByteCodes: %w", err)
+		}
+		res := &ByteCodesPacket{ID: req.ID}
+		err = h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			res.Codes, err = ServiceGetByteCodesQuery(tx, &req)
+			return err
+		})
+		if err != nil {
+			log.Debug("[snap] Failed to serve bytecodes", "hashes", len(req.Hashes), "err", err)
+			res.Codes = nil
+		}
+		return p2p.Send(rw, ByteCodesMsg, res)
+
+	case GetTrieNodesMsg:
+		var req GetTrieNodesPacket
+		if err = msg.Decode(&req); err != nil {
+			return fmt.Errorf("decoding GetTrieNodes: %w", err)
+		}
+		res := &TrieNodesPacket{ID: req.ID}
+		err = h.withState(req.Root, func(state *servedState) (err error) {
+			res.Nodes, err = state.trieNodes(h.ctx, &req)
+			return err
+		})
+		if err != nil {
+			log.Debug("[snap] Failed to serve trie nodes", "root", req.Root, "paths", len(req.Paths), "err", err)
+			res.Nodes = nil
+		}
+		log.Trace("[snap] Served trie nodes", "nodes", len(res.Nodes), "in", time.Since(start))
+		return p2p.Send(rw, TrieNodesMsg, res)
+
+	case AccountRangeMsg, StorageRangesMsg, ByteCodesMsg, TrieNodesMsg:
+		// the responses to requests never sent are ignored
+		return nil
+
+	default:
+		return fmt.Errorf("invalid message code: %v", msg.Code)
+	}
+}
+
+// responseLimit is the size of the response requested by the peer, capped by the soft limit of the responses
+func responseLimit(requested uint64) uint64 {
+	if requested > softResponseLimit {
+		return softResponseLimit
+	}
+	return requested
+}
+
+// servedState is the hashed state after the block of a root, unwound in memory. unwound has the keys changed since
+// the block, to load in the tries (see stagedsync.HashedStateAt).
+type servedState struct {
+	batch   *memdb.MemoryMutation
+	header  *types.Header
+	unwound *trie.RetainList
+}
+
+// openState unwinds the state of the root in memory, nil if no recent block has the root
+func openState(ctx context.Context, tx kv.Tx, root common.Hash, tmpDir string) (*servedState, error) {
+	unwound := trie.NewRetainList(0)
+	batch, header, err := stateAt(ctx, tx, root, tmpDir, unwound)
+	if err != nil || batch == nil {
+		return nil, err
+	}
+	return &servedState{batch: batch, header: header, unwound: unwound}, nil
+}
+
+// stateWorker serves the requests of a root from its state, unwound once in a read transaction. The transactions are
+// bound to the goroutine which opens them, so the worker runs the requests in its own goroutine, one at a time.
+type stateWorker struct {
+	requests chan stateRequest
+	done     chan struct{}
+}
+
+type stateRequest struct {
+	serve func(state *servedState) error
+	errc  chan error
+}
+
+// withState runs serve on the state of the root, unless no recent block has the root. The state is kept for the next
+// requests of the root during stateCacheTTL, up to maxCachedStates states.
+func (h *Handler) withState(root common.Hash, serve func(state *servedState) error) error {
+	for {
+		h.lock.Lock()
+		w, ok := h.states[root]
+		if !ok && len(h.states) < maxCachedStates {
+			w = &stateWorker{requests: make(chan stateRequest), done: make(chan struct{})}
+			h.states[root] = w
+			go h.runStateWorker(root, w)
+			ok = true
+		}
+		h.lock.Unlock()
+		if !ok {
+			// enough states are kept, this one is unwound for the request only
+			return h.db.View(h.ctx, func(tx kv.Tx) error {
+				state, err := openState(h.ctx, tx, root, h.tmpDir)
+				if err != nil || state == nil {
+					return err
+				}
+				defer state.batch.Rollback()
+				return serve(state)
+			})
+		}
+		errc := make(chan error, 1)
+		select {
+		case w.requests <- stateRequest{serve: serve, errc: errc}:
+			return <-errc
+		case <-w.done:
+			// the state expired meanwhile, it's unwound again
+		case <-h.ctx.Done():
+			return h.ctx.Err()
+		}
+	}
+}
+
+func (h *Handler) runStateWorker(root common.Hash, w *stateWorker) {
+	defer func() {
+		h.lock.Lock()
+		delete(h.states, root)
+		h.lock.Unlock()
+		close(w.done)
+	}()
+	var state *servedState
+	tx, err := h.db.BeginRo(h.ctx)
+	if err == nil {
+		defer tx.Rollback()
+		if state, err = openState(h.ctx, tx, root, h.tmpDir); state != nil {
+			defer state.batch.Rollback()
+		}
+	}
+	expire := time.NewTimer(stateCacheTTL)
+	defer expire.Stop()
+	for {
+		select {
+		case req := <-w.requests:
+			if err != nil || state == nil {
+				// not kept: the root may be the one of a block executed later
+				req.errc <- err
+				return
+			}
+			req.errc <- req.serve(state)
+		case <-expire.C:
+			return
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// stateAt returns the hashed state after the recent block with the state root, nil if no such block is served.
+// rl is the one of stagedsync.HashedStateAt, the keys to load in the trie are added to it.
+func stateAt(ctx context.Context, tx kv.Tx, root common.Hash, tmpDir string, rl *trie.RetainList) (*memdb.MemoryMutation, *types.Header, error) {
+	trieProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	for blockNum := trieProgress; blockNum+stateLookback >= trieProgress; blockNum-- {
+		header := rawdb.ReadHeaderByNumber(tx, blockNum)
+		if header == nil {
+			break
+		}
+		if header.Root == root {
+			batch, err := stagedsync.HashedStateAt(ctx, tx, header, tmpDir, stateLookback, 0, rl)
+			return batch, header, err
+		}
+		if blockNum == 0 {
+			break
+		}
+	}
+	return nil, nil, nil
+}
+
+// loadTrie loads the trie of the keys of the retain list, and checks its root against the header
+func loadTrie(ctx context.Context, batch kv.Tx, header *types.Header, rl *trie.RetainList) (*trie.Trie, error) {
+	loader := trie.NewFlatDBTrieLoader("snap")
+	if err := loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.LoadTrie(batch, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if t.Hash() != header.Root {
+		return nil, fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", header.Number.Uint64(), t.Hash(), header.Root)
+	}
+	return t, nil
+}
+
+// proofNodes returns the nodes of the proofs, without the duplicates
+func proofNodes(proofs ...[][]byte) [][]byte {
+	var nodes [][]byte
+	seen := map[string]struct{}{}
+	for _, proof := range proofs {
+		for _, node := range proof {
+			if _, ok := seen[string(node)]; !ok {
+				seen[string(node)] = struct{}{}
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes
+}
+
+// slimAccount is the account in the slim format of the snap protocol, the empty root and code hash omitted
+type slimAccount struct {
+	Nonce    uint64
+	Balance  *uint256.Int
+	Root     []byte
+	CodeHash []byte
+}
+
+func encodeSlimAccount(acc *accounts.Account) (rlp.RawValue, error) {
+	slim := slimAccount{Nonce: acc.Nonce, Balance: &acc.Balance}
+	if acc.Root != trie.EmptyRoot && acc.Root != (common.Hash{}) {
+		slim.Root = acc.Root[:]
+	}
+	if acc.CodeHash != trie.EmptyCodeHash && acc.CodeHash != (common.Hash{}) {
+		slim.CodeHash = acc.CodeHash[:]
+	}
+	return rlp.EncodeToBytes(&slim)
+}
+
+// ServiceGetAccountRangeQuery returns the accounts of the range requested, and the proof of its first and last keys
+func ServiceGetAccountRangeQuery(ctx context.Context, tx kv.Tx, req *GetAccountRangePacket, tmpDir string) ([]*AccountData, [][]byte, error) {
+	state, err := openState(ctx, tx, req.Root, tmpDir)
+	if err != nil || state == nil {
+		return nil, nil, err
+	}
+	defer state.batch.Rollback()
+	return state.accountRange(ctx, req)
+}
+
+func (s *servedState) accountRange(ctx context.Context, req *GetAccountRangePacket) ([]*AccountData, [][]byte, error) {
+	batch, header, rl := s.batch, s.header, s.unwound.Copy()
+	// the storage roots are only known to the trie, the accounts are collected first to load them
+	bytesLimit := responseLimit(req.Bytes)
+	var hashes []common.Hash
+	var size uint64
+	c, err := batch.Cursor(kv.HashedAccounts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(req.Origin[:]); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, nil, err
+		}
+		hash := common.BytesToHash(k)
+		hashes = append(hashes, hash)
+		// approximately the size of the slim account, with its storage root
+		size += uint64(2*common.HashLength + len(v))
+		if bytes.Compare(hash[:], req.Limit[:]) >= 0 || size >= bytesLimit {
+			break
+		}
+	}
+
+	rl.AddKey(req.Origin[:])
+	for _, hash := range hashes {
+		rl.AddKey(hash[:])
+	}
+	t, err := loadTrie(ctx, batch, header, rl)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*AccountData, len(hashes))
+	for i, hash := range hashes {
+		acc, ok := t.GetAccount(hash[:])
+		if !ok || acc == nil {
+			return nil, nil, fmt.Errorf("account %x missing in the trie", hash)
+		}
+		body, err := encodeSlimAccount(acc)
+		if err != nil {
+			return nil, nil, err
+		}
+		res[i] = &AccountData{Hash: hash, Body: body}
+	}
+
+	originProof, err := t.Prove(req.Origin[:], 0, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	var lastProof [][]byte
+	if len(hashes) > 0 {
+		if lastProof, err = t.Prove(hashes[len(hashes)-1][:], 0, false); err != nil {
+			return nil, nil, err
+		}
+	}
+	return res, proofNodes(originProof, lastProof), nil
+}
+
+// ServiceGetStorageRangesQuery returns the storage slots of the accounts requested, and the proof of the last range
+// if it is incomplete. Like in geth, the origin and the limit of the request apply to the first account.
+func ServiceGetStorageRangesQuery(ctx context.Context, tx kv.Tx, req *GetStorageRangesPacket, tmpDir string) ([][]*StorageData, [][]byte, error) {
+	state, err := openState(ctx, tx, req.Root, tmpDir)
+	if err != nil || state == nil {
+		return nil, nil, err
+	}
+	defer state.batch.Rollback()
+	return state.storageRanges(ctx, req)
+}
+
+func (s *servedState) storageRanges(ctx context.Context, req *GetStorageRangesPacket) ([][]*StorageData, [][]byte, error) {
+	batch, header, rl := s.batch, s.header, s.unwound.Copy()
+	bytesLimit := responseLimit(req.Bytes)
+	c, err := batch.CursorDupSort(kv.HashedStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	var slots [][]*StorageData
+	var size uint64
+	for _, account := range req.Accounts {
+		if size >= bytesLimit {
+			break
+		}
+		var origin common.Hash
+		if len(req.Origin) > 0 {
+			origin, req.Origin = common.BytesToHash(req.Origin), nil
+		}
+		limit := maxHash
+		if len(req.Limit) > 0 {
+			limit, req.Limit = common.BytesToHash(req.Limit), nil
+		}
+		enc, err := batch.GetOne(kv.HashedAccounts, account[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(enc) == 0 {
+			slots = append(slots, nil)
+			continue
+		}
+		incarnation, err := accounts.DecodeIncarnationFromStorage(enc)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefix := dbutils.GenerateStoragePrefix(account[:], incarnation)
+
+		var storage []*StorageData
+		var abort bool
+		v, err := c.SeekBothRange(prefix, origin[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		for v != nil {
+			if size >= bytesLimit {
+				abort = true
+				break
+			}
+			hash := common.BytesToHash(v[:common.HashLength])
+			body, err := rlp.EncodeToBytes(v[common.HashLength:])
+			if err != nil {
+				return nil, nil, err
+			}
+			storage = append(storage, &StorageData{Hash: hash, Body: body})
+			size += uint64(common.HashLength + len(body))
+			if bytes.Compare(hash[:], limit[:]) >= 0 {
+				break
+			}
+			if _, v, err = c.NextDup(); err != nil {
+				return nil, nil, err
+			}
+		}
+		slots = append(slots, storage)
+
+		// the proof is only needed for the sub-ranges, and for the ranges cut by the size limit
+		if origin == (common.Hash{}) && !(abort && len(storage) > 0) {
+			continue
+		}
+		rl.AddKey(account[:])
+		rl.AddKey(append(common.CopyBytes(prefix), origin[:]...))
+		if len(storage) > 0 {
+			rl.AddKey(append(common.CopyBytes(prefix), storage[len(storage)-1].Hash[:]...))
+		}
+		t, err := loadTrie(ctx, batch, header, rl)
+		if err != nil {
+			return nil, nil, err
+		}
+		originProof, err := t.Prove(append(account[:common.HashLength:common.HashLength], origin[:]...), 2*common.HashLength, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		var lastProof [][]byte
+		if len(storage) > 0 {
+			last := storage[len(storage)-1].Hash
+			if lastProof, err = t.Prove(append(account[:common.HashLength:common.HashLength], last[:]...), 2*common.HashLength, true); err != nil {
+				return nil, nil, err
+			}
+		}
+		return slots, proofNodes(originProof, lastProof), nil
+	}
+	return slots, nil, nil
+}
+
+// ServiceGetByteCodesQuery returns the bytecodes requested, skipping the unknown ones
+func ServiceGetByteCodesQuery(tx kv.Tx, req *GetByteCodesPacket) ([][]byte, error) {
+	bytesLimit := responseLimit(req.Bytes)
+	hashes := req.Hashes
+	if len(hashes) > maxCodeLookups {
+		hashes = hashes[:maxCodeLookups]
+	}
+	var codes [][]byte
+	var size uint64
+	for _, hash := range hashes {
+		if hash == trie.EmptyCodeHash {
+			codes = append(codes, []byte{})
+			continue
+		}
+		code, err := tx.GetOne(kv.Code, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(code) == 0 {
+			continue
+		}
+		codes = append(codes, common.CopyBytes(code))
+		if size += uint64(len(code)); size >= bytesLimit {
+			break
+		}
+	}
+	return codes, nil
+}
+
+// ServiceGetTrieNodesQuery returns the trie nodes at the paths requested, empty for the paths without a node. Like in
+// geth, a path set of one path is a path of the account trie, the next ones of a longer set are paths of the storage
+// trie of the account with the hash of its first path.
+func ServiceGetTrieNodesQuery(ctx context.Context, tx kv.Tx, req *GetTrieNodesPacket, tmpDir string) ([][]byte, error) {
+	state, err := openState(ctx, tx, req.Root, tmpDir)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	defer state.batch.Rollback()
+	return state.trieNodes(ctx, req)
+}
+
+func (s *servedState) trieNodes(ctx context.Context, req *GetTrieNodesPacket) ([][]byte, error) {
+	rl := s.unwound.Copy()
+	type lookup struct {
+		account []byte // nil in the account trie
+		hex     []byte
+	}
+	var lookups []lookup
+	for _, pathset := range req.Paths {
+		if len(lookups) >= maxTrieNodeLookups {
+			break
+		}
+		switch len(pathset) {
+		case 0:
+		case 1:
+			hex := trie.CompactToHex(pathset[0])
+			retainPath(rl, nil, hex)
+			lookups = append(lookups, lookup{hex: hex})
+		default:
+			account := common.BytesToHash(pathset[0])
+			rl.AddKey(account[:])
+			enc, err := s.batch.GetOne(kv.HashedAccounts, account[:])
+			if err != nil {
+				return nil, err
+			}
+			var prefix []byte
+			if len(enc) > 0 {
+				incarnation, err := accounts.DecodeIncarnationFromStorage(enc)
+				if err != nil {
+					return nil, err
+				}
+				prefix = dbutils.GenerateStoragePrefix(account[:], incarnation)
+			}
+			for _, path := range pathset[1:] {
+				hex := trie.CompactToHex(path)
+				if prefix != nil {
+					retainPath(rl, prefix, hex)
+				}
+				lookups = append(lookups, lookup{account: account[:], hex: hex})
+			}
+		}
+	}
+	if len(lookups) > maxTrieNodeLookups {
+		lookups = lookups[:maxTrieNodeLookups]
+	}
+
+	t, err := loadTrie(ctx, s.batch, s.header, rl)
+	if err != nil {
+		return nil, err
+	}
+	bytesLimit := responseLimit(req.Bytes)
+	var nodes [][]byte
+	var size uint64
+	for _, l := range lookups {
+		node, err := t.EncodedNode(l.account, l.hex)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if size += uint64(len(node)); size >= bytesLimit {
+			break
+		}
+	}
+	return nodes, nil
+}
+
+// retainPath adds to rl a key below the path of nibbles hex, in the storage with the prefix if it's given, so that the
+// trie is loaded down to the node at the path
+func retainPath(rl *trie.RetainList, prefix []byte, hex []byte) {
+	key := make([]byte, len(prefix)+common.HashLength)
+	copy(key, prefix)
+	for i := 0; i < len(hex) && i < 2*common.HashLength; i++ {
+		if i%2 == 0 {
+			key[len(prefix)+i/2] = hex[i] << 4
+		} else {
+			key[len(prefix)+i/2] |= hex[i] & 0xf
+		}
+	}
+	rl.AddKey(key)
+}
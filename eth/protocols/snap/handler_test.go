@@ -0,0 +1,225 @@
+package snap_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/snap"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
+)
+
+var maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+type slimAccount struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     []byte
+	CodeHash []byte
+}
+
+func decodeSlimAccount(t *testing.T, body []byte) *accounts.Account {
+	var slim slimAccount
+	require.NoError(t, rlp.DecodeBytes(body, &slim))
+	acc := accounts.NewAccount()
+	acc.Nonce = slim.Nonce
+	acc.Balance.SetFromBig(slim.Balance)
+	if len(slim.Root) > 0 {
+		acc.Root = common.BytesToHash(slim.Root)
+	}
+	if len(slim.CodeHash) > 0 {
+		acc.CodeHash = common.BytesToHash(slim.CodeHash)
+	}
+	return &acc
+}
+
+// verifyProof checks that the first node of the proof is the root node
+func verifyProof(t *testing.T, root common.Hash, proof [][]byte) {
+	require.NotEmpty(t, proof)
+	require.Equal(t, root, crypto.Keccak256Hash(proof[0]))
+}
+
+func TestServeState(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	ctx, tmpDir := context.Background(), t.TempDir()
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	head := rawdb.ReadCurrentHeader(tx)
+	// the older blocks need the state to be unwound in memory
+	for _, blockNum := range []uint64{head.Number.Uint64(), 4, 2} {
+		root := rawdb.ReadHeaderByNumber(tx, blockNum).Root
+
+		// the complete state has the root of the block
+		accs, proof, err := snap.ServiceGetAccountRangeQuery(ctx, tx, &snap.GetAccountRangePacket{Root: root, Limit: maxHash, Bytes: 1 << 20}, tmpDir)
+		require.NoError(t, err)
+		require.NotEmpty(t, accs)
+		verifyProof(t, root, proof)
+		stateTrie := trie.New(trie.EmptyRoot)
+		var withStorage []common.Hash
+		var storageRoots []common.Hash
+		for _, a := range accs {
+			acc := decodeSlimAccount(t, a.Body)
+			stateTrie.UpdateAccount(a.Hash[:], acc)
+			if acc.Root != trie.EmptyRoot {
+				withStorage = append(withStorage, a.Hash)
+				storageRoots = append(storageRoots, acc.Root)
+			}
+		}
+		require.Equal(t, root, stateTrie.Hash(), "block %d", blockNum)
+
+		if blockNum >= 4 {
+			require.NotEmpty(t, withStorage, "the token is deployed in block 3")
+		}
+		slots, proof, err := snap.ServiceGetStorageRangesQuery(ctx, tx, &snap.GetStorageRangesPacket{Root: root, Accounts: withStorage, Bytes: 1 << 20}, tmpDir)
+		require.NoError(t, err)
+		require.Empty(t, proof, "the ranges are complete")
+		require.Len(t, slots, len(withStorage))
+		for i, storage := range slots {
+			storageTrie := trie.New(trie.EmptyRoot)
+			for _, slot := range storage {
+				var value []byte
+				require.NoError(t, rlp.DecodeBytes(slot.Body, &value))
+				storageTrie.Update(slot.Hash[:], value)
+			}
+			require.Equal(t, storageRoots[i], storageTrie.Hash(), "block %d", blockNum)
+		}
+
+		// the range cut by the size limit is proven
+		accs, proof, err = snap.ServiceGetAccountRangeQuery(ctx, tx, &snap.GetAccountRangePacket{Root: root, Origin: common.Hash{0x10}, Limit: maxHash, Bytes: 1}, tmpDir)
+		require.NoError(t, err)
+		require.Len(t, accs, 1)
+		require.True(t, accs[0].Hash[0] >= 0x10)
+		verifyProof(t, root, proof)
+		if len(withStorage) > 0 {
+			slots, proof, err = snap.ServiceGetStorageRangesQuery(ctx, tx, &snap.GetStorageRangesPacket{Root: root, Accounts: withStorage[:1], Bytes: 1}, tmpDir)
+			require.NoError(t, err)
+			require.Len(t, slots, 1)
+			require.Len(t, slots[0], 1)
+			verifyProof(t, storageRoots[0], proof)
+		}
+	}
+
+	// the state of an unknown root isn't served
+	accs, proof, err := snap.ServiceGetAccountRangeQuery(ctx, tx, &snap.GetAccountRangePacket{Root: common.Hash{1}, Limit: maxHash, Bytes: 1 << 20}, tmpDir)
+	require.NoError(t, err)
+	require.Empty(t, accs)
+	require.Empty(t, proof)
+}
+
+func TestServeByteCodes(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	ctx := context.Background()
+	var codeHash common.Hash
+	var code []byte
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(kv.Code, nil, func(k, v []byte) error {
+			codeHash, code = common.BytesToHash(k), common.CopyBytes(v)
+			return nil
+		})
+	}))
+	require.NotEmpty(t, code)
+
+	handler := snap.NewHandler(ctx, db, t.TempDir(), 100)
+	protocol := handler.MakeProtocol()
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- protocol.Run(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), remote)
+	}()
+
+	req := &snap.GetByteCodesPacket{ID: 7, Hashes: []common.Hash{{1}, codeHash, trie.EmptyCodeHash}, Bytes: 1 << 20}
+	require.NoError(t, p2p.Send(local, snap.GetByteCodesMsg, req))
+	require.NoError(t, p2p.ExpectMsg(local, snap.ByteCodesMsg, &snap.ByteCodesPacket{ID: 7, Codes: [][]byte{code, {}}}))
+
+	require.NoError(t, p2p.Send(local, snap.GetTrieNodesMsg, &snap.GetTrieNodesPacket{ID: 8, Root: common.Hash{1}}))
+	require.NoError(t, p2p.ExpectMsg(local, snap.TrieNodesMsg, &snap.TrieNodesPacket{ID: 8}))
+
+	require.NoError(t, p2p.Send(local, 0x20, []uint{}))
+	require.Error(t, <-errc, "invalid message code")
+}
+
+func TestServeTrieNodes(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	ctx, tmpDir := context.Background(), t.TempDir()
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	head := rawdb.ReadCurrentHeader(tx)
+	for _, blockNum := range []uint64{head.Number.Uint64(), 4} {
+		root := rawdb.ReadHeaderByNumber(tx, blockNum).Root
+		accs, _, err := snap.ServiceGetAccountRangeQuery(ctx, tx, &snap.GetAccountRangePacket{Root: root, Limit: maxHash, Bytes: 1 << 20}, tmpDir)
+		require.NoError(t, err)
+		var withStorage common.Hash
+		var storageRoot common.Hash
+		for _, a := range accs {
+			if acc := decodeSlimAccount(t, a.Body); acc.Root != trie.EmptyRoot {
+				withStorage, storageRoot = a.Hash, acc.Root
+			}
+		}
+		require.NotEqual(t, common.Hash{}, withStorage, "the token is deployed in block 3")
+
+		// the roots of the account trie and of a storage trie, a child of the account root, and a missing node
+		child := []byte{0x10 | accs[0].Hash[0]>>4} // compact encoding of the first nibble of the first account
+		nodes, err := snap.ServiceGetTrieNodesQuery(ctx, tx, &snap.GetTrieNodesPacket{Root: root, Paths: []snap.TrieNodePathSet{
+			{{}}, {withStorage[:], {}}, {child}, {common.Hash{0xff, 0xff}.Bytes(), {}},
+		}, Bytes: 1 << 20}, tmpDir)
+		require.NoError(t, err)
+		require.Len(t, nodes, 4)
+		require.Equal(t, root, crypto.Keccak256Hash(nodes[0]), "block %d", blockNum)
+		require.Equal(t, storageRoot, crypto.Keccak256Hash(nodes[1]), "block %d", blockNum)
+		childHash := crypto.Keccak256(nodes[2])
+		require.True(t, bytes.Contains(nodes[0], childHash), "block %d", blockNum)
+		require.Empty(t, nodes[3])
+	}
+}
+
+func TestHandlerState(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // ends the state kept by the handler, and its read transaction
+	tmpDir := t.TempDir()
+	var root common.Hash
+	var accs []*snap.AccountData
+	var nodes [][]byte
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) (err error) {
+		root = rawdb.ReadHeaderByNumber(tx, 4).Root
+		if accs, _, err = snap.ServiceGetAccountRangeQuery(ctx, tx, &snap.GetAccountRangePacket{Root: root, Limit: maxHash, Bytes: 1 << 20}, tmpDir); err != nil {
+			return err
+		}
+		nodes, err = snap.ServiceGetTrieNodesQuery(ctx, tx, &snap.GetTrieNodesPacket{Root: root, Paths: []snap.TrieNodePathSet{{{}}}, Bytes: 1 << 20}, tmpDir)
+		return err
+	}))
+
+	protocol := snap.NewHandler(ctx, db, tmpDir, 0).MakeProtocol()
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	go protocol.Run(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), remote) //nolint:errcheck
+
+	// the state unwound for the first request is kept for the next ones
+	for id := uint64(1); id <= 2; id++ {
+		require.NoError(t, p2p.Send(local, snap.GetAccountRangeMsg, &snap.GetAccountRangePacket{ID: id, Root: root, Limit: maxHash, Bytes: 1 << 20}))
+		msg, err := local.ReadMsg()
+		require.NoError(t, err)
+		var res snap.AccountRangePacket
+		require.NoError(t, msg.Decode(&res))
+		require.Equal(t, id, res.ID)
+		require.Equal(t, len(accs), len(res.Accounts))
+	}
+	require.NoError(t, p2p.Send(local, snap.GetTrieNodesMsg, &snap.GetTrieNodesPacket{ID: 3, Root: root, Paths: []snap.TrieNodePathSet{{{}}}, Bytes: 1 << 20}))
+	require.NoError(t, p2p.ExpectMsg(local, snap.TrieNodesMsg, &snap.TrieNodesPacket{ID: 3, Nodes: nodes}))
+}
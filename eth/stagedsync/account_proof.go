@@ -0,0 +1,95 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// AccountProof is the Merkle proof of an account, and of some of its storage slots, in the state trie of a block.
+type AccountProof struct {
+	Account *accounts.Account // nil if the account doesn't exist
+	Proof   [][]byte          // the nodes of the state trie on the path of the account
+	Storage []StorageProof
+}
+
+// StorageProof is the Merkle proof of a storage slot in the storage trie of an account.
+type StorageProof struct {
+	Key   common.Hash
+	Value []byte   // without leading zeroes, nil if the slot is empty
+	Proof [][]byte // the nodes of the storage trie on the path of the slot
+}
+
+// GenerateAccountProof returns the proof of the account and of the storage keys in the state trie after the block of
+// the header, loaded from the hashed state returned by HashedStateAt. maxUnwind and maxMemory are the ones of
+// HashedStateAt.
+func GenerateAccountProof(ctx context.Context, tx kv.Tx, header *types.Header, address common.Address, storageKeys []common.Hash, tmpDir string, maxUnwind uint64, maxMemory datasize.ByteSize) (*AccountProof, error) {
+	const logPrefix = "AccountProof"
+	rl := trie.NewRetainList(0)
+	batch, err := HashedStateAt(ctx, tx, header, tmpDir, maxUnwind, maxMemory, rl)
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Rollback()
+
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	var incarnation uint64
+	enc, err := batch.GetOne(kv.HashedAccounts, addrHash[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) > 0 {
+		if incarnation, err = accounts.DecodeIncarnationFromStorage(enc); err != nil {
+			return nil, err
+		}
+	}
+	// the trie keeps the storage of the accounts by incarnation, its paths don't
+	storagePaths := make([][]byte, len(storageKeys))
+	rl.AddKey(addrHash[:])
+	for i, key := range storageKeys {
+		keyHash, err := common.HashData(key[:])
+		if err != nil {
+			return nil, err
+		}
+		rl.AddKey(append(dbutils.GenerateStoragePrefix(addrHash[:], incarnation), keyHash[:]...))
+		storagePaths[i] = append(addrHash[:common.HashLength:common.HashLength], keyHash[:]...)
+	}
+	loader := trie.NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.LoadTrie(batch, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if t.Hash() != header.Root {
+		return nil, fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", header.Number.Uint64(), t.Hash(), header.Root)
+	}
+
+	res := &AccountProof{Storage: make([]StorageProof, len(storageKeys))}
+	if res.Proof, err = t.Prove(addrHash[:], 0, false); err != nil {
+		return nil, err
+	}
+	res.Account, _ = t.GetAccount(addrHash[:])
+	for i, key := range storageKeys {
+		res.Storage[i].Key = key
+		if res.Account == nil {
+			continue
+		}
+		if res.Storage[i].Proof, err = t.Prove(storagePaths[i], 2*common.HashLength, true); err != nil {
+			return nil, err
+		}
+		res.Storage[i].Value, _ = t.Get(storagePaths[i])
+	}
+	return res, nil
+}
@@ -0,0 +1,406 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/c2h5oh/datasize"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	commonold "github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/olddb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// BackfillKinds - which data deleted by pruning Backfill writes again
+type BackfillKinds struct {
+	History    bool // change sets and history index
+	Receipts   bool // receipts, logs and logs index
+	CallTraces bool // call traces index
+}
+
+// Backfill - write again the data of blocks from..to deleted by pruning, and add them to the indices. The state of
+// pruned blocks can't be read from the history, so blocks are re-executed from genesis on a temporary copy of the state
+// in tmpdir. Pruning of the backfilled kinds is changed to keep the blocks, if it would delete them again.
+func Backfill(ctx context.Context, cfg ExecuteBlockCfg, genesis *core.Genesis, from, to uint64, kinds BackfillKinds) (err error) {
+	const logPrefix = "backfill"
+	quit := ctx.Done()
+	if from == 0 {
+		from = 1 // genesis has no receipts nor changes
+	}
+
+	tx, err := cfg.db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { tx.Rollback() }()
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if from > to || to > executed {
+		return fmt.Errorf("blocks %d-%d can't be backfilled, executed blocks: 1-%d", from, to, executed)
+	}
+	genesisHash, err := rawdb.ReadCanonicalHash(tx, 0)
+	if err != nil {
+		return err
+	}
+
+	stateDir := filepath.Join(cfg.tmpdir, "backfill")
+	defer os.RemoveAll(stateDir)
+	stateDB, err := mdbx.NewMDBX(log.New()).Label(kv.ChainDB).Path(stateDir).Open()
+	if err != nil {
+		return err
+	}
+	defer stateDB.Close()
+	stateTx, err := stateDB.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { stateTx.Rollback() }()
+	genesisBlock, _, err := genesis.WriteGenesisState(stateTx)
+	if err != nil {
+		return err
+	}
+	if genesisBlock.Hash() != genesisHash {
+		return fmt.Errorf("genesis %x doesn't match genesis of the database %x", genesisBlock.Hash(), genesisHash)
+	}
+
+	batch := olddb.NewHashBatch(stateTx, quit, cfg.tmpdir)
+	defer func() { batch.Rollback() }()
+	effectiveEngine := cfg.engine
+	if asyncEngine, ok := effectiveEngine.(consensus.AsyncEngine); ok {
+		asyncEngine = asyncEngine.WithExecutionContext(ctx)
+		effectiveEngine = asyncEngine.(consensus.Engine)
+	}
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	var currentStateGas uint64
+	gasState := uint64(cfg.batchSize) * uint64(datasize.KB) * 2
+
+	log.Info(fmt.Sprintf("[%s] Re-executing blocks", logPrefix), "from", 1, "to", to, "backfill_from", from)
+	for blockNum := uint64(1); blockNum <= to; blockNum++ {
+		if err = libcommon.Stopped(quit); err != nil {
+			return err
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		block, _, err := cfg.blockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			return fmt.Errorf("block %d not found", blockNum)
+		}
+		var contractHasTEVM func(contractHash commonold.Hash) (bool, error)
+		if cfg.vmConfig.EnableTEMV {
+			contractHasTEVM = ethdb.GetHasTEVM(tx)
+		}
+		write := blockNum >= from
+		if err = executeBlock(block, putTx{tx}, batch, cfg, *cfg.vmConfig, write && kinds.History, write && kinds.Receipts, write && kinds.CallTraces, contractHasTEVM, true, effectiveEngine, nil, nil, nil); err != nil {
+			return fmt.Errorf("block %d: %w", blockNum, err)
+		}
+
+		currentStateGas += block.GasUsed()
+		if currentStateGas >= gasState {
+			currentStateGas = 0
+			if err = batch.Commit(); err != nil {
+				return err
+			}
+			if err = stateTx.Commit(); err != nil {
+				return err
+			}
+			if err = tx.Commit(); err != nil {
+				return err
+			}
+			if stateTx, err = stateDB.BeginRw(ctx); err != nil {
+				return err
+			}
+			if tx, err = cfg.db.BeginRw(ctx); err != nil {
+				return err
+			}
+			batch = olddb.NewHashBatch(stateTx, quit, cfg.tmpdir)
+		}
+		select {
+		default:
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "block", blockNum, "to", to)
+		}
+	}
+
+	if kinds.History {
+		for _, csTable := range []string{kv.AccountChangeSet, kv.StorageChangeSet} {
+			index := newBackfillIndex(logPrefix, changeset.Mapper[csTable].IndexBucket, false, cfg.tmpdir)
+			defer index.close()
+			if err = changeset.ForRange(tx, csTable, from, to+1, func(blockNum uint64, k, _ []byte) error {
+				return index.add(dbutils.CompositeKeyWithoutIncarnation(k), blockNum)
+			}); err != nil {
+				return err
+			}
+			if err = index.load(tx, quit); err != nil {
+				return err
+			}
+		}
+	}
+	if kinds.Receipts {
+		if err = backfillLogIndex(logPrefix, tx, from, to, cfg.tmpdir, quit); err != nil {
+			return err
+		}
+	}
+	if kinds.CallTraces {
+		if err = backfillCallTracesIndex(logPrefix, tx, from, to, cfg.tmpdir, quit); err != nil {
+			return err
+		}
+	}
+
+	// keep the backfilled blocks from being deleted by the next pruning
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	keep := func(amount prune.BlockAmount, backfilled bool) prune.BlockAmount {
+		if backfilled && amount.Enabled() && amount.PruneTo(executed) > from {
+			return prune.Before(from)
+		}
+		return amount
+	}
+	changed := pm
+	changed.History = keep(pm.History, kinds.History)
+	changed.Receipts = keep(pm.Receipts, kinds.Receipts)
+	changed.CallTraces = keep(pm.CallTraces, kinds.CallTraces)
+	if changed != pm {
+		if err = prune.Override(tx, changed); err != nil {
+			return err
+		}
+		log.Warn(fmt.Sprintf("[%s] Changed pruning to keep backfilled blocks", logPrefix), "from", pm.String(), "to", changed.String())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[%s] Done", logPrefix), "from", from, "to", to)
+	return nil
+}
+
+// backfillLogIndex - add logs of blocks from..to to the logs index
+func backfillLogIndex(logPrefix string, tx kv.RwTx, from, to uint64, tmpdir string, quit <-chan struct{}) error {
+	topics := newBackfillIndex(logPrefix, kv.LogTopicIndex, true, tmpdir)
+	defer topics.close()
+	addrs := newBackfillIndex(logPrefix, kv.LogAddressIndex, true, tmpdir)
+	defer addrs.close()
+
+	logs, err := tx.Cursor(kv.Log)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	reader := bytes.NewReader(nil)
+	for k, v, err := logs.Seek(dbutils.LogKey(from, 0)); k != nil; k, v, err = logs.Next() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k[:8])
+		if blockNum > to {
+			break
+		}
+		var ll types.Logs
+		reader.Reset(v)
+		if err := cbor.Unmarshal(&ll, reader); err != nil {
+			return fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, blockNum)
+		}
+		for _, l := range ll {
+			for _, topic := range l.Topics {
+				if err := topics.add(topic.Bytes(), blockNum); err != nil {
+					return err
+				}
+			}
+			if err := addrs.add(l.Address.Bytes(), blockNum); err != nil {
+				return err
+			}
+		}
+	}
+	if err := topics.load(tx, quit); err != nil {
+		return err
+	}
+	return addrs.load(tx, quit)
+}
+
+// backfillCallTracesIndex - add call traces of blocks from..to to the call traces index
+func backfillCallTracesIndex(logPrefix string, tx kv.RwTx, from, to uint64, tmpdir string, quit <-chan struct{}) error {
+	froms := newBackfillIndex(logPrefix, kv.CallFromIndex, false, tmpdir)
+	defer froms.close()
+	tos := newBackfillIndex(logPrefix, kv.CallToIndex, false, tmpdir)
+	defer tos.close()
+
+	traces, err := tx.Cursor(kv.CallTraceSet)
+	if err != nil {
+		return err
+	}
+	defer traces.Close()
+	for k, v, err := traces.Seek(dbutils.EncodeBlockNumber(from)); k != nil; k, v, err = traces.Next() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum > to {
+			break
+		}
+		if len(v) != length.Addr+1 {
+			return fmt.Errorf("wrong size of value in CallTraceSet: %x (size %d)", v, len(v))
+		}
+		if v[length.Addr]&1 > 0 {
+			if err := froms.add(v[:length.Addr], blockNum); err != nil {
+				return err
+			}
+		}
+		if v[length.Addr]&2 > 0 {
+			if err := tos.add(v[:length.Addr], blockNum); err != nil {
+				return err
+			}
+		}
+	}
+	if err := froms.load(tx, quit); err != nil {
+		return err
+	}
+	return tos.load(tx, quit)
+}
+
+// backfillIndex - blocks of the keys of index table, collected to be added to it. Unlike the stages, which append
+// blocks to the last chunk of a key, all chunks of the key are rewritten: backfilled blocks are older than the indexed.
+type backfillIndex struct {
+	table     string
+	bits32    bool // chunks of the logs index have 4 bytes suffix and 32 bits bitmaps
+	bitmaps   map[string]*roaring64.Bitmap
+	collector *etl.Collector
+}
+
+func newBackfillIndex(logPrefix, table string, bits32 bool, tmpdir string) *backfillIndex {
+	return &backfillIndex{
+		table:     table,
+		bits32:    bits32,
+		bitmaps:   map[string]*roaring64.Bitmap{},
+		collector: etl.NewCollector(logPrefix, tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize)),
+	}
+}
+
+func (i *backfillIndex) close() { i.collector.Close() }
+
+func (i *backfillIndex) add(k []byte, blockNum uint64) error {
+	m, ok := i.bitmaps[string(k)]
+	if !ok {
+		m = roaring64.New()
+		i.bitmaps[string(k)] = m
+	}
+	m.Add(blockNum)
+	if len(i.bitmaps)%1024 == 0 && needFlush64(i.bitmaps, bitmapsBufLimit) {
+		if err := flushBitmaps64(i.collector, i.bitmaps); err != nil {
+			return err
+		}
+		i.bitmaps = map[string]*roaring64.Bitmap{}
+	}
+	return nil
+}
+
+func (i *backfillIndex) load(tx kv.RwTx, quit <-chan struct{}) error {
+	if err := flushBitmaps64(i.collector, i.bitmaps); err != nil {
+		return err
+	}
+	i.bitmaps = map[string]*roaring64.Bitmap{}
+	buf := bytes.NewBuffer(nil)
+	write := func(chunkKey []byte, chunk io.WriterTo) error {
+		buf.Reset()
+		if _, err := chunk.WriteTo(buf); err != nil {
+			return err
+		}
+		return tx.Put(i.table, chunkKey, buf.Bytes())
+	}
+	// nothing is loaded by etl: chunks are merged and written here
+	return i.collector.Load(tx, "", func(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		m := roaring64.New()
+		if _, err := m.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+		if i.bits32 {
+			m32 := roaring.New()
+			for it := m.Iterator(); it.HasNext(); {
+				m32.Add(uint32(it.Next()))
+			}
+			existing, err := bitmapdb.Get(tx, i.table, k, 0, math.MaxUint32)
+			if err != nil {
+				return err
+			}
+			m32.Or(existing)
+			if err = deleteChunks(tx, i.table, k, 4); err != nil {
+				return err
+			}
+			return bitmapdb.WalkChunkWithKeys(k, m32, bitmapdb.ChunkLimit, func(chunkKey []byte, chunk *roaring.Bitmap) error {
+				return write(chunkKey, chunk)
+			})
+		}
+		existing, err := bitmapdb.Get64(tx, i.table, k, 0, math.MaxUint64)
+		if err != nil {
+			return err
+		}
+		m.Or(existing)
+		if err = deleteChunks(tx, i.table, k, 8); err != nil {
+			return err
+		}
+		return bitmapdb.WalkChunkWithKeys64(k, m, bitmapdb.ChunkLimit, func(chunkKey []byte, chunk *roaring64.Bitmap) error {
+			return write(chunkKey, chunk)
+		})
+	}, etl.TransformArgs{Quit: quit})
+}
+
+// deleteChunks - delete all chunks of key in index table
+func deleteChunks(tx kv.RwTx, table string, key []byte, suffixLen int) error {
+	c, err := tx.RwCursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(key); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) || len(k) != len(key)+suffixLen {
+			break
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putTx - writes appended data with Put: backfilled blocks are before the blocks already in the tables
+type putTx struct {
+	kv.RwTx
+}
+
+func (tx putTx) Append(table string, k, v []byte) error    { return tx.Put(table, k, v) }
+func (tx putTx) AppendDup(table string, k, v []byte) error { return tx.Put(table, k, v) }
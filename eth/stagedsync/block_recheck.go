@@ -0,0 +1,178 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// BlockRecheckCfg is the configuration of RecheckBlock.
+type BlockRecheckCfg struct {
+	ChainConfig *params.ChainConfig
+	Engine      consensus.Engine
+	BlockReader services.FullBlockReader
+	VMConfig    vm.Config
+	TmpDir      string
+}
+
+// RecheckBlock executes the block again, against the plain state history, and compares the results with the ones
+// stored by the execution: the receipts with the header and with the stored receipts (if they aren't pruned), the
+// changes of the state with the change sets of the block, and the state root, computed from the hashed state of the
+// parent returned by HashedStateAt and the changes of the execution, with the root of the header. A mismatch means
+// that the DB is corrupted, or that the execution of the block changed.
+func RecheckBlock(ctx context.Context, tx kv.Tx, blockNum uint64, cfg BlockRecheckCfg) error {
+	const logPrefix = "Recheck"
+	hash, err := cfg.BlockReader.CanonicalHash(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	block, _, err := cfg.BlockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block %d not found", blockNum)
+	}
+	parent, err := cfg.BlockReader.Header(ctx, tx, block.ParentHash(), blockNum-1)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return fmt.Errorf("parent of block %d not found", blockNum)
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, _ := cfg.BlockReader.Header(ctx, tx, hash, number)
+		return h
+	}
+	// the hashed state of the block and the epochs written by the consensus engine go to the batch, dropped afterwards
+	rl := trie.NewRetainList(0)
+	batch, err := HashedStateAt(ctx, tx, parent, cfg.TmpDir, 0, 0, rl)
+	if err != nil {
+		return err
+	}
+	defer batch.Rollback()
+	writer := state.NewDbStateWriter(retainingPutDel{RwTx: batch, rl: rl}, blockNum)
+	vmConfig := cfg.VMConfig
+	receipts, _, err := core.ExecuteBlockEphemerally(cfg.ChainConfig, &vmConfig, getHeader, cfg.Engine, block, state.NewPlainState(tx, blockNum), writer,
+		epochReader{tx: batch}, chainReader{config: cfg.ChainConfig, tx: batch, blockReader: cfg.BlockReader}, nil)
+	if err != nil {
+		return err
+	}
+
+	if stored := rawdb.ReadRawReceipts(tx, blockNum); stored != nil {
+		if err := compareReceipts(receipts, stored); err != nil {
+			return err
+		}
+	}
+	accountChanges, err := writer.ChangeSetWriter().GetAccountChanges()
+	if err != nil {
+		return err
+	}
+	if err := compareChangeSet(tx, kv.AccountChangeSet, blockNum, accountChanges); err != nil {
+		return err
+	}
+	storageChanges, err := writer.ChangeSetWriter().GetStorageChanges()
+	if err != nil {
+		return err
+	}
+	if err := compareChangeSet(tx, kv.StorageChangeSet, blockNum, storageChanges); err != nil {
+		return err
+	}
+
+	loader := trie.NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(rl, nil, nil, false); err != nil {
+		return err
+	}
+	root, err := loader.CalcTrieRoot(batch, nil, ctx.Done())
+	if err != nil {
+		return err
+	}
+	if root != block.Root() {
+		return fmt.Errorf("state root %x, expected (from header): %x", root, block.Root())
+	}
+	return nil
+}
+
+// retainingPutDel adds the keys of the hashed state written to rl, as the intermediate hashes on their paths are stale
+type retainingPutDel struct {
+	kv.RwTx
+	rl *trie.RetainList
+}
+
+func (w retainingPutDel) Put(table string, k, v []byte) error {
+	if table == kv.HashedAccounts || table == kv.HashedStorage {
+		w.rl.AddKeyWithMarker(k, false)
+	}
+	return w.RwTx.Put(table, k, v)
+}
+
+func (w retainingPutDel) Delete(table string, k, v []byte) error {
+	if table == kv.HashedAccounts || table == kv.HashedStorage {
+		w.rl.AddKeyWithMarker(k, true)
+	}
+	return w.RwTx.Delete(table, k, v)
+}
+
+// compareReceipts compares the consensus fields of the receipts of the execution with the stored ones
+func compareReceipts(receipts, stored types.Receipts) error {
+	if len(receipts) != len(stored) {
+		return fmt.Errorf("%d receipts, %d stored", len(receipts), len(stored))
+	}
+	for i, r := range receipts {
+		s := stored[i]
+		if r.Status != s.Status || r.CumulativeGasUsed != s.CumulativeGasUsed || len(r.Logs) != len(s.Logs) {
+			return fmt.Errorf("receipt %d: status %d, cumulative gas %d, %d logs, stored: status %d, cumulative gas %d, %d logs",
+				i, r.Status, r.CumulativeGasUsed, len(r.Logs), s.Status, s.CumulativeGasUsed, len(s.Logs))
+		}
+		for j, l := range r.Logs {
+			sl := s.Logs[j]
+			if l.Address != sl.Address || !bytes.Equal(l.Data, sl.Data) || len(l.Topics) != len(sl.Topics) {
+				return fmt.Errorf("receipt %d: log %d differs from the stored one", i, j)
+			}
+			for k := range l.Topics {
+				if l.Topics[k] != sl.Topics[k] {
+					return fmt.Errorf("receipt %d: log %d differs from the stored one", i, j)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compareChangeSet compares the changes of the state by the execution of the block with its stored change set
+func compareChangeSet(tx kv.Tx, bucket string, blockNum uint64, expected *changeset.ChangeSet) error {
+	sort.Sort(expected)
+	i := 0
+	if err := changeset.ForPrefix(tx, bucket, dbutils.EncodeBlockNumber(blockNum), func(_ uint64, k, v []byte) error {
+		if i >= expected.Len() {
+			return fmt.Errorf("%s: unexpected change of %x: %x", bucket, k, v)
+		}
+		c := expected.Changes[i]
+		if !bytes.Equal(c.Key, k) || !bytes.Equal(c.Value, v) {
+			return fmt.Errorf("%s: stored change of %x: %x, executed change of %x: %x", bucket, k, v, c.Key, c.Value)
+		}
+		i++
+		return nil
+	}); err != nil {
+		return err
+	}
+	if i < expected.Len() {
+		return fmt.Errorf("%s: missing change of %x: %x", bucket, expected.Changes[i].Key, expected.Changes[i].Value)
+	}
+	return nil
+}
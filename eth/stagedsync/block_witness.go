@@ -0,0 +1,96 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// BlockWitnessCfg is the configuration of GenerateBlockWitness.
+type BlockWitnessCfg struct {
+	ChainConfig *params.ChainConfig
+	Engine      consensus.Engine
+	BlockReader services.FullBlockReader
+	TmpDir      string
+	MaxUnwind   uint64 // the oldest parent the witness can be generated for, in blocks behind the trie, 0 for no limit
+}
+
+// GenerateBlockWitness writes to w the witness of the block for stateless clients: the nodes of the state trie of
+// its parent on the paths of the accounts and storage slots read by the execution of the block, hashes instead of
+// the other nodes, and the codes read. The block is executed again, against the plain state history, and the trie
+// of the parent is loaded from the hashed state returned by HashedStateAt.
+func GenerateBlockWitness(ctx context.Context, tx kv.Tx, block *types.Block, cfg BlockWitnessCfg, w io.Writer) (*trie.BlockWitnessStats, error) {
+	const logPrefix = "BlockWitness"
+	quit := ctx.Done()
+	if block.NumberU64() == 0 {
+		return nil, fmt.Errorf("the genesis block has no witness")
+	}
+	parentNum := block.NumberU64() - 1
+	parent, err := cfg.BlockReader.Header(ctx, tx, block.ParentHash(), parentNum)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("parent %d of block %d not found", parentNum, block.NumberU64())
+	}
+	// the trie keeps the storage of the accounts by incarnation, the witness doesn't
+	loadList, witnessList := trie.NewRetainList(0), trie.NewRetainList(0)
+	batch, err := HashedStateAt(ctx, tx, parent, cfg.TmpDir, cfg.MaxUnwind, 0, loadList)
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Rollback()
+
+	reader := state.NewWitnessReader(state.NewPlainState(tx, block.NumberU64()))
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, _ := cfg.BlockReader.Header(ctx, tx, hash, number)
+		return h
+	}
+	if _, _, err := core.ExecuteBlockEphemerally(cfg.ChainConfig, &vm.Config{}, getHeader, cfg.Engine, block, reader, state.NewNoopWriter(),
+		epochReader{tx: batch}, chainReader{config: cfg.ChainConfig, tx: batch, blockReader: cfg.BlockReader}, nil); err != nil {
+		return nil, fmt.Errorf("executing block %d: %w", block.NumberU64(), err)
+	}
+
+	for _, addrHash := range reader.Accounts() {
+		loadList.AddKey(addrHash[:])
+		witnessList.AddKey(addrHash[:])
+	}
+	for _, k := range reader.Storage() {
+		loadList.AddKey(k)
+		witnessList.AddKey(append(k[:common.HashLength:common.HashLength], k[common.HashLength+common.IncarnationLength:]...))
+	}
+	loader := trie.NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(loadList, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.LoadTrie(batch, quit)
+	if err != nil {
+		return nil, err
+	}
+	if t.Hash() != parent.Root {
+		return nil, fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", parentNum, t.Hash(), parent.Root)
+	}
+	for addrHash, code := range reader.Codes() {
+		if err := t.UpdateAccountCode(addrHash[:], code); err != nil {
+			return nil, err
+		}
+		witnessList.AddCodeTouch(crypto.Keccak256Hash(code))
+	}
+	witness, err := t.ExtractWitness(false, witnessList)
+	if err != nil {
+		return nil, err
+	}
+	return witness.WriteInto(w)
+}
@@ -0,0 +1,154 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// commitmentStream is the streamed mode of the commitment: the keys of the state changes written by the execution
+// are hashed on a goroutine of its own while the next blocks are executed, and the changes are promoted to the
+// hashed state whenever the execution saves its progress - instead of the HashState stage reading them back from
+// the change sets and hashing them afterwards. The trie root is then computed from the hashed state as usual.
+type commitmentStream struct {
+	keys   chan []byte
+	hashed chan hashedKeys
+}
+
+type hashedKeys struct {
+	keys map[string][]byte // plain key -> hashed key
+	err  error
+}
+
+func newCommitmentStream() *commitmentStream {
+	c := &commitmentStream{}
+	c.start()
+	return c
+}
+
+func (c *commitmentStream) start() {
+	c.keys = make(chan []byte, 4096)
+	c.hashed = make(chan hashedKeys, 1)
+	go func(keys <-chan []byte, hashed chan<- hashedKeys) {
+		res := hashedKeys{keys: map[string][]byte{}}
+		for k := range keys {
+			if _, ok := res.keys[string(k)]; ok || res.err != nil {
+				continue
+			}
+			res.keys[string(k)], res.err = transformPlainStateKey(k)
+		}
+		hashed <- res
+	}(c.keys, c.hashed)
+}
+
+// close stops the hashing goroutine, dropping the changes which haven't been promoted.
+func (c *commitmentStream) close() {
+	close(c.keys)
+	<-c.hashed
+}
+
+// promote writes the values the changed keys have in the plain state to the hashed state, and saves the progress
+// of the HashState stage. The changes have to be written to the plain state of tx by then.
+func (c *commitmentStream) promote(ctx context.Context, logPrefix string, tx kv.RwTx, tmpDir string, blockNum uint64) error {
+	close(c.keys)
+	res := <-c.hashed
+	defer c.start()
+	if res.err != nil {
+		return res.err
+	}
+
+	accCollector := etl.NewCollector(logPrefix, tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer accCollector.Close()
+	storageCollector := etl.NewCollector(logPrefix, tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer storageCollector.Close()
+	codeCollector := etl.NewCollector(logPrefix, tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer codeCollector.Close()
+
+	for plainKey, hashedKey := range res.keys {
+		value, err := tx.GetOne(kv.PlainState, []byte(plainKey))
+		if err != nil {
+			return err
+		}
+		if len(plainKey) != length.Addr {
+			if err := storageCollector.Collect(hashedKey, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := accCollector.Collect(hashedKey, value); err != nil {
+			return err
+		}
+		// the same as the code promotion of the HashState stage
+		if len(value) == 0 {
+			continue
+		}
+		incarnation, err := accounts.DecodeIncarnationFromStorage(value)
+		if err != nil {
+			return err
+		}
+		if incarnation == 0 {
+			continue
+		}
+		codeHash, err := tx.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix([]byte(plainKey), incarnation))
+		if err != nil {
+			return fmt.Errorf("code hash of %x, inc %d: %w", plainKey, incarnation, err)
+		}
+		if codeHash == nil {
+			continue
+		}
+		if err := codeCollector.Collect(dbutils.GenerateStoragePrefix(hashedKey, incarnation), codeHash); err != nil {
+			return err
+		}
+	}
+
+	args := etl.TransformArgs{Quit: ctx.Done()}
+	if err := accCollector.Load(tx, kv.HashedAccounts, etl.IdentityLoadFunc, args); err != nil {
+		return err
+	}
+	if err := storageCollector.Load(tx, kv.HashedStorage, etl.IdentityLoadFunc, args); err != nil {
+		return err
+	}
+	if err := codeCollector.Load(tx, kv.ContractCode, etl.IdentityLoadFunc, args); err != nil {
+		return err
+	}
+	return stages.SaveStageProgress(tx, stages.HashState, blockNum)
+}
+
+// commitmentStreamWriter hands the keys written to the plain state over to the commitment stream.
+type commitmentStreamWriter struct {
+	state.WriterWithChangeSets
+	stream *commitmentStream
+}
+
+func (w *commitmentStreamWriter) ChangeSetWriter() *state.ChangeSetWriter {
+	if hasChangeSet, ok := w.WriterWithChangeSets.(HasChangeSetWriter); ok {
+		return hasChangeSet.ChangeSetWriter()
+	}
+	return nil
+}
+
+func (w *commitmentStreamWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	w.stream.keys <- common.CopyBytes(address[:])
+	return w.WriterWithChangeSets.UpdateAccountData(address, original, account)
+}
+
+func (w *commitmentStreamWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	w.stream.keys <- common.CopyBytes(address[:])
+	return w.WriterWithChangeSets.DeleteAccount(address, original)
+}
+
+func (w *commitmentStreamWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	if *original != *value {
+		w.stream.keys <- dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes())
+	}
+	return w.WriterWithChangeSets.WriteAccountStorage(address, incarnation, key, original, value)
+}
@@ -0,0 +1,45 @@
+package stagedsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitmentStream(t *testing.T) {
+	ctx := context.Background()
+	db1, tx1 := memdb.NewTestTx(t)
+	db2, tx2 := memdb.NewTestTx(t)
+
+	generateBlocks(t, 1, 50, plainWriterGen(tx1), changeCodeWithIncarnations)
+	generateBlocks(t, 1, 50, plainWriterGen(tx2), changeCodeWithIncarnations)
+	require.NoError(t, PromoteHashedStateCleanly("logPrefix", tx1, StageHashStateCfg(db1, t.TempDir()), ctx))
+	require.NoError(t, PromoteHashedStateCleanly("logPrefix", tx2, StageHashStateCfg(db2, t.TempDir()), ctx))
+
+	// batch mode, promoting twice
+	generateBlocks(t, 51, 25, plainWriterGen(tx1), changeCodeWithIncarnations)
+	require.NoError(t, promoteHashedStateIncrementally("logPrefix", &StageState{BlockNumber: 50}, 50, 75, tx1, StageHashStateCfg(db1, t.TempDir()), nil))
+	generateBlocks(t, 76, 25, plainWriterGen(tx1), changeCodeWithIncarnations)
+	require.NoError(t, promoteHashedStateIncrementally("logPrefix", &StageState{BlockNumber: 75}, 75, 100, tx1, StageHashStateCfg(db1, t.TempDir()), nil))
+
+	// stream mode, promoting at the same blocks
+	commitment := newCommitmentStream()
+	defer commitment.close()
+	streamWriterGen := func(blockNum uint64) state.WriterWithChangeSets {
+		return &commitmentStreamWriter{WriterWithChangeSets: state.NewPlainStateWriter(tx2, tx2, blockNum), stream: commitment}
+	}
+	generateBlocks(t, 51, 25, streamWriterGen, changeCodeWithIncarnations)
+	require.NoError(t, commitment.promote(ctx, "logPrefix", tx2, t.TempDir(), 75))
+	generateBlocks(t, 76, 25, streamWriterGen, changeCodeWithIncarnations)
+	require.NoError(t, commitment.promote(ctx, "logPrefix", tx2, t.TempDir(), 100))
+
+	compareCurrentState(t, tx1, tx2, kv.PlainState, kv.HashedAccounts, kv.HashedStorage, kv.ContractCode)
+	progress, err := stages.GetStageProgress(tx2, stages.HashState)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), progress)
+}
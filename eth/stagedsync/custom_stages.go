@@ -0,0 +1,104 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// StageFactory makes a custom stage for a new staged sync. Its Forward and Unwind funcs run in the
+// transaction of the sync, e.g. a stage registered after Execution reads the change sets of the
+// blocks executed since its own progress, and deletes what it derived from unwound blocks.
+type StageFactory func(ctx context.Context) *Stage
+
+type customStage struct {
+	after   stages.SyncStage
+	factory StageFactory
+}
+
+var (
+	customStagesMu sync.RWMutex
+	customStages   []customStage
+)
+
+// RegisterStage adds the stage made by factory to the default stages (see WithCustomStages), right after
+// the stage after: it is run after it, and unwound and pruned before it. Stages registered after the
+// same stage run in the order of registration. The stage progress is stored by the ID of the stage,
+// which must not clash with other stages, like "com.example.my-indexer".
+// If factory is nil, it panics.
+func RegisterStage(after stages.SyncStage, factory StageFactory) {
+	customStagesMu.Lock()
+	defer customStagesMu.Unlock()
+	if factory == nil {
+		panic("stagedsync: RegisterStage factory is nil")
+	}
+	customStages = append(customStages, customStage{after: after, factory: factory})
+}
+
+// WithCustomStages returns the stages with the registered custom stages inserted, and the orders to
+// unwind and prune them. Stages registered after a stage missing from stagesList are left out.
+func WithCustomStages(ctx context.Context, stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) ([]*Stage, UnwindOrder, PruneOrder, error) {
+	customStagesMu.RLock()
+	registered := make([]customStage, len(customStages))
+	copy(registered, customStages)
+	customStagesMu.RUnlock()
+	if len(registered) == 0 {
+		return stagesList, unwindOrder, pruneOrder, nil
+	}
+
+	ids := map[stages.SyncStage]bool{}
+	for _, s := range stagesList {
+		ids[s.ID] = true
+	}
+	// custom stages following each stage, a custom stage may follow another custom stage
+	following := map[stages.SyncStage][]*Stage{}
+	for _, c := range registered {
+		s := c.factory(ctx)
+		if s == nil || s.ID == "" || s.Forward == nil {
+			return nil, nil, nil, fmt.Errorf("custom stage after %s: stage must have ID and Forward", c.after)
+		}
+		if ids[s.ID] {
+			return nil, nil, nil, fmt.Errorf("custom stage %s: duplicate stage ID", s.ID)
+		}
+		ids[s.ID] = true
+		following[c.after] = append(following[c.after], s)
+	}
+
+	var forward []*Stage
+	inForward := map[stages.SyncStage]bool{}
+	var addForward func(s *Stage)
+	addForward = func(s *Stage) {
+		forward = append(forward, s)
+		inForward[s.ID] = true
+		for _, c := range following[s.ID] {
+			addForward(c)
+		}
+	}
+	for _, s := range stagesList {
+		addForward(s)
+	}
+
+	// unwind and prune in reverse: the latest custom stage first, and all of them before their stage
+	var addBackward func(order []stages.SyncStage, id stages.SyncStage) []stages.SyncStage
+	addBackward = func(order []stages.SyncStage, id stages.SyncStage) []stages.SyncStage {
+		custom := following[id]
+		if !inForward[id] {
+			custom = nil
+		}
+		for i := len(custom) - 1; i >= 0; i-- {
+			order = addBackward(order, custom[i].ID)
+		}
+		return append(order, id)
+	}
+	var unwind UnwindOrder
+	for _, id := range unwindOrder {
+		unwind = addBackward(unwind, id)
+	}
+	var prune PruneOrder
+	for _, id := range pruneOrder {
+		prune = addBackward(prune, id)
+	}
+	return forward, unwind, prune, nil
+}
@@ -0,0 +1,64 @@
+package stagedsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomStages(t *testing.T) {
+	defer func(registered []customStage) { customStages = registered }(customStages)
+	customStages = nil
+
+	flow := make([]stages.SyncStage, 0)
+	unwound := false
+	stage := func(id stages.SyncStage) *Stage {
+		return &Stage{
+			ID: id,
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				flow = append(flow, id)
+				if id == stages.Senders && !unwound {
+					unwound = true
+					u.UnwindTo(500, common.Hash{})
+				}
+				return s.Update(tx, 1000)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				flow = append(flow, unwindOf(id))
+				return u.Done(tx)
+			},
+		}
+	}
+	indexer, indexer2, indexerOfIndexer := stages.SyncStage("com.example.indexer"), stages.SyncStage("com.example.indexer2"), stages.SyncStage("com.example.indexer-of-indexer")
+	RegisterStage(stages.Bodies, func(ctx context.Context) *Stage { return stage(indexer) })
+	RegisterStage(indexer, func(ctx context.Context) *Stage { return stage(indexerOfIndexer) })
+	RegisterStage(stages.Bodies, func(ctx context.Context) *Stage { return stage(indexer2) })
+	RegisterStage(stages.Execution, func(ctx context.Context) *Stage { return stage("com.example.unused") })
+
+	s, unwindOrder, pruneOrder, err := WithCustomStages(context.Background(),
+		[]*Stage{stage(stages.Headers), stage(stages.Bodies), stage(stages.Senders)},
+		UnwindOrder{stages.Senders, stages.Bodies, stages.Headers}, nil)
+	require.NoError(t, err)
+	require.Len(t, s, 6)
+	require.Empty(t, pruneOrder)
+	state := New(s, unwindOrder, pruneOrder)
+	db, tx := memdb.NewTestTx(t)
+	require.NoError(t, state.Run(db, tx, true))
+
+	expectedFlow := []stages.SyncStage{
+		stages.Headers, stages.Bodies, indexer, indexerOfIndexer, indexer2, stages.Senders,
+		unwindOf(stages.Senders), unwindOf(indexer2), unwindOf(indexerOfIndexer), unwindOf(indexer), unwindOf(stages.Bodies), unwindOf(stages.Headers),
+		stages.Headers, stages.Bodies, indexer, indexerOfIndexer, indexer2, stages.Senders,
+	}
+	assert.Equal(t, expectedFlow, flow)
+
+	RegisterStage(stages.Headers, func(ctx context.Context) *Stage { return stage(stages.Bodies) })
+	_, _, _, err = WithCustomStages(context.Background(), []*Stage{stage(stages.Headers), stage(stages.Bodies)}, nil, nil)
+	require.Error(t, err)
+}
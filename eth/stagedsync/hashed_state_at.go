@@ -0,0 +1,149 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// HashedStateAt returns a batch over tx holding the hashed state after the block of the header, unwound in memory by
+// the change sets if the database is ahead of the block. The intermediate hashes of the database are not unwound:
+// they are stale on the paths of the keys changed since the block, which are added to rl, so that the tries loaded
+// from the batch with rl are the ones of the block - the root of the header is checked this way. maxUnwind limits
+// the number of blocks unwound, and maxMemory the size of their change sets, which the batch holds about: 0 for no
+// limit. The batch has to be rolled back by the caller.
+func HashedStateAt(ctx context.Context, tx kv.Tx, header *types.Header, tmpDir string, maxUnwind uint64, maxMemory datasize.ByteSize, rl *trie.RetainList) (*memdb.MemoryMutation, error) {
+	const logPrefix = "HashedStateAt"
+	blockNum := header.Number.Uint64()
+	hashStateProgress, err := stages.GetStageProgress(tx, stages.HashState)
+	if err != nil {
+		return nil, err
+	}
+	trieProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNum > trieProgress || trieProgress > hashStateProgress {
+		return nil, fmt.Errorf("the trie of block %d is not available, the trie is at block %d", blockNum, trieProgress)
+	}
+	if maxUnwind > 0 && trieProgress-blockNum > maxUnwind {
+		return nil, fmt.Errorf("block %d is too old, the trie is at block %d and can only be unwound by %d blocks", blockNum, trieProgress, maxUnwind)
+	}
+	if blockNum < trieProgress {
+		if err := checkHistoryRetained(tx, blockNum); err != nil {
+			return nil, err
+		}
+		if maxMemory > 0 {
+			if err := checkChangeSetsSize(tx, blockNum, trieProgress, maxMemory); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	batch := memdb.NewMemoryBatch(tx)
+	if blockNum == trieProgress {
+		return batch, nil
+	}
+	if err := unwindHashedStateInMemory(logPrefix, batch, header, hashStateProgress, trieProgress, tmpDir, rl, ctx.Done()); err != nil {
+		batch.Rollback()
+		return nil, err
+	}
+	return batch, nil
+}
+
+// unwindHashedStateInMemory is the unwind of the HashState and IntermediateHashes stages, without writing the
+// intermediate hashes: the deletions of the etl loads can be lost on a memory batch, as its cursors don't find
+// the last key of a table once the last key of the database is deleted.
+func unwindHashedStateInMemory(logPrefix string, batch kv.RwTx, header *types.Header, hashStateProgress, trieProgress uint64, tmpDir string, rl *trie.RetainList, quit <-chan struct{}) error {
+	blockNum := header.Number.Uint64()
+	u := &UnwindState{ID: stages.HashState, UnwindPoint: blockNum}
+	if err := unwindHashStateStageImpl(logPrefix, u, &StageState{ID: stages.HashState, BlockNumber: hashStateProgress}, batch, StageHashStateCfg(nil, tmpDir), quit); err != nil {
+		return err
+	}
+
+	changed := trie.NewRetainList(0)
+	collect := func(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		changed.AddKeyWithMarker(k, len(v) == 0)
+		rl.AddKeyWithMarker(k, len(v) == 0)
+		return nil
+	}
+	p := NewHashPromoter(batch, quit)
+	p.TempDir = tmpDir
+	u = &UnwindState{ID: stages.IntermediateHashes, UnwindPoint: blockNum}
+	s := &StageState{ID: stages.IntermediateHashes, BlockNumber: trieProgress}
+	if err := p.Unwind(logPrefix, s, u, false /* storage */, collect); err != nil {
+		return err
+	}
+	if err := p.Unwind(logPrefix, s, u, true /* storage */, collect); err != nil {
+		return err
+	}
+	loader := trie.NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(changed, nil, nil, false); err != nil {
+		return err
+	}
+	hash, err := loader.CalcTrieRoot(batch, nil, quit)
+	if err != nil {
+		return err
+	}
+	if hash != header.Root {
+		return fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", blockNum, hash, header.Root)
+	}
+	return nil
+}
+
+// checkHistoryRetained fails if the change sets needed to unwind the state to the block have been pruned.
+func checkHistoryRetained(tx kv.Tx, blockNum uint64) error {
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	prunedTo, err := pm.KeptFrom(tx, prune.KindHistory, executionProgress)
+	if err != nil {
+		return err
+	}
+	if blockNum+1 < prunedTo {
+		return fmt.Errorf("the state history of block %d is pruned, the node keeps it from block %d", blockNum, prunedTo-1)
+	}
+	return nil
+}
+
+// checkChangeSetsSize fails if the change sets of the blocks after blockNum, up to the block to, take more than
+// maxMemory.
+func checkChangeSetsSize(tx kv.Tx, blockNum, to uint64, maxMemory datasize.ByteSize) error {
+	var size datasize.ByteSize
+	for _, table := range []string{kv.AccountChangeSet, kv.StorageChangeSet} {
+		c, err := tx.Cursor(table)
+		if err != nil {
+			return err
+		}
+		for k, v, err := c.Seek(dbutils.EncodeBlockNumber(blockNum + 1)); k != nil; k, v, err = c.Next() {
+			if err != nil {
+				c.Close()
+				return err
+			}
+			if binary.BigEndian.Uint64(k) > to {
+				break
+			}
+			if size += datasize.ByteSize(len(k) + len(v)); size > maxMemory {
+				c.Close()
+				return fmt.Errorf("block %d is too old, the state changes since need more than %s of memory", blockNum, maxMemory.HumanReadable())
+			}
+		}
+		c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package stagedsync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+)
+
+// CopyFilter - of the entries of a table copied to a pruned database: returns the value to copy, and false to skip the
+// entry
+type CopyFilter func(k, v []byte) ([]byte, bool, error)
+
+// PruneCopyFilters - filters of the tables pruned by pm at head, which skip the data the prune stages would delete (and
+// the blocks of the indices): a pruned copy of a database is made without deleting anything from the original.
+// Tables without a filter are copied as is.
+func PruneCopyFilters(pm prune.Mode, head uint64) map[string]CopyFilter {
+	filters := map[string]CopyFilter{}
+	if pm.History.Enabled() {
+		pruneTo := pm.History.PruneTo(head)
+		filters[kv.AccountChangeSet] = blockPrefixFilter(pruneTo)
+		filters[kv.StorageChangeSet] = blockPrefixFilter(pruneTo)
+		filters[kv.AccountsHistory] = index64Filter(pruneTo)
+		filters[kv.StorageHistory] = index64Filter(pruneTo)
+	}
+	if pm.Receipts.Enabled() {
+		pruneTo := pm.Receipts.PruneTo(head)
+		filters[kv.Receipts] = blockPrefixFilter(pruneTo)
+		filters[kv.Log] = blockPrefixFilter(pruneTo)
+		filters[kv.LogTopicIndex] = index32Filter(pruneTo)
+		filters[kv.LogAddressIndex] = index32Filter(pruneTo)
+		filters[kv.Issuance] = func(k, v []byte) ([]byte, bool, error) {
+			if blockNum, ok := rawdb.TipSummaryNumber(k); ok && blockNum < pruneTo {
+				return nil, false, nil
+			}
+			return v, true, nil
+		}
+	}
+	if pm.TxIndex.Enabled() {
+		pruneTo := pm.TxIndex.PruneTo(head)
+		filters[kv.Senders] = blockPrefixFilter(pruneTo)
+		filters[kv.TxLookup] = func(k, v []byte) ([]byte, bool, error) {
+			if new(big.Int).SetBytes(v).Uint64() < pruneTo {
+				return nil, false, nil
+			}
+			return v, true, nil
+		}
+	}
+	if pm.CallTraces.Enabled() {
+		pruneTo := pm.CallTraces.PruneTo(head)
+		filters[kv.CallTraceSet] = blockPrefixFilter(pruneTo)
+		filters[kv.CallFromIndex] = index64Filter(pruneTo)
+		filters[kv.CallToIndex] = index64Filter(pruneTo)
+	}
+	return filters
+}
+
+// blockPrefixFilter - for the tables with keys prefixed by block number
+func blockPrefixFilter(pruneTo uint64) CopyFilter {
+	return func(k, v []byte) ([]byte, bool, error) {
+		if len(k) >= 8 && binary.BigEndian.Uint64(k) < pruneTo {
+			return nil, false, nil
+		}
+		return v, true, nil
+	}
+}
+
+// index64Filter - for the indices with chunks keyed by their last block
+func index64Filter(pruneTo uint64) CopyFilter {
+	return func(k, v []byte) ([]byte, bool, error) {
+		if binary.BigEndian.Uint64(k[len(k)-8:]) < pruneTo {
+			return nil, false, nil
+		}
+		m := roaring64.New()
+		if _, err := m.ReadFrom(bytes.NewReader(v)); err != nil {
+			return nil, false, err
+		}
+		if m.Minimum() >= pruneTo {
+			return v, true, nil
+		}
+		m.RemoveRange(0, pruneTo)
+		if m.IsEmpty() {
+			return nil, false, nil
+		}
+		v, err := m.ToBytes()
+		return v, err == nil, err
+	}
+}
+
+// index32Filter - for the logs indices, with chunks keyed by their last block as uint32
+func index32Filter(pruneTo uint64) CopyFilter {
+	return func(k, v []byte) ([]byte, bool, error) {
+		if uint64(binary.BigEndian.Uint32(k[len(k)-4:])) < pruneTo {
+			return nil, false, nil
+		}
+		m := roaring.New()
+		if _, err := m.ReadFrom(bytes.NewReader(v)); err != nil {
+			return nil, false, err
+		}
+		if uint64(m.Minimum()) >= pruneTo {
+			return v, true, nil
+		}
+		m.RemoveRange(0, pruneTo)
+		if m.IsEmpty() {
+			return nil, false, nil
+		}
+		v, err := m.ToBytes()
+		return v, err == nil, err
+	}
+}
@@ -0,0 +1,42 @@
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneCopyFilters(t *testing.T) {
+	pm, err := prune.FromCli("hr", 0, 0, 0, 0, 10, 10, 0, 0, nil, "")
+	require.NoError(t, err)
+	filters := PruneCopyFilters(pm, 100)
+	require.Nil(t, filters[kv.TxLookup])
+	require.Nil(t, filters[kv.PlainState])
+
+	_, keep, err := filters[kv.Receipts](dbutils.EncodeBlockNumber(8), []byte{1})
+	require.NoError(t, err)
+	require.False(t, keep)
+	v, keep, err := filters[kv.Receipts](dbutils.EncodeBlockNumber(9), []byte{1})
+	require.NoError(t, err)
+	require.True(t, keep)
+	require.Equal(t, []byte{1}, v)
+
+	// chunk ending before the pruned blocks is dropped, chunk ending after them is cut
+	chunk, err := roaring64.BitmapOf(1, 5, 8).ToBytes()
+	require.NoError(t, err)
+	_, keep, err = filters[kv.AccountsHistory](append([]byte{0xaa}, dbutils.EncodeBlockNumber(8)...), chunk)
+	require.NoError(t, err)
+	require.False(t, keep)
+	chunk, err = roaring64.BitmapOf(1, 5, 9, 20).ToBytes()
+	require.NoError(t, err)
+	v, keep, err = filters[kv.AccountsHistory](append([]byte{0xaa}, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff), chunk)
+	require.NoError(t, err)
+	require.True(t, keep)
+	m := roaring64.New()
+	require.NoError(t, m.UnmarshalBinary(v))
+	require.Equal(t, []uint64{9, 20}, m.ToArray())
+}
@@ -77,6 +77,8 @@ func (s *StageState) ExecutionAt(db kv.Getter) (uint64, error) {
 type Unwinder interface {
 	// UnwindTo begins staged sync unwind to the specified block.
 	UnwindTo(unwindPoint uint64, badBlock common.Hash)
+	// RevertTo begins staged sync unwind to the specified block, forgetting the unwound blocks.
+	RevertTo(unwindPoint uint64)
 }
 
 // UnwindState contains the information about unwind.
@@ -87,7 +89,9 @@ type UnwindState struct {
 	CurrentBlockNumber uint64
 	// If unwind is caused by a bad block, this hash is not empty
 	BadBlock common.Hash
-	state    *Sync
+	// If the unwind reverts the chain, the unwound blocks are deleted and their transactions aren't returned to the txpool
+	Revert bool
+	state  *Sync
 }
 
 func (u *UnwindState) LogPrefix() string { return u.state.LogPrefix() }
@@ -283,7 +283,7 @@ func UnwindBodiesStage(u *UnwindState, tx kv.RwTx, cfg BodiesCfg, ctx context.Co
 	defer logEvery.Stop()
 
 	badBlock := u.BadBlock != (common.Hash{})
-	if err := rawdb.MakeBodiesNonCanonical(tx, u.UnwindPoint+1, badBlock /* deleteBodies */, ctx, u.LogPrefix(), logEvery); err != nil {
+	if err := rawdb.MakeBodiesNonCanonical(tx, u.UnwindPoint+1, badBlock || u.Revert /* deleteBodies */, ctx, u.LogPrefix(), logEvery); err != nil {
 		return err
 	}
 
@@ -374,7 +374,11 @@ func PruneCallTraces(s *PruneState, tx kv.RwTx, cfg CallTracesCfg, ctx context.C
 	}
 
 	if cfg.prune.CallTraces.Enabled() {
-		if err = pruneCallTraces(tx, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), ctx, cfg.tmpdir); err != nil {
+		pruneTo, err := cfg.prune.PruneTo(tx, prune.KindCallTraces, s.ForwardProgress)
+		if err != nil {
+			return err
+		}
+		if err = pruneCallTraces(tx, logPrefix, pruneTo, ctx, cfg.tmpdir); err != nil {
 			return err
 		}
 	}
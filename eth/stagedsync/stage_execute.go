@@ -2,7 +2,6 @@ package stagedsync
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
@@ -12,7 +11,6 @@ import (
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/cmp"
-	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	commonold "github.com/ledgerwatch/erigon/common"
@@ -20,13 +18,14 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
-	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/eth/profiler"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
@@ -51,6 +50,11 @@ type ChangeSetHook func(blockNum uint64, wr *state.ChangeSetWriter)
 type ExecuteBlockCfg struct {
 	db            kv.RwDB
 	batchSize     datasize.ByteSize
+	workers       int                 // executing the transactions of a block in parallel, if more than 1
+	cache         bool                // caching the state read by the sequential execution across the blocks
+	streamCommit  bool                // promoting the changes to the hashed state during the execution, see commitmentStream
+	stateBackend  state.Backend       // the plain state if nil
+	stemTree      *stemTreeCommitment // the stemtree experiment, nil if disabled
 	prune         prune.Mode
 	changeSetHook ChangeSetHook
 	chainConfig   *params.ChainConfig
@@ -68,6 +72,10 @@ func StageExecuteBlocksCfg(
 	kv kv.RwDB,
 	prune prune.Mode,
 	batchSize datasize.ByteSize,
+	workers int,
+	cache bool,
+	streamCommit bool,
+	stateBackend state.Backend,
 	changeSetHook ChangeSetHook,
 	chainConfig *params.ChainConfig,
 	engine consensus.Engine,
@@ -79,10 +87,19 @@ func StageExecuteBlocksCfg(
 	blockReader services.FullBlockReader,
 	hd *headerdownload.HeaderDownload,
 ) ExecuteBlockCfg {
+	var stemTree *stemTreeCommitment
+	if prune.Experiments.StemTree {
+		stemTree = newStemTreeCommitment()
+	}
 	return ExecuteBlockCfg{
 		db:            kv,
 		prune:         prune,
 		batchSize:     batchSize,
+		workers:       workers,
+		cache:         cache,
+		streamCommit:  streamCommit,
+		stateBackend:  stateBackend,
+		stemTree:      stemTree,
 		changeSetHook: changeSetHook,
 		chainConfig:   chainConfig,
 		engine:        engine,
@@ -96,6 +113,19 @@ func StageExecuteBlocksCfg(
 	}
 }
 
+// stateCacheLimits returns the maximum size of the cache of the state reads, and the heap in use above which
+// the cache shrinks, both derived from the batch size.
+func (cfg ExecuteBlockCfg) stateCacheLimits() (maxLimit, memLimit datasize.ByteSize) {
+	return cfg.batchSize / 2, 4 * cfg.batchSize
+}
+
+func (cfg ExecuteBlockCfg) backend() state.Backend {
+	if cfg.stateBackend == nil {
+		return state.PlainBackend
+	}
+	return cfg.stateBackend
+}
+
 func executeBlock(
 	block *types.Block,
 	tx kv.RwTx,
@@ -108,9 +138,12 @@ func executeBlock(
 	contractHasTEVM func(contractHash commonold.Hash) (bool, error),
 	initialCycle bool,
 	effectiveEngine consensus.Engine,
+	commitment *commitmentStream,
+	stemTree *stemTreeCommitment,
+	cache *shards.StateCache,
 ) error {
 	blockNum := block.NumberU64()
-	stateReader, stateWriter, err := newStateReaderWriter(batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream)
+	stateReader, stateWriter, err := newStateReaderWriter(cfg.backend(), batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream, commitment, stemTree, cache)
 	if err != nil {
 		return err
 	}
@@ -124,12 +157,18 @@ func executeBlock(
 	callTracer := calltracer.NewCallTracer(contractHasTEVM)
 	vmConfig.Debug = true
 	vmConfig.Tracer = callTracer
+	vmConfig.TraceTxHash = writeCallTraces
+	if p := profiler.Active(); p != nil {
+		vmConfig.Tracer = p.Tracer(callTracer)
+	}
 
 	var receipts types.Receipts
 	var stateSyncReceipt *types.ReceiptForStorage
 	_, isPoSa := effectiveEngine.(consensus.PoSA)
 	if isPoSa {
 		receipts, err = core.ExecuteBlockEphemerallyForBSC(cfg.chainConfig, &vmConfig, getHeader, effectiveEngine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, contractHasTEVM)
+	} else if cfg.workers > 1 {
+		receipts, stateSyncReceipt, err = core.ExecuteBlockParallel(cfg.workers, cfg.chainConfig, &vmConfig, getHeader, effectiveEngine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, contractHasTEVM)
 	} else {
 		receipts, stateSyncReceipt, err = core.ExecuteBlockEphemerally(cfg.chainConfig, &vmConfig, getHeader, effectiveEngine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, contractHasTEVM)
 	}
@@ -138,7 +177,20 @@ func executeBlock(
 	}
 
 	if writeReceipts {
-		if err = rawdb.AppendReceipts(tx, blockNum, receipts); err != nil {
+		if cfg.prune.RegenerateReceipts {
+			err = rawdb.AppendLogs(tx, blockNum, receipts)
+		} else {
+			err = rawdb.AppendReceipts(tx, blockNum, receipts)
+		}
+		if err != nil {
+			return err
+		}
+		// for the reward percentiles of the fee history, which doesn't need to read the receipts back then
+		tips, err := types.NewTipSummary(block.Transactions(), receipts, block.BaseFee())
+		if err != nil {
+			return err
+		}
+		if err = rawdb.WriteTipSummary(tx, blockNum, tips); err != nil {
 			return err
 		}
 
@@ -156,12 +208,16 @@ func executeBlock(
 		}
 	}
 	if writeCallTraces {
+		if err = callTracer.WriteContractCreations(tx, block); err != nil {
+			return err
+		}
 		return callTracer.WriteToDb(tx, block, *cfg.vmConfig)
 	}
 	return nil
 }
 
 func newStateReaderWriter(
+	backend state.Backend,
 	batch ethdb.Database,
 	tx kv.RwTx,
 	block *types.Block,
@@ -169,12 +225,15 @@ func newStateReaderWriter(
 	accumulator *shards.Accumulator,
 	initialCycle bool,
 	stateStream bool,
+	commitment *commitmentStream,
+	stemTree *stemTreeCommitment,
+	cache *shards.StateCache,
 ) (state.StateReader, state.WriterWithChangeSets, error) {
 
 	var stateReader state.StateReader
 	var stateWriter state.WriterWithChangeSets
 
-	stateReader = state.NewPlainStateReader(batch)
+	stateReader = backend.NewReader(batch)
 
 	if !initialCycle && stateStream {
 		txs, err := rawdb.RawTransactionsRange(tx, block.NumberU64(), block.NumberU64())
@@ -185,10 +244,20 @@ func newStateReaderWriter(
 	} else {
 		accumulator = nil
 	}
-	if writeChangesets {
-		stateWriter = state.NewPlainStateWriter(batch, tx, block.NumberU64()).SetAccumulator(accumulator)
-	} else {
-		stateWriter = state.NewPlainStateWriterNoHistory(batch).SetAccumulator(accumulator)
+	changeSetsTx := tx
+	if !writeChangesets {
+		changeSetsTx = nil
+	}
+	stateWriter = backend.NewWriter(batch, changeSetsTx, block.NumberU64(), accumulator)
+	if commitment != nil {
+		stateWriter = &commitmentStreamWriter{WriterWithChangeSets: stateWriter, stream: commitment}
+	}
+	if stemTree != nil {
+		stateWriter = stemTree.writer(stateWriter)
+	}
+	if cache != nil {
+		stateReader = state.NewCachedReader(stateReader, cache)
+		stateWriter = &cachedStateWriter{CachedWriter: state.NewCachedWriter(stateWriter, cache), w: stateWriter}
 	}
 
 	return stateReader, stateWriter, nil
@@ -262,17 +331,35 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		asyncEngine = asyncEngine.WithExecutionContext(ctx)
 		effectiveEngine = asyncEngine.(consensus.Engine)
 	}
+	prefetcher := newStatePrefetcher(ctx, cfg.db, prefetchWorkers)
+	defer prefetcher.close()
+	// the hashed state can only be kept up to date with the execution if it already is
+	var commitment *commitmentStream
+	if cfg.streamCommit && cfg.backend() == state.PlainBackend && nextStageProgress > 0 && nextStageProgress == s.BlockNumber {
+		commitment = newCommitmentStream()
+		defer commitment.close()
+	}
+	// the stem tree is built from the plain state
+	var stemTree *stemTreeCommitment
+	if cfg.stemTree != nil && cfg.backend() == state.PlainBackend {
+		stemTree = cfg.stemTree
+		if err = stemTree.ensure(logPrefix, tx, s.BlockNumber, quit); err != nil {
+			return err
+		}
+	}
+	// the reads of the latest state are cached across the blocks, the writes go through to the batch as well
+	var cache *shards.StateCache
+	if cfg.cache && cfg.workers <= 1 {
+		maxLimit, _ := cfg.stateCacheLimits()
+		cache = shards.NewStateCache(32, maxLimit)
+	}
 Loop:
 	for blockNum := stageProgress + 1; blockNum <= to; blockNum++ {
 		if stoppedErr = common.Stopped(quit); stoppedErr != nil {
 			break
 		}
 
-		blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
-		if err != nil {
-			return err
-		}
-		block, _, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+		block, err := prefetcher.block(ctx, tx, cfg.blockReader, blockNum, to)
 		if err != nil {
 			return err
 		}
@@ -280,6 +367,7 @@ Loop:
 			log.Error(fmt.Sprintf("[%s] Empty block", logPrefix), "blocknum", blockNum)
 			break
 		}
+		blockHash := block.Hash()
 
 		lastLogTx += uint64(block.Transactions().Len())
 
@@ -293,7 +381,10 @@ Loop:
 		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
 		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
-		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, contractHasTEVM, initialCycle, effectiveEngine); err != nil {
+		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, contractHasTEVM, initialCycle, effectiveEngine, commitment, stemTree, cache); err != nil {
+			if stemTree != nil {
+				stemTree.valid = false // holds a part of the changes of the block
+			}
 			if !errors.Is(err, context.Canceled) {
 				log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
 				if cfg.hd != nil {
@@ -307,6 +398,13 @@ Loop:
 			break Loop
 		}
 		stageProgress = blockNum
+		if stemTree != nil {
+			stemTree.commit(blockNum)
+		}
+		if cache != nil {
+			// the writes are in the batch, they may be evicted like the reads
+			cache.TurnWritesToReads(cache.PrepareWrites())
+		}
 
 		if currentStateGas >= gasState {
 			log.Info("Committed State", "gas reached", currentStateGas, "gasTarget", gasState)
@@ -315,6 +413,11 @@ Loop:
 				return err
 			}
 			if !useExternalTx {
+				if commitment != nil {
+					if err = commitment.promote(ctx, logPrefix, tx, cfg.tmpdir, stageProgress); err != nil {
+						return err
+					}
+				}
 				if err = s.Update(tx, stageProgress); err != nil {
 					return err
 				}
@@ -351,6 +454,9 @@ Loop:
 			}
 			logBlock, logTx, logTime = logProgress(logPrefix, logBlock, logTime, blockNum, logTx, lastLogTx, gas, float64(currentStateGas)/float64(gasState), estimatedTime, batch)
 			gas = 0
+			if cache != nil {
+				cache.AdjustLimit(cfg.stateCacheLimits())
+			}
 			tx.CollectMetrics()
 			syncMetrics[stages.Execution].Set(blockNum)
 		}
@@ -362,6 +468,11 @@ Loop:
 	if err = batch.Commit(); err != nil {
 		return fmt.Errorf("batch commit: %v", err)
 	}
+	if commitment != nil {
+		if err = commitment.promote(ctx, logPrefix, tx, cfg.tmpdir, stageProgress); err != nil {
+			return err
+		}
+	}
 
 	if !useExternalTx {
 		if err = tx.Commit(); err != nil {
@@ -369,6 +480,9 @@ Loop:
 		}
 	}
 
+	if stemTree != nil {
+		stemTree.report(logPrefix)
+	}
 	log.Info(fmt.Sprintf("[%s] Completed on", logPrefix), "block", stageProgress)
 	return stoppedErr
 }
@@ -434,8 +548,6 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 
 func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan struct{}, cfg ExecuteBlockCfg, initialCycle bool) error {
 	logPrefix := s.LogPrefix()
-	stateBucket := kv.PlainState
-	storageKeyLength := length.Addr + length.Incarnation + length.Hash
 
 	var accumulator *shards.Accumulator
 	if !initialCycle && cfg.stateStream {
@@ -445,9 +557,11 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 		if err != nil {
 			return fmt.Errorf("read canonical hash of unwind point: %w", err)
 		}
-		txs, err := rawdb.RawTransactionsRange(tx, u.UnwindPoint, s.BlockNumber)
-		if err != nil {
-			return err
+		var txs [][]byte
+		if !u.Revert {
+			if txs, err = rawdb.RawTransactionsRange(tx, u.UnwindPoint, s.BlockNumber); err != nil {
+				return err
+			}
 		}
 		accumulator.StartChange(u.UnwindPoint, hash, txs, true)
 	}
@@ -458,76 +572,7 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 	if errRewind != nil {
 		return fmt.Errorf("getting rewind data: %w", errRewind)
 	}
-
-	if err := changes.Load(tx, stateBucket, func(k, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
-		if len(k) == 20 {
-			if len(v) > 0 {
-				var acc accounts.Account
-				if err := acc.DecodeForStorage(v); err != nil {
-					return err
-				}
-
-				// Fetch the code hash
-				recoverCodeHashPlain(&acc, tx, k)
-				var address commonold.Address
-				copy(address[:], k)
-
-				// cleanup contract code bucket
-				original, err := state.NewPlainStateReader(tx).ReadAccountData(address)
-				if err != nil {
-					return fmt.Errorf("read account for %x: %w", address, err)
-				}
-				if original != nil {
-					// clean up all the code incarnations original incarnation and the new one
-					for incarnation := original.Incarnation; incarnation > acc.Incarnation && incarnation > 0; incarnation-- {
-						err = tx.Delete(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], incarnation), nil)
-						if err != nil {
-							return fmt.Errorf("writeAccountPlain for %x: %w", address, err)
-						}
-					}
-				}
-
-				newV := make([]byte, acc.EncodingLengthForStorage())
-				acc.EncodeForStorage(newV)
-				if accumulator != nil {
-					accumulator.ChangeAccount(address, acc.Incarnation, newV)
-				}
-				if err := next(k, k, newV); err != nil {
-					return err
-				}
-			} else {
-				if accumulator != nil {
-					var address commonold.Address
-					copy(address[:], k)
-					accumulator.DeleteAccount(address)
-				}
-				if err := next(k, k, nil); err != nil {
-					return err
-				}
-			}
-			return nil
-		}
-		if accumulator != nil {
-			var address commonold.Address
-			var incarnation uint64
-			var location commonold.Hash
-			copy(address[:], k[:length.Addr])
-			incarnation = binary.BigEndian.Uint64(k[length.Addr:])
-			copy(location[:], k[length.Addr+length.Incarnation:])
-			accumulator.ChangeStorage(address, incarnation, location, common.Copy(v))
-		}
-		if len(v) > 0 {
-			if err := next(k, k[:storageKeyLength], v); err != nil {
-				return err
-			}
-		} else {
-			if err := next(k, k[:storageKeyLength], nil); err != nil {
-				return err
-			}
-		}
-		return nil
-
-	}, etl.TransformArgs{Quit: quit}); err != nil {
+	if err := cfg.backend().Unwind(logPrefix, tx, changes, accumulator, quit); err != nil {
 		return err
 	}
 
@@ -538,12 +583,18 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 	if err := rawdb.TruncateReceipts(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("truncate receipts: %w", err)
 	}
+	if err := rawdb.TruncateTipSummaries(tx, u.UnwindPoint+1); err != nil {
+		return fmt.Errorf("truncate tip summaries: %w", err)
+	}
 	if err := rawdb.TruncateBorReceipts(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("truncate bor receipts: %w", err)
 	}
 	if err := rawdb.DeleteNewerEpochs(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("delete newer epochs: %w", err)
 	}
+	if err := rawdb.TruncateContractCreations(tx, u.UnwindPoint+1); err != nil {
+		return fmt.Errorf("truncate contract creations: %w", err)
+	}
 
 	// Truncate CallTraceSet
 	keyStart := dbutils.EncodeBlockNumber(u.UnwindPoint + 1)
@@ -565,16 +616,6 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 	return nil
 }
 
-func recoverCodeHashPlain(acc *accounts.Account, db kv.Tx, key []byte) {
-	var address commonold.Address
-	copy(address[:], key)
-	if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
-		if codeHash, err2 := db.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], acc.Incarnation)); err2 == nil {
-			copy(acc.CodeHash[:], codeHash)
-		}
-	}
-}
-
 func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx context.Context, initialCycle bool) (err error) {
 	logPrefix := s.LogPrefix()
 	useExternalTx := tx != nil
@@ -590,25 +631,51 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 	defer logEvery.Stop()
 
 	if cfg.prune.History.Enabled() {
-		if err = PruneTableDupSort(tx, kv.AccountChangeSet, logPrefix, cfg.prune.History.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+		// changesets are required for unwind - keep them for blocks which engine doesn't consider final yet
+		pruneTo, err := cfg.prune.PruneTo(tx, prune.KindHistory, s.ForwardProgress)
+		if err != nil {
+			return err
+		}
+		engine := cfg.engine
+		if s, ok := engine.(*serenity.Serenity); ok {
+			// the finality of the eth1 engine still holds for the blocks before the merge
+			engine = s.InnerEngine()
+		}
+		if finality, ok := engine.(consensus.Finality); ok {
+			if finalized, _ := finality.FinalizedBlock(); finalized < pruneTo {
+				pruneTo = finalized
+			}
+		}
+		if err = PruneTableDupSort(tx, kv.AccountChangeSet, logPrefix, pruneTo, logEvery, ctx); err != nil {
 			return err
 		}
-		if err = PruneTableDupSort(tx, kv.StorageChangeSet, logPrefix, cfg.prune.History.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+		if err = PruneTableDupSort(tx, kv.StorageChangeSet, logPrefix, pruneTo, logEvery, ctx); err != nil {
 			return err
 		}
 	}
 
 	if cfg.prune.Receipts.Enabled() {
-		if err = PruneTable(tx, kv.Receipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
+		pruneTo, err := cfg.prune.PruneTo(tx, prune.KindReceipts, s.ForwardProgress)
+		if err != nil {
+			return err
+		}
+		if err = PruneTable(tx, kv.Receipts, pruneTo, ctx, math.MaxInt32); err != nil {
 			return err
 		}
 		// LogIndex.Prune will read everything what not pruned here
-		if err = PruneTable(tx, kv.Log, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
+		if err = PruneTable(tx, kv.Log, pruneTo, ctx, math.MaxInt32); err != nil {
+			return err
+		}
+		if err = rawdb.PruneTipSummaries(tx, pruneTo); err != nil {
 			return err
 		}
 	}
 	if cfg.prune.CallTraces.Enabled() {
-		if err = PruneTableDupSort(tx, kv.CallTraceSet, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+		pruneTo, err := cfg.prune.PruneTo(tx, prune.KindCallTraces, s.ForwardProgress)
+		if err != nil {
+			return err
+		}
+		if err = PruneTableDupSort(tx, kv.CallTraceSet, logPrefix, pruneTo, logEvery, ctx); err != nil {
 			return err
 		}
 	}
@@ -623,3 +690,16 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 	}
 	return nil
 }
+
+// cachedStateWriter keeps the change sets of the wrapped writer available to the change set hook.
+type cachedStateWriter struct {
+	*state.CachedWriter
+	w state.WriterWithChangeSets
+}
+
+func (w *cachedStateWriter) ChangeSetWriter() *state.ChangeSetWriter {
+	if hasChangeSet, ok := w.w.(HasChangeSetWriter); ok {
+		return hasChangeSet.ChangeSetWriter()
+	}
+	return nil
+}
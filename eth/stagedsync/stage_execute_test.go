@@ -6,7 +6,10 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/stretchr/testify/assert"
@@ -121,3 +124,30 @@ func TestPruneExecution(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(uint64(15), available)
 }
+
+// finalityEngine is an engine with its own finality, only FinalizedBlock is implemented.
+type finalityEngine struct {
+	consensus.Engine
+	finalized uint64
+}
+
+func (e finalityEngine) FinalizedBlock() (uint64, common.Hash) { return e.finalized, common.Hash{} }
+
+func TestPruneExecutionFinality(t *testing.T) {
+	ctx, assert := context.Background(), assert.New(t)
+	_, tx := memdb.NewTestTx(t)
+
+	generateBlocks(t, 1, 20, plainWriterGen(tx), changeCodeIndepenentlyOfIncarnations)
+	assert.NoError(stages.SaveStageProgress(tx, stages.Execution, 20))
+
+	// the changesets of the blocks after the finalized one are kept, also when the engine is wrapped for the merge
+	s := &PruneState{ID: stages.Execution, ForwardProgress: 20}
+	pm := prune.DefaultMode
+	pm.History = prune.Distance(5)
+	cfg := ExecuteBlockCfg{prune: pm, engine: serenity.New(finalityEngine{finalized: 10})}
+	assert.NoError(PruneExecutionStage(s, tx, cfg, ctx, false))
+
+	available, err := changeset.AvailableFrom(tx)
+	assert.NoError(err)
+	assert.Equal(uint64(10), available)
+}
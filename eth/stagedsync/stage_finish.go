@@ -17,6 +17,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -162,8 +163,8 @@ func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishS
 	notifier.OnNewHeader(headersRlp)
 	headerTiming := time.Since(t)
 	t = time.Now()
-	if notifier.HasLogSubsriptions() {
-		logs, err := ReadLogs(tx, notifyFrom, isUnwind)
+	if ok, interest := notifier.HasLogSubsriptions(); ok {
+		logs, err := ReadLogs(tx, notifyFrom, isUnwind, interest)
 		if err != nil {
 			return err
 		}
@@ -174,7 +175,9 @@ func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishS
 	return nil
 }
 
-func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsReply, error) {
+// ReadLogs reads the logs of the blocks from the given one, the ones of interest only: the blocks whose header bloom
+// rules them out are skipped without reading their transactions and logs.
+func ReadLogs(tx kv.Tx, from uint64, isUnwind bool, interest *privateapi.LogsInterest) ([]*remote.SubscribeLogsReply, error) {
 	logs, err := tx.Cursor(kv.Log)
 	if err != nil {
 		return nil, err
@@ -183,32 +186,57 @@ func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsRepl
 	reply := make([]*remote.SubscribeLogsReply, 0)
 	reader := bytes.NewReader(nil)
 
+	var started, skipBlock bool
 	var prevBlockNum uint64
-	var block *types.Block
+	var blockHash common2.Hash
+	var txs types.Transactions
 	var logIndex uint64
 	for k, v, err := logs.Seek(dbutils.LogKey(from, 0)); k != nil; k, v, err = logs.Next() {
 		if err != nil {
 			return nil, err
 		}
 		blockNum := binary.BigEndian.Uint64(k[:8])
-		if block == nil || blockNum != prevBlockNum {
+		if !started || blockNum != prevBlockNum {
+			started = true
 			logIndex = 0
 			prevBlockNum = blockNum
-			if block, err = rawdb.ReadBlockByNumber(tx, blockNum); err != nil {
+			if blockHash, err = rawdb.ReadCanonicalHash(tx, blockNum); err != nil {
 				return nil, err
 			}
+			header := rawdb.ReadHeader(tx, blockHash, blockNum)
+			if header == nil {
+				return nil, fmt.Errorf("header of block %d not found", blockNum)
+			}
+			if skipBlock = !interest.MayMatchBloom(header.Bloom); skipBlock {
+				continue
+			}
+			body := rawdb.ReadCanonicalBodyWithTransactions(tx, blockHash, blockNum)
+			if body == nil {
+				return nil, fmt.Errorf("body of block %d not found", blockNum)
+			}
+			txs = body.Transactions
+		}
+		if skipBlock {
+			continue
 		}
 		txIndex := uint64(binary.BigEndian.Uint32(k[8:]))
-		txHash := block.Transactions()[txIndex].Hash()
+		var txHash common2.Hash
 		var ll types.Logs
 		reader.Reset(v)
 		if err := cbor.Unmarshal(&ll, reader); err != nil {
 			return nil, fmt.Errorf("receipt unmarshal failed: %w, blocl=%d", err, blockNum)
 		}
 		for _, l := range ll {
+			if !interest.Matches(l) {
+				logIndex++
+				continue
+			}
+			if txHash == (common2.Hash{}) {
+				txHash = txs[txIndex].Hash()
+			}
 			r := &remote.SubscribeLogsReply{
 				Address:          gointerfaces.ConvertAddressToH160(l.Address),
-				BlockHash:        gointerfaces.ConvertHashToH256(block.Hash()),
+				BlockHash:        gointerfaces.ConvertHashToH256(blockHash),
 				BlockNumber:      blockNum,
 				Data:             l.Data,
 				LogIndex:         logIndex,
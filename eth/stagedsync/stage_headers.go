@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"time"
 
@@ -22,7 +23,9 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
@@ -32,6 +35,8 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/stages/bodydownload"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/log/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // The number of blocks we should be able to re-org sub-second on commodity hardware.
@@ -49,6 +54,8 @@ type HeadersCfg struct {
 	batchSize         datasize.ByteSize
 	noP2PDiscovery    bool
 	memoryOverlay     bool
+	backfillFromTip   bool // only follow the consensus layer, also before the terminal total difficulty
+	devRevert         bool // the developer chain reverts to its snapshots, see HeaderDownload.RequestUnwind
 	tmpdir            string
 
 	snapshots          *snapshotsync.RoSnapshots
@@ -70,6 +77,8 @@ func StageHeadersCfg(
 	batchSize datasize.ByteSize,
 	noP2PDiscovery bool,
 	memoryOverlay bool,
+	backfillFromTip bool,
+	devRevert bool,
 	snapshots *snapshotsync.RoSnapshots,
 	snapshotDownloader proto_downloader.DownloaderClient,
 	blockReader services.FullBlockReader,
@@ -95,6 +104,8 @@ func StageHeadersCfg(
 		execPayload:        execPayload,
 		notifications:      notifications,
 		memoryOverlay:      memoryOverlay,
+		backfillFromTip:    backfillFromTip,
+		devRevert:          devRevert,
 	}
 }
 
@@ -140,6 +151,10 @@ func SpawnStageHeaders(
 	if transitionedToPoS {
 		libcommon.SafeClose(cfg.hd.QuitPoWMining)
 		return HeadersPOS(s, u, ctx, tx, cfg, useExternalTx)
+	} else if cfg.backfillFromTip && cfg.chainConfig.TerminalTotalDifficulty != nil {
+		// The headers between the local head and the head given by the consensus layer are downloaded backwards
+		// by the PoS downloader, the PoW ones included, instead of forwards by HeadersPOW
+		return HeadersPOS(s, u, ctx, tx, cfg, useExternalTx)
 	} else {
 		return HeadersPOW(s, u, ctx, tx, cfg, initialCycle, test, useExternalTx)
 	}
@@ -190,13 +205,22 @@ func HeadersPOS(
 	cfg.hd.ClearPendingPayloadHash()
 	cfg.hd.SetPendingPayloadStatus(nil)
 
+	// the headers are inserted, and the rest of the stages run, within the trace of the request
 	var payloadStatus *privateapi.PayloadStatus
 	var err error
+	var span trace.Span
 	if forkChoiceInsteadOfNewPayload {
+		_, span = tracer.Start(s.state.TraceRequest(forkChoiceMessage.SpanContext), "Headers: forkchoiceUpdated")
 		payloadStatus, err = startHandlingForkChoice(forkChoiceMessage, requestStatus, requestId, s, u, ctx, tx, cfg, headerInserter, cfg.blockReader)
 	} else {
+		_, span = tracer.Start(s.state.TraceRequest(payloadMessage.SpanContext), "Headers: newPayload", trace.WithAttributes(
+			attribute.Int64("block.number", payloadMessage.Header.Number.Int64())))
 		payloadStatus, err = handleNewPayload(payloadMessage, requestStatus, requestId, s, ctx, tx, cfg, headerInserter)
 	}
+	if payloadStatus != nil {
+		span.SetAttributes(attribute.String("engine.status", payloadStatus.Status.String()))
+	}
+	endSpan(span, err)
 
 	if err != nil {
 		if requestStatus == engineapi.New {
@@ -778,6 +802,14 @@ func HeadersPOW(
 Loop:
 	for !stopped {
 
+		if cfg.devRevert {
+			// the developer chain reverts to a snapshot
+			if unwindPoint, ok := cfg.hd.UnwindRequest(); ok && unwindPoint < headerProgress {
+				u.RevertTo(unwindPoint)
+				break
+			}
+		}
+
 		transitionedToPoS, err := rawdb.Transitioned(tx, headerProgress, cfg.chainConfig.TerminalTotalDifficulty)
 		if err != nil {
 			return err
@@ -984,7 +1016,7 @@ func HeadersUnwind(u *UnwindState, s *StageState, tx kv.RwTx, cfg HeadersCfg, te
 			return fmt.Errorf("iterate over headers to mark bad headers: %w", err)
 		}
 	}
-	if err := rawdb.TruncateCanonicalHash(tx, u.UnwindPoint+1, false /* deleteHeaders */); err != nil {
+	if err := rawdb.TruncateCanonicalHash(tx, u.UnwindPoint+1, u.Revert /* deleteHeaders */); err != nil {
 		return err
 	}
 	if badBlock {
@@ -1047,6 +1079,17 @@ func HeadersUnwind(u *UnwindState, s *StageState, tx kv.RwTx, cfg HeadersCfg, te
 		if err = s.Update(tx, maxNum); err != nil {
 			return err
 		}
+	} else if u.Revert {
+		hash, err := rawdb.ReadCanonicalHash(tx, u.UnwindPoint)
+		if err != nil {
+			return err
+		}
+		if err = rawdb.WriteHeadHeaderHash(tx, hash); err != nil {
+			return err
+		}
+		if err = u.Done(tx); err != nil {
+			return err
+		}
 	}
 	if !useExternalTx {
 		if err := tx.Commit(); err != nil {
@@ -1261,6 +1304,10 @@ func DownloadAndIndexSnapshotsIfNeed(s *StageState, ctx context.Context, tx kv.R
 		s.BlockNumber = cfg.snapshots.BlocksAvailable()
 	}
 
+	if err := loadStateSnapshotIfNeed(ctx, s.LogPrefix(), tx, cfg); err != nil {
+		return err
+	}
+
 	if err := cfg.hd.AddHeadersFromSnapshot(tx, cfg.snapshots.BlocksAvailable(), cfg.blockReader); err != nil {
 		return err
 	}
@@ -1268,6 +1315,41 @@ func DownloadAndIndexSnapshotsIfNeed(s *StageState, ctx context.Context, tx kv.R
 	return nil
 }
 
+// loadStateSnapshotIfNeed - if nothing is executed yet, load the latest preverified state snapshot (if any): then only
+// blocks after it are executed. The state root is checked by the IntermediateHashes stage, which re-generates the trie.
+// The history, receipts and call traces of the blocks of the snapshot are missing: they are recorded as pruned.
+func loadStateSnapshotIfNeed(ctx context.Context, logPrefix string, tx kv.RwTx, cfg HeadersCfg) error {
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if executionProgress > 0 {
+		return nil
+	}
+	preverified := snapshothashes.KnownConfig(cfg.chainConfig.ChainName).Preverified
+	f, hash, ok, err := snapshotsync.LatestStateSnapshot(cfg.snapshots.Dir(), cfg.snapshots.BlocksAvailable(), preverified)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	log.Info(fmt.Sprintf("[%s] Verifying state snapshot", logPrefix), "file", filepath.Base(f.Path))
+	if err := snapshotsync.VerifyStateSnapshot(f, hash); err != nil {
+		return fmt.Errorf("VerifyStateSnapshot: %w", err)
+	}
+	log.Info(fmt.Sprintf("[%s] Loading state snapshot", logPrefix), "file", filepath.Base(f.Path), "block", f.To-1)
+	if err := snapshotsync.LoadState(ctx, tx, f, cfg.tmpdir); err != nil {
+		return fmt.Errorf("LoadState: %w", err)
+	}
+	for _, kind := range []prune.Kind{prune.KindHistory, prune.KindReceipts, prune.KindCallTraces} {
+		if err := prune.SetPrunedTo(tx, kind, f.To); err != nil {
+			return err
+		}
+	}
+	return stages.SaveStageProgress(tx, stages.Execution, f.To-1)
+}
+
 // WaitForDownloader - wait for Downloader service to download all expected snapshots
 // for MVP we sync with Downloader only once, in future will send new snapshots also
 func WaitForDownloader(ctx context.Context, cfg HeadersCfg, tx kv.RwTx) error {
@@ -0,0 +1,48 @@
+package stagedsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersUnwindRevert(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	var hashes []common.Hash
+	var parent common.Hash
+	for number := int64(0); number <= 3; number++ {
+		header := &types.Header{Number: big.NewInt(number), ParentHash: parent}
+		rawdb.WriteHeader(tx, header)
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, header.Hash(), header.Number.Uint64()))
+		parent = header.Hash()
+		hashes = append(hashes, parent)
+	}
+	require.NoError(t, rawdb.WriteHeadHeaderHash(tx, parent))
+	require.NoError(t, stages.SaveStageProgress(tx, stages.Headers, 3))
+
+	cfg := StageHeadersCfg(nil, headerdownload.NewHeaderDownload(16, 16, nil, nil), nil, params.ChainConfig{}, nil, nil, nil, 0, false, false, false, true, nil, nil, nil, "", nil, nil, nil)
+	u := New(nil, nil, nil).NewUnwindState(stages.Headers, 1, 3)
+	u.Revert = true
+	require.NoError(t, HeadersUnwind(u, nil, tx, cfg, true /* test */))
+
+	// the reverted headers are deleted, the unwind point becomes the head
+	require.NotNil(t, rawdb.ReadHeader(tx, hashes[1], 1))
+	for number := uint64(2); number <= 3; number++ {
+		require.Nil(t, rawdb.ReadHeader(tx, hashes[number], number))
+		hash, err := rawdb.ReadCanonicalHash(tx, number)
+		require.NoError(t, err)
+		require.Equal(t, common.Hash{}, hash)
+	}
+	require.Equal(t, hashes[1], rawdb.ReadHeadHeaderHash(tx))
+	progress, err := stages.GetStageProgress(tx, stages.Headers)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), progress)
+}
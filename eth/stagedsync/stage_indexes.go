@@ -366,7 +366,10 @@ func PruneAccountHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx con
 		defer tx.Rollback()
 	}
 
-	pruneTo := cfg.prune.History.PruneTo(s.ForwardProgress)
+	pruneTo, err := cfg.prune.PruneTo(tx, prune.KindHistory, s.ForwardProgress)
+	if err != nil {
+		return err
+	}
 	if err = pruneHistoryIndex(tx, kv.AccountChangeSet, logPrefix, cfg.tmpdir, pruneTo, ctx); err != nil {
 		return err
 	}
@@ -396,7 +399,10 @@ func PruneStorageHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx con
 		}
 		defer tx.Rollback()
 	}
-	pruneTo := cfg.prune.History.PruneTo(s.ForwardProgress)
+	pruneTo, err := cfg.prune.PruneTo(tx, prune.KindHistory, s.ForwardProgress)
+	if err != nil {
+		return err
+	}
 	if err = pruneHistoryIndex(tx, kv.StorageChangeSet, logPrefix, cfg.tmpdir, pruneTo, ctx); err != nil {
 		return err
 	}
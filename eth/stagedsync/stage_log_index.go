@@ -385,7 +385,10 @@ func PruneLogIndex(s *PruneState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Conte
 		defer tx.Rollback()
 	}
 
-	pruneTo := cfg.prune.Receipts.PruneTo(s.ForwardProgress)
+	pruneTo, err := cfg.prune.PruneTo(tx, prune.KindReceipts, s.ForwardProgress)
+	if err != nil {
+		return err
+	}
 	if err = pruneLogIndex(logPrefix, tx, cfg.tmpdir, pruneTo, ctx); err != nil {
 		return err
 	}
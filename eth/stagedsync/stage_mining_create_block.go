@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
+	"sort"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
+	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/txpool"
@@ -17,6 +20,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
@@ -41,7 +45,7 @@ type MiningState struct {
 	MiningConfig      *params.MiningConfig
 	PendingResultCh   chan *types.Block
 	MiningResultCh    chan *types.Block
-	MiningResultPOSCh chan *types.Block
+	MiningResultPOSCh chan *types.BlockWithReceipts
 	MiningBlock       *MiningBlock
 }
 
@@ -59,7 +63,7 @@ func NewProposingState(cfg *params.MiningConfig) MiningState {
 		MiningConfig:      cfg,
 		PendingResultCh:   make(chan *types.Block, 1),
 		MiningResultCh:    make(chan *types.Block, 1),
-		MiningResultPOSCh: make(chan *types.Block, 1),
+		MiningResultPOSCh: make(chan *types.BlockWithReceipts, 1),
 		MiningBlock:       &MiningBlock{},
 	}
 }
@@ -124,39 +128,6 @@ func SpawnMiningCreateBlockStage(s *StageState, tx kv.RwTx, cfg MiningCreateBloc
 	}
 
 	blockNum := executionAt + 1
-	var txs []types.Transaction
-	if err = cfg.txPool2DB.View(context.Background(), func(tx kv.Tx) error {
-		txSlots := types2.TxsRlp{}
-		if err := cfg.txPool2.Best(200, &txSlots, tx); err != nil {
-			return err
-		}
-
-		for i := range txSlots.Txs {
-			s := rlp.NewStream(bytes.NewReader(txSlots.Txs[i]), uint64(len(txSlots.Txs[i])))
-
-			transaction, err := types.DecodeTransaction(s)
-			if err == io.EOF {
-				continue
-			}
-			if err != nil {
-				return err
-			}
-			txs = append(txs, transaction)
-		}
-		var sender common.Address
-		for i := range txs {
-			copy(sender[:], txSlots.Senders.At(i))
-			txs[i].SetSender(sender)
-		}
-
-		return nil
-	}); err != nil {
-		return err
-	}
-	current.RemoteTxs = types.NewTransactionsFixedOrder(txs)
-	// txpool v2 - doesn't prioritise local txs over remote
-	current.LocalTxs = types.NewTransactionsFixedOrder(nil)
-	log.Debug(fmt.Sprintf("[%s] Candidate txs", logPrefix), "amount", len(txs))
 	localUncles, remoteUncles, err := readNonCanonicalHeaders(tx, blockNum, cfg.engine, coinbase, txPoolLocals)
 	if err != nil {
 		return err
@@ -225,6 +196,24 @@ func SpawnMiningCreateBlockStage(s *StageState, tx kv.RwTx, cfg MiningCreateBloc
 		return err
 	}
 
+	overrideGasLimit(cfg.engine, header, func(contract common.Address, data []byte) ([]byte, error) {
+		return core.SysCallContract(contract, data, cfg.chainConfig, ibs, header, cfg.engine)
+	})
+
+	// pick the candidates for the block, the most profitable ones first
+	txs, err := readBestTxs(cfg.txPool2, cfg.txPool2DB, header.GasLimit)
+	if err != nil {
+		return err
+	}
+	var baseFee *uint256.Int
+	if header.BaseFee != nil {
+		baseFee, _ = uint256.FromBig(header.BaseFee)
+	}
+	current.RemoteTxs = types.NewTransactionsByPriceAndNonce(*env.signer, groupTxsBySender(txs), baseFee)
+	// txpool v2 - doesn't prioritise local txs over remote
+	current.LocalTxs = types.NewTransactionsFixedOrder(nil)
+	log.Debug(fmt.Sprintf("[%s] Candidate txs", logPrefix), "amount", len(txs))
+
 	if cfg.blockBuilderParameters != nil {
 		header.MixDigest = cfg.blockBuilderParameters.PrevRandao
 
@@ -323,6 +312,57 @@ func SpawnMiningCreateBlockStage(s *StageState, tx kv.RwTx, cfg MiningCreateBloc
 	return nil
 }
 
+// readBestTxs reads the best transactions of the pool, as many as may fit into a block with the gas limit.
+func readBestTxs(pool *txpool.TxPool, poolDB kv.RoDB, gasLimit uint64) (txs types.Transactions, err error) {
+	n := gasLimit / params.TxGas
+	if n > math.MaxUint16 {
+		n = math.MaxUint16
+	}
+	err = poolDB.View(context.Background(), func(tx kv.Tx) error {
+		txSlots := types2.TxsRlp{}
+		if err := pool.Best(uint16(n), &txSlots, tx); err != nil {
+			return err
+		}
+
+		var sender common.Address
+		for i := range txSlots.Txs {
+			s := rlp.NewStream(bytes.NewReader(txSlots.Txs[i]), uint64(len(txSlots.Txs[i])))
+
+			transaction, err := types.DecodeTransaction(s)
+			if err == io.EOF {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			copy(sender[:], txSlots.Senders.At(i))
+			transaction.SetSender(sender)
+			txs = append(txs, transaction)
+		}
+		return nil
+	})
+	return txs, err
+}
+
+// groupTxsBySender groups the transactions by sender, ordered by nonce.
+func groupTxsBySender(txs types.Transactions) types.TransactionsGroupedBySender {
+	var groups types.TransactionsGroupedBySender
+	idx := map[common.Address]int{}
+	for _, txn := range txs {
+		sender, _ := txn.GetSender()
+		if i, ok := idx[sender]; ok {
+			groups[i] = append(groups[i], txn)
+		} else {
+			idx[sender] = len(groups)
+			groups = append(groups, types.Transactions{txn})
+		}
+	}
+	for _, group := range groups {
+		sort.Sort(types.TxByNonce(group))
+	}
+	return groups
+}
+
 func readNonCanonicalHeaders(tx kv.Tx, blockNum uint64, engine consensus.Engine, coinbase common.Address, txPoolLocals []common.Address) (localUncles, remoteUncles map[common.Hash]*types.Header, err error) {
 	localUncles, remoteUncles = map[common.Hash]*types.Header{}, map[common.Hash]*types.Header{}
 	nonCanonicalBlocks, err := rawdb.ReadHeadersByNumber(tx, blockNum)
@@ -339,3 +379,16 @@ func readNonCanonicalHeaders(tx kv.Tx, blockNum uint64, engine consensus.Engine,
 	}
 	return
 }
+
+// overrideGasLimit sets the gas limit of the header to the one of the engine, if it has one. The engine of the merge
+// is unwrapped: the gas limit of the eth1 engine still holds for the blocks mined before the merge.
+func overrideGasLimit(engine consensus.Engine, header *types.Header, syscall consensus.SystemCall) {
+	if s, ok := engine.(*serenity.Serenity); ok {
+		engine = s.InnerEngine()
+	}
+	if gasLimitProvider, ok := engine.(consensus.GasLimitProvider); ok {
+		if gasLimit, ok := gasLimitProvider.GasLimitOverride(header, syscall); ok {
+			header.GasLimit = gasLimit
+		}
+	}
+}
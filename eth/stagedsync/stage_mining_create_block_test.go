@@ -0,0 +1,36 @@
+package stagedsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// gasLimitEngine is an engine enforcing a gas limit, only GasLimitOverride is implemented.
+type gasLimitEngine struct {
+	consensus.Engine
+	gasLimit uint64
+}
+
+func (e gasLimitEngine) GasLimitOverride(*types.Header, consensus.SystemCall) (uint64, bool) {
+	return e.gasLimit, true
+}
+
+func TestOverrideGasLimit(t *testing.T) {
+	syscall := func(common.Address, []byte) ([]byte, error) { return nil, nil }
+	for _, engine := range []consensus.Engine{gasLimitEngine{gasLimit: 1000}, serenity.New(gasLimitEngine{gasLimit: 1000})} {
+		header := &types.Header{Number: big.NewInt(1), GasLimit: 8_000_000}
+		overrideGasLimit(engine, header, syscall)
+		assert.Equal(t, uint64(1000), header.GasLimit)
+	}
+
+	// the engines without a gas limit keep the one of the miner
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 8_000_000}
+	overrideGasLimit(serenity.New(finalityEngine{}), header, syscall)
+	assert.Equal(t, uint64(8_000_000), header.GasLimit)
+}
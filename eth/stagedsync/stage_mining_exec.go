@@ -25,15 +25,16 @@ import (
 )
 
 type MiningExecCfg struct {
-	db          kv.RwDB
-	miningState MiningState
-	notifier    ChainEventNotifier
-	chainConfig params.ChainConfig
-	engine      consensus.Engine
-	blockReader services.FullBlockReader
-	vmConfig    *vm.Config
-	tmpdir      string
-	interrupt   *int32
+	db           kv.RwDB
+	miningState  MiningState
+	notifier     ChainEventNotifier
+	chainConfig  params.ChainConfig
+	engine       consensus.Engine
+	blockReader  services.FullBlockReader
+	vmConfig     *vm.Config
+	tmpdir       string
+	interrupt    *int32
+	stateBackend state.Backend // the plain state if nil
 }
 
 func StageMiningExecCfg(
@@ -45,22 +46,31 @@ func StageMiningExecCfg(
 	vmConfig *vm.Config,
 	tmpdir string,
 	interrupt *int32,
+	stateBackend state.Backend,
 ) MiningExecCfg {
 	return MiningExecCfg{
-		db:          db,
-		miningState: miningState,
-		notifier:    notifier,
-		chainConfig: chainConfig,
-		engine:      engine,
-		blockReader: snapshotsync.NewBlockReader(),
-		vmConfig:    vmConfig,
-		tmpdir:      tmpdir,
-		interrupt:   interrupt,
+		db:           db,
+		miningState:  miningState,
+		notifier:     notifier,
+		chainConfig:  chainConfig,
+		engine:       engine,
+		blockReader:  snapshotsync.NewBlockReader(),
+		vmConfig:     vmConfig,
+		tmpdir:       tmpdir,
+		interrupt:    interrupt,
+		stateBackend: stateBackend,
 	}
 }
 
+func (cfg MiningExecCfg) backend() state.Backend {
+	if cfg.stateBackend == nil {
+		return state.PlainBackend
+	}
+	return cfg.stateBackend
+}
+
 // SpawnMiningExecStage
-//TODO:
+// TODO:
 // - resubmitAdjustCh - variable is not implemented
 func SpawnMiningExecStage(s *StageState, tx kv.RwTx, cfg MiningExecCfg, quit <-chan struct{}) error {
 	cfg.vmConfig.NoReceipts = false
@@ -70,9 +80,9 @@ func SpawnMiningExecStage(s *StageState, tx kv.RwTx, cfg MiningExecCfg, quit <-c
 	remoteTxs := current.RemoteTxs
 	noempty := true
 
-	stateReader := state.NewPlainStateReader(tx)
+	stateReader := cfg.backend().NewReader(tx)
 	ibs := state.New(stateReader)
-	stateWriter := state.NewPlainStateWriter(tx, tx, current.Header.Number.Uint64())
+	stateWriter := cfg.backend().NewWriter(tx, tx, current.Header.Number.Uint64(), nil)
 	if cfg.chainConfig.DAOForkSupport && cfg.chainConfig.DAOForkBlock != nil && cfg.chainConfig.DAOForkBlock.Cmp(current.Header.Number) == 0 {
 		misc.ApplyDAOHardFork(ibs)
 	}
@@ -128,11 +138,18 @@ func SpawnMiningExecStage(s *StageState, tx kv.RwTx, cfg MiningExecCfg, quit <-c
 		current.Receipts = types.Receipts{}
 	}
 
-	_, err := core.FinalizeBlockExecution(cfg.engine, stateReader, current.Header, current.Txs, current.Uncles, stateWriter,
+	// the engine may append its own transactions (e.g. the system transactions of parlia), keep them in the block
+	_, txs, receipts, err := core.FinalizeBlockExecution(cfg.engine, stateReader, current.Header, current.Txs, current.Uncles, stateWriter,
 		&cfg.chainConfig, ibs, current.Receipts, epochReader{tx: tx}, chainReader{config: &cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, true)
 	if err != nil {
 		return err
 	}
+	if txs != nil {
+		current.Txs = txs
+	}
+	if receipts != nil {
+		current.Receipts = receipts
+	}
 
 	/*
 		if w.isRunning() {
@@ -172,7 +189,17 @@ func SpawnMiningExecStage(s *StageState, tx kv.RwTx, cfg MiningExecCfg, quit <-c
 func addTransactionsToMiningBlock(logPrefix string, current *MiningBlock, chainConfig params.ChainConfig, vmConfig *vm.Config, getHeader func(hash common.Hash, number uint64) *types.Header, contractHasTEVM func(common.Hash) (bool, error), engine consensus.Engine, txs types.TransactionsStream, coinbase common.Address, ibs *state.IntraBlockState, quit <-chan struct{}, interrupt *int32) (types.Logs, error) {
 	header := current.Header
 	tcount := 0
-	gasPool := new(core.GasPool).AddGas(current.Header.GasLimit)
+	gasLimit := current.Header.GasLimit
+	posa, isPoSA := engine.(consensus.PoSA)
+	if isPoSA {
+		// leave room for the system transactions which the engine appends when finalizing the block
+		if gasLimit > params.SystemTxsGas {
+			gasLimit -= params.SystemTxsGas
+		} else {
+			gasLimit = 0
+		}
+	}
+	gasPool := new(core.GasPool).AddGas(gasLimit)
 	signer := types.MakeSigner(&chainConfig, header.Number.Uint64())
 
 	var coalescedLogs types.Logs
@@ -228,6 +255,15 @@ func addTransactionsToMiningBlock(logPrefix string, current *MiningBlock, chainC
 			txs.Pop()
 			continue
 		}
+		// Transactions which look like system transactions are only produced by the engine,
+		// the validators would reject the block otherwise
+		if isPoSA {
+			if isSystemTx, err := posa.IsSystemTransaction(txn, header); err != nil || isSystemTx {
+				log.Debug(fmt.Sprintf("[%s] Ignoring system transaction from the pool", logPrefix), "hash", txn.Hash(), "sender", from)
+				txs.Pop()
+				continue
+			}
+		}
 
 		// Start executing the transaction
 		ibs.Prepare(txn.Hash(), common.Hash{}, tcount)
@@ -405,7 +405,10 @@ func PruneSendersStage(s *PruneState, tx kv.RwTx, cfg SendersCfg, ctx context.Co
 			}
 		}
 	} else if cfg.prune.TxIndex.Enabled() {
-		to := cfg.prune.TxIndex.PruneTo(s.ForwardProgress)
+		to, err := cfg.prune.PruneTo(tx, prune.KindTxIndex, s.ForwardProgress)
+		if err != nil {
+			return err
+		}
 		if err = PruneTable(tx, kv.Senders, to, ctx, 1_000); err != nil {
 			return err
 		}
@@ -0,0 +1,273 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// TransferEventTopic is the topic of the Transfer(address,address,uint256) event of the ERC-20 and ERC-721 tokens
+var TransferEventTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// tokenBalancesFlushLimit - balance changes kept in memory before they're applied to the db
+const tokenBalancesFlushLimit = 1_000_000
+
+type TokenTransfersCfg struct {
+	db    kv.RwDB
+	prune prune.Mode
+}
+
+func StageTokenTransfersCfg(db kv.RwDB, prune prune.Mode) TokenTransfersCfg {
+	return TokenTransfersCfg{
+		db:    db,
+		prune: prune,
+	}
+}
+
+// SpawnTokenTransfers indexes the Transfer events of the logs of the executed blocks, for each token and holder, and
+// keeps the balances they imply. The blocks whose receipts are pruned are skipped.
+func SpawnTokenTransfers(s *StageState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) error {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		var err error
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	endBlock, err := s.ExecutionAt(tx)
+	if err != nil {
+		return fmt.Errorf("getting last executed block: %w", err)
+	}
+	if endBlock == s.BlockNumber {
+		return nil
+	}
+	startBlock := s.BlockNumber
+	if startBlock > 0 {
+		startBlock++
+	}
+	keptFrom, err := cfg.prune.KeptFrom(tx, prune.KindReceipts, endBlock)
+	if err != nil {
+		return err
+	}
+	if startBlock < keptFrom {
+		startBlock = keptFrom
+		if err = rawdb.WriteTokenTransfersIndexedFrom(tx, startBlock); err != nil {
+			return err
+		}
+	}
+
+	if err = walkTokenTransfers(s.LogPrefix(), tx, startBlock, endBlock, false, ctx); err != nil {
+		return err
+	}
+	if err = s.Update(tx, endBlock); err != nil {
+		return err
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func UnwindTokenTransfers(u *UnwindState, s *StageState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	if err = walkTokenTransfers(u.LogPrefix(), tx, u.UnwindPoint+1, s.BlockNumber, true, ctx); err != nil {
+		return err
+	}
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTokenTransfers - the transfers are kept whole, the balances depend on all of them
+func PruneTokenTransfers(p *PruneState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+	if err = p.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenBalanceChange - the balance is increased by in and decreased by out, modulo 2^256: unwinding reverts the
+// changes exactly, even for the tokens which don't follow their own events
+type tokenBalanceChange struct {
+	token, holder common.Address
+	in, out       uint256.Int
+}
+
+// walkTokenTransfers indexes the transfers of the blocks from-to, or deletes them and reverts their balance changes
+// on unwind
+func walkTokenTransfers(logPrefix string, tx kv.RwTx, from, to uint64, unwind bool, ctx context.Context) error {
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	changes := map[string]*tokenBalanceChange{}
+	change := func(token, holder common.Address) *tokenBalanceChange {
+		k := string(token[:]) + string(holder[:])
+		c, ok := changes[k]
+		if !ok {
+			c = &tokenBalanceChange{token: token, holder: holder}
+			changes[k] = c
+		}
+		return c
+	}
+	flush := func() error {
+		for _, c := range changes {
+			balance, err := rawdb.ReadTokenBalance(tx, c.token, c.holder)
+			if err != nil {
+				return err
+			}
+			if unwind {
+				balance.Add(balance, &c.out).Sub(balance, &c.in)
+			} else {
+				balance.Add(balance, &c.in).Sub(balance, &c.out)
+			}
+			if err = rawdb.WriteTokenBalance(tx, c.token, c.holder, balance); err != nil {
+				return err
+			}
+		}
+		changes = map[string]*tokenBalanceChange{}
+		return nil
+	}
+
+	logs, err := tx.Cursor(kv.Log)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	reader := bytes.NewReader(nil)
+	var blockNum uint64
+	var logIndex uint32
+	for k, v, err := logs.Seek(dbutils.EncodeBlockNumber(from)); k != nil; k, v, err = logs.Next() {
+		if err != nil {
+			return err
+		}
+		if n := binary.BigEndian.Uint64(k[:8]); n != blockNum {
+			if n > to {
+				break
+			}
+			blockNum, logIndex = n, 0
+		}
+		txIndex := binary.BigEndian.Uint32(k[8:])
+		select {
+		default:
+		case <-ctx.Done():
+			return libcommon.ErrStopped
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "number", blockNum)
+		}
+
+		var ll types.Logs
+		reader.Reset(v)
+		if err := cbor.Unmarshal(&ll, reader); err != nil {
+			return fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, blockNum)
+		}
+		for _, l := range ll {
+			index := logIndex
+			logIndex++
+			sender, recipient, value, nft, ok := ParseTokenTransfer(l)
+			if !ok {
+				continue
+			}
+			amount := value
+			if nft {
+				amount = uint256.NewInt(1)
+			}
+			t := &rawdb.TokenTransfer{Token: l.Address, BlockNumber: blockNum, LogIndex: index, TxIndex: txIndex, NFT: nft, Value: value}
+			// a transfer to self is indexed once, as incoming
+			if sender != (common.Address{}) && sender != recipient {
+				t.Holder, t.Counterparty, t.Incoming = sender, recipient, false
+				if err := writeTokenTransfer(tx, t, unwind); err != nil {
+					return err
+				}
+				c := change(l.Address, sender)
+				c.out.Add(&c.out, amount)
+			}
+			if recipient != (common.Address{}) {
+				t.Holder, t.Counterparty, t.Incoming = recipient, sender, true
+				if err := writeTokenTransfer(tx, t, unwind); err != nil {
+					return err
+				}
+				if sender != recipient {
+					c := change(l.Address, recipient)
+					c.in.Add(&c.in, amount)
+				}
+			}
+		}
+		if len(changes) >= tokenBalancesFlushLimit {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+func writeTokenTransfer(tx kv.RwTx, t *rawdb.TokenTransfer, unwind bool) error {
+	if unwind {
+		return rawdb.DeleteTokenTransfer(tx, t)
+	}
+	return rawdb.WriteTokenTransfer(tx, t)
+}
+
+// ParseTokenTransfer decodes the Transfer event of an ERC-20 token (value is the amount), or of an ERC-721 token
+// (nft, value is the ID of the token). ok is false for the other logs.
+func ParseTokenTransfer(l *types.Log) (sender, recipient common.Address, value *uint256.Int, nft bool, ok bool) {
+	if len(l.Topics) < 3 || l.Topics[0] != TransferEventTopic {
+		return common.Address{}, common.Address{}, nil, false, false
+	}
+	switch {
+	case len(l.Topics) == 3 && len(l.Data) == 32: // ERC-20
+		value = new(uint256.Int).SetBytes(l.Data)
+	case len(l.Topics) == 4 && len(l.Data) == 0: // ERC-721
+		value, nft = new(uint256.Int).SetBytes(l.Topics[3][:]), true
+	default:
+		return common.Address{}, common.Address{}, nil, false, false
+	}
+	return common.BytesToAddress(l.Topics[1][12:]), common.BytesToAddress(l.Topics[2][12:]), value, nft, true
+}
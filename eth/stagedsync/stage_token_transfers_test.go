@@ -0,0 +1,128 @@
+package stagedsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/stretchr/testify/require"
+)
+
+func transferLog(token, from, to common.Address, value uint64, nft bool) *types.Log {
+	l := &types.Log{Address: token, Topics: []common.Hash{TransferEventTopic, from.Hash(), to.Hash()}}
+	v := uint256.NewInt(value).Bytes32()
+	if nft {
+		l.Topics = append(l.Topics, v)
+	} else {
+		l.Data = v[:]
+	}
+	return l
+}
+
+func TestTokenTransfers(t *testing.T) {
+	require, ctx := require.New(t), context.Background()
+	_, tx := memdb.NewTestTx(t)
+
+	erc20, erc721 := common.Address{0xe2}, common.Address{0xe7}
+	alice, bob := common.Address{0xa}, common.Address{0xb}
+	blocks := []types.Receipts{
+		{{Logs: types.Logs{transferLog(erc20, common.Address{}, alice, 100, false)}}},
+		{
+			{Logs: types.Logs{{Address: erc20, Topics: []common.Hash{{1}}}}},
+			{Logs: types.Logs{transferLog(erc20, alice, bob, 30, false), transferLog(erc721, common.Address{}, bob, 7, true)}},
+		},
+		{{Logs: types.Logs{transferLog(erc20, bob, alice, 10, false), transferLog(erc20, alice, alice, 5, false)}}},
+	}
+	for i, receipts := range blocks {
+		require.NoError(rawdb.AppendReceipts(tx, uint64(i+1), receipts))
+	}
+	balance := func(token, holder common.Address) uint64 {
+		b, err := rawdb.ReadTokenBalance(tx, token, holder)
+		require.NoError(err)
+		return b.Uint64()
+	}
+
+	require.NoError(walkTokenTransfers("", tx, 1, 3, false, ctx))
+	require.Equal(uint64(80), balance(erc20, alice))
+	require.Equal(uint64(20), balance(erc20, bob))
+	require.Equal(uint64(1), balance(erc721, bob))
+	require.Equal(uint64(0), balance(erc20, common.Address{}))
+
+	transfers, next, err := rawdb.ReadTokenTransfers(tx, erc20, alice, 0, 100)
+	require.NoError(err)
+	require.Equal(uint64(0), next)
+	require.Len(transfers, 4)
+	require.True(transfers[0].Incoming)
+	require.Equal(uint64(100), transfers[0].Value.Uint64())
+	require.False(transfers[1].Incoming)
+	require.Equal(bob, transfers[1].Counterparty)
+	require.Equal(uint32(1), transfers[1].LogIndex)
+	require.Equal(uint32(1), transfers[1].TxIndex)
+	require.True(transfers[3].Incoming)
+	require.Equal(alice, transfers[3].Counterparty)
+
+	transfers, next, err = rawdb.ReadTokenTransfers(tx, erc721, bob, 0, 100)
+	require.NoError(err)
+	require.Len(transfers, 1)
+	require.True(transfers[0].NFT)
+	require.Equal(uint64(7), transfers[0].Value.Uint64())
+
+	// Pages end with the transfers of the whole block
+	transfers, next, err = rawdb.ReadTokenTransfers(tx, erc20, alice, 0, 1)
+	require.NoError(err)
+	require.Len(transfers, 1)
+	require.Equal(uint64(2), next)
+	transfers, next, err = rawdb.ReadTokenTransfers(tx, erc20, alice, 3, 1)
+	require.NoError(err)
+	require.Len(transfers, 2)
+	require.Equal(uint64(0), next)
+
+	// Unwind reverts the transfers of the unwound blocks
+	require.NoError(walkTokenTransfers("", tx, 3, 3, true, ctx))
+	require.Equal(uint64(70), balance(erc20, alice))
+	require.Equal(uint64(30), balance(erc20, bob))
+	transfers, _, err = rawdb.ReadTokenTransfers(tx, erc20, alice, 0, 100)
+	require.NoError(err)
+	require.Len(transfers, 2)
+
+	require.NoError(walkTokenTransfers("", tx, 1, 2, true, ctx))
+	require.Equal(uint64(0), balance(erc20, alice))
+	require.Equal(uint64(0), balance(erc721, bob))
+}
+
+func TestTokenTransfersPrunedReceipts(t *testing.T) {
+	require, ctx := require.New(t), context.Background()
+	_, tx := memdb.NewTestTx(t)
+
+	erc20, erc721 := common.Address{0xe2}, common.Address{0xe7}
+	alice, bob := common.Address{0xa}, common.Address{0xb}
+	blocks := []types.Receipts{
+		{{Logs: types.Logs{transferLog(erc20, common.Address{}, alice, 100, false)}}},
+		{{Logs: types.Logs{transferLog(erc20, alice, bob, 30, false), transferLog(erc721, common.Address{}, bob, 7, true)}}},
+		{{Logs: types.Logs{transferLog(erc20, bob, alice, 10, false)}}},
+	}
+	for i, receipts := range blocks {
+		require.NoError(rawdb.AppendReceipts(tx, uint64(i+1), receipts))
+	}
+	require.NoError(stages.SaveStageProgress(tx, stages.Execution, 3))
+
+	// the receipts are kept from block 2: the stage indexes it, and records that it indexed from there
+	cfg := StageTokenTransfersCfg(nil, prune.Mode{Receipts: prune.Distance(1)})
+	require.NoError(SpawnTokenTransfers(&StageState{ID: stages.TokenTransfers}, tx, cfg, ctx))
+	indexedFrom, err := rawdb.ReadTokenTransfersIndexedFrom(tx)
+	require.NoError(err)
+	require.Equal(uint64(2), indexedFrom)
+
+	balance, err := rawdb.ReadTokenBalance(tx, erc20, bob)
+	require.NoError(err)
+	require.Equal(uint64(20), balance.Uint64())
+	balance, err = rawdb.ReadTokenBalance(tx, erc721, bob)
+	require.NoError(err)
+	require.Equal(uint64(1), balance.Uint64())
+}
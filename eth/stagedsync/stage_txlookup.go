@@ -184,7 +184,9 @@ func PruneTxLookup(s *PruneState, tx kv.RwTx, cfg TxLookupCfg, ctx context.Conte
 
 	// Forward stage doesn't write anything before PruneTo point
 	if cfg.prune.TxIndex.Enabled() {
-		blockTo = cfg.prune.TxIndex.PruneTo(s.ForwardProgress)
+		if blockTo, err = cfg.prune.PruneTo(tx, prune.KindTxIndex, s.ForwardProgress); err != nil {
+			return err
+		}
 	} else if cfg.snapshots != nil && cfg.snapshots.Cfg().Enabled {
 		blockTo = snapshotsync.CanDeleteTo(s.ForwardProgress, cfg.snapshots)
 	}
@@ -15,7 +15,7 @@ type ChainEventNotifier interface {
 	OnNewHeader(newHeadersRlp [][]byte)
 	OnNewPendingLogs(types.Logs)
 	OnLogs([]*remote.SubscribeLogsReply)
-	HasLogSubsriptions() bool
+	HasLogSubsriptions() (bool, *privateapi.LogsInterest)
 }
 
 type Notifications struct {
@@ -43,6 +43,7 @@ var (
 	LogIndex            SyncStage = "LogIndex"            // Generating logs index (from receipts)
 	CallTraces          SyncStage = "CallTraces"          // Generating call traces index
 	TxLookup            SyncStage = "TxLookup"            // Generating transactions lookup index
+	TokenTransfers      SyncStage = "TokenTransfers"      // Indexing the transfers of ERC-20 and ERC-721 tokens (from receipts)
 	Issuance            SyncStage = "WatchTheBurn"        // Compute ether issuance for each block
 	Finish              SyncStage = "Finish"              // Nominal stage after all other stages
 
@@ -63,6 +64,7 @@ var AllStages = []SyncStage{
 	AccountHistoryIndex,
 	StorageHistoryIndex,
 	LogIndex,
+	TokenTransfers,
 	CallTraces,
 	TxLookup,
 	Finish,
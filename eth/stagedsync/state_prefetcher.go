@@ -0,0 +1,143 @@
+package stagedsync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	prefetchDistance = 8 // how many blocks ahead of the execution are read
+	prefetchWorkers  = 2
+)
+
+// statePrefetcher reads the blocks ahead of the execution, and lets the workers touch the state they are
+// going to use: the coinbase, the senders and recipients with their code, and the storage slots of the
+// access lists. The workers read in their own transactions, so they only bring the database pages into
+// memory - the values are never handed to the execution, which has to see the uncommitted changes of
+// its own batch.
+type statePrefetcher struct {
+	db     kv.RoDB
+	blocks chan *types.Block
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	ahead map[uint64]*types.Block // the blocks read ahead, by number
+	next  uint64                  // the next block to read ahead
+}
+
+func newStatePrefetcher(ctx context.Context, db kv.RoDB, workers int) *statePrefetcher {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &statePrefetcher{db: db, blocks: make(chan *types.Block, prefetchDistance), cancel: cancel, ahead: map[uint64]*types.Block{}}
+	if db == nil {
+		workers = 0
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for block := range p.blocks {
+				if ctx.Err() != nil {
+					continue // drain
+				}
+				if err := p.warm(ctx, block); err != nil && ctx.Err() == nil {
+					log.Debug("State prefetch failed", "block", block.NumberU64(), "err", err)
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// block returns the block with senders to execute, and queues the following blocks up to the given one for prefetching.
+// Returns nil if the block is not found.
+func (p *statePrefetcher) block(ctx context.Context, tx kv.Tx, blockReader services.FullBlockReader, blockNum, to uint64) (*types.Block, error) {
+	if p.next <= blockNum {
+		p.next = blockNum
+	}
+	for ; p.next <= to && p.next <= blockNum+prefetchDistance; p.next++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, p.next)
+		if err != nil {
+			return nil, err
+		}
+		block, _, err := blockReader.BlockWithSenders(ctx, tx, hash, p.next)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		p.ahead[p.next] = block
+		if p.next > blockNum {
+			select {
+			case p.blocks <- block:
+			default: // the workers are behind, the execution will read the state itself
+			}
+		}
+	}
+	block := p.ahead[blockNum]
+	delete(p.ahead, blockNum)
+	return block, nil
+}
+
+func (p *statePrefetcher) warm(ctx context.Context, block *types.Block) error {
+	return p.db.View(ctx, func(tx kv.Tx) error {
+		r := state.NewPlainStateReader(tx)
+		touch := func(address common.Address, slots []common.Hash) error {
+			acc, err := r.ReadAccountData(address)
+			if err != nil || acc == nil {
+				return err
+			}
+			if !acc.IsEmptyCodeHash() {
+				if _, err := r.ReadAccountCode(address, acc.Incarnation, acc.CodeHash); err != nil {
+					return err
+				}
+			}
+			for i := range slots {
+				if _, err := r.ReadAccountStorage(address, acc.Incarnation, &slots[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := touch(block.Coinbase(), nil); err != nil {
+			return err
+		}
+		for _, txn := range block.Transactions() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if sender, ok := txn.GetSender(); ok {
+				if err := touch(sender, nil); err != nil {
+					return err
+				}
+			}
+			if to := txn.GetTo(); to != nil {
+				if err := touch(*to, nil); err != nil {
+					return err
+				}
+			}
+			for _, tuple := range txn.GetAccessList() {
+				if err := touch(tuple.Address, tuple.StorageKeys); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// close stops the workers, and waits for them to finish.
+func (p *statePrefetcher) close() {
+	p.cancel()
+	close(p.blocks)
+	p.wg.Wait()
+}
@@ -0,0 +1,44 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatePrefetcherReturnsBlocksInOrder(t *testing.T) {
+	ctx := context.Background()
+	db, tx := memdb.NewTestTx(t)
+
+	const last = 20
+	for i := uint64(1); i <= last; i++ {
+		txn := types.NewTransaction(i, common.Address{byte(i)}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i), Coinbase: common.Address{0xc}}, []types.Transaction{txn}, nil, nil)
+		require.NoError(t, rawdb.WriteBlock(tx, block))
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, block.Hash(), i))
+	}
+
+	p := newStatePrefetcher(ctx, db, prefetchWorkers)
+	defer p.close()
+	blockReader := snapshotsync.NewBlockReader()
+	for i := uint64(1); i <= last; i++ {
+		block, err := p.block(ctx, tx, blockReader, i, last+1)
+		require.NoError(t, err)
+		require.NotNil(t, block)
+		require.Equal(t, i, block.NumberU64())
+		require.Equal(t, i, block.Transactions()[0].GetNonce())
+	}
+	// the missing block
+	block, err := p.block(ctx, tx, blockReader, last+1, last+1)
+	require.NoError(t, err)
+	require.Nil(t, block)
+	require.Empty(t, p.ahead)
+}
@@ -0,0 +1,164 @@
+package stagedsync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// stemTreeCommitment keeps a stem tree of the state besides the trie (the stemtree experiment): the tree is built
+// from the plain state - the migration - and then updated with the changes written by the execution, so that both
+// commitments are computed for every block. It only lives in memory, and is built again after a restart or an unwind.
+type stemTreeCommitment struct {
+	tree       *trie.StemTree
+	blockNum   uint64 // the block whose state the tree holds
+	valid      bool
+	commitTime time.Duration // spent committing the blocks since the last report
+}
+
+func newStemTreeCommitment() *stemTreeCommitment {
+	return &stemTreeCommitment{}
+}
+
+// ensure builds the tree from the plain state of tx, unless it already holds the state of the block.
+func (v *stemTreeCommitment) ensure(logPrefix string, tx kv.Tx, blockNum uint64, quit <-chan struct{}) error {
+	if v.valid && v.blockNum == blockNum {
+		return nil
+	}
+	v.valid = false
+	log.Info(fmt.Sprintf("[%s] Building the stem tree of the state", logPrefix), "block", blockNum)
+	start := time.Now()
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	tree := trie.NewStemTree(trie.KeccakStemCommitter{})
+	w := state.NewStemTreeStateWriter(tree)
+	var address common.Address
+	var acc accounts.Account
+	var accountsCount, slots int
+	// the accounts come before their storage in the plain state
+	if err := tx.ForEach(kv.PlainState, nil, func(k, val []byte) error {
+		if err := libcommon.Stopped(quit); err != nil {
+			return err
+		}
+		select {
+		default:
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Building the stem tree", logPrefix), "accounts", accountsCount, "slots", slots, "key", fmt.Sprintf("%x", k[:4]))
+		}
+		if len(k) == length.Addr {
+			copy(address[:], k)
+			if err := acc.DecodeForStorage(val); err != nil {
+				return err
+			}
+			accountsCount++
+			if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
+				codeHash, err := tx.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(k, acc.Incarnation))
+				if err != nil {
+					return err
+				}
+				if len(codeHash) > 0 {
+					acc.CodeHash = common.BytesToHash(codeHash)
+				}
+			}
+			if err := w.UpdateAccountData(address, nil, &acc); err != nil {
+				return err
+			}
+			if acc.IsEmptyCodeHash() {
+				return nil
+			}
+			code, err := tx.GetOne(kv.Code, acc.CodeHash[:])
+			if err != nil {
+				return err
+			}
+			return w.UpdateAccountCode(address, acc.Incarnation, acc.CodeHash, code)
+		}
+		addr, incarnation, slot := dbutils.PlainParseCompositeStorageKey(k)
+		if addr != address || incarnation != acc.Incarnation {
+			return nil // the storage of a previous incarnation
+		}
+		slots++
+		var value, original = new(uint256.Int).SetBytes(val), new(uint256.Int)
+		return w.WriteAccountStorage(address, incarnation, &slot, original, value)
+	}); err != nil {
+		return err
+	}
+	root := tree.Root()
+	log.Info(fmt.Sprintf("[%s] Built the stem tree of the state", logPrefix), "block", blockNum, "accounts", accountsCount, "slots", slots,
+		"leaves", tree.Leaves(), "root", fmt.Sprintf("%x", root), "took", time.Since(start))
+	v.tree, v.blockNum, v.valid, v.commitTime = tree, blockNum, true, 0
+	return nil
+}
+
+// writer returns the writer of the execution, writing to the tree as well.
+func (v *stemTreeCommitment) writer(w state.WriterWithChangeSets) state.WriterWithChangeSets {
+	return &stemTreeWriter{WriterWithChangeSets: w, stemTree: state.NewStemTreeStateWriter(v.tree)}
+}
+
+// commit computes the commitment of the tree once the block has been executed.
+func (v *stemTreeCommitment) commit(blockNum uint64) {
+	start := time.Now()
+	root := v.tree.Root()
+	v.commitTime += time.Since(start)
+	v.blockNum = blockNum
+	log.Debug("Stem tree root", "block", blockNum, "root", fmt.Sprintf("%x", root))
+}
+
+func (v *stemTreeCommitment) report(logPrefix string) {
+	if !v.valid {
+		return
+	}
+	log.Info(fmt.Sprintf("[%s] Stem tree commitment", logPrefix), "block", v.blockNum, "root", fmt.Sprintf("%x", v.tree.Root()), "leaves", v.tree.Leaves(), "took", v.commitTime)
+	v.commitTime = 0
+}
+
+// stemTreeWriter writes the changes of the execution to the stem tree as well.
+type stemTreeWriter struct {
+	state.WriterWithChangeSets
+	stemTree *state.StemTreeStateWriter
+}
+
+func (w *stemTreeWriter) ChangeSetWriter() *state.ChangeSetWriter {
+	if hasChangeSet, ok := w.WriterWithChangeSets.(HasChangeSetWriter); ok {
+		return hasChangeSet.ChangeSetWriter()
+	}
+	return nil
+}
+
+func (w *stemTreeWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	if err := w.stemTree.UpdateAccountData(address, original, account); err != nil {
+		return err
+	}
+	return w.WriterWithChangeSets.UpdateAccountData(address, original, account)
+}
+
+func (w *stemTreeWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	if err := w.stemTree.UpdateAccountCode(address, incarnation, codeHash, code); err != nil {
+		return err
+	}
+	return w.WriterWithChangeSets.UpdateAccountCode(address, incarnation, codeHash, code)
+}
+
+func (w *stemTreeWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	if err := w.stemTree.DeleteAccount(address, original); err != nil {
+		return err
+	}
+	return w.WriterWithChangeSets.DeleteAccount(address, original)
+}
+
+func (w *stemTreeWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	if err := w.stemTree.WriteAccountStorage(address, incarnation, key, original, value); err != nil {
+		return err
+	}
+	return w.WriterWithChangeSets.WriteAccountStorage(address, incarnation, key, original, value)
+}
@@ -0,0 +1,64 @@
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStemTreeCommitment(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	generateBlocks(t, 1, 50, plainWriterGen(tx), changeCodeWithIncarnations)
+
+	incremental := newStemTreeCommitment()
+	require.NoError(t, incremental.ensure("logPrefix", tx, 50, nil))
+	require.Equal(t, uint64(50), incremental.blockNum)
+	root := incremental.tree.Root()
+	require.NoError(t, incremental.ensure("logPrefix", tx, 50, nil)) // kept
+	require.Equal(t, root, incremental.tree.Root())
+
+	generateBlocks(t, 51, 50, func(blockNum uint64) state.WriterWithChangeSets {
+		return incremental.writer(state.NewPlainStateWriter(tx, tx, blockNum))
+	}, changeCodeWithIncarnations)
+	incremental.commit(100)
+	require.NotEqual(t, root, incremental.tree.Root())
+
+	migrated := newStemTreeCommitment()
+	require.NoError(t, migrated.ensure("logPrefix", tx, 100, nil))
+
+	// the values of the current state are the same, whether they were migrated or written by the execution
+	c := migrated.tree.Committer()
+	var incarnation uint64
+	var checked int
+	require.NoError(t, tx.ForEach(kv.PlainState, nil, func(k, v []byte) error {
+		var key []byte
+		if len(k) == length.Addr {
+			var acc accounts.Account
+			require.NoError(t, acc.DecodeForStorage(v))
+			incarnation = acc.Incarnation
+			for _, leafKey := range []byte{trie.StemBalanceLeafKey, trie.StemNonceLeafKey, trie.StemCodeKeccakLeafKey, trie.StemCodeSizeLeafKey} {
+				key = trie.StemHeaderKey(c, k, leafKey)
+				require.Equal(t, migrated.tree.Get(key), incremental.tree.Get(key))
+			}
+		} else {
+			addr, inc, slot := dbutils.PlainParseCompositeStorageKey(k)
+			if inc != incarnation {
+				return nil
+			}
+			key = trie.StemStorageKey(c, addr[:], new(uint256.Int).SetBytes(slot[:]))
+			require.NotNil(t, migrated.tree.Get(key))
+			require.Equal(t, migrated.tree.Get(key), incremental.tree.Get(key))
+		}
+		checked++
+		return nil
+	}))
+	require.Greater(t, checked, 0)
+}
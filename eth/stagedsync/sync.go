@@ -11,12 +11,20 @@ import (
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/log/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer makes a span of each sync cycle, and of each stage run, unwound or pruned in it
+var tracer = otel.Tracer("github.com/ledgerwatch/erigon/eth/stagedsync")
+
 type Sync struct {
 	unwindPoint     *uint64 // used to run stages
 	prevUnwindPoint *uint64 // used to get value from outside of staged sync after cycle (for example to notify RPCDaemon)
 	badBlock        common.Hash
+	revert          bool
 
 	stages       []*Stage
 	unwindOrder  []*Stage
@@ -24,6 +32,7 @@ type Sync struct {
 	currentStage uint
 	timings      []Timing
 	logPrefixes  []string
+	traceCtx     context.Context // the parent of the spans of the stages: the cycle, or the engine API request it handles
 }
 
 type Timing struct {
@@ -37,7 +46,7 @@ func (s *Sync) Len() int                 { return len(s.stages) }
 func (s *Sync) PrevUnwindPoint() *uint64 { return s.prevUnwindPoint }
 
 func (s *Sync) NewUnwindState(id stages.SyncStage, unwindPoint, currentProgress uint64) *UnwindState {
-	return &UnwindState{id, unwindPoint, currentProgress, common.Hash{}, s}
+	return &UnwindState{id, unwindPoint, currentProgress, common.Hash{}, false, s}
 }
 
 func (s *Sync) PruneStageState(id stages.SyncStage, forwardProgress uint64, tx kv.Tx, db kv.RwDB) (*PruneState, error) {
@@ -109,6 +118,14 @@ func (s *Sync) UnwindTo(unwindPoint uint64, badBlock common.Hash) {
 	log.Info("UnwindTo", "block", unwindPoint, "bad_block_hash", badBlock.String())
 	s.unwindPoint = &unwindPoint
 	s.badBlock = badBlock
+	s.revert = false
+}
+
+func (s *Sync) RevertTo(unwindPoint uint64) {
+	log.Info("RevertTo", "block", unwindPoint)
+	s.unwindPoint = &unwindPoint
+	s.badBlock = common.Hash{}
+	s.revert = true
 }
 
 func (s *Sync) IsDone() bool {
@@ -162,6 +179,7 @@ func New(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) *S
 		unwindOrder:  unwindStages,
 		pruningOrder: pruneStages,
 		logPrefixes:  logPrefixes,
+		traceCtx:     context.Background(),
 	}
 }
 
@@ -189,9 +207,38 @@ func (s *Sync) StageState(stage stages.SyncStage, tx kv.Tx, db kv.RoDB) (*StageS
 	return &StageState{s, stage, blockNum}, nil
 }
 
-func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool) error {
+// TraceRequest makes the spans of the stages of the rest of the cycle part of the trace of the engine API request of
+// the span context, and returns the context of the trace.
+func (s *Sync) TraceRequest(spanCtx trace.SpanContext) context.Context {
+	if spanCtx.IsValid() {
+		s.traceCtx = trace.ContextWithRemoteSpanContext(s.traceCtx, spanCtx)
+	}
+	return s.traceCtx
+}
+
+// startSpan starts the span of a stage, ended by endSpan
+func (s *Sync) startSpan(name string, stage stages.SyncStage, progress uint64) trace.Span {
+	_, span := tracer.Start(s.traceCtx, name+" "+string(stage), trace.WithAttributes(
+		attribute.String("stage", string(stage)),
+		attribute.Int64("stage.progress", int64(progress)),
+	))
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool) (err error) {
 	s.prevUnwindPoint = nil
 	s.timings = s.timings[:0]
+	var span trace.Span
+	s.traceCtx, span = tracer.Start(context.Background(), "sync cycle", trace.WithAttributes(attribute.Bool("firstCycle", firstCycle)))
+	defer func() { endSpan(span, err) }()
 
 	for !s.IsDone() {
 		var badBlockUnwind bool
@@ -206,10 +253,12 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool) error {
 			}
 			s.prevUnwindPoint = s.unwindPoint
 			s.unwindPoint = nil
-			if s.badBlock != (common.Hash{}) {
+			// after a bad block or a revert the cycle moves on without waiting for new headers
+			if s.badBlock != (common.Hash{}) || s.revert {
 				badBlockUnwind = true
 			}
 			s.badBlock = common.Hash{}
+			s.revert = false
 			if err := s.SetCurrentStage(s.stages[0].ID); err != nil {
 				return err
 			}
@@ -324,7 +373,10 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, tx kv.RwTx, firstCycle bool, b
 		return err
 	}
 
-	if err = stage.Forward(firstCycle, badBlockUnwind, stageState, s, tx); err != nil {
+	span := s.startSpan("forward", stage.ID, stageState.BlockNumber)
+	err = stage.Forward(firstCycle, badBlockUnwind, stageState, s, tx)
+	endSpan(span, err)
+	if err != nil {
 		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
 	}
 
@@ -347,6 +399,7 @@ func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx
 
 	unwind := s.NewUnwindState(stage.ID, *s.unwindPoint, stageState.BlockNumber)
 	unwind.BadBlock = s.badBlock
+	unwind.Revert = s.revert
 
 	if stageState.BlockNumber <= unwind.UnwindPoint {
 		return nil
@@ -356,7 +409,10 @@ func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx
 		return err
 	}
 
+	span := s.startSpan("unwind", stage.ID, stageState.BlockNumber)
+	span.SetAttributes(attribute.Int64("unwind.point", int64(unwind.UnwindPoint)))
 	err = stage.Unwind(firstCycle, unwind, stageState, tx)
+	endSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
 	}
@@ -387,7 +443,9 @@ func (s *Sync) pruneStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx)
 		return err
 	}
 
+	span := s.startSpan("prune", stage.ID, stageState.BlockNumber)
 	err = stage.Prune(firstCycle, prune, tx)
+	endSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
 	}
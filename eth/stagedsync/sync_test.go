@@ -1,6 +1,7 @@
 package stagedsync
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -10,6 +11,9 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestStagesSuccess(t *testing.T) {
@@ -337,6 +341,61 @@ func TestUnwind(t *testing.T) {
 
 }
 
+func TestRevertTo(t *testing.T) {
+	var reverts []bool
+	var badBlockUnwinds []bool
+	reverted := false
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				badBlockUnwinds = append(badBlockUnwinds, badBlockUnwind)
+				if s.BlockNumber == 0 {
+					return s.Update(tx, 2000)
+				}
+				return nil
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				reverts = append(reverts, u.Revert)
+				return u.Done(tx)
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodiess",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				if !reverted {
+					reverted = true
+					u.RevertTo(500)
+					return s.Update(tx, 2000)
+				}
+				return nil
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				reverts = append(reverts, u.Revert)
+				return u.Done(tx)
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[1].ID, s[0].ID}, nil)
+	db, tx := memdb.NewTestTx(t)
+	assert.NoError(t, state.Run(db, tx, true))
+
+	// the unwind of a revert is told to every stage, and the cycle after it doesn't wait for new headers
+	assert.Equal(t, []bool{true, true}, reverts)
+	assert.Equal(t, []bool{false, true}, badBlockUnwinds)
+	stageState, err := state.StageState(stages.Headers, tx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, int(stageState.BlockNumber))
+
+	// the next unwind isn't a revert
+	reverts = reverts[:0]
+	state.UnwindTo(100, common.Hash{})
+	assert.NoError(t, state.Run(db, tx, true))
+	assert.Equal(t, []bool{false, false}, reverts)
+}
+
 func TestUnwindEmptyUnwinder(t *testing.T) {
 	flow := make([]stages.SyncStage, 0)
 	unwound := false
@@ -616,3 +675,40 @@ func TestSyncInterruptLongUnwind(t *testing.T) {
 func unwindOf(s stages.SyncStage) stages.SyncStage {
 	return stages.SyncStage(append([]byte(s), 0xF0))
 }
+
+func TestStagesTraceRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, request := otel.Tracer("test").Start(context.Background(), "request")
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				s.state.TraceRequest(request.SpanContext())
+				return nil
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodiess",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				return nil
+			},
+		},
+	}
+	state := New(s, nil, nil)
+	db, tx := memdb.NewTestTx(t)
+	err := state.Run(db, tx, true)
+	assert.NoError(t, err)
+	request.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	// the stages after the request was taken are a part of its trace
+	assert.Equal(t, spans["sync cycle"].SpanContext().SpanID(), spans["forward Headers"].Parent().SpanID())
+	assert.Equal(t, request.SpanContext().SpanID(), spans["forward Bodies"].Parent().SpanID())
+	assert.Equal(t, request.SpanContext().TraceID(), spans["forward Bodies"].SpanContext().TraceID())
+}
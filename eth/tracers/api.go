@@ -1,6 +1,8 @@
 package tracers
 
 import (
+	"encoding/json"
+
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 )
@@ -9,8 +11,10 @@ import (
 type TraceConfig struct {
 	*vm.LogConfig
 	Tracer         *string
+	TracerConfig   json.RawMessage // Configuration of the native tracers, e.g. {"diffMode": true} for prestateTracer
 	Timeout        *string
 	Reexec         *uint64
 	NoRefunds      *bool // Turns off gas refunds when tracing
 	StateOverrides *ethapi.StateOverrides
+	BlockOverrides *ethapi.BlockOverrides // Only used by debug_traceCall
 }
@@ -105,6 +105,9 @@ func (vm *JSVM) GetBuffer(index int) (rawPtr unsafe.Pointer, outSize uint) {
 func (vm *JSVM) GetPropString(objIndex int, key string) bool {
 	obj := vm.stack[objIndex].ToObject(vm.vm)
 	v := obj.Get(key)
+	if v == nil { // the property doesn't exist
+		v = goja.Undefined()
+	}
 	vm.stack = append(vm.stack, v)
 	return !goja.IsUndefined(v)
 }
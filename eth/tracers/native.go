@@ -0,0 +1,44 @@
+package tracers
+
+import (
+	"encoding/json"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// NativeTracer is a tracer implemented in Go, which writes its result straight to the output stream instead of
+// assembling it in memory, for the large transactions.
+type NativeTracer interface {
+	vm.Tracer
+	// CaptureTxStart is called before the execution of the traced message, with its gas limit.
+	CaptureTxStart(gasLimit uint64)
+	// CaptureTxEnd is called after the execution of the traced message, with the gas left.
+	CaptureTxEnd(restGas uint64)
+	// WriteResult writes the result of the trace, or returns the error which interrupted it.
+	WriteResult(stream *jsoniter.Stream) error
+	// Stop interrupts the trace at the first opportune moment.
+	Stop(err error)
+}
+
+// NativeConstructor creates a native tracer from the tracer configuration of the request, if any.
+type NativeConstructor func(cfg json.RawMessage) (NativeTracer, error)
+
+// natives contains the native tracers by name.
+var natives = make(map[string]NativeConstructor)
+
+// RegisterNative makes a native tracer available by name. The native tracers take precedence over the JavaScript
+// tracers of the same name.
+func RegisterNative(name string, ctor NativeConstructor) {
+	natives[name] = ctor
+}
+
+// NewNative creates the native tracer of the given name, if there is one.
+func NewNative(name string, cfg json.RawMessage) (NativeTracer, bool, error) {
+	ctor, ok := natives[name]
+	if !ok {
+		return nil, false, nil
+	}
+	tracer, err := ctor(cfg)
+	return tracer, true, err
+}
@@ -0,0 +1,356 @@
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	tracers.RegisterNative("bundlerCollectorTracer", func(cfg json.RawMessage) (tracers.NativeTracer, error) {
+		return NewBundlerCollectorTracer(), nil
+	})
+}
+
+// maxCallOutput is the maximum length of the outputs of the calls reported by the bundlerCollectorTracer.
+const maxCallOutput = 2000
+
+// beforeExecutionTopic is the topic of the BeforeExecution event of the EntryPoint, which ends the validation of
+// the user operations.
+var beforeExecutionTopic = crypto.Keccak256Hash([]byte("BeforeExecution()"))
+
+// BundlerCollectorResult is what the bundlerCollectorTracer collects about the validation of ERC-4337 user operations
+// by the EntryPoint: what each of the calls of the EntryPoint does, the preimages of the keccak hashes, the calls and
+// the logs.
+type BundlerCollectorResult struct {
+	CallsFromEntryPoint []*TopLevelCallInfo `json:"callsFromEntryPoint"`
+	Keccak              []hexutil.Bytes     `json:"keccak"`
+	Calls               []*CallFrameInfo    `json:"calls"`
+	Logs                []*LogInfo          `json:"logs"`
+	Debug               []string            `json:"debug"`
+}
+
+// TopLevelCallInfo is what a call of the EntryPoint does: its opcodes, the storage it accesses, the code sizes and
+// code of the other contracts it looks at, and whether it runs out of gas.
+type TopLevelCallInfo struct {
+	TopLevelMethodSig     hexutil.Bytes                        `json:"topLevelMethodSig"`
+	TopLevelTargetAddress common.Address                       `json:"topLevelTargetAddress"`
+	Opcodes               map[string]uint64                    `json:"opcodes"`
+	Access                map[common.Address]*AccessInfo       `json:"access"`
+	ContractSize          map[common.Address]*ContractSizeInfo `json:"contractSize"`
+	ExtCodeAccessInfo     map[common.Address]string            `json:"extCodeAccessInfo"`
+	OOG                   bool                                 `json:"oog"`
+}
+
+// AccessInfo is the storage of a contract a call accesses: the slots read, with their value before the first
+// access, and the number of writes of each slot.
+type AccessInfo struct {
+	Reads  map[common.Hash]common.Hash `json:"reads"`
+	Writes map[common.Hash]uint64      `json:"writes"`
+}
+
+// ContractSizeInfo is the code size of a contract, and the opcode which first looks at it.
+type ContractSizeInfo struct {
+	ContractSize int    `json:"contractSize"`
+	Opcode       string `json:"opcode"`
+}
+
+// CallFrameInfo is the start of a call, or its end with the type RETURN or REVERT.
+type CallFrameInfo struct {
+	Type    string          `json:"type"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Method  hexutil.Bytes   `json:"method,omitempty"`
+	Gas     *uint64         `json:"gas,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	GasUsed *uint64         `json:"gasUsed,omitempty"`
+	Data    hexutil.Bytes   `json:"data,omitempty"`
+}
+
+// LogInfo is a log emitted during the validation.
+type LogInfo struct {
+	Topics []common.Hash `json:"topics"`
+	Data   hexutil.Bytes `json:"data"`
+}
+
+type extCodeAccess struct {
+	addr common.Address
+	op   vm.OpCode
+}
+
+// BundlerCollectorTracer collects what the ERC-4337 bundlers need to check the validation of user operations against
+// the rules of the specification, like the bundlerCollectorTracer JavaScript tracer of the reference bundler. The
+// validation is made of the calls of the EntryPoint to the entities: the factory, the account and the paymaster.
+type BundlerCollectorTracer struct {
+	result         BundlerCollectorResult
+	currentLevel   *TopLevelCallInfo
+	stopCollecting bool
+	lastOp         vm.OpCode
+	lastExtCode    *extCodeAccess
+	precompiles    map[common.Address]struct{}
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// NewBundlerCollectorTracer returns a bundlerCollectorTracer.
+func NewBundlerCollectorTracer() *BundlerCollectorTracer {
+	return &BundlerCollectorTracer{
+		result: BundlerCollectorResult{
+			CallsFromEntryPoint: []*TopLevelCallInfo{},
+			Keccak:              []hexutil.Bytes{},
+			Calls:               []*CallFrameInfo{},
+			Logs:                []*LogInfo{},
+			Debug:               []string{},
+		},
+		precompiles: make(map[common.Address]struct{}),
+	}
+}
+
+// Result returns what the tracer has collected.
+func (t *BundlerCollectorTracer) Result() *BundlerCollectorResult {
+	return &t.result
+}
+
+func (t *BundlerCollectorTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *BundlerCollectorTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *BundlerCollectorTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth == 0 {
+		for _, addr := range vm.ActivePrecompiles(env.ChainRules(), env.Context().BlockNumber) {
+			t.precompiles[addr] = struct{}{}
+		}
+		return
+	}
+	if t.stopCollecting {
+		return
+	}
+	frame := &CallFrameInfo{Type: callTypeName(callType), From: &from, To: &to, Gas: &gas}
+	frame.Method = common.CopyBytes(input)
+	if len(frame.Method) > 4 {
+		frame.Method = frame.Method[:4]
+	}
+	// The delegate and static calls have no value
+	if value != nil && value.Sign() >= 0 {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	t.result.Calls = append(t.result.Calls, frame)
+}
+
+func (t *BundlerCollectorTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+	if depth == 0 || t.stopCollecting {
+		return
+	}
+	frame := &CallFrameInfo{Type: "RETURN", Data: common.CopyBytes(output)}
+	if err != nil {
+		frame.Type = "REVERT"
+	}
+	if len(frame.Data) > maxCallOutput {
+		frame.Data = frame.Data[:maxCallOutput]
+	}
+	gasUsed := startGas - endGas
+	frame.GasUsed = &gasUsed
+	t.result.Calls = append(t.result.Calls, frame)
+}
+
+func (t *BundlerCollectorTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// If tracing was interrupted, stop the execution
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return
+	}
+	if t.stopCollecting {
+		return
+	}
+	if err != nil {
+		if t.currentLevel != nil && (errors.Is(err, vm.ErrOutOfGas) || gas < cost) {
+			t.currentLevel.OOG = true
+		}
+		return
+	}
+	stack, memory := scope.Stack, scope.Memory
+
+	// The calls of the EntryPoint start the validation by each of the entities
+	if depth == 1 {
+		switch {
+		case op == vm.CALL || op == vm.STATICCALL:
+			argsOffset := stack.Back(3)
+			if op == vm.STATICCALL {
+				argsOffset = stack.Back(2)
+			}
+			t.currentLevel = &TopLevelCallInfo{
+				TopLevelMethodSig:     memorySlice(memory, argsOffset, uint256.NewInt(4)),
+				TopLevelTargetAddress: common.Address(stack.Back(1).Bytes20()),
+				Opcodes:               make(map[string]uint64),
+				Access:                make(map[common.Address]*AccessInfo),
+				ContractSize:          make(map[common.Address]*ContractSizeInfo),
+				ExtCodeAccessInfo:     make(map[common.Address]string),
+			}
+			t.result.CallsFromEntryPoint = append(t.result.CallsFromEntryPoint, t.currentLevel)
+		case op == vm.LOG1 && common.Hash(stack.Back(2).Bytes32()) == beforeExecutionTopic:
+			t.stopCollecting = true
+		}
+		t.lastOp = vm.STOP
+		return
+	}
+	if t.currentLevel == nil {
+		return
+	}
+	level := t.currentLevel
+
+	// GAS is only allowed right before a call
+	if t.lastOp == vm.GAS && !strings.Contains(op.String(), "CALL") {
+		level.Opcodes[vm.GAS.String()]++
+	}
+	// EXTCODESIZE is only allowed to check whether a contract exists
+	if t.lastExtCode != nil {
+		if t.lastExtCode.op != vm.EXTCODESIZE || op != vm.ISZERO {
+			level.ExtCodeAccessInfo[t.lastExtCode.addr] = t.lastExtCode.op.String()
+		}
+		t.lastExtCode = nil
+	}
+	if op != vm.GAS && !unimportantOp(op) {
+		level.Opcodes[op.String()]++
+	}
+
+	ibs := env.IntraBlockState()
+	switch op {
+	case vm.EXTCODESIZE, vm.EXTCODEHASH, vm.EXTCODECOPY, vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		target := stack.Back(1)
+		if op == vm.EXTCODESIZE || op == vm.EXTCODEHASH || op == vm.EXTCODECOPY {
+			target = stack.Back(0)
+		}
+		addr := common.Address(target.Bytes20())
+		if _, ok := level.ContractSize[addr]; !ok && !t.isPrecompiled(addr) {
+			level.ContractSize[addr] = &ContractSizeInfo{ContractSize: ibs.GetCodeSize(addr), Opcode: op.String()}
+		}
+		if op == vm.EXTCODESIZE || op == vm.EXTCODEHASH || op == vm.EXTCODECOPY {
+			t.lastExtCode = &extCodeAccess{addr: addr, op: op}
+		}
+	case vm.SLOAD, vm.SSTORE:
+		slot := common.Hash(stack.Back(0).Bytes32())
+		addr := scope.Contract.Address()
+		access, ok := level.Access[addr]
+		if !ok {
+			access = &AccessInfo{Reads: make(map[common.Hash]common.Hash), Writes: make(map[common.Hash]uint64)}
+			level.Access[addr] = access
+		}
+		if op == vm.SLOAD {
+			// The value before the validation: unless the slot has been written before being read
+			_, read := access.Reads[slot]
+			_, written := access.Writes[slot]
+			if !read && !written {
+				var value uint256.Int
+				ibs.GetState(addr, &slot, &value)
+				access.Reads[slot] = value.Bytes32()
+			}
+		} else {
+			access.Writes[slot]++
+		}
+	case vm.SHA3:
+		// The preimages of the keccak hashes tell which storage slots are associated with an address
+		if size := stack.Back(1); size.IsUint64() && size.Uint64() > 20 && size.Uint64() < 512 {
+			t.result.Keccak = append(t.result.Keccak, memorySlice(memory, stack.Back(0), size))
+		}
+	case vm.LOG0, vm.LOG1, vm.LOG2, vm.LOG3, vm.LOG4:
+		count := int(op - vm.LOG0)
+		l := &LogInfo{Topics: make([]common.Hash, count), Data: memorySlice(memory, stack.Back(0), stack.Back(1))}
+		for i := 0; i < count; i++ {
+			l.Topics[i] = stack.Back(2 + i).Bytes32()
+		}
+		t.result.Logs = append(t.result.Logs, l)
+	}
+	t.lastOp = op
+}
+
+func (t *BundlerCollectorTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *BundlerCollectorTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+}
+
+func (t *BundlerCollectorTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *BundlerCollectorTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+func (t *BundlerCollectorTracer) WriteResult(stream *jsoniter.Stream) error {
+	if t.reason != nil {
+		return t.reason
+	}
+	enc, err := json.Marshal(&t.result)
+	if err != nil {
+		return err
+	}
+	stream.Write(enc)
+	return nil
+}
+
+func (t *BundlerCollectorTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+func (t *BundlerCollectorTracer) isPrecompiled(addr common.Address) bool {
+	_, ok := t.precompiles[addr]
+	return ok
+}
+
+// unimportantOp tells whether the given opcode isn't counted: the stack, arithmetic and comparison opcodes.
+func unimportantOp(op vm.OpCode) bool {
+	if op.IsPush() || (op >= vm.DUP1 && op <= vm.DUP16) || (op >= vm.SWAP1 && op <= vm.SWAP16) {
+		return true
+	}
+	switch op {
+	case vm.POP, vm.ADD, vm.SUB, vm.MUL, vm.DIV, vm.EQ, vm.LT, vm.GT, vm.SLT, vm.SGT, vm.SHL, vm.SHR,
+		vm.AND, vm.OR, vm.NOT, vm.CALLVALUE, vm.ISZERO:
+		return true
+	}
+	return false
+}
+
+// memorySlice returns a copy of the given slice of the memory, or as much of it as the memory has.
+func memorySlice(memory *vm.Memory, offset, size *uint256.Int) hexutil.Bytes {
+	length := uint64(memory.Len())
+	if !offset.IsUint64() || offset.Uint64() >= length {
+		return hexutil.Bytes{}
+	}
+	end := length
+	if size.IsUint64() && offset.Uint64()+size.Uint64() < length {
+		end = offset.Uint64() + size.Uint64()
+	}
+	return memory.GetCopy(offset.Uint64(), end-offset.Uint64())
+}
+
+func callTypeName(callType vm.CallType) string {
+	switch callType {
+	case vm.CALLCODET:
+		return "CALLCODE"
+	case vm.DELEGATECALLT:
+		return "DELEGATECALL"
+	case vm.STATICCALLT:
+		return "STATICCALL"
+	case vm.CREATET:
+		return "CREATE"
+	case vm.CREATE2T:
+		return "CREATE2"
+	default:
+		return "CALL"
+	}
+}
@@ -0,0 +1,75 @@
+package native
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+func TestBundlerCollectorTracer(t *testing.T) {
+	origin := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	entryPoint := common.HexToAddress("0x00000000000000000000000000000000000000e0")
+	entity := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+
+	// The entry point calls the entity with the method 0x12345678
+	entryPointCode := append(hexutil.MustDecode("0x631234567860e01b60005260006000600460006000"+"73"), entity[:]...)
+	entryPointCode = append(entryPointCode, hexutil.MustDecode("0x5af15000")...)
+	// The entity reads the timestamp, loads the slot 1, stores 5 in the slot 2, hashes 32 bytes and logs the topic 0xaa
+	entityCode := hexutil.MustDecode("0x42506001545060056002556020600020" + "5060aa60006000a100")
+	alloc := core.GenesisAlloc{
+		entryPoint: {Code: entryPointCode, Balance: big.NewInt(0)},
+		entity:     {Code: entityCode, Balance: big.NewInt(0), Storage: map[common.Hash]common.Hash{{31: 1}: {31: 7}}},
+		origin:     {Balance: big.NewInt(1_000_000_000)},
+	}
+
+	_, tx := memdb.NewTestTx(t)
+	blockCtx := vm.BlockContext{
+		CanTransfer:     core.CanTransfer,
+		Transfer:        core.Transfer,
+		ContractHasTEVM: func(common.Hash) (bool, error) { return false, nil },
+		BlockNumber:     8000000,
+		Difficulty:      big.NewInt(0x30000),
+		GasLimit:        6000000,
+	}
+	statedb, err := tests.MakePreState(&params.Rules{}, tx, alloc, blockCtx.BlockNumber)
+	require.NoError(t, err)
+	tracer := NewBundlerCollectorTracer()
+	evm := vm.NewEVM(blockCtx, vm.TxContext{Origin: origin, GasPrice: big.NewInt(1)}, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	msg := types.NewMessage(origin, &entryPoint, 0, uint256.NewInt(0), 100000, uint256.NewInt(1), nil, nil, nil, nil, false)
+	res, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+	require.False(t, res.Failed())
+
+	result := tracer.Result()
+	require.Len(t, result.CallsFromEntryPoint, 1)
+	call := result.CallsFromEntryPoint[0]
+	require.Equal(t, hexutil.Bytes{0x12, 0x34, 0x56, 0x78}, call.TopLevelMethodSig)
+	require.Equal(t, entity, call.TopLevelTargetAddress)
+	// The stack and arithmetic opcodes aren't counted
+	require.Equal(t, map[string]uint64{"TIMESTAMP": 1, "SLOAD": 1, "SSTORE": 1, "SHA3": 1, "LOG1": 1, "STOP": 1}, call.Opcodes)
+	require.Equal(t, map[common.Address]*AccessInfo{entity: {
+		Reads:  map[common.Hash]common.Hash{{31: 1}: {31: 7}},
+		Writes: map[common.Hash]uint64{{31: 2}: 1},
+	}}, call.Access)
+	require.False(t, call.OOG)
+
+	require.Equal(t, []hexutil.Bytes{make([]byte, 32)}, result.Keccak)
+	require.Len(t, result.Logs, 1)
+	require.Equal(t, []common.Hash{{31: 0xaa}}, result.Logs[0].Topics)
+	require.Len(t, result.Calls, 2)
+	require.Equal(t, "CALL", result.Calls[0].Type)
+	require.Equal(t, entity, *result.Calls[0].To)
+	require.Equal(t, hexutil.Bytes{0x12, 0x34, 0x56, 0x78}, result.Calls[0].Method)
+	require.Equal(t, "RETURN", result.Calls[1].Type)
+}
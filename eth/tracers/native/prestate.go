@@ -0,0 +1,309 @@
+// Package native is a collection of the tracers implemented in Go, registered in the tracers package on import.
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	tracers.RegisterNative("prestateTracer", newPrestateTracer)
+}
+
+type account struct {
+	balance *uint256.Int // nil in the post state when unchanged
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+func (a *account) exists() bool {
+	return a.nonce > 0 || len(a.code) > 0 || len(a.storage) > 0 || (a.balance != nil && !a.balance.IsZero())
+}
+
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // Whether to return the post state of the modified accounts along with their pre state
+}
+
+// prestateTracer reports the state of the accounts a transaction accesses before its execution: enough to replay it
+// on top of a genesis made of these accounts. In the diff mode, it reports the pre and post states of the accounts
+// the transaction modifies instead, limited to their modified fields.
+type prestateTracer struct {
+	config   prestateTracerConfig
+	ibs      vm.IntraBlockState
+	pre      map[common.Address]*account
+	post     map[common.Address]*account
+	created  map[common.Address]struct{}
+	gasLimit uint64
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+func newPrestateTracer(cfg json.RawMessage) (tracers.NativeTracer, error) {
+	t := &prestateTracer{
+		pre:     make(map[common.Address]*account),
+		post:    make(map[common.Address]*account),
+		created: make(map[common.Address]struct{}),
+	}
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &t.config); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+func (t *prestateTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth != 0 {
+		return
+	}
+	t.ibs = env.IntraBlockState()
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context().Coinbase)
+	if create {
+		t.created[to] = struct{}{}
+	}
+
+	// The gas of the transaction has been bought, and the nonce of the sender incremented unless it creates a
+	// contract: the value is transferred later
+	if gasPrice := env.TxContext().GasPrice; gasPrice != nil {
+		fee, _ := uint256.FromBig(gasPrice)
+		fee.Mul(fee, uint256.NewInt(t.gasLimit))
+		t.pre[from].balance.Add(t.pre[from].balance, fee)
+	}
+	if !create {
+		t.pre[from].nonce--
+	}
+}
+
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil {
+		return
+	}
+	// If tracing was interrupted, stop the execution
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return
+	}
+	stack, caller := scope.Stack, scope.Contract.Address()
+	switch {
+	case stack.Len() >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		t.lookupStorage(caller, common.Hash(stack.Back(0).Bytes32()))
+	case stack.Len() >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		t.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case stack.Len() >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		t.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		addr := crypto.CreateAddress(caller, env.IntraBlockState().GetNonce(caller))
+		t.lookupAccount(addr)
+		t.created[addr] = struct{}{}
+	case stack.Len() >= 4 && op == vm.CREATE2:
+		offset, size := stack.Back(1), stack.Back(2)
+		if !offset.IsUint64() || !size.IsUint64() || offset.Uint64()+size.Uint64() > uint64(scope.Memory.Len()) {
+			return
+		}
+		initCode := scope.Memory.GetCopy(offset.Uint64(), size.Uint64())
+		addr := crypto.CreateAddress2(caller, stack.Back(3).Bytes32(), crypto.Keccak256(initCode))
+		t.lookupAccount(addr)
+		t.created[addr] = struct{}{}
+	}
+}
+
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *prestateTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+}
+
+func (t *prestateTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+	t.lookupAccount(to)
+}
+
+func (t *prestateTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *prestateTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+func (t *prestateTracer) CaptureTxEnd(restGas uint64) {
+	if t.ibs == nil {
+		return
+	}
+	if t.config.DiffMode {
+		for addr, pre := range t.pre {
+			// The state of the self-destructed accounts is kept in the pre state, and dropped from the post state
+			if t.ibs.HasSuicided(addr) {
+				continue
+			}
+			modified := false
+			post := &account{storage: make(map[common.Hash]common.Hash)}
+			if balance := t.ibs.GetBalance(addr); !balance.Eq(pre.balance) {
+				modified = true
+				post.balance = balance.Clone()
+			}
+			if nonce := t.ibs.GetNonce(addr); nonce != pre.nonce {
+				modified = true
+				post.nonce = nonce
+			}
+			if code := t.ibs.GetCode(addr); string(code) != string(pre.code) {
+				modified = true
+				post.code = code
+			}
+			for key, val := range pre.storage {
+				key := key
+				var value uint256.Int
+				t.ibs.GetState(addr, &key, &value)
+				newVal := common.Hash(value.Bytes32())
+				// The empty slots are not reported, the slots cleared by the transaction are missing in the post state
+				if val == newVal || val == (common.Hash{}) {
+					delete(pre.storage, key)
+				}
+				if val != newVal {
+					modified = true
+					if newVal != (common.Hash{}) {
+						post.storage[key] = newVal
+					}
+				}
+			}
+			if modified {
+				t.post[addr] = post
+			} else {
+				// The accounts the transaction hasn't modified aren't reported
+				delete(t.pre, addr)
+			}
+		}
+	}
+	// The created contracts didn't exist before the transaction, unless the creation failed on a collision
+	for addr := range t.created {
+		if pre, ok := t.pre[addr]; ok && !pre.exists() {
+			delete(t.pre, addr)
+		}
+	}
+}
+
+func (t *prestateTracer) WriteResult(stream *jsoniter.Stream) error {
+	if t.reason != nil {
+		return t.reason
+	}
+	if !t.config.DiffMode {
+		writeAccounts(stream, t.pre)
+		return nil
+	}
+	stream.WriteObjectStart()
+	stream.WriteObjectField("pre")
+	writeAccounts(stream, t.pre)
+	stream.WriteMore()
+	stream.WriteObjectField("post")
+	writeAccounts(stream, t.post)
+	stream.WriteObjectEnd()
+	return nil
+}
+
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount adds the given account to the pre state, unless it's there already.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &account{
+		balance: t.ibs.GetBalance(addr).Clone(),
+		nonce:   t.ibs.GetNonce(addr),
+		code:    t.ibs.GetCode(addr),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage adds the given storage slot of the given account to the pre state, unless it's there already. The
+// account is in the pre state already: it's executing the code which accesses the slot.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].storage[key]; ok {
+		return
+	}
+	var value uint256.Int
+	t.ibs.GetState(addr, &key, &value)
+	t.pre[addr].storage[key] = value.Bytes32()
+}
+
+// writeAccounts writes the accounts ordered by address, with their storage ordered by slot.
+func writeAccounts(stream *jsoniter.Stream, accounts map[common.Address]*account) {
+	addrs := make([]common.Address, 0, len(accounts))
+	for addr := range accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	stream.WriteObjectStart()
+	for i, addr := range addrs {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		acc := accounts[addr]
+		stream.WriteObjectField(hexutil.Encode(addr[:]))
+		stream.WriteObjectStart()
+		first := true
+		field := func(name string) {
+			if !first {
+				stream.WriteMore()
+			}
+			first = false
+			stream.WriteObjectField(name)
+		}
+		if acc.balance != nil {
+			field("balance")
+			stream.WriteString(acc.balance.Hex())
+		}
+		if acc.nonce > 0 {
+			field("nonce")
+			stream.WriteUint64(acc.nonce)
+		}
+		if len(acc.code) > 0 {
+			field("code")
+			stream.WriteString(hexutil.Encode(acc.code))
+		}
+		if len(acc.storage) > 0 {
+			keys := make([]common.Hash, 0, len(acc.storage))
+			for key := range acc.storage {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+			field("storage")
+			stream.WriteObjectStart()
+			for j, key := range keys {
+				if j > 0 {
+					stream.WriteMore()
+				}
+				stream.WriteObjectField(key.Hex())
+				stream.WriteString(acc.storage[key].Hex())
+			}
+			stream.WriteObjectEnd()
+		}
+		stream.WriteObjectEnd()
+	}
+	stream.WriteObjectEnd()
+}
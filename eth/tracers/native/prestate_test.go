@@ -0,0 +1,103 @@
+package native
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+func TestPrestateTracer(t *testing.T) {
+	key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	require.NoError(t, err)
+	origin := crypto.PubkeyToAddress(key.PublicKey)
+	contract := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	coinbase := common.HexToAddress("0x00000000000000000000000000000000000000cb")
+
+	// The contract stores 5 in the slot 1, and loads the slot 2
+	code := hexutil.MustDecode("0x60056001556002545000")
+	alloc := core.GenesisAlloc{
+		contract: {Nonce: 1, Code: code, Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{31: 2}: {31: 3}}},
+		origin:   {Nonce: 1, Balance: big.NewInt(1_000_000_000)},
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	txn, err := types.SignTx(types.NewTransaction(1, contract, uint256.NewInt(7), 100000, uint256.NewInt(2), nil), *signer, key)
+	require.NoError(t, err)
+
+	trace := func(config string) string {
+		_, tx := memdb.NewTestTx(t)
+		rules := &params.Rules{}
+		blockCtx := vm.BlockContext{
+			CanTransfer:     core.CanTransfer,
+			Transfer:        core.Transfer,
+			Coinbase:        coinbase,
+			ContractHasTEVM: func(common.Hash) (bool, error) { return false, nil },
+			BlockNumber:     8000000,
+			Difficulty:      big.NewInt(0x30000),
+			GasLimit:        6000000,
+		}
+		statedb, err := tests.MakePreState(rules, tx, alloc, blockCtx.BlockNumber)
+		require.NoError(t, err)
+		tracer, ok, err := tracers.NewNative("prestateTracer", []byte(config))
+		require.NoError(t, err)
+		require.True(t, ok)
+		evm := vm.NewEVM(blockCtx, vm.TxContext{Origin: origin, GasPrice: big.NewInt(2)}, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+		msg, err := txn.AsMessage(*signer, nil, rules)
+		require.NoError(t, err)
+
+		tracer.CaptureTxStart(msg.Gas())
+		res, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.Gas())).TransitionDb(true /* refunds */, false /* gasBailout */)
+		require.NoError(t, err)
+		require.False(t, res.Failed())
+		tracer.CaptureTxEnd(msg.Gas() - res.UsedGas)
+
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		require.NoError(t, tracer.WriteResult(stream))
+		require.NoError(t, stream.Flush())
+		return buf.String()
+	}
+
+	// The gas used: the intrinsic gas, a SSTORE setting a slot, a SLOAD (200 in Petersburg), 3 pushes and a pop
+	fee := 2 * (21000 + 20000 + 200 + 3*3 + 2)
+	require.JSONEq(t, fmt.Sprintf(`{
+		"0x00000000000000000000000000000000000000cb": {"balance": "0x0"},
+		"0x00000000000000000000000000000000deadbeef": {"balance": "0x1", "nonce": 1, "code": "%s", "storage": {
+			"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000003"
+		}},
+		"%s": {"balance": "0x3b9aca00", "nonce": 1}
+	}`, hexutil.Encode(code), hexutil.Encode(origin[:])), trace(""))
+
+	require.JSONEq(t, fmt.Sprintf(`{
+		"pre": {
+			"0x00000000000000000000000000000000000000cb": {"balance": "0x0"},
+			"0x00000000000000000000000000000000deadbeef": {"balance": "0x1", "nonce": 1, "code": "%s"},
+			"%s": {"balance": "0x3b9aca00", "nonce": 1}
+		},
+		"post": {
+			"0x00000000000000000000000000000000000000cb": {"balance": "%#x"},
+			"0x00000000000000000000000000000000deadbeef": {"balance": "0x8", "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000005"
+			}},
+			"%s": {"balance": "%#x", "nonce": 2}
+		}
+	}`, hexutil.Encode(code), hexutil.Encode(origin[:]), fee, hexutil.Encode(origin[:]), 1_000_000_000-7-fee), trace(`{"diffMode": true}`))
+}
@@ -289,6 +289,102 @@ func (cw *contractWrapper) pushObject(vm *JSVM) {
 	vm.PutPropString(obj, "getInput")
 }
 
+// callTypes are the names of the call types, as reported by frame.getType.
+var callTypes = map[vm.CallType]string{
+	vm.CALLT:         "CALL",
+	vm.CALLCODET:     "CALLCODE",
+	vm.DELEGATECALLT: "DELEGATECALL",
+	vm.STATICCALLT:   "STATICCALL",
+	vm.CREATET:       "CREATE",
+	vm.CREATE2T:      "CREATE2",
+}
+
+// frameWrapper provides a JavaScript wrapper around a call frame entered by the
+// VM, passed to the tracer's 'enter' function.
+type frameWrapper struct {
+	typ   vm.CallType
+	from  common.Address
+	to    common.Address
+	input []byte
+	gas   uint64
+	value *big.Int // nil for the calls not transferring value (delegatecall and staticcall)
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame and pushes
+// it onto the VM stack.
+func (fw *frameWrapper) pushObject(vm *JSVM) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *JSVM) int { ctx.PushString(callTypes[fw.typ]); return 1 })
+	vm.PutPropString(obj, "getType")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		copy(makeSlice(ctx.PushFixedBuffer(20), 20), fw.from[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getFrom")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		copy(makeSlice(ctx.PushFixedBuffer(20), 20), fw.to[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getTo")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		ptr := ctx.PushFixedBuffer(len(fw.input))
+		copy(makeSlice(ptr, uint(len(fw.input))), fw.input)
+		return 1
+	})
+	vm.PutPropString(obj, "getInput")
+
+	vm.PushGoFunction(func(ctx *JSVM) int { ctx.PushUint(uint(fw.gas)); return 1 })
+	vm.PutPropString(obj, "getGas")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		if fw.value == nil {
+			ctx.PushUndefined()
+		} else {
+			pushBigInt(fw.value, ctx)
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getValue")
+}
+
+// frameResultWrapper provides a JavaScript wrapper around the result of a call
+// frame exited by the VM, passed to the tracer's 'exit' function.
+type frameResultWrapper struct {
+	gasUsed uint64
+	output  []byte
+	err     error
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame result and
+// pushes it onto the VM stack.
+func (rw *frameResultWrapper) pushObject(vm *JSVM) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *JSVM) int { ctx.PushUint(uint(rw.gasUsed)); return 1 })
+	vm.PutPropString(obj, "getGasUsed")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		ptr := ctx.PushFixedBuffer(len(rw.output))
+		copy(makeSlice(ptr, uint(len(rw.output))), rw.output)
+		return 1
+	})
+	vm.PutPropString(obj, "getOutput")
+
+	vm.PushGoFunction(func(ctx *JSVM) int {
+		if rw.err != nil {
+			ctx.PushString(rw.err.Error())
+		} else {
+			ctx.PushUndefined()
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getError")
+}
+
 // Tracer provides an implementation of Tracer that evaluates a Javascript
 // function for each VM execution step.
 type Tracer struct {
@@ -303,6 +399,10 @@ type Tracer struct {
 	contractWrapper *contractWrapper // Wrapper around the contract object
 	dbWrapper       *dbWrapper       // Wrapper around the VM environment
 
+	frameWrapper       *frameWrapper       // Wrapper around the entered call frame
+	frameResultWrapper *frameResultWrapper // Wrapper around the result of the exited call frame
+	traceFrames        bool                // Whether the tracer exposes 'enter' and 'exit' functions
+
 	pcValue     *uint   // Swappable pc value wrapped by a log accessor
 	gasValue    *uint   // Swappable gas value wrapped by a log accessor
 	costValue   *uint   // Swappable cost value wrapped by a log accessor
@@ -329,25 +429,28 @@ type Context struct {
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
+// and 'result' functions, and optionally 'enter' and 'exit' functions called on
+// the call frames entered and exited within the traced call.
 func New(code string, ctx *Context) (*Tracer, error) {
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
 		code = tracer
 	}
 	tracer := &Tracer{
-		vm:              JSVMNew(),
-		ctx:             make(map[string]interface{}),
-		opWrapper:       new(opWrapper),
-		stackWrapper:    new(stackWrapper),
-		memoryWrapper:   new(memoryWrapper),
-		contractWrapper: new(contractWrapper),
-		dbWrapper:       new(dbWrapper),
-		pcValue:         new(uint),
-		gasValue:        new(uint),
-		costValue:       new(uint),
-		depthValue:      new(uint),
-		refundValue:     new(uint),
+		vm:                 JSVMNew(),
+		ctx:                make(map[string]interface{}),
+		opWrapper:          new(opWrapper),
+		stackWrapper:       new(stackWrapper),
+		memoryWrapper:      new(memoryWrapper),
+		contractWrapper:    new(contractWrapper),
+		dbWrapper:          new(dbWrapper),
+		frameWrapper:       new(frameWrapper),
+		frameResultWrapper: new(frameResultWrapper),
+		pcValue:            new(uint),
+		gasValue:           new(uint),
+		costValue:          new(uint),
+		depthValue:         new(uint),
+		refundValue:        new(uint),
 	}
 	if ctx.BlockHash != (common.Hash{}) {
 		tracer.ctx["blockHash"] = ctx.BlockHash
@@ -481,6 +584,16 @@ func New(code string, ctx *Context) (*Tracer, error) {
 	}
 	tracer.vm.Pop()
 
+	// The call frames are traced only when the tracer exposes both 'enter' and 'exit'
+	hasEnter := tracer.vm.GetPropString(tracer.tracerObject, "enter")
+	tracer.vm.Pop()
+	hasExit := tracer.vm.GetPropString(tracer.tracerObject, "exit")
+	tracer.vm.Pop()
+	if hasEnter != hasExit {
+		return nil, fmt.Errorf("trace object must expose either both or none of enter() and exit()")
+	}
+	tracer.traceFrames = hasEnter
+
 	// Tracer is valid, inject the big int library to access large numbers
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
@@ -532,6 +645,12 @@ func New(code string, ctx *Context) (*Tracer, error) {
 	tracer.dbWrapper.pushObject(tracer.vm)
 	tracer.vm.PutPropString(tracer.stateObject, "db")
 
+	tracer.frameWrapper.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frame")
+
+	tracer.frameResultWrapper.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frameResult")
+
 	return tracer, nil
 }
 
@@ -580,6 +699,7 @@ func wrapError(context string, err error) error {
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
 func (jst *Tracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
 	if depth != 0 {
+		jst.captureEnter(calltype, from, to, input, gas, value)
 		return
 	}
 	jst.ctx["type"] = "CALL"
@@ -655,6 +775,7 @@ func (jst *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (jst *Tracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, t time.Duration, err error) {
 	if depth != 0 {
+		jst.captureExit(output, startGas-endGas, err)
 		return
 	}
 	jst.ctx["output"] = output
@@ -666,6 +787,45 @@ func (jst *Tracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64,
 	}
 }
 
+// captureEnter calls the tracer's 'enter' function when a call frame is entered
+// within the traced call.
+func (jst *Tracer) captureEnter(calltype vm.CallType, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if !jst.traceFrames || jst.err != nil {
+		return
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		jst.err = jst.reason
+		return
+	}
+	jst.frameWrapper.typ = calltype
+	jst.frameWrapper.from = from
+	jst.frameWrapper.to = to
+	jst.frameWrapper.input = input
+	jst.frameWrapper.gas = gas
+	jst.frameWrapper.value = value
+	if value != nil && value.Sign() < 0 { // the VM reports the delegatecalls and staticcalls with negative values
+		jst.frameWrapper.value = nil
+	}
+	if _, err := jst.call(true, "enter", "frame"); err != nil {
+		jst.err = wrapError("enter", err)
+	}
+}
+
+// captureExit calls the tracer's 'exit' function when a call frame entered
+// within the traced call is exited.
+func (jst *Tracer) captureExit(output []byte, gasUsed uint64, err error) {
+	if !jst.traceFrames || jst.err != nil {
+		return
+	}
+	jst.frameResultWrapper.output = output
+	jst.frameResultWrapper.gasUsed = gasUsed
+	jst.frameResultWrapper.err = err
+	if _, err := jst.call(true, "exit", "frameResult"); err != nil {
+		jst.err = wrapError("exit", err)
+	}
+}
+
 func (jst *Tracer) CaptureSelfDestruct(from, to common.Address, value *big.Int) {
 }
 
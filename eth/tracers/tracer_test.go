@@ -211,3 +211,36 @@ func TestNoStepExec(t *testing.T) {
 		}
 	}
 }
+
+func TestEnterExit(t *testing.T) {
+	// test that either both or none of enter() and exit() are defined
+	if _, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}}", new(Context)); err == nil {
+		t.Fatal("tracer creation should've failed without exit() definition")
+	}
+	if _, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}, exit: function() {}}", new(Context)); err != nil {
+		t.Fatal(err)
+	}
+
+	// test that the enter and exit methods are invoked for the inner call frames, with their values
+	tracer, err := New(`{frames: [], step: function() {}, fault: function() {}, result: function() { return this.frames; },
+		enter: function(frame) { this.frames.push({type: frame.getType(), to: toHex(frame.getTo()), gas: frame.getGas(), value: frame.getValue() === undefined ? null : frame.getValue().toString()}); },
+		exit: function(res) { this.frames.push({gasUsed: res.getGasUsed(), output: toHex(res.getOutput()), error: res.getError() || null}); }}`, new(Context))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.Address{1}
+	tracer.CaptureStart(nil, 1, common.Address{}, to, false, false, vm.CALLT, nil, 1000, big.NewInt(5), nil)
+	tracer.CaptureStart(nil, 2, to, common.Address{2}, false, false, vm.DELEGATECALLT, nil, 500, big.NewInt(-1), nil)
+	tracer.CaptureEnd(2, nil, 500, 500, 0, errors.New("stahp"))
+	tracer.CaptureEnd(1, []byte{0x42}, 1000, 600, 0, nil)
+	have, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"type":"CALL","to":"0x0100000000000000000000000000000000000000","gas":1000,"value":"5"},` +
+		`{"type":"DELEGATECALL","to":"0x0200000000000000000000000000000000000000","gas":500,"value":null},` +
+		`{"gasUsed":0,"output":"0x","error":"stahp"},{"gasUsed":400,"output":"0x42","error":null}]`
+	if string(have) != want {
+		t.Errorf("frames mismatch: have %s, want %s", have, want)
+	}
+}
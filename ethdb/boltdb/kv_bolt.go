@@ -0,0 +1,529 @@
+// Package boltdb implements kv.RwDB on bolt (go.etcd.io/bbolt), the pure Go engine of --db.backend=bolt for the
+// filesystems where mdbx performs poorly (network filesystems, ZFS).
+//
+// A table is a bucket of the bolt file. The pairs of a DupSort table are the keys of its bucket: the key escaped to
+// keep the order (0x00 -> 0x00 0xff) and terminated by 0x00 0x01, followed by the value, so that the pairs are ordered
+// by key and then by value like in mdbx. AutoDupSortKeysConversion is applied to the keys like by kv/mdbx.
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName - the file of the database in its directory
+const FileName = "bolt.db"
+
+// lockTimeout - how long to wait for the file lock, taken by the process writing to the database
+const lockTimeout = time.Second
+
+type TableCfgFunc func(defaultBuckets kv.TableCfg) kv.TableCfg
+
+type BoltOpts struct {
+	bucketsCfg TableCfgFunc
+	path       string
+	label      kv.Label
+	mapSize    datasize.ByteSize
+	readonly   bool
+	log        log.Logger
+}
+
+func NewBolt(log log.Logger) BoltOpts {
+	return BoltOpts{
+		bucketsCfg: func(defaultBuckets kv.TableCfg) kv.TableCfg { return defaultBuckets },
+		mapSize:    datasize.TB,
+		log:        log,
+	}
+}
+
+func (opts BoltOpts) Path(path string) BoltOpts {
+	opts.path = path
+	return opts
+}
+
+func (opts BoltOpts) Label(label kv.Label) BoltOpts {
+	opts.label = label
+	return opts
+}
+
+// MapSize - the size of the memory mapping of the file, bolt maps it again only if the file grows larger: the
+// transactions in progress have to be closed first.
+func (opts BoltOpts) MapSize(sz datasize.ByteSize) BoltOpts {
+	opts.mapSize = sz
+	return opts
+}
+
+// Readonly - open the database written by another process: bolt allows a single process to write to a file, and
+// only while no other process reads it.
+func (opts BoltOpts) Readonly() BoltOpts {
+	opts.readonly = true
+	return opts
+}
+
+func (opts BoltOpts) WithTablesCfg(f TableCfgFunc) BoltOpts {
+	opts.bucketsCfg = f
+	return opts
+}
+
+func (opts BoltOpts) Open() (kv.RwDB, error) {
+	if !opts.readonly {
+		if err := os.MkdirAll(opts.path, 0744); err != nil {
+			return nil, fmt.Errorf("could not create dir: %s, %w", opts.path, err)
+		}
+	}
+	db, err := bolt.Open(filepath.Join(opts.path, FileName), 0644, &bolt.Options{
+		Timeout:         lockTimeout,
+		ReadOnly:        opts.readonly,
+		InitialMmapSize: int(opts.mapSize),
+		FreelistType:    bolt.FreelistMapType,
+		NoFreelistSync:  true,
+	})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			err = fmt.Errorf("%w: the database is open by another process", err)
+		}
+		return nil, fmt.Errorf("%w, label: %s, path: %s", err, opts.label, opts.path)
+	}
+
+	kvDB := &BoltKV{opts: opts, db: db, buckets: kv.TableCfg{}}
+	for name, cfg := range opts.bucketsCfg(kv.ChaindataTablesCfg) { // copy map to avoid changing global variable
+		kvDB.buckets[name] = cfg
+	}
+	if !opts.readonly {
+		if err := db.Update(func(tx *bolt.Tx) error {
+			for name, cfg := range kvDB.buckets {
+				if cfg.IsDeprecated {
+					continue
+				}
+				if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+					return fmt.Errorf("create bucket: %s, %w", name, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return kvDB, nil
+}
+
+func (opts BoltOpts) MustOpen() kv.RwDB {
+	db, err := opts.Open()
+	if err != nil {
+		panic(fmt.Errorf("fail to open bolt: %w", err))
+	}
+	return db
+}
+
+type BoltKV struct {
+	opts    BoltOpts
+	db      *bolt.DB
+	buckets kv.TableCfg
+}
+
+// Close waits for the transactions in progress
+func (db *BoltKV) Close() {
+	if err := db.db.Close(); err != nil {
+		db.opts.log.Warn("failed to close bolt db", "label", db.opts.label, "err", err)
+	}
+}
+
+func (db *BoltKV) AllBuckets() kv.TableCfg { return db.buckets }
+
+func (db *BoltKV) PageSize() uint64 { return uint64(db.db.Info().PageSize) }
+
+func (db *BoltKV) BeginRo(ctx context.Context) (kv.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return nil, fmt.Errorf("%w, label: %s", err, db.opts.label)
+	}
+	return &BoltTx{db: db, tx: tx, readOnly: true}, nil
+}
+
+func (db *BoltKV) BeginRw(ctx context.Context) (kv.RwTx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx, err := db.db.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("%w, label: %s", err, db.opts.label)
+	}
+	return &BoltTx{db: db, tx: tx}, nil
+}
+
+func (db *BoltKV) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+func (db *BoltKV) Update(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type BoltTx struct {
+	db               *BoltKV
+	tx               *bolt.Tx
+	readOnly         bool
+	version          uint64 // incremented by the changes, after which the cursors find their position again
+	statelessCursors map[string]*BoltCursor
+}
+
+func (tx *BoltTx) Commit() error {
+	if tx.tx == nil {
+		return nil
+	}
+	defer func() { tx.tx = nil }()
+	if tx.readOnly {
+		return tx.tx.Rollback()
+	}
+	return tx.tx.Commit()
+}
+
+func (tx *BoltTx) Rollback() {
+	if tx.tx == nil {
+		return
+	}
+	defer func() { tx.tx = nil }()
+	_ = tx.tx.Rollback()
+}
+
+func (tx *BoltTx) ViewID() uint64 { return uint64(tx.tx.ID()) }
+
+func (tx *BoltTx) CollectMetrics() {}
+
+func (tx *BoltTx) DBSize() (uint64, error) { return uint64(tx.tx.Size()), nil }
+
+func (tx *BoltTx) BucketSize(name string) (uint64, error) {
+	b := tx.tx.Bucket([]byte(name))
+	if b == nil {
+		return 0, nil
+	}
+	st := b.Stats()
+	return uint64(st.BranchPageN+st.BranchOverflowN+st.LeafPageN+st.LeafOverflowN) * uint64(tx.db.db.Info().PageSize), nil
+}
+
+func (tx *BoltTx) ListBuckets() ([]string, error) {
+	var names []string
+	err := tx.tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		names = append(names, string(name))
+		return nil
+	})
+	return names, err
+}
+
+func (tx *BoltTx) CreateBucket(name string) error {
+	if tx.readOnly {
+		return nil
+	}
+	tx.bucketsChanged()
+	if _, err := tx.tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+		return fmt.Errorf("create bucket: %s, %w", name, err)
+	}
+	return nil
+}
+
+func (tx *BoltTx) DropBucket(name string) error {
+	if cfg, ok := tx.db.buckets[name]; !(ok && cfg.IsDeprecated) {
+		return fmt.Errorf("%w, bucket: %s", kv.ErrAttemptToDeleteNonDeprecatedBucket, name)
+	}
+	tx.bucketsChanged()
+	if err := tx.tx.DeleteBucket([]byte(name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (tx *BoltTx) ClearBucket(name string) error {
+	if tx.tx.Bucket([]byte(name)) == nil {
+		return nil
+	}
+	tx.bucketsChanged()
+	if err := tx.tx.DeleteBucket([]byte(name)); err != nil {
+		return err
+	}
+	_, err := tx.tx.CreateBucket([]byte(name))
+	return err
+}
+
+func (tx *BoltTx) ExistsBucket(name string) (bool, error) {
+	return tx.tx.Bucket([]byte(name)) != nil, nil
+}
+
+// bucketsChanged - the buckets are created or deleted, the cursors open on them aren't valid anymore
+func (tx *BoltTx) bucketsChanged() {
+	tx.version++
+	tx.statelessCursors = nil
+}
+
+func (tx *BoltTx) statelessCursor(bucket string) (*BoltCursor, error) {
+	if tx.statelessCursors == nil {
+		tx.statelessCursors = map[string]*BoltCursor{}
+	}
+	c, ok := tx.statelessCursors[bucket]
+	if !ok {
+		var err error
+		if c, err = tx.cursor(bucket); err != nil {
+			return nil, err
+		}
+		tx.statelessCursors[bucket] = c
+	}
+	return c, nil
+}
+
+func (tx *BoltTx) Put(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.Put(k, v)
+}
+
+func (tx *BoltTx) Delete(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.Delete(k, v)
+}
+
+func (tx *BoltTx) Append(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.Append(k, v)
+}
+
+func (tx *BoltTx) AppendDup(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.AppendDup(k, v)
+}
+
+func (tx *BoltTx) GetOne(bucket string, k []byte) ([]byte, error) {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !c.dupSort {
+		if c.b == nil {
+			return nil, nil
+		}
+		return c.b.Get(k), nil
+	}
+	_, v, err := c.SeekExact(k)
+	return v, err
+}
+
+func (tx *BoltTx) Has(bucket string, key []byte) (bool, error) {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return false, err
+	}
+	k, _, err := c.Seek(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(key, k), nil
+}
+
+func (tx *BoltTx) ForEach(bucket string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	c, err := tx.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(fromPrefix); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err := walker(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *BoltTx) ForPrefix(bucket string, prefix []byte, walker func(k, v []byte) error) error {
+	c, err := tx.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(prefix); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		if err := walker(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *BoltTx) ForAmount(bucket string, fromPrefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	if amount == 0 {
+		return nil
+	}
+	c, err := tx.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(fromPrefix); k != nil && amount > 0; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err := walker(k, v); err != nil {
+			return err
+		}
+		amount--
+	}
+	return nil
+}
+
+func (tx *BoltTx) IncrementSequence(bucket string, amount uint64) (uint64, error) {
+	current, err := tx.ReadSequence(bucket)
+	if err != nil {
+		return 0, err
+	}
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, current+amount)
+	if err = tx.Put(kv.Sequence, []byte(bucket), next); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+func (tx *BoltTx) ReadSequence(bucket string) (uint64, error) {
+	v, err := tx.GetOne(kv.Sequence, []byte(bucket))
+	if err != nil || len(v) == 0 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func (tx *BoltTx) cursor(bucket string) (*BoltCursor, error) {
+	cfg, ok := tx.db.buckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("table: %s, unknown table", bucket)
+	}
+	c := &BoltCursor{tx: tx, bucketName: bucket, bucketCfg: cfg, dupSort: cfg.Flags&kv.DupSort != 0}
+	if c.b = tx.tx.Bucket([]byte(bucket)); c.b != nil {
+		c.c = c.b.Cursor()
+	}
+	return c, nil
+}
+
+// stdCursor, stdRwCursor - the cursors without the methods of kv.CursorDupSort, like the ones of kv/mdbx: the callers
+// check for these methods to write the DupSort tables
+type stdCursor struct{ kv.Cursor }
+type stdRwCursor struct{ kv.RwCursor }
+
+func (tx *BoltTx) Cursor(bucket string) (kv.Cursor, error) {
+	c, err := tx.cursor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return stdCursor{c}, nil
+}
+
+func (tx *BoltTx) RwCursor(bucket string) (kv.RwCursor, error) {
+	c, err := tx.cursor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if c.dupSort && !c.bucketCfg.AutoDupSortKeysConversion {
+		return c, nil
+	}
+	return stdRwCursor{c}, nil
+}
+
+func (tx *BoltTx) CursorDupSort(bucket string) (kv.CursorDupSort, error) { return tx.cursor(bucket) }
+
+func (tx *BoltTx) RwCursorDupSort(bucket string) (kv.RwCursorDupSort, error) {
+	return tx.cursor(bucket)
+}
+
+// dupKey - the bolt key of the pair k/v of a DupSort table
+func dupKey(k, v []byte) []byte {
+	bk := make([]byte, 0, len(k)+2+len(v)+8)
+	for _, b := range k {
+		if b == 0 {
+			bk = append(bk, 0, 0xff)
+		} else {
+			bk = append(bk, b)
+		}
+	}
+	bk = append(bk, 0, 1)
+	return append(bk, v...)
+}
+
+// dupKeyEnd - the bolt key after the pairs of the key k of a DupSort table
+func dupKeyEnd(k []byte) []byte {
+	bk := dupKey(k, nil)
+	bk[len(bk)-1]++
+	return bk
+}
+
+// splitDupKey - the key and the value of a pair of a DupSort table
+func splitDupKey(bk []byte) (k, v []byte, err error) {
+	escaped := false
+	for i := 0; i+1 < len(bk); i++ {
+		if bk[i] != 0 {
+			continue
+		}
+		switch bk[i+1] {
+		case 1:
+			if !escaped {
+				return bk[:i], bk[i+2:], nil
+			}
+			k = make([]byte, 0, i)
+			for j := 0; j < i; j++ {
+				k = append(k, bk[j])
+				if bk[j] == 0 {
+					j++
+				}
+			}
+			return k, bk[i+2:], nil
+		case 0xff:
+			escaped = true
+			i++
+		default:
+			return nil, nil, fmt.Errorf("invalid key of dupsort table: %x", bk)
+		}
+	}
+	return nil, nil, fmt.Errorf("invalid key of dupsort table: %x", bk)
+}
@@ -0,0 +1,502 @@
+package boltdb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltCursor - the cursor of any table: the methods of kv.CursorDupSort work on DupSort tables only. Bolt cursors are
+// invalidated by the changes of their bucket, so the cursor keeps the bolt key of its position and seeks it again
+// after the changes: like with mdbx, the cursor is then at the next key if the key at the position was deleted.
+type BoltCursor struct {
+	tx         *BoltTx
+	bucketName string
+	bucketCfg  kv.TableCfgItem
+	dupSort    bool
+	b          *bolt.Bucket // nil if the table doesn't exist in the database open read-only
+	c          *bolt.Cursor
+
+	key     []byte // the bolt key of the position, nil if the cursor isn't positioned
+	valid   bool   // the bolt cursor is at key
+	version uint64 // tx.version when the bolt cursor was positioned
+}
+
+func (c *BoltCursor) synced() bool { return c.valid && c.version == c.tx.version }
+
+// at - positions the cursor at the bolt key/value found by the bolt cursor. At the end, the position stays where it
+// was, like with mdbx.
+func (c *BoltCursor) at(bk, bv []byte) ([]byte, []byte) {
+	if bk == nil {
+		c.valid = false
+		return nil, nil
+	}
+	c.key, c.valid, c.version = bk, true, c.tx.version
+	return bk, bv
+}
+
+func (c *BoltCursor) firstRaw() ([]byte, []byte) {
+	if c.c == nil {
+		return nil, nil
+	}
+	return c.at(c.c.First())
+}
+
+func (c *BoltCursor) lastRaw() ([]byte, []byte) {
+	if c.c == nil {
+		return nil, nil
+	}
+	return c.at(c.c.Last())
+}
+
+func (c *BoltCursor) seekRaw(seek []byte) ([]byte, []byte) {
+	if c.c == nil {
+		return nil, nil
+	}
+	return c.at(c.c.Seek(seek))
+}
+
+// nextRaw - the bolt key/value after the position, or the next one if the key at the position was deleted
+func (c *BoltCursor) nextRaw() ([]byte, []byte) {
+	if c.c == nil {
+		return nil, nil
+	}
+	if c.key == nil {
+		return c.firstRaw()
+	}
+	if !c.synced() {
+		bk, bv := c.c.Seek(c.key)
+		if !bytes.Equal(bk, c.key) {
+			return c.at(bk, bv)
+		}
+	}
+	return c.at(c.c.Next())
+}
+
+// prevRaw - the bolt key/value before the position
+func (c *BoltCursor) prevRaw() ([]byte, []byte) {
+	if c.c == nil {
+		return nil, nil
+	}
+	if c.key == nil {
+		return c.lastRaw()
+	}
+	if !c.synced() {
+		if bk, _ := c.c.Seek(c.key); bk == nil {
+			return c.lastRaw()
+		}
+	}
+	return c.at(c.c.Prev())
+}
+
+func (c *BoltCursor) currentRaw() ([]byte, []byte) {
+	if c.c == nil || c.key == nil {
+		return nil, nil
+	}
+	return c.at(c.c.Seek(c.key))
+}
+
+// pair - the key and the value of a bolt key/value: the pair of a DupSort table is in the bolt key
+func (c *BoltCursor) pair(bk, bv []byte) ([]byte, []byte, error) {
+	if bk == nil || !c.dupSort {
+		return bk, bv, nil
+	}
+	k, v, err := splitDupKey(bk)
+	if err != nil {
+		return []byte{}, nil, fmt.Errorf("table: %s, %w", c.bucketName, err)
+	}
+	return k, v, nil
+}
+
+// user - the key/value as written by the application, see kv.TableCfgItem.AutoDupSortKeysConversion
+func (c *BoltCursor) user(k, v []byte, err error) ([]byte, []byte, error) {
+	if err != nil || k == nil {
+		return k, v, err
+	}
+	b := c.bucketCfg
+	if b.AutoDupSortKeysConversion && len(k) == b.DupToLen {
+		keyPart := b.DupFromLen - b.DupToLen
+		if len(v) < keyPart {
+			return []byte{}, nil, fmt.Errorf("table: %s, value too short for key %x: %x", c.bucketName, k, v)
+		}
+		k = append(append(make([]byte, 0, b.DupFromLen), k...), v[:keyPart]...)
+		v = v[keyPart:]
+	}
+	return k, v, nil
+}
+
+// currentKey - the key of the table at the position
+func (c *BoltCursor) currentKey() ([]byte, error) {
+	if c.key == nil {
+		return nil, nil
+	}
+	k, _, err := c.pair(c.key, nil)
+	return k, err
+}
+
+// seekKey - the bolt key to seek the key of the table
+func (c *BoltCursor) seekKey(seek []byte) []byte {
+	if !c.dupSort {
+		return seek
+	}
+	b := c.bucketCfg
+	if b.AutoDupSortKeysConversion && len(seek) > b.DupToLen {
+		return dupKey(seek[:b.DupToLen], seek[b.DupToLen:])
+	}
+	return dupKey(seek, nil)
+}
+
+// splitKey - the key/value of a DupSort table stored for the key/value written by the application, see
+// kv.TableCfgItem.AutoDupSortKeysConversion. The keys shorter than DupToLen have a single value.
+func (c *BoltCursor) splitKey(op string, k, v []byte) ([]byte, []byte, error) {
+	b := c.bucketCfg
+	if !b.AutoDupSortKeysConversion {
+		return k, v, nil
+	}
+	from, to := b.DupFromLen, b.DupToLen
+	if len(k) != from && len(k) >= to {
+		return nil, nil, fmt.Errorf("%s dupsort bucket: %s, can have keys of len==%d and len<%d. key: %x,%d", op, c.bucketName, from, to, k, len(k))
+	}
+	if len(k) == from {
+		v = append(append(make([]byte, 0, from-to+len(v)), k[to:]...), v...)
+		k = k[:to]
+	}
+	return k, v, nil
+}
+
+func (c *BoltCursor) First() ([]byte, []byte, error) {
+	return c.user(c.pair(c.firstRaw()))
+}
+
+func (c *BoltCursor) Last() ([]byte, []byte, error) {
+	return c.user(c.pair(c.lastRaw()))
+}
+
+func (c *BoltCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	if len(seek) == 0 {
+		return c.First()
+	}
+	return c.user(c.pair(c.seekRaw(c.seekKey(seek))))
+}
+
+func (c *BoltCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	if !c.dupSort {
+		bk, bv := c.seekRaw(key)
+		if !bytes.Equal(bk, key) {
+			return nil, nil, nil
+		}
+		return bk, bv, nil
+	}
+	b := c.bucketCfg
+	if b.AutoDupSortKeysConversion && len(key) == b.DupFromLen {
+		from, to := b.DupFromLen, b.DupToLen
+		k, v, err := c.pair(c.seekRaw(dupKey(key[:to], key[to:])))
+		if err != nil {
+			return []byte{}, nil, err
+		}
+		if !bytes.Equal(k, key[:to]) || !bytes.HasPrefix(v, key[to:]) {
+			return nil, nil, nil
+		}
+		return key[:to], v[from-to:], nil
+	}
+	k, v, err := c.pair(c.seekRaw(dupKey(key, nil)))
+	if err != nil {
+		return []byte{}, nil, err
+	}
+	if !bytes.Equal(k, key) {
+		return nil, nil, nil
+	}
+	return k, v, nil
+}
+
+func (c *BoltCursor) Next() ([]byte, []byte, error) {
+	return c.user(c.pair(c.nextRaw()))
+}
+
+func (c *BoltCursor) Prev() ([]byte, []byte, error) {
+	return c.user(c.pair(c.prevRaw()))
+}
+
+func (c *BoltCursor) Current() ([]byte, []byte, error) {
+	return c.user(c.pair(c.currentRaw()))
+}
+
+// Count - the number of the entries, counted by reading the whole table
+func (c *BoltCursor) Count() (uint64, error) {
+	if c.b == nil {
+		return 0, nil
+	}
+	return uint64(c.b.Stats().KeyN), nil
+}
+
+func (c *BoltCursor) Close() {}
+
+func (c *BoltCursor) bucket() (*bolt.Bucket, error) {
+	if c.b == nil {
+		return nil, fmt.Errorf("table: %s, the table doesn't exist", c.bucketName)
+	}
+	return c.b, nil
+}
+
+// put - writes a copy of the bolt key/value, bolt keeps them until the commit
+func (c *BoltCursor) put(bk, bv []byte) error {
+	b, err := c.bucket()
+	if err != nil {
+		return err
+	}
+	bk = append(make([]byte, 0, len(bk)), bk...)
+	bv = append(make([]byte, 0, len(bv)), bv...)
+	c.tx.version++
+	if err = b.Put(bk, bv); err != nil {
+		return fmt.Errorf("table: %s, err: %w", c.bucketName, err)
+	}
+	c.key, c.valid = bk, false
+	return nil
+}
+
+// del - deletes the bolt key, the cursor is then at the next key
+func (c *BoltCursor) del(bk []byte) error {
+	b, err := c.bucket()
+	if err != nil {
+		return err
+	}
+	bk = append(make([]byte, 0, len(bk)), bk...)
+	c.tx.version++
+	if err = b.Delete(bk); err != nil {
+		return fmt.Errorf("table: %s, err: %w", c.bucketName, err)
+	}
+	c.key, c.valid = bk, false
+	return nil
+}
+
+// delPrefix - deletes the first bolt key with the prefix, if any
+func (c *BoltCursor) delPrefix(prefix []byte) error {
+	bk, _ := c.seekRaw(prefix)
+	if bk == nil || !bytes.HasPrefix(bk, prefix) {
+		return nil
+	}
+	return c.del(bk)
+}
+
+// delDups - deletes the values of the key of a DupSort table, the cursor is then at the next key
+func (c *BoltCursor) delDups(k []byte) error {
+	start := dupKey(k, nil)
+	for {
+		bk, _ := c.seekRaw(start)
+		if bk == nil || !bytes.HasPrefix(bk, start) {
+			break
+		}
+		if err := c.del(bk); err != nil {
+			return err
+		}
+	}
+	c.key, c.valid = start, false
+	return nil
+}
+
+func (c *BoltCursor) Put(k, v []byte) error {
+	if len(k) == 0 {
+		return fmt.Errorf("bolt doesn't support empty keys. bucket: %s", c.bucketName)
+	}
+	if !c.dupSort {
+		return c.put(k, v)
+	}
+	if c.bucketCfg.AutoDupSortKeysConversion {
+		// replaces the value with the same key part, or the first value of a short key, like mdbx
+		k2, v2, err := c.splitKey("put", k, v)
+		if err != nil {
+			return err
+		}
+		if err = c.delPrefix(dupKey(k2, k[len(k2):])); err != nil {
+			return err
+		}
+		k, v = k2, v2
+	}
+	return c.put(dupKey(k, v), []byte{})
+}
+
+// Append - like Put, but the key must be after the last one: with DupSort tables, the value must be after the last
+// one of the key.
+func (c *BoltCursor) Append(k, v []byte) error {
+	if len(k) == 0 {
+		return fmt.Errorf("bolt doesn't support empty keys. bucket: %s", c.bucketName)
+	}
+	if !c.dupSort {
+		if c.c != nil {
+			if last, _ := c.c.Last(); last != nil && bytes.Compare(k, last) <= 0 {
+				c.valid = false
+				return fmt.Errorf("bucket: %s, append key %x isn't after the last key %x", c.bucketName, k, last)
+			}
+		}
+		return c.put(k, v)
+	}
+	k, v, err := c.splitKey("append", k, v)
+	if err != nil {
+		return err
+	}
+	return c.AppendDup(k, v)
+}
+
+// AppendDup - like Append, without AutoDupSortKeysConversion
+func (c *BoltCursor) AppendDup(k, v []byte) error {
+	if !c.dupSort {
+		return c.Append(k, v)
+	}
+	bk := dupKey(k, v)
+	if c.c != nil {
+		last, _ := c.c.Seek(dupKeyEnd(k))
+		if last == nil {
+			last, _ = c.c.Last()
+		} else {
+			last, _ = c.c.Prev()
+		}
+		c.valid = false
+		if last != nil && bytes.HasPrefix(last, dupKey(k, nil)) && bytes.Compare(bk, last) <= 0 {
+			return fmt.Errorf("bucket: %s, append value %x of key %x isn't after the last value", c.bucketName, v, k)
+		}
+	}
+	return c.put(bk, []byte{})
+}
+
+func (c *BoltCursor) Delete(k, v []byte) error {
+	if !c.dupSort {
+		return c.del(k)
+	}
+	if b := c.bucketCfg; b.AutoDupSortKeysConversion {
+		from, to := b.DupFromLen, b.DupToLen
+		if len(k) != from && len(k) >= to {
+			return fmt.Errorf("delete from dupsort bucket: %s, can have keys of len==%d and len<%d. key: %x,%d", c.bucketName, from, to, k, len(k))
+		}
+		if len(k) == from {
+			return c.delPrefix(dupKey(k[:to], k[to:]))
+		}
+		return c.delPrefix(dupKey(k, nil))
+	}
+	bk := dupKey(k, v)
+	if found, _ := c.seekRaw(bk); !bytes.Equal(found, bk) {
+		return nil
+	}
+	return c.del(bk)
+}
+
+func (c *BoltCursor) DeleteCurrent() error {
+	bk, _ := c.currentRaw()
+	if bk == nil {
+		return fmt.Errorf("table: %s, the cursor isn't at a key", c.bucketName)
+	}
+	return c.del(bk)
+}
+
+func (c *BoltCursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	bk := dupKey(key, value)
+	if found, _ := c.seekRaw(bk); !bytes.Equal(found, bk) {
+		return nil, nil, nil
+	}
+	return key, value, nil
+}
+
+func (c *BoltCursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	k, v, err := c.pair(c.seekRaw(dupKey(key, value)))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(k, key) {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (c *BoltCursor) FirstDup() ([]byte, error) {
+	k, err := c.currentKey()
+	if err != nil || k == nil {
+		return nil, err
+	}
+	k2, v, err := c.pair(c.seekRaw(dupKey(k, nil)))
+	if err != nil || !bytes.Equal(k2, k) {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (c *BoltCursor) LastDup() ([]byte, error) {
+	k, err := c.currentKey()
+	if err != nil || k == nil {
+		return nil, err
+	}
+	pos := c.key
+	c.key, c.valid = dupKeyEnd(k), false
+	k2, v, err := c.pair(c.prevRaw())
+	if err != nil || !bytes.Equal(k2, k) {
+		c.key, c.valid = pos, false
+		return nil, err
+	}
+	return v, nil
+}
+
+// NextDup - the next value of the key at the position, nil after the last one
+func (c *BoltCursor) NextDup() ([]byte, []byte, error) {
+	k, err := c.currentKey()
+	if err != nil || k == nil {
+		return nil, nil, err
+	}
+	pos := c.key
+	k2, v, err := c.pair(c.nextRaw())
+	if err != nil {
+		return []byte{}, nil, err
+	}
+	if !bytes.Equal(k2, k) {
+		c.key, c.valid = pos, false
+		return nil, nil, nil
+	}
+	return k2, v, nil
+}
+
+// NextNoDup - the first value of the key after the one at the position
+func (c *BoltCursor) NextNoDup() ([]byte, []byte, error) {
+	k, err := c.currentKey()
+	if err != nil {
+		return []byte{}, nil, err
+	}
+	if k == nil {
+		return c.pair(c.firstRaw())
+	}
+	return c.pair(c.seekRaw(dupKeyEnd(k)))
+}
+
+func (c *BoltCursor) CountDuplicates() (uint64, error) {
+	k, err := c.currentKey()
+	if err != nil || k == nil {
+		return 0, err
+	}
+	start := dupKey(k, nil)
+	var count uint64
+	bc := c.b.Cursor()
+	for bk, _ := bc.Seek(start); bk != nil && bytes.HasPrefix(bk, start); bk, _ = bc.Next() {
+		count++
+	}
+	return count, nil
+}
+
+func (c *BoltCursor) PutNoDupData(k, v []byte) error {
+	bk, bv := k, v
+	if c.dupSort {
+		bk, bv = dupKey(k, v), []byte{}
+	}
+	if found, _ := c.seekRaw(bk); bytes.Equal(found, bk) {
+		return fmt.Errorf("in PutNoDupData: bucket: %s, key/value exists: %x/%x", c.bucketName, k, v)
+	}
+	return c.put(bk, bv)
+}
+
+func (c *BoltCursor) DeleteCurrentDuplicates() error {
+	k, err := c.currentKey()
+	if err != nil {
+		return err
+	}
+	if k == nil {
+		return fmt.Errorf("table: %s, the cursor isn't at a key", c.bucketName)
+	}
+	return c.delDups(k)
+}
@@ -0,0 +1,260 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBolt(t *testing.T) kv.RwDB {
+	db := NewBolt(log.New()).Path(t.TempDir()).MapSize(1 << 24).MustOpen()
+	t.Cleanup(db.Close)
+	return db
+}
+
+// the keys are made of few bytes, with zeros, for the writes to hit the same keys and the escaping of the keys of
+// the DupSort tables
+func randBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Intn(3))
+	}
+	return b
+}
+
+func randKey(r *rand.Rand, table string) []byte {
+	if table == kv.PlainState {
+		if r.Intn(2) == 0 {
+			return bytes.Repeat([]byte{byte(r.Intn(3))}, 20)
+		}
+		k := bytes.Repeat([]byte{byte(r.Intn(3))}, 28)
+		return append(k, bytes.Repeat([]byte{byte(r.Intn(3))}, 32)...)
+	}
+	return randBytes(r, 1+r.Intn(3))
+}
+
+func requireSame(t *testing.T, step string, expected, actual []byte) {
+	t.Helper()
+	if (expected == nil) != (actual == nil) || !bytes.Equal(expected, actual) {
+		require.FailNow(t, "bolt differs from mdbx", "%s: expected %x (nil: %t), got %x (nil: %t)", step, expected, expected == nil, actual, actual == nil)
+	}
+}
+
+// TestBoltLikeMdbx - the same random operations give the same results with mdbx and with bolt, on a plain table, a
+// DupSort table and a table with AutoDupSortKeysConversion
+func TestBoltLikeMdbx(t *testing.T) {
+	for _, table := range []string{kv.Headers, kv.AccountChangeSet, kv.PlainState} {
+		table := table
+		t.Run(table, func(t *testing.T) {
+			r := rand.New(rand.NewSource(42))
+			ctx := context.Background()
+			mdbxTx, err := memdb.NewTestDB(t).BeginRw(ctx)
+			require.NoError(t, err)
+			defer mdbxTx.Rollback()
+			boltTx, err := newTestBolt(t).BeginRw(ctx)
+			require.NoError(t, err)
+			defer boltTx.Rollback()
+
+			dupSort := table != kv.Headers
+			mc, err := mdbxTx.RwCursorDupSort(table)
+			require.NoError(t, err)
+			defer mc.Close()
+			bc, err := boltTx.RwCursorDupSort(table)
+			require.NoError(t, err)
+			defer bc.Close()
+
+			positioned := false
+			for i := 0; i < 5000; i++ {
+				k, v := randKey(r, table), randBytes(r, 1+r.Intn(2))
+				op := r.Intn(18)
+				if !dupSort && op >= 10 || !positioned && op >= 6 {
+					op = r.Intn(6)
+				}
+				step := fmt.Sprintf("step %d, op %d, key %x, value %x", i, op, k, v)
+				var mk, mv, bk, bv []byte
+				var merr, berr error
+				switch op {
+				case 0:
+					require.NoError(t, mc.Put(k, v), step)
+					require.NoError(t, bc.Put(k, v), step)
+					continue
+				case 1:
+					// mdbx moves the cursors at the changes made by the other cursors
+					require.NoError(t, mdbxTx.Delete(table, k, v), step)
+					require.NoError(t, boltTx.Delete(table, k, v), step)
+					positioned = false
+					continue
+				case 2:
+					mk, mv, merr = mc.Seek(k)
+					bk, bv, berr = bc.Seek(k)
+				case 3:
+					mk, mv, merr = mc.SeekExact(k)
+					bk, bv, berr = bc.SeekExact(k)
+				case 4:
+					mk, mv, merr = mc.First()
+					bk, bv, berr = bc.First()
+				case 5:
+					mk, mv, merr = mc.Last()
+					bk, bv, berr = bc.Last()
+				case 6:
+					mk, mv, merr = mc.Next()
+					bk, bv, berr = bc.Next()
+				case 7:
+					mk, mv, merr = mc.Prev()
+					bk, bv, berr = bc.Prev()
+				case 8:
+					mk, mv, merr = mc.Current()
+					bk, bv, berr = bc.Current()
+				case 9:
+					require.NoError(t, mc.DeleteCurrent(), step)
+					require.NoError(t, bc.DeleteCurrent(), step)
+					positioned = false
+					continue
+				case 10:
+					mk, mv, merr = mc.NextDup()
+					bk, bv, berr = bc.NextDup()
+				case 11:
+					mk, mv, merr = mc.SeekBothExact(k, v)
+					bk, bv, berr = bc.SeekBothExact(k, v)
+				case 12:
+					mk, mv, merr = mc.NextNoDup()
+					bk, bv, berr = bc.NextNoDup()
+				case 13:
+					require.NoError(t, mc.DeleteCurrentDuplicates(), step)
+					require.NoError(t, bc.DeleteCurrentDuplicates(), step)
+					positioned = false
+					continue
+				case 14:
+					merr, berr = mc.PutNoDupData(k, v), bc.PutNoDupData(k, v)
+					require.Equal(t, merr == nil, berr == nil, step)
+					continue
+				case 15:
+					mv, merr = mc.LastDup()
+					bv, berr = bc.LastDup()
+					mk, bk = mv, bv
+				case 16:
+					mv, merr = mc.SeekBothRange(k, v)
+					bv, berr = bc.SeekBothRange(k, v)
+					mk, bk = mv, bv
+				case 17:
+					var mn, bn uint64
+					mn, merr = mc.CountDuplicates()
+					bn, berr = bc.CountDuplicates()
+					require.NoError(t, merr, step)
+					require.NoError(t, berr, step)
+					require.Equal(t, mn, bn, step)
+					continue
+				}
+				require.NoError(t, merr, step)
+				require.NoError(t, berr, step)
+				requireSame(t, step, mk, bk)
+				requireSame(t, step, mv, bv)
+				// the position after the end, or after a miss, isn't defined
+				positioned = mk != nil
+			}
+
+			var expected, actual [][]byte
+			require.NoError(t, mdbxTx.ForEach(table, nil, func(k, v []byte) error {
+				expected = append(expected, copyBytes(k), copyBytes(v))
+				return nil
+			}))
+			require.NoError(t, boltTx.ForEach(table, nil, func(k, v []byte) error {
+				actual = append(actual, copyBytes(k), copyBytes(v))
+				return nil
+			}))
+			require.NotEmpty(t, expected)
+			require.Equal(t, expected, actual)
+
+			// deleting while iterating, like the unwinds and the prunes
+			var kept [][]byte
+			j := 0
+			for k, v, err := bc.First(); k != nil; k, v, err = bc.Next() {
+				require.NoError(t, err)
+				if j++; j%2 == 0 {
+					kept = append(kept, copyBytes(k), copyBytes(v))
+				} else {
+					require.NoError(t, bc.DeleteCurrent())
+				}
+			}
+			actual = nil
+			require.NoError(t, boltTx.ForEach(table, nil, func(k, v []byte) error {
+				actual = append(actual, copyBytes(k), copyBytes(v))
+				return nil
+			}))
+			require.Equal(t, kept, actual)
+		})
+	}
+}
+
+func copyBytes(b []byte) []byte { return append([]byte{}, b...) }
+
+func TestBoltReopen(t *testing.T) {
+	dir := t.TempDir()
+	db := NewBolt(log.New()).Path(dir).MapSize(1 << 24).MustOpen()
+	ctx := context.Background()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		if err := tx.Put(kv.PlainState, []byte{1, 0, 2}, []byte{3}); err != nil {
+			return err
+		}
+		_, err := tx.IncrementSequence(kv.EthTx, 10)
+		return err
+	}))
+	db.Close()
+
+	db = NewBolt(log.New()).Path(dir).MapSize(1 << 24).Readonly().MustOpen()
+	defer db.Close()
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.PlainState, []byte{1, 0, 2})
+		require.NoError(t, err)
+		require.Equal(t, []byte{3}, v)
+		seq, err := tx.ReadSequence(kv.EthTx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(10), seq)
+		return nil
+	}))
+	_, err := db.BeginRw(ctx)
+	require.Error(t, err)
+}
+
+func TestBoltAppend(t *testing.T) {
+	ctx := context.Background()
+	for _, db := range []kv.RwDB{memdb.NewTestDB(t), newTestBolt(t)} {
+		tx, err := db.BeginRw(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		// the keys of the storage are split like by Put
+		acc, storage := bytes.Repeat([]byte{1}, 20), bytes.Repeat([]byte{1}, 60)
+		c, err := tx.RwCursor(kv.PlainState)
+		require.NoError(t, err)
+		_, isDupSort := c.(kv.RwCursorDupSort) // the raw keys aren't written by mistake
+		require.False(t, isDupSort)
+		require.NoError(t, c.Append(acc, []byte{1}))
+		require.NoError(t, c.Append(storage, []byte{2}))
+		v, err := tx.GetOne(kv.PlainState, storage)
+		require.NoError(t, err)
+		require.Equal(t, []byte{2}, v)
+
+		dc, err := tx.RwCursorDupSort(kv.AccountChangeSet)
+		require.NoError(t, err)
+		require.NoError(t, dc.AppendDup([]byte{2}, []byte{1}))
+		require.NoError(t, dc.AppendDup([]byte{2}, []byte{2}))
+		require.Error(t, dc.AppendDup([]byte{2}, []byte{1}))
+		require.NoError(t, dc.AppendDup([]byte{1}, []byte{3}))
+		n, err := dc.CountDuplicates()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), n)
+
+		hc, err := tx.RwCursor(kv.Headers)
+		require.NoError(t, err)
+		require.NoError(t, hc.Append([]byte{1}, []byte{1}))
+		require.Error(t, hc.Append([]byte{1}, []byte{2}))
+	}
+}
@@ -0,0 +1,151 @@
+// Package dbstats collects per-table statistics of the chaindata database: to see which tables grow
+package dbstats
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+)
+
+var ErrNotLocal = errors.New("db stats are only available for local mdbx database")
+
+type Table struct {
+	Name          string `json:"name"`
+	Entries       uint64 `json:"entries"`
+	Size          uint64 `json:"size"` // bytes of all pages of the table
+	Depth         uint   `json:"depth"`
+	BranchPages   uint64 `json:"branchPages"`
+	LeafPages     uint64 `json:"leafPages"`
+	OverflowPages uint64 `json:"overflowPages"`
+	// Payload - bytes of keys and values, only counted by Scan
+	Payload uint64 `json:"payload,omitempty"`
+	// GrowthPerDay - bytes per day, by the size of the table in a previous Sample
+	GrowthPerDay int64 `json:"growthPerDay"`
+}
+
+// Utilization - share of the table pages filled by keys and values, 0 if the table is not scanned
+func (t Table) Utilization() float64 {
+	if t.Size == 0 {
+		return 0
+	}
+	return float64(t.Payload) / float64(t.Size)
+}
+
+// Collect - statistics of non-empty tables, biggest first. Payload is not counted, see Scan.
+func Collect(tx kv.Tx) (tables []Table, pageSize uint64, err error) {
+	mdbxTx, ok := tx.(*mdbx.MdbxTx)
+	if !ok {
+		return nil, 0, ErrNotLocal
+	}
+	for _, name := range kv.ChaindataTables {
+		st, err := mdbxTx.BucketStat(name)
+		if err != nil { // tables of newer versions don't exist in the databases of older ones
+			continue
+		}
+		pageSize = uint64(st.PSize)
+		if st.Entries == 0 {
+			continue
+		}
+		tables = append(tables, Table{
+			Name:          name,
+			Entries:       st.Entries,
+			Size:          (st.BranchPages + st.LeafPages + st.OverflowPages) * uint64(st.PSize),
+			Depth:         st.Depth,
+			BranchPages:   st.BranchPages,
+			LeafPages:     st.LeafPages,
+			OverflowPages: st.OverflowPages,
+		})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Size > tables[j].Size })
+	return tables, pageSize, nil
+}
+
+// Scan - count Payload of the table: reads all the table
+func Scan(ctx context.Context, tx kv.Tx, t *Table) error {
+	t.Payload = 0
+	return tx.ForEach(t.Name, nil, func(k, v []byte) error {
+		t.Payload += uint64(len(k) + len(v))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		return nil
+	})
+}
+
+// Sample - sizes of tables at some moment
+type Sample struct {
+	Time  time.Time         `json:"time"`
+	Sizes map[string]uint64 `json:"sizes"`
+}
+
+func NewSample(tables []Table, now time.Time) Sample {
+	s := Sample{Time: now, Sizes: make(map[string]uint64, len(tables))}
+	for _, t := range tables {
+		s.Sizes[t.Name] = t.Size
+	}
+	return s
+}
+
+// SetGrowth - set GrowthPerDay of tables by their sizes in previous sample prev
+func SetGrowth(tables []Table, prev Sample, now time.Time) {
+	elapsed := now.Sub(prev.Time)
+	if elapsed <= 0 {
+		return
+	}
+	for i := range tables {
+		diff := int64(tables[i].Size) - int64(prev.Sizes[tables[i].Name])
+		tables[i].GrowthPerDay = int64(float64(diff) * float64(24*time.Hour) / float64(elapsed))
+	}
+}
+
+// HistorySize - max number of samples kept by History
+const HistorySize = 64
+
+// History - samples of last Keep duration, to measure growth by the oldest one. It's a ring buffer of HistorySize
+// samples at least Keep/HistorySize apart: a sample closer to the newest one replaces it.
+type History struct {
+	Keep    time.Duration
+	lock    sync.Mutex
+	samples [HistorySize]Sample
+	first   int // index of the oldest sample
+	count   int
+}
+
+func NewHistory(keep time.Duration) *History { return &History{Keep: keep} }
+
+func (h *History) at(i int) *Sample { return &h.samples[(h.first+i)%HistorySize] }
+
+// Add - adds sample s, returns the oldest sample of the history (kept the last one older than Keep, to measure
+// growth over whole Keep period), ok=false if s is the first sample
+func (h *History) Add(s Sample) (oldest Sample, ok bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for h.count > 1 && s.Time.Sub(h.at(1).Time) >= h.Keep {
+		h.first = (h.first + 1) % HistorySize
+		h.count--
+	}
+	if h.count == 0 {
+		*h.at(0) = s
+		h.count = 1
+		return oldest, false
+	}
+	oldest = *h.at(0)
+	switch {
+	case h.count > 1 && s.Time.Sub(h.at(h.count-1).Time) < h.Keep/HistorySize:
+		*h.at(h.count - 1) = s
+	case h.count == HistorySize:
+		h.first = (h.first + 1) % HistorySize
+		*h.at(h.count - 1) = s
+	default:
+		*h.at(h.count) = s
+		h.count++
+	}
+	return oldest, true
+}
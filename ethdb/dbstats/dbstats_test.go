@@ -0,0 +1,47 @@
+package dbstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrowth(t *testing.T) {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHistory(24 * time.Hour)
+	_, ok := h.Add(Sample{Time: start, Sizes: map[string]uint64{"A": 100}})
+	require.False(t, ok)
+
+	tables := []Table{{Name: "A", Size: 200}, {Name: "B", Size: 50}}
+	now := start.Add(12 * time.Hour)
+	prev, ok := h.Add(NewSample(tables, now))
+	require.True(t, ok)
+	require.Equal(t, start, prev.Time)
+	SetGrowth(tables, prev, now)
+	require.Equal(t, int64(200), tables[0].GrowthPerDay)
+	require.Equal(t, int64(100), tables[1].GrowthPerDay)
+
+	// samples older than Keep are dropped, but the last of them is kept
+	prev, _ = h.Add(Sample{Time: start.Add(30 * time.Hour)})
+	require.Equal(t, start, prev.Time)
+	prev, _ = h.Add(Sample{Time: start.Add(40 * time.Hour)})
+	require.Equal(t, now, prev.Time)
+
+	// frequent samples replace the newest one, the history stays bounded
+	for i := 0; i < 10*HistorySize; i++ {
+		prev, _ = h.Add(Sample{Time: start.Add(40*time.Hour + time.Duration(i)*time.Second)})
+		require.Equal(t, now, prev.Time)
+	}
+	require.Equal(t, 3, h.count)
+	last := start.Add(41 * time.Hour)
+	for i := 0; i < 10*HistorySize; i++ {
+		last = last.Add(h.Keep / HistorySize)
+		prev, _ = h.Add(Sample{Time: last})
+		require.LessOrEqual(t, h.count, HistorySize)
+	}
+	require.GreaterOrEqual(t, last.Sub(prev.Time), h.Keep-h.Keep/HistorySize)
+
+	require.Equal(t, 0.5, Table{Size: 200, Payload: 100}.Utilization())
+	require.Zero(t, Table{}.Utilization())
+}
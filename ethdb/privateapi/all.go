@@ -8,16 +8,18 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentryadmin"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // accept (and respond with) gzip compression, see rpcdaemon --private.api.compression
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txPoolServer txpool_proto.TxpoolServer,
-	miningServer txpool_proto.MiningServer, addr string, rateLimit uint32, creds credentials.TransportCredentials,
-	healthCheck bool) (*grpc.Server, error) {
+	miningServer txpool_proto.MiningServer, firehoseServer *FirehoseServer, sentryAdmin sentryadmin.Admin, addr string, rateLimit uint32,
+	creds credentials.TransportCredentials, healthCheck bool) (*grpc.Server, error) {
 	log.Info("Starting private RPC server", "on", addr)
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -32,6 +34,12 @@ func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txP
 	if miningServer != nil {
 		txpool_proto.RegisterMiningServer(grpcServer, miningServer)
 	}
+	if firehoseServer != nil {
+		RegisterFirehoseServer(grpcServer, firehoseServer)
+	}
+	if sentryAdmin != nil {
+		sentryadmin.RegisterAdminServer(grpcServer, sentryAdmin)
+	}
 	remote.RegisterKVServer(grpcServer, kv)
 	var healthServer *health.Server
 	if healthCheck {
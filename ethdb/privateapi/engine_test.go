@@ -2,6 +2,7 @@ package privateapi
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
@@ -254,3 +255,60 @@ func TestNoTTD(t *testing.T) {
 
 	require.Equal(err.Error(), "not a proof-of-stake chain")
 }
+
+func TestBackfillBeforeTTD(t *testing.T) {
+	db := memdb.New()
+	ctx := context.Background()
+	require := require.New(t)
+
+	makeTestDb(ctx, db)
+	tx, _ := db.BeginRw(ctx)
+	require.NoError(rawdb.WriteTd(tx, startingHeadHash, 50, big.NewInt(10)))
+	require.NoError(tx.Commit())
+	config := &params.ChainConfig{TerminalTotalDifficulty: big.NewInt(100)}
+	forkChoice := func(head common.Hash) *remote.EngineForkChoiceUpdatedRequest {
+		return &remote.EngineForkChoiceUpdatedRequest{ForkchoiceState: &remote.EngineForkChoiceState{
+			HeadBlockHash:      gointerfaces.ConvertHashToH256(head),
+			SafeBlockHash:      gointerfaces.ConvertHashToH256(head),
+			FinalizedBlockHash: gointerfaces.ConvertHashToH256(head),
+		}}
+	}
+
+	// The heads known to be before the terminal total difficulty are refused
+	backend := NewEthBackendServer(ctx, nil, db, NewEvents(), nil, config, engineapi.NewRequestList(), make(chan PayloadStatus), nil, false)
+	reply, err := backend.EngineNewPayloadV1(ctx, mockPayload3)
+	require.NoError(err)
+	require.Equal(remote.EngineStatus_INVALID, reply.Status)
+	fcuReply, err := backend.EngineForkChoiceUpdatedV1(ctx, forkChoice(startingHeadHash))
+	require.NoError(err)
+	require.Equal(remote.EngineStatus_INVALID, fcuReply.PayloadStatus.Status)
+
+	// but the ones whose total difficulty isn't backfilled yet are handed to the staged sync
+	beaconRequestList := engineapi.NewRequestList()
+	statusCh := make(chan PayloadStatus)
+	backend = NewEthBackendServer(ctx, nil, db, NewEvents(), nil, config, beaconRequestList, statusCh, nil, false)
+	done := make(chan bool)
+	go func() {
+		reply, err = backend.EngineNewPayloadV1(ctx, mockPayload1)
+		done <- true
+	}()
+	_, _, request := beaconRequestList.WaitForRequest(true)
+	require.Equal(uint64(100), request.Message.(*engineapi.PayloadMessage).Header.Number.Uint64())
+	statusCh <- PayloadStatus{Status: remote.EngineStatus_SYNCING}
+	<-done
+	require.NoError(err)
+	require.Equal(remote.EngineStatus_SYNCING, reply.Status)
+
+	beaconRequestList = engineapi.NewRequestList()
+	backend = NewEthBackendServer(ctx, nil, db, NewEvents(), nil, config, beaconRequestList, statusCh, nil, false)
+	go func() {
+		fcuReply, err = backend.EngineForkChoiceUpdatedV1(ctx, forkChoice(payload1Hash))
+		done <- true
+	}()
+	_, _, request = beaconRequestList.WaitForRequest(true)
+	require.Equal(payload1Hash, request.Message.(*engineapi.ForkChoiceMessage).HeadBlockHash)
+	statusCh <- PayloadStatus{Status: remote.EngineStatus_SYNCING}
+	<-done
+	require.NoError(err)
+	require.Equal(remote.EngineStatus_SYNCING, fcuReply.PayloadStatus.Status)
+}
@@ -25,6 +25,9 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/log/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slices"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -39,6 +42,9 @@ var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 1, Patch: 0}
 
 const MaxBuilders = 128
 
+// tracer makes the spans of the engine API requests, which the spans of the stages handling them are part of
+var tracer = otel.Tracer("github.com/ledgerwatch/erigon/ethdb/privateapi")
+
 var UnknownPayloadErr = rpc.CustomError{Code: -38001, Message: "Unknown payload"}
 var InvalidForkchoiceStateErr = rpc.CustomError{Code: -38002, Message: "Invalid forkchoice state"}
 var InvalidPayloadAttributesErr = rpc.CustomError{Code: -38003, Message: "Invalid payload attributes"}
@@ -274,6 +280,12 @@ func (s *EthBackendServer) stageLoopIsBusy() bool {
 
 // EngineNewPayloadV1 validates and possibly executes payload
 func (s *EthBackendServer) EngineNewPayloadV1(ctx context.Context, req *types2.ExecutionPayload) (*remote.EnginePayloadStatus, error) {
+	ctx, span := tracer.Start(ctx, "EngineNewPayloadV1", trace.WithAttributes(
+		attribute.Int64("block.number", int64(req.BlockNumber)),
+		attribute.String("block.hash", common.Hash(gointerfaces.ConvertH256ToHash(req.BlockHash)).String()),
+	))
+	defer span.End()
+
 	if s.config.TerminalTotalDifficulty == nil {
 		log.Error("[NewPayload] not a proof-of-stake chain")
 		return nil, fmt.Errorf("not a proof-of-stake chain")
@@ -317,6 +329,7 @@ func (s *EthBackendServer) EngineNewPayloadV1(ctx context.Context, req *types2.E
 	if err != nil {
 		return nil, err
 	}
+	// The total difficulty of a parent not backfilled yet (--sync.backfill) is unknown: only the known ones are checked
 	if parentTd != nil && parentTd.Cmp(s.config.TerminalTotalDifficulty) < 0 {
 		log.Warn("[NewPayload] TTD not reached yet", "height", header.Number, "hash", common.Hash(blockHash))
 		return &remote.EnginePayloadStatus{Status: remote.EngineStatus_INVALID, LatestValidHash: gointerfaces.ConvertHashToH256(common.Hash{})}, nil
@@ -351,10 +364,12 @@ func (s *EthBackendServer) EngineNewPayloadV1(ctx context.Context, req *types2.E
 			Transactions: req.Transactions,
 			Uncles:       nil,
 		},
+		SpanContext: span.SpanContext(),
 	})
 
 	payloadStatus := <-s.statusCh
 	log.Debug("[NewPayload] got reply", "payloadStatus", payloadStatus)
+	span.SetAttributes(attribute.String("engine.status", payloadStatus.Status.String()))
 
 	if payloadStatus.CriticalError != nil {
 		return nil, payloadStatus.CriticalError
@@ -384,7 +399,8 @@ func (s *EthBackendServer) EngineGetPayloadV1(ctx context.Context, req *remote.E
 		return nil, &UnknownPayloadErr
 	}
 
-	block := builder.Stop()
+	// the payload isn't rebuilt after it's requested, so that the proposal doesn't keep the execution busy
+	block := builder.Block()
 
 	var baseFeeReply *types2.H256
 	if block.Header().BaseFee != nil {
@@ -423,10 +439,17 @@ func (s *EthBackendServer) EngineForkChoiceUpdatedV1(ctx context.Context, req *r
 		return nil, fmt.Errorf("not a proof-of-stake chain")
 	}
 
+	ctx, span := tracer.Start(ctx, "EngineForkChoiceUpdatedV1", trace.WithAttributes(
+		attribute.String("head.hash", common.Hash(gointerfaces.ConvertH256ToHash(req.ForkchoiceState.HeadBlockHash)).String()),
+		attribute.Bool("payload.attributes", req.PayloadAttributes != nil),
+	))
+	defer span.End()
+
 	forkChoice := engineapi.ForkChoiceMessage{
 		HeadBlockHash:      gointerfaces.ConvertH256ToHash(req.ForkchoiceState.HeadBlockHash),
 		SafeBlockHash:      gointerfaces.ConvertH256ToHash(req.ForkchoiceState.SafeBlockHash),
 		FinalizedBlockHash: gointerfaces.ConvertH256ToHash(req.ForkchoiceState.FinalizedBlockHash),
+		SpanContext:        span.SpanContext(),
 	}
 
 	tx1, err := s.db.BeginRo(ctx)
@@ -463,6 +486,7 @@ func (s *EthBackendServer) EngineForkChoiceUpdatedV1(ctx context.Context, req *r
 
 	status := <-s.statusCh
 	log.Debug("[ForkChoiceUpdated] got reply", "payloadStatus", status)
+	span.SetAttributes(attribute.String("engine.status", status.Status.String()))
 
 	if status.CriticalError != nil {
 		return nil, status.CriticalError
@@ -504,13 +528,6 @@ func (s *EthBackendServer) EngineForkChoiceUpdatedV1(ctx context.Context, req *r
 		return nil, &InvalidPayloadAttributesErr
 	}
 
-	// Initiate payload building
-
-	s.evictOldBuilders()
-
-	// payload IDs start from 1 (0 signifies null)
-	s.payloadId++
-
 	emptyHeader := core.MakeEmptyHeader(headHeader, s.config, req.PayloadAttributes.Timestamp, nil)
 	emptyHeader.Coinbase = gointerfaces.ConvertH160toAddress(req.PayloadAttributes.SuggestedFeeRecipient)
 	emptyHeader.MixDigest = gointerfaces.ConvertH256ToHash(req.PayloadAttributes.PrevRandao)
@@ -522,17 +539,42 @@ func (s *EthBackendServer) EngineForkChoiceUpdatedV1(ctx context.Context, req *r
 		SuggestedFeeRecipient: emptyHeader.Coinbase,
 	}
 
-	s.builders[s.payloadId] = builder.NewBlockBuilder(s.builderFunc, &param, emptyHeader)
+	// The same attributes may be sent again (e.g. by redundant consensus clients), keep building the same payload
+	payloadId, ok := s.builderByParams(&param)
+	if !ok {
+		// Initiate payload building
+		s.evictOldBuilders()
+
+		// a new payload supersedes the previous ones, which remain available as they are
+		for _, b := range s.builders {
+			b.Interrupt()
+		}
+
+		// payload IDs start from 1 (0 signifies null)
+		s.payloadId++
+		payloadId = s.payloadId
+
+		s.builders[payloadId] = builder.NewBlockBuilder(s.builderFunc, &param, emptyHeader)
+	}
 
 	return &remote.EngineForkChoiceUpdatedReply{
 		PayloadStatus: &remote.EnginePayloadStatus{
 			Status:          remote.EngineStatus_VALID,
 			LatestValidHash: gointerfaces.ConvertHashToH256(headHash),
 		},
-		PayloadId: s.payloadId,
+		PayloadId: payloadId,
 	}, nil
 }
 
+func (s *EthBackendServer) builderByParams(param *core.BlockBuilderParameters) (uint64, bool) {
+	for id, b := range s.builders {
+		if *b.Params() == *param {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
 func (s *EthBackendServer) evictOldBuilders() {
 	// sort payload IDs in ascending order
 	ids := make([]uint64, 0, len(s.builders))
@@ -543,6 +585,7 @@ func (s *EthBackendServer) evictOldBuilders() {
 
 	// remove old builders so that at most MaxBuilders - 1 remain
 	for i := 0; i <= len(s.builders)-MaxBuilders; i++ {
+		s.builders[ids[i]].Interrupt()
 		delete(s.builders, ids[i])
 	}
 }
@@ -25,7 +25,7 @@ type Events struct {
 	pendingBlockSubscriptions map[int]PendingBlockSubscription
 	pendingTxsSubscriptions   map[int]PendingTxsSubscription
 	logsSubscriptions         map[int]chan []*remote.SubscribeLogsReply
-	hasLogSubscriptions       bool
+	logsInterest              *LogsInterest // nil if there is no logs subscriber
 	lock                      sync.RWMutex
 	onNewSnapshotsHappened    bool
 }
@@ -80,16 +80,17 @@ func (e *Events) AddLogsSubscription() (chan []*remote.SubscribeLogsReply, func(
 	}
 }
 
-func (e *Events) EmptyLogSubsctiption(empty bool) {
+func (e *Events) SetLogsInterest(li *LogsInterest) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	e.hasLogSubscriptions = !empty
+	e.logsInterest = li
 }
 
-func (e *Events) HasLogSubsriptions() bool {
+// HasLogSubsriptions returns whether there are logs subscribers, and the logs they are interested in.
+func (e *Events) HasLogSubsriptions() (bool, *LogsInterest) {
 	e.lock.RLock()
 	defer e.lock.RUnlock()
-	return e.hasLogSubscriptions
+	return e.logsInterest != nil, e.logsInterest
 }
 
 func (e *Events) AddPendingLogsSubscription(s PendingLogsSubscription) {
@@ -0,0 +1,325 @@
+package privateapi
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// The firehose streams the data of each block once it's executed and indexed, and undoes blocks
+// unwound by reorgs, for indexers following the node (like TheGraph). Messages are encoded in JSON,
+// wrapped in BytesValue, see FirehoseBlock.
+
+const (
+	// FirehoseStepNew - block added to the canonical chain
+	FirehoseStepNew = "new"
+	// FirehoseStepUndo - block sent before, which isn't canonical anymore: its data must be reverted
+	FirehoseStepUndo = "undo"
+
+	firehoseBatch    = 100  // blocks read in a read transaction, and sent after it
+	firehoseMaxUndos = 1024 // blocks remembered to undo, like headers notified to rpcdaemon
+)
+
+// FirehoseRequest - blocks are streamed from StartBlock, then as they are executed
+type FirehoseRequest struct {
+	StartBlock uint64 `json:"startBlock"`
+}
+
+// FirehoseBlock - step of the stream. Undo steps have only the number and the hash of the block.
+// Receipts, balance and storage changes, and call addresses of blocks deleted by pruning are empty.
+type FirehoseBlock struct {
+	Step           string                  `json:"step"`
+	Number         uint64                  `json:"number"`
+	Hash           common.Hash             `json:"hash"`
+	Header         *types.Header           `json:"header,omitempty"`
+	Transactions   []hexutil.Bytes         `json:"transactions,omitempty"` // RLP encoded
+	Senders        []common.Address        `json:"senders,omitempty"`
+	Receipts       types.Receipts          `json:"receipts,omitempty"`
+	BalanceChanges []FirehoseBalanceChange `json:"balanceChanges,omitempty"`
+	StorageChanges []FirehoseStorageChange `json:"storageChanges,omitempty"`
+	CallAddresses  []FirehoseCallAddress   `json:"callAddresses,omitempty"`
+}
+
+type FirehoseBalanceChange struct {
+	Address common.Address `json:"address"`
+	Old     *hexutil.Big   `json:"old"`
+	New     *hexutil.Big   `json:"new"`
+}
+
+type FirehoseStorageChange struct {
+	Address     common.Address `json:"address"`
+	Incarnation uint64         `json:"incarnation"`
+	Location    common.Hash    `json:"location"`
+	Old         hexutil.Bytes  `json:"old"`
+	New         hexutil.Bytes  `json:"new"`
+}
+
+// FirehoseCallAddress - address which was a sender (From) or a recipient (To) of calls in the block,
+// as indexed by the call traces stage
+type FirehoseCallAddress struct {
+	Address common.Address `json:"address"`
+	From    bool           `json:"from"`
+	To      bool           `json:"to"`
+}
+
+type FirehoseServer struct {
+	ctx         context.Context
+	db          kv.RoDB
+	events      *Events
+	blockReader services.FullBlockReader
+}
+
+func NewFirehoseServer(ctx context.Context, db kv.RoDB, events *Events, blockReader services.FullBlockReader) *FirehoseServer {
+	return &FirehoseServer{ctx: ctx, db: db, events: events, blockReader: blockReader}
+}
+
+type firehoseSentBlock struct {
+	number uint64
+	hash   common.Hash
+}
+
+// Blocks - stream the blocks from req.StartBlock up to the head, then the new blocks after each sync cycle
+func (s *FirehoseServer) Blocks(req *FirehoseRequest, stream grpc.ServerStream) error {
+	ch, clean := s.events.AddHeaderSubscription()
+	defer clean()
+	log.Info("firehose subscription established", "from", req.StartBlock)
+
+	next := req.StartBlock
+	var sent []firehoseSentBlock
+	for {
+		// the steps are read in a read transaction, and sent after it: not to keep it open on slow clients
+		var steps []*FirehoseBlock
+		var more bool
+		if err := s.db.View(stream.Context(), func(tx kv.Tx) error {
+			// undo the blocks unwound since they were sent, the latest first
+			for len(sent) > 0 {
+				last := sent[len(sent)-1]
+				hash, err := s.blockReader.CanonicalHash(stream.Context(), tx, last.number)
+				if err != nil {
+					return err
+				}
+				if hash == last.hash {
+					break
+				}
+				steps = append(steps, &FirehoseBlock{Step: FirehoseStepUndo, Number: last.number, Hash: last.hash})
+				sent, next = sent[:len(sent)-1], last.number
+			}
+
+			head, err := stages.GetStageProgress(tx, stages.Finish)
+			if err != nil {
+				return err
+			}
+			for i := 0; next <= head; i++ {
+				if i == firehoseBatch {
+					more = true
+					return nil
+				}
+				block, err := s.block(stream.Context(), tx, next)
+				if err != nil {
+					return err
+				}
+				steps = append(steps, block)
+				sent = append(sent, firehoseSentBlock{number: block.Number, hash: block.Hash})
+				if len(sent) > firehoseMaxUndos {
+					sent = sent[1:]
+				}
+				next++
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if err := sendFirehoseMsg(stream, step); err != nil {
+				return err
+			}
+		}
+		if more {
+			continue
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case _, ok := <-ch:
+			if !ok {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *FirehoseServer) block(ctx context.Context, tx kv.Tx, number uint64) (*FirehoseBlock, error) {
+	hash, err := s.blockReader.CanonicalHash(ctx, tx, number)
+	if err != nil {
+		return nil, err
+	}
+	block, senders, err := s.blockReader.BlockWithSenders(ctx, tx, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	res := &FirehoseBlock{Step: FirehoseStepNew, Number: number, Hash: hash, Header: block.Header(), Senders: senders}
+	txs, err := types.MarshalTransactionsBinary(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	for _, enc := range txs {
+		res.Transactions = append(res.Transactions, enc)
+	}
+	res.Receipts = rawdb.ReadReceipts(tx, block, senders)
+	for _, r := range res.Receipts {
+		if r.Logs == nil {
+			r.Logs = types.Logs{} // required in JSON
+		}
+	}
+
+	// the state after the block, to read the new values of the changes
+	after := state.NewPlainState(tx, number+1)
+	if err := changeset.ForPrefix(tx, kv.AccountChangeSet, dbutils.EncodeBlockNumber(number), func(_ uint64, k, v []byte) error {
+		var old accounts.Account
+		if len(v) > 0 {
+			if err := old.DecodeForStorage(v); err != nil {
+				return err
+			}
+		}
+		address := common.BytesToAddress(k)
+		acc, err := after.ReadAccountData(address)
+		if err != nil {
+			return err
+		}
+		newBalance := new(big.Int)
+		if acc != nil {
+			newBalance = acc.Balance.ToBig()
+		}
+		if oldBalance := old.Balance.ToBig(); oldBalance.Cmp(newBalance) != 0 {
+			res.BalanceChanges = append(res.BalanceChanges, FirehoseBalanceChange{Address: address, Old: (*hexutil.Big)(oldBalance), New: (*hexutil.Big)(newBalance)})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := changeset.ForPrefix(tx, kv.StorageChangeSet, dbutils.EncodeBlockNumber(number), func(_ uint64, k, v []byte) error {
+		change := FirehoseStorageChange{
+			Address:     common.BytesToAddress(k[:length.Addr]),
+			Incarnation: binary.BigEndian.Uint64(k[length.Addr:]),
+			Location:    common.BytesToHash(k[length.Addr+length.Incarnation:]),
+			Old:         common.CopyBytes(v),
+		}
+		newValue, err := after.ReadAccountStorage(change.Address, change.Incarnation, &change.Location)
+		if err != nil {
+			return err
+		}
+		change.New = common.CopyBytes(newValue)
+		res.StorageChanges = append(res.StorageChanges, change)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.ForPrefix(kv.CallTraceSet, dbutils.EncodeBlockNumber(number), func(_, v []byte) error {
+		res.CallAddresses = append(res.CallAddresses, FirehoseCallAddress{
+			Address: common.BytesToAddress(v[:length.Addr]),
+			From:    v[length.Addr]&1 > 0,
+			To:      v[length.Addr]&2 > 0,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// firehoseService - the handler type of the gRPC service, implemented by FirehoseServer
+type firehoseService interface {
+	Blocks(req *FirehoseRequest, stream grpc.ServerStream) error
+}
+
+var firehoseServiceDesc = grpc.ServiceDesc{
+	ServiceName: "erigon.Firehose",
+	HandlerType: (*firehoseService)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Blocks",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(FirehoseRequest)
+				if err := recvFirehoseMsg(stream, req); err != nil {
+					return err
+				}
+				return srv.(firehoseService).Blocks(req, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "firehose.go",
+}
+
+func RegisterFirehoseServer(s *grpc.Server, srv *FirehoseServer) {
+	s.RegisterService(&firehoseServiceDesc, srv)
+}
+
+// FirehoseClient - the stream of blocks from the firehose of a node, call Recv until it fails
+type FirehoseClient struct {
+	stream grpc.ClientStream
+}
+
+func NewFirehoseClient(ctx context.Context, cc grpc.ClientConnInterface, req *FirehoseRequest) (*FirehoseClient, error) {
+	stream, err := cc.NewStream(ctx, &firehoseServiceDesc.Streams[0], "/erigon.Firehose/Blocks")
+	if err != nil {
+		return nil, err
+	}
+	if err := sendFirehoseMsg(stream, req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &FirehoseClient{stream: stream}, nil
+}
+
+func (c *FirehoseClient) Recv() (*FirehoseBlock, error) {
+	block := new(FirehoseBlock)
+	if err := recvFirehoseMsg(c.stream, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// sendFirehoseMsg - sends v in JSON, wrapped in BytesValue: the messages need no codec of their own
+func sendFirehoseMsg(stream grpc.Stream, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(wrapperspb.Bytes(data))
+}
+
+func recvFirehoseMsg(stream grpc.Stream, v interface{}) error {
+	in := new(wrapperspb.BytesValue)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return json.Unmarshal(in.Value, v)
+}
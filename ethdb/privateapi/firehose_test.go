@@ -0,0 +1,95 @@
+package privateapi_test
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestFirehose(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+	)
+	m := stages.MockWithGenesis(t, gspec, key)
+	generate := func(n int, to common.Address) *core.ChainPack {
+		chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, n, func(i int, block *core.BlockGen) {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), to, uint256.NewInt(1), 21000, uint256.NewInt(1), nil), *types.LatestSignerForChainID(nil), key)
+			require.NoError(t, err)
+			block.AddTx(tx)
+		}, false /* intermediateHashes */)
+		require.NoError(t, err)
+		return chain
+	}
+	chain, fork := generate(2, common.Address{1}), generate(3, common.Address{2})
+	require.NoError(t, m.InsertChain(chain))
+
+	ctx, cancel := context.WithCancel(m.Ctx)
+	defer cancel()
+	server := grpc.NewServer()
+	privateapi.RegisterFirehoseServer(server, privateapi.NewFirehoseServer(ctx, m.DB, m.Notifications.Events, snapshotsync.NewBlockReader()))
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener) //nolint:errcheck
+	defer server.Stop()
+	conn, err := grpc.DialContext(ctx, "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client, err := privateapi.NewFirehoseClient(ctx, conn, &privateapi.FirehoseRequest{StartBlock: 1})
+	require.NoError(t, err)
+	for i, expected := range chain.Blocks {
+		block, err := client.Recv()
+		require.NoError(t, err)
+		require.Equal(t, privateapi.FirehoseStepNew, block.Step)
+		require.Equal(t, expected.Hash(), block.Hash)
+		require.Equal(t, expected.Header().Hash(), block.Header.Hash())
+		require.Equal(t, 1, len(block.Transactions))
+		require.Equal(t, []common.Address{address}, block.Senders)
+		require.Equal(t, 1, len(block.Receipts))
+		require.Equal(t, types.ReceiptStatusSuccessful, block.Receipts[0].Status)
+		// sender, recipient and miner
+		require.Equal(t, 3, len(block.BalanceChanges))
+		for _, change := range block.BalanceChanges {
+			if change.Address == (common.Address{1}) {
+				require.Equal(t, int64(i), change.Old.ToInt().Int64())
+				require.Equal(t, int64(i+1), change.New.ToInt().Int64())
+			}
+		}
+		require.NotEmpty(t, block.CallAddresses)
+	}
+
+	// the fork replaces both blocks
+	require.NoError(t, m.InsertChain(fork))
+	for i := len(chain.Blocks) - 1; i >= 0; i-- {
+		block, err := client.Recv()
+		require.NoError(t, err)
+		require.Equal(t, privateapi.FirehoseStepUndo, block.Step)
+		require.Equal(t, chain.Blocks[i].Hash(), block.Hash)
+	}
+	for _, expected := range fork.Blocks {
+		block, err := client.Recv()
+		require.NoError(t, err)
+		require.Equal(t, privateapi.FirehoseStepNew, block.Step)
+		require.Equal(t, expected.Hash(), block.Hash)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon/common"
+	types2 "github.com/ledgerwatch/erigon/core/types"
 )
 
 type LogsFilterAggregator struct {
@@ -54,8 +55,87 @@ func (a *LogsFilterAggregator) insertLogsFilter(sender remote.ETHBACKEND_Subscri
 	return filterId, filter
 }
 
-func (a *LogsFilterAggregator) checkEmpty() {
-	a.events.EmptyLogSubsctiption(a.aggLogsFilter.allAddrs == 0 && len(a.aggLogsFilter.addrs) == 0 && a.aggLogsFilter.allTopics == 0 && len(a.aggLogsFilter.topics) == 0)
+// LogsInterest is a snapshot of the aggregated log filter, for the notifier to skip the blocks and the logs none of
+// the subscribers is interested in before sending them to the aggregator. A nil interest matches all the logs.
+type LogsInterest struct {
+	allAddrs  bool
+	addrs     []common.Address
+	allTopics bool
+	topics    map[common.Hash]struct{}
+}
+
+// MayMatchBloom reports whether a block with the bloom may have logs of interest: the bloom has false positives,
+// but no false negatives.
+func (li *LogsInterest) MayMatchBloom(bloom types2.Bloom) bool {
+	if li == nil {
+		return true
+	}
+	if !li.allAddrs {
+		var found bool
+		for _, addr := range li.addrs {
+			if types2.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !li.allTopics {
+		for topic := range li.topics {
+			if types2.BloomLookup(bloom, topic) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Matches reports whether the log is of interest, with the same rules as the aggregated filter in distributeLogs.
+func (li *LogsInterest) Matches(l *types2.Log) bool {
+	if li == nil {
+		return true
+	}
+	if !li.allAddrs {
+		var found bool
+		for _, addr := range li.addrs {
+			if addr == l.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !li.allTopics {
+		for _, topic := range l.Topics {
+			if _, ok := li.topics[topic]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// updateInterest hands the snapshot of the aggregated filter to the events, nil if there is no subscriber.
+func (a *LogsFilterAggregator) updateInterest() {
+	agg := &a.aggLogsFilter
+	if agg.allAddrs == 0 && len(agg.addrs) == 0 && agg.allTopics == 0 && len(agg.topics) == 0 {
+		a.events.SetLogsInterest(nil)
+		return
+	}
+	li := &LogsInterest{allAddrs: agg.allAddrs > 0, allTopics: agg.allTopics > 0, topics: make(map[common.Hash]struct{}, len(agg.topics))}
+	for addr := range agg.addrs {
+		li.addrs = append(li.addrs, addr)
+	}
+	for topic := range agg.topics {
+		li.topics[topic] = struct{}{}
+	}
+	a.events.SetLogsInterest(li)
 }
 
 func (a *LogsFilterAggregator) removeLogsFilter(filterId uint64, filter *LogsFilter) {
@@ -63,7 +143,7 @@ func (a *LogsFilterAggregator) removeLogsFilter(filterId uint64, filter *LogsFil
 	defer a.logsFilterLock.Unlock()
 	a.subtractLogFilters(filter)
 	delete(a.logsFilters, filterId)
-	a.checkEmpty()
+	a.updateInterest()
 }
 
 func (a *LogsFilterAggregator) updateLogsFilter(filter *LogsFilter, filterReq *remote.LogsFilterRequest) {
@@ -89,7 +169,7 @@ func (a *LogsFilterAggregator) updateLogsFilter(filter *LogsFilter, filterReq *r
 		}
 	}
 	a.addLogsFilters(filter)
-	a.checkEmpty()
+	a.updateInterest()
 }
 
 func (a *LogsFilterAggregator) subtractLogFilters(f *LogsFilter) {
@@ -177,6 +257,9 @@ outerLoop:
 		a.subtractLogFilters(filter)
 		delete(a.logsFilters, filterId)
 	}
+	if len(filtersToDelete) > 0 {
+		a.updateInterest()
+	}
 
 	return nil
 }
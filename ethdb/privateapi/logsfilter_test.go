@@ -0,0 +1,59 @@
+package privateapi
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsInterest(t *testing.T) {
+	addr1, addr2 := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+	topic1, topic2 := common.HexToHash("0x11"), common.HexToHash("0x12")
+	log1 := &types.Log{Address: addr1, Topics: []common.Hash{topic1}}
+	log2 := &types.Log{Address: addr2, Topics: []common.Hash{topic2}}
+	bloom1 := types.BytesToBloom(types.LogsBloom([]*types.Log{log1}))
+	bloom2 := types.BytesToBloom(types.LogsBloom([]*types.Log{log2}))
+
+	events := NewEvents()
+	a := NewLogsFilterAggregator(events)
+	ok, interest := events.HasLogSubsriptions()
+	require.False(t, ok)
+	require.Nil(t, interest)
+	// a nil interest matches everything
+	require.True(t, interest.MayMatchBloom(bloom1))
+	require.True(t, interest.Matches(log2))
+
+	_, f := a.insertLogsFilter(nil)
+	a.updateLogsFilter(f, &remote.LogsFilterRequest{
+		Addresses: []*types2.H160{gointerfaces.ConvertAddressToH160(addr1)},
+		AllTopics: true,
+	})
+	ok, interest = events.HasLogSubsriptions()
+	require.True(t, ok)
+	require.True(t, interest.MayMatchBloom(bloom1))
+	require.False(t, interest.MayMatchBloom(bloom2))
+	require.True(t, interest.Matches(log1))
+	require.False(t, interest.Matches(log2))
+
+	_, f2 := a.insertLogsFilter(nil)
+	a.updateLogsFilter(f2, &remote.LogsFilterRequest{
+		AllAddresses: true,
+		Topics:       []*types2.H256{gointerfaces.ConvertHashToH256(topic2)},
+	})
+	_, interest = events.HasLogSubsriptions()
+	require.True(t, interest.MayMatchBloom(bloom1))
+	require.True(t, interest.MayMatchBloom(bloom2))
+	require.True(t, interest.Matches(log1))
+	require.True(t, interest.Matches(log2))
+
+	a.updateLogsFilter(f, &remote.LogsFilterRequest{})
+	a.updateLogsFilter(f2, &remote.LogsFilterRequest{})
+	ok, interest = events.HasLogSubsriptions()
+	require.False(t, ok)
+	require.Nil(t, interest)
+}
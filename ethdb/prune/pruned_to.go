@@ -0,0 +1,57 @@
+package prune
+
+import (
+	"encoding/binary"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// prunedToKey - key of the block before which the data of the kind was deleted, in kv.DatabaseInfo
+func prunedToKey(kind Kind) []byte {
+	return []byte("prunedTo." + kind.String())
+}
+
+// PrunedTo - the block before which the data of the kind was deleted: by size targets (see Mode.PruneTo), or missing
+// since the state snapshot the node started from. 0 if none was.
+func PrunedTo(tx kv.Getter, kind Kind) (uint64, error) {
+	v, err := tx.GetOne(kv.DatabaseInfo, prunedToKey(kind))
+	if err != nil {
+		return 0, err
+	}
+	if len(v) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// SetPrunedTo - records that the data of the kind is deleted before the block, see PrunedTo. The block only moves
+// forward.
+func SetPrunedTo(tx kv.RwTx, kind Kind, pruneTo uint64) error {
+	prunedTo, err := PrunedTo(tx, kind)
+	if err != nil {
+		return err
+	}
+	if pruneTo <= prunedTo {
+		return nil
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, pruneTo)
+	return tx.Put(kv.DatabaseInfo, prunedToKey(kind), v)
+}
+
+// KeptFrom - the first block of which the data of the kind is kept at head: after the distance (or before) of the
+// mode, and after the block it was pruned to, which may be further (see PrunedTo).
+func (m Mode) KeptFrom(tx kv.Getter, kind Kind, head uint64) (uint64, error) {
+	var keptFrom uint64
+	if amount := m.amount(kind); amount.Enabled() {
+		keptFrom = amount.PruneTo(head)
+	}
+	prunedTo, err := PrunedTo(tx, kind)
+	if err != nil {
+		return 0, err
+	}
+	if prunedTo > keptFrom {
+		keptFrom = prunedTo
+	}
+	return keptFrom, nil
+}
@@ -0,0 +1,240 @@
+package prune
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Kind of pruned data
+type Kind int
+
+const (
+	KindHistory Kind = iota
+	KindReceipts
+	KindTxIndex
+	KindCallTraces
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindHistory:
+		return "history"
+	case KindReceipts:
+		return "receipts"
+	case KindTxIndex:
+		return "txIndex"
+	case KindCallTraces:
+		return "callTraces"
+	default:
+		return fmt.Sprintf("unknown kind %d", int(k))
+	}
+}
+
+// tables - tables of the kind of data, and the one with block number prefixed keys (to find the first kept block)
+func (k Kind) tables() (tables []string, blockTable string) {
+	switch k {
+	case KindHistory:
+		return []string{kv.AccountChangeSet, kv.StorageChangeSet, kv.AccountsHistory, kv.StorageHistory}, kv.AccountChangeSet
+	case KindReceipts:
+		return []string{kv.Receipts, kv.Log, kv.LogTopicIndex, kv.LogAddressIndex}, kv.Receipts
+	case KindTxIndex:
+		return []string{kv.TxLookup}, ""
+	case KindCallTraces:
+		return []string{kv.CallTraceSet, kv.CallFromIndex, kv.CallToIndex}, kv.CallTraceSet
+	default:
+		panic(k.String())
+	}
+}
+
+// SizeTargets - max sizes of the kinds of data, 0 - no target. When the data grows over its target, pruning deletes
+// more blocks than its distance: as many oldest ones as needed to fit the target. Unlike distances, targets are not
+// stored in the database and may change between runs.
+type SizeTargets struct {
+	History    datasize.ByteSize
+	Receipts   datasize.ByteSize
+	TxIndex    datasize.ByteSize
+	CallTraces datasize.ByteSize
+}
+
+func (s SizeTargets) get(kind Kind) datasize.ByteSize {
+	switch kind {
+	case KindHistory:
+		return s.History
+	case KindReceipts:
+		return s.Receipts
+	case KindTxIndex:
+		return s.TxIndex
+	case KindCallTraces:
+		return s.CallTraces
+	}
+	return 0
+}
+
+func (s SizeTargets) String() string {
+	var res []string
+	for _, kind := range []Kind{KindHistory, KindReceipts, KindTxIndex, KindCallTraces} {
+		if target := s.get(kind); target > 0 {
+			res = append(res, fmt.Sprintf("--prune.%c.size=%s", kind.String()[0], target))
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+// SizeTargetsFromCli - parse --prune.{h,r,t,c}.size
+func SizeTargetsFromCli(history, receipts, txIndex, callTraces string) (SizeTargets, error) {
+	var res SizeTargets
+	for kind, it := range map[Kind]struct {
+		value  string
+		target *datasize.ByteSize
+	}{
+		KindHistory:    {history, &res.History},
+		KindReceipts:   {receipts, &res.Receipts},
+		KindTxIndex:    {txIndex, &res.TxIndex},
+		KindCallTraces: {callTraces, &res.CallTraces},
+	} {
+		if it.value == "" {
+			continue
+		}
+		if err := it.target.UnmarshalText([]byte(it.value)); err != nil {
+			return SizeTargets{}, fmt.Errorf("size target of %s: %w", kind, err)
+		}
+	}
+	return res, nil
+}
+
+// validate - kinds with a size target must be pruned by distance (or before) too
+func (s SizeTargets) validate(m Mode) error {
+	for _, kind := range []Kind{KindHistory, KindReceipts, KindTxIndex, KindCallTraces} {
+		if s.get(kind) > 0 && !m.amount(kind).Enabled() {
+			c := kind.String()[0]
+			return fmt.Errorf("--prune.%c.size requires pruning of %s: --prune=%c or --prune.%c.older", c, kind, c, c)
+		}
+	}
+	return nil
+}
+
+// sizePruning - prune points by size targets, computed once per head: all stages pruning a kind of data (e.g. log
+// index and receipts) must prune to the same block
+type sizePruning struct {
+	lock   sync.Mutex
+	points map[Kind][2]uint64 // head, pruneTo
+}
+
+func (m Mode) amount(kind Kind) BlockAmount {
+	switch kind {
+	case KindHistory:
+		return m.History
+	case KindReceipts:
+		return m.Receipts
+	case KindTxIndex:
+		return m.TxIndex
+	case KindCallTraces:
+		return m.CallTraces
+	default:
+		panic(kind.String())
+	}
+}
+
+// PruneTo - the block before which data of the kind must be deleted at head: by distance (or before), or further if
+// the data is over its size target. In dry run mode logs what would be deleted and returns 0: nothing is deleted.
+// With a kv.RwTx, the prune point is recorded, see PrunedTo: it's behind the distance of the mode once the data was
+// pruned by size.
+func (m Mode) PruneTo(tx kv.Tx, kind Kind, head uint64) (uint64, error) {
+	pruneTo, err := m.pruneTo(tx, kind, head)
+	if err != nil {
+		return 0, err
+	}
+	if rwTx, ok := tx.(kv.RwTx); ok && pruneTo > 0 {
+		if err := SetPrunedTo(rwTx, kind, pruneTo); err != nil {
+			return 0, err
+		}
+	}
+	return pruneTo, nil
+}
+
+func (m Mode) pruneTo(tx kv.Tx, kind Kind, head uint64) (uint64, error) {
+	pruneTo := m.amount(kind).PruneTo(head)
+	target := m.Sizes.get(kind)
+	if (target == 0 && !m.DryRun) || m.sizePruning == nil {
+		return pruneTo, nil
+	}
+
+	m.sizePruning.lock.Lock()
+	defer m.sizePruning.lock.Unlock()
+	if p, ok := m.sizePruning.points[kind]; ok && p[0] == head {
+		if m.DryRun {
+			return 0, nil
+		}
+		return p[1], nil
+	}
+	size, firstBlock, err := dataSize(tx, kind, pruneTo)
+	if err != nil {
+		return 0, err
+	}
+	if target > 0 && size > uint64(target) && head > firstBlock {
+		// sizes of blocks are close to each other - keep the newest blocks which fit target
+		keep := uint64(float64(head-firstBlock) * float64(target) / float64(size))
+		if head-keep > pruneTo {
+			pruneTo = head - keep
+		}
+	}
+	m.sizePruning.points[kind] = [2]uint64{head, pruneTo}
+	if !m.DryRun {
+		return pruneTo, nil
+	}
+	if pruneTo > firstBlock && head > firstBlock {
+		deleted := uint64(float64(size) * float64(pruneTo-firstBlock) / float64(head-firstBlock))
+		log.Info("[prune] dry run: would delete", "kind", kind, "blocks", fmt.Sprintf("%d-%d", firstBlock, pruneTo-1),
+			"size", common.ByteCount(deleted), "of", common.ByteCount(size))
+	} else {
+		log.Info("[prune] dry run: nothing to delete", "kind", kind, "size", common.ByteCount(size))
+	}
+	return 0, nil
+}
+
+// dataSize - size of the tables of the kind of data, and the first block they keep. Size is 0 if the database is not
+// local mdbx.
+func dataSize(tx kv.Tx, kind Kind, pruneTo uint64) (size, firstBlock uint64, err error) {
+	tables, blockTable := kind.tables()
+	mdbxTx, ok := tx.(*mdbx.MdbxTx)
+	if !ok {
+		return 0, 0, nil
+	}
+	for _, table := range tables {
+		tableSize, err := mdbxTx.BucketSize(table)
+		if err != nil {
+			return 0, 0, err
+		}
+		size += tableSize
+	}
+
+	if blockTable == "" {
+		// not ordered by blocks - pruning of the tx index moves the prune progress of its stage
+		if firstBlock, err = stages.GetStagePruneProgress(tx, stages.TxLookup); err != nil {
+			return 0, 0, err
+		}
+		return size, firstBlock, nil
+	}
+	c, err := tx.Cursor(blockTable)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer c.Close()
+	k, _, err := c.First()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(k) < 8 {
+		return size, pruneTo, nil
+	}
+	return size, binary.BigEndian.Uint64(k), nil
+}
@@ -0,0 +1,73 @@
+package prune
+
+import (
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeTargetsFromCli(t *testing.T) {
+	sizes, err := SizeTargetsFromCli("", "200GB", "", "1MB")
+	require.NoError(t, err)
+	require.Equal(t, SizeTargets{Receipts: 200 * datasize.GB, CallTraces: datasize.MB}, sizes)
+	require.Equal(t, "--prune.r.size=200GB --prune.c.size=1MB", sizes.String())
+
+	_, err = SizeTargetsFromCli("lots", "", "", "")
+	require.Error(t, err)
+
+	// target requires pruning of the kind
+	mode, err := FromCli("h", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, SizeTargets{History: datasize.GB}.validate(mode))
+	require.Error(t, SizeTargets{Receipts: datasize.GB}.validate(mode))
+}
+
+func TestEnsureNotChangedSizeTargets(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	mode, err := FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	require.NoError(t, err)
+	_, err = EnsureNotChanged(tx, mode)
+	require.NoError(t, err)
+
+	// size targets may change between runs
+	stored, err := EnsureNotChanged(tx, mode.WithSizeTargets(SizeTargets{Receipts: datasize.GB}, true))
+	require.NoError(t, err)
+	require.Equal(t, datasize.GB, stored.Sizes.Receipts)
+	require.True(t, stored.DryRun)
+
+	_, err = EnsureNotChanged(tx, mode.WithSizeTargets(SizeTargets{History: datasize.GB}, false))
+	require.Error(t, err)
+}
+
+func TestPruneToSizeTarget(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	value := make([]byte, 1024)
+	for block := uint64(0); block < 1000; block++ {
+		require.NoError(t, tx.Put(kv.Receipts, dbutils.EncodeBlockNumber(block), value))
+	}
+	mode, err := FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	require.NoError(t, err)
+	pruneTo, err := mode.PruneTo(tx, KindReceipts, 1000)
+	require.NoError(t, err)
+	require.Zero(t, pruneTo) // 90K blocks are kept by distance
+
+	sizes := SizeTargets{Receipts: 500 * datasize.KB}
+	pruneTo, err = mode.WithSizeTargets(sizes, false).PruneTo(tx, KindReceipts, 1000)
+	require.NoError(t, err)
+	require.Greater(t, pruneTo, uint64(400))
+	require.Less(t, pruneTo, uint64(700))
+
+	// the data pruned by size isn't kept, though the distance would keep it
+	keptFrom, err := mode.KeptFrom(tx, KindReceipts, 1000)
+	require.NoError(t, err)
+	require.Equal(t, pruneTo, keptFrom)
+
+	// dry run deletes nothing
+	pruneTo, err = mode.WithSizeTargets(sizes, true).PruneTo(tx, KindReceipts, 1000)
+	require.NoError(t, err)
+	require.Zero(t, pruneTo)
+}
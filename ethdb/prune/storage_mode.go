@@ -22,11 +22,28 @@ var DefaultMode = Mode{
 }
 
 type Experiments struct {
-	TEVM bool
+	TEVM     bool
+	StemTree bool // computing a stem tree of the state besides the trie, see stagedsync.stemTreeCommitment
+	Tokens   bool // indexing the transfers of ERC-20 and ERC-721 tokens, see stagedsync.SpawnTokenTransfers
 }
 
+// storageModeStemTree is the key of the stemtree experiment in kv.DatabaseInfo
+var storageModeStemTree = []byte("smStemTree")
+
+// storageModeTokens is the key of the tokens experiment in kv.DatabaseInfo
+var storageModeTokens = []byte("smTokens")
+
+// storageModeRegenerateReceipts is the key of the receipts mode in kv.DatabaseInfo
+var storageModeRegenerateReceipts = []byte("smRegenerateReceipts")
+
+// The receipts modes: whether the receipts are stored, or only their logs
+const (
+	ReceiptsPersist    = "persist"
+	ReceiptsRegenerate = "regenerate"
+)
+
 func FromCli(flags string, exactHistory, exactReceipts, exactTxIndex, exactCallTraces,
-	beforeH, beforeR, beforeT, beforeC uint64, experiments []string) (Mode, error) {
+	beforeH, beforeR, beforeT, beforeC uint64, experiments []string, receiptsMode string) (Mode, error) {
 	mode := DefaultMode
 	if flags != "default" && flags != "disabled" {
 		mode.Initialised = true
@@ -85,6 +102,12 @@ func FromCli(flags string, exactHistory, exactReceipts, exactTxIndex, exactCallT
 		case "tevm":
 			mode.Initialised = true
 			mode.Experiments.TEVM = true
+		case "stemtree":
+			mode.Initialised = true
+			mode.Experiments.StemTree = true
+		case "tokens":
+			mode.Initialised = true
+			mode.Experiments.Tokens = true
 		case "":
 			// skip
 		default:
@@ -92,6 +115,17 @@ func FromCli(flags string, exactHistory, exactReceipts, exactTxIndex, exactCallT
 		}
 	}
 
+	switch receiptsMode {
+	case "":
+	case ReceiptsPersist:
+		mode.receiptsModeSet = true
+	case ReceiptsRegenerate:
+		mode.receiptsModeSet = true
+		mode.RegenerateReceipts = true
+	default:
+		return DefaultMode, fmt.Errorf("unexpected receipts mode: %s", receiptsMode)
+	}
+
 	return mode, nil
 }
 
@@ -137,6 +171,24 @@ func Get(db kv.Getter) (Mode, error) {
 	}
 	prune.Experiments.TEVM = len(v) == 1 && v[0] == 1
 
+	v, err = db.GetOne(kv.DatabaseInfo, storageModeStemTree)
+	if err != nil {
+		return prune, err
+	}
+	prune.Experiments.StemTree = len(v) == 1 && v[0] == 1
+
+	v, err = db.GetOne(kv.DatabaseInfo, storageModeTokens)
+	if err != nil {
+		return prune, err
+	}
+	prune.Experiments.Tokens = len(v) == 1 && v[0] == 1
+
+	v, err = db.GetOne(kv.DatabaseInfo, storageModeRegenerateReceipts)
+	if err != nil {
+		return prune, err
+	}
+	prune.RegenerateReceipts = len(v) == 1 && v[0] == 1
+
 	return prune, nil
 }
 
@@ -147,6 +199,23 @@ type Mode struct {
 	TxIndex     BlockAmount
 	CallTraces  BlockAmount
 	Experiments Experiments
+	// RegenerateReceipts is set when only the logs of the receipts are stored: the receipts are regenerated by
+	// re-executing their block when requested. Unlike the rest of the mode, it may change.
+	RegenerateReceipts bool
+	// receiptsModeSet is set when --receipts.mode is given: without it, the receipts mode of the database is kept
+	receiptsModeSet bool
+	// Sizes are the size targets of the pruned data, and DryRun makes pruning only log what it would delete (see
+	// PruneTo). They are not stored in the database and may change.
+	Sizes       SizeTargets
+	DryRun      bool
+	sizePruning *sizePruning
+}
+
+// WithSizeTargets - mode pruning data also by size targets, see SizeTargets
+func (m Mode) WithSizeTargets(sizes SizeTargets, dryRun bool) Mode {
+	m.Sizes, m.DryRun = sizes, dryRun
+	m.sizePruning = &sizePruning{points: map[Kind][2]uint64{}}
+	return m
 }
 
 type BlockAmount interface {
@@ -233,6 +302,18 @@ func (m Mode) String() string {
 	if m.Experiments.TEVM {
 		long += " --experiments.tevm=enabled"
 	}
+	if m.Experiments.StemTree {
+		long += " --experiments.stemtree=enabled"
+	}
+	if m.Experiments.Tokens {
+		long += " --experiments.tokens=enabled"
+	}
+	if m.RegenerateReceipts {
+		long += " --receipts.mode=" + ReceiptsRegenerate
+	}
+	if sizes := m.Sizes.String(); sizes != "" {
+		long += " " + sizes
+	}
 
 	return strings.TrimLeft(short+long, " ")
 }
@@ -267,6 +348,21 @@ func Override(db kv.RwTx, sm Mode) error {
 		return err
 	}
 
+	err = setMode(db, storageModeStemTree, sm.Experiments.StemTree)
+	if err != nil {
+		return err
+	}
+
+	err = setMode(db, storageModeTokens, sm.Experiments.Tokens)
+	if err != nil {
+		return err
+	}
+
+	err = setMode(db, storageModeRegenerateReceipts, sm.RegenerateReceipts)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -284,11 +380,24 @@ func EnsureNotChanged(tx kv.GetPut, pruneMode Mode) (Mode, error) {
 
 	if pruneMode.Initialised {
 		// If storage mode is not explicitly specified, we take whatever is in the database
-		if !reflect.DeepEqual(pm, pruneMode) {
+		stored := pruneMode
+		stored.RegenerateReceipts, stored.receiptsModeSet = pm.RegenerateReceipts, false
+		stored.Sizes, stored.DryRun, stored.sizePruning = SizeTargets{}, false, nil
+		if !reflect.DeepEqual(pm, stored) {
 			return pm, errors.New("not allowed change of --prune flag, last time you used: " + pm.String())
 		}
 	}
-	return pm, nil
+	// The receipts mode may change: the receipts missing in the database are regenerated on request
+	if pruneMode.receiptsModeSet && pm.RegenerateReceipts != pruneMode.RegenerateReceipts {
+		if err = setMode(tx, storageModeRegenerateReceipts, pruneMode.RegenerateReceipts); err != nil {
+			return pm, err
+		}
+		pm.RegenerateReceipts = pruneMode.RegenerateReceipts
+	}
+	if err := pruneMode.Sizes.validate(pm); err != nil {
+		return pm, err
+	}
+	return pm.WithSizeTargets(pruneMode.Sizes, pruneMode.DryRun), nil
 }
 
 func setIfNotExist(db kv.GetPut, pm Mode) error {
@@ -318,6 +427,21 @@ func setIfNotExist(db kv.GetPut, pm Mode) error {
 		return err
 	}
 
+	err = setModeOnEmpty(db, storageModeStemTree, pm.Experiments.StemTree)
+	if err != nil {
+		return err
+	}
+
+	err = setModeOnEmpty(db, storageModeTokens, pm.Experiments.Tokens)
+	if err != nil {
+		return err
+	}
+
+	err = setModeOnEmpty(db, storageModeRegenerateReceipts, pm.RegenerateReceipts)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -397,7 +521,7 @@ func setOnEmpty(db kv.GetPut, key []byte, blockAmount BlockAmount) error {
 	return nil
 }
 
-func setMode(db kv.RwTx, key []byte, currentValue bool) error {
+func setMode(db kv.Putter, key []byte, currentValue bool) error {
 	val := []byte{2}
 	if currentValue {
 		val = []byte{1}
@@ -15,16 +15,16 @@ func TestSetStorageModeIfNotExist(t *testing.T) {
 	prune, err := Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(math.MaxUint64), Distance(math.MaxUint64),
-		Distance(math.MaxUint64), Distance(math.MaxUint64), Experiments{TEVM: false}}, prune)
+		Distance(math.MaxUint64), Distance(math.MaxUint64), Experiments{TEVM: false}, false, false, SizeTargets{}, false, nil}, prune)
 
 	err = setIfNotExist(tx, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{TEVM: false}})
+		Before(3), Before(4), Experiments{TEVM: false}, false, false, SizeTargets{}, false, nil})
 	assert.NoError(t, err)
 
 	prune, err = Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{TEVM: false}}, prune)
+		Before(3), Before(4), Experiments{TEVM: false}, false, false, SizeTargets{}, false, nil}, prune)
 }
 
 var distanceTests = []struct {
@@ -73,3 +73,89 @@ func TestBeforePruneTo(t *testing.T) {
 		})
 	}
 }
+
+func TestReceiptsModeMayChange(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	mode, err := FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsPersist)
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.False(t, mode.RegenerateReceipts)
+
+	regenerate, err := FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsRegenerate)
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, regenerate)
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+	assert.Equal(t, "--prune.r.older=90000 --receipts.mode=regenerate", mode.String())
+
+	// Without flags, the mode of the database is kept
+	mode, err = EnsureNotChanged(tx, Mode{})
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+
+	// The receipts mode is a flag of its own
+	mode, err = FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsPersist)
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.False(t, mode.RegenerateReceipts)
+	assert.Equal(t, "--prune.r.older=90000", mode.String())
+
+	persist, err := FromCli("r", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsPersist)
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, persist)
+	assert.NoError(t, err)
+	assert.False(t, mode.RegenerateReceipts)
+	mode, err = EnsureNotChanged(tx, regenerate)
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+
+	// The rest of the mode may not change
+	mode, err = FromCli("h", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsRegenerate)
+	assert.NoError(t, err)
+	_, err = EnsureNotChanged(tx, mode)
+	assert.Error(t, err)
+
+	_, err = FromCli("", 0, 0, 0, 0, 0, 0, 0, 0, nil, "never")
+	assert.Error(t, err)
+}
+
+func TestRestartWithoutFlags(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	mode, err := FromCli("default", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	assert.NoError(t, err)
+	_, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+
+	// Only the receipts mode changes with --receipts.mode
+	mode, err = FromCli("default", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsRegenerate)
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+	assert.Equal(t, "--receipts.mode=regenerate", mode.String())
+
+	// Without --receipts.mode, the receipts mode of the database is kept
+	mode, err = FromCli("default", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+
+	// A pruned database still needs its --prune flags
+	_, tx = memdb.NewTestTx(t)
+	mode, err = FromCli("hrtc", 0, 0, 0, 0, 0, 0, 0, 0, nil, ReceiptsRegenerate)
+	assert.NoError(t, err)
+	_, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	mode, err = FromCli("default", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	assert.NoError(t, err)
+	_, err = EnsureNotChanged(tx, mode)
+	assert.Error(t, err)
+	mode, err = FromCli("hrtc", 0, 0, 0, 0, 0, 0, 0, 0, nil, "")
+	assert.NoError(t, err)
+	mode, err = EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.True(t, mode.RegenerateReceipts)
+}
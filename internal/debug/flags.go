@@ -22,7 +22,6 @@ import (
 	_ "net/http/pprof" //nolint:gosec
 	"os"
 
-	metrics2 "github.com/VictoriaMetrics/metrics"
 	"github.com/ledgerwatch/erigon/common/fdlimit"
 	"github.com/ledgerwatch/erigon/metrics"
 	"github.com/ledgerwatch/erigon/metrics/exp"
@@ -83,6 +82,7 @@ var Flags = []cli.Flag{
 	verbosityFlag, logjsonFlag, //backtraceAtFlag, vmoduleFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	cpuprofileFlag, traceFlag,
+	otelEndpointFlag, otelInsecureFlag, otelSampleFlag,
 }
 
 //var glogger *log.GlogHandler
@@ -142,6 +142,22 @@ func SetupCobra(cmd *cobra.Command) error {
 		}
 	}
 
+	otelEndpoint, err := flags.GetString(otelEndpointFlag.Name)
+	if err != nil {
+		return err
+	}
+	otelInsecure, err := flags.GetBool(otelInsecureFlag.Name)
+	if err != nil {
+		return err
+	}
+	otelSample, err := flags.GetFloat64(otelSampleFlag.Name)
+	if err != nil {
+		return err
+	}
+	if err := setupOtel(otelEndpoint, otelInsecure, otelSample, cmd.Root().Name()); err != nil {
+		return err
+	}
+
 	go ListenSignals(nil)
 	pprof, err := flags.GetBool(pprofFlag.Name)
 	if err != nil {
@@ -214,6 +230,10 @@ func Setup(ctx *cli.Context) error {
 			return err
 		}
 	}
+	if err := setupOtel(ctx.String(otelEndpointFlag.Name), ctx.Bool(otelInsecureFlag.Name), ctx.Float64(otelSampleFlag.Name), ctx.App.Name); err != nil {
+		return err
+	}
+
 	pprofEnabled := ctx.Bool(pprofFlag.Name)
 	metricsAddr := ctx.String(metricsAddrFlag.Name)
 
@@ -240,10 +260,7 @@ func StartPProf(address string, withMetrics bool) {
 	// Hook go-metrics into expvar on any /debug/metrics request, load all vars
 	// from the registry into expvar, and execute regular expvar handler.
 	if withMetrics {
-		http.HandleFunc("/debug/metrics/prometheus", func(w http.ResponseWriter, req *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			metrics2.WritePrometheus(w, true)
-		})
+		exp.RegisterHandlers(http.DefaultServeMux)
 	}
 	cpuMsg := fmt.Sprintf("go tool pprof -lines -http=: http://%s/%s", address, "debug/pprof/profile?seconds=20")
 	heapMsg := fmt.Sprintf("go tool pprof -lines -http=: http://%s/%s", address, "debug/pprof/heap")
@@ -260,6 +277,7 @@ func StartPProf(address string, withMetrics bool) {
 func Exit() {
 	_ = Handler.StopCPUProfile()
 	_ = Handler.StopGoTrace()
+	stopOtel()
 }
 
 // RaiseFdLimit raises out the number of allowed file handles per process
@@ -0,0 +1,93 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+var (
+	otelEndpointFlag = cli.StringFlag{
+		Name:  "otel.endpoint",
+		Usage: "Export OpenTelemetry traces over OTLP/HTTP to the collector at host:port (e.g. localhost:4318). Tracing is disabled when empty",
+	}
+	otelInsecureFlag = cli.BoolFlag{
+		Name:  "otel.insecure",
+		Usage: "Export the OpenTelemetry traces over plain HTTP instead of HTTPS",
+	}
+	otelSampleFlag = cli.Float64Flag{
+		Name:  "otel.sample",
+		Usage: "Fraction of the traces to record, from 0 to 1. The traces of the requests sampled by the caller are always recorded",
+		Value: 1,
+	}
+)
+
+var (
+	otelLock     sync.Mutex
+	otelProvider *sdktrace.TracerProvider
+)
+
+// setupOtel makes the spans of the process exported to the collector at endpoint, under the name of the service. The
+// trace context of the requests is read from, and written to, the W3C traceparent header.
+func setupOtel(endpoint string, insecure bool, sample float64, service string) error {
+	otelLock.Lock()
+	defer otelLock.Unlock()
+	if endpoint == "" || otelProvider != nil {
+		return nil
+	}
+	if sample < 0 || sample > 1 {
+		return fmt.Errorf("--%s must be within 0 and 1, got %v", otelSampleFlag.Name, sample)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	// the exporter connects on the first export, an unreachable collector only fails the exports
+	exporter, err := otlptrace.New(context.Background(), otlptracehttp.NewClient(opts...))
+	if err != nil {
+		return err
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(service),
+		semconv.ServiceVersionKey.String(params.VersionWithCommit(params.GitCommit, "")),
+	)
+	otelProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sample))),
+	)
+	otel.SetTracerProvider(otelProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Debug("OpenTelemetry", "err", err)
+	}))
+	log.Info("Exporting OpenTelemetry traces", "endpoint", endpoint, "service", service, "sample", sample)
+	return nil
+}
+
+// stopOtel exports the spans ended, and stops exporting
+func stopOtel() {
+	otelLock.Lock()
+	defer otelLock.Unlock()
+	if otelProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := otelProvider.Shutdown(ctx); err != nil {
+		log.Warn("Failed to export the OpenTelemetry traces", "err", err)
+	}
+	otelProvider = nil
+}
@@ -0,0 +1,51 @@
+package ethapi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// BlockOverrides is the set of the block fields a call is executed with, instead of those of the block it is
+// executed on top of.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+	BaseFee    *hexutil.Big    `json:"baseFee"`
+}
+
+func (overrides *BlockOverrides) Override(blockCtx *vm.BlockContext) error {
+	if overrides.Number != nil {
+		if !(*big.Int)(overrides.Number).IsUint64() {
+			return fmt.Errorf("block number higher than 2^64-1")
+		}
+		blockCtx.BlockNumber = (*big.Int)(overrides.Number).Uint64()
+	}
+	if overrides.Difficulty != nil {
+		blockCtx.Difficulty = new(big.Int).Set((*big.Int)(overrides.Difficulty))
+	}
+	if overrides.Time != nil {
+		blockCtx.Time = uint64(*overrides.Time)
+	}
+	if overrides.GasLimit != nil {
+		blockCtx.GasLimit = uint64(*overrides.GasLimit)
+	}
+	if overrides.Coinbase != nil {
+		blockCtx.Coinbase = *overrides.Coinbase
+	}
+	if overrides.BaseFee != nil {
+		baseFee, overflow := uint256.FromBig((*big.Int)(overrides.BaseFee))
+		if overflow {
+			return fmt.Errorf("base fee higher than 2^256-1")
+		}
+		blockCtx.BaseFee = baseFee
+	}
+	return nil
+}
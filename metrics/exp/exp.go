@@ -3,20 +3,35 @@
 package exp
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strings"
 
 	metrics2 "github.com/VictoriaMetrics/metrics"
 	"github.com/ledgerwatch/log/v3"
 )
 
+// Components are the prefixes of the names of the metrics of each component, see cmd/prometheus/Readme.md. The metrics
+// of a component are served at /metrics/<component>.
+var Components = map[string][]string{
+	"db":         {"db_", "table_", "tx_"},
+	"sync":       {"sync", "chain_", "evm_"},
+	"rpc":        {"rpc_"},
+	"cache":      {"cache_", "state_cache_"},
+	"txpool":     {"pool_", "txpool_"},
+	"downloader": {"downloader_"},
+	"p2p":        {"p2p_"},
+	"process":    {"go_", "process_"},
+}
+
 // Setup starts a dedicated metrics server at the given address.
 // This function enables metrics reporting separate from pprof.
 func Setup(address string) {
-	http.HandleFunc("/debug/metrics/prometheus", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		metrics2.WritePrometheus(w, true)
-	})
+	RegisterHandlers(http.DefaultServeMux)
 	//m.Handle("/debug/metrics", ExpHandler(metrics.DefaultRegistry))
 	//m.Handle("/debug/metrics/prometheus2", promhttp.HandlerFor(prometheus2.DefaultGatherer, promhttp.HandlerOpts{
 	//	EnableOpenMetrics: true,
@@ -28,3 +43,51 @@ func Setup(address string) {
 		}
 	}()
 }
+
+// RegisterHandlers serves all the metrics of the process at /debug/metrics/prometheus and /metrics, and the metrics of
+// each of the components at /metrics/<component>.
+func RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/metrics/prometheus", handleMetrics)
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/metrics/", handleComponentMetrics)
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	metrics2.WritePrometheus(w, true)
+}
+
+func handleComponentMetrics(w http.ResponseWriter, r *http.Request) {
+	component := strings.TrimPrefix(r.URL.Path, "/metrics/")
+	prefixes, ok := Components[component]
+	if !ok {
+		names := make([]string, 0, len(Components))
+		for name := range Components {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		http.Error(w, fmt.Sprintf("unknown component %q, the components are: %s", component, strings.Join(names, ", ")), http.StatusNotFound)
+		return
+	}
+	var buf bytes.Buffer
+	metrics2.WritePrometheus(&buf, true)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeComponent(w, &buf, prefixes)
+}
+
+// writeComponent copies the lines of the metrics in the Prometheus text format whose names have one of the prefixes
+func writeComponent(w io.Writer, metrics io.Reader, prefixes []string) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	scanner := bufio.NewScanner(metrics)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				fmt.Fprintln(bw, line)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package exp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metrics2 "github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentMetrics(t *testing.T) {
+	metrics2.GetOrCreateCounter(`downloader_exp_test{state="a"}`).Set(1)
+	metrics2.GetOrCreateCounter(`rpc_exp_test`).Set(2)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/metrics")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "downloader_exp_test{state=\"a\"} 1\n")
+	require.Contains(t, body, "rpc_exp_test 2\n")
+	require.Contains(t, body, "go_goroutines")
+
+	code, body = get("/metrics/downloader")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "downloader_exp_test{state=\"a\"} 1\n")
+	require.NotContains(t, body, "rpc_exp_test")
+	require.NotContains(t, body, "go_goroutines")
+
+	code, body = get("/metrics/process")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "go_goroutines")
+	require.NotContains(t, body, "downloader_exp_test")
+
+	code, body = get("/metrics/unknown")
+	require.Equal(t, http.StatusNotFound, code)
+	require.Contains(t, body, "cache, db, downloader, p2p, process, rpc, sync, txpool")
+}
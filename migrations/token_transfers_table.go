@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+)
+
+// tokenTransfersTable - the token transfers were indexed in kv.CodeSettings, a table of the erigon22 domains which
+// chaindata doesn't use: it's cleared, and the TokenTransfers stage indexes them again besides the issuance.
+var tokenTransfersTable = Migration{
+	Name: "token_transfers_table",
+	Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+		tx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := tx.ClearBucket(kv.CodeSettings); err != nil {
+			return err
+		}
+		if err := stages.SaveStageProgress(tx, stages.TokenTransfers, 0); err != nil {
+			return err
+		}
+		if err := stages.SaveStagePruneProgress(tx, stages.TokenTransfers, 0); err != nil {
+			return err
+		}
+
+		if err := BeforeCommit(tx, nil, true); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
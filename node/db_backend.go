@@ -0,0 +1,131 @@
+package node
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/ethdb/boltdb"
+	"github.com/ledgerwatch/erigon/node/nodecfg"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// DefaultDBBackend - name of the database engine used when --db.backend is not set
+const DefaultDBBackend = "mdbx"
+
+// DBOpenMode - how the database is open by DBOpener
+type DBOpenMode int
+
+const (
+	DBOpenDefault   DBOpenMode = iota
+	DBOpenExclusive            // to apply migrations
+	DBOpenReadonly             // by the tools reading the database of a running node, like rpcdaemon
+)
+
+// DBOpener opens the database of given label at path.
+// Everything above kv.RwDB (stages, history, state readers) doesn't know the engine, so a backend only needs
+// to implement erigon-lib kv interfaces: all tables of kv.ChaindataTables with their dupsort flags.
+type DBOpener func(config *nodecfg.Config, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error)
+
+var (
+	dbBackendsLock sync.RWMutex
+	dbBackends     = map[string]DBOpener{DefaultDBBackend: openMdbx, "bolt": openBolt}
+
+	// dbFiles - the data file of each engine in the directory of a database, not to open the database of an engine
+	// with another one
+	dbFiles = map[string]string{DefaultDBBackend: "mdbx.dat", "bolt": boltdb.FileName}
+)
+
+// RegisterDBBackend - makes a database engine available for --db.backend. Engines with
+// heavy dependencies (cgo) register themselves from init() of files under build tag.
+func RegisterDBBackend(name string, open DBOpener) {
+	dbBackendsLock.Lock()
+	defer dbBackendsLock.Unlock()
+	if _, ok := dbBackends[name]; ok {
+		panic("db backend registered twice: " + name)
+	}
+	dbBackends[name] = open
+}
+
+// DBBackends - names of registered database engines
+func DBBackends() []string {
+	dbBackendsLock.RLock()
+	defer dbBackendsLock.RUnlock()
+	names := make([]string, 0, len(dbBackends))
+	for name := range dbBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func dbBackend(name string) (DBOpener, error) {
+	if name == "" {
+		name = DefaultDBBackend
+	}
+	dbBackendsLock.RLock()
+	open, ok := dbBackends[name]
+	dbBackendsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown db backend %q, available: %v (other backends need build tags)", name, DBBackends())
+	}
+	return open, nil
+}
+
+// openDB opens the database at path with the engine of config.DBBackend
+func openDB(config *nodecfg.Config, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error) {
+	name := config.DBBackend
+	if name == "" {
+		name = DefaultDBBackend
+	}
+	open, err := dbBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	for other, file := range dbFiles {
+		if other == name {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, file)); err == nil {
+			return nil, fmt.Errorf("the database at %s was created by the %q db backend, not %q: set --db.backend=%s", path, other, name, other)
+		}
+	}
+	return open(config, logger, label, path, mode)
+}
+
+// OpenDBBackend opens the database at path with the given engine, for the tools opening the databases of a node
+// without its config (rpcdaemon, integration, db compact)
+func OpenDBBackend(name string, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error) {
+	config := nodecfg.DefaultConfig
+	config.DBBackend = name
+	config.MdbxPageSize = 4 * datasize.KB // of the new databases only, like the default of --db.pagesize
+	return openDB(&config, logger, label, path, mode)
+}
+
+func openMdbx(config *nodecfg.Config, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error) {
+	opts := mdbx.NewMDBX(logger).Path(path).Label(label).DBVerbosity(config.DatabaseVerbosity)
+	switch mode {
+	case DBOpenExclusive:
+		opts = opts.Exclusive()
+	case DBOpenReadonly:
+		opts = opts.Readonly()
+	}
+	if label == kv.ChainDB && mode != DBOpenReadonly {
+		opts = opts.PageSize(config.MdbxPageSize.Bytes()).MapSize(8 * datasize.TB)
+	}
+	return opts.Open()
+}
+
+// openBolt - bolt allows a single process to write to the database, so it's always exclusive
+func openBolt(config *nodecfg.Config, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error) {
+	opts := boltdb.NewBolt(logger).Path(path).Label(label)
+	if mode == DBOpenReadonly {
+		opts = opts.Readonly()
+	}
+	return opts.Open()
+}
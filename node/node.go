@@ -27,13 +27,11 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
 	"github.com/ledgerwatch/erigon/params"
 
 	"github.com/gofrs/flock"
 	"github.com/ledgerwatch/erigon-lib/kv"
-	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/erigon/p2p"
@@ -311,19 +309,15 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 
 	dbPath := filepath.Join(config.Dirs.DataDir, name)
 	var openFunc func(exclusive bool) (kv.RwDB, error)
-	log.Info("Opening Database", "label", name, "path", dbPath)
+	log.Info("Opening Database", "label", name, "path", dbPath, "backend", config.DBBackend)
 	openFunc = func(exclusive bool) (kv.RwDB, error) {
-		opts := mdbx.NewMDBX(logger).Path(dbPath).Label(label).DBVerbosity(config.DatabaseVerbosity)
+		mode := DBOpenDefault
 		if exclusive {
-			opts = opts.Exclusive()
+			mode = DBOpenExclusive
 		}
-		if label == kv.ChainDB {
-			opts = opts.PageSize(config.MdbxPageSize.Bytes()).MapSize(8 * datasize.TB)
-		}
-		return opts.Open()
+		return openDB(config, logger, label, dbPath, mode)
 	}
-	var err error
-	db, err = openFunc(false)
+	db, err := openFunc(false)
 	if err != nil {
 		return nil, err
 	}
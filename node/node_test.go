@@ -19,6 +19,7 @@ package node
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -180,6 +181,60 @@ func TestNodeCloseClosesDB(t *testing.T) {
 	//}
 }
 
+// This test checks that OpenDatabase uses the db backend of config.
+func TestOpenDatabaseBackend(t *testing.T) {
+	config := testNodeConfig(t)
+	config.Dirs = datadir.New(t.TempDir())
+
+	config.DBBackend = "unknown"
+	_, err := OpenDatabase(config, log.New(), kv.SentryDB)
+	require.Error(t, err)
+
+	var opened kv.Label
+	RegisterDBBackend("test_backend", func(config *nodecfg.Config, logger log.Logger, label kv.Label, path string, mode DBOpenMode) (kv.RwDB, error) {
+		opened = label
+		return openMdbx(config, logger, label, path, mode)
+	})
+	t.Cleanup(func() {
+		dbBackendsLock.Lock()
+		defer dbBackendsLock.Unlock()
+		delete(dbBackends, "test_backend")
+	})
+	require.Contains(t, DBBackends(), "test_backend")
+	config.DBBackend = "test_backend"
+	db, err := OpenDatabase(config, log.New(), kv.SentryDB)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, kv.SentryDB, opened)
+}
+
+// This test checks that the bolt db backend stores the chaindata, and that it isn't open by another backend.
+func TestOpenDatabaseBolt(t *testing.T) {
+	config := testNodeConfig(t)
+	config.Dirs = datadir.New(t.TempDir())
+	config.DBBackend = "bolt"
+
+	db, err := OpenDatabase(config, log.New(), kv.ChainDB)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.PlainState, []byte("testK"), []byte("testV"))
+	}))
+	db.Close()
+
+	config.DBBackend = DefaultDBBackend
+	_, err = OpenDatabase(config, log.New(), kv.ChainDB)
+	require.Error(t, err)
+
+	db, err = OpenDBBackend("bolt", log.New(), kv.ChainDB, filepath.Join(config.Dirs.DataDir, "chaindata"), DBOpenReadonly)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.PlainState, []byte("testK"))
+		require.Equal(t, []byte("testV"), v)
+		return err
+	}))
+}
+
 // This test checks that OpenDatabase can be used from within a Lifecycle Start method.
 func TestNodeOpenDatabaseFromLifecycleStart(t *testing.T) {
 	if runtime.GOOS == "windows" {
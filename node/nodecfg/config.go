@@ -162,6 +162,9 @@ type Config struct {
 
 	MdbxPageSize datasize.ByteSize
 
+	// DBBackend is the name of the database engine, see node.RegisterDBBackend
+	DBBackend string
+
 	// HealthCheck enables standard grpc health check
 	HealthCheck bool
 
@@ -17,6 +17,8 @@
 package nodecfg
 
 import (
+	"time"
+
 	"github.com/ledgerwatch/erigon/common/paths"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/p2p"
@@ -44,10 +46,12 @@ var DefaultConfig = Config{
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
 	P2P: p2p.Config{
-		ListenAddr:      ":30303",
-		ListenAddr65:    ":30304",
-		MaxPeers:        100,
-		MaxPendingPeers: 1000,
-		NAT:             nat.Any(),
+		ListenAddr:       ":30303",
+		ListenAddr65:     ":30304",
+		MaxPeers:         100,
+		MaxPendingPeers:  1000,
+		PeerBanThreshold: -100,
+		PeerBanTTL:       time.Hour,
+		NAT:              nat.Any(),
 	},
 }
@@ -125,6 +125,11 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// PeerBanThreshold is the score of a peer at which the sentry bans it for PeerBanTTL.
+	// The defaults of the sentry are used if they're zero.
+	PeerBanThreshold int           `toml:",omitempty"`
+	PeerBanTTL       time.Duration `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -181,6 +181,14 @@ var SokolBootnodes = []string{
 
 var FermionBootnodes = []string{}
 
+var GnosisBootnodes = []string{
+	"enode://fb14d72321ee823fcf21e163091849ee42e0f6ac0cddc737d79e324b0a734c4fc51823ef0a96b749c954483c25e8d2e534d1d5fc2619ea22d58671aff96f5188@65.109.103.148:30303",
+	"enode://40f40acd78004650cce57aa302de9acbf54becf91b609da93596a18979bb203ba79fcbee5c2e637407b91be23ce72f0cc13dfa38d13e657005ce842eafb6b172@65.109.103.149:30303",
+	"enode://9e50857aa48a7a31bc7b46957e8ced0ef69a7165d3199bea924cb6d02b81f1f35bd8e29d21a54f4a331316bf09bb92716772ea76d3ef75ce027699eccfa14fad@141.94.97.22:30303",
+	"enode://96dc133ce3aeb5d9430f1dce1d77a36418c8789b443ae0445f06f73c6b363f5b35c019086700a098c3e6e54974d64f37e97d72a5c711d1eae34dc06e3e00eed5@141.94.97.74:30303",
+	"enode://516cbfbe9bbf26b6395ed68b24e383401fc33e7fe96b9d235ebca86c9f812fde8d33a7dbebc0fb5595459d2c5cc6381595d96507af89e6b48b5bdd0ebf8af0c0@141.94.97.84:30303",
+}
+
 var V5Bootnodes = []string{
 	// Teku team's bootnode
 	"enr:-KG4QOtcP9X1FbIMOe17QNMKqDxCpm14jcX5tiOE4_TyMrFqbmhPZHK_ZPG2Gxb1GE2xdtodOfx9-cgvNtxnRyHEmC0ghGV0aDKQ9aX9QgAAAAD__________4JpZIJ2NIJpcIQDE8KdiXNlY3AyNTZrMaEDhpehBDbZjM_L9ek699Y7vhUJ-eAdMyQW_Fil522Y0fODdGNwgiMog3VkcIIjKA",
@@ -253,6 +261,8 @@ func BootnodeURLsOfChain(chain string) []string {
 		return RialtoBootnodes
 	case networkname.SokolChainName:
 		return SokolBootnodes
+	case networkname.GnosisChainName:
+		return GnosisBootnodes
 	case networkname.FermionChainName:
 		return FermionBootnodes
 	case networkname.MumbaiChainName:
@@ -260,6 +270,9 @@ func BootnodeURLsOfChain(chain string) []string {
 	case networkname.BorMainnetChainName:
 		return BorMainnetBootnodes
 	default:
+		if chain := customChain(chain); chain != nil {
+			return chain.Bootnodes
+		}
 		return []string{}
 	}
 }
@@ -51,11 +51,12 @@ func readChainSpec(filename string) *ChainConfig {
 type ConsensusType string
 
 const (
-	AuRaConsensus   ConsensusType = "aura"
-	EtHashConsensus ConsensusType = "ethash"
-	CliqueConsensus ConsensusType = "clique"
-	ParliaConsensus ConsensusType = "parlia"
-	BorConsensus    ConsensusType = "bor"
+	AuRaConsensus    ConsensusType = "aura"
+	EtHashConsensus  ConsensusType = "ethash"
+	CliqueConsensus  ConsensusType = "clique"
+	ParliaConsensus  ConsensusType = "parlia"
+	BorConsensus     ConsensusType = "bor"
+	NoProofConsensus ConsensusType = "noproof" // accepts any seal and seals blocks instantly, for devnets only
 )
 
 // Genesis hashes to enforce below configs on.
@@ -75,6 +76,7 @@ var (
 	MumbaiGenesisHash     = common.HexToHash("0x7b66506a9ebdbf30d32b43c5f15a3b1216269a1ec3a75aa3182b86176a2b1ca7")
 	BorMainnetGenesisHash = common.HexToHash("0xa9c28ce2141b56c474f1dc504bee9b01eb1bd7d1a507580d5519d4437a97de1b")
 	BorDevnetGenesisHash  = common.HexToHash("0x5a06b25b0c6530708ea0b98a3409290e39dce6be7f558493aeb6e4b99a172a87")
+	GnosisGenesisHash     = common.HexToHash("0x4f1dd23188aab3a76b463e4af801b52b1248ef073c648cbdc4c9333d3da79756")
 )
 
 var (
@@ -84,6 +86,7 @@ var (
 var (
 	SokolGenesisStateRoot   = common.HexToHash("0xfad4af258fd11939fae0c6c6eec9d340b1caac0b0196fd9a1bc3f489c5bf00b3")
 	FermionGenesisStateRoot = common.HexToHash("0x08982dc16236c51b6d9aff8b76cd0faa7067eb55eba62395d5a82649d8fb73c4")
+	GnosisGenesisStateRoot  = common.HexToHash("0x40cf4430ecaa733787d1a65154a3b9efb560c95d9e324a23b97f0609b539133b")
 )
 
 var (
@@ -117,6 +120,9 @@ var (
 
 	FermionChainConfig = readChainSpec("chainspecs/fermion.json")
 
+	// GnosisChainConfig contains the chain parameters to run a node on Gnosis Chain (formerly xDai).
+	GnosisChainConfig = readChainSpec("chainspecs/gnosis.json")
+
 	// AllEthashProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Ethash consensus.
 	AllEthashProtocolChanges = &ChainConfig{
@@ -247,6 +253,13 @@ type ChainConfig struct {
 	ArrowGlacierBlock   *big.Int `json:"arrowGlacierBlock,omitempty"`   // EIP-4345 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	GrayGlacierBlock    *big.Int `json:"grayGlacierBlock,omitempty"`    // EIP-5133 (bomb delay) switch block (nil = no fork, 0 = already activated)
 
+	// EVM Object Format: EIP-3540, EIP-3670, EIP-4200 and EIP-4750, for the EOF testnets
+	EOFBlock *big.Int `json:"eofBlock,omitempty"` // EOF switch block (nil = no fork, 0 = already activated)
+
+	// Gnosis Chain sends the EIP-1559 base fee to a fee collector instead of burning it
+	Eip1559FeeCollector           *common.Address `json:"eip1559FeeCollector,omitempty"`           // (Optional) Address where burnt EIP-1559 fees go to
+	Eip1559FeeCollectorTransition *big.Int        `json:"eip1559FeeCollectorTransition,omitempty"` // (Optional) Block from which burnt EIP-1559 fees go to the Eip1559FeeCollector
+
 	// Parlia fork blocks
 	RamanujanBlock  *big.Int `json:"ramanujanBlock,omitempty" toml:",omitempty"`  // ramanujanBlock switch block (nil = no fork, 0 = already activated)
 	NielsBlock      *big.Int `json:"nielsBlock,omitempty" toml:",omitempty"`      // nielsBlock switch block (nil = no fork, 0 = already activated)
@@ -554,6 +567,11 @@ func (c *ChainConfig) IsLondon(num uint64) bool {
 	return isForked(c.LondonBlock, num)
 }
 
+// IsEip1559FeeCollector returns whether the EIP-1559 base fee of block num goes to the Eip1559FeeCollector.
+func (c *ChainConfig) IsEip1559FeeCollector(num uint64) bool {
+	return c.Eip1559FeeCollector != nil && isForked(c.Eip1559FeeCollectorTransition, num)
+}
+
 // IsArrowGlacier returns whether num is either equal to the Arrow Glacier (EIP-4345) fork block or greater.
 func (c *ChainConfig) IsArrowGlacier(num uint64) bool {
 	return isForked(c.ArrowGlacierBlock, num)
@@ -564,6 +582,11 @@ func (c *ChainConfig) IsGrayGlacier(num uint64) bool {
 	return isForked(c.GrayGlacierBlock, num)
 }
 
+// IsEOF returns whether num is either equal to the EOF fork block or greater.
+func (c *ChainConfig) IsEOF(num uint64) bool {
+	return isForked(c.EOFBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -584,6 +607,70 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
 // to guarantee that forks can be implemented in a different order than on official networks
+// OverridableForks are the names of the forks whose blocks can be overridden, as in the --override.<fork>Block flags
+var OverridableForks = []string{
+	"homestead", "daoFork", "tangerineWhistle", "spuriousDragon", "byzantium", "constantinople", "petersburg",
+	"istanbul", "muirGlacier", "berlin", "london", "arrowGlacier", "grayGlacier", "eof", "mergeNetsplit",
+	"ramanujan", "niels", "mirrorSync", "bruno", "euler",
+}
+
+func (c *ChainConfig) forkBlock(fork string) **big.Int {
+	switch fork {
+	case "homestead":
+		return &c.HomesteadBlock
+	case "daoFork":
+		return &c.DAOForkBlock
+	case "tangerineWhistle":
+		return &c.TangerineWhistleBlock
+	case "spuriousDragon":
+		return &c.SpuriousDragonBlock
+	case "byzantium":
+		return &c.ByzantiumBlock
+	case "constantinople":
+		return &c.ConstantinopleBlock
+	case "petersburg":
+		return &c.PetersburgBlock
+	case "istanbul":
+		return &c.IstanbulBlock
+	case "muirGlacier":
+		return &c.MuirGlacierBlock
+	case "berlin":
+		return &c.BerlinBlock
+	case "london":
+		return &c.LondonBlock
+	case "arrowGlacier":
+		return &c.ArrowGlacierBlock
+	case "grayGlacier":
+		return &c.GrayGlacierBlock
+	case "eof":
+		return &c.EOFBlock
+	case "mergeNetsplit":
+		return &c.MergeNetsplitBlock
+	case "ramanujan":
+		return &c.RamanujanBlock
+	case "niels":
+		return &c.NielsBlock
+	case "mirrorSync":
+		return &c.MirrorSyncBlock
+	case "bruno":
+		return &c.BrunoBlock
+	case "euler":
+		return &c.EulerBlock
+	default:
+		return nil
+	}
+}
+
+// OverrideForkBlock sets the block of the fork, one of OverridableForks
+func (c *ChainConfig) OverrideForkBlock(fork string, block *big.Int) error {
+	b := c.forkBlock(fork)
+	if b == nil {
+		return fmt.Errorf("unknown fork %q", fork)
+	}
+	*b = new(big.Int).Set(block)
+	return nil
+}
+
 func (c *ChainConfig) CheckConfigForkOrder() error {
 	if c != nil && c.ChainID != nil && c.ChainID.Uint64() == 77 {
 		return nil
@@ -609,6 +696,7 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "londonBlock", block: c.LondonBlock},
 		{name: "arrowGlacierBlock", block: c.ArrowGlacierBlock, optional: true},
 		{name: "grayGlacierBlock", block: c.GrayGlacierBlock, optional: true},
+		{name: "eofBlock", block: c.EOFBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -682,6 +770,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head uint64) *ConfigC
 	if isForkIncompatible(c.GrayGlacierBlock, newcfg.GrayGlacierBlock, head) {
 		return newCompatError("Gray Glacier fork block", c.GrayGlacierBlock, newcfg.GrayGlacierBlock)
 	}
+	if isForkIncompatible(c.EOFBlock, newcfg.EOFBlock, head) {
+		return newCompatError("EOF fork block", c.EOFBlock, newcfg.EOFBlock)
+	}
 	if isForkIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, head) {
 		return newCompatError("Merge netsplit block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
@@ -769,7 +860,7 @@ type Rules struct {
 	ChainID                                                 *big.Int
 	IsHomestead, IsTangerineWhistle, IsSpuriousDragon       bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
-	IsBerlin, IsLondon                                      bool
+	IsBerlin, IsLondon, IsEOF                               bool
 	IsParlia, IsStarknet                                    bool
 }
 
@@ -790,6 +881,7 @@ func (c *ChainConfig) Rules(num uint64) *Rules {
 		IsIstanbul:         c.IsIstanbul(num),
 		IsBerlin:           c.IsBerlin(num),
 		IsLondon:           c.IsLondon(num),
+		IsEOF:              c.IsEOF(num),
 		IsParlia:           c.Parlia != nil,
 	}
 }
@@ -812,6 +904,8 @@ func ChainConfigByChainName(chain string) *ChainConfig {
 		return KilnDevnetChainConfig
 	case networkname.SokolChainName:
 		return SokolChainConfig
+	case networkname.GnosisChainName:
+		return GnosisChainConfig
 	case networkname.FermionChainName:
 		return FermionChainConfig
 	case networkname.BSCChainName:
@@ -827,6 +921,9 @@ func ChainConfigByChainName(chain string) *ChainConfig {
 	case networkname.BorDevnetChainName:
 		return BorDevnetChainConfig
 	default:
+		if chain := customChain(chain); chain != nil {
+			return chain.Config
+		}
 		return nil
 	}
 }
@@ -849,6 +946,8 @@ func GenesisHashByChainName(chain string) *common.Hash {
 		return &KilnDevnetGensisHash
 	case networkname.SokolChainName:
 		return &SokolGenesisHash
+	case networkname.GnosisChainName:
+		return &GnosisGenesisHash
 	case networkname.FermionChainName:
 		return &FermionGenesisHash
 	case networkname.BSCChainName:
@@ -864,6 +963,9 @@ func GenesisHashByChainName(chain string) *common.Hash {
 	case networkname.BorDevnetChainName:
 		return &BorDevnetGenesisHash
 	default:
+		if chain := customChain(chain); chain != nil {
+			return &chain.GenesisHash
+		}
 		return nil
 	}
 }
@@ -886,6 +988,8 @@ func ChainConfigByGenesisHash(genesisHash common.Hash) *ChainConfig {
 		return KilnDevnetChainConfig
 	case genesisHash == SokolGenesisHash:
 		return SokolChainConfig
+	case genesisHash == GnosisGenesisHash:
+		return GnosisChainConfig
 	case genesisHash == FermionGenesisHash:
 		return FermionChainConfig
 	case genesisHash == BSCGenesisHash:
@@ -899,6 +1003,9 @@ func ChainConfigByGenesisHash(genesisHash common.Hash) *ChainConfig {
 	case genesisHash == BorMainnetGenesisHash:
 		return BorMainnetChainConfig
 	default:
+		if chain := customChainByGenesisHash(genesisHash); chain != nil {
+			return chain.Config
+		}
 		return nil
 	}
 }
@@ -910,6 +1017,9 @@ func NetworkIDByChainName(chain string) uint64 {
 	case networkname.DevChainName:
 		return 1337
 	default:
+		if chain := customChain(chain); chain != nil && chain.NetworkID != 0 {
+			return chain.NetworkID
+		}
 		config := ChainConfigByChainName(chain)
 		if config == nil {
 			return 0
@@ -0,0 +1,62 @@
+package params
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// CustomChain is a network which isn't built into Erigon, but registered at startup (see --chain.config).
+type CustomChain struct {
+	Name        string
+	Config      *ChainConfig
+	GenesisHash common.Hash
+	NetworkID   uint64
+	Bootnodes   []string
+}
+
+var (
+	customChainsLock sync.RWMutex
+	customChains     = map[string]*CustomChain{}
+)
+
+// RegisterChain makes the network known by its name to the functions which look up chains by name
+// or genesis hash. Built-in networks can't be redefined.
+func RegisterChain(chain *CustomChain) error {
+	if chain.Name == "" {
+		return fmt.Errorf("custom chain has no name")
+	}
+	if chain.Config == nil || chain.Config.ChainID == nil {
+		return fmt.Errorf("custom chain %s has no chain id", chain.Name)
+	}
+	if GenesisHashByChainName(chain.Name) != nil || ChainConfigByGenesisHash(chain.GenesisHash) != nil {
+		return fmt.Errorf("chain %s is already known", chain.Name)
+	}
+	customChainsLock.Lock()
+	defer customChainsLock.Unlock()
+	customChains[chain.Name] = chain
+	return nil
+}
+
+// IsCustomChain tells whether the network was registered by RegisterChain.
+func IsCustomChain(name string) bool {
+	return customChain(name) != nil
+}
+
+func customChain(name string) *CustomChain {
+	customChainsLock.RLock()
+	defer customChainsLock.RUnlock()
+	return customChains[name]
+}
+
+func customChainByGenesisHash(genesisHash common.Hash) *CustomChain {
+	customChainsLock.RLock()
+	defer customChainsLock.RUnlock()
+	for _, chain := range customChains {
+		if chain.GenesisHash == genesisHash {
+			return chain
+		}
+	}
+	return nil
+}
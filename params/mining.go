@@ -21,4 +21,6 @@ type MiningConfig struct {
 	GasLimit   uint64            // Target gas limit for mined blocks.
 	GasPrice   *big.Int          // Minimum gas price for mining a transaction
 	Recommit   time.Duration     // The time interval for miner to re-create mining work.
+	Dev        bool              // Developer chain: the evm_* rpc controls the mining and the time of the blocks
+	DevManual  bool              // Developer chain: mine only the blocks requested by evm_mine
 }
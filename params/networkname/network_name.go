@@ -10,6 +10,7 @@ const (
 	KilnDevnetChainName = "kiln-devnet"
 	DevChainName        = "dev"
 	SokolChainName      = "sokol"
+	GnosisChainName     = "gnosis"
 	FermionChainName    = "fermion"
 	BSCChainName        = "bsc"
 	ChapelChainName     = "chapel"
@@ -29,6 +30,7 @@ var All = []string{
 	KilnDevnetChainName,
 	//DevChainName,
 	SokolChainName,
+	GnosisChainName,
 	FermionChainName,
 	BSCChainName,
 	ChapelChainName,
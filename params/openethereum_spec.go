@@ -0,0 +1,277 @@
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/math"
+)
+
+// OpenEthereumSpec is a chain specification in the JSON format of OpenEthereum (formerly Parity).
+// Only the fields which have a counterpart in Erigon are parsed: engine params, fork transitions,
+// genesis header and accounts. Gas limit bound divisor, minimum gas limit and similar protocol
+// constants can't be customised and are ignored.
+type OpenEthereumSpec struct {
+	Name     string                         `json:"name"`
+	DataDir  string                         `json:"dataDir"`
+	Engine   OpenEthereumEngine             `json:"engine"`
+	Params   OpenEthereumParams             `json:"params"`
+	Genesis  OpenEthereumGenesis            `json:"genesis"`
+	Nodes    []string                       `json:"nodes"`
+	Accounts map[string]OpenEthereumAccount `json:"accounts"`
+}
+
+// OpenEthereumEngine describes the consensus engine of the chain, exactly one of the fields is set.
+type OpenEthereumEngine struct {
+	AuthorityRound *struct {
+		Params json.RawMessage `json:"params"` // parsed by the AuRa engine itself
+	} `json:"authorityRound"`
+	Clique *struct {
+		Params struct {
+			Period uint64 `json:"period"`
+			Epoch  uint64 `json:"epoch"`
+		} `json:"params"`
+	} `json:"clique"`
+	Ethash *struct {
+		Params struct {
+			HomesteadTransition *OpenEthereumNumber `json:"homesteadTransition"`
+		} `json:"params"`
+	} `json:"Ethash"`
+	InstantSeal *json.RawMessage `json:"instantSeal"`
+	Null        *json.RawMessage `json:"null"`
+}
+
+// OpenEthereumParams are the common params of the chain. A missing EIP transition means that
+// the EIP is never activated, apart from the ones which OpenEthereum enables from genesis by default.
+type OpenEthereumParams struct {
+	NetworkID *OpenEthereumNumber `json:"networkID"`
+	ChainID   *OpenEthereumNumber `json:"chainID"`
+
+	EIP150Transition    *OpenEthereumNumber `json:"eip150Transition"`
+	EIP155Transition    *OpenEthereumNumber `json:"eip155Transition"`
+	EIP161abcTransition *OpenEthereumNumber `json:"eip161abcTransition"`
+
+	EIP140Transition *OpenEthereumNumber `json:"eip140Transition"`
+	EIP211Transition *OpenEthereumNumber `json:"eip211Transition"`
+	EIP214Transition *OpenEthereumNumber `json:"eip214Transition"`
+	EIP658Transition *OpenEthereumNumber `json:"eip658Transition"`
+
+	EIP145Transition         *OpenEthereumNumber `json:"eip145Transition"`
+	EIP1014Transition        *OpenEthereumNumber `json:"eip1014Transition"`
+	EIP1052Transition        *OpenEthereumNumber `json:"eip1052Transition"`
+	EIP1283Transition        *OpenEthereumNumber `json:"eip1283Transition"`
+	EIP1283DisableTransition *OpenEthereumNumber `json:"eip1283DisableTransition"`
+
+	EIP1344Transition *OpenEthereumNumber `json:"eip1344Transition"`
+	EIP1884Transition *OpenEthereumNumber `json:"eip1884Transition"`
+	EIP2028Transition *OpenEthereumNumber `json:"eip2028Transition"`
+	EIP2200Transition *OpenEthereumNumber `json:"eip1283ReenableTransition"`
+
+	EIP2929Transition *OpenEthereumNumber `json:"eip2929Transition"`
+	EIP2930Transition *OpenEthereumNumber `json:"eip2930Transition"`
+
+	EIP1559Transition *OpenEthereumNumber `json:"eip1559Transition"`
+	EIP3198Transition *OpenEthereumNumber `json:"eip3198Transition"`
+	EIP3529Transition *OpenEthereumNumber `json:"eip3529Transition"`
+	EIP3541Transition *OpenEthereumNumber `json:"eip3541Transition"`
+
+	EIP1559FeeCollector           *common.Address     `json:"eip1559FeeCollector"`
+	EIP1559FeeCollectorTransition *OpenEthereumNumber `json:"eip1559FeeCollectorTransition"`
+}
+
+// OpenEthereumGenesis is the genesis header of the chain.
+type OpenEthereumGenesis struct {
+	Seal       OpenEthereumSeal    `json:"seal"`
+	Difficulty *OpenEthereumNumber `json:"difficulty"`
+	Author     common.Address      `json:"author"`
+	Timestamp  *OpenEthereumNumber `json:"timestamp"`
+	ParentHash common.Hash         `json:"parentHash"`
+	ExtraData  hexBytes            `json:"extraData"`
+	GasLimit   *OpenEthereumNumber `json:"gasLimit"`
+}
+
+// OpenEthereumSeal is the seal of the genesis header, at most one of the fields is set.
+type OpenEthereumSeal struct {
+	AuthorityRound *struct {
+		Step      OpenEthereumNumber `json:"step"`
+		Signature hexBytes           `json:"signature"`
+	} `json:"authorityRound"`
+	Ethereum *struct {
+		Nonce   hexBytes    `json:"nonce"`
+		MixHash common.Hash `json:"mixHash"`
+	} `json:"ethereum"`
+	Generic hexBytes `json:"generic"` // RLP encoded list of seal fields
+}
+
+// OpenEthereumAccount is an account of the genesis state, possibly holding a precompiled contract.
+type OpenEthereumAccount struct {
+	Balance     *OpenEthereumNumber         `json:"balance"`
+	Nonce       *OpenEthereumNumber         `json:"nonce"`
+	Code        hexBytes                    `json:"code"`
+	Storage     map[common.Hash]common.Hash `json:"storage"`
+	Constructor hexBytes                    `json:"constructor"`
+	Builtin     *OpenEthereumBuiltin        `json:"builtin"`
+}
+
+// OpenEthereumBuiltin is a precompiled contract. Its pricing is ignored - precompiles
+// are priced by the fork rules in Erigon, or by their own implementation for the custom ones.
+type OpenEthereumBuiltin struct {
+	Name       string              `json:"name"`
+	ActivateAt *OpenEthereumNumber `json:"activate_at"` // only used by the custom precompiles
+}
+
+// OpenEthereumNumber is a number which may be given as a JSON number or as a decimal or hex string.
+type OpenEthereumNumber struct {
+	big.Int
+}
+
+func (n *OpenEthereumNumber) UnmarshalJSON(input []byte) error {
+	v, ok := math.ParseBig256(strings.Trim(string(input), `"`))
+	if !ok {
+		return fmt.Errorf("invalid number %s", input)
+	}
+	n.Set(v)
+	return nil
+}
+
+// hexBytes is a byte string which may be given with or without the 0x prefix.
+type hexBytes []byte
+
+func (b *hexBytes) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return err
+	}
+	*b = common.FromHex(s)
+	return nil
+}
+
+// ReadOpenEthereumSpec reads a chain spec in the OpenEthereum format from the file.
+func ReadOpenEthereumSpec(filename string) (*OpenEthereumSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	spec := &OpenEthereumSpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("parsing chain spec %s: %w", filename, err)
+	}
+	return spec, nil
+}
+
+// NetworkID returns the devp2p network id of the chain.
+func (s *OpenEthereumSpec) NetworkID() uint64 {
+	if s.Params.NetworkID == nil {
+		return 0
+	}
+	return s.Params.NetworkID.Uint64()
+}
+
+// ChainConfig converts the spec to the chain config, fork blocks are derived from the EIP transitions.
+func (s *OpenEthereumSpec) ChainConfig() (*ChainConfig, error) {
+	config := &ChainConfig{ChainName: strings.ToLower(s.Name)}
+	switch {
+	case s.Params.ChainID != nil:
+		config.ChainID = new(big.Int).Set(&s.Params.ChainID.Int)
+	case s.Params.NetworkID != nil:
+		config.ChainID = new(big.Int).Set(&s.Params.NetworkID.Int)
+	default:
+		return nil, fmt.Errorf("chain spec has neither chainID nor networkID")
+	}
+
+	config.HomesteadBlock = big.NewInt(0)
+	switch {
+	case s.Engine.AuthorityRound != nil:
+		config.Consensus = AuRaConsensus
+		config.Aura = &AuRaConfig{}
+	case s.Engine.Clique != nil:
+		config.Consensus = CliqueConsensus
+		config.Clique = &CliqueConfig{Period: s.Engine.Clique.Params.Period, Epoch: s.Engine.Clique.Params.Epoch}
+	case s.Engine.Ethash != nil:
+		config.Consensus = EtHashConsensus
+		config.Ethash = &EthashConfig{}
+		if t := s.Engine.Ethash.Params.HomesteadTransition; t != nil {
+			config.HomesteadBlock = new(big.Int).Set(&t.Int)
+		}
+	case s.Engine.InstantSeal != nil, s.Engine.Null != nil:
+		config.Consensus = NoProofConsensus
+	default:
+		return nil, fmt.Errorf("chain spec has unsupported consensus engine")
+	}
+
+	p := &s.Params
+	// these are enabled from genesis unless specified otherwise
+	config.TangerineWhistleBlock = transitionOrGenesis(p.EIP150Transition)
+	config.SpuriousDragonBlock = transitionOrGenesis(p.EIP155Transition)
+	if eip161 := transitionOrGenesis(p.EIP161abcTransition); eip161.Cmp(config.SpuriousDragonBlock) > 0 {
+		config.SpuriousDragonBlock = eip161
+	}
+
+	var err error
+	if config.ByzantiumBlock, err = forkBlock("byzantium", p.EIP140Transition, p.EIP211Transition, p.EIP214Transition, p.EIP658Transition); err != nil {
+		return nil, err
+	}
+	if config.ConstantinopleBlock, err = forkBlock("constantinople", p.EIP145Transition, p.EIP1014Transition, p.EIP1052Transition); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.EIP1283DisableTransition != nil:
+		config.PetersburgBlock = new(big.Int).Set(&p.EIP1283DisableTransition.Int)
+	case p.EIP1283Transition != nil:
+		return nil, fmt.Errorf("chain spec enables EIP-1283 without disabling it, which is not supported")
+	default:
+		config.PetersburgBlock = config.ConstantinopleBlock
+	}
+	if config.IstanbulBlock, err = forkBlock("istanbul", p.EIP1344Transition, p.EIP1884Transition, p.EIP2028Transition, p.EIP2200Transition); err != nil {
+		return nil, err
+	}
+	if config.BerlinBlock, err = forkBlock("berlin", p.EIP2929Transition, p.EIP2930Transition); err != nil {
+		return nil, err
+	}
+	if config.LondonBlock, err = forkBlock("london", p.EIP1559Transition, p.EIP3198Transition, p.EIP3529Transition, p.EIP3541Transition); err != nil {
+		return nil, err
+	}
+	if p.EIP1559FeeCollector != nil {
+		config.Eip1559FeeCollector = p.EIP1559FeeCollector
+		config.Eip1559FeeCollectorTransition = transitionOrGenesis(p.EIP1559FeeCollectorTransition)
+	}
+
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func transitionOrGenesis(transition *OpenEthereumNumber) *big.Int {
+	if transition == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(&transition.Int)
+}
+
+// forkBlock returns the block of the fork which consists of the given EIP transitions - when all of them are
+// activated. Returns nil if none of the EIPs are enabled, and an error if the fork is enabled partially.
+func forkBlock(fork string, transitions ...*OpenEthereumNumber) (*big.Int, error) {
+	var block *big.Int
+	for _, t := range transitions {
+		if t == nil {
+			continue
+		}
+		if block == nil || t.Cmp(block) > 0 {
+			block = new(big.Int).Set(&t.Int)
+		}
+	}
+	if block == nil {
+		return nil, nil
+	}
+	for _, t := range transitions {
+		if t == nil {
+			return nil, fmt.Errorf("chain spec enables %s partially, which is not supported", fork)
+		}
+	}
+	return block, nil
+}
@@ -28,6 +28,8 @@ const (
 	MaxGasLimit     uint64 = 0x7fffffffffffffff // Maximum the gas limit may ever be.
 	GenesisGasLimit uint64 = 4712388            // Gas limit of the Genesis block.
 
+	SystemTxsGas uint64 = 20_000_000 // Gas reserved in the blocks of PoSA engines (parlia) for the system transactions.
+
 	MaximumExtraDataSize  uint64 = 32    // Maximum size extra data may be after Genesis.
 	CallValueTransferGas  uint64 = 9000  // Paid for CALL when the value transfer is non-zero.
 	CallNewAccountGas     uint64 = 25000 // Paid for CALL when the destination address didn't exist prior.
@@ -79,6 +79,11 @@ type Client struct {
 	services        *serviceRegistry
 	methodAllowList AllowList
 
+	// The limits of the batches the connection serves
+	batchConcurrency uint
+	batchLimit       int
+	slowThreshold    time.Duration
+
 	idCounter uint32
 
 	// This function, if non-nil, is called when the connection is lost.
@@ -112,7 +117,7 @@ type clientConn struct {
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, 50, false /* traceRequests */)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, c.batchConcurrency, c.batchLimit, c.slowThreshold, false /* traceRequests */)
 	return &clientConn{conn, handler}
 }
 
@@ -196,27 +201,31 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil, 50, 0, 0)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, allowList AllowList, batchConcurrency uint, batchLimit int, slowThreshold time.Duration) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
-		idgen:       idgen,
-		isHTTP:      isHTTP,
-		services:    services,
-		writeConn:   conn,
-		close:       make(chan struct{}),
-		closing:     make(chan struct{}),
-		didClose:    make(chan struct{}),
-		reconnected: make(chan ServerCodec),
-		readOp:      make(chan readOp),
-		readErr:     make(chan error),
-		reqInit:     make(chan *requestOp),
-		reqSent:     make(chan error, 1),
-		reqTimeout:  make(chan *requestOp),
+		idgen:            idgen,
+		isHTTP:           isHTTP,
+		services:         services,
+		methodAllowList:  allowList,
+		batchConcurrency: batchConcurrency,
+		batchLimit:       batchLimit,
+		slowThreshold:    slowThreshold,
+		writeConn:        conn,
+		close:            make(chan struct{}),
+		closing:          make(chan struct{}),
+		didClose:         make(chan struct{}),
+		reconnected:      make(chan ServerCodec),
+		readOp:           make(chan readOp),
+		readErr:          make(chan error),
+		reqInit:          make(chan *requestOp),
+		reqSent:          make(chan error, 1),
+		reqTimeout:       make(chan *requestOp),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
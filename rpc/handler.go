@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
@@ -28,6 +30,10 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/log/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // handler handles JSON-RPC messages. There is one handler per connection. Note that
@@ -69,7 +75,10 @@ type handler struct {
 
 	subLock             sync.Mutex
 	serverSubs          map[ID]*Subscription
+	connValues          map[interface{}]interface{} // see Notifier.ConnectionValue
 	maxBatchConcurrency uint
+	batchLimit          int           // the maximum number of messages in a batch, 0 if unlimited
+	slowThreshold       time.Duration // the calls taking longer are logged as slow, 0 if none are
 	traceRequests       bool
 }
 
@@ -78,7 +87,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, traceRequests bool) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, batchLimit int, slowThreshold time.Duration, traceRequests bool) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	forbiddenList := newForbiddenList()
 	h := &handler{
@@ -96,6 +105,8 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		forbiddenList:  forbiddenList,
 
 		maxBatchConcurrency: maxBatchConcurrency,
+		batchLimit:          batchLimit,
+		slowThreshold:       slowThreshold,
 		traceRequests:       traceRequests,
 	}
 
@@ -115,6 +126,13 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// The batches over the limit are rejected whole, before running any of their calls
+	if h.batchLimit > 0 && len(msgs) > h.batchLimit {
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, errorMessage(&invalidRequestError{fmt.Sprintf("batch of %d requests over the limit of %d", len(msgs), h.batchLimit)}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -134,7 +152,7 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		boundedConcurrency := make(chan struct{}, h.maxBatchConcurrency)
 		defer close(boundedConcurrency)
 		wg := sync.WaitGroup{}
-		wg.Add(len(msgs))
+		wg.Add(len(calls))
 		for i := range calls {
 			boundedConcurrency <- struct{}{}
 			go func(i int) {
@@ -184,9 +202,17 @@ func (h *handler) handleMsg(msg *jsonrpcMessage, stream *jsoniter.Stream) {
 	}
 	h.startCallProc(func(cp *callProc) {
 		needWriteStream := false
+		var streamWriter io.WriteCloser
 		if stream == nil {
-			stream = jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
-			needWriteStream = true
+			if conn, ok := h.conn.(streamingConn); ok {
+				streamWriter = conn.streamWriter()
+			}
+			if streamWriter != nil {
+				stream = jsoniter.NewStream(jsoniter.ConfigDefault, streamWriter, 4096)
+			} else {
+				stream = jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
+				needWriteStream = true
+			}
 		}
 		answer := h.handleCallMsg(cp, msg, stream)
 		h.addSubscriptions(cp.notifiers)
@@ -196,6 +222,12 @@ func (h *handler) handleMsg(msg *jsonrpcMessage, stream *jsoniter.Stream) {
 		}
 		if needWriteStream {
 			h.conn.writeJSON(cp.ctx, json.RawMessage(stream.Buffer()))
+		} else if streamWriter != nil {
+			// The connection is locked for writing until the message is closed: the notifications come after it
+			stream.Flush()
+			if err := streamWriter.Close(); err != nil {
+				h.log.Debug("Failed to write the streamed response", "err", err)
+			}
 		} else {
 			stream.Write([]byte("\n"))
 		}
@@ -347,31 +379,15 @@ func (h *handler) handleResponse(msg *jsonrpcMessage) {
 // handleCallMsg executes a call message and returns the answer.
 func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) *jsonrpcMessage {
 	start := time.Now()
+	stats := &RequestStats{}
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg, stream)
-		if h.traceRequests {
-			h.log.Info("Served", "t", time.Since(start), "method", msg.Method, "params", string(msg.Params))
-		} else {
-			h.log.Trace("Served", "t", time.Since(start), "method", msg.Method, "params", string(msg.Params))
-		}
+		h.handleCall(ctx, msg, stream, stats)
+		h.logServed(msg, time.Since(start), stats, nil)
 		return nil
 	case msg.isCall():
-		resp := h.handleCall(ctx, msg, stream)
-		if resp != nil && resp.Error != nil {
-			if resp.Error.Data != nil {
-				h.log.Warn("Served", "method", msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start),
-					"err", resp.Error.Message, "errdata", resp.Error.Data)
-			} else {
-				h.log.Warn("Served", "method", msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start),
-					"err", resp.Error.Message)
-			}
-		}
-		if h.traceRequests {
-			h.log.Info("Served", "t", time.Since(start), "method", msg.Method, "reqid", idForLog{msg.ID}, "params", string(msg.Params))
-		} else {
-			h.log.Trace("Served", "t", time.Since(start), "method", msg.Method, "reqid", idForLog{msg.ID}, "params", string(msg.Params))
-		}
+		resp := h.handleCall(ctx, msg, stream, stats)
+		h.logServed(msg, time.Since(start), stats, resp)
 		return resp
 	case msg.hasValidID():
 		return msg.errorResponse(&invalidRequestError{"invalid request"})
@@ -380,6 +396,31 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage, stream *json
 	}
 }
 
+// logServed logs the call with its duration and its reads of the database: at WARN if it failed or was slow, at
+// INFO if the requests are traced, at TRACE otherwise.
+func (h *handler) logServed(msg *jsonrpcMessage, d time.Duration, stats *RequestStats, resp *jsonrpcMessage) {
+	ctx := []interface{}{"method", msg.Method}
+	if msg.isCall() {
+		ctx = append(ctx, "reqid", idForLog{msg.ID})
+	}
+	ctx = append(ctx, "t", d, "dbtxs", stats.DBTxs(), "dbreads", stats.DBReads(), "blocks", stats.Blocks())
+	slow := h.slowThreshold > 0 && d >= h.slowThreshold
+	switch {
+	case resp != nil && resp.Error != nil:
+		ctx = append(ctx, "err", resp.Error.Message)
+		if resp.Error.Data != nil {
+			ctx = append(ctx, "errdata", resp.Error.Data)
+		}
+		h.log.Warn("Served", ctx...)
+	case slow:
+		h.log.Warn("Served slow request", append(ctx, "params", string(msg.Params))...)
+	case h.traceRequests:
+		h.log.Info("Served", append(ctx, "params", string(msg.Params))...)
+	default:
+		h.log.Trace("Served", append(ctx, "params", string(msg.Params))...)
+	}
+}
+
 func (h *handler) isMethodAllowedByGranularControl(method string) bool {
 	_, isForbidden := h.forbiddenList[method]
 	if len(h.allowList) == 0 {
@@ -394,7 +435,7 @@ func (h *handler) isMethodAllowedByGranularControl(method string) bool {
 }
 
 // handleCall processes method calls.
-func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) *jsonrpcMessage {
+func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream, stats *RequestStats) *jsonrpcMessage {
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg, stream)
 	}
@@ -412,16 +453,33 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args, stream)
+	ctx, span := tracer.Start(WithRequestStats(cp.ctx, stats), msg.Method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemKey.String("jsonrpc"), semconv.RPCMethodKey.String(msg.Method)))
+	answer := h.runMethod(ctx, msg, callb, args, stream)
+	span.SetAttributes(attribute.Int64("rpc.db_reads", int64(stats.DBReads())), attribute.Int("rpc.blocks", stats.Blocks()))
+	if answer != nil && answer.Error != nil {
+		span.SetAttributes(semconv.RPCJsonrpcErrorCodeKey.Int(answer.Error.Code))
+		span.SetStatus(codes.Error, answer.Error.Message)
+	}
+	span.End()
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
 	if callb != h.unsubscribeCb {
+		d := time.Since(start)
+		failed := answer != nil && answer.Error != nil
+		slow := h.slowThreshold > 0 && d >= h.slowThreshold
 		rpcRequestGauge.Inc()
-		if answer != nil && answer.Error != nil {
+		if failed {
 			failedReqeustGauge.Inc()
 		}
-		newRPCServingTimerMS(msg.Method, answer == nil || answer.Error == nil).UpdateDuration(start)
+		if slow {
+			slowRequestGauge.Inc()
+		}
+		newRPCServingTimerMS(msg.Method, !failed).UpdateDuration(start)
+		newRPCServingHistogram(msg.Method).Update(d.Seconds())
+		newRPCDBReadsHistogram(msg.Method).Update(float64(stats.DBReads()))
+		addMethodStats(msg.Method, d, failed, slow, stats)
 	}
 	return answer
 }
@@ -474,34 +532,13 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 		stream.WriteObjectField("result")
 		_, err := callb.call(ctx, msg.Method, args, stream)
 		if err != nil {
-			return msg.errorResponse(err)
-			/*
-				stream.WriteMore()
-				stream.WriteObjectField("error")
-				stream.WriteObjectStart()
-				stream.WriteObjectField("code")
-				ec, ok := err.(Error)
-				if ok {
-					stream.WriteInt(ec.ErrorCode())
-				} else {
-					stream.WriteInt(defaultErrorCode)
-				}
-				stream.WriteMore()
-				stream.WriteObjectField("message")
-				stream.WriteString(fmt.Sprintf("%v", err))
-				de, ok := err.(DataError)
-				if ok {
-					stream.WriteMore()
-					stream.WriteObjectField("data")
-					data, derr := json.Marshal(de.ErrorData())
-					if derr == nil {
-						stream.Write(data)
-					} else {
-						stream.WriteString(fmt.Sprintf("%v", derr))
-					}
-				}
-				stream.WriteObjectEnd()
-			*/
+			// A part of the result may have been sent already: the error comes along with it
+			if bytes.HasSuffix(stream.Buffer(), []byte(`"result":`)) {
+				stream.WriteNil()
+			}
+			stream.WriteMore()
+			stream.WriteObjectField("error")
+			stream.WriteVal(errorMessage(err).Error)
 		}
 		stream.WriteObjectEnd()
 		stream.Flush()
@@ -32,6 +32,8 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 const (
@@ -219,8 +221,29 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
 
 	w.Header().Set("content-type", contentType)
+	if s.rateLimiter != nil {
+		// The calls are weighed before being served
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestContentLength))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The malformed requests are answered with a parse error, for free
+		if json.Valid(body) {
+			msgs, batch := parseMessage(body)
+			if err := s.rateLimiter.take(r, msgs); err != nil {
+				w.WriteHeader(http.StatusTooManyRequests)
+				if answer := rateLimitedResponse(msgs, batch, err); answer != nil {
+					json.NewEncoder(w).Encode(answer) //nolint:errcheck
+				}
+				return
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
 	codec := newHTTPServerConn(r, w)
 	defer codec.close()
 	var stream *jsoniter.Stream
@@ -258,6 +281,11 @@ func validateRequest(r *http.Request) (int, error) {
 }
 
 func CheckJwtSecret(w http.ResponseWriter, r *http.Request, jwtSecret []byte) bool {
+	return CheckJwtSecrets(w, r, [][]byte{jwtSecret})
+}
+
+// CheckJwtSecrets accepts the token signed by any of the secrets, e.g. both the old and the new one while the secret is rotated.
+func CheckJwtSecrets(w http.ResponseWriter, r *http.Request, jwtSecrets [][]byte) bool {
 	var tokenStr string
 	// Check if JWT signature is correct
 	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
@@ -269,16 +297,26 @@ func CheckJwtSecret(w http.ResponseWriter, r *http.Request, jwtSecret []byte) bo
 		return false
 	}
 
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+	var (
+		token  *jwt.Token
+		claims jwt.RegisteredClaims
+		err    = errors.New("no secret configured")
+	)
+	for _, jwtSecret := range jwtSecrets {
+		keyFunc := func(token *jwt.Token) (interface{}, error) {
+			return jwtSecret, nil
+		}
+		claims = jwt.RegisteredClaims{}
+		// We explicitly set only HS256 allowed, and also disables the
+		// claim-check: the RegisteredClaims internally requires 'iat' to
+		// be no later than 'now', but we allow for a bit of drift.
+		token, err = jwt.ParseWithClaims(tokenStr, &claims, keyFunc,
+			jwt.WithValidMethods([]string{"HS256"}),
+			jwt.WithoutClaimsValidation())
+		if err == nil && token.Valid {
+			break
+		}
 	}
-	claims := jwt.RegisteredClaims{}
-	// We explicitly set only HS256 allowed, and also disables the
-	// claim-check: the RegisteredClaims internally requires 'iat' to
-	// be no later than 'now', but we allow for a bit of drift.
-	token, err := jwt.ParseWithClaims(tokenStr, &claims, keyFunc,
-		jwt.WithValidMethods([]string{"HS256"}),
-		jwt.WithoutClaimsValidation())
 
 	switch {
 	case err != nil:
@@ -21,6 +21,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
 )
 
 func confirmStatusCode(t *testing.T, got, want int) {
@@ -126,3 +129,30 @@ func TestHTTPRespBodyUnlimited(t *testing.T) {
 		t.Fatalf("response has wrong length %d, want %d", len(r), respLength)
 	}
 }
+
+func TestCheckJwtSecrets(t *testing.T) {
+	oldSecret, newSecret := make([]byte, 32), make([]byte, 32)
+	oldSecret[0], newSecret[0] = 1, 2
+	sign := func(secret []byte) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())})
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	check := func(token string, secrets ...[]byte) int {
+		r := httptest.NewRequest(http.MethodPost, "http://url.com", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		if CheckJwtSecrets(w, r, secrets) {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+
+	confirmStatusCode(t, check(sign(oldSecret), newSecret, oldSecret), http.StatusOK)
+	confirmStatusCode(t, check(sign(newSecret), newSecret, oldSecret), http.StatusOK)
+	confirmStatusCode(t, check(sign(oldSecret), newSecret), http.StatusForbidden)
+	confirmStatusCode(t, check(sign(oldSecret)), http.StatusForbidden)
+}
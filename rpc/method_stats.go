@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The latencies of the calls are aggregated in the buckets of the powers of 2 of their microseconds, so the
+// percentiles are approximated by the upper bounds of the buckets, within twice the latency.
+const methodStatsBuckets = 40
+
+type methodStats struct {
+	calls, failures, slow uint64
+	dbReads               uint64
+	total, max            time.Duration
+	buckets               [methodStatsBuckets]uint64
+}
+
+func (s *methodStats) percentile(p float64) time.Duration {
+	rank := uint64(math.Ceil(p * float64(s.calls)))
+	var n uint64
+	for i, count := range s.buckets {
+		n += count
+		if n >= rank {
+			if bound := time.Microsecond << i; bound < s.max {
+				return bound
+			}
+			break
+		}
+	}
+	return s.max
+}
+
+var (
+	methodStatsLock   sync.Mutex
+	methodStatsSince  = time.Now()
+	methodStatsByName = map[string]*methodStats{}
+)
+
+// addMethodStats aggregates a call of the method, served by any of the servers of the process
+func addMethodStats(method string, d time.Duration, failed, slow bool, stats *RequestStats) {
+	bucket := bits.Len64(uint64(d / time.Microsecond))
+	if bucket >= methodStatsBuckets {
+		bucket = methodStatsBuckets - 1
+	}
+	methodStatsLock.Lock()
+	defer methodStatsLock.Unlock()
+	s, ok := methodStatsByName[method]
+	if !ok {
+		s = &methodStats{}
+		methodStatsByName[method] = s
+	}
+	s.calls++
+	if failed {
+		s.failures++
+	}
+	if slow {
+		s.slow++
+	}
+	s.dbReads += stats.DBReads()
+	s.total += d
+	if d > s.max {
+		s.max = d
+	}
+	s.buckets[bucket]++
+}
+
+// Stats - the latencies of the method calls served since the start of the process, or the last reset.
+type Stats struct {
+	Since   time.Time      `json:"since"`
+	Methods []*MethodStats `json:"methods"`
+}
+
+// MethodStats - the calls of a method, the slow ones took longer than the slow threshold of their server. The
+// percentiles are approximate.
+type MethodStats struct {
+	Method   string        `json:"method"`
+	Calls    uint64        `json:"calls"`
+	Failures uint64        `json:"failures"`
+	Slow     uint64        `json:"slow"`
+	DBReads  uint64        `json:"dbReads"`
+	Time     time.Duration `json:"time"` // nanoseconds, as the rest
+	Mean     time.Duration `json:"mean"`
+	Max      time.Duration `json:"max"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P99      time.Duration `json:"p99"`
+}
+
+// GetStats returns the stats of the methods, sorted by their total time. With reset, the stats start over.
+func GetStats(reset bool) *Stats {
+	methodStatsLock.Lock()
+	defer methodStatsLock.Unlock()
+	res := &Stats{Since: methodStatsSince, Methods: []*MethodStats{}}
+	for method, s := range methodStatsByName {
+		res.Methods = append(res.Methods, &MethodStats{
+			Method:   method,
+			Calls:    s.calls,
+			Failures: s.failures,
+			Slow:     s.slow,
+			DBReads:  s.dbReads,
+			Time:     s.total,
+			Mean:     s.total / time.Duration(s.calls),
+			Max:      s.max,
+			P50:      s.percentile(0.5),
+			P90:      s.percentile(0.9),
+			P99:      s.percentile(0.99),
+		})
+	}
+	sort.Slice(res.Methods, func(i, j int) bool {
+		if res.Methods[i].Time != res.Methods[j].Time {
+			return res.Methods[i].Time > res.Methods[j].Time
+		}
+		return res.Methods[i].Method < res.Methods[j].Method
+	})
+	if reset {
+		methodStatsSince, methodStatsByName = time.Now(), map[string]*methodStats{}
+	}
+	return res
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type statsService struct{}
+
+func (s *statsService) Read(ctx context.Context, reads int) error {
+	stats := RequestStatsFromContext(ctx)
+	stats.AddDBTx()
+	stats.AddDBReads(uint64(reads))
+	stats.AddBlock(1)
+	stats.AddBlock(1)
+	stats.AddBlock(2)
+	return nil
+}
+
+func TestMethodStats(t *testing.T) {
+	srv := NewServer(50, false /* traceRequests */, true)
+	defer srv.Stop()
+	require.NoError(t, srv.RegisterName("stats", new(statsService)))
+	srv.SetSlowThreshold(time.Nanosecond)
+	client := DialInProc(srv)
+	defer client.Close()
+
+	GetStats(true)
+	for _, reads := range []int{3, 5} {
+		require.NoError(t, client.Call(nil, "stats_read", reads))
+	}
+	require.Error(t, client.Call(nil, "stats_read", "x"))
+
+	stats := GetStats(true)
+	require.Len(t, stats.Methods, 1)
+	m := stats.Methods[0]
+	require.Equal(t, "stats_read", m.Method)
+	require.Equal(t, uint64(2), m.Calls)
+	require.Equal(t, uint64(0), m.Failures)
+	require.Equal(t, uint64(2), m.Slow)
+	require.Equal(t, uint64(8), m.DBReads)
+	require.Equal(t, m.Time/2, m.Mean)
+	require.LessOrEqual(t, m.P50, m.P99)
+	require.LessOrEqual(t, m.P99, m.Max)
+	require.Empty(t, GetStats(false).Methods)
+}
+
+func TestMethodStatsPercentile(t *testing.T) {
+	GetStats(true)
+	for _, d := range []time.Duration{time.Microsecond, 3 * time.Microsecond, 100 * time.Microsecond, time.Second} {
+		addMethodStats("m", d, false, false, &RequestStats{})
+	}
+	s := methodStatsByName["m"]
+	GetStats(true)
+	require.Equal(t, 2*time.Microsecond, s.percentile(0.25))
+	require.Equal(t, 4*time.Microsecond, s.percentile(0.5))
+	require.Equal(t, 128*time.Microsecond, s.percentile(0.75))
+	require.Equal(t, time.Second, s.percentile(0.99))
+}
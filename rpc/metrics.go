@@ -20,11 +20,16 @@ import (
 	"fmt"
 
 	"github.com/VictoriaMetrics/metrics"
+	"go.opentelemetry.io/otel"
 )
 
 var (
 	rpcRequestGauge    = metrics.GetOrCreateCounter("rpc_total")
 	failedReqeustGauge = metrics.GetOrCreateCounter("rpc_failure")
+	slowRequestGauge   = metrics.GetOrCreateCounter("rpc_slow")
+
+	// tracer makes a span of each method call, the child of the trace context of the HTTP request if any
+	tracer = otel.Tracer("github.com/ledgerwatch/erigon/rpc")
 )
 
 func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
@@ -35,3 +40,11 @@ func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
 	m := fmt.Sprintf(`rpc_duration_seconds{method="%s",success="%s"}`, method, flag)
 	return metrics.GetOrCreateSummary(m)
 }
+
+func newRPCServingHistogram(method string) *metrics.Histogram {
+	return metrics.GetOrCreateHistogram(fmt.Sprintf(`rpc_duration_histogram_seconds{method="%s"}`, method))
+}
+
+func newRPCDBReadsHistogram(method string) *metrics.Histogram {
+	return metrics.GetOrCreateHistogram(fmt.Sprintf(`rpc_db_reads{method="%s"}`, method))
+}
@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// APIKeyHeader is the HTTP header of the requests which carries the API key of the client, if any.
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit is the budget of a client: the cost of the calls it may make per second, and at once.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// RateLimits is the configuration of the rate limiting of the calls. The cost of a call is the weight of its method,
+// or of its namespace as "namespace_*", 1 by default. The clients with a known API key have the budget of their key,
+// the others the budget of their IP address. The clients without a budget aren't limited.
+type RateLimits struct {
+	Weights map[string]int       `json:"weights"`
+	PerIP   *RateLimit           `json:"perIP"`
+	APIKeys map[string]RateLimit `json:"apiKeys"`
+}
+
+// weight returns the cost of a call of the given method.
+func (l *RateLimits) weight(method string) int {
+	if w, ok := l.Weights[method]; ok {
+		return w
+	}
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		if w, ok := l.Weights[method[:i]+serviceMethodSeparator+"*"]; ok {
+			return w
+		}
+	}
+	return 1
+}
+
+type rateLimitedError struct{ cost int }
+
+func (e *rateLimitedError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: the request costs %d", e.cost)
+}
+
+// rateLimiter keeps track of the budgets the clients have left.
+type rateLimiter struct {
+	limits RateLimits
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{limits: limits, clients: make(map[string]*clientLimiter), lastSweep: time.Now()}
+}
+
+// take spends the cost of the calls among the given messages from the budget of the client of the given request,
+// or returns the error to answer them with if it's exhausted.
+func (l *rateLimiter) take(r *http.Request, msgs []*jsonrpcMessage) error {
+	cost := 0
+	for _, msg := range msgs {
+		if msg.Method != "" {
+			cost += l.limits.weight(msg.Method)
+		}
+	}
+	return l.spend(r, cost)
+}
+
+// spend spends the given cost from the budget of the client of the given request, or returns the error to answer it
+// with if it's exhausted.
+func (l *rateLimiter) spend(r *http.Request, cost int) error {
+	if cost == 0 {
+		return nil
+	}
+
+	var client string
+	var limit RateLimit
+	if key := r.Header.Get(APIKeyHeader); key != "" && l.limits.APIKeys[key] != (RateLimit{}) {
+		client, limit = "key:"+key, l.limits.APIKeys[key]
+	} else if l.limits.PerIP != nil {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		client, limit = "ip:"+ip, *l.limits.PerIP
+	} else {
+		return nil
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{Limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		l.clients[client] = c
+	}
+	c.lastUsed = now
+	if !c.AllowN(now, cost) {
+		return &rateLimitedError{cost: cost}
+	}
+	return nil
+}
+
+// sweep forgets the clients which have had the time to refill their budget, about once a minute.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for client, c := range l.clients {
+		// Without a rate, the budget is never refilled
+		if c.Limit() <= 0 {
+			continue
+		}
+		refill := time.Duration(float64(c.Burst()) / float64(c.Limit()) * float64(time.Second))
+		if now.Sub(c.lastUsed) > refill {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// RateLimitHandler rate limits the requests to the given handler, served next to the server like GraphQL, from the
+// same budgets as the calls: each request costs the weight of the given method.
+func (s *Server) RateLimitHandler(method string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter := s.rateLimiter; limiter != nil {
+			if err := limiter.spend(r, limiter.limits.weight(method)); err != nil {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitedResponse returns the answer to the given messages rejected for the given error: the error for each of
+// the calls, in a batch if the messages came in one. It returns nil if there are no calls to answer.
+func rateLimitedResponse(msgs []*jsonrpcMessage, batch bool, err error) interface{} {
+	answers := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.isCall() {
+			answers = append(answers, msg.errorResponse(err))
+		}
+	}
+	switch {
+	case len(answers) == 0:
+		return nil
+	case !batch:
+		return answers[0]
+	default:
+		return answers
+	}
+}
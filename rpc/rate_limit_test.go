@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitedTestServer(t *testing.T) *Server {
+	srv := NewServer(50, false /* traceRequests */, true)
+	require.NoError(t, srv.RegisterName("test", new(testService)))
+	srv.SetRateLimits(&RateLimits{
+		Weights: map[string]int{"test_echo": 3, "rpc_*": 0},
+		PerIP:   &RateLimit{Rate: 0.001, Burst: 5},
+		APIKeys: map[string]RateLimit{"key": {Rate: 0.001, Burst: 100}},
+	})
+	return srv
+}
+
+func TestRateLimitsWeight(t *testing.T) {
+	limits := RateLimits{Weights: map[string]int{"debug_traceTransaction": 100, "debug_*": 10}}
+	require.Equal(t, 100, limits.weight("debug_traceTransaction"))
+	require.Equal(t, 10, limits.weight("debug_traceCall"))
+	require.Equal(t, 1, limits.weight("eth_call"))
+}
+
+func TestHTTPRateLimit(t *testing.T) {
+	srv := newRateLimitedTestServer(t)
+	defer srv.Stop()
+	httpsrv := httptest.NewServer(srv)
+	defer httpsrv.Close()
+
+	post := func(body, apiKey string) (int, jsonrpcMessage) {
+		req, err := http.NewRequest(http.MethodPost, httpsrv.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("content-type", contentType)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var msg jsonrpcMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&msg))
+		return resp.StatusCode, msg
+	}
+	echo := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`
+
+	// The budget of the IP address is 5: one echo, and the calls without a cost
+	status, msg := post(echo, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Nil(t, msg.Error)
+	status, msg = post(echo, "")
+	require.Equal(t, http.StatusTooManyRequests, status)
+	require.Equal(t, -32005, msg.Error.Code)
+	require.Equal(t, json.RawMessage("1"), msg.ID)
+	status, _ = post(`{"jsonrpc":"2.0","id":2,"method":"rpc_modules"}`, "")
+	require.Equal(t, http.StatusOK, status)
+
+	// The clients with a known API key have its budget instead
+	status, msg = post(echo, "key")
+	require.Equal(t, http.StatusOK, status)
+	require.Nil(t, msg.Error)
+	status, _ = post(echo, "unknown")
+	require.Equal(t, http.StatusTooManyRequests, status)
+}
+
+func TestWebsocketRateLimit(t *testing.T) {
+	srv := newRateLimitedTestServer(t)
+	defer srv.Stop()
+	httpsrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}, nil, false))
+	defer httpsrv.Close()
+
+	client, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"), "")
+	require.NoError(t, err)
+	defer client.Close()
+
+	var result echoResult
+	require.NoError(t, client.Call(&result, "test_echo", "x", 1))
+	err = client.Call(&result, "test_echo", "x", 1)
+	require.Error(t, err)
+	require.Equal(t, -32005, err.(Error).ErrorCode())
+
+	// The connection is still usable for the calls within the budget
+	var modules map[string]string
+	require.NoError(t, client.Call(&modules, "rpc_modules"))
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	srv := newRateLimitedTestServer(t)
+	defer srv.Stop()
+	srv.rateLimiter.limits.Weights["graphql"] = 4
+	httpsrv := httptest.NewServer(srv.RateLimitHandler("graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer httpsrv.Close()
+
+	get := func() int {
+		resp, err := http.Get(httpsrv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// The budget of the IP address is 5, a query costs 4
+	require.Equal(t, http.StatusOK, get())
+	require.Equal(t, http.StatusTooManyRequests, get())
+
+	// without rate limits, the handler is served as is
+	srv.SetRateLimits(nil)
+	require.Equal(t, http.StatusOK, get())
+}
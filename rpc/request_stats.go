@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// maxRequestStatsBlocks is the maximum number of the distinct blocks a request counts, the blocks read past it aren't
+// counted.
+const maxRequestStatsBlocks = 1 << 16
+
+// RequestStats are the reads of the database by a method call. The server puts them in the context of the call, and
+// the database of the methods counts its reads in them, see RequestStatsFromContext. They're logged with the call,
+// and aggregated by method, see MethodStats.
+type RequestStats struct {
+	dbTxs   uint64 // atomic
+	dbReads uint64 // atomic
+
+	lock   sync.Mutex
+	blocks map[uint64]struct{}
+}
+
+type requestStatsKey struct{}
+
+// WithRequestStats returns the context with the stats, in which the reads of the database are counted.
+func WithRequestStats(ctx context.Context, stats *RequestStats) context.Context {
+	return context.WithValue(ctx, requestStatsKey{}, stats)
+}
+
+// RequestStatsFromContext returns the stats of the method call of the context, nil if it's not a method call.
+func RequestStatsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(requestStatsKey{}).(*RequestStats)
+	return stats
+}
+
+// AddDBTx counts a read transaction begun by the call.
+func (s *RequestStats) AddDBTx() {
+	atomic.AddUint64(&s.dbTxs, 1)
+}
+
+// AddDBReads counts the reads of keys, or the steps of cursors, by the call.
+func (s *RequestStats) AddDBReads(n uint64) {
+	atomic.AddUint64(&s.dbReads, n)
+}
+
+// AddBlock counts a block the call has read the header, the body or the receipts of.
+func (s *RequestStats) AddBlock(number uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.blocks == nil {
+		s.blocks = map[uint64]struct{}{}
+	}
+	if len(s.blocks) < maxRequestStatsBlocks {
+		s.blocks[number] = struct{}{}
+	}
+}
+
+// DBTxs returns the number of the read transactions begun by the call.
+func (s *RequestStats) DBTxs() uint64 {
+	return atomic.LoadUint64(&s.dbTxs)
+}
+
+// DBReads returns the number of the reads by the call.
+func (s *RequestStats) DBReads() uint64 {
+	return atomic.LoadUint64(&s.dbReads)
+}
+
+// Blocks returns the number of the distinct blocks read by the call.
+func (s *RequestStats) Blocks() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.blocks)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"io"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	jsoniter "github.com/json-iterator/go"
@@ -45,11 +46,14 @@ const (
 type Server struct {
 	services        serviceRegistry
 	methodAllowList AllowList
+	rateLimiter     *rateLimiter // nil if the calls aren't rate limited
 	idgen           func() ID
 	run             int32
 	codecs          mapset.Set
 
 	batchConcurrency uint
+	batchLimit       int           // the maximum number of messages in a batch, 0 if unlimited
+	slowThreshold    time.Duration // the calls taking longer are logged at WARN, 0 if none are
 	disableStreaming bool
 	traceRequests    bool // Whether to print requests at INFO level
 }
@@ -64,6 +68,27 @@ func NewServer(batchConcurrency uint, traceRequests, disableStreaming bool) *Ser
 	return server
 }
 
+// SetRateLimits sets the rate limits of the calls served over HTTP and WebSocket, nil disables the rate limiting.
+func (s *Server) SetRateLimits(limits *RateLimits) {
+	if limits == nil {
+		s.rateLimiter = nil
+		return
+	}
+	s.rateLimiter = newRateLimiter(*limits)
+}
+
+// SetBatchLimit sets the maximum number of messages in the batches served, 0 for no limit. The batches over it are
+// answered with an error.
+func (s *Server) SetBatchLimit(limit int) {
+	s.batchLimit = limit
+}
+
+// SetSlowThreshold sets the duration over which the calls are logged at WARN with their reads of the database, and
+// counted as slow. 0 disables it.
+func (s *Server) SetSlowThreshold(threshold time.Duration) {
+	s.slowThreshold = threshold
+}
+
 // SetAllowList sets the allow list for methods that are handled by this server
 func (s *Server) SetAllowList(allowList AllowList) {
 	s.methodAllowList = allowList
@@ -94,7 +119,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.batchLimit, s.slowThreshold)
 	<-codec.closed()
 	c.Close()
 }
@@ -108,7 +133,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.batchLimit, s.slowThreshold, s.traceRequests)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
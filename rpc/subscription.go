@@ -141,6 +141,22 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// ConnectionValue returns the value kept under the key for the RPC connection of the notifier, created by
+// newValue on first use. It lets the subscriptions of a connection share state, e.g. a rate limiter.
+func (n *Notifier) ConnectionValue(key interface{}, newValue func() interface{}) interface{} {
+	n.h.subLock.Lock()
+	defer n.h.subLock.Unlock()
+	if n.h.connValues == nil {
+		n.h.connValues = map[interface{}]interface{}{}
+	}
+	v, ok := n.h.connValues[key]
+	if !ok {
+		v = newValue()
+		n.h.connValues[key] = v
+	}
+	return v
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
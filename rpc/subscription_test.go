@@ -171,6 +171,41 @@ func TestServerUnsubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that the subscriptions of a connection share its values, and only them.
+func TestNotifierConnectionValue(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	for conn := 0; conn < 2; conn++ {
+		p1, p2 := net.Pipe()
+		go server.ServeCodec(NewCodec(p1), 0)
+		p2.SetDeadline(time.Now().Add(10 * time.Second))
+
+		var (
+			resps         = make(chan subConfirmation)
+			notifications = make(chan subscriptionResult)
+			errors        = make(chan error, 1)
+		)
+		go waitForMessages(json.NewDecoder(p2), resps, notifications, errors)
+		for i := 1; i <= 2; i++ {
+			p2.Write([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"nftest_subscribe","params":["counterSubscription"]}`, i)))
+			select {
+			case <-resps:
+			case err := <-errors:
+				t.Fatal(err)
+			}
+			select {
+			case n := <-notifications:
+				if string(n.Result) != fmt.Sprint(i) {
+					t.Fatalf("connection %d: wrong counter, want %d, got %s", conn, i, n.Result)
+				}
+			case err := <-errors:
+				t.Fatal(err)
+			}
+		}
+		p2.Close()
+	}
+}
+
 type subConfirmation struct {
 	reqid int
 	subid ID
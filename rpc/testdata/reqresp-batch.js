@@ -6,3 +6,8 @@
 
 --> [{"jsonrpc":"2.0","id":2,"method":"test_echo","params":[]}, {"jsonrpc":"2.0","id": 3,"method":"test_echo","params":["x",3]}]
 <-- [{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"missing value for required argument 0"}},{"jsonrpc":"2.0","id":3,"result":{"String":"x","Int":3,"Args":null}}]
+
+// The calls of batches mixed with responses are answered.
+
+--> [{"jsonrpc":"2.0","id":4,"method":"test_echo","params":["x",4]}, {"jsonrpc":"2.0","id":5,"result":"unexpected"}]
+<-- [{"jsonrpc":"2.0","id":4,"result":{"String":"x","Int":4,"Args":null}}]
@@ -22,12 +22,16 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 func newTestServer() *Server {
 	server := NewServer(50, false /* traceRequests */, true)
 	server.idgen = sequentialIDGenerator()
+	server.SetBatchLimit(5)
 	if err := server.RegisterName("test", new(testService)); err != nil {
 		panic(err)
 	}
@@ -110,6 +114,28 @@ func (s *testService) ReturnError() error {
 	return testError{}
 }
 
+// Stream writes the numbers from 0 to n-1, flushing them one by one, and fails afterwards if requested.
+func (s *testService) Stream(n int, fail bool, stream *jsoniter.Stream) error {
+	if fail && n == 0 {
+		return testError{}
+	}
+	stream.WriteArrayStart()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteInt(i)
+		if err := stream.Flush(); err != nil {
+			return err
+		}
+	}
+	stream.WriteArrayEnd()
+	if fail {
+		return testError{}
+	}
+	return nil
+}
+
 func (s *testService) CallMeBack(ctx context.Context, method string, args []interface{}) (interface{}, error) {
 	c, ok := ClientFromContext(ctx)
 	if !ok {
@@ -180,6 +206,21 @@ func (s *notificationTestService) SomeSubscription(ctx context.Context, n, val i
 	return subscription, nil
 }
 
+// CounterSubscription notifies the number of counter subscriptions made by the connection so far.
+func (s *notificationTestService) CounterSubscription(ctx context.Context) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	counter := notifier.ConnectionValue("counter", func() interface{} { return new(int32) }).(*int32)
+	n := atomic.AddInt32(counter, 1)
+	subscription := notifier.CreateSubscription()
+	go func() {
+		notifier.Notify(subscription.ID, n)
+	}()
+	return subscription, nil
+}
+
 // HangSubscription blocks on s.unblockHangSubscription before sending anything.
 func (s *notificationTestService) HangSubscription(ctx context.Context, val int) (*Subscription, error) {
 	notifier, supported := NotifierFromContext(ctx)
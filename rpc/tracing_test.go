@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	srv := newTestServer()
+	defer srv.Stop()
+	httpsrv := httptest.NewServer(srv)
+	defer httpsrv.Close()
+
+	post := func(body string) {
+		req, err := http.NewRequest(http.MethodPost, httpsrv.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("content-type", contentType)
+		req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	post(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`)
+	post(`{"jsonrpc":"2.0","id":2,"method":"test_returnError","params":[]}`)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	parentID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	for _, span := range spans {
+		require.Equal(t, traceID, span.SpanContext().TraceID())
+		require.Equal(t, parentID, span.Parent().SpanID())
+		require.Equal(t, trace.SpanKindServer, span.SpanKind())
+	}
+	require.Equal(t, "test_echo", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, "test_returnError", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
@@ -67,6 +68,15 @@ type jsonWriter interface {
 	remoteAddr() string
 }
 
+// streamingConn is implemented by the connections which can send a response as it's written, instead of buffering it
+// whole in memory first.
+type streamingConn interface {
+	// streamWriter returns a writer which sends the bytes written to it as a single message, complete once the writer
+	// is closed, or nil if the streaming is disabled. The connection is locked for writing from the first write until
+	// then.
+	streamWriter() io.WriteCloser
+}
+
 type BlockNumber int64
 type Timestamp uint64
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -46,7 +47,8 @@ var wsBufferPool = new(sync.Pool)
 //
 // allowedOrigins should be a comma-separated list of allowed origin URLs.
 // To allow connections with any origin, pass "*".
-func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, compression bool) http.Handler {
+// jwtSecrets returns the secrets accepted for the authentication of the connection, nil disables it.
+func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecrets func() [][]byte, compression bool) http.Handler {
 	upgrader := websocket.Upgrader{
 		EnableCompression: compression,
 		ReadBufferSize:    wsReadBuffer,
@@ -55,7 +57,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 		CheckOrigin:       wsHandshakeValidator(allowedOrigins),
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if jwtSecret != nil && !CheckJwtSecret(w, r, jwtSecret) {
+		if jwtSecrets != nil && !CheckJwtSecrets(w, r, jwtSecrets()) {
 			return
 		}
 		conn, err := upgrader.Upgrade(w, r, nil)
@@ -63,7 +65,10 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 			log.Warn("WebSocket upgrade failed", "err", err)
 			return
 		}
-		codec := newWebsocketCodec(conn)
+		codec := newWebsocketCodec(conn, !s.disableStreaming)
+		if limiter := s.rateLimiter; limiter != nil {
+			codec.limit = func(msgs []*jsonrpcMessage) error { return limiter.take(r, msgs) }
+		}
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -201,7 +206,7 @@ func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, diale
 			}
 			return nil, hErr
 		}
-		return newWebsocketCodec(conn), nil
+		return newWebsocketCodec(conn, false), nil
 	})
 }
 
@@ -238,17 +243,20 @@ func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
 
 type websocketCodec struct {
 	*jsonCodec
-	conn *websocket.Conn
+	conn      *websocket.Conn
+	streaming bool                               // Whether the responses are sent as they're written
+	limit     func(msgs []*jsonrpcMessage) error // Spends the cost of the calls from the budget of the client, if limited
 
 	wg        sync.WaitGroup
 	pingReset chan struct{}
 }
 
-func newWebsocketCodec(conn *websocket.Conn) ServerCodec {
+func newWebsocketCodec(conn *websocket.Conn, streaming bool) *websocketCodec {
 	conn.SetReadLimit(wsMessageSizeLimit)
 	wc := &websocketCodec{
 		jsonCodec: NewFuncCodec(conn, conn.WriteJSON, conn.ReadJSON).(*jsonCodec),
 		conn:      conn,
+		streaming: streaming,
 		pingReset: make(chan struct{}, 1),
 	}
 	wc.wg.Add(1)
@@ -261,14 +269,80 @@ func (wc *websocketCodec) close() {
 	wc.wg.Wait()
 }
 
+// readBatch reads the next messages, and answers right away those over the rate limit of the client.
+func (wc *websocketCodec) readBatch() ([]*jsonrpcMessage, bool, error) {
+	for {
+		msgs, batch, err := wc.jsonCodec.readBatch()
+		if err != nil || wc.limit == nil {
+			return msgs, batch, err
+		}
+		limitErr := wc.limit(msgs)
+		if limitErr == nil {
+			return msgs, batch, nil
+		}
+		if answer := rateLimitedResponse(msgs, batch, limitErr); answer != nil {
+			if err := wc.writeJSON(context.Background(), answer); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+}
+
 func (wc *websocketCodec) writeJSON(ctx context.Context, v interface{}) error {
 	err := wc.jsonCodec.writeJSON(ctx, v)
 	if err == nil {
-		// Notify pingLoop to delay the next idle ping.
-		select {
-		case wc.pingReset <- struct{}{}:
-		default:
+		wc.delayPing()
+	}
+	return err
+}
+
+func (wc *websocketCodec) streamWriter() io.WriteCloser {
+	if !wc.streaming {
+		return nil
+	}
+	return &wsStreamWriter{wc: wc}
+}
+
+// delayPing notifies pingLoop to delay the next idle ping.
+func (wc *websocketCodec) delayPing() {
+	select {
+	case wc.pingReset <- struct{}{}:
+	default:
+	}
+}
+
+// wsStreamWriter sends the bytes written to it in a single text message, frame by frame.
+type wsStreamWriter struct {
+	wc *websocketCodec
+	w  io.WriteCloser // nil until the first write
+}
+
+func (sw *wsStreamWriter) Write(p []byte) (int, error) {
+	if sw.w == nil {
+		sw.wc.jsonCodec.encMu.Lock()
+	}
+	// Each write has its own deadline: producing the message may take much longer than sending it
+	sw.wc.conn.SetWriteDeadline(time.Now().Add(defaultWriteTimeout)) //nolint:errcheck
+	if sw.w == nil {
+		w, err := sw.wc.conn.NextWriter(websocket.TextMessage)
+		if err != nil {
+			sw.wc.jsonCodec.encMu.Unlock()
+			return 0, err
 		}
+		sw.w = w
+	}
+	return sw.w.Write(p)
+}
+
+func (sw *wsStreamWriter) Close() error {
+	if sw.w == nil {
+		return nil
+	}
+	err := sw.w.Close()
+	sw.w = nil
+	sw.wc.jsonCodec.encMu.Unlock()
+	if err == nil {
+		sw.wc.delayPing()
 	}
 	return err
 }
@@ -112,6 +112,50 @@ func TestWebsocketLargeCall(t *testing.T) {
 	}
 }
 
+// This test checks that the responses of the streaming methods are sent whole, along with their errors, whether
+// they're streamed or buffered.
+func TestWebsocketStreaming(t *testing.T) {
+	t.Parallel()
+
+	for _, disableStreaming := range []bool{false, true} {
+		srv := NewServer(50, false /* traceRequests */, disableStreaming)
+		if err := srv.RegisterName("test", new(testService)); err != nil {
+			t.Fatal(err)
+		}
+		httpsrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}, nil, false))
+		wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+
+		client, err := DialWebsocket(context.Background(), wsURL, "")
+		if err != nil {
+			t.Fatalf("can't dial: %v", err)
+		}
+
+		var result []int
+		if err := client.Call(&result, "test_stream", 10000, false); err != nil {
+			t.Fatalf("streaming call failed: %v", err)
+		}
+		if len(result) != 10000 || result[9999] != 9999 {
+			t.Fatalf("wrong streamed result of length %d", len(result))
+		}
+		for _, n := range []int{0, 10} {
+			err := client.Call(&result, "test_stream", n, true)
+			if err == nil || err.Error() != "testError" {
+				t.Fatalf("wrong error for the streaming call failing after %d numbers: %v", n, err)
+			}
+		}
+
+		// The connection is still usable
+		var echo echoResult
+		if err := client.Call(&echo, "test_echo", "x", 1); err != nil || echo.String != "x" {
+			t.Fatalf("call after streaming failed: %v", err)
+		}
+
+		client.Close()
+		httpsrv.Close()
+		srv.Stop()
+	}
+}
+
 // This test checks that client handles WebSocket ping frames correctly.
 func TestClientWebsocketPing(t *testing.T) {
 	if runtime.GOOS == "windows" {
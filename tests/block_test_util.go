@@ -100,6 +100,13 @@ type btHeaderMarshaling struct {
 	BaseFee    *math.HexOrDecimal256
 }
 
+// Network returns the fork rules the blocks of the test are executed with.
+func (t *BlockTest) Network() string {
+	return t.json.Network
+}
+
+// Run imports the blocks of the test, and validates the chain and the post state. tst is nil when the test isn't
+// run by go test, e.g. by the evm blocktest command.
 func (t *BlockTest) Run(tst *testing.T, _ bool) error {
 	config, ok := Forks[t.json.Network]
 	if !ok {
@@ -112,6 +119,9 @@ func (t *BlockTest) Run(tst *testing.T, _ bool) error {
 		engine = ethash.NewShared()
 	}
 	m := stages.MockWithGenesisEngine(tst, t.genesis(config), engine)
+	if tst == nil {
+		defer m.Close()
+	}
 
 	// import pre accounts & construct test genesis block & state root
 	if m.Genesis.Hash() != t.json.Genesis.Hash {
@@ -195,6 +195,21 @@ var Forks = map[string]*params.ChainConfig{
 		LondonBlock:           big.NewInt(0),
 		ArrowGlacierBlock:     big.NewInt(0),
 	},
+	"EOF": {
+		ChainID:               big.NewInt(1),
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		BerlinBlock:           big.NewInt(0),
+		LondonBlock:           big.NewInt(0),
+		ArrowGlacierBlock:     big.NewInt(0),
+		EOFBlock:              big.NewInt(0),
+	},
 }
 
 // Returns the set of defined fork names
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/cmd/hack/tool"
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/ethconsensusconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/internal/debug"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+)
+
+var backfillCommand = cli.Command{
+	Name:   "backfill",
+	Action: doBackfill,
+	Usage:  "Write again receipts, logs index, history and call traces of blocks deleted by pruning. Erigon must be stopped",
+	Description: `Blocks are re-executed from genesis on a temporary copy of the state in <datadir>/temp: it takes
+as long as the execution of blocks up to --to, and as much disk space as the state.
+Pruning of the backfilled data is changed to keep the blocks, if it would delete them again.`,
+	Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+	Flags: append([]cli.Flag{
+		utils.DataDirFlag,
+		SnapshotFromFlag,
+		BackfillToFlag,
+		BackfillDataFlag,
+	}, debug.Flags...),
+}
+
+var (
+	BackfillToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "To block number. Zero - means the last executed block",
+	}
+	BackfillDataFlag = cli.StringFlag{
+		Name: "data",
+		Usage: `Data to write again, like --prune flag:
+	h - history (ChangeSets, HistoryIndices)
+	r - receipts (Receipts, Logs, LogTopicIndex, LogAddressIndex)
+	c - call traces (CallFromIndex, CallToIndex)`,
+		Value: "hrc",
+	}
+)
+
+func doBackfill(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	data := cliCtx.String(BackfillDataFlag.Name)
+	kinds := stagedsync.BackfillKinds{
+		History:    strings.Contains(data, "h"),
+		Receipts:   strings.Contains(data, "r"),
+		CallTraces: strings.Contains(data, "c"),
+	}
+
+	db := mdbx.NewMDBX(log.New()).Label(kv.ChainDB).Path(dirs.Chaindata).Exclusive().MustOpen()
+	defer db.Close()
+	to := cliCtx.Uint64(BackfillToFlag.Name)
+	if to == 0 {
+		if err := db.View(ctx, func(tx kv.Tx) (err error) {
+			to, err = stages.GetStageProgress(tx, stages.Execution)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return backfill(ctx, dirs, db, cliCtx.Uint64(SnapshotFromFlag.Name), to, kinds)
+}
+
+// backfill - write again data of blocks from..to deleted by pruning, see stagedsync.Backfill
+func backfill(ctx context.Context, dirs datadir.Dirs, db kv.RwDB, from, to uint64, kinds stagedsync.BackfillKinds) error {
+	chainConfig := tool.ChainConfigFromDB(db)
+	genesis := core.DefaultGenesisBlockByChainName(chainConfig.ChainName)
+	if genesis == nil {
+		return fmt.Errorf("backfill needs the genesis of the chain, unknown chain %q", chainConfig.ChainName)
+	}
+
+	var pm prune.Mode
+	var snapshotsEnabled bool
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		if pm, err = prune.Get(tx); err != nil {
+			return err
+		}
+		snapshotsEnabled, err = snap.Enabled(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	var blockReader services.FullBlockReader = snapshotsync.NewBlockReader()
+	var snapshots *snapshotsync.RoSnapshots
+	if snapshotsEnabled {
+		snapshots = snapshotsync.NewRoSnapshots(ethconfig.NewSnapCfg(true, true, true), dirs.Snap)
+		if err := snapshots.Reopen(); err != nil {
+			return err
+		}
+		defer snapshots.Close()
+		blockReader = snapshotsync.NewBlockReaderWithSnapshots(snapshots)
+	}
+	engine := backfillEngine(chainConfig, dirs, snapshots)
+	defer engine.Close()
+
+	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, ethconfig.Defaults.BatchSize, 1, false, false, nil, nil, chainConfig, engine, &vm.Config{}, nil,
+		/*stateStream=*/ false,
+		/*badBlockHalt=*/ true, dirs.Tmp, blockReader, nil)
+	return stagedsync.Backfill(ctx, cfg, genesis, from, to, kinds)
+}
+
+func backfillEngine(chainConfig *params.ChainConfig, dirs datadir.Dirs, snapshots *snapshotsync.RoSnapshots) consensus.Engine {
+	config := ethconfig.Defaults
+	var consensusConfig interface{}
+	if chainConfig.Clique != nil {
+		c := params.CliqueSnapshot
+		c.DBPath = filepath.Join(dirs.DataDir, "clique", "db")
+		consensusConfig = c
+	} else if chainConfig.Aura != nil {
+		consensusConfig = &params.AuRaConfig{DBPath: filepath.Join(dirs.DataDir, "aura")}
+	} else if chainConfig.Parlia != nil {
+		consensusConfig = &params.ParliaConfig{DBPath: filepath.Join(dirs.DataDir, "parlia")}
+	} else if chainConfig.Bor != nil {
+		consensusConfig = &config.Bor
+	} else {
+		consensusConfig = &config.Ethash
+	}
+	return ethconsensusconfig.CreateConsensusEngine(chainConfig, log.New(), consensusConfig, config.Miner.Notify, config.Miner.Noverify, "", true, dirs.DataDir, snapshots)
+}
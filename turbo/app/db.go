@@ -0,0 +1,583 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	mdbx2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/dbstats"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/internal/debug"
+	"github.com/ledgerwatch/erigon/node"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	erigoncli "github.com/ledgerwatch/erigon/turbo/cli"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/torquem-ch/mdbx-go/mdbx"
+	"github.com/urfave/cli"
+)
+
+var dbCommand = cli.Command{
+	Name:        "db",
+	Description: `Managing database`,
+	Subcommands: []cli.Command{
+		{
+			Name:   "compact",
+			Action: doCompactCommand,
+			Usage:  "Rewrite chaindata to reclaim free pages (after pruning). Erigon must be stopped, unless --online",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: append([]cli.Flag{
+				utils.DataDirFlag,
+				utils.DbBackendFlag,
+				DbCompactOnlineFlag,
+				DbCompactTablesFlag,
+			}, debug.Flags...),
+		},
+		{
+			Name:   "stats",
+			Action: doStatsCommand,
+			Usage:  "Print statistics of chaindata tables, with their growth since the previous run",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: append([]cli.Flag{
+				utils.DataDirFlag,
+				DbStatsUtilizationFlag,
+			}, debug.Flags...),
+		},
+		{
+			Name:   "convert",
+			Action: doConvertCommand,
+			Usage:  "Change prune mode of chaindata without resync: pruned data is dropped by copying only kept data, deleted data is written again by re-executing blocks. Erigon must be stopped",
+			Description: `Data pruned more by the new mode is dropped by copying chaindata without it (needs free space for the copy).
+Data pruned less is written again like by 'erigon backfill', and tx index is rebuilt by the next start.`,
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: append([]cli.Flag{
+				utils.DataDirFlag,
+				utils.DbBackendFlag,
+				erigoncli.PruneFlag,
+				erigoncli.PruneHistoryFlag,
+				erigoncli.PruneReceiptFlag,
+				erigoncli.PruneTxIndexFlag,
+				erigoncli.PruneCallTracesFlag,
+				erigoncli.PruneHistoryBeforeFlag,
+				erigoncli.PruneReceiptBeforeFlag,
+				erigoncli.PruneTxIndexBeforeFlag,
+				erigoncli.PruneCallTracesBeforeFlag,
+			}, debug.Flags...),
+		},
+	},
+}
+
+var (
+	DbCompactOnlineFlag = cli.BoolFlag{
+		Name:  "online",
+		Usage: "Prepare compacted copy while Erigon is running. Tables changed after the copy are re-copied by the next (offline) run, which replaces chaindata",
+	}
+	DbCompactTablesFlag = cli.StringFlag{
+		Name:  "tables",
+		Usage: "Comma separated list of tables to compact in this run (default: all). Chaindata is replaced when all tables are compacted",
+	}
+	DbStatsUtilizationFlag = cli.BoolFlag{
+		Name:  "utilization",
+		Usage: "Read all tables to count which share of their pages is filled by data (slow)",
+	}
+)
+
+// compactProgressFile - in compacted copy, tables already copied: table name -> id of txn which modified the table last
+const compactProgressFile = "compact.json"
+
+func doCompactCommand(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	online := cliCtx.Bool(DbCompactOnlineFlag.Name)
+	tables := utils.SplitAndTrim(cliCtx.String(DbCompactTablesFlag.Name))
+	to := dirs.Chaindata + "-compact"
+
+	done, err := compactDB(ctx, cliCtx.String(utils.DbBackendFlag.Name), dirs.Chaindata, to, tables, online, nil)
+	if err != nil {
+		return err
+	}
+	if !done {
+		log.Info("[db] Not all tables are compacted yet, run the command again to compact the rest", "copy", to)
+		return nil
+	}
+	if online {
+		log.Info("[db] Compacted copy is ready, stop Erigon and run `erigon db compact` to re-copy changed tables and replace chaindata", "copy", to)
+		return nil
+	}
+	return replaceChaindata(dirs.Chaindata, to)
+}
+
+// compactDB - copy tables of db at path from to db at path to, table by table, with the entries passing their filter (if
+// any). Tables copied by previous runs and not modified since then are skipped. Returns true if all tables are copied
+// and the copy is verified: each table has as many entries as the source, or as passed its filter.
+func compactDB(ctx context.Context, backend, from, to string, tables []string, online bool, filters map[string]stagedsync.CopyFilter) (bool, error) {
+	src, dst, err := openCompactDBs(backend, from, to, online)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+	defer dst.Close()
+
+	progressPath := filepath.Join(to, compactProgressFile)
+	copied := map[string]uint64{}
+	if data, err := os.ReadFile(progressPath); err == nil {
+		if err := json.Unmarshal(data, &copied); err != nil {
+			return false, fmt.Errorf("%s: %w", progressPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	srcTx, err := src.BeginRo(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer srcTx.Rollback()
+
+	var all []string
+	for name, cfg := range src.AllBuckets() {
+		if !cfg.IsDeprecated {
+			all = append(all, name)
+		}
+	}
+	sort.Strings(all)
+	if len(tables) == 0 {
+		tables = all
+	}
+
+	var done = true
+	expected := map[string]uint64{} // table -> entries of the copy
+	for i, name := range all {
+		st, err := readTableStat(srcTx, name)
+		if err != nil {
+			return false, err
+		}
+		expected[name] = st.entries
+		if txID, ok := copied[name]; ok && txID == st.lastTxID {
+			continue
+		}
+		if !contains(tables, name) {
+			done = false
+			continue
+		}
+		log.Info(fmt.Sprintf("[db] Compacting table %d/%d", i+1, len(all)), "table", name, "entries", st.entries, "size", common.ByteCount(st.size))
+		kept, err := copyTable(ctx, srcTx, dst, name, st.entries, filters[name])
+		if err != nil {
+			return false, fmt.Errorf("table %s: %w", name, err)
+		}
+		expected[name] = kept
+		copied[name] = st.lastTxID
+		data, err := json.Marshal(copied)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(progressPath, data, 0644); err != nil {
+			return false, err
+		}
+	}
+	if !done {
+		return false, nil
+	}
+	if err := verifyCopy(ctx, dst, expected); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyCopy - check that the tables of the copy have the expected number of entries
+func verifyCopy(ctx context.Context, dst kv.RoDB, expected map[string]uint64) error {
+	return dst.View(ctx, func(tx kv.Tx) error {
+		for name, entries := range expected {
+			st, err := readTableStat(tx, name)
+			if err != nil {
+				return err
+			}
+			if st.entries != entries {
+				return fmt.Errorf("table %s: %d entries in the copy, expected %d", name, st.entries, entries)
+			}
+		}
+		return nil
+	})
+}
+
+// openCompactDBs - the db to compact, and its copy with the same page size
+func openCompactDBs(backend, from, to string, online bool) (src, dst kv.RwDB, err error) {
+	if backend != "" && backend != node.DefaultDBBackend {
+		if online {
+			return nil, nil, fmt.Errorf("--%s isn't supported by --%s=%s", DbCompactOnlineFlag.Name, utils.DbBackendFlag.Name, backend)
+		}
+		if src, err = node.OpenDBBackend(backend, log.New(), kv.ChainDB, from, node.DBOpenExclusive); err != nil {
+			return nil, nil, err
+		}
+		if dst, err = node.OpenDBBackend(backend, log.New(), kv.ChainDB, to, node.DBOpenDefault); err != nil {
+			src.Close()
+			return nil, nil, err
+		}
+		return src, dst, nil
+	}
+
+	srcOpts := mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(from)
+	if online {
+		srcOpts = srcOpts.Flags(func(flags uint) uint { return flags | mdbx.Readonly | mdbx.Accede })
+	} else {
+		srcOpts = srcOpts.Exclusive()
+	}
+	if src, err = srcOpts.Open(); err != nil {
+		return nil, nil, err
+	}
+	stat, err := src.(*mdbx2.MdbxKV).Env().Stat()
+	if err != nil {
+		src.Close()
+		return nil, nil, err
+	}
+	if dst, err = mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(to).PageSize(uint64(stat.PSize)).MapSize(8 * datasize.TB).Open(); err != nil {
+		src.Close()
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+type tableStat struct {
+	lastTxID uint64 // of the last change of the table
+	entries  uint64
+	size     uint64
+}
+
+func readTableStat(tx kv.Tx, name string) (tableStat, error) {
+	if mdbxTx, ok := tx.(*mdbx2.MdbxTx); ok {
+		st, err := mdbxTx.BucketStat(name)
+		if err != nil {
+			return tableStat{}, err
+		}
+		return tableStat{lastTxID: st.LastTxId, entries: st.Entries, size: (st.LeafPages + st.BranchPages + st.OverflowPages) * uint64(st.PSize)}, nil
+	}
+	// the other engines don't track the changes of the tables: the tables are copied again after any change of the db
+	c, err := tx.Cursor(name)
+	if err != nil {
+		return tableStat{}, err
+	}
+	defer c.Close()
+	entries, err := c.Count()
+	if err != nil {
+		return tableStat{}, err
+	}
+	size, err := tx.BucketSize(name)
+	if err != nil {
+		return tableStat{}, err
+	}
+	return tableStat{lastTxID: tx.ViewID(), entries: entries, size: size}, nil
+}
+
+// copyTable - returns the number of the entries kept by the filter
+func copyTable(ctx context.Context, srcTx kv.Tx, dst kv.RwDB, name string, entries uint64, filter stagedsync.CopyFilter) (kept uint64, err error) {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+	commitEvery := time.NewTicker(30 * time.Second)
+	defer commitEvery.Stop()
+
+	dstTx, err := dst.BeginRw(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { dstTx.Rollback() }()
+	if err := dstTx.ClearBucket(name); err != nil {
+		return 0, err
+	}
+	srcC, err := srcTx.Cursor(name)
+	if err != nil {
+		return 0, err
+	}
+	defer srcC.Close()
+	c, err := dstTx.RwCursor(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	for k, v, err := srcC.First(); k != nil; k, v, err = srcC.Next() {
+		if err != nil {
+			return 0, err
+		}
+		keep := true
+		if filter != nil {
+			if v, keep, err = filter(k, v); err != nil {
+				return 0, err
+			}
+		}
+		if casted, isDupsort := c.(kv.RwCursorDupSort); keep && isDupsort {
+			err = casted.AppendDup(k, v)
+		} else if keep {
+			err = c.Append(k, v)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if keep {
+			kept++
+		}
+		count++
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-logEvery.C:
+			log.Info("[db] Compacting", "table", name, "progress", fmt.Sprintf("%.2f%%", 100*float64(count)/float64(entries+1)), "key", fmt.Sprintf("%x", k))
+		case <-commitEvery.C:
+			if err := dstTx.Commit(); err != nil {
+				return 0, err
+			}
+			if dstTx, err = dst.BeginRw(ctx); err != nil {
+				return 0, err
+			}
+			if c, err = dstTx.RwCursor(name); err != nil {
+				return 0, err
+			}
+		default:
+		}
+	}
+	return kept, dstTx.Commit()
+}
+
+// replaceChaindata - move compacted copy, verified by compactDB, in place of chaindata. Old chaindata is removed only
+// after the move, and moved back if the move fails.
+func replaceChaindata(chaindata, compacted string) error {
+	sizeBefore, sizeAfter := dirSize(chaindata), dirSize(compacted)
+	if err := os.Remove(filepath.Join(compacted, compactProgressFile)); err != nil {
+		return err
+	}
+	old := chaindata + "-old"
+	if err := os.Rename(chaindata, old); err != nil {
+		return err
+	}
+	if err := os.Rename(compacted, chaindata); err != nil {
+		if restoreErr := os.Rename(old, chaindata); restoreErr != nil {
+			return fmt.Errorf("%w, and restoring %s failed: %v", err, chaindata, restoreErr)
+		}
+		return err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	log.Info("[db] Compacted", "size_before", common.ByteCount(sizeBefore), "size_after", common.ByteCount(sizeAfter))
+	return nil
+}
+
+func dirSize(dir string) (size uint64) {
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size
+}
+
+func doConvertCommand(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	backend := cliCtx.String(utils.DbBackendFlag.Name)
+	db, err := node.OpenDBBackend(backend, log.New(), kv.ChainDB, dirs.Chaindata, node.DBOpenExclusive)
+	if err != nil {
+		return err
+	}
+	var current prune.Mode
+	var head uint64
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		if current, err = prune.Get(tx); err != nil {
+			return err
+		}
+		head, err = stages.GetStageProgress(tx, stages.Execution)
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+	db.Close()
+
+	target, err := prune.FromCli(
+		cliCtx.String(erigoncli.PruneFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneHistoryFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneReceiptFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneTxIndexFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneCallTracesFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneHistoryBeforeFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneReceiptBeforeFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneTxIndexBeforeFlag.Name),
+		cliCtx.Uint64(erigoncli.PruneCallTracesBeforeFlag.Name),
+		nil, "",
+	)
+	if err != nil {
+		return err
+	}
+	target.Experiments, target.RegenerateReceipts = current.Experiments, current.RegenerateReceipts
+	log.Info("[db] Converting", "from", current.String(), "to", target.String(), "head", head)
+
+	// drop data pruned more by copying chaindata without it
+	if target.History.PruneTo(head) > current.History.PruneTo(head) ||
+		target.Receipts.PruneTo(head) > current.Receipts.PruneTo(head) ||
+		target.TxIndex.PruneTo(head) > current.TxIndex.PruneTo(head) ||
+		target.CallTraces.PruneTo(head) > current.CallTraces.PruneTo(head) {
+		to := dirs.Chaindata + "-convert"
+		if err := os.RemoveAll(to); err != nil { // copy of previous run may be filtered by another mode
+			return err
+		}
+		if _, err := compactDB(ctx, backend, dirs.Chaindata, to, nil, false, stagedsync.PruneCopyFilters(target, head)); err != nil {
+			return err
+		}
+		if err := replaceChaindata(dirs.Chaindata, to); err != nil {
+			return err
+		}
+	}
+
+	if db, err = node.OpenDBBackend(backend, log.New(), kv.ChainDB, dirs.Chaindata, node.DBOpenExclusive); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// write again data pruned less, by re-executing blocks
+	var kinds stagedsync.BackfillKinds
+	from, to := head, uint64(0)
+	less := func(t, c prune.BlockAmount) bool {
+		if t.PruneTo(head) >= c.PruneTo(head) {
+			return false
+		}
+		if t.PruneTo(head) < from {
+			from = t.PruneTo(head)
+		}
+		if c.PruneTo(head) > to {
+			to = c.PruneTo(head)
+		}
+		return true
+	}
+	kinds.History = less(target.History, current.History)
+	kinds.Receipts = less(target.Receipts, current.Receipts)
+	kinds.CallTraces = less(target.CallTraces, current.CallTraces)
+	if kinds.History || kinds.Receipts || kinds.CallTraces {
+		if err := backfill(ctx, dirs, db, from, to, kinds); err != nil {
+			return err
+		}
+	}
+
+	return db.Update(ctx, func(tx kv.RwTx) error {
+		if err := prune.Override(tx, target); err != nil {
+			return err
+		}
+		txIndexTo, currentTxIndexTo := target.TxIndex.PruneTo(head), current.TxIndex.PruneTo(head)
+		if txIndexTo > currentTxIndexTo {
+			return stages.SaveStagePruneProgress(tx, stages.TxLookup, txIndexTo)
+		}
+		if txIndexTo < currentTxIndexTo { // rebuilt by the next start
+			if err := stages.SaveStageProgress(tx, stages.TxLookup, 0); err != nil {
+				return err
+			}
+			return stages.SaveStagePruneProgress(tx, stages.TxLookup, 0)
+		}
+		return nil
+	})
+}
+
+// dbStatsFile - in datadir, sample of the previous `erigon db stats` run to measure growth
+const dbStatsFile = "dbstats.json"
+
+func doStatsCommand(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	db := mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(dirs.Chaindata).Readonly().MustOpen()
+	defer db.Close()
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, pageSize, err := dbstats.Collect(tx)
+	if err != nil {
+		return err
+	}
+	utilization := cliCtx.Bool(DbStatsUtilizationFlag.Name)
+	if utilization {
+		for i := range tables {
+			log.Info("[db] Scanning", "table", tables[i].Name)
+			if err := dbstats.Scan(ctx, tx, &tables[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	samplePath := filepath.Join(dirs.DataDir, dbStatsFile)
+	var prev dbstats.Sample
+	if data, err := os.ReadFile(samplePath); err == nil {
+		if err := json.Unmarshal(data, &prev); err != nil {
+			return fmt.Errorf("%s: %w", samplePath, err)
+		}
+		dbstats.SetGrowth(tables, prev, now)
+	}
+	data, err := json.Marshal(dbstats.NewSample(tables, now))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(samplePath, data, 0644); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "table\tentries\tsize\tdepth\tbranch pages\tleaf pages\toverflow pages\tutilization\tgrowth/day\t\n")
+	var total uint64
+	for _, t := range tables {
+		u, growth := "-", "-"
+		if utilization {
+			u = fmt.Sprintf("%.1f%%", 100*t.Utilization())
+		}
+		if !prev.Time.IsZero() {
+			growth = common.ByteCount(uint64(abs(t.GrowthPerDay)))
+			if t.GrowthPerDay < 0 {
+				growth = "-" + growth
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t\n", t.Name, t.Entries, common.ByteCount(t.Size), t.Depth,
+			t.BranchPages, t.LeafPages, t.OverflowPages, u, growth)
+		total += t.Size
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("page size: %s, tables total: %s, db file: %s\n", common.ByteCount(pageSize), common.ByteCount(total), common.ByteCount(dirSize(dirs.Chaindata)))
+	if !prev.Time.IsZero() {
+		fmt.Printf("growth since %s\n", prev.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, it := range list {
+		if it == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	mdbx2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+var compactTestTables = []string{kv.Headers, kv.AccountChangeSet, kv.Receipts}
+
+func newCompactTestDB(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "chaindata")
+	db := mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(path).MustOpen()
+	defer db.Close()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := uint64(0); i < 100; i++ {
+			if err := tx.Put(kv.Headers, dbutils.EncodeBlockNumber(i), []byte(fmt.Sprintf("header %d", i))); err != nil {
+				return err
+			}
+			if err := tx.Put(kv.Receipts, dbutils.EncodeBlockNumber(i), []byte(fmt.Sprintf("receipts %d", i))); err != nil {
+				return err
+			}
+			// several values of the dupsort table by key
+			if err := tx.Put(kv.AccountChangeSet, []byte{byte(i % 10)}, []byte(fmt.Sprintf("changes %d", i))); err != nil {
+				return err
+			}
+		}
+		// pages to reclaim
+		for i := uint64(0); i < 50; i++ {
+			if err := tx.Delete(kv.Headers, dbutils.EncodeBlockNumber(i), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return path
+}
+
+// readCompactTestDB - the entries of the tables, in order
+func readCompactTestDB(t *testing.T, path string) map[string][]string {
+	db := mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(path).Readonly().MustOpen()
+	defer db.Close()
+	entries := map[string][]string{}
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		for _, name := range compactTestTables {
+			if err := tx.ForEach(name, nil, func(k, v []byte) error {
+				entries[name] = append(entries[name], fmt.Sprintf("%x:%x", k, v))
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return entries
+}
+
+func TestCompactDB(t *testing.T) {
+	ctx := context.Background()
+	chaindata := newCompactTestDB(t)
+	before := readCompactTestDB(t, chaindata)
+	require.Len(t, before[kv.Headers], 50)
+	require.Len(t, before[kv.AccountChangeSet], 100)
+
+	to := chaindata + "-compact"
+	done, err := compactDB(ctx, "", chaindata, to, nil, false, nil)
+	require.NoError(t, err)
+	require.True(t, done)
+	require.NoError(t, replaceChaindata(chaindata, to))
+
+	require.Equal(t, before, readCompactTestDB(t, chaindata))
+	for _, dir := range []string{to, chaindata + "-old"} {
+		_, err = os.Stat(dir)
+		require.True(t, os.IsNotExist(err), dir)
+	}
+}
+
+func TestCompactDBVerify(t *testing.T) {
+	ctx := context.Background()
+	chaindata := newCompactTestDB(t)
+	to := chaindata + "-compact"
+	done, err := compactDB(ctx, "", chaindata, to, nil, false, nil)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	// the next run skips the unchanged tables, and finds the entry missing from the copy
+	db := mdbx2.NewMDBX(log.New()).Label(kv.ChainDB).Path(to).MustOpen()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Delete(kv.Headers, dbutils.EncodeBlockNumber(99), nil)
+	}))
+	db.Close()
+	_, err = compactDB(ctx, "", chaindata, to, nil, false, nil)
+	require.ErrorContains(t, err, "table "+kv.Headers)
+}
+
+func TestConvertCopy(t *testing.T) {
+	ctx := context.Background()
+	chaindata := newCompactTestDB(t)
+	before := readCompactTestDB(t, chaindata)
+
+	pm, err := prune.FromCli("r", 0, 0, 0, 0, 0, 60, 0, 0, nil, "")
+	require.NoError(t, err)
+	to := chaindata + "-convert"
+	done, err := compactDB(ctx, "", chaindata, to, nil, false, stagedsync.PruneCopyFilters(pm, 99))
+	require.NoError(t, err)
+	require.True(t, done)
+	require.NoError(t, replaceChaindata(chaindata, to))
+
+	after := readCompactTestDB(t, chaindata)
+	require.Equal(t, before[kv.Headers], after[kv.Headers])
+	require.Equal(t, before[kv.AccountChangeSet], after[kv.AccountChangeSet])
+	// the receipts of the blocks before 59 are dropped
+	require.Equal(t, before[kv.Receipts][59:], after[kv.Receipts])
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/anacrolix/torrent/metainfo"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/cmp"
@@ -19,17 +20,20 @@ import (
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloader"
 	"github.com/ledgerwatch/erigon/cmd/hack/tool"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
 	"github.com/ledgerwatch/log/v3"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/urfave/cli"
 )
 
@@ -49,6 +53,19 @@ var snapshotCommand = cli.Command{
 				SnapshotFromFlag,
 				SnapshotToFlag,
 				SnapshotSegmentSizeFlag,
+				SnapshotManifestFlag,
+			}, debug.Flags...),
+		},
+		{
+			Name:   "state",
+			Action: doStateSnapshotCommand,
+			Usage:  "Create flat state snapshot after the last block of given segment (default: the latest executed one)",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: append([]cli.Flag{
+				utils.DataDirFlag,
+				SnapshotToFlag,
+				SnapshotSegmentSizeFlag,
+				SnapshotManifestFlag,
 			}, debug.Flags...),
 		},
 		{
@@ -115,6 +132,10 @@ var (
 		Name:  "rebuild",
 		Usage: "Force rebuild",
 	}
+	SnapshotManifestFlag = cli.StringFlag{
+		Name:  "manifest",
+		Usage: "Create .torrent files of the snapshots and write their info hashes to this .toml file (format of https://github.com/ledgerwatch/erigon-snapshot)",
+	}
 )
 
 func doIndicesCommand(cliCtx *cli.Context) error {
@@ -296,6 +317,101 @@ func doSnapshotCommand(cliCtx *cli.Context) error {
 
 	if err := snapshotBlocks(ctx, chainDB, fromBlock, toBlock, segmentSize, dirs.Snap, dirs.Tmp); err != nil {
 		log.Error("Error", "err", err)
+		return nil
+	}
+	if manifest := cliCtx.String(SnapshotManifestFlag.Name); manifest != "" {
+		if err := snapshotsManifest(ctx, dirs.Snap, manifest); err != nil {
+			log.Error("Error", "err", err)
+		}
+	}
+	return nil
+}
+
+// snapshotsManifest creates the missing .torrent files of the snapshots, and writes the info hash of each of them
+// to the given file - which can then be embedded as the preverified snapshots of a private chain.
+func snapshotsManifest(ctx context.Context, snapDir, manifest string) error {
+	if err := downloader.BuildTorrentFilesIfNeed(ctx, snapDir); err != nil {
+		return err
+	}
+	files, err := downloader.AllTorrentPaths(snapDir)
+	if err != nil {
+		return err
+	}
+	res := map[string]string{}
+	for _, torrentFilePath := range files {
+		mi, err := metainfo.LoadFromFile(torrentFilePath)
+		if err != nil {
+			return err
+		}
+		info, err := mi.UnmarshalInfo()
+		if err != nil {
+			return err
+		}
+		res[info.Name] = mi.HashInfoBytes().String()
+	}
+	serialized, err := toml.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifest, serialized, 0644); err != nil {
+		return err
+	}
+	log.Info("Snapshots manifest written", "file", manifest, "torrents", len(res))
+	return nil
+}
+
+func doStateSnapshotCommand(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	to := cliCtx.Uint64(SnapshotToFlag.Name)
+	segmentSize := cliCtx.Uint64(SnapshotSegmentSizeFlag.Name)
+	if segmentSize < 1000 {
+		return fmt.Errorf("too small --segment.size %d", segmentSize)
+	}
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	dir.MustExist(dirs.Snap)
+	dir.MustExist(dirs.Tmp)
+
+	chainDB := mdbx.NewMDBX(log.New()).Label(kv.ChainDB).Path(dirs.Chaindata).Readonly().MustOpen()
+	defer chainDB.Close()
+
+	if err := chainDB.View(ctx, func(tx kv.Tx) error {
+		executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+		if err != nil {
+			return err
+		}
+		if to == 0 {
+			to = executionProgress + 1 - (executionProgress+1)%segmentSize
+		}
+		if to == 0 || to%segmentSize != 0 {
+			return fmt.Errorf("--to %d must be a non-zero multiple of --segment.size %d", to, segmentSize)
+		}
+		if to-1 > executionProgress {
+			return fmt.Errorf("block %d is not executed yet, execution is at block %d", to-1, executionProgress)
+		}
+		pm, err := prune.Get(tx)
+		if err != nil {
+			return err
+		}
+		prunedTo, err := pm.KeptFrom(tx, prune.KindHistory, executionProgress)
+		if err != nil {
+			return err
+		}
+		if to < prunedTo {
+			return fmt.Errorf("the state history of block %d is pruned, the node keeps it from block %d", to-1, prunedTo-1)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	workers := cmp.Max(1, runtime.GOMAXPROCS(-1)-1)
+	if err := snapshotsync.DumpState(ctx, chainDB, dirs.Snap, dirs.Tmp, to-segmentSize, to, workers, log.LvlInfo); err != nil {
+		return fmt.Errorf("DumpState: %w", err)
+	}
+	if manifest := cliCtx.String(SnapshotManifestFlag.Name); manifest != "" {
+		return snapshotsManifest(ctx, dirs.Snap, manifest)
 	}
 	return nil
 }
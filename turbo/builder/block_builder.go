@@ -3,54 +3,167 @@ package builder
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/log/v3"
 )
 
-type BlockBuilderFunc func(param *core.BlockBuilderParameters, interrupt *int32) (*types.Block, error)
+const (
+	// RecommitInterval is the pause between the rebuilds of a payload, which pick up the new transactions of the pool
+	RecommitInterval = 2 * time.Second
+	// BuildTimeout is how long a payload is being improved, a slot of the beacon chain
+	BuildTimeout = 12 * time.Second
+)
+
+type BlockBuilderFunc func(param *core.BlockBuilderParameters, interrupt *int32) (*types.BlockWithReceipts, error)
 
-// BlockBuilder wraps a goroutine that builds Proof-of-Stake payloads (PoS "mining")
+// BlockBuilder wraps a goroutine that builds Proof-of-Stake payloads (PoS "mining").
+// The payload is rebuilt every RecommitInterval until it's requested, the builder is stopped or
+// BuildTimeout expires, the most profitable one is kept.
 type BlockBuilder struct {
+	param       *core.BlockBuilderParameters
 	emptyHeader *types.Header
 	interrupt   int32
 	syncCond    *sync.Cond
 	block       *types.Block
+	fees        *uint256.Int
 	err         error
+	stopped     bool
+	done        bool
+	quit        chan struct{}
 }
 
 func NewBlockBuilder(build BlockBuilderFunc, param *core.BlockBuilderParameters, emptyHeader *types.Header) *BlockBuilder {
 	b := new(BlockBuilder)
+	b.param = param
 	b.emptyHeader = emptyHeader
 	b.syncCond = sync.NewCond(new(sync.Mutex))
+	b.quit = make(chan struct{})
+
+	go b.run(build, time.Now().Add(BuildTimeout))
 
-	go func() {
-		block, err := build(param, &b.interrupt)
+	return b
+}
 
+func (b *BlockBuilder) run(build BlockBuilderFunc, deadline time.Time) {
+	defer func() {
 		b.syncCond.L.Lock()
 		defer b.syncCond.L.Unlock()
-		b.block = block
-		b.err = err
+		b.done = true
 		b.syncCond.Broadcast()
 	}()
 
-	return b
+	for {
+		result, err := build(b.param, &b.interrupt)
+
+		b.syncCond.L.Lock()
+		if err == nil {
+			if fees := blockFees(result); b.block == nil || fees.Gt(b.fees) {
+				b.block, b.fees = result.Block, fees
+			}
+		} else if b.block == nil {
+			b.err = err
+		}
+		stopped := b.stopped
+		// a hurried build of the first payload doesn't interrupt the next ones
+		atomic.StoreInt32(&b.interrupt, 0)
+		b.syncCond.Broadcast()
+		b.syncCond.L.Unlock()
+
+		if err != nil {
+			// e.g. the head has moved, further payloads can't be built on the parent either
+			log.Debug("BlockBuilder", "err", err)
+			return
+		}
+		if stopped || time.Now().Add(RecommitInterval).After(deadline) {
+			return
+		}
+		select {
+		case <-b.quit:
+			return
+		case <-time.After(RecommitInterval):
+		}
+	}
+}
+
+// Block returns the most profitable payload built so far, waiting for the first one if needed
+// (which is then hurried up). No payload is rebuilt afterwards, the rebuild under way is still
+// completed and returned by the later calls if it pays more.
+func (b *BlockBuilder) Block() *types.Block {
+	b.syncCond.L.Lock()
+	defer b.syncCond.L.Unlock()
+	if b.block == nil && b.err == nil {
+		atomic.StoreInt32(&b.interrupt, 1)
+	}
+	for b.block == nil && b.err == nil && !b.done {
+		b.syncCond.Wait()
+	}
+	b.stop()
+	return b.result()
+}
+
+// Interrupt stops the building of new payloads without waiting for the current one.
+func (b *BlockBuilder) Interrupt() {
+	b.syncCond.L.Lock()
+	defer b.syncCond.L.Unlock()
+	if b.stop() {
+		atomic.StoreInt32(&b.interrupt, 1)
+	}
+}
+
+// stop ends the rebuilds, it's called with the lock held and returns false if they were already ended.
+func (b *BlockBuilder) stop() bool {
+	if b.stopped {
+		return false
+	}
+	b.stopped = true
+	close(b.quit)
+	return true
 }
 
+// Stop stops the building and returns the most profitable payload.
 func (b *BlockBuilder) Stop() *types.Block {
-	atomic.StoreInt32(&b.interrupt, 1)
+	b.Interrupt()
 
 	b.syncCond.L.Lock()
 	defer b.syncCond.L.Unlock()
-	for b.block == nil && b.err == nil {
+	for !b.done {
 		b.syncCond.Wait()
 	}
+	return b.result()
+}
+
+// Params returns the attributes of the payload.
+func (b *BlockBuilder) Params() *core.BlockBuilderParameters {
+	return b.param
+}
 
-	if b.err != nil {
-		log.Error("BlockBuilder", "err", b.err)
+func (b *BlockBuilder) result() *types.Block {
+	if b.block == nil {
+		if b.err != nil {
+			log.Error("BlockBuilder", "err", b.err)
+		}
 		return types.NewBlock(b.emptyHeader, nil, nil, nil)
 	}
-
 	return b.block
 }
+
+// blockFees returns the tips earned by the fee recipient of the block.
+func blockFees(result *types.BlockWithReceipts) *uint256.Int {
+	var baseFee *uint256.Int
+	if result.Block.BaseFee() != nil {
+		baseFee, _ = uint256.FromBig(result.Block.BaseFee())
+	}
+	fees := new(uint256.Int)
+	for i, txn := range result.Block.Transactions() {
+		if i >= len(result.Receipts) {
+			break
+		}
+		tip := txn.GetEffectiveGasTip(baseFee)
+		fees.Add(fees, new(uint256.Int).Mul(tip, uint256.NewInt(result.Receipts[i].GasUsed)))
+	}
+	return fees
+}
@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// blockWithTip builds a block with a single transaction paying the given tip for 21000 gas.
+func blockWithTip(header *types.Header, tip uint64) *types.BlockWithReceipts {
+	txn := types.NewTransaction(0, common.Address{}, uint256.NewInt(0), 21000, uint256.NewInt(tip), nil)
+	receipts := types.Receipts{{GasUsed: 21000}}
+	return &types.BlockWithReceipts{
+		Block:    types.NewBlock(header, []types.Transaction{txn}, nil, receipts),
+		Receipts: receipts,
+	}
+}
+
+func TestBlockBuilderKeepsMostProfitable(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1)}
+	tips := []uint64{2, 5, 3}
+	var builds int32
+	build := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.BlockWithReceipts, error) {
+		i := atomic.AddInt32(&builds, 1) - 1
+		if int(i) >= len(tips) {
+			return blockWithTip(header, 1), nil
+		}
+		return blockWithTip(header, tips[i]), nil
+	}
+
+	b := NewBlockBuilder(build, &core.BlockBuilderParameters{Timestamp: 1}, header)
+	// wait for the rebuilds which pay more and then less
+	time.Sleep(2*RecommitInterval + RecommitInterval/2)
+	best := b.Stop()
+	require.GreaterOrEqual(t, atomic.LoadInt32(&builds), int32(3))
+	require.Equal(t, uint256.NewInt(5), best.Transactions()[0].GetPrice())
+	require.Equal(t, best.Hash(), b.Block().Hash())
+}
+
+func TestBlockBuilderStopsWhenRequested(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1)}
+	var builds int32
+	build := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.BlockWithReceipts, error) {
+		return blockWithTip(header, uint64(atomic.AddInt32(&builds, 1))), nil
+	}
+
+	b := NewBlockBuilder(build, &core.BlockBuilderParameters{Timestamp: 1}, header)
+	first := b.Block()
+	require.Equal(t, uint256.NewInt(1), first.Transactions()[0].GetPrice())
+
+	// the requested payload isn't rebuilt
+	time.Sleep(RecommitInterval + RecommitInterval/2)
+	require.Equal(t, int32(1), atomic.LoadInt32(&builds))
+	require.Equal(t, first.Hash(), b.Stop().Hash())
+}
+
+func TestBlockBuilderFailure(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1)}
+	build := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.BlockWithReceipts, error) {
+		return nil, errors.New("parent is gone")
+	}
+
+	b := NewBlockBuilder(build, &core.BlockBuilderParameters{Timestamp: 1}, header)
+	block := b.Block()
+	require.Empty(t, block.Transactions())
+	require.Equal(t, header.Number, block.Number())
+	require.Empty(t, b.Stop().Transactions())
+}
@@ -7,13 +7,14 @@ import (
 )
 
 // DefaultFlags contains all flags that are used and supported by Erigon binary.
-var DefaultFlags = []cli.Flag{
+var DefaultFlags = append([]cli.Flag{
 	utils.DataDirFlag,
 	utils.EthashDatasetDirFlag,
 	utils.SnapshotFlag,
 	utils.TxPoolDisableFlag,
 	utils.TxPoolLocalsFlag,
 	utils.TxPoolNoLocalsFlag,
+	utils.TxPoolNoJournalFlag,
 	utils.TxPoolPriceLimitFlag,
 	utils.TxPoolPriceBumpFlag,
 	utils.TxPoolAccountSlotsFlag,
@@ -32,7 +33,18 @@ var DefaultFlags = []cli.Flag{
 	PruneReceiptBeforeFlag,
 	PruneTxIndexBeforeFlag,
 	PruneCallTracesBeforeFlag,
+	PruneHistorySizeFlag,
+	PruneReceiptSizeFlag,
+	PruneTxIndexSizeFlag,
+	PruneCallTracesSizeFlag,
+	PruneDryRunFlag,
+	ReceiptsModeFlag,
 	BatchSizeFlag,
+	ExecWorkersFlag,
+	ExecProfileFlag,
+	ExecCacheFlag,
+	CommitmentModeFlag,
+	StateBackendFlag,
 	BlockDownloaderWindowFlag,
 	DatabaseVerbosityFlag,
 	PrivateApiAddr,
@@ -44,6 +56,7 @@ var DefaultFlags = []cli.Flag{
 	TLSCACertFlag,
 	StateStreamDisableFlag,
 	SyncLoopThrottleFlag,
+	SyncBackfillFlag,
 	BadBlockFlag,
 
 	utils.HTTPEnabledFlag,
@@ -52,18 +65,28 @@ var DefaultFlags = []cli.Flag{
 	utils.EngineAddr,
 	utils.EnginePort,
 	utils.JWTSecretPath,
+	utils.JWTSecretRotationFlag,
 	utils.HttpCompressionFlag,
 	utils.HTTPCORSDomainFlag,
 	utils.HTTPVirtualHostsFlag,
 	utils.HTTPApiFlag,
 	utils.WSEnabledFlag,
 	utils.WsCompressionFlag,
+	utils.WsPendingTxsRateFlag,
+	utils.GraphQLEnabledFlag,
 	utils.HTTPTraceFlag,
 	utils.StateCacheFlag,
+	utils.StateCacheWarmupFlag,
 	utils.RpcBatchConcurrencyFlag,
+	utils.RpcBatchLimitFlag,
+	utils.RpcSlowThresholdFlag,
+	utils.HealthMaxBlocksBehindFlag,
+	utils.HealthMinPeersFlag,
+	utils.HealthEngineTimeoutFlag,
 	utils.RpcStreamingDisableFlag,
 	utils.DBReadConcurrencyFlag,
 	utils.RpcAccessListFlag,
+	utils.RpcRateLimitsFlag,
 	utils.RpcTraceCompatFlag,
 	utils.RpcGasCapFlag,
 	utils.StarknetGrpcAddressFlag,
@@ -71,6 +94,8 @@ var DefaultFlags = []cli.Flag{
 	utils.MemoryOverlayFlag,
 	utils.TxpoolApiAddrFlag,
 	utils.TraceMaxtracesFlag,
+	utils.TraceCacheSizeFlag,
+	utils.TraceCacheDirFlag,
 	HTTPReadTimeoutFlag,
 	HTTPWriteTimeoutFlag,
 	HTTPIdleTimeoutFlag,
@@ -81,10 +106,13 @@ var DefaultFlags = []cli.Flag{
 	utils.SnapKeepBlocksFlag,
 	utils.SnapStopFlag,
 	utils.DbPageSizeFlag,
+	utils.DbBackendFlag,
 	utils.TorrentPortFlag,
 	utils.TorrentMaxPeersFlag,
 	utils.TorrentConnsPerFileFlag,
 	utils.TorrentDownloadSlotsFlag,
+	utils.TorrentRateScheduleFlag,
+	utils.TorrentWebSeedsFlag,
 	utils.TorrentUploadRateFlag,
 	utils.TorrentDownloadRateFlag,
 	utils.TorrentVerbosityFlag,
@@ -100,13 +128,29 @@ var DefaultFlags = []cli.Flag{
 	utils.StaticPeersFlag,
 	utils.TrustedPeersFlag,
 	utils.MaxPeersFlag,
+	utils.PeerBanThresholdFlag,
+	utils.PeerBanTTLFlag,
+	utils.SnapServeFlag,
+	utils.SnapServeRateFlag,
+	utils.LightServeFlag,
+	utils.LightMaxPeersFlag,
+	utils.LightCapacityFlag,
 	utils.ChainFlag,
+	utils.ChainSpecFlag,
+	utils.ChainConfigFlag,
 	utils.DeveloperPeriodFlag,
+	utils.DeveloperManualFlag,
+	utils.ForkURLFlag,
+	utils.ForkDatadirFlag,
+	utils.ForkBlockFlag,
 	utils.VMEnableDebugFlag,
 	utils.NetworkIdFlag,
 	utils.FakePoWFlag,
 	utils.GpoBlocksFlag,
 	utils.GpoPercentileFlag,
+	utils.GpoMinGasPriceFlag,
+	utils.GpoMaxGasPriceFlag,
+	utils.GpoStrategyFlag,
 	utils.InsecureUnlockAllowedFlag,
 	utils.MetricsEnabledFlag,
 	utils.MetricsEnabledExpensiveFlag,
@@ -118,6 +162,7 @@ var DefaultFlags = []cli.Flag{
 	utils.CliqueSnapshotInmemorySignaturesFlag,
 	utils.CliqueDataDirFlag,
 	utils.EnabledIssuance,
+	utils.FirehoseFlag,
 	utils.MiningEnabledFlag,
 	utils.ProposingDisableFlag,
 	utils.MinerNotifyFlag,
@@ -131,10 +176,13 @@ var DefaultFlags = []cli.Flag{
 	utils.DownloaderAddrFlag,
 	utils.NoDownloaderFlag,
 	utils.DownloaderVerifyFlag,
+	utils.DownloaderVerifyIntervalFlag,
 	HealthCheckFlag,
 	utils.HeimdallURLFlag,
 	utils.WithoutHeimdallFlag,
 	utils.EthStatsURLFlag,
+	utils.LightClientBeaconAPIFlag,
+	utils.LightClientCheckpointFlag,
+	utils.LightClientCheckpointSyncURLFlag,
 	utils.OverrideTerminalTotalDifficulty,
-	utils.OverrideMergeNetsplitBlock,
-}
+}, utils.OverrideForkBlockFlags...)
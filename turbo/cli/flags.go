@@ -12,9 +12,11 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/health"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
@@ -34,6 +36,29 @@ var (
 		Usage: "Batch size for the execution stage",
 		Value: "256M",
 	}
+	ExecWorkersFlag = cli.IntFlag{
+		Name:  "exec.workers",
+		Usage: "Number of workers executing the transactions of a block in parallel (experimental), 1 executes them sequentially",
+		Value: 1,
+	}
+	ExecCacheFlag = cli.BoolFlag{
+		Name:  "exec.cache",
+		Usage: "Cache the state read by the sequential execution of the blocks across the blocks, sized by --batchSize (experimental)",
+	}
+	CommitmentModeFlag = cli.StringFlag{
+		Name:  "commitment.mode",
+		Usage: "How the state commitment is computed: 'batch' - hashing the state changes after the execution, 'stream' - during the execution (experimental)",
+		Value: "batch",
+	}
+	ExecProfileFlag = cli.BoolFlag{
+		Name:  "exec.profile",
+		Usage: "Profile the gas and the time of the opcodes, contracts and transactions executed by the execution stage (slows it down), served by debug_getExecutionProfile and the metrics",
+	}
+	StateBackendFlag = cli.StringFlag{
+		Name:  "state.backend",
+		Usage: "Storage engine of the state the blocks are executed against, registered by name (experimental)",
+		Value: state.PlainBackendName,
+	}
 	EtlBufferSizeFlag = cli.StringFlag{
 		Name:  "etl.bufferSize",
 		Usage: "Buffer size for ETL operations.",
@@ -103,13 +128,45 @@ var (
 		Usage: `Prune data before this block`,
 	}
 
+	// Size targets of pruned data
+	PruneHistorySizeFlag = cli.StringFlag{
+		Name:  "prune.h.size",
+		Usage: `Prune more old history when it's bigger than this size (like 200GB), requires pruning of history`,
+	}
+	PruneReceiptSizeFlag = cli.StringFlag{
+		Name:  "prune.r.size",
+		Usage: `Prune more old receipts when they're bigger than this size (like 200GB), requires pruning of receipts`,
+	}
+	PruneTxIndexSizeFlag = cli.StringFlag{
+		Name:  "prune.t.size",
+		Usage: `Prune more old tx index when it's bigger than this size (like 200GB), requires pruning of tx index`,
+	}
+	PruneCallTracesSizeFlag = cli.StringFlag{
+		Name:  "prune.c.size",
+		Usage: `Prune more old call traces when they're bigger than this size (like 200GB), requires pruning of call traces`,
+	}
+	PruneDryRunFlag = cli.BoolFlag{
+		Name:  "prune.dryrun",
+		Usage: `Don't delete anything, only log what pruning would delete`,
+	}
+
 	ExperimentsFlag = cli.StringFlag{
 		Name: "experiments",
 		Usage: `Enable some experimental stages:
-* tevm - write TEVM translated code to the DB`,
+* tevm - write TEVM translated code to the DB
+* stemtree - compute a tree of the state besides the trie during the execution (in memory), keyed like the verkle proposal but hashed with Keccak, not a verkle commitment
+* tokens - index the transfers of ERC-20 and ERC-721 tokens, for erigon_getTokenBalance and erigon_getTokenTransfers`,
 		Value: "default",
 	}
 
+	ReceiptsModeFlag = cli.StringFlag{
+		Name: "receipts.mode",
+		Usage: `How to keep the receipts of the executed blocks:
+* persist - store them along with their logs
+* regenerate - store only their logs, and regenerate the receipts by re-executing their block when requested
+Without it, the mode of the database is kept (persist for a new one)`,
+	}
+
 	// mTLS flags
 	TLSFlag = cli.BoolFlag{
 		Name:  "tls",
@@ -142,6 +199,11 @@ var (
 		Value: "",
 	}
 
+	SyncBackfillFlag = cli.BoolFlag{
+		Name:  "sync.backfill",
+		Usage: "Follow the head given by the consensus layer only, downloading the headers backwards from it instead of forwards from the local head (needs a chain with a terminal total difficulty)",
+	}
+
 	BadBlockFlag = cli.StringFlag{
 		Name:  "bad.block",
 		Usage: "Marks block with given hex string as bad and forces initial reorg before normal staged sync",
@@ -198,17 +260,32 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		ctx.GlobalUint64(PruneTxIndexBeforeFlag.Name),
 		ctx.GlobalUint64(PruneCallTracesBeforeFlag.Name),
 		strings.Split(ctx.GlobalString(ExperimentsFlag.Name), ","),
+		ctx.GlobalString(ReceiptsModeFlag.Name),
 	)
 	if err != nil {
 		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 	}
-	cfg.Prune = mode
+	sizes, err := prune.SizeTargetsFromCli(
+		ctx.GlobalString(PruneHistorySizeFlag.Name),
+		ctx.GlobalString(PruneReceiptSizeFlag.Name),
+		ctx.GlobalString(PruneTxIndexSizeFlag.Name),
+		ctx.GlobalString(PruneCallTracesSizeFlag.Name),
+	)
+	if err != nil {
+		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
+	}
+	cfg.Prune = mode.WithSizeTargets(sizes, ctx.GlobalBool(PruneDryRunFlag.Name))
 	if ctx.GlobalString(BatchSizeFlag.Name) != "" {
 		err := cfg.BatchSize.UnmarshalText([]byte(ctx.GlobalString(BatchSizeFlag.Name)))
 		if err != nil {
 			utils.Fatalf("Invalid batchSize provided: %v", err)
 		}
 	}
+	cfg.ExecWorkers = ctx.GlobalInt(ExecWorkersFlag.Name)
+	cfg.ExecProfile = ctx.GlobalBool(ExecProfileFlag.Name)
+	cfg.ExecCache = ctx.GlobalBool(ExecCacheFlag.Name)
+	cfg.StreamCommitment = streamCommitment(ctx.GlobalString(CommitmentModeFlag.Name))
+	cfg.StateBackend = stateBackend(ctx.GlobalString(StateBackendFlag.Name))
 
 	if ctx.GlobalString(EtlBufferSizeFlag.Name) != "" {
 		sizeVal := datasize.ByteSize(0)
@@ -230,6 +307,7 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		}
 		cfg.Sync.LoopThrottle = syncLoopThrottle
 	}
+	cfg.Sync.BackfillFromTip = ctx.GlobalBool(SyncBackfillFlag.Name)
 
 	if ctx.GlobalString(BadBlockFlag.Name) != "" {
 		bytes, err := hexutil.Decode(ctx.GlobalString(BadBlockFlag.Name))
@@ -248,6 +326,10 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 		if exp := f.StringSlice(ExperimentsFlag.Name, nil, ExperimentsFlag.Usage); exp != nil {
 			experiments = *exp
 		}
+		var receiptsMode string
+		if v := f.String(ReceiptsModeFlag.Name, ReceiptsModeFlag.Value, ReceiptsModeFlag.Usage); v != nil {
+			receiptsMode = *v
+		}
 		var exactH, exactR, exactT, exactC uint64
 		if v := f.Uint64(PruneHistoryFlag.Name, PruneHistoryFlag.Value, PruneHistoryFlag.Usage); v != nil {
 			exactH = *v
@@ -276,7 +358,7 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 			beforeC = *v
 		}
 
-		mode, err := prune.FromCli(*v, exactH, exactR, exactT, exactC, beforeH, beforeR, beforeT, beforeC, experiments)
+		mode, err := prune.FromCli(*v, exactH, exactR, exactT, exactC, beforeH, beforeR, beforeT, beforeC, experiments, receiptsMode)
 		if err != nil {
 			utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 		}
@@ -288,6 +370,21 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 			utils.Fatalf("Invalid batchSize provided: %v", err)
 		}
 	}
+	if v := f.Int(ExecWorkersFlag.Name, ExecWorkersFlag.Value, ExecWorkersFlag.Usage); v != nil {
+		cfg.ExecWorkers = *v
+	}
+	if v := f.Bool(ExecProfileFlag.Name, false, ExecProfileFlag.Usage); v != nil {
+		cfg.ExecProfile = *v
+	}
+	if v := f.Bool(ExecCacheFlag.Name, false, ExecCacheFlag.Usage); v != nil {
+		cfg.ExecCache = *v
+	}
+	if v := f.String(CommitmentModeFlag.Name, CommitmentModeFlag.Value, CommitmentModeFlag.Usage); v != nil {
+		cfg.StreamCommitment = streamCommitment(*v)
+	}
+	if v := f.String(StateBackendFlag.Name, StateBackendFlag.Value, StateBackendFlag.Usage); v != nil {
+		cfg.StateBackend = stateBackend(*v)
+	}
 	if v := f.String(EtlBufferSizeFlag.Name, EtlBufferSizeFlag.Value, EtlBufferSizeFlag.Usage); v != nil {
 		sizeVal := datasize.ByteSize(0)
 		size := &sizeVal
@@ -304,6 +401,25 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 	}
 }
 
+func streamCommitment(mode string) bool {
+	switch mode {
+	case "batch":
+		return false
+	case "stream":
+		return true
+	default:
+		utils.Fatalf("Invalid commitment.mode provided: %s, expected 'batch' or 'stream'", mode)
+		return false
+	}
+}
+
+func stateBackend(name string) string {
+	if _, ok := state.LookupBackend(name); !ok {
+		utils.Fatalf("Invalid state.backend provided: %s, registered: %s", name, strings.Join(state.Backends(), ", "))
+	}
+	return name
+}
+
 func ApplyFlagsForNodeConfig(ctx *cli.Context, cfg *nodecfg.Config) {
 	setPrivateApi(ctx, cfg)
 	setEmbeddedRpcDaemon(ctx, cfg)
@@ -323,15 +439,16 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		TLSCACert:   cfg.TLSCACert,
 		TLSCertfile: cfg.TLSCertFile,
 
-		HttpListenAddress:       ctx.GlobalString(utils.HTTPListenAddrFlag.Name),
-		HttpPort:                ctx.GlobalInt(utils.HTTPPortFlag.Name),
-		EngineHTTPListenAddress: ctx.GlobalString(utils.EngineAddr.Name),
-		EnginePort:              ctx.GlobalInt(utils.EnginePort.Name),
-		JWTSecretPath:           jwtSecretPath,
-		TraceRequests:           ctx.GlobalBool(utils.HTTPTraceFlag.Name),
-		HttpCORSDomain:          strings.Split(ctx.GlobalString(utils.HTTPCORSDomainFlag.Name), ","),
-		HttpVirtualHost:         strings.Split(ctx.GlobalString(utils.HTTPVirtualHostsFlag.Name), ","),
-		API:                     strings.Split(ctx.GlobalString(utils.HTTPApiFlag.Name), ","),
+		HttpListenAddress:         ctx.GlobalString(utils.HTTPListenAddrFlag.Name),
+		HttpPort:                  ctx.GlobalInt(utils.HTTPPortFlag.Name),
+		EngineHTTPListenAddresses: strings.Split(ctx.GlobalString(utils.EngineAddr.Name), ","),
+		EnginePort:                ctx.GlobalInt(utils.EnginePort.Name),
+		JWTSecretPath:             jwtSecretPath,
+		JWTSecretRotation:         ctx.GlobalDuration(utils.JWTSecretRotationFlag.Name),
+		TraceRequests:             ctx.GlobalBool(utils.HTTPTraceFlag.Name),
+		HttpCORSDomain:            strings.Split(ctx.GlobalString(utils.HTTPCORSDomainFlag.Name), ","),
+		HttpVirtualHost:           strings.Split(ctx.GlobalString(utils.HTTPVirtualHostsFlag.Name), ","),
+		API:                       strings.Split(ctx.GlobalString(utils.HTTPApiFlag.Name), ","),
 		HTTPTimeouts: rpccfg.HTTPTimeouts{
 			ReadTimeout:  ctx.GlobalDuration(HTTPReadTimeoutFlag.Name),
 			WriteTimeout: ctx.GlobalDuration(HTTPWriteTimeoutFlag.Name),
@@ -343,21 +460,32 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 			IdleTimeout:  ctx.GlobalDuration(HTTPIdleTimeoutFlag.Name),
 		},
 
-		WebsocketEnabled:     ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
-		RpcBatchConcurrency:  ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
-		RpcStreamingDisable:  ctx.GlobalBool(utils.RpcStreamingDisableFlag.Name),
-		DBReadConcurrency:    ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
-		RpcAllowListFilePath: ctx.GlobalString(utils.RpcAccessListFlag.Name),
-		Gascap:               ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
-		MaxTraces:            ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
-		TraceCompatibility:   ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
-		StarknetGRPCAddress:  ctx.GlobalString(utils.StarknetGrpcAddressFlag.Name),
-		TevmEnabled:          ctx.GlobalBool(utils.TevmFlag.Name),
+		WebsocketEnabled:      ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
+		RpcBatchConcurrency:   ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
+		RpcBatchLimit:         ctx.GlobalInt(utils.RpcBatchLimitFlag.Name),
+		RpcSlowThreshold:      ctx.GlobalDuration(utils.RpcSlowThresholdFlag.Name),
+		RpcStreamingDisable:   ctx.GlobalBool(utils.RpcStreamingDisableFlag.Name),
+		DBReadConcurrency:     ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
+		RpcAllowListFilePath:  ctx.GlobalString(utils.RpcAccessListFlag.Name),
+		RpcRateLimitsFilePath: ctx.GlobalString(utils.RpcRateLimitsFlag.Name),
+		Gascap:                ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
+		MaxTraces:             ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
+		TraceCompatibility:    ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
+		StarknetGRPCAddress:   ctx.GlobalString(utils.StarknetGrpcAddressFlag.Name),
+		TevmEnabled:           ctx.GlobalBool(utils.TevmFlag.Name),
 
 		TxPoolApiAddr: ctx.GlobalString(utils.TxpoolApiAddrFlag.Name),
 
+		Health: health.Criteria{
+			MaxBlocksBehind: ctx.GlobalUint64(utils.HealthMaxBlocksBehindFlag.Name),
+			MinPeers:        ctx.GlobalUint(utils.HealthMinPeersFlag.Name),
+			EngineTimeout:   ctx.GlobalDuration(utils.HealthEngineTimeoutFlag.Name),
+		},
+
 		StateCache: kvcache.DefaultCoherentConfig,
+		GPO:        ethconfig.Defaults.GPO,
 	}
+	utils.SetGPO(ctx, &c.GPO)
 	if ctx.GlobalIsSet(utils.HttpCompressionFlag.Name) {
 		c.HttpCompression = ctx.GlobalBool(utils.HttpCompressionFlag.Name)
 	} else {
@@ -368,8 +496,16 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 	} else {
 		c.WebsocketCompression = true
 	}
+	c.WebsocketPendingTxsRate = ctx.GlobalInt(utils.WsPendingTxsRateFlag.Name)
+	c.GraphQLEnabled = ctx.GlobalBool(utils.GraphQLEnabledFlag.Name)
+
+	if err := c.TraceCacheSize.UnmarshalText([]byte(ctx.GlobalString(utils.TraceCacheSizeFlag.Name))); err != nil {
+		utils.Fatalf("Invalid --%s: %v", utils.TraceCacheSizeFlag.Name, err)
+	}
+	c.TraceCacheDir = ctx.GlobalString(utils.TraceCacheDirFlag.Name)
 
 	c.StateCache.CodeKeysLimit = ctx.GlobalInt(utils.StateCacheFlag.Name)
+	c.StateCacheWarmupBlocks = ctx.GlobalUint64(utils.StateCacheWarmupFlag.Name)
 
 	/*
 		rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
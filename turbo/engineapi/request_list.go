@@ -8,12 +8,14 @@ import (
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // The message we are going to send to the stage sync in NewPayload
 type PayloadMessage struct {
-	Header *types.Header
-	Body   *types.RawBody
+	Header      *types.Header
+	Body        *types.RawBody
+	SpanContext trace.SpanContext // of the request, the stages handling it are traced within it
 }
 
 // The message we are going to send to the stage sync in ForkchoiceUpdated
@@ -21,6 +23,7 @@ type ForkChoiceMessage struct {
 	HeadBlockHash      common.Hash
 	SafeBlockHash      common.Hash
 	FinalizedBlockHash common.Hash
+	SpanContext        trace.SpanContext // of the request, the stages handling it are traced within it
 }
 
 type RequestStatus int
@@ -0,0 +1,152 @@
+package lightclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// maxUpdatesPerRequest is the maximum number of the periods requested at once
+const maxUpdatesPerRequest = 128
+
+// beaconAPI is a client of the light client endpoints of the beacon API, https://ethereum.github.io/beacon-APIs/.
+// The data isn't trusted, it's verified by the store.
+type beaconAPI struct {
+	url    string
+	client *http.Client
+}
+
+func newBeaconAPI(url string) *beaconAPI {
+	return &beaconAPI{url: strings.TrimSuffix(url, "/"), client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// dataResponse is the envelope of the responses of the beacon API
+type dataResponse struct {
+	Version string          `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (a *beaconAPI) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (a *beaconAPI) getData(ctx context.Context, path string, result interface{}) (string, error) {
+	var resp dataResponse
+	if err := a.get(ctx, path, &resp); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(resp.Data, result); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return resp.Version, nil
+}
+
+// headerResponse is the header of a block by the headers endpoint
+type headerResponse struct {
+	Root   common.Hash             `json:"root"`
+	Header SignedBeaconBlockHeader `json:"header"`
+}
+
+// finalizedHeader is the header of the finalized checkpoint block by the beacon node, trusted for the checkpoint sync
+func (a *beaconAPI) finalizedHeader(ctx context.Context) (*BeaconBlockHeader, error) {
+	var resp headerResponse
+	if _, err := a.getData(ctx, "/eth/v1/beacon/headers/finalized", &resp); err != nil {
+		return nil, err
+	}
+	if root := resp.Header.Message.HashTreeRoot(); root != resp.Root {
+		return nil, fmt.Errorf("finalized header: root %x, header root %x", resp.Root, root)
+	}
+	return &resp.Header.Message, nil
+}
+
+func (a *beaconAPI) bootstrap(ctx context.Context, root common.Hash) (*LightClientBootstrap, error) {
+	var bootstrap LightClientBootstrap
+	if _, err := a.getData(ctx, fmt.Sprintf("/eth/v1/beacon/light_client/bootstrap/%#x", root), &bootstrap); err != nil {
+		return nil, err
+	}
+	return &bootstrap, nil
+}
+
+// updates are the best updates of the periods, in order
+func (a *beaconAPI) updates(ctx context.Context, startPeriod, count uint64) ([]*LightClientUpdate, error) {
+	path := fmt.Sprintf("/eth/v1/beacon/light_client/updates?start_period=%d&count=%d", startPeriod, count)
+	var raw json.RawMessage
+	if err := a.get(ctx, path, &raw); err != nil {
+		return nil, err
+	}
+	// the updates are a list of envelopes, or the list of the data of a single envelope by the older servers
+	var envelopes []dataResponse
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &envelopes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else {
+		var envelope dataResponse
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var data []json.RawMessage
+		if err := json.Unmarshal(envelope.Data, &data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, d := range data {
+			envelopes = append(envelopes, dataResponse{Version: envelope.Version, Data: d})
+		}
+	}
+	updates := make([]*LightClientUpdate, len(envelopes))
+	for i, envelope := range envelopes {
+		updates[i] = &LightClientUpdate{}
+		if err := json.Unmarshal(envelope.Data, updates[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return updates, nil
+}
+
+func (a *beaconAPI) finalityUpdate(ctx context.Context) (*LightClientUpdate, error) {
+	var update LightClientUpdate
+	if _, err := a.getData(ctx, "/eth/v1/beacon/light_client/finality_update", &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func (a *beaconAPI) optimisticUpdate(ctx context.Context) (*LightClientUpdate, error) {
+	var update LightClientUpdate
+	if _, err := a.getData(ctx, "/eth/v1/beacon/light_client/optimistic_update", &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func (a *beaconAPI) block(ctx context.Context, root common.Hash) (*SignedBeaconBlock, error) {
+	var block SignedBeaconBlock
+	if _, err := a.getData(ctx, fmt.Sprintf("/eth/v2/beacon/blocks/%#x", root), &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
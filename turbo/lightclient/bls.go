@@ -0,0 +1,62 @@
+package lightclient
+
+import (
+	"errors"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto/bls12381"
+)
+
+// blsDomain is the domain separation tag of the signatures of the beacon chain
+var blsDomain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// decodePubkeys decompresses and validates the pubkeys of the sync committee
+func decodePubkeys(committee *SyncCommittee) ([]*bls12381.PointG1, error) {
+	if len(committee.Pubkeys) != SyncCommitteeSize {
+		return nil, errors.New("invalid sync committee size")
+	}
+	g1 := bls12381.NewG1()
+	pubkeys := make([]*bls12381.PointG1, len(committee.Pubkeys))
+	decoded := make(map[string]*bls12381.PointG1) // the members may be repeated
+	for i, pubkey := range committee.Pubkeys {
+		if p, ok := decoded[string(pubkey)]; ok {
+			pubkeys[i] = p
+			continue
+		}
+		p, err := g1.FromCompressed(pubkey)
+		if err != nil {
+			return nil, err
+		}
+		if g1.IsZero(p) {
+			return nil, errors.New("infinity pubkey")
+		}
+		pubkeys[i], decoded[string(pubkey)] = p, p
+	}
+	return pubkeys, nil
+}
+
+// fastAggregateVerify checks the aggregate signature of the message by all the pubkeys
+func fastAggregateVerify(pubkeys []*bls12381.PointG1, msg common.Hash, signature []byte) bool {
+	if len(pubkeys) == 0 {
+		return false
+	}
+	g1 := bls12381.NewG1()
+	aggregate := g1.Zero()
+	for _, pubkey := range pubkeys {
+		g1.Add(aggregate, aggregate, pubkey)
+	}
+	g2 := bls12381.NewG2()
+	sig, err := g2.FromCompressed(signature)
+	if err != nil {
+		return false
+	}
+	h, err := g2.HashToCurve(msg[:], blsDomain)
+	if err != nil {
+		return false
+	}
+	// e(pubkey, H(msg)) == e(g1, signature)
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(aggregate, h)
+	engine.AddPairInv(engine.G1.One(), sig)
+	return engine.Check()
+}
@@ -0,0 +1,132 @@
+package lightclient
+
+import (
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/params/networkname"
+)
+
+const (
+	SlotsPerEpoch                = 32
+	EpochsPerSyncCommitteePeriod = 256
+	SyncCommitteeSize            = 512
+	SecondsPerSlot               = 12
+
+	minSyncCommitteeParticipants = 1
+
+	// weakSubjectivityPeriod is the age of the checkpoints the light client starts from, beyond which the sync
+	// committees could have been corrupted by the validators which have exited since. It's the conservative period
+	// of https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/weak-subjectivity.md for the size of the
+	// mainnet validator set.
+	weakSubjectivityPeriod = 3360 // epochs, about 2 weeks
+
+	// generalized indices of the beacon state, and the depths of their branches
+	finalizedRootIndex        = 105
+	finalizedRootDepth        = 6
+	currentSyncCommitteeIndex = 54
+	nextSyncCommitteeIndex    = 55
+	syncCommitteeDepth        = 5
+)
+
+var domainSyncCommittee = [4]byte{0x07, 0x00, 0x00, 0x00}
+
+// BeaconConfig are the parameters of the beacon chain of a network, which are needed to verify the sync committee
+// signatures, and to tell the current slot
+type BeaconConfig struct {
+	GenesisTime           uint64
+	GenesisValidatorsRoot common.Hash
+	GenesisForkVersion    [4]byte
+	AltairForkVersion     [4]byte
+	AltairForkEpoch       uint64
+	BellatrixForkVersion  [4]byte
+	BellatrixForkEpoch    uint64
+}
+
+var beaconConfigs = map[string]*BeaconConfig{
+	networkname.MainnetChainName: {
+		GenesisTime:           1606824023,
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		GenesisForkVersion:    [4]byte{0x00, 0x00, 0x00, 0x00},
+		AltairForkVersion:     [4]byte{0x01, 0x00, 0x00, 0x00},
+		AltairForkEpoch:       74240,
+		BellatrixForkVersion:  [4]byte{0x02, 0x00, 0x00, 0x00},
+		BellatrixForkEpoch:    144896,
+	},
+	networkname.GoerliChainName: {
+		GenesisTime:           1616508000,
+		GenesisValidatorsRoot: common.HexToHash("0x043db0d9a83813551ee2f33450d23797757d430911a9320530ad8a0eabc43efb"),
+		GenesisForkVersion:    [4]byte{0x00, 0x00, 0x10, 0x20},
+		AltairForkVersion:     [4]byte{0x01, 0x00, 0x10, 0x20},
+		AltairForkEpoch:       36660,
+		BellatrixForkVersion:  [4]byte{0x02, 0x00, 0x10, 0x20},
+		BellatrixForkEpoch:    112260,
+	},
+	networkname.SepoliaChainName: {
+		GenesisTime:           1655733600,
+		GenesisValidatorsRoot: common.HexToHash("0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		GenesisForkVersion:    [4]byte{0x90, 0x00, 0x00, 0x69},
+		AltairForkVersion:     [4]byte{0x90, 0x00, 0x00, 0x70},
+		AltairForkEpoch:       50,
+		BellatrixForkVersion:  [4]byte{0x90, 0x00, 0x00, 0x71},
+		BellatrixForkEpoch:    100,
+	},
+}
+
+// BeaconConfigByChainName returns the beacon chain of the network, or nil if it's unknown
+func BeaconConfigByChainName(chainName string) *BeaconConfig {
+	return beaconConfigs[chainName]
+}
+
+func (c *BeaconConfig) forkVersion(epoch uint64) [4]byte {
+	switch {
+	case epoch >= c.BellatrixForkEpoch:
+		return c.BellatrixForkVersion
+	case epoch >= c.AltairForkEpoch:
+		return c.AltairForkVersion
+	default:
+		return c.GenesisForkVersion
+	}
+}
+
+// forkDigest is the digest of the fork of the epoch, which identifies the network
+func (c *BeaconConfig) forkDigest(epoch uint64) [4]byte {
+	var digest [4]byte
+	root := c.forkDataRoot(c.forkVersion(epoch))
+	copy(digest[:], root[:4])
+	return digest
+}
+
+func (c *BeaconConfig) forkDataRoot(version [4]byte) common.Hash {
+	var v common.Hash
+	copy(v[:], version[:])
+	return containerRoot(v, c.GenesisValidatorsRoot)
+}
+
+// syncCommitteeDomain is the domain of the signatures of the sync committee in the slot
+func (c *BeaconConfig) syncCommitteeDomain(signatureSlot uint64) common.Hash {
+	// the signature is made in the slot after the attested block, by the fork version of that block
+	if signatureSlot > 0 {
+		signatureSlot--
+	}
+	var domain common.Hash
+	copy(domain[:4], domainSyncCommittee[:])
+	root := c.forkDataRoot(c.forkVersion(signatureSlot / SlotsPerEpoch))
+	copy(domain[4:], root[:28])
+	return domain
+}
+
+// slotAt is the slot at the unix time
+func (c *BeaconConfig) slotAt(time uint64) uint64 {
+	if time < c.GenesisTime {
+		return 0
+	}
+	return (time - c.GenesisTime) / SecondsPerSlot
+}
+
+// withinWeakSubjectivityPeriod is true if the checkpoint at the slot is recent enough to start from at the current slot
+func withinWeakSubjectivityPeriod(slot, currentSlot uint64) bool {
+	return currentSlot/SlotsPerEpoch <= slot/SlotsPerEpoch+weakSubjectivityPeriod
+}
+
+func syncCommitteePeriod(slot uint64) uint64 {
+	return slot / SlotsPerEpoch / EpochsPerSyncCommitteePeriod
+}
@@ -0,0 +1,330 @@
+package lightclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ExecutionEngine is the part of the engine API driven by the light client, as by a consensus layer client
+type ExecutionEngine interface {
+	EngineNewPayloadV1(ctx context.Context, req *types2.ExecutionPayload) (*remote.EnginePayloadStatus, error)
+	EngineForkChoiceUpdatedV1(ctx context.Context, req *remote.EngineForkChoiceUpdatedRequest) (*remote.EngineForkChoiceUpdatedReply, error)
+}
+
+// checkpointFile is the file of the last finalized checkpoint in the directory of the light client, which it resumes
+// from
+const checkpointFile = "checkpoint"
+
+// slotOffset is how far into the slot the light client looks for the updates, after the block and the sync committee
+// signatures of the previous slot are propagated
+const slotOffset = 4 * time.Second
+
+// LightClient follows the beacon chain by the sync committees, from the updates served by a beacon node, and drives
+// the fork choice of the execution engine: the head is the execution block of the optimistic header, the safe and
+// the finalized blocks are the execution block of the finalized header. The execution payloads are fetched from the
+// beacon node too, and are verified against the headers. It's meant for the read-only use of the chain without a
+// consensus layer client: it doesn't attest, and it trusts the majority of the sync committee.
+type LightClient struct {
+	config         *BeaconConfig
+	api            *beaconAPI
+	checkpoint     common.Hash // zero if the checkpoint is synced
+	checkpointSync *beaconAPI  // nil if the checkpoint is given
+	dir            string      // the directory of the persisted checkpoint, nothing is persisted if empty
+	engine         ExecutionEngine
+	store          *Store
+	now            func() time.Time
+
+	persisted     common.Hash // the last persisted checkpoint
+	lastHead      common.Hash // the execution blocks of the last fork choice
+	lastFinalized common.Hash
+}
+
+// New creates the light client of the beacon chain driving the execution engine. It starts from the trusted
+// checkpoint block root, or, if it's zero, from the finalized checkpoint of the checkpoint sync provider at
+// checkpointSyncURL. Either is superseded by the last finalized checkpoint persisted in dir by the previous runs.
+func New(config *BeaconConfig, beaconAPIURL string, checkpoint common.Hash, checkpointSyncURL string, dir string, engine ExecutionEngine) *LightClient {
+	lc := &LightClient{
+		config:     config,
+		api:        newBeaconAPI(beaconAPIURL),
+		checkpoint: checkpoint,
+		dir:        dir,
+		engine:     engine,
+		now:        time.Now,
+	}
+	if checkpoint == (common.Hash{}) && checkpointSyncURL != "" {
+		lc.checkpointSync = newBeaconAPI(checkpointSyncURL)
+	}
+	return lc
+}
+
+func (lc *LightClient) currentSlot() uint64 {
+	return lc.config.slotAt(uint64(lc.now().Unix()))
+}
+
+// Run follows the beacon chain until the context is cancelled
+func (lc *LightClient) Run(ctx context.Context) {
+	log.Info("[LightClient] Started")
+	for {
+		if err := lc.step(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("[LightClient] Failed", "err", err)
+		}
+		// wait for the next slot
+		next := lc.config.GenesisTime + (lc.currentSlot()+1)*SecondsPerSlot
+		wait := time.Until(time.Unix(int64(next), 0).Add(slotOffset))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (lc *LightClient) step(ctx context.Context) error {
+	if lc.store == nil {
+		if err := lc.initStore(ctx); err != nil {
+			return err
+		}
+	}
+	err := lc.sync(ctx)
+	if root := lc.store.FinalizedHeader.HashTreeRoot(); root != lc.persisted {
+		if err := lc.persistCheckpoint(root); err != nil {
+			log.Warn("[LightClient] Failed to persist the checkpoint", "err", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return lc.updateForkChoice(ctx)
+}
+
+// initStore bootstraps the store from the last persisted checkpoint, or else from the trusted or the synced one.
+// The checkpoints older than the weak subjectivity period are rejected, as their sync committees can't be trusted.
+func (lc *LightClient) initStore(ctx context.Context) error {
+	if resumed, err := lc.readCheckpoint(); err != nil {
+		log.Warn("[LightClient] Failed to read the persisted checkpoint", "err", err)
+	} else if resumed != (common.Hash{}) {
+		err := lc.bootstrap(ctx, resumed)
+		if err == nil {
+			return nil
+		}
+		log.Warn("[LightClient] Failed to resume from the persisted checkpoint", "root", resumed, "err", err)
+	}
+	checkpoint := lc.checkpoint
+	if lc.checkpointSync != nil {
+		header, err := lc.checkpointSync.finalizedHeader(ctx)
+		if err != nil {
+			return fmt.Errorf("checkpoint sync: %w", err)
+		}
+		if !withinWeakSubjectivityPeriod(header.Slot, lc.currentSlot()) {
+			return fmt.Errorf("checkpoint sync: %w: slot %d", ErrStaleCheckpoint, header.Slot)
+		}
+		checkpoint = header.HashTreeRoot()
+		log.Info("[LightClient] Synced the checkpoint", "slot", header.Slot, "root", checkpoint)
+	}
+	return lc.bootstrap(ctx, checkpoint)
+}
+
+func (lc *LightClient) bootstrap(ctx context.Context, checkpoint common.Hash) error {
+	bootstrap, err := lc.api.bootstrap(ctx, checkpoint)
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+	if !withinWeakSubjectivityPeriod(bootstrap.Header.Slot, lc.currentSlot()) {
+		return fmt.Errorf("bootstrap: %w: slot %d", ErrStaleCheckpoint, bootstrap.Header.Slot)
+	}
+	store, err := NewStore(lc.config, checkpoint, bootstrap)
+	if err != nil {
+		return err
+	}
+	lc.store = store
+	log.Info("[LightClient] Bootstrapped", "slot", bootstrap.Header.Slot, "period", store.Period(), "checkpoint", checkpoint)
+	return nil
+}
+
+// readCheckpoint is the last persisted checkpoint, zero if there is none
+func (lc *LightClient) readCheckpoint() (common.Hash, error) {
+	if lc.dir == "" {
+		return common.Hash{}, nil
+	}
+	b, err := os.ReadFile(filepath.Join(lc.dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return common.Hash{}, nil
+	} else if err != nil {
+		return common.Hash{}, err
+	}
+	root := common.FromHex(strings.TrimSpace(string(b)))
+	if len(root) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid checkpoint %q", b)
+	}
+	return common.BytesToHash(root), nil
+}
+
+func (lc *LightClient) persistCheckpoint(root common.Hash) error {
+	if lc.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(lc.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(lc.dir, checkpointFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(root.Hex()+"\n"), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	lc.persisted = root
+	return nil
+}
+
+// sync brings the store to the current slot, through the best updates of the periods which are behind
+func (lc *LightClient) sync(ctx context.Context) error {
+	currentSlot := lc.currentSlot()
+	currentPeriod := syncCommitteePeriod(currentSlot)
+	for lc.store.Period() < currentPeriod || !lc.store.NextSyncCommitteeKnown() {
+		period := lc.store.Period()
+		count := currentPeriod - period + 1
+		if count > maxUpdatesPerRequest {
+			count = maxUpdatesPerRequest
+		}
+		updates, err := lc.api.updates(ctx, period, count)
+		if err != nil {
+			return fmt.Errorf("updates: %w", err)
+		}
+		for _, update := range updates {
+			if err := lc.store.ProcessUpdate(update, currentSlot); err != nil && !errors.Is(err, ErrStaleUpdate) {
+				return err
+			}
+		}
+		if lc.store.Period() == period && (lc.store.Period() < currentPeriod || !lc.store.NextSyncCommitteeKnown()) {
+			// no progress, the updates of the period aren't available yet
+			break
+		}
+		log.Info("[LightClient] Synced the period", "period", lc.store.Period(), "slot", lc.store.FinalizedHeader.Slot)
+	}
+
+	finalityUpdate, err := lc.api.finalityUpdate(ctx)
+	if err != nil {
+		return fmt.Errorf("finality update: %w", err)
+	}
+	if err := lc.store.ProcessUpdate(finalityUpdate, currentSlot); err != nil && !errors.Is(err, ErrStaleUpdate) {
+		return err
+	}
+	optimisticUpdate, err := lc.api.optimisticUpdate(ctx)
+	if err != nil {
+		return fmt.Errorf("optimistic update: %w", err)
+	}
+	if err := lc.store.ProcessUpdate(optimisticUpdate, currentSlot); err != nil && !errors.Is(err, ErrStaleUpdate) {
+		return err
+	}
+	return nil
+}
+
+// executionPayload is the verified execution payload of the block of the header, nil before the merge
+func (lc *LightClient) executionPayload(ctx context.Context, header *BeaconBlockHeader) (*ExecutionPayload, error) {
+	root := header.HashTreeRoot()
+	block, err := lc.api.block(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("block %x: %w", root, err)
+	}
+	if blockRoot := block.Message.Header().HashTreeRoot(); blockRoot != root {
+		return nil, fmt.Errorf("block %x: root mismatch %x", root, blockRoot)
+	}
+	payload := block.Message.Body.ExecutionPayload
+	if payload == nil || payload.BlockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	return payload, nil
+}
+
+func (lc *LightClient) updateForkChoice(ctx context.Context) error {
+	head, err := lc.executionPayload(ctx, &lc.store.OptimisticHeader)
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		log.Debug("[LightClient] The head is before the merge", "slot", lc.store.OptimisticHeader.Slot)
+		return nil
+	}
+	var finalizedHash common.Hash
+	if lc.store.FinalizedHeader.Slot == lc.store.OptimisticHeader.Slot {
+		finalizedHash = head.BlockHash
+	} else {
+		finalized, err := lc.executionPayload(ctx, &lc.store.FinalizedHeader)
+		if err != nil {
+			return err
+		}
+		if finalized != nil {
+			finalizedHash = finalized.BlockHash
+		}
+	}
+	if head.BlockHash == lc.lastHead && finalizedHash == lc.lastFinalized {
+		return nil
+	}
+
+	status, err := lc.engine.EngineNewPayloadV1(ctx, convertPayload(head))
+	if err != nil {
+		return fmt.Errorf("new payload %d %x: %w", head.BlockNumber, head.BlockHash, err)
+	}
+	if status.Status == remote.EngineStatus_INVALID || status.Status == remote.EngineStatus_INVALID_BLOCK_HASH {
+		return fmt.Errorf("new payload %d %x: %s %s", head.BlockNumber, head.BlockHash, status.Status, status.ValidationError)
+	}
+	reply, err := lc.engine.EngineForkChoiceUpdatedV1(ctx, &remote.EngineForkChoiceUpdatedRequest{
+		ForkchoiceState: &remote.EngineForkChoiceState{
+			HeadBlockHash:      gointerfaces.ConvertHashToH256(head.BlockHash),
+			SafeBlockHash:      gointerfaces.ConvertHashToH256(finalizedHash),
+			FinalizedBlockHash: gointerfaces.ConvertHashToH256(finalizedHash),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("fork choice %d %x: %w", head.BlockNumber, head.BlockHash, err)
+	}
+	if reply.PayloadStatus != nil && reply.PayloadStatus.Status == remote.EngineStatus_INVALID {
+		return fmt.Errorf("fork choice %d %x: %s %s", head.BlockNumber, head.BlockHash, reply.PayloadStatus.Status, reply.PayloadStatus.ValidationError)
+	}
+	lc.lastHead, lc.lastFinalized = head.BlockHash, finalizedHash
+	log.Info("[LightClient] Updated the fork choice", "head", head.BlockNumber, "hash", head.BlockHash,
+		"finalized", finalizedHash, "slot", lc.store.OptimisticHeader.Slot)
+	return nil
+}
+
+func convertPayload(payload *ExecutionPayload) *types2.ExecutionPayload {
+	baseFee, _ := uint256.FromBig((*big.Int)(&payload.BaseFeePerGas))
+	transactions := make([][]byte, len(payload.Transactions))
+	for i, transaction := range payload.Transactions {
+		transactions[i] = transaction
+	}
+	return &types2.ExecutionPayload{
+		ParentHash:    gointerfaces.ConvertHashToH256(payload.ParentHash),
+		Coinbase:      gointerfaces.ConvertAddressToH160(payload.FeeRecipient),
+		StateRoot:     gointerfaces.ConvertHashToH256(payload.StateRoot),
+		ReceiptRoot:   gointerfaces.ConvertHashToH256(payload.ReceiptsRoot),
+		LogsBloom:     gointerfaces.ConvertBytesToH2048(payload.LogsBloom),
+		PrevRandao:    gointerfaces.ConvertHashToH256(payload.PrevRandao),
+		BlockNumber:   payload.BlockNumber,
+		GasLimit:      payload.GasLimit,
+		GasUsed:       payload.GasUsed,
+		Timestamp:     payload.Timestamp,
+		ExtraData:     payload.ExtraData,
+		BaseFeePerGas: gointerfaces.ConvertUint256IntToH256(baseFee),
+		BlockHash:     gointerfaces.ConvertHashToH256(payload.BlockHash),
+		Transactions:  transactions,
+	}
+}
@@ -0,0 +1,325 @@
+package lightclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto/bls12381"
+	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkDigest(t *testing.T) {
+	config := BeaconConfigByChainName(networkname.MainnetChainName)
+	for _, tt := range []struct {
+		epoch  uint64
+		digest string
+	}{
+		{0, "b5303f2a"},
+		{config.AltairForkEpoch, "afcaaba0"},
+		{config.BellatrixForkEpoch, "4a26c58b"},
+	} {
+		digest := config.forkDigest(tt.epoch)
+		require.Equal(t, tt.digest, common.Bytes2Hex(digest[:]), "epoch %d", tt.epoch)
+	}
+}
+
+// testCommittee is a sync committee of a few keys, repeated to the committee size
+type testCommittee struct {
+	secretKeys []*big.Int
+	committee  SyncCommittee
+}
+
+func newTestCommittee() *testCommittee {
+	g1 := bls12381.NewG1()
+	c := &testCommittee{}
+	for i := int64(1); i <= 4; i++ {
+		c.secretKeys = append(c.secretKeys, big.NewInt(i*1000003))
+	}
+	for i := 0; i < SyncCommitteeSize; i++ {
+		pubkey := g1.MulScalar(g1.New(), g1.One(), c.secretKeys[i%len(c.secretKeys)])
+		c.committee.Pubkeys = append(c.committee.Pubkeys, g1.ToCompressed(pubkey))
+	}
+	c.committee.AggregatePubkey = c.committee.Pubkeys[0]
+	return c
+}
+
+// sign is the sync aggregate of the whole committee over the header
+func (c *testCommittee) sign(t *testing.T, config *BeaconConfig, header *BeaconBlockHeader, signatureSlot uint64) SyncAggregate {
+	g2 := bls12381.NewG2()
+	signingRoot := containerRoot(header.HashTreeRoot(), config.syncCommitteeDomain(signatureSlot))
+	h, err := g2.HashToCurve(signingRoot[:], blsDomain)
+	require.NoError(t, err)
+	secret := new(big.Int)
+	for i := 0; i < SyncCommitteeSize; i++ {
+		secret.Add(secret, c.secretKeys[i%len(c.secretKeys)])
+	}
+	bits := make([]byte, SyncCommitteeSize/8)
+	for i := range bits {
+		bits[i] = 0xff
+	}
+	return SyncAggregate{SyncCommitteeBits: bits, SyncCommitteeSignature: g2.ToCompressed(g2.MulScalar(g2.New(), h, secret))}
+}
+
+// testState is the part of a beacon state proven to the light client, the rest of the tree is zero
+type testState struct {
+	leaves map[uint64]common.Hash // by generalized index
+}
+
+func newTestState(finalized *BeaconBlockHeader, committee *SyncCommittee) *testState {
+	s := &testState{leaves: map[uint64]common.Hash{
+		currentSyncCommitteeIndex: committee.HashTreeRoot(),
+		nextSyncCommitteeIndex:    committee.HashTreeRoot(),
+	}}
+	if finalized != nil {
+		s.leaves[finalizedRootIndex] = finalized.HashTreeRoot()
+	}
+	return s
+}
+
+func (s *testState) node(index uint64) common.Hash {
+	if leaf, ok := s.leaves[index]; ok {
+		return leaf
+	}
+	if index >= 1<<(finalizedRootDepth+1) {
+		return common.Hash{}
+	}
+	return hashPair(s.node(2*index), s.node(2*index+1))
+}
+
+func (s *testState) root() common.Hash {
+	return s.node(1)
+}
+
+func (s *testState) branch(index uint64) []common.Hash {
+	var branch []common.Hash
+	for ; index > 1; index /= 2 {
+		branch = append(branch, s.node(index^1))
+	}
+	return branch
+}
+
+// testChain is a beacon chain with a block at each of the slots, finalizing the block of the previous checkpoint
+type testChain struct {
+	config    *BeaconConfig
+	committee *testCommittee
+	blocks    map[common.Hash]*BeaconBlock
+	headers   map[uint64]*BeaconBlockHeader // by slot
+	states    map[uint64]*testState
+}
+
+func newTestChain(t *testing.T, config *BeaconConfig, slots ...uint64) *testChain {
+	c := &testChain{config: config, committee: newTestCommittee(), blocks: map[common.Hash]*BeaconBlock{},
+		headers: map[uint64]*BeaconBlockHeader{}, states: map[uint64]*testState{}}
+	var finalized *BeaconBlockHeader
+	for i, slot := range slots {
+		state := newTestState(finalized, &c.committee.committee)
+		block := &BeaconBlock{
+			Slot:      slot,
+			StateRoot: state.root(),
+			Body: BeaconBlockBody{
+				RandaoReveal:  make([]byte, 96),
+				SyncAggregate: &SyncAggregate{SyncCommitteeBits: make([]byte, SyncCommitteeSize/8), SyncCommitteeSignature: make([]byte, 96)},
+				ExecutionPayload: &ExecutionPayload{
+					LogsBloom:   make([]byte, 256),
+					BlockNumber: uint64(i + 1),
+					BlockHash:   common.BigToHash(big.NewInt(int64(1000 + i))),
+				},
+			},
+		}
+		header := block.Header()
+		c.blocks[header.HashTreeRoot()], c.headers[slot], c.states[slot] = block, header, state
+		finalized = header
+	}
+	return c
+}
+
+func (c *testChain) bootstrap(slot uint64) *LightClientBootstrap {
+	return &LightClientBootstrap{
+		Header:                     *c.headers[slot],
+		CurrentSyncCommittee:       c.committee.committee,
+		CurrentSyncCommitteeBranch: c.states[slot].branch(currentSyncCommitteeIndex),
+	}
+}
+
+// update is the update attesting the header of the slot, with its finalized header and the next sync committee
+func (c *testChain) update(t *testing.T, slot uint64, finality, syncCommittee bool) *LightClientUpdate {
+	attested := c.headers[slot]
+	state := c.states[slot]
+	update := &LightClientUpdate{AttestedHeader: *attested, SignatureSlot: slot + 1}
+	if finality {
+		for _, block := range c.blocks {
+			if block.Header().HashTreeRoot() == state.leaves[finalizedRootIndex] {
+				update.FinalizedHeader = block.Header()
+			}
+		}
+		update.FinalityBranch = state.branch(finalizedRootIndex)
+	}
+	if syncCommittee {
+		update.NextSyncCommittee = &c.committee.committee
+		update.NextSyncCommitteeBranch = state.branch(nextSyncCommitteeIndex)
+	}
+	update.SyncAggregate = c.committee.sign(t, c.config, attested, update.SignatureSlot)
+	return update
+}
+
+var testBeaconConfig = &BeaconConfig{
+	GenesisTime:          1600000000,
+	GenesisForkVersion:   [4]byte{0x00, 0x00, 0x00, 0x01},
+	AltairForkVersion:    [4]byte{0x01, 0x00, 0x00, 0x01},
+	BellatrixForkVersion: [4]byte{0x02, 0x00, 0x00, 0x01},
+}
+
+func TestStore(t *testing.T) {
+	chain := newTestChain(t, testBeaconConfig, 64, 96, 128)
+	checkpoint := chain.headers[64].HashTreeRoot()
+
+	_, err := NewStore(testBeaconConfig, common.Hash{1}, chain.bootstrap(64))
+	require.ErrorIs(t, err, ErrInvalidBootstrap)
+	store, err := NewStore(testBeaconConfig, checkpoint, chain.bootstrap(64))
+	require.NoError(t, err)
+
+	// the signature of another header
+	update := chain.update(t, 128, true, true)
+	update.SyncAggregate = chain.update(t, 96, false, false).SyncAggregate
+	require.ErrorIs(t, store.ProcessUpdate(update, 200), ErrInvalidUpdate)
+	// the finalized header not in the attested state
+	update = chain.update(t, 128, true, true)
+	update.FinalizedHeader = chain.headers[64]
+	require.ErrorIs(t, store.ProcessUpdate(update, 200), ErrInvalidUpdate)
+	// signed in the future
+	require.ErrorIs(t, store.ProcessUpdate(chain.update(t, 128, true, true), 100), ErrInvalidUpdate)
+
+	require.NoError(t, store.ProcessUpdate(chain.update(t, 128, true, true), 200))
+	require.Equal(t, uint64(96), store.FinalizedHeader.Slot)
+	require.Equal(t, uint64(128), store.OptimisticHeader.Slot)
+	require.True(t, store.NextSyncCommitteeKnown())
+	require.ErrorIs(t, store.ProcessUpdate(chain.update(t, 96, true, false), 200), ErrStaleUpdate)
+}
+
+type testEngine struct {
+	payloads    []*types2.ExecutionPayload
+	forkChoices []*remote.EngineForkChoiceState
+}
+
+func (e *testEngine) EngineNewPayloadV1(ctx context.Context, req *types2.ExecutionPayload) (*remote.EnginePayloadStatus, error) {
+	e.payloads = append(e.payloads, req)
+	return &remote.EnginePayloadStatus{Status: remote.EngineStatus_SYNCING}, nil
+}
+
+func (e *testEngine) EngineForkChoiceUpdatedV1(ctx context.Context, req *remote.EngineForkChoiceUpdatedRequest) (*remote.EngineForkChoiceUpdatedReply, error) {
+	e.forkChoices = append(e.forkChoices, req.ForkchoiceState)
+	return &remote.EngineForkChoiceUpdatedReply{PayloadStatus: &remote.EnginePayloadStatus{Status: remote.EngineStatus_SYNCING}}, nil
+}
+
+// newTestBeaconServer serves the chain by the beacon API, finalizing the slot
+func newTestBeaconServer(t *testing.T, chain *testChain, finalized uint64) *httptest.Server {
+	respond := func(w http.ResponseWriter, data interface{}) {
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"version": "bellatrix", "data": data}))
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.Path; {
+		case strings.HasPrefix(path, "/eth/v1/beacon/light_client/bootstrap/"):
+			block, ok := chain.blocks[common.HexToHash(strings.TrimPrefix(path, "/eth/v1/beacon/light_client/bootstrap/"))]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			respond(w, chain.bootstrap(block.Slot))
+		case path == "/eth/v1/beacon/light_client/updates":
+			require.Equal(t, "0", r.URL.Query().Get("start_period"))
+			require.NoError(t, json.NewEncoder(w).Encode([]interface{}{
+				map[string]interface{}{"version": "bellatrix", "data": chain.update(t, 96, true, true)},
+			}))
+		case path == "/eth/v1/beacon/light_client/finality_update":
+			respond(w, chain.update(t, 128, true, false))
+		case path == "/eth/v1/beacon/light_client/optimistic_update":
+			respond(w, chain.update(t, 160, false, false))
+		case path == "/eth/v1/beacon/headers/finalized":
+			header := chain.headers[finalized]
+			respond(w, &headerResponse{Root: header.HashTreeRoot(), Header: SignedBeaconBlockHeader{Message: *header, Signature: make([]byte, 96)}})
+		case strings.HasPrefix(path, "/eth/v2/beacon/blocks/"):
+			block, ok := chain.blocks[common.HexToHash(strings.TrimPrefix(path, "/eth/v2/beacon/blocks/"))]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			respond(w, &SignedBeaconBlock{Message: *block, Signature: make([]byte, 96)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testTime(slot uint64) func() time.Time {
+	return func() time.Time { return time.Unix(int64(testBeaconConfig.GenesisTime+slot*SecondsPerSlot), 0) }
+}
+
+func TestLightClient(t *testing.T) {
+	chain := newTestChain(t, testBeaconConfig, 64, 96, 128, 160)
+	checkpoint := chain.headers[64].HashTreeRoot()
+	server := newTestBeaconServer(t, chain, 96)
+	defer server.Close()
+
+	engine := &testEngine{}
+	lc := New(testBeaconConfig, server.URL, checkpoint, "", t.TempDir(), engine)
+	lc.now = testTime(200)
+	require.NoError(t, lc.step(context.Background()))
+	require.Equal(t, uint64(96), lc.store.FinalizedHeader.Slot)
+	require.Equal(t, uint64(160), lc.store.OptimisticHeader.Slot)
+
+	require.Len(t, engine.payloads, 1)
+	require.Equal(t, uint64(4), engine.payloads[0].BlockNumber)
+	require.Len(t, engine.forkChoices, 1)
+	head, finalized := chain.blocks[chain.headers[160].HashTreeRoot()], chain.blocks[chain.headers[96].HashTreeRoot()]
+	require.Equal(t, head.Body.ExecutionPayload.BlockHash, common.Hash(gointerfaces.ConvertH256ToHash(engine.forkChoices[0].HeadBlockHash)))
+	require.Equal(t, finalized.Body.ExecutionPayload.BlockHash, common.Hash(gointerfaces.ConvertH256ToHash(engine.forkChoices[0].FinalizedBlockHash)))
+
+	// the same fork choice isn't sent again
+	require.NoError(t, lc.step(context.Background()))
+	require.Len(t, engine.forkChoices, 1)
+
+	// a block not matching its header
+	head.Body.ExecutionPayload.BlockHash = common.Hash{1}
+	lc.lastHead = common.Hash{}
+	require.Error(t, lc.step(context.Background()))
+	require.Len(t, engine.forkChoices, 1)
+}
+
+func TestCheckpointSync(t *testing.T) {
+	chain := newTestChain(t, testBeaconConfig, 64, 96, 128, 160)
+	server := newTestBeaconServer(t, chain, 128)
+	defer server.Close()
+	dir := t.TempDir()
+
+	lc := New(testBeaconConfig, server.URL, common.Hash{}, server.URL, dir, &testEngine{})
+	lc.now = testTime(200)
+	require.NoError(t, lc.step(context.Background()))
+	require.Equal(t, uint64(128), lc.store.FinalizedHeader.Slot)
+	require.True(t, lc.store.NextSyncCommitteeKnown())
+
+	// resumed from the persisted checkpoint rather than the older trusted one
+	lc = New(testBeaconConfig, server.URL, chain.headers[64].HashTreeRoot(), "", dir, &testEngine{})
+	lc.now = testTime(200)
+	require.NoError(t, lc.initStore(context.Background()))
+	require.Equal(t, uint64(128), lc.store.FinalizedHeader.Slot)
+
+	// the checkpoints beyond the weak subjectivity period
+	stale := testTime((128/SlotsPerEpoch + weakSubjectivityPeriod + 1) * SlotsPerEpoch)
+	lc = New(testBeaconConfig, server.URL, common.Hash{}, server.URL, t.TempDir(), &testEngine{})
+	lc.now = stale
+	require.ErrorIs(t, lc.initStore(context.Background()), ErrStaleCheckpoint)
+	lc = New(testBeaconConfig, server.URL, chain.headers[128].HashTreeRoot(), "", t.TempDir(), &testEngine{})
+	lc.now = stale
+	require.ErrorIs(t, lc.initStore(context.Background()), ErrStaleCheckpoint)
+}
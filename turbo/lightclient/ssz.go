@@ -0,0 +1,138 @@
+package lightclient
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// The hash tree roots of the SSZ types, https://github.com/ethereum/consensus-specs/blob/dev/ssz/simple-serialize.md#merkleization
+
+// zeroHashes are the roots of the empty trees of every depth
+var zeroHashes [64]common.Hash
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+func hashPair(a, b common.Hash) common.Hash {
+	var buf [64]byte
+	copy(buf[:32], a[:])
+	copy(buf[32:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+// merkleize is the root of the chunks, padded with zero chunks to the limit rounded up to a power of two
+func merkleize(chunks []common.Hash, limit uint64) common.Hash {
+	if uint64(len(chunks)) > limit {
+		limit = uint64(len(chunks))
+	}
+	depth := 0
+	if limit > 1 {
+		depth = bits.Len64(limit - 1)
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+	layer := make([]common.Hash, len(chunks))
+	copy(layer, chunks)
+	for level := 0; level < depth; level++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[level])
+		}
+		next := make([]common.Hash, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+func mixInLength(root common.Hash, length uint64) common.Hash {
+	var l common.Hash
+	binary.LittleEndian.PutUint64(l[:], length)
+	return hashPair(root, l)
+}
+
+// pack splits the bytes into chunks, the last one padded with zeros
+func pack(b []byte) []common.Hash {
+	chunks := make([]common.Hash, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return chunks
+}
+
+func uint64Root(v uint64) common.Hash {
+	var root common.Hash
+	binary.LittleEndian.PutUint64(root[:], v)
+	return root
+}
+
+// byteVectorRoot is the root of a vector of bytes of a fixed size
+func byteVectorRoot(b []byte) common.Hash {
+	chunks := pack(b)
+	return merkleize(chunks, uint64(len(chunks)))
+}
+
+// byteListRoot is the root of a list of at most maxLen bytes
+func byteListRoot(b []byte, maxLen uint64) common.Hash {
+	return mixInLength(merkleize(pack(b), (maxLen+31)/32), uint64(len(b)))
+}
+
+// bitlistRoot is the root of a list of at most maxLen bits, given in the serialized form ending by a delimiter bit
+func bitlistRoot(b []byte, maxLen uint64) common.Hash {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		// invalid without the delimiter, the root can't match
+		return common.Hash{}
+	}
+	last := b[len(b)-1]
+	msb := bits.Len8(last) - 1
+	length := uint64(len(b)-1)*8 + uint64(msb)
+	stripped := make([]byte, len(b))
+	copy(stripped, b)
+	stripped[len(b)-1] = last &^ (1 << msb)
+	if msb == 0 {
+		stripped = stripped[:len(b)-1]
+	}
+	return mixInLength(merkleize(pack(stripped), (maxLen+255)/256), length)
+}
+
+// uint64ListRoot is the root of a list of at most maxLen integers
+func uint64ListRoot(values []uint64, maxLen uint64) common.Hash {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+	return mixInLength(merkleize(pack(b), (maxLen*8+31)/32), uint64(len(values)))
+}
+
+// listRoot is the root of a list of at most maxLen composite elements, given their roots
+func listRoot(roots []common.Hash, maxLen uint64) common.Hash {
+	return mixInLength(merkleize(roots, maxLen), uint64(len(roots)))
+}
+
+func containerRoot(fields ...common.Hash) common.Hash {
+	return merkleize(fields, uint64(len(fields)))
+}
+
+// isValidMerkleBranch checks the branch proving the leaf at the index of a tree of the given depth
+func isValidMerkleBranch(leaf common.Hash, branch []common.Hash, depth int, index uint64, root common.Hash) bool {
+	if len(branch) != depth {
+		return false
+	}
+	value := leaf
+	for i := 0; i < depth; i++ {
+		if (index>>i)&1 == 1 {
+			value = hashPair(branch[i], value)
+		} else {
+			value = hashPair(value, branch[i])
+		}
+	}
+	return value == root
+}
@@ -0,0 +1,202 @@
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto/bls12381"
+)
+
+var (
+	ErrInvalidBootstrap = errors.New("invalid light client bootstrap")
+	ErrInvalidUpdate    = errors.New("invalid light client update")
+	ErrStaleUpdate      = errors.New("stale light client update")
+	// ErrStaleCheckpoint is the error of the checkpoints older than the weak subjectivity period
+	ErrStaleCheckpoint = errors.New("light client checkpoint outside of the weak subjectivity period")
+	// ErrUnknownSyncCommittee is the error of the updates signed by a sync committee which is not known yet, the
+	// updates of the periods in between are needed
+	ErrUnknownSyncCommittee = errors.New("light client update of an unknown sync committee")
+)
+
+// Store is the state of the light client of
+// https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md: the finalized
+// header with the sync committees of its period and of the next, and the optimistic header signed by the majority
+// of the sync committee. The updates are applied as soon as they are valid, without the forced updates of the spec
+// when the finality stalls.
+type Store struct {
+	config *BeaconConfig
+
+	FinalizedHeader  BeaconBlockHeader
+	OptimisticHeader BeaconBlockHeader
+
+	currentSyncCommittee *SyncCommittee
+	currentPubkeys       []*bls12381.PointG1
+	nextSyncCommittee    *SyncCommittee // nil if unknown
+	nextPubkeys          []*bls12381.PointG1
+
+	previousMaxActiveParticipants int
+	currentMaxActiveParticipants  int
+}
+
+// NewStore initializes the store from the bootstrap of the trusted block root
+func NewStore(config *BeaconConfig, trustedRoot common.Hash, bootstrap *LightClientBootstrap) (*Store, error) {
+	if root := bootstrap.Header.HashTreeRoot(); root != trustedRoot {
+		return nil, fmt.Errorf("%w: header root %x, trusted root %x", ErrInvalidBootstrap, root, trustedRoot)
+	}
+	if !isValidMerkleBranch(bootstrap.CurrentSyncCommittee.HashTreeRoot(), bootstrap.CurrentSyncCommitteeBranch,
+		syncCommitteeDepth, currentSyncCommitteeIndex%(1<<syncCommitteeDepth), bootstrap.Header.StateRoot) {
+		return nil, fmt.Errorf("%w: invalid sync committee branch", ErrInvalidBootstrap)
+	}
+	pubkeys, err := decodePubkeys(&bootstrap.CurrentSyncCommittee)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBootstrap, err)
+	}
+	return &Store{
+		config:               config,
+		FinalizedHeader:      bootstrap.Header,
+		OptimisticHeader:     bootstrap.Header,
+		currentSyncCommittee: &bootstrap.CurrentSyncCommittee,
+		currentPubkeys:       pubkeys,
+	}, nil
+}
+
+// Period is the sync committee period of the finalized header
+func (s *Store) Period() uint64 {
+	return syncCommitteePeriod(s.FinalizedHeader.Slot)
+}
+
+// NextSyncCommitteeKnown is true when the updates of the next period can be verified
+func (s *Store) NextSyncCommitteeKnown() bool {
+	return s.nextSyncCommittee != nil
+}
+
+func (s *Store) validateUpdate(update *LightClientUpdate, currentSlot uint64) error {
+	participants := update.SyncAggregate.participants()
+	if participants < minSyncCommitteeParticipants {
+		return fmt.Errorf("%w: not enough participants", ErrInvalidUpdate)
+	}
+	if len(update.SyncAggregate.SyncCommitteeBits) != SyncCommitteeSize/8 {
+		return fmt.Errorf("%w: invalid sync committee bits", ErrInvalidUpdate)
+	}
+	if currentSlot < update.SignatureSlot || update.SignatureSlot <= update.AttestedHeader.Slot {
+		return fmt.Errorf("%w: invalid signature slot %d", ErrInvalidUpdate, update.SignatureSlot)
+	}
+	if update.isFinalityUpdate() && update.AttestedHeader.Slot < update.FinalizedHeader.Slot {
+		return fmt.Errorf("%w: finalized header after attested header", ErrInvalidUpdate)
+	}
+	storePeriod := s.Period()
+	signaturePeriod := syncCommitteePeriod(update.SignatureSlot)
+	if signaturePeriod != storePeriod && (s.nextSyncCommittee == nil || signaturePeriod != storePeriod+1) {
+		if signaturePeriod < storePeriod {
+			return fmt.Errorf("%w: signature period %d, store period %d", ErrStaleUpdate, signaturePeriod, storePeriod)
+		}
+		return fmt.Errorf("%w: signature period %d, store period %d", ErrUnknownSyncCommittee, signaturePeriod, storePeriod)
+	}
+	attestedPeriod := syncCommitteePeriod(update.AttestedHeader.Slot)
+	hasNextSyncCommittee := s.nextSyncCommittee == nil && update.isSyncCommitteeUpdate() && attestedPeriod == storePeriod
+	if update.AttestedHeader.Slot <= s.FinalizedHeader.Slot && !hasNextSyncCommittee {
+		return fmt.Errorf("%w: attested slot %d, finalized slot %d", ErrStaleUpdate, update.AttestedHeader.Slot, s.FinalizedHeader.Slot)
+	}
+
+	if update.isFinalityUpdate() {
+		var finalizedRoot common.Hash // the genesis checkpoint is zero
+		if update.FinalizedHeader.Slot != 0 {
+			finalizedRoot = update.FinalizedHeader.HashTreeRoot()
+		}
+		if !isValidMerkleBranch(finalizedRoot, update.FinalityBranch, finalizedRootDepth,
+			finalizedRootIndex%(1<<finalizedRootDepth), update.AttestedHeader.StateRoot) {
+			return fmt.Errorf("%w: invalid finality branch", ErrInvalidUpdate)
+		}
+	}
+	if update.isSyncCommitteeUpdate() {
+		committeeRoot := update.NextSyncCommittee.HashTreeRoot()
+		if attestedPeriod == storePeriod && s.nextSyncCommittee != nil && committeeRoot != s.nextSyncCommittee.HashTreeRoot() {
+			return fmt.Errorf("%w: conflicting next sync committee", ErrInvalidUpdate)
+		}
+		if !isValidMerkleBranch(committeeRoot, update.NextSyncCommitteeBranch, syncCommitteeDepth,
+			nextSyncCommitteeIndex%(1<<syncCommitteeDepth), update.AttestedHeader.StateRoot) {
+			return fmt.Errorf("%w: invalid next sync committee branch", ErrInvalidUpdate)
+		}
+	}
+
+	committee := s.currentPubkeys
+	if signaturePeriod != storePeriod {
+		committee = s.nextPubkeys
+	}
+	signers := make([]*bls12381.PointG1, 0, participants)
+	for i, pubkey := range committee {
+		if update.SyncAggregate.SyncCommitteeBits[i/8]&(1<<(i%8)) != 0 {
+			signers = append(signers, pubkey)
+		}
+	}
+	signingRoot := containerRoot(update.AttestedHeader.HashTreeRoot(), s.config.syncCommitteeDomain(update.SignatureSlot))
+	if !fastAggregateVerify(signers, signingRoot, update.SyncAggregate.SyncCommitteeSignature) {
+		return fmt.Errorf("%w: invalid sync committee signature", ErrInvalidUpdate)
+	}
+	return nil
+}
+
+// ProcessUpdate validates the update at the current slot, and applies it: the optimistic header follows the headers
+// signed by the majority of the committee, the finalized header and the sync committees follow the updates signed by
+// two thirds of it.
+func (s *Store) ProcessUpdate(update *LightClientUpdate, currentSlot uint64) error {
+	if err := s.validateUpdate(update, currentSlot); err != nil {
+		return err
+	}
+	participants := update.SyncAggregate.participants()
+	if participants > s.currentMaxActiveParticipants {
+		s.currentMaxActiveParticipants = participants
+	}
+
+	safetyThreshold := s.previousMaxActiveParticipants
+	if s.currentMaxActiveParticipants > safetyThreshold {
+		safetyThreshold = s.currentMaxActiveParticipants
+	}
+	if participants > safetyThreshold/2 && update.AttestedHeader.Slot > s.OptimisticHeader.Slot {
+		s.OptimisticHeader = update.AttestedHeader
+	}
+
+	hasFinalizedNextSyncCommittee := s.nextSyncCommittee == nil && update.isSyncCommitteeUpdate() && update.isFinalityUpdate() &&
+		syncCommitteePeriod(update.FinalizedHeader.Slot) == syncCommitteePeriod(update.AttestedHeader.Slot)
+	if participants*3 >= SyncCommitteeSize*2 && update.isFinalityUpdate() &&
+		(update.FinalizedHeader.Slot > s.FinalizedHeader.Slot || hasFinalizedNextSyncCommittee) {
+		return s.applyUpdate(update)
+	}
+	return nil
+}
+
+func (s *Store) applyUpdate(update *LightClientUpdate) error {
+	storePeriod := s.Period()
+	finalizedPeriod := syncCommitteePeriod(update.FinalizedHeader.Slot)
+	var nextPubkeys []*bls12381.PointG1
+	if update.isSyncCommitteeUpdate() {
+		var err error
+		if nextPubkeys, err = decodePubkeys(update.NextSyncCommittee); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+		}
+	}
+	if s.nextSyncCommittee == nil {
+		if finalizedPeriod != storePeriod {
+			return fmt.Errorf("%w: finalized period %d, store period %d", ErrStaleUpdate, finalizedPeriod, storePeriod)
+		}
+		if update.isSyncCommitteeUpdate() {
+			s.nextSyncCommittee, s.nextPubkeys = update.NextSyncCommittee, nextPubkeys
+		}
+	} else if finalizedPeriod == storePeriod+1 {
+		s.currentSyncCommittee, s.currentPubkeys = s.nextSyncCommittee, s.nextPubkeys
+		s.nextSyncCommittee, s.nextPubkeys = nil, nil
+		if update.isSyncCommitteeUpdate() {
+			s.nextSyncCommittee, s.nextPubkeys = update.NextSyncCommittee, nextPubkeys
+		}
+		s.previousMaxActiveParticipants = s.currentMaxActiveParticipants
+		s.currentMaxActiveParticipants = 0
+	}
+	if update.FinalizedHeader.Slot > s.FinalizedHeader.Slot {
+		s.FinalizedHeader = *update.FinalizedHeader
+		if s.FinalizedHeader.Slot > s.OptimisticHeader.Slot {
+			s.OptimisticHeader = s.FinalizedHeader
+		}
+	}
+	return nil
+}
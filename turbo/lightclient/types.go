@@ -0,0 +1,390 @@
+package lightclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+// The containers of the beacon chain, in the JSON encoding of the beacon API, with their hash tree roots.
+// https://github.com/ethereum/consensus-specs/tree/dev/specs
+
+const (
+	maxProposerSlashings      = 16
+	maxAttesterSlashings      = 2
+	maxAttestations           = 128
+	maxDeposits               = 16
+	maxVoluntaryExits         = 16
+	maxValidatorsPerCommittee = 2048
+	depositProofLength        = 33 // DEPOSIT_CONTRACT_TREE_DEPTH + 1
+	maxExtraDataBytes         = 32
+	maxBytesPerTransaction    = 1 << 30
+	maxTransactionsPerPayload = 1 << 20
+)
+
+// indices are a list of integers encoded as decimal strings
+type indices []uint64
+
+func (l indices) MarshalJSON() ([]byte, error) {
+	strs := make([]string, len(l))
+	for i, v := range l {
+		strs[i] = strconv.FormatUint(v, 10)
+	}
+	return json.Marshal(strs)
+}
+
+func (l *indices) UnmarshalJSON(input []byte) error {
+	var strs []string
+	if err := json.Unmarshal(input, &strs); err != nil {
+		return err
+	}
+	*l = make([]uint64, len(strs))
+	for i, s := range strs {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		(*l)[i] = v
+	}
+	return nil
+}
+
+// decimalBig is a big integer encoded as a decimal string
+type decimalBig big.Int
+
+func (b decimalBig) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(&b).String())
+}
+
+func (b *decimalBig) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return err
+	}
+	if _, ok := (*big.Int)(b).SetString(s, 10); !ok {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	return nil
+}
+
+type BeaconBlockHeader struct {
+	Slot          uint64      `json:"slot,string"`
+	ProposerIndex uint64      `json:"proposer_index,string"`
+	ParentRoot    common.Hash `json:"parent_root"`
+	StateRoot     common.Hash `json:"state_root"`
+	BodyRoot      common.Hash `json:"body_root"`
+}
+
+func (h *BeaconBlockHeader) HashTreeRoot() common.Hash {
+	return containerRoot(uint64Root(h.Slot), uint64Root(h.ProposerIndex), h.ParentRoot, h.StateRoot, h.BodyRoot)
+}
+
+type SignedBeaconBlockHeader struct {
+	Message   BeaconBlockHeader `json:"message"`
+	Signature hexutil.Bytes     `json:"signature"`
+}
+
+func (h *SignedBeaconBlockHeader) HashTreeRoot() common.Hash {
+	return containerRoot(h.Message.HashTreeRoot(), byteVectorRoot(h.Signature))
+}
+
+type SyncCommittee struct {
+	Pubkeys         []hexutil.Bytes `json:"pubkeys"`
+	AggregatePubkey hexutil.Bytes   `json:"aggregate_pubkey"`
+}
+
+func (c *SyncCommittee) HashTreeRoot() common.Hash {
+	roots := make([]common.Hash, len(c.Pubkeys))
+	for i, pubkey := range c.Pubkeys {
+		roots[i] = byteVectorRoot(pubkey)
+	}
+	return containerRoot(merkleize(roots, SyncCommitteeSize), byteVectorRoot(c.AggregatePubkey))
+}
+
+type SyncAggregate struct {
+	SyncCommitteeBits      hexutil.Bytes `json:"sync_committee_bits"`
+	SyncCommitteeSignature hexutil.Bytes `json:"sync_committee_signature"`
+}
+
+func (a *SyncAggregate) HashTreeRoot() common.Hash {
+	return containerRoot(byteVectorRoot(a.SyncCommitteeBits), byteVectorRoot(a.SyncCommitteeSignature))
+}
+
+// participants is the number of the members of the committee who signed
+func (a *SyncAggregate) participants() int {
+	var n int
+	for i := 0; i < SyncCommitteeSize && i/8 < len(a.SyncCommitteeBits); i++ {
+		if a.SyncCommitteeBits[i/8]&(1<<(i%8)) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+type Eth1Data struct {
+	DepositRoot  common.Hash `json:"deposit_root"`
+	DepositCount uint64      `json:"deposit_count,string"`
+	BlockHash    common.Hash `json:"block_hash"`
+}
+
+func (d *Eth1Data) HashTreeRoot() common.Hash {
+	return containerRoot(d.DepositRoot, uint64Root(d.DepositCount), d.BlockHash)
+}
+
+type Checkpoint struct {
+	Epoch uint64      `json:"epoch,string"`
+	Root  common.Hash `json:"root"`
+}
+
+func (c *Checkpoint) HashTreeRoot() common.Hash {
+	return containerRoot(uint64Root(c.Epoch), c.Root)
+}
+
+type AttestationData struct {
+	Slot            uint64      `json:"slot,string"`
+	Index           uint64      `json:"index,string"`
+	BeaconBlockRoot common.Hash `json:"beacon_block_root"`
+	Source          Checkpoint  `json:"source"`
+	Target          Checkpoint  `json:"target"`
+}
+
+func (d *AttestationData) HashTreeRoot() common.Hash {
+	return containerRoot(uint64Root(d.Slot), uint64Root(d.Index), d.BeaconBlockRoot, d.Source.HashTreeRoot(), d.Target.HashTreeRoot())
+}
+
+type Attestation struct {
+	AggregationBits hexutil.Bytes   `json:"aggregation_bits"`
+	Data            AttestationData `json:"data"`
+	Signature       hexutil.Bytes   `json:"signature"`
+}
+
+func (a *Attestation) HashTreeRoot() common.Hash {
+	return containerRoot(bitlistRoot(a.AggregationBits, maxValidatorsPerCommittee), a.Data.HashTreeRoot(), byteVectorRoot(a.Signature))
+}
+
+type IndexedAttestation struct {
+	AttestingIndices indices         `json:"attesting_indices"`
+	Data             AttestationData `json:"data"`
+	Signature        hexutil.Bytes   `json:"signature"`
+}
+
+func (a *IndexedAttestation) HashTreeRoot() common.Hash {
+	return containerRoot(uint64ListRoot(a.AttestingIndices, maxValidatorsPerCommittee), a.Data.HashTreeRoot(), byteVectorRoot(a.Signature))
+}
+
+type ProposerSlashing struct {
+	SignedHeader1 SignedBeaconBlockHeader `json:"signed_header_1"`
+	SignedHeader2 SignedBeaconBlockHeader `json:"signed_header_2"`
+}
+
+func (s *ProposerSlashing) HashTreeRoot() common.Hash {
+	return containerRoot(s.SignedHeader1.HashTreeRoot(), s.SignedHeader2.HashTreeRoot())
+}
+
+type AttesterSlashing struct {
+	Attestation1 IndexedAttestation `json:"attestation_1"`
+	Attestation2 IndexedAttestation `json:"attestation_2"`
+}
+
+func (s *AttesterSlashing) HashTreeRoot() common.Hash {
+	return containerRoot(s.Attestation1.HashTreeRoot(), s.Attestation2.HashTreeRoot())
+}
+
+type DepositData struct {
+	Pubkey                hexutil.Bytes `json:"pubkey"`
+	WithdrawalCredentials common.Hash   `json:"withdrawal_credentials"`
+	Amount                uint64        `json:"amount,string"`
+	Signature             hexutil.Bytes `json:"signature"`
+}
+
+func (d *DepositData) HashTreeRoot() common.Hash {
+	return containerRoot(byteVectorRoot(d.Pubkey), d.WithdrawalCredentials, uint64Root(d.Amount), byteVectorRoot(d.Signature))
+}
+
+type Deposit struct {
+	Proof []common.Hash `json:"proof"`
+	Data  DepositData   `json:"data"`
+}
+
+func (d *Deposit) HashTreeRoot() common.Hash {
+	return containerRoot(merkleize(d.Proof, depositProofLength), d.Data.HashTreeRoot())
+}
+
+type VoluntaryExit struct {
+	Epoch          uint64 `json:"epoch,string"`
+	ValidatorIndex uint64 `json:"validator_index,string"`
+}
+
+type SignedVoluntaryExit struct {
+	Message   VoluntaryExit `json:"message"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+func (e *SignedVoluntaryExit) HashTreeRoot() common.Hash {
+	return containerRoot(containerRoot(uint64Root(e.Message.Epoch), uint64Root(e.Message.ValidatorIndex)), byteVectorRoot(e.Signature))
+}
+
+type ExecutionPayload struct {
+	ParentHash    common.Hash     `json:"parent_hash"`
+	FeeRecipient  common.Address  `json:"fee_recipient"`
+	StateRoot     common.Hash     `json:"state_root"`
+	ReceiptsRoot  common.Hash     `json:"receipts_root"`
+	LogsBloom     hexutil.Bytes   `json:"logs_bloom"`
+	PrevRandao    common.Hash     `json:"prev_randao"`
+	BlockNumber   uint64          `json:"block_number,string"`
+	GasLimit      uint64          `json:"gas_limit,string"`
+	GasUsed       uint64          `json:"gas_used,string"`
+	Timestamp     uint64          `json:"timestamp,string"`
+	ExtraData     hexutil.Bytes   `json:"extra_data"`
+	BaseFeePerGas decimalBig      `json:"base_fee_per_gas"`
+	BlockHash     common.Hash     `json:"block_hash"`
+	Transactions  []hexutil.Bytes `json:"transactions"`
+}
+
+func (p *ExecutionPayload) HashTreeRoot() common.Hash {
+	var baseFee common.Hash // little endian
+	fee := (*big.Int)(&p.BaseFeePerGas).Bytes()
+	for i := 0; i < len(fee) && i < len(baseFee); i++ {
+		baseFee[i] = fee[len(fee)-1-i]
+	}
+	txs := make([]common.Hash, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		txs[i] = byteListRoot(tx, maxBytesPerTransaction)
+	}
+	return containerRoot(
+		p.ParentHash,
+		byteVectorRoot(p.FeeRecipient[:]),
+		p.StateRoot,
+		p.ReceiptsRoot,
+		byteVectorRoot(p.LogsBloom),
+		p.PrevRandao,
+		uint64Root(p.BlockNumber),
+		uint64Root(p.GasLimit),
+		uint64Root(p.GasUsed),
+		uint64Root(p.Timestamp),
+		byteListRoot(p.ExtraData, maxExtraDataBytes),
+		baseFee,
+		p.BlockHash,
+		listRoot(txs, maxTransactionsPerPayload),
+	)
+}
+
+// BeaconBlockBody is the body of the phase0, altair and bellatrix blocks, which differ by the sync aggregate and the
+// execution payload
+type BeaconBlockBody struct {
+	RandaoReveal      hexutil.Bytes         `json:"randao_reveal"`
+	Eth1Data          Eth1Data              `json:"eth1_data"`
+	Graffiti          common.Hash           `json:"graffiti"`
+	ProposerSlashings []ProposerSlashing    `json:"proposer_slashings"`
+	AttesterSlashings []AttesterSlashing    `json:"attester_slashings"`
+	Attestations      []Attestation         `json:"attestations"`
+	Deposits          []Deposit             `json:"deposits"`
+	VoluntaryExits    []SignedVoluntaryExit `json:"voluntary_exits"`
+	SyncAggregate     *SyncAggregate        `json:"sync_aggregate"`
+	ExecutionPayload  *ExecutionPayload     `json:"execution_payload"`
+}
+
+func (b *BeaconBlockBody) HashTreeRoot() common.Hash {
+	proposerSlashings := make([]common.Hash, len(b.ProposerSlashings))
+	for i := range b.ProposerSlashings {
+		proposerSlashings[i] = b.ProposerSlashings[i].HashTreeRoot()
+	}
+	attesterSlashings := make([]common.Hash, len(b.AttesterSlashings))
+	for i := range b.AttesterSlashings {
+		attesterSlashings[i] = b.AttesterSlashings[i].HashTreeRoot()
+	}
+	attestations := make([]common.Hash, len(b.Attestations))
+	for i := range b.Attestations {
+		attestations[i] = b.Attestations[i].HashTreeRoot()
+	}
+	deposits := make([]common.Hash, len(b.Deposits))
+	for i := range b.Deposits {
+		deposits[i] = b.Deposits[i].HashTreeRoot()
+	}
+	exits := make([]common.Hash, len(b.VoluntaryExits))
+	for i := range b.VoluntaryExits {
+		exits[i] = b.VoluntaryExits[i].HashTreeRoot()
+	}
+	fields := []common.Hash{
+		byteVectorRoot(b.RandaoReveal),
+		b.Eth1Data.HashTreeRoot(),
+		b.Graffiti,
+		listRoot(proposerSlashings, maxProposerSlashings),
+		listRoot(attesterSlashings, maxAttesterSlashings),
+		listRoot(attestations, maxAttestations),
+		listRoot(deposits, maxDeposits),
+		listRoot(exits, maxVoluntaryExits),
+	}
+	if b.SyncAggregate != nil {
+		fields = append(fields, b.SyncAggregate.HashTreeRoot())
+		if b.ExecutionPayload != nil {
+			fields = append(fields, b.ExecutionPayload.HashTreeRoot())
+		}
+	}
+	return containerRoot(fields...)
+}
+
+type BeaconBlock struct {
+	Slot          uint64          `json:"slot,string"`
+	ProposerIndex uint64          `json:"proposer_index,string"`
+	ParentRoot    common.Hash     `json:"parent_root"`
+	StateRoot     common.Hash     `json:"state_root"`
+	Body          BeaconBlockBody `json:"body"`
+}
+
+// Header is the header of the block, whose root is the root of the block
+func (b *BeaconBlock) Header() *BeaconBlockHeader {
+	return &BeaconBlockHeader{
+		Slot:          b.Slot,
+		ProposerIndex: b.ProposerIndex,
+		ParentRoot:    b.ParentRoot,
+		StateRoot:     b.StateRoot,
+		BodyRoot:      b.Body.HashTreeRoot(),
+	}
+}
+
+type SignedBeaconBlock struct {
+	Message   BeaconBlock   `json:"message"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// LightClientBootstrap is the trusted header, and the sync committee of its period
+type LightClientBootstrap struct {
+	Header                     BeaconBlockHeader `json:"header"`
+	CurrentSyncCommittee       SyncCommittee     `json:"current_sync_committee"`
+	CurrentSyncCommitteeBranch []common.Hash     `json:"current_sync_committee_branch"`
+}
+
+// LightClientUpdate is a header signed by the sync committee, and optionally the finalized header and the next sync
+// committee proven by its state. The finality and the optimistic updates are updates without the next sync committee,
+// and without the finalized header for the latter.
+type LightClientUpdate struct {
+	AttestedHeader          BeaconBlockHeader  `json:"attested_header"`
+	NextSyncCommittee       *SyncCommittee     `json:"next_sync_committee"`
+	NextSyncCommitteeBranch []common.Hash      `json:"next_sync_committee_branch"`
+	FinalizedHeader         *BeaconBlockHeader `json:"finalized_header"`
+	FinalityBranch          []common.Hash      `json:"finality_branch"`
+	SyncAggregate           SyncAggregate      `json:"sync_aggregate"`
+	SignatureSlot           uint64             `json:"signature_slot,string"`
+}
+
+func isEmptyBranch(branch []common.Hash) bool {
+	for _, h := range branch {
+		if h != (common.Hash{}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (u *LightClientUpdate) isSyncCommitteeUpdate() bool {
+	return u.NextSyncCommittee != nil && !isEmptyBranch(u.NextSyncCommitteeBranch)
+}
+
+func (u *LightClientUpdate) isFinalityUpdate() bool {
+	return u.FinalizedHeader != nil && !isEmptyBranch(u.FinalityBranch)
+}
@@ -35,6 +35,22 @@ func (eri *ErigonNode) Serve() error {
 	return nil
 }
 
+// Start starts the node without blocking, for the embedders running several nodes in a process. The node is stopped
+// by Close.
+func (eri *ErigonNode) Start() error {
+	return eri.stack.Start()
+}
+
+// Close stops the node
+func (eri *ErigonNode) Close() error {
+	return eri.stack.Close()
+}
+
+// Backend is the eth service of the node
+func (eri *ErigonNode) Backend() *eth.Ethereum {
+	return eri.backend
+}
+
 func (eri *ErigonNode) run() {
 	utils.StartNode(eri.stack)
 	// we don't have accounts locally and we don't do mining
@@ -52,6 +68,8 @@ type Params struct {
 }
 
 func NewNodConfigUrfave(ctx *cli.Context) *nodecfg.Config {
+	utils.RegisterCustomChain(ctx)
+
 	// If we're running a known preset, log it for convenience.
 	chain := ctx.GlobalString(utils.ChainFlag.Name)
 	switch chain {
@@ -77,6 +95,8 @@ func NewNodConfigUrfave(ctx *cli.Context) *nodecfg.Config {
 		log.Info("Starting Erigon on Bor Mainnet...")
 	case networkname.BorDevnetChainName:
 		log.Info("Starting Erigon on Bor Devnet...")
+	case networkname.GnosisChainName:
+		log.Info("Starting Erigon on Gnosis Chain...")
 	case "", networkname.MainnetChainName:
 		if !ctx.GlobalIsSet(utils.NetworkIdFlag.Name) {
 			log.Info("Starting Erigon on Ethereum mainnet...")
@@ -91,11 +111,11 @@ func NewNodConfigUrfave(ctx *cli.Context) *nodecfg.Config {
 	return nodeConfig
 }
 func NewEthConfigUrfave(ctx *cli.Context, nodeConfig *nodecfg.Config) *ethconfig.Config {
-	ethConfig := &ethconfig.Defaults
-	utils.SetEthConfig(ctx, nodeConfig, ethConfig)
-	erigoncli.ApplyFlagsForEthConfig(ctx, ethConfig)
+	ethConfig := ethconfig.Defaults // a copy, the nodes of a process don't share their config
+	utils.SetEthConfig(ctx, nodeConfig, &ethConfig)
+	erigoncli.ApplyFlagsForEthConfig(ctx, &ethConfig)
 
-	return ethConfig
+	return &ethConfig
 }
 
 // New creates a new `ErigonNode`.
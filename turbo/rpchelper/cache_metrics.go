@@ -0,0 +1,20 @@
+package rpchelper
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+// RegisterCacheHitRatio exports the hit ratios since the start of the coherent state cache with the metrics label, of
+// its state and of its code, from the hit and miss counters of the cache.
+func RegisterCacheHitRatio(label string) {
+	for _, m := range []string{"cache", "cache_code"} {
+		hits := metrics.GetOrCreateCounter(fmt.Sprintf(`%s_total{result="hit",name="%s"}`, m, label))
+		misses := metrics.GetOrCreateCounter(fmt.Sprintf(`%s_total{result="miss",name="%s"}`, m, label))
+		metrics.GetOrCreateGauge(fmt.Sprintf(`%s_hit_ratio{name="%s"}`, m, label), func() float64 {
+			return shards.HitRatio(hits, misses)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package rpchelper
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// CacheWarmer pre-fills the state cache with the accounts, storage slots and contract code
+// changed by the recent blocks - the next blocks, and the requests about them, are likely
+// to touch the same state. The cache is cold after a restart, and a long reorg invalidates it,
+// so both trigger a warm-up.
+type CacheWarmer struct {
+	db         kv.RoDB
+	cache      kvcache.Cache
+	blocks     uint64 // the number of recent blocks to replay
+	reorgDepth uint64 // unwinds of at least this many blocks trigger a warm-up
+
+	started uint32 // the first state changes have been received
+	running uint32
+}
+
+// NewCacheWarmer creates a warmer which replays the state changes of the given number of recent blocks.
+func NewCacheWarmer(db kv.RoDB, cache kvcache.Cache, blocks uint64) *CacheWarmer {
+	return &CacheWarmer{db: db, cache: cache, blocks: blocks, reorgDepth: blocks / 2}
+}
+
+// OnNewBlock starts a warm-up in the background when the cache has received its first state changes,
+// or was unwound by a long reorg. Has to be called after the cache has processed the changes.
+func (w *CacheWarmer) OnNewBlock(ctx context.Context, stateChanges *remote.StateChangeBatch) {
+	var unwound uint64
+	for _, sc := range stateChanges.ChangeBatch {
+		if sc.Direction == remote.Direction_UNWIND {
+			unwound++
+		}
+	}
+	first := atomic.CompareAndSwapUint32(&w.started, 0, 1)
+	if !first && (unwound == 0 || unwound < w.reorgDepth) {
+		return
+	}
+	if !atomic.CompareAndSwapUint32(&w.running, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreUint32(&w.running, 0)
+		start := time.Now()
+		keys, err := w.Warm(ctx)
+		if err != nil {
+			log.Warn("[rpc] state cache warm-up failed", "err", err)
+			return
+		}
+		log.Info("[rpc] state cache warmed up", "keys", keys, "unwound", unwound, "took", time.Since(start))
+	}()
+}
+
+// Warm reads the state changed by the recent blocks through the cache, and returns the number of keys read.
+func (w *CacheWarmer) Warm(ctx context.Context) (int, error) {
+	tx, err := w.db.BeginRo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	view, err := w.cache.View(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	to, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return 0, err
+	}
+	var from uint64
+	if to >= w.blocks {
+		from = to - w.blocks + 1
+	}
+
+	seen := map[string]struct{}{}
+	var acc accounts.Account
+	warm := func(blockN uint64, k, _ []byte) error {
+		if _, ok := seen[string(k)]; ok {
+			return nil
+		}
+		seen[string(k)] = struct{}{}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		// the current value, the changesets hold the previous ones
+		v, err := view.Get(k)
+		if err != nil {
+			return err
+		}
+		if len(k) != 20 || len(v) == 0 {
+			return nil
+		}
+		if err := acc.DecodeForStorage(v); err != nil {
+			return err
+		}
+		if !acc.IsEmptyCodeHash() {
+			if _, err := view.GetCode(acc.CodeHash[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := changeset.ForRange(tx, kv.AccountChangeSet, from, to+1, warm); err != nil {
+		return 0, err
+	}
+	if err := changeset.ForRange(tx, kv.StorageChangeSet, from, to+1, warm); err != nil {
+		return 0, err
+	}
+	return len(seen), nil
+}
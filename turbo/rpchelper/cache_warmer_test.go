@@ -0,0 +1,68 @@
+package rpchelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheWarmer(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+
+	code := []byte{0x60, 0x00}
+	codeHash := crypto.Keccak256Hash(code)
+	oldAddr, eoa, contract := common.Address{1}, common.Address{2}, common.Address{3}
+	slot := dbutils.PlainGenerateCompositeStorageKey(contract[:], 1, common.Hash{4}.Bytes())
+
+	err := db.Update(ctx, func(tx kv.RwTx) error {
+		encode := func(acc accounts.Account) []byte {
+			enc := make([]byte, acc.EncodingLengthForStorage())
+			acc.EncodeForStorage(enc)
+			return enc
+		}
+		put := func(table string, k, v []byte) {
+			require.NoError(t, tx.Put(table, k, v))
+		}
+		put(kv.PlainState, oldAddr[:], encode(accounts.Account{Balance: *uint256.NewInt(1), Initialised: true}))
+		put(kv.PlainState, eoa[:], encode(accounts.Account{Balance: *uint256.NewInt(2), Initialised: true}))
+		put(kv.PlainState, contract[:], encode(accounts.Account{Incarnation: 1, CodeHash: codeHash, Initialised: true}))
+		put(kv.PlainState, slot, []byte{5})
+		put(kv.Code, codeHash[:], code)
+
+		// the account changed long ago isn't loaded
+		put(kv.AccountChangeSet, dbutils.EncodeBlockNumber(1), oldAddr[:])
+		put(kv.AccountChangeSet, dbutils.EncodeBlockNumber(9), eoa[:])
+		put(kv.AccountChangeSet, dbutils.EncodeBlockNumber(10), contract[:])
+		put(kv.AccountChangeSet, dbutils.EncodeBlockNumber(10), eoa[:])
+		put(kv.StorageChangeSet, append(dbutils.EncodeBlockNumber(10), dbutils.PlainGenerateStoragePrefix(contract[:], 1)...), common.Hash{4}.Bytes())
+		return stages.SaveStageProgress(tx, stages.Execution, 10)
+	})
+	require.NoError(t, err)
+
+	cfg := kvcache.DefaultCoherentConfig
+	cfg.MetricsLabel = "warmer"
+	cache := kvcache.New(cfg)
+	// the cache follows the db from this point
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	cache.OnNewBlock(&remote.StateChangeBatch{DatabaseViewID: tx.ViewID()})
+	tx.Rollback()
+
+	warmer := NewCacheWarmer(db, cache, 5)
+	keys, err := warmer.Warm(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, keys)
+	require.Equal(t, 3, cache.Len())
+}
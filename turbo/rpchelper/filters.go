@@ -31,6 +31,8 @@ type (
 	PendingLogsSubID  SubscriptionID
 	PendingBlockSubID SubscriptionID
 	PendingTxsSubID   SubscriptionID
+	RemovedTxsSubID   SubscriptionID
+	ReorgsSubID       SubscriptionID
 	LogsSubID         uint64
 )
 
@@ -43,10 +45,17 @@ type Filters struct {
 	pendingLogsSubs  map[PendingLogsSubID]chan types.Logs
 	pendingBlockSubs map[PendingBlockSubID]chan *types.Block
 	pendingTxsSubs   map[PendingTxsSubID]chan []types.Transaction
+	removedTxsSubs   map[RemovedTxsSubID]chan []RemovedTx
+	reorgsSubs       map[ReorgsSubID]chan *Reorg
 	logsSubs         *LogsFilterAggregator
 	logsRequestor    atomic.Value
 	onNewSnapshot    func()
 
+	txPool       txpool.TxpoolClient
+	poolCheck    chan struct{}
+	poolSnapshot map[common.Hash]poolTxKey // only used by the removed txs watcher
+	recentBlocks []recentBlock             // only used by OnStateChanges
+
 	storeMu            sync.Mutex
 	logsStores         map[LogsSubID][]*types.Log
 	pendingHeadsStores map[HeadsSubID][]*types.Header
@@ -59,6 +68,8 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 	ff := &Filters{
 		headsSubs:          make(map[HeadsSubID]chan *types.Header),
 		pendingTxsSubs:     make(map[PendingTxsSubID]chan []types.Transaction),
+		removedTxsSubs:     make(map[RemovedTxsSubID]chan []RemovedTx),
+		reorgsSubs:         make(map[ReorgsSubID]chan *Reorg),
 		pendingLogsSubs:    make(map[PendingLogsSubID]chan types.Logs),
 		pendingBlockSubs:   make(map[PendingBlockSubID]chan *types.Block),
 		logsSubs:           NewLogsFilterAggregator(),
@@ -66,6 +77,8 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		logsStores:         make(map[LogsSubID][]*types.Log),
 		pendingHeadsStores: make(map[HeadsSubID][]*types.Header),
 		pendingTxsStores:   make(map[PendingTxsSubID][][]types.Transaction),
+		txPool:             txPool,
+		poolCheck:          make(chan struct{}, 1),
 	}
 
 	go func() {
@@ -120,6 +133,7 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 	}()
 
 	if txPool != nil {
+		go ff.watchRemovedTxs(ctx)
 		go func() {
 			for {
 				select {
@@ -374,6 +388,27 @@ func (ff *Filters) UnsubscribePendingTxs(id PendingTxsSubID) bool {
 	return false
 }
 
+func (ff *Filters) SubscribeRemovedTxs(out chan []RemovedTx) RemovedTxsSubID {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	id := RemovedTxsSubID(generateSubscriptionID())
+	ff.removedTxsSubs[id] = out
+	// the first subscriber needs the snapshot of the pool the next one is compared to
+	ff.checkPool()
+	return id
+}
+
+func (ff *Filters) UnsubscribeRemovedTxs(id RemovedTxsSubID) bool {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	if ch, ok := ff.removedTxsSubs[id]; ok {
+		close(ch)
+		delete(ff.removedTxsSubs, id)
+		return true
+	}
+	return false
+}
+
 func (ff *Filters) SubscribeLogs(out chan *types.Log, crit filters.FilterCriteria) LogsSubID {
 	id, f := ff.logsSubs.insertLogsFilter(out)
 	f.addrs = map[common.Address]int{}
@@ -385,15 +420,15 @@ func (ff *Filters) SubscribeLogs(out chan *types.Log, crit filters.FilterCriteri
 		}
 	}
 	f.topics = map[common.Hash]int{}
-	if len(crit.Topics) == 0 {
-		f.allTopics = 1
-	} else {
-		for _, topics := range crit.Topics {
-			for _, topic := range topics {
-				f.topics[topic] = 1
-			}
+	for _, topics := range crit.Topics {
+		for _, topic := range topics {
+			f.topics[topic] = 1
 		}
 	}
+	// only wildcards: all the topics are requested, the positions are checked by distributeLog
+	if len(f.topics) == 0 {
+		f.allTopics = 1
+	}
 	f.topicsOriginal = crit.Topics
 	ff.logsSubs.addLogsFilters(f)
 	lfr := &remote.LogsFilterRequest{
@@ -465,6 +500,9 @@ func (ff *Filters) OnNewEvent(event *remote.SubscribeReply) {
 			for _, v := range ff.headsSubs {
 				v <- &header
 			}
+			if len(ff.removedTxsSubs) > 0 {
+				ff.checkPool()
+			}
 		}
 	case remote.Event_NEW_SNAPSHOT:
 		ff.onNewSnapshot()
@@ -522,21 +560,6 @@ func (ff *Filters) OnNewTx(reply *txpool.OnAddReply) {
 }
 
 func (ff *Filters) OnNewLogs(reply *remote.SubscribeLogsReply) {
-	lg := &types.Log{
-		Address:     gointerfaces.ConvertH160toAddress(reply.Address),
-		Data:        reply.Data,
-		BlockNumber: reply.BlockNumber,
-		TxHash:      gointerfaces.ConvertH256ToHash(reply.TransactionHash),
-		TxIndex:     uint(reply.TransactionIndex),
-		BlockHash:   gointerfaces.ConvertH256ToHash(reply.BlockHash),
-		Index:       uint(reply.LogIndex),
-		Removed:     reply.Removed,
-	}
-	t := make([]common.Hash, 0)
-	for _, v := range reply.Topics {
-		t = append(t, gointerfaces.ConvertH256ToHash(v))
-	}
-	lg.Topics = t
 	ff.logsSubs.distributeLog(reply)
 }
 
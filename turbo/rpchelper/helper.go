@@ -97,7 +97,14 @@ func GetAccount(tx kv.Tx, blockNumber uint64, address common.Address) (*accounts
 	return reader.ReadAccountData(address)
 }
 
-func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNumberOrHash, filters *Filters, stateCache kvcache.Cache) (state.StateReader, error) {
+// StateOverlay reads the state missing from the local state reader, e.g. the state of the network the dev chain is
+// forked from (see state.ForkBackend). The reader reads the state after the blocks before blockNum.
+type StateOverlay interface {
+	Reader(db kv.Getter, local state.StateReader, blockNum uint64) state.StateReader
+}
+
+// CreateStateReader creates the reader of the state at the block, the overlay is optional
+func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNumberOrHash, filters *Filters, stateCache kvcache.Cache, overlay StateOverlay) (state.StateReader, error) {
 	blockNumber, _, latest, err := _GetBlockNumber(true, blockNrOrHash, tx, filters)
 	if err != nil {
 		return nil, err
@@ -112,5 +119,8 @@ func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNum
 	} else {
 		stateReader = state.NewPlainState(tx, blockNumber+1)
 	}
+	if overlay != nil {
+		stateReader = overlay.Reader(tx, stateReader, blockNumber+1)
+	}
 	return stateReader, nil
 }
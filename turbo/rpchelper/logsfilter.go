@@ -94,49 +94,54 @@ func (a *LogsFilterAggregator) addLogsFilters(f *LogsFilter) {
 func (a *LogsFilterAggregator) distributeLog(eventLog *remote.SubscribeLogsReply) error {
 	a.logsFilterLock.Lock()
 	defer a.logsFilterLock.Unlock()
+	// the log is converted once, and only if a subscriber is interested in it
+	var lg *types2.Log
+	address := gointerfaces.ConvertH160toAddress(eventLog.Address)
+	topics := make([]common.Hash, 0, len(eventLog.Topics))
+	for _, topic := range eventLog.Topics {
+		topics = append(topics, gointerfaces.ConvertH256ToHash(topic))
+	}
 	for _, filter := range a.logsFilters {
 		if filter.allAddrs == 0 {
-			_, addrOk := filter.addrs[gointerfaces.ConvertH160toAddress(eventLog.Address)]
-			if !addrOk {
+			if _, addrOk := filter.addrs[address]; !addrOk {
 				continue
 			}
 		}
-		var topics []common.Hash
-		for _, topic := range eventLog.Topics {
-			topics = append(topics, gointerfaces.ConvertH256ToHash(topic))
+		if !a.chooseTopics(filter, topics) {
+			continue
 		}
-		if filter.allTopics == 0 {
-			if !a.chooseTopics(filter, topics) {
-				continue
+		if lg == nil {
+			lg = &types2.Log{
+				Address:     address,
+				Topics:      topics,
+				Data:        eventLog.Data,
+				BlockNumber: eventLog.BlockNumber,
+				TxHash:      gointerfaces.ConvertH256ToHash(eventLog.TransactionHash),
+				TxIndex:     uint(eventLog.TransactionIndex),
+				BlockHash:   gointerfaces.ConvertH256ToHash(eventLog.BlockHash),
+				Index:       uint(eventLog.LogIndex),
+				Removed:     eventLog.Removed,
 			}
 		}
-		lg := &types2.Log{
-			Address:     gointerfaces.ConvertH160toAddress(eventLog.Address),
-			Topics:      topics,
-			Data:        eventLog.Data,
-			BlockNumber: eventLog.BlockNumber,
-			TxHash:      gointerfaces.ConvertH256ToHash(eventLog.TransactionHash),
-			TxIndex:     uint(eventLog.TransactionIndex),
-			BlockHash:   gointerfaces.ConvertH256ToHash(eventLog.BlockHash),
-			Index:       uint(eventLog.LogIndex),
-			Removed:     eventLog.Removed,
-		}
 		filter.sender <- lg
 	}
 
 	return nil
 }
 
+// chooseTopics matches the topics of the log against the topic filters of the subscriber, by position.
 func (a *LogsFilterAggregator) chooseTopics(filter *LogsFilter, logTopics []common.Hash) bool {
-	var found bool
-	for _, logTopic := range logTopics {
-		if _, ok := filter.topics[logTopic]; ok {
-			found = true
-			break
+	if filter.allTopics == 0 {
+		var found bool
+		for _, logTopic := range logTopics {
+			if _, ok := filter.topics[logTopic]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
 		}
-	}
-	if !found {
-		return false
 	}
 	if len(filter.topicsOriginal) > len(logTopics) {
 		return false
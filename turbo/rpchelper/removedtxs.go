@@ -0,0 +1,103 @@
+package rpchelper
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// RemovedTx is a transaction which has left the txpool: mined, dropped, or replaced by the transaction with the
+// same sender and nonce ReplacedBy.
+type RemovedTx struct {
+	Hash       common.Hash
+	Sender     common.Address
+	Nonce      uint64
+	ReplacedBy *common.Hash
+}
+
+type poolTxKey struct {
+	sender common.Address
+	nonce  uint64
+}
+
+// checkPool asks the watcher to compare the txpool to its last snapshot, unless it's about to already.
+func (ff *Filters) checkPool() {
+	select {
+	case ff.poolCheck <- struct{}{}:
+	default:
+	}
+}
+
+// watchRemovedTxs notifies the transactions removed from the txpool to the subscribers. The txpool has no stream of
+// them, so its content is compared at each new block to the previous one: the transactions added and removed
+// in between are missed.
+func (ff *Filters) watchRemovedTxs(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ff.poolCheck:
+			if err := ff.checkRemovedTxs(ctx); err != nil {
+				log.Debug("rpc filters: error checking removed transactions", "err", err)
+			}
+		}
+	}
+}
+
+// checkRemovedTxs takes a snapshot of the txpool and notifies the transactions of the previous one it's missing.
+func (ff *Filters) checkRemovedTxs(ctx context.Context) error {
+	ff.mu.RLock()
+	subscribed := len(ff.removedTxsSubs) > 0
+	ff.mu.RUnlock()
+	if !subscribed {
+		// without subscribers, the snapshot would be outdated by the next one
+		ff.poolSnapshot = nil
+		return nil
+	}
+
+	reply, err := ff.txPool.All(ctx, &txpool.AllRequest{})
+	if err != nil {
+		return err
+	}
+	snapshot := make(map[common.Hash]poolTxKey, len(reply.Txs))
+	bySenderNonce := make(map[poolTxKey]common.Hash, len(reply.Txs))
+	for _, t := range reply.Txs {
+		txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(t.RlpTx), uint64(len(t.RlpTx))))
+		if err != nil {
+			return err
+		}
+		key := poolTxKey{sender: gointerfaces.ConvertH160toAddress(t.Sender), nonce: txn.GetNonce()}
+		snapshot[txn.Hash()] = key
+		bySenderNonce[key] = txn.Hash()
+	}
+	previous := ff.poolSnapshot
+	ff.poolSnapshot = snapshot
+
+	var removed []RemovedTx
+	for hash, key := range previous {
+		if _, ok := snapshot[hash]; ok {
+			continue
+		}
+		r := RemovedTx{Hash: hash, Sender: key.sender, Nonce: key.nonce}
+		if replacement, ok := bySenderNonce[key]; ok {
+			r.ReplacedBy = &replacement
+		}
+		removed = append(removed, r)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	ff.mu.RLock()
+	defer ff.mu.RUnlock()
+	for _, v := range ff.removedTxsSubs {
+		v <- removed
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package rpchelper
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// allTxpoolClient is a txpool which only answers the requests of its whole content.
+type allTxpoolClient struct {
+	txpool.TxpoolClient
+	reply *txpool.AllReply
+}
+
+func (c *allTxpoolClient) All(ctx context.Context, in *txpool.AllRequest, opts ...grpc.CallOption) (*txpool.AllReply, error) {
+	return c.reply, nil
+}
+
+func TestRemovedTxs(t *testing.T) {
+	ctx := context.Background()
+	sender := common.Address{1}
+	poolTx := func(nonce uint64, price uint64) (common.Hash, *txpool.AllReply_Tx) {
+		txn := types.NewTransaction(nonce, common.Address{2}, uint256.NewInt(0), 21000, uint256.NewInt(price), nil)
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, txn.MarshalBinary(buf))
+		return txn.Hash(), &txpool.AllReply_Tx{Sender: gointerfaces.ConvertAddressToH160(sender), RlpTx: buf.Bytes()}
+	}
+	first, firstTx := poolTx(0, 1)
+	second, secondTx := poolTx(1, 1)
+	replacement, replacementTx := poolTx(1, 2)
+
+	pool := &allTxpoolClient{reply: &txpool.AllReply{Txs: []*txpool.AllReply_Tx{firstTx, secondTx}}}
+	ff := &Filters{
+		removedTxsSubs: make(map[RemovedTxsSubID]chan []RemovedTx),
+		txPool:         pool,
+		poolCheck:      make(chan struct{}, 1),
+	}
+	ch := make(chan []RemovedTx, 1)
+	id := ff.SubscribeRemovedTxs(ch)
+
+	// The first snapshot has nothing to compare to
+	require.NoError(t, ff.checkRemovedTxs(ctx))
+	require.Empty(t, ch)
+
+	pool.reply = &txpool.AllReply{Txs: []*txpool.AllReply_Tx{replacementTx}}
+	require.NoError(t, ff.checkRemovedTxs(ctx))
+	removed := <-ch
+	require.Len(t, removed, 2)
+	byHash := map[common.Hash]RemovedTx{removed[0].Hash: removed[0], removed[1].Hash: removed[1]}
+	require.Nil(t, byHash[first].ReplacedBy)
+	require.Equal(t, sender, byHash[first].Sender)
+	require.Equal(t, &replacement, byHash[second].ReplacedBy)
+	require.Equal(t, uint64(1), byHash[second].Nonce)
+
+	// Without subscribers, the snapshots aren't kept
+	require.True(t, ff.UnsubscribeRemovedTxs(id))
+	require.NoError(t, ff.checkRemovedTxs(ctx))
+	require.Nil(t, ff.poolSnapshot)
+}
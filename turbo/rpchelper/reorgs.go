@@ -0,0 +1,89 @@
+package rpchelper
+
+import (
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// reorgWindow is the number of recent canonical blocks remembered to list the blocks removed by a reorg.
+const reorgWindow = 1024
+
+// Reorg is an unwind of the canonical chain to CommonAncestor: the Removed blocks were replaced by the Added
+// ones, both listed from the lowest. Removed blocks are only the ones seen since rpcdaemon is subscribed to
+// the state changes, at most reorgWindow of them.
+type Reorg struct {
+	CommonAncestorNumber uint64
+	CommonAncestor       common.Hash
+	Removed              []common.Hash
+	Added                []common.Hash
+}
+
+type recentBlock struct {
+	number uint64
+	hash   common.Hash
+}
+
+func (ff *Filters) SubscribeReorgs(out chan *Reorg) ReorgsSubID {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	id := ReorgsSubID(generateSubscriptionID())
+	ff.reorgsSubs[id] = out
+	return id
+}
+
+func (ff *Filters) UnsubscribeReorgs(id ReorgsSubID) bool {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	if ch, ok := ff.reorgsSubs[id]; ok {
+		close(ch)
+		delete(ff.reorgsSubs, id)
+		return true
+	}
+	return false
+}
+
+// OnStateChanges follows the canonical chain by the blocks of the state changes, and notifies the reorgs to the
+// subscribers. The state changes of a sync cycle start by the unwind to the common ancestor, if any, followed by
+// the added blocks. Has to be called by a single goroutine.
+func (ff *Filters) OnStateChanges(batch *remote.StateChangeBatch) {
+	var reorgs []*Reorg
+	var reorg *Reorg
+	for _, sc := range batch.ChangeBatch {
+		hash := gointerfaces.ConvertH256ToHash(sc.BlockHash)
+		if sc.Direction == remote.Direction_UNWIND {
+			reorg = &Reorg{CommonAncestorNumber: sc.BlockHeight, CommonAncestor: hash}
+			reorgs = append(reorgs, reorg)
+			i := len(ff.recentBlocks)
+			for i > 0 && ff.recentBlocks[i-1].number > sc.BlockHeight {
+				i--
+			}
+			for _, b := range ff.recentBlocks[i:] {
+				reorg.Removed = append(reorg.Removed, b.hash)
+			}
+			ff.recentBlocks = ff.recentBlocks[:i]
+			continue
+		}
+		if n := len(ff.recentBlocks); n > 0 && ff.recentBlocks[n-1].number >= sc.BlockHeight {
+			continue // the same block again, e.g. after re-subscription
+		}
+		if reorg != nil {
+			reorg.Added = append(reorg.Added, hash)
+		}
+		ff.recentBlocks = append(ff.recentBlocks, recentBlock{number: sc.BlockHeight, hash: hash})
+		if len(ff.recentBlocks) > reorgWindow {
+			ff.recentBlocks = ff.recentBlocks[len(ff.recentBlocks)-reorgWindow:]
+		}
+	}
+	if len(reorgs) == 0 {
+		return
+	}
+
+	ff.mu.RLock()
+	defer ff.mu.RUnlock()
+	for _, r := range reorgs {
+		for _, v := range ff.reorgsSubs {
+			v <- r
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package rpchelper
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorgs(t *testing.T) {
+	change := func(direction remote.Direction, number uint64, hash common.Hash) *remote.StateChange {
+		return &remote.StateChange{Direction: direction, BlockHeight: number, BlockHash: gointerfaces.ConvertHashToH256(hash)}
+	}
+	ff := &Filters{reorgsSubs: make(map[ReorgsSubID]chan *Reorg)}
+	ch := make(chan *Reorg, 1)
+	id := ff.SubscribeReorgs(ch)
+
+	ff.OnStateChanges(&remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{
+		change(remote.Direction_FORWARD, 1, common.Hash{1}),
+		change(remote.Direction_FORWARD, 2, common.Hash{2}),
+		change(remote.Direction_FORWARD, 3, common.Hash{3}),
+	}})
+	require.Empty(t, ch)
+
+	ff.OnStateChanges(&remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{
+		change(remote.Direction_UNWIND, 1, common.Hash{1}),
+		change(remote.Direction_FORWARD, 2, common.Hash{0x22}),
+		change(remote.Direction_FORWARD, 3, common.Hash{0x33}),
+		change(remote.Direction_FORWARD, 4, common.Hash{0x44}),
+	}})
+	require.Equal(t, &Reorg{
+		CommonAncestorNumber: 1,
+		CommonAncestor:       common.Hash{1},
+		Removed:              []common.Hash{{2}, {3}},
+		Added:                []common.Hash{{0x22}, {0x33}, {0x44}},
+	}, <-ch)
+
+	// the removed blocks are the ones of the new chain
+	ff.OnStateChanges(&remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{
+		change(remote.Direction_UNWIND, 3, common.Hash{0x33}),
+		change(remote.Direction_FORWARD, 4, common.Hash{0x4}),
+	}})
+	require.Equal(t, &Reorg{
+		CommonAncestorNumber: 3,
+		CommonAncestor:       common.Hash{0x33},
+		Removed:              []common.Hash{{0x44}},
+		Added:                []common.Hash{{0x4}},
+	}, <-ch)
+
+	require.True(t, ff.UnsubscribeReorgs(id))
+}
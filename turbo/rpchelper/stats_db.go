@@ -0,0 +1,170 @@
+package rpchelper
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// blockTables are the tables keyed by the number of the block, the reads of which count the block as read
+var blockTables = map[string]bool{
+	kv.Headers:         true,
+	kv.HeaderCanonical: true,
+	kv.BlockBody:       true,
+	kv.Receipts:        true,
+}
+
+// StatsDB counts the read transactions, the reads and the blocks read by the method calls in their request stats,
+// see rpc.RequestStats. The transactions begun outside of method calls aren't counted.
+type StatsDB struct {
+	kv.RoDB
+}
+
+func NewStatsDB(db kv.RoDB) *StatsDB {
+	return &StatsDB{RoDB: db}
+}
+
+func (db *StatsDB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	tx, err := db.RoDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := rpc.RequestStatsFromContext(ctx)
+	if stats == nil {
+		return tx, nil
+	}
+	stats.AddDBTx()
+	return &statsTx{Tx: tx, stats: stats}, nil
+}
+
+func (db *StatsDB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+// UnwrapTx returns the transaction of the database under the stats, to check its type.
+func UnwrapTx(tx kv.Tx) kv.Tx {
+	if s, ok := tx.(*statsTx); ok {
+		return s.Tx
+	}
+	return tx
+}
+
+type statsTx struct {
+	kv.Tx
+	stats *rpc.RequestStats
+}
+
+func (tx *statsTx) read(table string, k []byte) {
+	tx.stats.AddDBReads(1)
+	if blockTables[table] && len(k) >= 8 {
+		tx.stats.AddBlock(binary.BigEndian.Uint64(k))
+	}
+}
+
+func (tx *statsTx) GetOne(table string, key []byte) ([]byte, error) {
+	tx.read(table, key)
+	return tx.Tx.GetOne(table, key)
+}
+
+func (tx *statsTx) Has(table string, key []byte) (bool, error) {
+	tx.read(table, key)
+	return tx.Tx.Has(table, key)
+}
+
+func (tx *statsTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.Tx.ForEach(table, fromPrefix, tx.walker(table, walker))
+}
+
+func (tx *statsTx) ForPrefix(table string, prefix []byte, walker func(k, v []byte) error) error {
+	return tx.Tx.ForPrefix(table, prefix, tx.walker(table, walker))
+}
+
+func (tx *statsTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.Tx.ForAmount(table, prefix, amount, tx.walker(table, walker))
+}
+
+func (tx *statsTx) walker(table string, walker func(k, v []byte) error) func(k, v []byte) error {
+	return func(k, v []byte) error {
+		tx.read(table, k)
+		return walker(k, v)
+	}
+}
+
+func (tx *statsTx) Cursor(table string) (kv.Cursor, error) {
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return &statsCursor{Cursor: c, tx: tx, table: table}, nil
+}
+
+func (tx *statsTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return &statsCursorDupSort{CursorDupSort: c, statsCursor: statsCursor{Cursor: c, tx: tx, table: table}}, nil
+}
+
+// statsCursor counts a read per positioning of the cursor
+type statsCursor struct {
+	kv.Cursor
+	tx    *statsTx
+	table string
+}
+
+func (c *statsCursor) counted(k, v []byte, err error) ([]byte, []byte, error) {
+	c.tx.read(c.table, k)
+	return k, v, err
+}
+
+func (c *statsCursor) First() ([]byte, []byte, error)   { return c.counted(c.Cursor.First()) }
+func (c *statsCursor) Next() ([]byte, []byte, error)    { return c.counted(c.Cursor.Next()) }
+func (c *statsCursor) Prev() ([]byte, []byte, error)    { return c.counted(c.Cursor.Prev()) }
+func (c *statsCursor) Last() ([]byte, []byte, error)    { return c.counted(c.Cursor.Last()) }
+func (c *statsCursor) Current() ([]byte, []byte, error) { return c.Cursor.Current() }
+func (c *statsCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.counted(c.Cursor.Seek(seek))
+}
+func (c *statsCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.counted(c.Cursor.SeekExact(key))
+}
+
+type statsCursorDupSort struct {
+	kv.CursorDupSort
+	statsCursor
+}
+
+func (c *statsCursorDupSort) First() ([]byte, []byte, error)   { return c.statsCursor.First() }
+func (c *statsCursorDupSort) Next() ([]byte, []byte, error)    { return c.statsCursor.Next() }
+func (c *statsCursorDupSort) Prev() ([]byte, []byte, error)    { return c.statsCursor.Prev() }
+func (c *statsCursorDupSort) Last() ([]byte, []byte, error)    { return c.statsCursor.Last() }
+func (c *statsCursorDupSort) Current() ([]byte, []byte, error) { return c.statsCursor.Current() }
+func (c *statsCursorDupSort) Count() (uint64, error)           { return c.statsCursor.Count() }
+func (c *statsCursorDupSort) Close()                           { c.statsCursor.Close() }
+func (c *statsCursorDupSort) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.statsCursor.Seek(seek)
+}
+func (c *statsCursorDupSort) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.statsCursor.SeekExact(key)
+}
+func (c *statsCursorDupSort) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	return c.counted(c.CursorDupSort.SeekBothExact(key, value))
+}
+func (c *statsCursorDupSort) SeekBothRange(key, value []byte) ([]byte, error) {
+	c.tx.read(c.table, key)
+	return c.CursorDupSort.SeekBothRange(key, value)
+}
+func (c *statsCursorDupSort) NextDup() ([]byte, []byte, error) {
+	return c.counted(c.CursorDupSort.NextDup())
+}
+func (c *statsCursorDupSort) NextNoDup() ([]byte, []byte, error) {
+	return c.counted(c.CursorDupSort.NextNoDup())
+}
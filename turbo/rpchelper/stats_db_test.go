@@ -0,0 +1,52 @@
+package rpchelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsDB(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for number := uint64(1); number <= 3; number++ {
+			if err := tx.Put(kv.HeaderCanonical, dbutils.EncodeBlockNumber(number), []byte{byte(number)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	statsDB := NewStatsDB(db)
+
+	stats := &rpc.RequestStats{}
+	ctx := rpc.WithRequestStats(context.Background(), stats)
+	tx, err := statsDB.BeginRo(ctx)
+	require.NoError(t, err)
+	_, err = tx.GetOne(kv.HeaderCanonical, dbutils.EncodeBlockNumber(2))
+	require.NoError(t, err)
+	c, err := tx.Cursor(kv.HeaderCanonical)
+	require.NoError(t, err)
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		require.NoError(t, err)
+	}
+	c.Close()
+	tx.Rollback()
+
+	require.Equal(t, uint64(1), stats.DBTxs())
+	require.Equal(t, uint64(5), stats.DBReads()) // the get, the 3 keys and the end of the cursor
+	require.Equal(t, 3, stats.Blocks())
+	require.Equal(t, db, NewStatsDB(db).RoDB)
+
+	// the transactions outside of method calls aren't wrapped
+	tx, err = statsDB.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	require.Equal(t, tx, UnwrapTx(tx))
+	_, isStats := tx.(*statsTx)
+	require.False(t, isStats)
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"container/heap"
 	"fmt"
+	"runtime"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/c2h5oh/datasize"
 	"github.com/google/btree"
 	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 )
@@ -22,8 +24,41 @@ var (
 	AccRead    = metrics.GetOrCreateCounter(`cache_total{target="acc_read"}`)
 	StRead     = metrics.GetOrCreateCounter(`cache_total{target="st_read"}`)
 	WritesRead = metrics.GetOrCreateCounter(`cache_total{target="write"}`)
+
+	// per item type, indexed by id()
+	cacheHits      = itemTypeCounters(`state_cache_total{type="%s",result="hit"}`)
+	cacheMisses    = itemTypeCounters(`state_cache_total{type="%s",result="miss"}`)
+	cacheEvictions = itemTypeCounters(`state_cache_evict_total{type="%s"}`)
 )
 
+var itemTypes = [5]string{"account", "storage", "code", "account_trie", "storage_trie"}
+
+func itemTypeCounters(format string) (counters [5]*metrics.Counter) {
+	for i, itemType := range itemTypes {
+		counters[i] = metrics.GetOrCreateCounter(fmt.Sprintf(format, itemType))
+	}
+	return counters
+}
+
+func init() {
+	// each item type is cached in a B-tree of its own, their hit ratios since the start
+	for i, itemType := range itemTypes {
+		hits, misses := cacheHits[i], cacheMisses[i]
+		metrics.GetOrCreateGauge(fmt.Sprintf(`state_cache_hit_ratio{type="%s"}`, itemType), func() float64 {
+			return HitRatio(hits, misses)
+		})
+	}
+}
+
+// HitRatio returns the ratio of the hits to the reads of a cache, 0 before the first read.
+func HitRatio(hits, misses *metrics.Counter) float64 {
+	h, m := hits.Get(), misses.Get()
+	if h+m == 0 {
+		return 0
+	}
+	return float64(h) / float64(h+m)
+}
+
 const (
 	ModifiedFlag    uint16 = 1 // Set when the item is different seek what is last committed to the database
 	AbsentFlag      uint16 = 2 // Set when the item is absent in the state
@@ -406,10 +441,13 @@ func (sc *StateCache) Clone() *StateCache {
 
 func (sc *StateCache) get(key btree.Item) (CacheItem, bool) {
 	WritesRead.Inc()
-	item := sc.readWrites[id(key)].Get(key)
+	id := id(key)
+	item := sc.readWrites[id].Get(key)
 	if item == nil {
+		cacheMisses[id].Inc()
 		return nil, false
 	}
+	cacheHits[id].Inc()
 	cacheItem := item.(CacheItem)
 	if cacheItem.HasFlag(DeletedFlag) || cacheItem.HasFlag(AbsentFlag) {
 		return nil, true
@@ -532,20 +570,28 @@ func (sc *StateCache) setRead(item CacheItem, absent bool) {
 		item.ClearFlags(AbsentFlag)
 	}
 
-	if sc.limit != 0 && sc.readSize+item.GetSize() > int(sc.limit) {
-		for sc.readQueue[id].Len() > 0 && sc.readSize+item.GetSize() > int(sc.limit) {
-			// Read queue cannot grow anymore, need to evict one element
-			cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
-			sc.readSize -= cacheItem.GetSize()
-			sc.readWrites[id].Delete(cacheItem)
-		}
-	}
+	// Read queue cannot grow anymore, need to evict
+	sc.evict(id, item.GetSize())
 	// Push new element on the read queue
 	heap.Push(&sc.readQueue[id], item)
 	sc.readWrites[id].ReplaceOrInsert(item)
 	sc.readSize += item.GetSize()
 }
 
+// evict removes the least recently used reads of the given type until an item of the given size fits into the limit
+func (sc *StateCache) evict(id uint8, size int) {
+	for sc.limit != 0 && sc.readSize+size > int(sc.limit) && sc.readQueue[id].Len() > 0 {
+		sc.evictOldest(id)
+	}
+}
+
+func (sc *StateCache) evictOldest(id uint8) {
+	cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
+	sc.readWrites[id].Delete(cacheItem)
+	sc.readSize -= cacheItem.GetSize()
+	cacheEvictions[id].Inc()
+}
+
 func (sc *StateCache) readQueuesLen() (res int) {
 	for i := 0; i < len(sc.readQueue); i++ {
 		res += sc.readQueue[i].Len()
@@ -660,14 +706,8 @@ func (sc *StateCache) setWrite(item CacheItem, writeItem CacheWriteItem, delete
 		sc.writeSize += writeItem.GetSize()
 		return
 	}
-	if sc.limit != 0 && sc.readSize+item.GetSize() > int(sc.limit) {
-		for sc.readQueue[id].Len() > 0 && sc.readSize+item.GetSize() > int(sc.limit) {
-			// There is no space available, need to evict one read element
-			cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
-			sc.readWrites[id].Delete(cacheItem)
-			sc.readSize -= cacheItem.GetSize()
-		}
-	}
+	// There is no space available, need to evict read elements
+	sc.evict(id, item.GetSize())
 	item.SetSequence(sc.sequence)
 	sc.sequence++
 	item.SetFlags(ModifiedFlag)
@@ -1018,3 +1058,55 @@ func (sc *StateCache) WriteCount() (res int) {
 }
 func (sc *StateCache) WriteSize() int { return sc.writeSize }
 func (sc *StateCache) ReadSize() int  { return sc.readSize }
+
+// Limit returns the maximum size of the cache, 0 means unlimited
+func (sc *StateCache) Limit() datasize.ByteSize { return sc.limit }
+
+// SetLimit changes the maximum size of the cache. If the cache is larger, the least recently used reads
+// are evicted, regardless of their type. Writes are never evicted, so the cache may stay over the limit.
+func (sc *StateCache) SetLimit(limit datasize.ByteSize) {
+	sc.limit = limit
+	for limit != 0 && sc.readSize > int(limit) {
+		oldest := -1
+		for i := range sc.readQueue {
+			if sc.readQueue[i].Len() == 0 {
+				continue
+			}
+			if oldest == -1 || sc.readQueue[i].items[0].GetSequence() < sc.readQueue[oldest].items[0].GetSequence() {
+				oldest = i
+			}
+		}
+		if oldest == -1 {
+			return
+		}
+		sc.evictOldest(uint8(oldest))
+	}
+}
+
+// AdjustLimit resizes the cache to the memory pressure: while the heap in use exceeds memLimit, the cache
+// shrinks by the excess (down to 1/8 of maxLimit), otherwise it grows back towards maxLimit by the headroom.
+// It reads the runtime memory stats, which stops the world, so it should be called rarely - e.g. once per
+// batch of blocks. Returns the new limit.
+func (sc *StateCache) AdjustLimit(maxLimit, memLimit datasize.ByteSize) datasize.ByteSize {
+	var m runtime.MemStats
+	libcommon.ReadMemStats(&m)
+	sc.SetLimit(adaptiveLimit(sc.limit, maxLimit, datasize.ByteSize(m.HeapInuse), memLimit))
+	return sc.limit
+}
+
+func adaptiveLimit(limit, maxLimit, heapInUse, memLimit datasize.ByteSize) datasize.ByteSize {
+	if limit == 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+	minLimit := maxLimit / 8
+	if heapInUse > memLimit {
+		if excess := heapInUse - memLimit; limit > minLimit+excess {
+			return limit - excess
+		}
+		return minLimit
+	}
+	if headroom := memLimit - heapInUse; limit+headroom < maxLimit {
+		return limit + headroom
+	}
+	return maxLimit
+}
@@ -564,3 +564,41 @@ func TestWriteCodeExceedLimit(t *testing.T) {
 		sc.SetCodeWrite(addr.Bytes(), 1, code)
 	}
 }
+
+func TestSetLimitEvictsLeastRecentlyUsed(t *testing.T) {
+	sc := NewStateCache(32, 0 /* no limit */)
+	var addr1, addr2, addr3 common.Address
+	addr1[0], addr2[0], addr3[0] = 1, 2, 3
+	var account accounts.Account
+	sc.SetAccountRead(addr1.Bytes(), &account)
+	sc.SetStorageRead(addr2.Bytes(), 1, common.Hash{}.Bytes(), []byte{1})
+	sc.SetAccountRead(addr3.Bytes(), &account)
+	sc.SetAccountWrite(addr2.Bytes(), &account)
+	assert.Equal(t, 4, sc.TotalCount())
+
+	// the oldest reads go first, whatever their type, the write stays
+	sc.SetLimit(datasize.ByteSize(sc.ReadSize() - 1))
+	assert.Equal(t, 3, sc.TotalCount())
+	_, ok := sc.GetAccount(addr1.Bytes())
+	assert.False(t, ok)
+	_, ok = sc.GetStorage(addr2.Bytes(), 1, common.Hash{}.Bytes())
+	assert.True(t, ok)
+
+	sc.SetLimit(1)
+	assert.Equal(t, 1, sc.TotalCount())
+	_, ok = sc.GetAccount(addr2.Bytes())
+	assert.True(t, ok)
+	assert.Equal(t, datasize.ByteSize(1), sc.Limit())
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	const max = 800 * datasize.MB
+	// unset limit starts at the maximum
+	assert.Equal(t, max, adaptiveLimit(0, max, 1*datasize.GB, 2*datasize.GB))
+	// shrinks by the excess of the heap, but not below 1/8 of the maximum
+	assert.Equal(t, 700*datasize.MB, adaptiveLimit(max, max, 2148*datasize.MB, 2*datasize.GB))
+	assert.Equal(t, 100*datasize.MB, adaptiveLimit(max, max, 4*datasize.GB, 2*datasize.GB))
+	// grows back by the headroom, up to the maximum
+	assert.Equal(t, 300*datasize.MB, adaptiveLimit(100*datasize.MB, max, 1848*datasize.MB, 2*datasize.GB))
+	assert.Equal(t, max, adaptiveLimit(100*datasize.MB, max, 1*datasize.GB, 2*datasize.GB))
+}
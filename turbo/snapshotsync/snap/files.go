@@ -20,6 +20,8 @@ const (
 	Headers Type = iota
 	Bodies
 	Transactions
+	// State - flat state after the last block of segment, it's not a block snapshot (not in AllSnapshotTypes)
+	State
 	NumberOfTypes
 )
 
@@ -31,6 +33,8 @@ func (ft Type) String() string {
 		return "bodies"
 	case Transactions:
 		return "transactions"
+	case State:
+		return "state"
 	default:
 		panic(fmt.Sprintf("unknown file type: %d", ft))
 	}
@@ -44,6 +48,8 @@ func ParseFileType(s string) (Type, bool) {
 		return Bodies, true
 	case "transactions":
 		return Transactions, true
+	case "state":
+		return State, true
 	default:
 		return NumberOfTypes, false
 	}
@@ -122,6 +128,8 @@ func ParseFileName(dir, fileName string) (res FileInfo, err error) {
 		snapshotType = Bodies
 	case Transactions:
 		snapshotType = Transactions
+	case State:
+		snapshotType = State
 	default:
 		return res, fmt.Errorf("unexpected snapshot suffix: %s,%w", parts[2], ErrInvalidFileName)
 	}
@@ -0,0 +1,262 @@
+package snapshotsync
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloader/downloadercfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapshothashes"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// LatestStateSnapshot - preverified state snapshot of the biggest block not after given one, with its hash, ok=false if
+// there is none. The state snapshots which aren't preverified are ignored: their state is trusted, not re-executed.
+func LatestStateSnapshot(snapDir string, maxBlock uint64, preverified snapshothashes.Preverified) (f snap.FileInfo, hash string, ok bool, err error) {
+	list, err := snap.Segments(snapDir)
+	if err != nil {
+		return f, "", false, err
+	}
+	hashes := make(map[string]string, len(preverified))
+	for _, p := range preverified {
+		hashes[p.Name] = p.Hash
+	}
+	for _, it := range list {
+		if it.T != snap.State || it.To-1 > maxBlock {
+			continue
+		}
+		h, preverified := hashes[filepath.Base(it.Path)]
+		if !preverified {
+			log.Warn("[snapshots] Ignoring state snapshot, it's not preverified", "file", filepath.Base(it.Path))
+			continue
+		}
+		if !ok || it.To > f.To {
+			f, hash, ok = it, h, true
+		}
+	}
+	return f, hash, ok, nil
+}
+
+// VerifyStateSnapshot - checks that the torrent info hash of the state snapshot is the preverified one
+func VerifyStateSnapshot(f snap.FileInfo, hash string) error {
+	info := &metainfo.Info{PieceLength: downloadercfg.DefaultPieceSize}
+	if err := info.BuildFromFilePath(f.Path); err != nil {
+		return err
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if infoHash := metainfo.HashBytes(infoBytes).HexString(); infoHash != hash {
+		return fmt.Errorf("%s: info hash %s, expected (preverified): %s", filepath.Base(f.Path), infoHash, hash)
+	}
+	return nil
+}
+
+// DumpState - flat state after block blockTo-1 (end of segment [blockFrom, blockTo)), as of history of db
+// Format: pairs of words key, value: address_20bytes, account_encoded_for_storage
+// or: address_20bytes + incarnation_8bytes + location_32bytes, storage_value
+// or: code_hash_32bytes, code
+func DumpState(ctx context.Context, db kv.RoDB, snapDir, tmpDir string, blockFrom, blockTo uint64, workers int, lvl log.Lvl) error {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	segmentFile := filepath.Join(snapDir, snap.SegmentFileName(blockFrom, blockTo, snap.State))
+	f, err := compress.NewCompressor(ctx, "State", segmentFile, tmpDir, compress.MinPatternScore, workers, lvl)
+	if err != nil {
+		return fmt.Errorf("NewCompressor: %w, %s", err, segmentFile)
+	}
+	defer f.Close()
+
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	codeHashes := map[common.Hash]struct{}{}
+	var acc accounts.Account
+	var accs, slots uint64
+	if err := state.WalkAsOfAccounts(tx, common.Address{}, blockTo, func(k, v []byte) (bool, error) {
+		if len(k) != common.AddressLength {
+			return true, nil
+		}
+		if err := acc.DecodeForStorage(v); err != nil {
+			return false, fmt.Errorf("decoding %x for %x: %w", v, k, err)
+		}
+		// history doesn't have code hashes of contracts - restore them
+		if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
+			codeHash, err := tx.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(k, acc.Incarnation))
+			if err != nil {
+				return false, err
+			}
+			if len(codeHash) > 0 {
+				acc.CodeHash = common.BytesToHash(codeHash)
+			}
+		}
+		if !acc.IsEmptyCodeHash() {
+			codeHashes[acc.CodeHash] = struct{}{}
+		}
+		value := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(value)
+		if err := f.AddWord(k); err != nil {
+			return false, err
+		}
+		if err := f.AddWord(value); err != nil {
+			return false, err
+		}
+		accs++
+
+		if acc.Incarnation > 0 {
+			addr, incarnation := common.BytesToAddress(k), acc.Incarnation
+			if err := state.WalkAsOfStorage(tx, addr, incarnation, common.Hash{}, blockTo, func(_, loc, v []byte) (bool, error) {
+				if err := f.AddWord(dbutils.PlainGenerateCompositeStorageKey(addr[:], incarnation, loc)); err != nil {
+					return false, err
+				}
+				if err := f.AddWord(v); err != nil {
+					return false, err
+				}
+				slots++
+				return true, nil
+			}); err != nil {
+				return false, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-logEvery.C:
+			var m runtime.MemStats
+			if lvl >= log.LvlInfo {
+				common2.ReadMemStats(&m)
+			}
+			log.Log(lvl, "[snapshots] Dumping state", "block num", blockTo-1, "address", fmt.Sprintf("%x", k), "accounts", accs, "slots", slots,
+				"alloc", common2.ByteCount(m.Alloc), "sys", common2.ByteCount(m.Sys),
+			)
+		default:
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	for codeHash := range codeHashes {
+		code, err := tx.GetOne(kv.Code, codeHash[:])
+		if err != nil {
+			return err
+		}
+		if code == nil {
+			return fmt.Errorf("code missed in db: code_hash=%x", codeHash)
+		}
+		if err := f.AddWord(common.CopyBytes(codeHash[:])); err != nil {
+			return err
+		}
+		if err := f.AddWord(code); err != nil {
+			return err
+		}
+	}
+	if err := f.Compress(); err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+	log.Log(lvl, "[snapshots] Dumped state", "block num", blockTo-1, "accounts", accs, "slots", slots, "codes", len(codeHashes))
+	return nil
+}
+
+// LoadState - replace flat state of tx by the one of state snapshot. Hashed state and intermediate hashes are
+// removed: HashState and IntermediateHashes stages re-generate them from scratch, and check state root.
+func LoadState(ctx context.Context, tx kv.RwTx, f snap.FileInfo, tmpDir string) error {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	for _, table := range []string{kv.PlainState, kv.PlainContractCode, kv.HashedAccounts, kv.HashedStorage, kv.ContractCode, kv.TrieOfAccounts, kv.TrieOfStorage} {
+		if err := tx.ClearBucket(table); err != nil {
+			return err
+		}
+	}
+
+	d, err := compress.NewDecompressor(f.Path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	plainState := etl.NewCollector("LoadState", tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer plainState.Close()
+	contractCodes := etl.NewCollector("LoadState", tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer contractCodes.Close()
+	codes := etl.NewCollector("LoadState", tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer codes.Close()
+
+	var acc accounts.Account
+	var key, value []byte
+	if err := d.WithReadAhead(func() error {
+		g := d.MakeGetter()
+		for g.HasNext() {
+			key, _ = g.Next(key[:0])
+			if !g.HasNext() {
+				return fmt.Errorf("%s: value missed for key %x", f.Path, key)
+			}
+			value, _ = g.Next(value[:0])
+			switch len(key) {
+			case common.AddressLength:
+				if err := acc.DecodeForStorage(value); err != nil {
+					return fmt.Errorf("decoding %x for %x: %w", value, key, err)
+				}
+				if acc.Incarnation > 0 && !acc.IsEmptyCodeHash() {
+					if err := contractCodes.Collect(dbutils.PlainGenerateStoragePrefix(key, acc.Incarnation), acc.CodeHash[:]); err != nil {
+						return err
+					}
+				}
+				if err := plainState.Collect(key, value); err != nil {
+					return err
+				}
+			case common.AddressLength + common.IncarnationLength + common.HashLength:
+				if err := plainState.Collect(key, value); err != nil {
+					return err
+				}
+			case common.HashLength:
+				if err := codes.Collect(key, value); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s: unexpected key %x", f.Path, key)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-logEvery.C:
+				log.Info("[snapshots] Loading state", "file", filepath.Base(f.Path), "key", fmt.Sprintf("%x", key))
+			default:
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := plainState.Load(tx, kv.PlainState, etl.IdentityLoadFunc, etl.TransformArgs{Quit: ctx.Done()}); err != nil {
+		return err
+	}
+	if err := contractCodes.Load(tx, kv.PlainContractCode, etl.IdentityLoadFunc, etl.TransformArgs{Quit: ctx.Done()}); err != nil {
+		return err
+	}
+	if err := codes.Load(tx, kv.Code, etl.IdentityLoadFunc, etl.TransformArgs{Quit: ctx.Done()}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package snapshotsync
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloader/downloadercfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapshothashes"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpLoadState(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	encode := func(acc accounts.Account) []byte {
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		return v
+	}
+	eoa, contract := common.Address{1}, common.Address{2}
+	code := []byte{0x60, 0x00}
+	codeHash := crypto.Keccak256Hash(code)
+	slot := dbutils.PlainGenerateCompositeStorageKey(contract[:], 1, common.Hash{3}.Bytes())
+
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(t, tx.Put(kv.PlainState, eoa[:], encode(accounts.Account{Nonce: 1, Balance: *uint256.NewInt(5), CodeHash: crypto.Keccak256Hash(nil)})))
+		require.NoError(t, tx.Put(kv.PlainState, contract[:], encode(accounts.Account{Incarnation: 1, CodeHash: codeHash})))
+		require.NoError(t, tx.Put(kv.PlainState, slot, []byte{7}))
+		require.NoError(t, tx.Put(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(contract[:], 1), codeHash[:]))
+		return tx.Put(kv.Code, codeHash[:], code)
+	}))
+	require.NoError(t, DumpState(ctx, db, dir, dir, 0, 500_000, 1, log.LvlDebug))
+
+	fileName := snap.SegmentFileName(0, 500_000, snap.State)
+	info := &metainfo.Info{PieceLength: downloadercfg.DefaultPieceSize}
+	require.NoError(t, info.BuildFromFilePath(filepath.Join(dir, fileName)))
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+	preverified := snapshothashes.Preverified{{Name: fileName, Hash: metainfo.HashBytes(infoBytes).HexString()}}
+
+	// not preverified
+	_, _, ok, err := LatestStateSnapshot(dir, 499_999, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+	f, hash, ok, err := LatestStateSnapshot(dir, 499_999, preverified)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, snap.State, f.T)
+	require.NoError(t, VerifyStateSnapshot(f, hash))
+	require.Error(t, VerifyStateSnapshot(f, "0000000000000000000000000000000000000000"))
+	_, _, ok, err = LatestStateSnapshot(dir, 499_998, preverified)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, tx := memdb.NewTestTx(t)
+	// the state of genesis is replaced
+	require.NoError(t, tx.Put(kv.PlainState, common.Address{9}.Bytes(), encode(accounts.Account{Nonce: 1})))
+	require.NoError(t, LoadState(ctx, tx, f, dir))
+
+	has, err := tx.Has(kv.PlainState, common.Address{9}.Bytes())
+	require.NoError(t, err)
+	require.False(t, has)
+	for _, table := range []string{kv.PlainState, kv.PlainContractCode, kv.Code} {
+		require.NoError(t, db.View(ctx, func(expected kv.Tx) error {
+			return expected.ForEach(table, nil, func(k, v []byte) error {
+				got, err := tx.GetOne(table, k)
+				require.NoError(t, err)
+				require.Equal(t, v, got, "%s %x", table, k)
+				return nil
+			})
+		}))
+	}
+}
@@ -0,0 +1,67 @@
+package stages_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+func TestRecheckBlock(t *testing.T) {
+	m := stages.Mock(t)
+	signer := types.LatestSigner(m.ChainConfig)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 3, func(i int, b *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(m.Address), common.Address{1}, uint256.NewInt(1000), params.TxGas, uint256.NewInt(params.InitialBaseFee), nil), *signer, m.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.AddTx(tx)
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.InsertChain(chain); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := stagedsync.BlockRecheckCfg{ChainConfig: m.ChainConfig, Engine: m.Engine, BlockReader: snapshotsync.NewBlockReader(), VMConfig: vm.Config{}, TmpDir: t.TempDir()}
+	tx, err := m.DB.BeginRw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for n := uint64(1); n <= 3; n++ {
+		if err = stagedsync.RecheckBlock(context.Background(), tx, n, cfg); err != nil {
+			t.Errorf("block %d: %v", n, err)
+		}
+	}
+
+	// a lost change of the state
+	c, err := tx.RwCursorDupSort(kv.AccountChangeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, _, err = c.SeekExact(dbutils.EncodeBlockNumber(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteCurrent(); err != nil {
+		t.Fatal(err)
+	}
+	if err = stagedsync.RecheckBlock(context.Background(), tx, 2, cfg); err == nil {
+		t.Errorf("expected a mismatch of the change set of block 2")
+	}
+	if err = stagedsync.RecheckBlock(context.Background(), tx, 3, cfg); err != nil {
+		t.Errorf("block 3: %v", err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
@@ -43,7 +44,9 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
 
@@ -831,6 +834,130 @@ func doModesTest(t *testing.T, pm prune.Mode) error {
 	return nil
 }
 
+func TestBackfill(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+	)
+	pm := prune.DefaultMode
+	pm.History = prune.Before(4)
+	pm.Receipts = prune.Before(4)
+	pm.CallTraces = prune.Before(4)
+	m := stages.MockWithGenesisPruneMode(t, gspec, key, pm)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 6, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil), *types.LatestSignerForChainID(nil), key)
+		require.NoError(t, err)
+		block.AddTx(tx)
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+
+	receipts := func(blockNum uint64) types.Receipts {
+		tx, err := m.DB.BeginRo(m.Ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		return rawdb.ReadRawReceipts(tx, blockNum)
+	}
+	require.Nil(t, receipts(1))
+	require.NoError(t, m.DB.Update(m.Ctx, func(tx kv.RwTx) error { return prune.Override(tx, pm) }))
+
+	cfg := stagedsync.StageExecuteBlocksCfg(m.DB, pm, 512*datasize.MB, 1, false, false, nil, nil, m.ChainConfig, m.Engine, &vm.Config{}, nil,
+		false, true, t.TempDir(), snapshotsync.NewBlockReader(), nil)
+	require.NoError(t, stagedsync.Backfill(m.Ctx, cfg, gspec, 1, 3, stagedsync.BackfillKinds{History: true, Receipts: true, CallTraces: true}))
+
+	require.Equal(t, 1, len(receipts(1)))
+	require.Equal(t, 1, len(receipts(3)))
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	history, err := bitmapdb.Get64(tx, kv.AccountsHistory, address[:], 0, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6}, history.ToArray())
+	froms, err := bitmapdb.Get64(tx, kv.CallFromIndex, address[:], 0, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, froms.ToArray())
+	// pruning keeps the backfilled blocks
+	stored, err := prune.Get(tx)
+	require.NoError(t, err)
+	require.Equal(t, prune.Before(1), stored.Receipts)
+}
+
+func TestExecCache(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))}},
+		}
+		// increments the slots 0 and NUMBER
+		counter = common.FromHex("601180600b6000396000f3" + "6000546001016000554354600101435500")
+		// self-destructs to the caller
+		destruct = common.FromHex("600280600b6000396000f3" + "33ff")
+		gasPrice = uint256.NewInt(10 * params.GWei)
+	)
+	counterAddr, destructAddr := crypto.CreateAddress(address, 0), crypto.CreateAddress(address, 1)
+	m := stages.MockWithGenesis(t, gspec, key)
+	cached := stages.MockWithExecCache(t, gspec, key)
+
+	signer := types.LatestSignerForChainID(nil)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 8, func(i int, block *core.BlockGen) {
+		var txs []types.Transaction
+		switch i {
+		case 0:
+			txs = append(txs,
+				types.NewContractCreation(block.TxNonce(address), new(uint256.Int), 1e6, gasPrice, counter),
+				types.NewContractCreation(block.TxNonce(address)+1, new(uint256.Int), 1e6, gasPrice, destruct))
+		case 3:
+			txs = append(txs, types.NewTransaction(block.TxNonce(address), destructAddr, new(uint256.Int), 1e6, gasPrice, nil))
+		case 5:
+			// the destructed account is created again
+			txs = append(txs, types.NewTransaction(block.TxNonce(address), destructAddr, uint256.NewInt(1), 21000, gasPrice, nil))
+		}
+		for j := 0; j < 2; j++ {
+			txs = append(txs, types.NewTransaction(block.TxNonce(address)+uint64(len(txs)), counterAddr, new(uint256.Int), 1e6, gasPrice, nil))
+		}
+		txs = append(txs, types.NewTransaction(block.TxNonce(address)+uint64(len(txs)), common.Address{byte(i + 1)}, uint256.NewInt(1), 21000, gasPrice, nil))
+		for _, txn := range txs {
+			signed, err := types.SignTx(txn, *signer, key)
+			require.NoError(t, err)
+			block.AddTx(signed)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+	require.NoError(t, cached.InsertChain(chain))
+
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	cachedTx, err := cached.DB.BeginRo(cached.Ctx)
+	require.NoError(t, err)
+	defer cachedTx.Rollback()
+	var slot common.Hash
+	count, err := state.NewPlainStateReader(cachedTx).ReadAccountStorage(counterAddr, 1, &slot)
+	require.NoError(t, err)
+	require.Equal(t, []byte{16}, count)
+	for _, table := range []string{kv.PlainState, kv.PlainContractCode, kv.Code, kv.IncarnationMap,
+		kv.AccountChangeSet, kv.StorageChangeSet, kv.Receipts} {
+		require.Equal(t, tableContent(t, tx, table), tableContent(t, cachedTx, table), table)
+	}
+}
+
+func tableContent(t *testing.T, tx kv.Tx, table string) map[string]string {
+	content := map[string]string{}
+	require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+		content[string(k)] = string(v)
+		return nil
+	}))
+	require.NotEmpty(t, content, table)
+	return content
+}
+
 func runWithModesPermuations(t *testing.T, testFunc func(*testing.T, prune.Mode) error) {
 	err := runPermutation(t, testFunc, 0, prune.DefaultMode)
 	if err != nil {
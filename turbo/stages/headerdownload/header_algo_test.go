@@ -7,6 +7,8 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -59,3 +61,36 @@ func TestInserter1(t *testing.T) {
 		t.Errorf("feed empty header 2: %v", err)
 	}
 }
+
+func TestInsertMinedHeader(t *testing.T) {
+	hd := NewHeaderDownload(100, 100, ethash.NewFullFaker(), nil)
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)}
+	genesisRaw, _ := rlp.EncodeToBytes(genesis)
+	hd.addHeaderAsLink(ChainSegmentHeader{Header: genesis, HeaderRaw: genesisRaw, Hash: genesis.Hash(), Number: 0}, true /* persisted */)
+
+	// A block mined here, and a block of another signer on top of it
+	mined := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2), ParentHash: genesis.Hash()}
+	if err := hd.AddMinedHeader(mined); err != nil {
+		t.Fatal(err)
+	}
+	next := &types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(2), ParentHash: mined.Hash()}
+	nextRaw, _ := rlp.EncodeToBytes(next)
+	hd.ProcessHeaders([]ChainSegmentHeader{{Header: next, HeaderRaw: nextRaw, Hash: next.Hash(), Number: 2}}, true /* newBlock */, [64]byte{1})
+
+	var inserted []uint64
+	hf := func(header *types.Header, headerRaw []byte, hash common.Hash, blockHeight uint64) (*big.Int, error) {
+		inserted = append(inserted, blockHeight)
+		return nil, nil
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := hd.InsertHeader(hf, nil, "headers", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(inserted) != 2 || inserted[0] != 1 || inserted[1] != 2 {
+		t.Fatalf("inserted blocks %v, expected [1 2]", inserted)
+	}
+	if hd.highestInDb != 2 {
+		t.Fatalf("highest in db %d, expected 2", hd.highestInDb)
+	}
+}
@@ -560,10 +560,6 @@ func (hd *HeaderDownload) InsertHeader(hf FeedHeaderFunc, terminalTotalDifficult
 				lastD = link.header.Difficulty
 			}
 		}
-		if link.blockHeight == hd.latestMinedBlockNumber {
-			return false, true, 0, nil
-		}
-
 		if link.blockHeight > hd.highestInDb {
 			if hd.trace {
 				log.Info("Highest in DB change", "number", link.blockHeight, "hash", link.hash)
@@ -579,6 +575,10 @@ func (hd *HeaderDownload) InsertHeader(hf FeedHeaderFunc, terminalTotalDifficult
 				hd.moveLinkToQueue(child, InsertQueueID)
 			}
 		}
+		// the mined block is inserted like the others, so that the blocks of the other signers follow it
+		if link.blockHeight == hd.latestMinedBlockNumber {
+			return false, true, 0, nil
+		}
 	}
 	for hd.persistedLinkQueue.Len() > hd.persistedLinkLimit {
 		link := heap.Pop(&hd.persistedLinkQueue).(*Link)
@@ -1347,6 +1347,40 @@ func (hd *HeaderDownload) AddMinedHeader(header *types.Header) error {
 	return nil
 }
 
+// RequestUnwind asks the headers stage to unwind the chain to the given block, and forgets the headers above it,
+// so that the same blocks can be mined and inserted again
+func (hd *HeaderDownload) RequestUnwind(blockHeight uint64) {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	for _, link := range hd.links {
+		if link.blockHeight == blockHeight {
+			link.fChild = nil
+		} else if link.blockHeight > blockHeight {
+			hd.removeUpwards(link)
+		}
+	}
+	if hd.highestInDb > blockHeight {
+		hd.highestInDb = blockHeight
+	}
+	hd.unwindRequest = &blockHeight
+	select {
+	case hd.DeliveryNotify <- struct{}{}:
+	default:
+	}
+}
+
+// UnwindRequest returns the block to unwind to passed to RequestUnwind, once
+func (hd *HeaderDownload) UnwindRequest() (uint64, bool) {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	if hd.unwindRequest == nil {
+		return 0, false
+	}
+	blockHeight := *hd.unwindRequest
+	hd.unwindRequest = nil
+	return blockHeight, true
+}
+
 func (hd *HeaderDownload) AddHeadersFromSnapshot(tx kv.Tx, n uint64, r services.FullBlockReader) error {
 	hd.lock.Lock()
 	defer hd.lock.Unlock()
@@ -300,6 +300,7 @@ type HeaderDownload struct {
 	fetchingNew            bool   // Set when the stage that is actively fetching the headers is in progress
 	topSeenHeightPoW       uint64
 	latestMinedBlockNumber uint64
+	unwindRequest          *uint64 // Block to unwind to, requested by the developer chain
 	QuitPoWMining          chan struct{}
 	trace                  bool
 	stats                  Stats
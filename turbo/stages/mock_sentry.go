@@ -28,6 +28,7 @@ import (
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -187,6 +188,18 @@ func MockWithGenesisPruneMode(t *testing.T, gspec *core.Genesis, key *ecdsa.Priv
 }
 
 func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool) *MockSentry {
+	return mockWithEverything(t, gspec, key, prune, engine, withTxPool, nil)
+}
+
+// MockWithExecCache is a mock whose execution stage caches the state read across the blocks
+func MockWithExecCache(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey) *MockSentry {
+	return mockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), false, func(cfg *ethconfig.Config) {
+		cfg.ExecCache = true
+	})
+}
+
+// mockWithEverything - configure changes the defaults of the node config of the mock, if not nil
+func mockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, configure func(*ethconfig.Config)) *MockSentry {
 	var tmpdir string
 	if t != nil {
 		tmpdir = t.TempDir()
@@ -233,6 +246,9 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	cfg.Sync.BodyDownloadTimeoutSeconds = 10
 	cfg.DeprecatedTxPool.Disable = !withTxPool
 	cfg.DeprecatedTxPool.StartOnInit = true
+	if configure != nil {
+		configure(&cfg)
+	}
 
 	mock.SentryClient = direct.NewSentryClientDirect(eth.ETH66, mock)
 	sentries := []direct.SentryClient{mock.SentryClient}
@@ -309,7 +325,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 
 	mock.Sync = stagedsync.New(
 		stagedsync.DefaultStages(mock.Ctx, prune,
-			stagedsync.StageHeadersCfg(mock.DB, mock.sentriesClient.Hd, mock.sentriesClient.Bd, *mock.ChainConfig, sendHeaderRequest, propagateNewBlockHashes, penalize, cfg.BatchSize, false, false, allSnapshots, snapshotsDownloader, blockReader, mock.tmpdir, mock.Notifications.Events, mock.Notifications, nil),
+			stagedsync.StageHeadersCfg(mock.DB, mock.sentriesClient.Hd, mock.sentriesClient.Bd, *mock.ChainConfig, sendHeaderRequest, propagateNewBlockHashes, penalize, cfg.BatchSize, false, false, cfg.Sync.BackfillFromTip, false, allSnapshots, snapshotsDownloader, blockReader, mock.tmpdir, mock.Notifications.Events, mock.Notifications, nil),
 			stagedsync.StageCumulativeIndexCfg(mock.DB),
 			stagedsync.StageBlockHashesCfg(mock.DB, mock.tmpdir, mock.ChainConfig),
 			stagedsync.StageBodiesCfg(
@@ -330,6 +346,10 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 				mock.DB,
 				prune,
 				cfg.BatchSize,
+				cfg.ExecWorkers,
+				cfg.ExecCache,
+				cfg.StreamCommitment,
+				state.PlainBackend,
 				nil,
 				mock.ChainConfig,
 				mock.Engine,
@@ -346,6 +366,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 			stagedsync.StageTrieCfg(mock.DB, true, true, false, mock.tmpdir, blockReader, nil),
 			stagedsync.StageHistoryCfg(mock.DB, prune, mock.tmpdir),
 			stagedsync.StageLogIndexCfg(mock.DB, prune, mock.tmpdir),
+			stagedsync.StageTokenTransfersCfg(mock.DB, prune),
 			stagedsync.StageCallTracesCfg(mock.DB, prune, 0, mock.tmpdir),
 			stagedsync.StageTxLookupCfg(mock.DB, prune, mock.tmpdir, allSnapshots, isBor),
 			stagedsync.StageFinishCfg(mock.DB, mock.tmpdir, mock.Log, nil), true),
@@ -367,7 +388,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	mock.MiningSync = stagedsync.New(
 		stagedsync.MiningStages(mock.Ctx,
 			stagedsync.StageMiningCreateBlockCfg(mock.DB, miner, *mock.ChainConfig, mock.Engine, mock.TxPool, nil, nil, mock.tmpdir),
-			stagedsync.StageMiningExecCfg(mock.DB, miner, nil, *mock.ChainConfig, mock.Engine, &vm.Config{}, mock.tmpdir, nil),
+			stagedsync.StageMiningExecCfg(mock.DB, miner, nil, *mock.ChainConfig, mock.Engine, &vm.Config{}, mock.tmpdir, nil, nil),
 			stagedsync.StageHashStateCfg(mock.DB, mock.tmpdir),
 			stagedsync.StageTrieCfg(mock.DB, false, true, false, mock.tmpdir, blockReader, nil),
 			stagedsync.StageMiningFinishCfg(mock.DB, *mock.ChainConfig, mock.Engine, miner, mock.Ctx.Done()),
@@ -434,6 +455,25 @@ func MockWithZeroTTD(t *testing.T) *MockSentry {
 	return MockWithGenesis(t, gspec, key)
 }
 
+// MockWithBackfillFromTip is a mock of a PoW chain far from its terminal total difficulty, whose headers are
+// backfilled from the head given by the consensus layer
+func MockWithBackfillFromTip(t *testing.T) *MockSentry {
+	funds := big.NewInt(1 * params.Ether)
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	chainConfig := *params.AllEthashProtocolChanges
+	chainConfig.TerminalTotalDifficulty = big.NewInt(1_000_000_000_000)
+	gspec := &core.Genesis{
+		Config: &chainConfig,
+		Alloc: core.GenesisAlloc{
+			address: {Balance: funds},
+		},
+	}
+	return mockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), false, func(cfg *ethconfig.Config) {
+		cfg.Sync.BackfillFromTip = true
+	})
+}
+
 func (ms *MockSentry) EnableLogs() {
 	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.StderrHandler))
 	ms.t.Cleanup(func() {
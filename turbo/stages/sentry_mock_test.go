@@ -620,6 +620,54 @@ func TestPoSDownloader(t *testing.T) {
 	assert.Equal(t, chain.TopBlock.Hash(), headBlockHash)
 }
 
+func TestBackfillFromTip(t *testing.T) {
+	m := stages.MockWithBackfillFromTip(t)
+
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 3 /* n */, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+
+	// The PoW head is given by the consensus layer, long before the terminal total difficulty
+	forkChoiceMessage := engineapi.ForkChoiceMessage{
+		HeadBlockHash:      chain.TopBlock.Hash(),
+		SafeBlockHash:      chain.TopBlock.Hash(),
+		FinalizedBlockHash: chain.TopBlock.Hash(),
+	}
+	m.SendForkChoiceRequest(&forkChoiceMessage)
+	headBlockHash, err := stages.StageLoopStep(m.Ctx, m.DB, m.Sync, 0, m.Notifications, true, m.UpdateHead, nil)
+	require.NoError(t, err)
+	stages.SendPayloadStatus(m.HeaderDownload(), headBlockHash, err)
+
+	payloadStatus := m.ReceivePayloadStatus()
+	assert.Equal(t, remote.EngineStatus_SYNCING, payloadStatus.Status)
+	assert.True(t, m.HeaderDownload().POSSync(), "the headers are downloaded backwards")
+
+	// Send the headers, from the head down to the local chain
+	b, err := rlp.EncodeToBytes(&eth.BlockHeadersPacket66{
+		RequestId:          1,
+		BlockHeadersPacket: chain.Headers,
+	})
+	require.NoError(t, err)
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: sentry.MessageId_BLOCK_HEADERS_66, Data: b, PeerId: m.PeerId}) {
+		require.NoError(t, err)
+	}
+	m.ReceiveWg.Wait()
+
+	// First cycle: save the downloaded headers
+	headBlockHash, err = stages.StageLoopStep(m.Ctx, m.DB, m.Sync, 0, m.Notifications, false, m.UpdateHead, nil)
+	require.NoError(t, err)
+	stages.SendPayloadStatus(m.HeaderDownload(), headBlockHash, err)
+
+	// Second cycle: process the fork choice again, now that its head is known
+	headBlockHash, err = stages.StageLoopStep(m.Ctx, m.DB, m.Sync, 0, m.Notifications, false, m.UpdateHead, nil)
+	require.NoError(t, err)
+	stages.SendPayloadStatus(m.HeaderDownload(), headBlockHash, err)
+
+	assert.Equal(t, chain.TopBlock.Hash(), headBlockHash)
+}
+
 // https://hackmd.io/GDc0maGsQeKfP8o2C7L52w
 func TestPoSSyncWithInvalidHeader(t *testing.T) {
 	m := stages.MockWithZeroTTD(t)
@@ -18,9 +18,11 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/profiler"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
@@ -319,6 +321,21 @@ func StateStep(ctx context.Context, batch kv.RwTx, stateSync *stagedsync.Sync, h
 	return nil
 }
 
+// lookupStateBackend returns the state backend given, e.g. of a forked chain, or the one named by cfg.StateBackend
+func lookupStateBackend(cfg ethconfig.Config, stateBackend state.Backend) (state.Backend, error) {
+	if stateBackend != nil {
+		if cfg.StateBackend != "" && cfg.StateBackend != state.PlainBackendName {
+			return nil, fmt.Errorf("state backend %q can't be used by the forked chain", cfg.StateBackend)
+		}
+		return stateBackend, nil
+	}
+	stateBackend, ok := state.LookupBackend(cfg.StateBackend)
+	if !ok {
+		return nil, fmt.Errorf("unknown state backend %q, registered: %v", cfg.StateBackend, state.Backends())
+	}
+	return stateBackend, nil
+}
+
 func NewStagedSync(
 	ctx context.Context,
 	logger log.Logger,
@@ -332,7 +349,15 @@ func NewStagedSync(
 	snapshots *snapshotsync.RoSnapshots,
 	headCh chan *types.Block,
 	execPayload stagedsync.ExecutePayloadFunc,
+	stateBackend state.Backend,
 ) (*stagedsync.Sync, error) {
+	stateBackend, err := lookupStateBackend(cfg, stateBackend)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ExecProfile {
+		profiler.Enable()
+	}
 	var blockReader services.FullBlockReader
 	if cfg.Snapshot.Enabled {
 		blockReader = snapshotsync.NewBlockReaderWithSnapshots(snapshots)
@@ -345,7 +370,7 @@ func NewStagedSync(
 	// Hence we run it in the test mode.
 	runInTestMode := cfg.ImportMode
 	isBor := controlServer.ChainConfig.Bor != nil
-	return stagedsync.New(
+	stagesList, unwindOrder, pruneOrder, err := stagedsync.WithCustomStages(ctx,
 		stagedsync.DefaultStages(ctx, cfg.Prune,
 			stagedsync.StageHeadersCfg(
 				db,
@@ -358,6 +383,8 @@ func NewStagedSync(
 				cfg.BatchSize,
 				p2pCfg.NoDiscovery,
 				cfg.MemoryOverlay,
+				cfg.Sync.BackfillFromTip,
+				cfg.Miner.Dev,
 				snapshots,
 				snapDownloader,
 				blockReader,
@@ -385,6 +412,10 @@ func NewStagedSync(
 				db,
 				cfg.Prune,
 				cfg.BatchSize,
+				cfg.ExecWorkers,
+				cfg.ExecCache,
+				cfg.StreamCommitment,
+				stateBackend,
 				nil,
 				controlServer.ChainConfig,
 				controlServer.Engine,
@@ -401,21 +432,33 @@ func NewStagedSync(
 			stagedsync.StageTrieCfg(db, true, true, false, tmpdir, blockReader, controlServer.Hd),
 			stagedsync.StageHistoryCfg(db, cfg.Prune, tmpdir),
 			stagedsync.StageLogIndexCfg(db, cfg.Prune, tmpdir),
+			stagedsync.StageTokenTransfersCfg(db, cfg.Prune),
 			stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, tmpdir),
 			stagedsync.StageTxLookupCfg(db, cfg.Prune, tmpdir, snapshots, isBor),
 			stagedsync.StageFinishCfg(db, tmpdir, logger, headCh), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
-	), nil
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stagedsync.New(stagesList, unwindOrder, pruneOrder), nil
 }
 
-func NewInMemoryExecution(ctx context.Context, logger log.Logger, db kv.RwDB, cfg ethconfig.Config, controlServer *sentry.MultiClient, tmpdir string, notifications *stagedsync.Notifications, snapshots *snapshotsync.RoSnapshots) (*stagedsync.Sync, error) {
+func NewInMemoryExecution(ctx context.Context, logger log.Logger, db kv.RwDB, cfg ethconfig.Config, controlServer *sentry.MultiClient, tmpdir string, notifications *stagedsync.Notifications, snapshots *snapshotsync.RoSnapshots, stateBackend state.Backend) (*stagedsync.Sync, error) {
+	stateBackend, err := lookupStateBackend(cfg, stateBackend)
+	if err != nil {
+		return nil, err
+	}
 	var blockReader services.FullBlockReader
 	if cfg.Snapshot.Enabled {
 		blockReader = snapshotsync.NewBlockReaderWithSnapshots(snapshots)
 	} else {
 		blockReader = snapshotsync.NewBlockReader()
 	}
+	// the stem tree follows the canonical chain only
+	execPrune := cfg.Prune
+	execPrune.Experiments.StemTree = false
 
 	return stagedsync.New(
 		stagedsync.StateStages(ctx,
@@ -430,6 +473,8 @@ func NewInMemoryExecution(ctx context.Context, logger log.Logger, db kv.RwDB, cf
 				cfg.BatchSize,
 				false,
 				cfg.MemoryOverlay,
+				false,
+				false,
 				snapshots,
 				nil,
 				blockReader,
@@ -450,8 +495,12 @@ func NewInMemoryExecution(ctx context.Context, logger log.Logger, db kv.RwDB, cf
 			stagedsync.StageSendersCfg(db, controlServer.ChainConfig, true, tmpdir, cfg.Prune, nil, controlServer.Hd),
 			stagedsync.StageExecuteBlocksCfg(
 				db,
-				cfg.Prune,
+				execPrune,
 				cfg.BatchSize,
+				cfg.ExecWorkers,
+				cfg.ExecCache,
+				cfg.StreamCommitment,
+				stateBackend,
 				nil,
 				controlServer.ChainConfig,
 				controlServer.Engine,
@@ -33,6 +33,7 @@ func DoCall(
 	chainConfig *params.ChainConfig,
 	filters *rpchelper.Filters,
 	stateCache kvcache.Cache,
+	stateOverlay rpchelper.StateOverlay,
 	contractHasTEVM func(hash common.Hash) (bool, error),
 	headerReader services.HeaderReader,
 ) (*core.ExecutionResult, error) {
@@ -43,7 +44,7 @@ func DoCall(
 			return state, block.Header(), nil
 		}
 	*/
-	stateReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, filters, stateCache)
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, filters, stateCache, stateOverlay)
 	if err != nil {
 		return nil, err
 	}
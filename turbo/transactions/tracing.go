@@ -19,6 +19,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/tracers"
+	_ "github.com/ledgerwatch/erigon/eth/tracers/native" // the native tracers register themselves
 	"github.com/ledgerwatch/erigon/params"
 )
 
@@ -89,9 +90,10 @@ func TraceTx(
 	chainConfig *params.ChainConfig,
 	stream *jsoniter.Stream,
 ) error {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger, the native tracer or the JavaScript tracer
 	var (
 		tracer vm.Tracer
+		native tracers.NativeTracer
 		err    error
 	)
 	var streaming bool
@@ -105,18 +107,30 @@ func TraceTx(
 				return err
 			}
 		}
-		// Construct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer, &tracers.Context{
-			TxHash: txCtx.TxHash,
-		}); err != nil {
+		// Construct the native tracer, or else the JavaScript tracer, to execute with
+		var stop func(error)
+		var isNative bool
+		if native, isNative, err = tracers.NewNative(*config.Tracer, config.TracerConfig); err != nil {
 			stream.WriteNil()
 			return err
 		}
+		if isNative {
+			tracer, stop = native, native.Stop
+		} else {
+			jsTracer, err := tracers.New(*config.Tracer, &tracers.Context{
+				TxHash: txCtx.TxHash,
+			})
+			if err != nil {
+				stream.WriteNil()
+				return err
+			}
+			tracer, stop = jsTracer, jsTracer.Stop
+		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 		streaming = false
@@ -140,6 +154,9 @@ func TraceTx(
 		stream.WriteObjectField("structLogs")
 		stream.WriteArrayStart()
 	}
+	if native != nil {
+		native.CaptureTxStart(message.Gas())
+	}
 	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), refunds, false /* gasBailout */)
 	if err != nil {
 		if streaming {
@@ -148,6 +165,9 @@ func TraceTx(
 		}
 		return fmt.Errorf("tracing failed: %w", err)
 	}
+	if native != nil {
+		native.CaptureTxEnd(message.Gas() - result.UsedGas)
+	}
 	// Depending on the tracer type, format and return the output
 	if streaming {
 		stream.WriteArrayEnd()
@@ -166,6 +186,8 @@ func TraceTx(
 		stream.WriteObjectField("returnValue")
 		stream.WriteString(returnVal)
 		stream.WriteObjectEnd()
+	} else if native != nil {
+		return native.WriteResult(stream)
 	} else {
 		if r, err1 := tracer.(*tracers.Tracer).GetResult(); err1 == nil {
 			stream.Write(r)
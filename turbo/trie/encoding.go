@@ -72,6 +72,11 @@ func compactToHex(compact []byte) []byte {
 	return base[chop:]
 }
 
+// CompactToHex translates from COMPACT to HEX encoding, without the terminator
+func CompactToHex(compact []byte) []byte {
+	return compactToHex(compact)
+}
+
 // Keybytes represent a packed encoding of hex sequences
 // where 2 nibbles per byte are stored in Data
 // + an additional flag for terminating nodes.
@@ -95,3 +95,80 @@ func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error)
 	}
 	return proof, nil
 }
+
+// EncodedNode returns the encoding of the node at the path of nibbles hex, in the storage trie of the account if
+// accountKey is given, like the trie nodes served by the snap protocol. nil if no node starts at the path.
+func (t *Trie) EncodedNode(accountKey []byte, hex []byte) ([]byte, error) {
+	tn := t.root
+	if accountKey != nil {
+		accountHex := keybytesToHex(accountKey)
+		n, err := nodeAt(tn, accountHex[:len(accountHex)-1])
+		if err != nil {
+			return nil, err
+		}
+		acc, ok := n.(*accountNode)
+		if !ok {
+			return nil, nil
+		}
+		tn = acc.storage
+	}
+	n, err := nodeAt(tn, hex)
+	if err != nil {
+		return nil, err
+	}
+	switch n.(type) {
+	case *shortNode, *duoNode, *fullNode:
+		hasher := newHasher(false)
+		defer returnHasherToPool(hasher)
+		rlp, err := hasher.hashChildren(n, 0)
+		if err != nil {
+			return nil, err
+		}
+		return common.CopyBytes(rlp), nil
+	default:
+		return nil, nil
+	}
+}
+
+// nodeAt returns the node starting at the path of nibbles hex below tn, nil if the path ends inside a node or
+// doesn't exist
+func nodeAt(tn node, hex []byte) (node, error) {
+	for len(hex) > 0 && tn != nil {
+		switch n := tn.(type) {
+		case *shortNode:
+			nKey := n.Key
+			if nKey[len(nKey)-1] == 16 {
+				nKey = nKey[:len(nKey)-1]
+			}
+			if len(hex) < len(nKey) || !bytes.Equal(nKey, hex[:len(nKey)]) {
+				return nil, nil
+			}
+			tn, hex = n.Val, hex[len(nKey):]
+		case *duoNode:
+			i1, i2 := n.childrenIdx()
+			switch hex[0] {
+			case i1:
+				tn = n.child1
+			case i2:
+				tn = n.child2
+			default:
+				return nil, nil
+			}
+			hex = hex[1:]
+		case *fullNode:
+			if hex[0] >= 16 {
+				return nil, nil
+			}
+			tn, hex = n.Children[hex[0]], hex[1:]
+		case hashNode:
+			return nil, fmt.Errorf("encountered hashNode unexpectedly, remaining path %x", hex)
+		default:
+			// the values and the accounts are leaves of their trie
+			return nil, nil
+		}
+	}
+	if _, ok := tn.(hashNode); ok {
+		return nil, fmt.Errorf("encountered hashNode unexpectedly at the end of the path")
+	}
+	return tn, nil
+}
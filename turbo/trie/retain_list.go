@@ -53,6 +53,17 @@ func NewRetainList(minLength int) *RetainList {
 	return &RetainList{minLength: minLength, codeTouches: make(map[common.Hash]struct{})}
 }
 
+// Copy returns a list of the same keys, more keys can be added to it without changing this one
+func (rl *RetainList) Copy() *RetainList {
+	c := NewRetainList(rl.minLength)
+	c.hexes = append(c.hexes, rl.hexes...)
+	c.markers = append(c.markers, rl.markers...)
+	for codeHash := range rl.codeTouches {
+		c.codeTouches[codeHash] = struct{}{}
+	}
+	return c
+}
+
 func (rl *RetainList) Len() int {
 	return len(rl.hexes)
 }
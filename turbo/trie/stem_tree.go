@@ -0,0 +1,259 @@
+package trie
+
+import (
+	"bytes"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+const (
+	StemTreeWidth = 256 // number of children of the nodes
+	StemLength    = 31  // the keys are a stem and the index of the value in its leaf
+)
+
+// StemCommitter computes the commitments of a stem tree, and derives its keys. The shape of the tree only
+// depends on the width of the nodes and on the key derivation, so the commitment scheme - Pedersen vector
+// commitments over Bandersnatch in the verkle proposal - is pluggable.
+type StemCommitter interface {
+	// TreeKeyStem derives the stem of the keys of the account at the given tree index.
+	TreeKeyStem(address []byte, treeIndex *uint256.Int) []byte
+	// CommitLeaf commits to the stem and the values of a leaf, nil for the absent ones.
+	CommitLeaf(stem []byte, values *[StemTreeWidth][]byte) []byte
+	// CommitInternal commits to the commitments of the children of a node, nil for the absent ones.
+	CommitInternal(children *[StemTreeWidth][]byte) []byte
+}
+
+// KeccakStemCommitter is a stand-in for the vector commitments of the verkle proposal, hashing the nodes with
+// Keccak256 instead. It doesn't produce the roots of the proposal, nor its compact proofs, but lets measure the
+// tree - e.g. the number of nodes touched by the blocks, or the duration of a migration.
+type KeccakStemCommitter struct{}
+
+func (KeccakStemCommitter) TreeKeyStem(address []byte, treeIndex *uint256.Int) []byte {
+	var buf [64]byte
+	copy(buf[32-len(address):32], address)
+	index := treeIndex.Bytes32()
+	for i := 0; i < 32; i++ { // little-endian, as in the proposal
+		buf[32+i] = index[31-i]
+	}
+	return crypto.Keccak256(buf[:])[:StemLength]
+}
+
+func (KeccakStemCommitter) CommitLeaf(stem []byte, values *[StemTreeWidth][]byte) []byte {
+	h := crypto.NewKeccakState()
+	h.Write([]byte{1}) //nolint:errcheck
+	h.Write(stem)      //nolint:errcheck
+	for i, v := range values {
+		if v != nil {
+			h.Write([]byte{byte(i), byte(len(v))}) //nolint:errcheck
+			h.Write(v)                             //nolint:errcheck
+		}
+	}
+	return h.Sum(nil)
+}
+
+func (KeccakStemCommitter) CommitInternal(children *[StemTreeWidth][]byte) []byte {
+	h := crypto.NewKeccakState()
+	h.Write([]byte{2}) //nolint:errcheck
+	for i, c := range children {
+		if c != nil {
+			h.Write([]byte{byte(i)}) //nolint:errcheck
+			h.Write(c)               //nolint:errcheck
+		}
+	}
+	return h.Sum(nil)
+}
+
+// StemTree is an in-memory tree shaped like the verkle tree: the internal nodes have StemTreeWidth children indexed
+// by the bytes of the stems, the leaves hold the StemTreeWidth values of a stem. The commitments of the nodes are
+// cached, and only the ones on the paths of the changed values are computed again.
+type StemTree struct {
+	committer StemCommitter
+	root      *stemInternal
+	leaves    int
+}
+
+type stemInternal struct {
+	children   [StemTreeWidth]interface{} // *stemInternal or *stemLeaf
+	commitment []byte                     // nil if a value below has changed
+}
+
+type stemLeaf struct {
+	stem       []byte
+	values     [StemTreeWidth][]byte
+	commitment []byte
+}
+
+func NewStemTree(committer StemCommitter) *StemTree {
+	return &StemTree{committer: committer, root: &stemInternal{}}
+}
+
+func (t *StemTree) Committer() StemCommitter {
+	return t.committer
+}
+
+// Leaves returns the number of leaves (stems) of the tree.
+func (t *StemTree) Leaves() int {
+	return t.leaves
+}
+
+// Get returns the value of the key, nil if it is absent.
+func (t *StemTree) Get(key []byte) []byte {
+	stem := key[:StemLength]
+	node := t.root
+	for depth := 0; depth < StemLength; depth++ {
+		switch child := node.children[stem[depth]].(type) {
+		case *stemInternal:
+			node = child
+		case *stemLeaf:
+			if bytes.Equal(child.stem, stem) {
+				return child.values[key[StemLength]]
+			}
+			return nil
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Insert sets the value of the key, nil removes it. The leaves of the stems are kept when all their values
+// are removed, as they would be by the proposal.
+func (t *StemTree) Insert(key []byte, value []byte) {
+	stem := key[:StemLength]
+	node := t.root
+	for depth := 0; ; depth++ {
+		node.commitment = nil
+		idx := stem[depth]
+		switch child := node.children[idx].(type) {
+		case *stemInternal:
+			node = child
+			continue
+		case *stemLeaf:
+			if bytes.Equal(child.stem, stem) {
+				child.values[key[StemLength]] = value
+				child.commitment = nil
+				return
+			}
+			if value == nil {
+				return
+			}
+			// split: the two stems get internal nodes down to their first different byte
+			split := &stemInternal{}
+			split.children[child.stem[depth+1]] = child
+			node.children[idx] = split
+			node = split
+			continue
+		default:
+			if value == nil {
+				return
+			}
+			leaf := &stemLeaf{stem: append([]byte(nil), stem...)}
+			leaf.values[key[StemLength]] = value
+			node.children[idx] = leaf
+			t.leaves++
+			return
+		}
+	}
+}
+
+// Root returns the commitment of the tree.
+func (t *StemTree) Root() []byte {
+	return t.commitInternal(t.root)
+}
+
+func (t *StemTree) commitInternal(node *stemInternal) []byte {
+	if node.commitment != nil {
+		return node.commitment
+	}
+	var children [StemTreeWidth][]byte
+	for i, child := range node.children {
+		switch child := child.(type) {
+		case *stemInternal:
+			children[i] = t.commitInternal(child)
+		case *stemLeaf:
+			if child.commitment == nil {
+				child.commitment = t.committer.CommitLeaf(child.stem, &child.values)
+			}
+			children[i] = child.commitment
+		}
+	}
+	node.commitment = t.committer.CommitInternal(&children)
+	return node.commitment
+}
+
+// The layout of the accounts in the tree, as in the verkle proposal: the header values, the first storage
+// slots and the code chunks of an account share the leaves of its first tree indices.
+const (
+	StemVersionLeafKey    = 0
+	StemBalanceLeafKey    = 1
+	StemNonceLeafKey      = 2
+	StemCodeKeccakLeafKey = 3
+	StemCodeSizeLeafKey   = 4
+
+	stemHeaderStorageOffset = 64
+	stemCodeOffset          = 128
+)
+
+// stemMainStorageTreeIndex is the tree index of MAIN_STORAGE_OFFSET = 256^31
+var stemMainStorageTreeIndex = new(uint256.Int).Lsh(uint256.NewInt(1), 240)
+
+// StemTreeKey returns the key of the value at the given tree and sub index of the account.
+func StemTreeKey(c StemCommitter, address []byte, treeIndex *uint256.Int, subIndex byte) []byte {
+	key := make([]byte, StemLength+1)
+	copy(key, c.TreeKeyStem(address, treeIndex))
+	key[StemLength] = subIndex
+	return key
+}
+
+// StemHeaderKey returns the key of a header value of the account, e.g. StemBalanceLeafKey.
+func StemHeaderKey(c StemCommitter, address []byte, leafKey byte) []byte {
+	return StemTreeKey(c, address, new(uint256.Int), leafKey)
+}
+
+// StemStorageKey returns the key of a storage slot of the account.
+func StemStorageKey(c StemCommitter, address []byte, slot *uint256.Int) []byte {
+	if slot.LtUint64(stemCodeOffset - stemHeaderStorageOffset) {
+		pos := slot.Uint64() + stemHeaderStorageOffset
+		return StemTreeKey(c, address, new(uint256.Int), byte(pos))
+	}
+	treeIndex := new(uint256.Int).Rsh(slot, 8)
+	treeIndex.Add(treeIndex, stemMainStorageTreeIndex)
+	return StemTreeKey(c, address, treeIndex, byte(slot.Uint64()))
+}
+
+// StemCodeChunkKey returns the key of a code chunk of the account.
+func StemCodeChunkKey(c StemCommitter, address []byte, chunk uint64) []byte {
+	pos := uint256.NewInt(stemCodeOffset)
+	pos.Add(pos, uint256.NewInt(chunk))
+	return StemTreeKey(c, address, new(uint256.Int).Rsh(pos, 8), byte(pos.Uint64()))
+}
+
+// ChunkifyCode splits the code in chunks of 31 bytes, each prefixed by the number of its leading bytes
+// which are the data of a PUSH instruction.
+func ChunkifyCode(code []byte) [][]byte {
+	const push1, push32 = 0x60, 0x7f
+	pushData := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		if op := code[pc]; op >= push1 && op <= push32 {
+			for i := 0; i <= int(op-push1) && pc+1 < len(code); i++ {
+				pc++
+				pushData[pc] = true
+			}
+		}
+	}
+	chunks := make([][]byte, 0, (len(code)+30)/31)
+	for start := 0; start < len(code); start += 31 {
+		end := start + 31
+		if end > len(code) {
+			end = len(code)
+		}
+		chunk := make([]byte, 32)
+		copy(chunk[1:], code[start:end])
+		for i := start; i < end && pushData[i]; i++ {
+			chunk[0]++
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
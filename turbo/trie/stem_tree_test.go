@@ -0,0 +1,74 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStemTree(t *testing.T) {
+	tree := NewStemTree(KeccakStemCommitter{})
+	empty := tree.Root()
+
+	key := func(stem ...byte) []byte {
+		k := make([]byte, StemLength+1)
+		copy(k, stem)
+		return k
+	}
+	k1, k2, k3 := key(1, 2, 3), key(1, 2, 4), key(1, 2, 3)
+	k3[StemLength] = 7 // same stem as k1
+
+	tree.Insert(k1, []byte{1})
+	root1 := tree.Root()
+	require.NotEqual(t, empty, root1)
+	require.Equal(t, root1, tree.Root())
+
+	tree.Insert(k2, []byte{2}) // splits the leaf of k1
+	tree.Insert(k3, []byte{3})
+	require.Equal(t, 2, tree.Leaves())
+	require.Equal(t, []byte{1}, tree.Get(k1))
+	require.Equal(t, []byte{2}, tree.Get(k2))
+	require.Equal(t, []byte{3}, tree.Get(k3))
+	require.Nil(t, tree.Get(key(1, 2, 5)))
+
+	// the root only depends on the values
+	other := NewStemTree(KeccakStemCommitter{})
+	other.Insert(k3, []byte{3})
+	other.Insert(k2, []byte{2})
+	other.Insert(k1, []byte{1})
+	require.Equal(t, tree.Root(), other.Root())
+
+	tree.Insert(k2, nil)
+	require.Nil(t, tree.Get(k2))
+	require.Equal(t, 2, tree.Leaves()) // kept
+	require.NotEqual(t, other.Root(), tree.Root())
+	other.Insert(k2, nil)
+	require.Equal(t, tree.Root(), other.Root())
+}
+
+func TestStemTreeKeys(t *testing.T) {
+	c := KeccakStemCommitter{}
+	addr := []byte{0xa}
+	balance := StemHeaderKey(c, addr, StemBalanceLeafKey)
+	// the header, the first slots and the first code chunks share a stem
+	require.Equal(t, balance[:StemLength], StemStorageKey(c, addr, uint256.NewInt(0))[:StemLength])
+	require.Equal(t, byte(64), StemStorageKey(c, addr, uint256.NewInt(0))[StemLength])
+	require.Equal(t, byte(128), StemCodeChunkKey(c, addr, 0)[StemLength])
+	require.Equal(t, balance[:StemLength], StemCodeChunkKey(c, addr, 127)[:StemLength])
+	require.NotEqual(t, balance[:StemLength], StemCodeChunkKey(c, addr, 128)[:StemLength])
+	require.NotEqual(t, balance[:StemLength], StemStorageKey(c, addr, uint256.NewInt(64))[:StemLength])
+}
+
+func TestChunkifyCode(t *testing.T) {
+	code := make([]byte, 40)
+	code[29] = 0x61 // PUSH2, its data crosses the chunk boundary
+	code[32] = 0x7f // PUSH32, truncated
+	chunks := ChunkifyCode(code)
+	require.Len(t, chunks, 2)
+	require.Equal(t, byte(0), chunks[0][0])
+	require.Equal(t, code[:31], chunks[0][1:])
+	require.Equal(t, byte(1), chunks[1][0])
+	require.Equal(t, code[31:], chunks[1][1:10])
+	require.Empty(t, ChunkifyCode(nil))
+}
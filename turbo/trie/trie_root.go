@@ -122,6 +122,9 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+	retainDecider  RetainDecider // the nodes to build, see FlatDBTrieLoader.LoadTrie - none if nil
+	retainHex      []byte
+	rootNode       node
 }
 
 type StreamReceiver interface {
@@ -313,6 +316,29 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 	return l.receiver.Root(), nil
 }
 
+// LoadTrie reads the trie like CalcTrieRoot, building the nodes on the paths of the keys retained by the decider
+// passed to Reset - the other nodes are hashes. The keys of the storage retained are the hashed addresses of
+// their accounts followed by the incarnations and the hashed locations, as in HashedStorage.
+func (l *FlatDBTrieLoader) LoadTrie(tx kv.Tx, quit <-chan struct{}) (*Trie, error) {
+	if l.receiver != l.defaultReceiver {
+		return nil, fmt.Errorf("the trie can't be loaded with a stream receiver")
+	}
+	l.defaultReceiver.retainDecider = l.rd
+	defer func() {
+		l.defaultReceiver.retainDecider = nil
+		l.defaultReceiver.rootNode = nil
+	}()
+	root, err := l.CalcTrieRoot(tx, []byte{}, quit)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	if l.defaultReceiver.rootNode != nil {
+		t.root = l.defaultReceiver.rootNode
+	}
+	return t, nil
+}
+
 func (l *FlatDBTrieLoader) logProgress(accountKey, ihK []byte) {
 	var k string
 	if accountKey != nil {
@@ -327,6 +353,23 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+func (r *RootHashAggregator) retainAccount(prefix []byte) bool {
+	return r.retainDecider != nil && r.retainDecider.Retain(prefix)
+}
+
+// retainStorage decides with the prefix of the storage key following the key of its account
+func (r *RootHashAggregator) retainStorage(prefix []byte) bool {
+	if r.retainDecider == nil {
+		return false
+	}
+	r.retainHex = r.retainHex[:0]
+	for _, b := range r.currAccK {
+		r.retainHex = append(r.retainHex, b/16, b%16)
+	}
+	r.retainHex = append(r.retainHex, prefix...)
+	return r.retainDecider.Retain(r.retainHex)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2, trace bool) {
 	r.hc = hc
 	r.shc = shc
@@ -493,8 +536,10 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			r.rootNode = r.hb.root()
 		} else {
 			r.root = EmptyRoot
+			r.rootNode = nil
 		}
 		r.groups = r.groups[:0]
 		r.hasTree = r.hasTree[:0]
@@ -563,7 +608,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.retainStorage, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.shc == nil {
 			return nil
 		}
@@ -631,7 +676,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.retainAccount, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.hc == nil {
 			return nil
 		}
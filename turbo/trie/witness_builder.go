@@ -174,6 +174,10 @@ func (b *WitnessBuilder) processAccountStorage(n *accountNode, hex []byte, limit
 	if n.storage == nil {
 		return b.addEmptyRoot()
 	}
+	// the storage wasn't loaded, none of its keys are retained
+	if hn, ok := n.storage.(hashNode); ok {
+		return b.addHashOp(hn)
+	}
 
 	// Here we substitute rs parameter for storageRs, because it needs to become the default
 	return b.makeBlockWitness(n.storage, hex, limiter, true)
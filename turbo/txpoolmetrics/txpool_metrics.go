@@ -0,0 +1,112 @@
+// Package txpoolmetrics exports the depths of the queues of the senders in the transaction pool, which the pool doesn't
+// export itself: the senders are counted per sub-pool, in the buckets of the number of their transactions.
+package txpoolmetrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	metrics2 "github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon/metrics"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// DefaultInterval - the content of the pool is copied at each update, so it isn't updated at each scrape
+const DefaultInterval = 30 * time.Second
+
+var subPools = map[txpool_proto.AllReply_TxnType]string{
+	txpool_proto.AllReply_PENDING:  "pending",
+	txpool_proto.AllReply_BASE_FEE: "basefee",
+	txpool_proto.AllReply_QUEUED:   "queued",
+}
+
+// senderBuckets are the upper bounds of the numbers of the transactions of the senders counted in each bucket
+var senderBuckets = []struct {
+	label string
+	max   int
+}{{"1", 1}, {"2-4", 4}, {"5-16", 16}, {"17-64", 64}, {"65+", math.MaxInt}}
+
+type subPoolMetrics struct {
+	txs, senders, maxSenderTxs *metrics2.Counter
+	buckets                    []*metrics2.Counter
+}
+
+var metricsBySubPool = map[txpool_proto.AllReply_TxnType]*subPoolMetrics{}
+
+func init() {
+	for t, subPool := range subPools {
+		m := &subPoolMetrics{
+			txs:          metrics2.GetOrCreateCounter(fmt.Sprintf(`txpool_txs{subpool="%s"}`, subPool)),
+			senders:      metrics2.GetOrCreateCounter(fmt.Sprintf(`txpool_senders{subpool="%s"}`, subPool)),
+			maxSenderTxs: metrics2.GetOrCreateCounter(fmt.Sprintf(`txpool_sender_txs_max{subpool="%s"}`, subPool)),
+		}
+		for _, b := range senderBuckets {
+			m.buckets = append(m.buckets, metrics2.GetOrCreateCounter(fmt.Sprintf(`txpool_senders_by_txs{subpool="%s",txs="%s"}`, subPool, b.label)))
+		}
+		metricsBySubPool[t] = m
+	}
+}
+
+// Loop updates the metrics of the pool every interval until the context is done, if the metrics are enabled.
+func Loop(ctx context.Context, pool txpool_proto.TxpoolServer, interval time.Duration) {
+	if !metrics.Enabled {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := Update(ctx, pool); err != nil {
+			log.Debug("Failed to update the txpool metrics", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update sets the metrics to the current content of the pool.
+func Update(ctx context.Context, pool txpool_proto.TxpoolServer) error {
+	reply, err := pool.All(ctx, &txpool_proto.AllRequest{})
+	if err != nil {
+		return err
+	}
+	perSender := map[txpool_proto.AllReply_TxnType]map[[20]byte]int{}
+	for t := range subPools {
+		perSender[t] = map[[20]byte]int{}
+	}
+	for _, tx := range reply.Txs {
+		if senders, ok := perSender[tx.TxnType]; ok {
+			senders[gointerfaces.ConvertH160toAddress(tx.Sender)]++
+		}
+	}
+	for t, senders := range perSender {
+		m := metricsBySubPool[t]
+		counts := make([]uint64, len(senderBuckets))
+		var txs, maxTxs int
+		for _, n := range senders {
+			txs += n
+			if n > maxTxs {
+				maxTxs = n
+			}
+			for i, b := range senderBuckets {
+				if n <= b.max {
+					counts[i]++
+					break
+				}
+			}
+		}
+		m.txs.Set(uint64(txs))
+		m.senders.Set(uint64(len(senders)))
+		m.maxSenderTxs.Set(uint64(maxTxs))
+		for i, count := range counts {
+			m.buckets[i].Set(count)
+		}
+	}
+	return nil
+}
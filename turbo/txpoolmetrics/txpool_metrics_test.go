@@ -0,0 +1,56 @@
+package txpoolmetrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/stretchr/testify/require"
+)
+
+type testPool struct {
+	txpool_proto.UnimplementedTxpoolServer
+	txs []*txpool_proto.AllReply_Tx
+}
+
+func (p *testPool) All(context.Context, *txpool_proto.AllRequest) (*txpool_proto.AllReply, error) {
+	return &txpool_proto.AllReply{Txs: p.txs}, nil
+}
+
+func (p *testPool) add(sender byte, t txpool_proto.AllReply_TxnType, n int) {
+	for i := 0; i < n; i++ {
+		p.txs = append(p.txs, &txpool_proto.AllReply_Tx{Sender: gointerfaces.ConvertAddressToH160([20]byte{sender}), TxnType: t})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	pool := &testPool{}
+	pool.add(1, txpool_proto.AllReply_PENDING, 1)
+	pool.add(2, txpool_proto.AllReply_PENDING, 3)
+	pool.add(3, txpool_proto.AllReply_PENDING, 4)
+	pool.add(3, txpool_proto.AllReply_QUEUED, 70)
+	require.NoError(t, Update(context.Background(), pool))
+
+	pending, queued, baseFee := metricsBySubPool[txpool_proto.AllReply_PENDING], metricsBySubPool[txpool_proto.AllReply_QUEUED], metricsBySubPool[txpool_proto.AllReply_BASE_FEE]
+	require.Equal(t, uint64(8), pending.txs.Get())
+	require.Equal(t, uint64(3), pending.senders.Get())
+	require.Equal(t, uint64(4), pending.maxSenderTxs.Get())
+	require.Equal(t, []uint64{1, 2, 0, 0, 0}, bucketCounts(pending))
+	require.Equal(t, uint64(70), queued.txs.Get())
+	require.Equal(t, []uint64{0, 0, 0, 0, 1}, bucketCounts(queued))
+	require.Equal(t, uint64(0), baseFee.senders.Get())
+
+	// the senders gone from the pool aren't counted anymore
+	pool.txs = nil
+	require.NoError(t, Update(context.Background(), pool))
+	require.Equal(t, uint64(0), pending.txs.Get())
+	require.Equal(t, []uint64{0, 0, 0, 0, 0}, bucketCounts(pending))
+}
+
+func bucketCounts(m *subPoolMetrics) (counts []uint64) {
+	for _, b := range m.buckets {
+		counts = append(counts, b.Get())
+	}
+	return counts
+}